@@ -0,0 +1,185 @@
+// Package access records the files a command opens by running it under
+// strace, so doctrus can report tasks that read files outside their declared
+// inputs. Tracing is only available on Linux with strace installed.
+package access
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Access is a file a traced command opened.
+type Access struct {
+	// Path is the absolute path of the file, as resolved by the kernel
+	Path string
+	// Write is set when the file was first opened for writing or created,
+	// so later reads saw what the command itself produced
+	Write bool
+}
+
+// traced are the system calls that open files.
+var traced = map[string]bool{"open": true, "openat": true, "openat2": true, "creat": true}
+
+// Available reports whether commands can be traced on this machine.
+func Available() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	_, err := exec.LookPath("strace")
+	return err == nil
+}
+
+// Command returns command run under strace, following its child processes
+// and logging the files they open to logPath.
+func Command(logPath string, command []string) []string {
+	return append([]string{
+		"strace", "-f", "-qq",
+		// Print the path behind every file descriptor, so opened files
+		// are logged as the kernel resolved them
+		"-y",
+		"-e", "trace=/^(open|openat2?|creat)$/",
+		"-o", logPath,
+		"--",
+	}, command...)
+}
+
+// ReadLog parses the strace log at path.
+func ReadLog(path string) ([]Access, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file access log: %w", err)
+	}
+	defer file.Close()
+	return ParseLog(file)
+}
+
+// ParseLog parses a log written by strace with the flags of Command. It
+// returns each file opened successfully once, in the order first opened.
+// Directories are left out.
+func ParseLog(r io.Reader) ([]Access, error) {
+	var accesses []Access
+	seen := make(map[string]bool)
+	// Calls interrupted by another process's output, by process ID
+	unfinished := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		pid, call := splitPID(scanner.Text())
+		if before, ok := strings.CutSuffix(call, " <unfinished ...>"); ok {
+			unfinished[pid] = before
+			continue
+		}
+		if strings.HasPrefix(call, "<... ") {
+			_, after, ok := strings.Cut(call, " resumed>")
+			if !ok {
+				continue
+			}
+			call = unfinished[pid] + after
+			delete(unfinished, pid)
+		}
+
+		access, ok := parseCall(call)
+		if !ok || seen[access.Path] {
+			continue
+		}
+		seen[access.Path] = true
+		accesses = append(accesses, access)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file access log: %w", err)
+	}
+	return accesses, nil
+}
+
+// splitPID splits the process ID strace prefixes lines with when following
+// child processes from the rest of the line.
+func splitPID(line string) (string, string) {
+	pid, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		return "", line
+	}
+	if _, err := strconv.Atoi(pid); err != nil {
+		return "", line
+	}
+	return pid, strings.TrimLeft(rest, " ")
+}
+
+// parseCall parses a completed call such as
+//
+//	openat(AT_FDCWD, "src/main.go", O_RDONLY|O_CLOEXEC) = 3</app/src/main.go>
+func parseCall(call string) (Access, bool) {
+	name, args, ok := strings.Cut(call, "(")
+	if !ok || !traced[name] {
+		return Access{}, false
+	}
+	i := strings.LastIndex(args, ") = ")
+	if i < 0 {
+		return Access{}, false
+	}
+	args, result := args[:i], args[i+len(") = "):]
+
+	// Failed calls return -1 and an error name instead of a descriptor
+	_, path, ok := strings.Cut(result, "<")
+	if !ok || !strings.HasSuffix(path, ">") || strings.HasPrefix(result, "-") {
+		return Access{}, false
+	}
+	path = unescape(strings.TrimSuffix(path, ">"))
+
+	// The flags follow the path argument, whose quotes are escaped within it
+	flags := args
+	if end := strings.LastIndex(args, `"`); end >= 0 {
+		flags = args[end+1:]
+	}
+	if strings.Contains(flags, "O_DIRECTORY") {
+		return Access{}, false
+	}
+	write := name == "creat" || strings.Contains(flags, "O_WRONLY") || strings.Contains(flags, "O_CREAT")
+	return Access{Path: path, Write: write}, true
+}
+
+// unescape decodes the C escapes strace writes in paths: \\, \", \n, \t,
+// \xNN and octal \NNN.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; {
+		case c == 'n':
+			b.WriteByte('\n')
+		case c == 't':
+			b.WriteByte('\t')
+		case c == 'x' && i+2 < len(s):
+			if v, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 2
+			} else {
+				b.WriteByte(c)
+			}
+		case c >= '0' && c <= '7':
+			end := i + 1
+			for end < len(s) && end < i+3 && s[end] >= '0' && s[end] <= '7' {
+				end++
+			}
+			v, _ := strconv.ParseUint(s[i:end], 8, 8)
+			b.WriteByte(byte(v))
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
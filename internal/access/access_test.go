@@ -0,0 +1,129 @@
+package access
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLog(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+		want []Access
+	}{
+		{
+			name: "reads and writes",
+			log: `openat(AT_FDCWD, "src/main.go", O_RDONLY|O_CLOEXEC) = 3</app/src/main.go>
+open("/etc/hosts", O_RDONLY) = 4</etc/hosts>
+openat(AT_FDCWD, "out.txt", O_WRONLY|O_CREAT|O_TRUNC, 0666) = 5</app/out.txt>
+creat("log.txt", 0644) = 6</app/log.txt>
+openat2(AT_FDCWD, "go.mod", {flags=O_RDONLY, resolve=0}, 24) = 7</app/go.mod>
+openat(AT_FDCWD, "data.db", O_RDWR) = 8</app/data.db>
+`,
+			want: []Access{
+				{Path: "/app/src/main.go"},
+				{Path: "/etc/hosts"},
+				{Path: "/app/out.txt", Write: true},
+				{Path: "/app/log.txt", Write: true},
+				{Path: "/app/go.mod"},
+				{Path: "/app/data.db"},
+			},
+		},
+		{
+			name: "skips failed calls and directories",
+			log: `openat(AT_FDCWD, "missing", O_RDONLY) = -1 ENOENT (No such file or directory)
+openat(AT_FDCWD, "src", O_RDONLY|O_NONBLOCK|O_CLOEXEC|O_DIRECTORY) = 3</app/src>
++++ exited with 0 +++
+`,
+		},
+		{
+			name: "keeps the first access",
+			log: `openat(AT_FDCWD, "out.txt", O_WRONLY|O_CREAT, 0666) = 3</app/out.txt>
+openat(AT_FDCWD, "out.txt", O_RDONLY) = 3</app/out.txt>
+openat(AT_FDCWD, "in.txt", O_RDONLY) = 3</app/in.txt>
+openat(AT_FDCWD, "in.txt", O_WRONLY|O_TRUNC) = 3</app/in.txt>
+`,
+			want: []Access{
+				{Path: "/app/out.txt", Write: true},
+				{Path: "/app/in.txt"},
+			},
+		},
+		{
+			name: "child processes",
+			log: `101   openat(AT_FDCWD, "a.txt", O_RDONLY <unfinished ...>
+102   openat(AT_FDCWD, "b.txt", O_RDONLY) = 3</app/b.txt>
+101   <... openat resumed>) = 4</app/a.txt>
+`,
+			want: []Access{
+				{Path: "/app/b.txt"},
+				{Path: "/app/a.txt"},
+			},
+		},
+		{
+			name: "escaped paths",
+			log: `openat(AT_FDCWD, "we\"ird", O_RDONLY) = 3</app/we"ird>
+openat(AT_FDCWD, "caf\303\251", O_RDONLY) = 3</app/caf\303\251>
+openat(AT_FDCWD, "a\x3eb", O_RDONLY) = 3</app/a\x3eb>
+`,
+			want: []Access{
+				{Path: `/app/we"ird`},
+				{Path: "/app/café"},
+				{Path: "/app/a>b"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLog(strings.NewReader(tt.log))
+			if err != nil {
+				t.Fatalf("ParseLog() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLog() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandTracesReads(t *testing.T) {
+	if !Available() {
+		t.Skip("strace not available")
+	}
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	if err := os.WriteFile(input, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(dir, "access.log")
+	args := Command(logPath, []string{"sh", "-c", "cat input.txt > output.txt"})
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("traced command failed: %v\n%s", err, out)
+	}
+
+	accesses, err := ReadLog(logPath)
+	if err != nil {
+		t.Fatalf("ReadLog() error = %v", err)
+	}
+	found := make(map[string]bool)
+	for _, a := range accesses {
+		found[a.Path] = a.Write
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if write, ok := found[filepath.Join(realDir, "input.txt")]; !ok || write {
+		t.Errorf("input.txt not logged as read: %+v", accesses)
+	}
+	if write := found[filepath.Join(realDir, "output.txt")]; !write {
+		t.Errorf("output.txt not logged as written: %+v", accesses)
+	}
+}
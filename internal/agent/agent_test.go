@@ -0,0 +1,277 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+func TestExecutorRunsTaskOnAgent(t *testing.T) {
+	t.Setenv(TokenEnv, "secret")
+	server := httptest.NewServer(NewServer("secret", 1, nil))
+	defer server.Close()
+
+	projectDir := t.TempDir()
+	appDir := filepath.Join(projectDir, "app")
+	if err := os.MkdirAll(filepath.Join(appDir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(appDir, "src", "name.txt"), []byte("doctrus"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{RemoteExecution: &config.RemoteExecution{URL: server.URL}}
+	execution := &workspace.TaskExecution{
+		WorkspaceName: "app",
+		TaskName:      "build",
+		Task: &config.Task{
+			Command: []string{"sh", "-c", `mkdir -p dist && echo "hello $(cat src/name.txt) $GREETING" | tee dist/out.txt && echo warn >&2`},
+			Inputs:  []string{"src/**/*.txt"},
+			Outputs: []string{"dist/*.txt"},
+		},
+		Workspace: &config.Workspace{Path: "app"},
+		AbsPath:   appDir,
+		Env:       map[string]string{"GREETING": "remotely"},
+	}
+
+	var stdout, stderr bytes.Buffer
+	result := NewExecutor(cfg, projectDir).Execute(context.Background(), execution, &stdout, &stderr)
+	if result.Error != nil || result.ExitCode != 0 {
+		t.Fatalf("Execute() = exit %d, error %v; stderr %q", result.ExitCode, result.Error, stderr.String())
+	}
+	if got, want := stdout.String(), "hello doctrus remotely\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	if result.Stdout != stdout.String() {
+		t.Errorf("result.Stdout = %q, want %q", result.Stdout, stdout.String())
+	}
+	if got := stderr.String(); got != "warn\n" {
+		t.Errorf("stderr = %q, want %q", got, "warn\n")
+	}
+
+	data, err := os.ReadFile(filepath.Join(appDir, "dist", "out.txt"))
+	if err != nil {
+		t.Fatalf("output not unpacked: %v", err)
+	}
+	if string(data) != "hello doctrus remotely\n" {
+		t.Errorf("output = %q", data)
+	}
+}
+
+func TestExecutorReportsFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		command   []string
+		inputs    []string
+		wantExit  int
+		wantError string
+	}{
+		{
+			name:     "command fails",
+			token:    "secret",
+			command:  []string{"sh", "-c", "exit 3"},
+			wantExit: 3,
+		},
+		{
+			name:     "undeclared input is missing",
+			token:    "secret",
+			command:  []string{"cat", "secret.txt"},
+			wantExit: 1,
+		},
+		{
+			name:      "wrong token",
+			token:     "guess",
+			command:   []string{"true"},
+			wantExit:  1,
+			wantError: "401 Unauthorized",
+		},
+	}
+
+	server := httptest.NewServer(NewServer("secret", 0, nil))
+	defer server.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(TokenEnv, tt.token)
+			projectDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(projectDir, "secret.txt"), []byte("x"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := &config.Config{RemoteExecution: &config.RemoteExecution{URL: server.URL}}
+			execution := &workspace.TaskExecution{
+				WorkspaceName: "app",
+				TaskName:      "check",
+				Task:          &config.Task{Command: tt.command, Inputs: tt.inputs},
+				Workspace:     &config.Workspace{},
+				AbsPath:       projectDir,
+				Env:           map[string]string{},
+			}
+
+			result := NewExecutor(cfg, projectDir).Execute(context.Background(), execution, nil, nil)
+			if result.ExitCode != tt.wantExit {
+				t.Errorf("exit code = %d, want %d (error %v)", result.ExitCode, tt.wantExit, result.Error)
+			}
+			if tt.wantError != "" && (result.Error == nil || !strings.Contains(result.Error.Error(), tt.wantError)) {
+				t.Errorf("error = %v, want it to contain %q", result.Error, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestServerRejectsInvalidRequests(t *testing.T) {
+	server := httptest.NewServer(NewServer("", 0, nil))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{name: "unknown path", method: http.MethodPost, path: "/v1/other", want: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodGet, path: ExecutePath, want: http.StatusMethodNotAllowed},
+		{name: "not multipart", method: http.MethodPost, path: ExecutePath, body: "{}", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckListen(t *testing.T) {
+	tests := []struct {
+		addr    string
+		token   string
+		wantErr bool
+	}{
+		{"127.0.0.1:7070", "", false},
+		{"[::1]:7070", "", false},
+		{"localhost:7070", "", false},
+		{":7070", "", true},
+		{"0.0.0.0:7070", "", true},
+		{"10.0.0.5:7070", "", true},
+		{"10.0.0.5:7070", "secret", false},
+		{":7070", "secret", false},
+		{"7070", "", true},
+	}
+	for _, tt := range tests {
+		if err := CheckListen(tt.addr, tt.token); (err != nil) != tt.wantErr {
+			t.Errorf("CheckListen(%q, %q) error = %v, wantErr %v", tt.addr, tt.token, err, tt.wantErr)
+		}
+	}
+}
+
+func TestInsideRoot(t *testing.T) {
+	tests := map[string]bool{
+		"":              true,
+		".":             true,
+		"app/src/a.go":  true,
+		"app/../b":      true,
+		"..":            false,
+		"../etc/passwd": false,
+		"a/../../b":     false,
+		"/etc/passwd":   false,
+	}
+	for name, want := range tests {
+		if got := insideRoot(name); got != want {
+			t.Errorf("insideRoot(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestExecutorSharesTasksBetweenAgents(t *testing.T) {
+	first := httptest.NewServer(NewServer("", 0, nil))
+	defer first.Close()
+	second := httptest.NewServer(NewServer("", 0, nil))
+	defer second.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	cfg := &config.Config{RemoteExecution: &config.RemoteExecution{Agents: []config.RemoteAgent{
+		{URL: down.URL, Jobs: 1},
+		{URL: first.URL, Jobs: 1},
+		{URL: second.URL, Jobs: 1},
+	}}}
+	projectDir := t.TempDir()
+	executor := NewExecutor(cfg, projectDir)
+	if got := executor.Capacity(); got != 3 {
+		t.Errorf("Capacity() = %d, want 3", got)
+	}
+
+	// Two tasks at once fill the slots of both reachable agents
+	release := make(chan struct{})
+	results := make(chan int, 2)
+	for _, command := range []string{"sleep 0.2", "sleep 0.2"} {
+		go func() {
+			execution := &workspace.TaskExecution{
+				WorkspaceName: "app",
+				TaskName:      "sleep",
+				Task:          &config.Task{Command: []string{"sh", "-c", command}},
+				Workspace:     &config.Workspace{},
+				AbsPath:       projectDir,
+				Env:           map[string]string{},
+			}
+			<-release
+			results <- executor.Execute(context.Background(), execution, nil, nil).ExitCode
+		}()
+	}
+	close(release)
+	for range 2 {
+		if code := <-results; code != 0 {
+			t.Errorf("exit code = %d, want 0", code)
+		}
+	}
+
+	stats := executor.Stats()
+	if !stats[0].Unreachable || stats[0].Tasks != 0 {
+		t.Errorf("stats[0] = %+v, want the closed agent unreachable without tasks", stats[0])
+	}
+	if stats[1].Tasks != 1 || stats[2].Tasks != 1 {
+		t.Errorf("tasks per agent = %d, %d, want one each", stats[1].Tasks, stats[2].Tasks)
+	}
+}
+
+func TestExecutorFailsWithoutReachableAgents(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	projectDir := t.TempDir()
+	cfg := &config.Config{RemoteExecution: &config.RemoteExecution{URL: down.URL}}
+	execution := &workspace.TaskExecution{
+		WorkspaceName: "app",
+		TaskName:      "check",
+		Task:          &config.Task{Command: []string{"true"}},
+		Workspace:     &config.Workspace{},
+		AbsPath:       projectDir,
+		Env:           map[string]string{},
+	}
+
+	result := NewExecutor(cfg, projectDir).Execute(context.Background(), execution, nil, nil)
+	if result.ExitCode != 1 || result.Error == nil || !strings.Contains(result.Error.Error(), "no agent is reachable") {
+		t.Errorf("Execute() = exit %d, error %v, want no agent is reachable", result.ExitCode, result.Error)
+	}
+}
@@ -0,0 +1,257 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/docker"
+	"doctrus/internal/workspace"
+)
+
+// Executor runs tasks on the agents configured under remote_execution. It
+// sends the files matching the task's inputs, streams the command's output
+// to the writers as the agent reports it, and unpacks the output files the
+// agent returns into the project. Cancelling the task closes the
+// connection, which makes the agent kill the command.
+//
+// With several agents, each task goes to the least busy agent with a free
+// slot, and tasks that cannot be sent to an agent are retried on the others.
+type Executor struct {
+	config     *config.Config
+	workingDir string
+	client     *http.Client
+	pool       *pool
+}
+
+// NewExecutor returns the remote executor for the project in workingDir.
+func NewExecutor(cfg *config.Config, workingDir string) *Executor {
+	return &Executor{
+		config:     cfg,
+		workingDir: workingDir,
+		client:     http.DefaultClient,
+		pool:       newPool(cfg.RemoteExecution.AgentList()),
+	}
+}
+
+// Stats returns what every agent did so far, in the configured order.
+func (e *Executor) Stats() []AgentStats {
+	return e.pool.stats()
+}
+
+// Capacity returns the number of tasks the agents take at once, or 0 when
+// one of them has no limit.
+func (e *Executor) Capacity() int {
+	return e.pool.capacity()
+}
+
+func (e *Executor) Execute(ctx context.Context, execution *workspace.TaskExecution, stdoutWriter, stderrWriter io.Writer) *docker.ExecutionResult {
+	start := time.Now()
+	job, inputs, err := e.newJob(execution)
+	if err != nil {
+		return failed(err)
+	}
+
+	for {
+		agent, err := e.pool.acquire(ctx)
+		if err != nil {
+			return interrupted(ctx, err, start)
+		}
+		result, sent := e.send(ctx, agent.url, job, inputs, stdoutWriter, stderrWriter)
+		if !sent && ctx.Err() == nil {
+			e.pool.unreachable(agent)
+			continue
+		}
+		e.pool.release(agent, result.Usage.WallTime, result.ExitCode)
+		return result
+	}
+}
+
+// send runs job on the agent at url. It reports whether the agent received
+// the job; when it did not, nothing ran and the job can go to another agent.
+func (e *Executor) send(ctx context.Context, url string, job Job, inputs []deps.FileInfo, stdoutWriter, stderrWriter io.Writer) (*docker.ExecutionResult, bool) {
+	start := time.Now()
+	remote := e.config.RemoteExecution
+	body, contentType := requestBody(job, e.workingDir, inputs)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(url, "/")+ExecutePath, body)
+	if err != nil {
+		return failed(fmt.Errorf("invalid remote execution request: %w", err)), true
+	}
+	req.Header.Set("Content-Type", contentType)
+	tokenEnv := remote.TokenEnv
+	if tokenEnv == "" {
+		tokenEnv = TokenEnv
+	}
+	if token := os.Getenv(tokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return interrupted(ctx, fmt.Errorf("failed to reach agent %s: %w", url, err), start), false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return failed(fmt.Errorf("agent %s rejected the task: %s", url, responseError(resp))), true
+	}
+
+	var stdout, stderr bytes.Buffer
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var frame Frame
+		if err := decoder.Decode(&frame); err != nil {
+			return interrupted(ctx, fmt.Errorf("agent %s closed the connection: %w", url, err), start), true
+		}
+
+		switch frame.Stream {
+		case "stdout":
+			write(&stdout, stdoutWriter, frame.Data)
+		case "stderr":
+			write(&stderr, stderrWriter, frame.Data)
+		}
+		if !frame.Done {
+			continue
+		}
+
+		result := &docker.ExecutionResult{
+			ExitCode: frame.ExitCode,
+			Stdout:   stdout.String(),
+			Stderr:   stderr.String(),
+			Usage:    docker.ResourceUsage{WallTime: time.Since(start)},
+		}
+		if frame.Error != "" {
+			result.Error = errors.New(frame.Error)
+		}
+		if len(frame.Outputs) > 0 {
+			if _, err := cache.ExtractArchive(bytes.NewReader(frame.Outputs), e.workingDir); err != nil {
+				result.ExitCode, result.Error = 1, fmt.Errorf("failed to unpack outputs from agent: %w", err)
+			}
+		}
+		return result, true
+	}
+}
+
+// newJob describes execution for the agent along with the input files to
+// send, which must all be inside the project.
+func (e *Executor) newJob(execution *workspace.TaskExecution) (Job, []deps.FileInfo, error) {
+	if len(e.pool.agents) == 0 {
+		return Job{}, nil, fmt.Errorf("remote_execution has no agents configured")
+	}
+	if len(execution.Task.Command) == 0 {
+		return Job{}, nil, fmt.Errorf("no command specified")
+	}
+
+	dir, err := projectPath(e.workingDir, execution.AbsPath)
+	if err != nil {
+		return Job{}, nil, fmt.Errorf("workspace directory %s: %w", execution.AbsPath, err)
+	}
+	inputs, err := deps.NewTracker(e.workingDir).InputHashes(execution)
+	if err != nil {
+		return Job{}, nil, err
+	}
+	for i, input := range inputs {
+		if inputs[i].Path, err = projectPath(e.workingDir, filepath.Join(e.workingDir, input.Path)); err != nil {
+			return Job{}, nil, fmt.Errorf("input %s: %w", input.Path, err)
+		}
+	}
+
+	return Job{
+		Version:   ProtocolVersion,
+		Task:      execution.WorkspaceName + ":" + execution.TaskName,
+		Command:   execution.Task.Command,
+		Dir:       dir,
+		Env:       execution.Env,
+		AppendEnv: execution.AppendEnv,
+		Hermetic:  execution.Task.Hermetic,
+		PassEnv:   execution.Task.PassEnv,
+		Inputs:    inputs,
+		Outputs:   execution.Task.Outputs,
+	}, inputs, nil
+}
+
+// projectPath returns path relative to root, slash-separated, failing for
+// paths outside root.
+func projectPath(root, path string) (string, error) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("outside the project directory %s", root)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// requestBody streams the multipart body of a job: the job itself, then the
+// archive of its input files read below root.
+func requestBody(job Job, root string, inputs []deps.FileInfo) (io.Reader, string) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeRequest(writer, job, root, inputs))
+	}()
+	return pr, writer.FormDataContentType()
+}
+
+func writeRequest(writer *multipart.Writer, job Job, root string, inputs []deps.FileInfo) error {
+	part, err := writer.CreateFormField("job")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(part).Encode(job); err != nil {
+		return err
+	}
+	part, err = writer.CreateFormFile("inputs", "inputs.tar.gz")
+	if err != nil {
+		return err
+	}
+	if err := cache.WriteArchive(part, root, inputs); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func write(buffer *bytes.Buffer, writer io.Writer, data string) {
+	buffer.WriteString(data)
+	if writer != nil {
+		io.WriteString(writer, data)
+	}
+}
+
+func failed(err error) *docker.ExecutionResult {
+	return &docker.ExecutionResult{ExitCode: 1, Error: err}
+}
+
+// interrupted is the result of a job whose connection broke: a cancelled
+// task, with the exit codes local commands get, or a failure.
+func interrupted(ctx context.Context, err error, start time.Time) *docker.ExecutionResult {
+	result := failed(err)
+	result.Usage.WallTime = time.Since(start)
+	if ctx.Err() != nil {
+		result.Cause = context.Cause(ctx)
+		result.ExitCode = 130
+		if errors.Is(result.Cause, context.DeadlineExceeded) {
+			result.ExitCode = 124
+		}
+	}
+	return result
+}
+
+// responseError describes an unexpected response, including the start of
+// its body, which agents use for error messages.
+func responseError(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if message := strings.TrimSpace(string(body)); message != "" {
+		return fmt.Sprintf("%s: %s", resp.Status, message)
+	}
+	return resp.Status
+}
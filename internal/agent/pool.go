@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"doctrus/internal/config"
+)
+
+// AgentStats is what an agent did during a run.
+type AgentStats struct {
+	URL string
+	// Tasks counts the tasks the agent ran, Failed those that did not
+	// succeed
+	Tasks  int
+	Failed int
+	// Busy is the summed wall time of its tasks
+	Busy time.Duration
+	// Unreachable is set when a task could not be sent to the agent, which
+	// then got no further tasks
+	Unreachable bool
+}
+
+// pool hands tasks to agents. Idle agents take the next task as soon as a
+// slot frees up, so faster agents end up running more of a graph than slower
+// ones, and tasks that could not be sent to an agent move on to the others.
+type pool struct {
+	mu      sync.Mutex
+	agents  []*remoteAgent
+	changed chan struct{}
+}
+
+type remoteAgent struct {
+	url     string
+	jobs    int
+	running int
+	stats   AgentStats
+}
+
+func newPool(agents []config.RemoteAgent) *pool {
+	p := &pool{changed: make(chan struct{})}
+	for _, agent := range agents {
+		p.agents = append(p.agents, &remoteAgent{url: agent.URL, jobs: agent.Jobs, stats: AgentStats{URL: agent.URL}})
+	}
+	return p
+}
+
+// acquire waits for an agent with a free slot and takes it, picking the one
+// running the fewest tasks. It fails once every agent is unreachable.
+func (p *pool) acquire(ctx context.Context) (*remoteAgent, error) {
+	for {
+		p.mu.Lock()
+		var best *remoteAgent
+		reachable := false
+		for _, agent := range p.agents {
+			if agent.stats.Unreachable {
+				continue
+			}
+			reachable = true
+			if agent.jobs > 0 && agent.running >= agent.jobs {
+				continue
+			}
+			if best == nil || agent.running < best.running {
+				best = agent
+			}
+		}
+		if best != nil {
+			best.running++
+			p.mu.Unlock()
+			return best, nil
+		}
+		changed := p.changed
+		p.mu.Unlock()
+
+		if !reachable {
+			return nil, fmt.Errorf("no agent is reachable")
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		}
+	}
+}
+
+// release returns the slot of a task that ran on agent for busy and
+// finished with exitCode.
+func (p *pool) release(agent *remoteAgent, busy time.Duration, exitCode int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	agent.running--
+	agent.stats.Tasks++
+	agent.stats.Busy += busy
+	if exitCode != 0 {
+		agent.stats.Failed++
+	}
+	p.notify()
+}
+
+// unreachable returns the slot of a task that could not be sent to agent
+// and stops sending it tasks.
+func (p *pool) unreachable(agent *remoteAgent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	agent.running--
+	agent.stats.Unreachable = true
+	p.notify()
+}
+
+func (p *pool) notify() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// capacity is the number of tasks the agents take at once, or 0 when one of
+// them has no limit.
+func (p *pool) capacity() int {
+	total := 0
+	for _, agent := range p.agents {
+		if agent.jobs == 0 {
+			return 0
+		}
+		total += agent.jobs
+	}
+	return total
+}
+
+func (p *pool) stats() []AgentStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]AgentStats, len(p.agents))
+	for i, agent := range p.agents {
+		stats[i] = agent.stats
+	}
+	return stats
+}
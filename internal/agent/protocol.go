@@ -0,0 +1,64 @@
+// Package agent offloads tasks to another machine. A doctrus agent, started
+// with doctrus agent, receives a job over HTTP, runs it in a scratch copy of
+// the task's input files and streams its output and output files back. The
+// remote executor is the client side.
+//
+// A job is a multipart/form-data POST to /v1/execute with two parts: "job",
+// the JSON encoded Job, and "inputs", a gzip-compressed tar of the files in
+// the job's input manifest (see cache.WriteArchive). The response is a
+// stream of JSON encoded Frames, one per line: stdout and stderr chunks
+// while the command runs, then a final frame with Done set.
+package agent
+
+import (
+	"doctrus/internal/deps"
+)
+
+// ProtocolVersion is the version of the job format. Agents reject jobs of
+// other versions.
+const ProtocolVersion = 1
+
+// ExecutePath is where agents accept jobs.
+const ExecutePath = "/v1/execute"
+
+// TokenEnv is the variable holding the bearer token agents require and
+// clients send, unless remote_execution.token_env names another.
+const TokenEnv = "DOCTRUS_AGENT_TOKEN"
+
+// Job is a task to run on an agent. Paths are slash-separated and relative
+// to the project root, which the agent recreates in a scratch directory from
+// the inputs archive.
+type Job struct {
+	Version int    `json:"version"`
+	Task    string `json:"task"`
+	// Command is the task's command, including its wrapper
+	Command []string `json:"command"`
+	// Dir is the task's working directory
+	Dir string `json:"dir"`
+	// Env is the task's resolved environment, added to the agent's own, and
+	// AppendEnv the PATH-like variables in it that extend the agent's value
+	Env       map[string]string `json:"env,omitempty"`
+	AppendEnv []string          `json:"append_env,omitempty"`
+	Hermetic  bool              `json:"hermetic,omitempty"`
+	PassEnv   []string          `json:"pass_env,omitempty"`
+	// Inputs is the manifest of the files in the inputs archive, whose
+	// hashes the agent verifies
+	Inputs []deps.FileInfo `json:"inputs"`
+	// Outputs are the task's output globs, relative to Dir
+	Outputs []string `json:"outputs,omitempty"`
+}
+
+// Frame is a line of an agent's response.
+type Frame struct {
+	// Stream is stdout or stderr for output chunks, which carry Data
+	Stream string `json:"stream,omitempty"`
+	Data   string `json:"data,omitempty"`
+
+	// Done marks the final frame, which carries the command's exit code,
+	// the error that kept it from running or completing, and for commands
+	// that succeeded the output files as a gzip-compressed tar
+	Done     bool   `json:"done,omitempty"`
+	ExitCode int    `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Outputs  []byte `json:"outputs,omitempty"`
+}
@@ -0,0 +1,256 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/docker"
+	"doctrus/internal/workspace"
+)
+
+// Server is the HTTP handler of a doctrus agent. Every job runs with the
+// local executor in its own scratch directory, which is removed afterwards.
+type Server struct {
+	token string
+	// slots holds a token for every job running when the number of jobs at
+	// once is limited, and is nil otherwise
+	slots chan struct{}
+	logf  func(format string, args ...any)
+}
+
+// NewServer returns an agent that requires token as bearer token, unless it
+// is empty, and runs at most maxJobs jobs at once, or any number when
+// maxJobs is zero. Each finished job is reported through logf.
+func NewServer(token string, maxJobs int, logf func(format string, args ...any)) *Server {
+	s := &Server{token: token, logf: logf}
+	if maxJobs > 0 {
+		s.slots = make(chan struct{}, maxJobs)
+	}
+	if s.logf == nil {
+		s.logf = func(string, ...any) {}
+	}
+	return s
+}
+
+// CheckListen refuses to listen on addr without token unless addr is a
+// loopback address, as an agent without a token runs any command it is
+// sent.
+func CheckListen(addr, token string) error {
+	if token != "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %s: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("refusing to listen on %s without a token: set %s or listen on a loopback address such as 127.0.0.1", addr, TokenEnv)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != ExecutePath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.token)) != 1 {
+		http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+
+	if s.slots != nil {
+		select {
+		case s.slots <- struct{}{}:
+			defer func() { <-s.slots }()
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "doctrus-agent-*")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to create job directory: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	job, err := receiveJob(r, dir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	frames := &frameWriter{w: w}
+	frames.flusher, _ = w.(http.Flusher)
+
+	start := time.Now()
+	final := runJob(r, job, dir, frames)
+	frames.send(final)
+	s.logf("%s finished with exit code %d in %s\n", job.Task, final.ExitCode, time.Since(start).Round(time.Millisecond))
+}
+
+// receiveJob reads a job and unpacks its inputs below dir, verifying them
+// against the job's manifest.
+func receiveJob(r *http.Request, dir string) (Job, error) {
+	var job Job
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return job, fmt.Errorf("invalid job request: %w", err)
+	}
+
+	part, err := reader.NextPart()
+	if err != nil || part.FormName() != "job" {
+		return job, fmt.Errorf("invalid job request: expected the job first")
+	}
+	if err := json.NewDecoder(part).Decode(&job); err != nil {
+		return job, fmt.Errorf("invalid job: %w", err)
+	}
+	switch {
+	case job.Version != ProtocolVersion:
+		return job, fmt.Errorf("unsupported job version %d (this agent speaks version %d)", job.Version, ProtocolVersion)
+	case len(job.Command) == 0:
+		return job, fmt.Errorf("invalid job: command is required")
+	case !insideRoot(job.Dir):
+		return job, fmt.Errorf("invalid job: directory %s is outside the project", job.Dir)
+	}
+
+	part, err = reader.NextPart()
+	if err != nil || part.FormName() != "inputs" {
+		return job, fmt.Errorf("invalid job request: expected the inputs after the job")
+	}
+	written, err := cache.ExtractArchive(part, dir)
+	if err != nil {
+		return job, fmt.Errorf("invalid inputs: %w", err)
+	}
+	if len(written) != len(job.Inputs) {
+		return job, fmt.Errorf("invalid inputs: received %d files, the manifest lists %d", len(written), len(job.Inputs))
+	}
+
+	tracker := deps.NewTracker(dir)
+	for _, input := range job.Inputs {
+		if !insideRoot(input.Path) {
+			return job, fmt.Errorf("invalid inputs: %s is outside the project", input.Path)
+		}
+		info, err := tracker.HashFile(filepath.Join(dir, filepath.FromSlash(input.Path)))
+		if err != nil {
+			return job, fmt.Errorf("invalid inputs: %s: %w", input.Path, err)
+		}
+		if info.Hash != input.Hash {
+			return job, fmt.Errorf("invalid inputs: %s does not match its hash in the manifest", input.Path)
+		}
+	}
+	return job, nil
+}
+
+// runJob runs the job's command in its directory below dir, streaming its
+// output as frames, and returns the final frame.
+func runJob(r *http.Request, job Job, dir string, frames *frameWriter) Frame {
+	workspaceName, taskName, _ := strings.Cut(job.Task, ":")
+	execution := &workspace.TaskExecution{
+		WorkspaceName: workspaceName,
+		TaskName:      taskName,
+		Task: &config.Task{
+			Command:  job.Command,
+			Outputs:  job.Outputs,
+			Hermetic: job.Hermetic,
+			PassEnv:  job.PassEnv,
+		},
+		Workspace: &config.Workspace{},
+		AbsPath:   filepath.Join(dir, filepath.FromSlash(job.Dir)),
+		Env:       job.Env,
+		AppendEnv: job.AppendEnv,
+	}
+	if execution.Env == nil {
+		execution.Env = map[string]string{}
+	}
+	if err := os.MkdirAll(execution.AbsPath, 0755); err != nil {
+		return Frame{Done: true, ExitCode: 1, Error: fmt.Sprintf("failed to create working directory: %v", err)}
+	}
+
+	result := docker.NewLocalExecutor().Execute(r.Context(), execution, frames.stream("stdout"), frames.stream("stderr"))
+	final := Frame{Done: true, ExitCode: result.ExitCode}
+	if result.Error != nil {
+		final.Error = result.Error.Error()
+	}
+	if result.ExitCode != 0 || result.Error != nil || len(job.Outputs) == 0 {
+		return final
+	}
+
+	outputs, err := deps.NewTracker(dir).OutputHashes(execution)
+	if err != nil {
+		return Frame{Done: true, ExitCode: 1, Error: fmt.Sprintf("failed to collect outputs: %v", err)}
+	}
+	var archive bytes.Buffer
+	if err := cache.WriteArchive(&archive, dir, outputs); err != nil {
+		return Frame{Done: true, ExitCode: 1, Error: fmt.Sprintf("failed to collect outputs: %v", err)}
+	}
+	final.Outputs = archive.Bytes()
+	return final
+}
+
+// insideRoot reports whether a slash-separated relative path stays inside
+// the directory it is relative to.
+func insideRoot(name string) bool {
+	name = path.Clean(name)
+	return !path.IsAbs(name) && name != ".." && !strings.HasPrefix(name, "../")
+}
+
+// frameWriter writes frames to the response, flushing each so output
+// reaches the client as the command produces it.
+type frameWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func (f *frameWriter) send(frame Frame) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := json.NewEncoder(f.w).Encode(frame); err != nil {
+		return
+	}
+	if f.flusher != nil {
+		f.flusher.Flush()
+	}
+}
+
+// stream returns a writer sending everything written to it as output
+// frames of stream.
+func (f *frameWriter) stream(stream string) io.Writer {
+	return streamWriter{frames: f, stream: stream}
+}
+
+type streamWriter struct {
+	frames *frameWriter
+	stream string
+}
+
+func (s streamWriter) Write(p []byte) (int, error) {
+	s.frames.send(Frame{Stream: s.stream, Data: string(p)})
+	return len(p), nil
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"doctrus/internal/deps"
+	"doctrus/internal/fsutil"
+)
+
+// WriteArchive writes the files as a gzip-compressed tar to w, named by
+// their slash-separated paths relative to root. This is the artifact format
+// of the Turborepo remote cache API.
+func WriteArchive(w io.Writer, root string, files []deps.FileInfo) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, file := range files {
+		if err := addToArchive(tw, root, file.Path); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+func addToArchive(tw *tar.Writer, root, name string) error {
+	file, err := os.Open(filepath.Join(root, name))
+	if err != nil {
+		return fmt.Errorf("failed to archive %s: %w", name, err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to archive %s: %w", name, err)
+	}
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     filepath.ToSlash(name),
+		Mode:     int64(stat.Mode().Perm()),
+		Size:     stat.Size(),
+		ModTime:  stat.ModTime(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", name, err)
+	}
+	if _, err := io.Copy(tw, file); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", name, err)
+	}
+	return nil
+}
+
+// ExtractArchive unpacks an archive written by WriteArchive below root,
+// replacing existing files, and returns the paths it wrote. Entries that
+// are not regular files or directories, or whose paths would leave root,
+// are rejected.
+func ExtractArchive(r io.Reader, root string) ([]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer gz.Close()
+
+	var written []string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return written, fmt.Errorf("archive entry %s is outside the project", header.Name)
+		}
+		target := filepath.Join(root, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return written, fmt.Errorf("failed to extract %s: %w", name, err)
+			}
+		case tar.TypeReg:
+			if err := extractFile(tr, target, os.FileMode(header.Mode).Perm()); err != nil {
+				return written, fmt.Errorf("failed to extract %s: %w", name, err)
+			}
+			written = append(written, name)
+		default:
+			return written, fmt.Errorf("archive entry %s is not a regular file", header.Name)
+		}
+	}
+}
+
+func extractFile(r io.Reader, target string, mode os.FileMode) error {
+	if mode == 0 {
+		mode = 0644
+	}
+	return fsutil.WriteFileAtomic(target, mode, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// indexFile is an append-only log of cache writes kept next to the per-task
+// files. Every Set appends the entry and every Delete a tombstone, so the
+// latest state of many tasks can be read in one pass. The leading dot keeps
+// it apart from task files, whose names are derived from task keys.
+const indexFile = ".index.jsonl"
+
+// compactRatio triggers a rewrite of the index once it holds this many
+// records per live entry.
+const compactRatio = 4
+
+// indexRecord is one line of the index log.
+type indexRecord struct {
+	CacheEntry
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// Prefetch loads the cache entries of keys with a single read of the index,
+// so later Get calls for them need no further I/O. Keys missing from the
+// index, such as those cached before the index existed, are read from their
+// task files. Prefetching is an optimisation: on failure Get simply falls
+// back to reading task files.
+func (m *Manager) Prefetch(keys []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pending []string
+	for _, key := range keys {
+		if _, ok := m.loaded[key]; !ok {
+			pending = append(pending, key)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	index, records, err := m.readIndex()
+	if err != nil {
+		return err
+	}
+
+	if m.loaded == nil {
+		m.loaded = make(map[string]*CacheEntry)
+	}
+	for _, key := range pending {
+		if entry, ok := index[key]; ok {
+			m.loaded[key] = entry
+			continue
+		}
+		entry, err := m.readEntry(key)
+		if err != nil {
+			continue
+		}
+		m.loaded[key] = entry
+	}
+
+	if records > compactRatio*len(index) && records > 100 {
+		return m.compactIndex(index)
+	}
+	return nil
+}
+
+// readIndex replays the index log, returning the latest entry per key (nil
+// for deleted keys) and the number of records read. Corrupt lines, such as a
+// write interrupted by a crash, are skipped.
+func (m *Manager) readIndex() (map[string]*CacheEntry, int, error) {
+	index := make(map[string]*CacheEntry)
+
+	data, err := os.ReadFile(filepath.Join(m.cacheDir, indexFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, 0, nil
+		}
+		return nil, 0, fmt.Errorf("failed to read cache index: %w", err)
+	}
+
+	records := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		var record indexRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.TaskKey == "" {
+			continue
+		}
+		records++
+		if record.Deleted {
+			index[record.TaskKey] = nil
+			continue
+		}
+		entry := record.CacheEntry
+		index[record.TaskKey] = &entry
+	}
+
+	return index, records, nil
+}
+
+// appendIndex records a write or deletion of one entry.
+func (m *Manager) appendIndex(record indexRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache index record: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(m.cacheDir, indexFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open cache index: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write cache index: %w", err)
+	}
+	return nil
+}
+
+// compactIndex rewrites the index with one record per live entry.
+func (m *Manager) compactIndex(index map[string]*CacheEntry) error {
+	var buf bytes.Buffer
+	for _, entry := range index {
+		if entry == nil {
+			continue
+		}
+		line, err := json.Marshal(indexRecord{CacheEntry: *entry})
+		if err != nil {
+			return fmt.Errorf("failed to marshal cache index record: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	tmp, err := os.CreateTemp(m.cacheDir, indexFile+".*")
+	if err != nil {
+		return fmt.Errorf("failed to compact cache index: %w", err)
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to compact cache index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to compact cache index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(m.cacheDir, indexFile)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to compact cache index: %w", err)
+	}
+	return nil
+}
+
+// Reindex rebuilds the index from the task files, such as after files were
+// copied into the cache directory by hand or by an import, and forgets
+// prefetched entries.
+func (m *Manager) Reindex() error {
+	entries, err := m.List(ListFilter{})
+	if err != nil {
+		return err
+	}
+	index := make(map[string]*CacheEntry, len(entries))
+	for i := range entries {
+		index[entries[i].TaskKey] = &entries[i]
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loaded = nil
+	if err := m.Initialize(); err != nil {
+		return err
+	}
+	return m.compactIndex(index)
+}
@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManagerPrefetchReadsIndexOnce(t *testing.T) {
+	manager, tempDir := createTestManager(t)
+
+	for _, key := range []string{"app:build", "lib:build", "lib:test"} {
+		if err := manager.Set(key, createTestTaskState(key, true), 0); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+	if err := manager.Delete("lib:test"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	reader := NewManager(tempDir)
+	if err := reader.Prefetch([]string{"app:build", "lib:build", "lib:test", "web:build"}); err != nil {
+		t.Fatalf("Prefetch() error = %v", err)
+	}
+
+	// Prefetched entries are served from memory without reading task files
+	for _, name := range []string{"appbuild.json", "libbuild.json"} {
+		if err := os.Remove(filepath.Join(tempDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for key, wantCached := range map[string]bool{"app:build": true, "lib:build": true, "lib:test": false, "web:build": false} {
+		state, err := reader.Get(key)
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", key, err)
+		}
+		if (state != nil) != wantCached {
+			t.Errorf("Get(%s) = %v, want cached %v", key, state, wantCached)
+		}
+	}
+}
+
+func TestManagerPrefetchFallsBackToTaskFiles(t *testing.T) {
+	manager, tempDir := createTestManager(t)
+
+	// A cache written before the index existed only has task files
+	entry := CacheEntry{TaskKey: "app:build", State: createTestTaskState("app:build", true)}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "appbuild.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.Prefetch([]string{"app:build"}); err != nil {
+		t.Fatalf("Prefetch() error = %v", err)
+	}
+	state, err := manager.Get("app:build")
+	if err != nil || state == nil || state.TaskKey != "app:build" {
+		t.Fatalf("Get() = %v, %v; want the legacy entry", state, err)
+	}
+}
+
+func TestManagerSetUpdatesPrefetchedEntries(t *testing.T) {
+	manager, _ := createTestManager(t)
+
+	if err := manager.Prefetch([]string{"app:build"}); err != nil {
+		t.Fatalf("Prefetch() error = %v", err)
+	}
+	if state, _ := manager.Get("app:build"); state != nil {
+		t.Fatalf("Get() = %v before Set", state)
+	}
+
+	if err := manager.Set("app:build", createTestTaskState("app:build", true), 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if state, _ := manager.Get("app:build"); state == nil {
+		t.Fatal("Get() should see the entry written after Prefetch")
+	}
+
+	if err := manager.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if state, _ := manager.Get("app:build"); state != nil {
+		t.Fatal("Get() should not return entries after Clear")
+	}
+}
+
+func TestManagerIndexCompaction(t *testing.T) {
+	manager, tempDir := createTestManager(t)
+
+	for i := 0; i < 150; i++ {
+		if err := manager.Set("app:build", createTestTaskState(fmt.Sprintf("run %d", i), true), 0); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+
+	if err := NewManager(tempDir).Prefetch([]string{"app:build"}); err != nil {
+		t.Fatalf("Prefetch() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, indexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 1 {
+		t.Fatalf("index has %d records after compaction, want 1", lines)
+	}
+	if !strings.Contains(string(data), "run 149") {
+		t.Fatal("compaction should keep the latest entry")
+	}
+
+	entries, err := manager.List(ListFilter{})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("List() = %d entries, %v; the index must not be listed", len(entries), err)
+	}
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// logDir holds the output of the runs that produced the cache entries below
+// the cache directory, one file per task, named like the task's cache file.
+const logDir = "logs"
+
+// maxLogSize is how much of each stream is kept. Longer output keeps its
+// end, where summaries and the last warnings are.
+const maxLogSize = 1 << 20
+
+// TaskLogs is the output of the run that produced a cache entry.
+type TaskLogs struct {
+	Stdout string `json:"stdout,omitempty"`
+	Stderr string `json:"stderr,omitempty"`
+	// Truncated is set when the start of a stream was dropped
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// SetLogs stores the output of the run that produced taskKey's cache entry.
+// Set removes it, so it is written after the entry.
+func (m *Manager) SetLogs(taskKey string, logs *TaskLogs) error {
+	stored := *logs
+	stored.Stdout, stored.Truncated = keepTail(logs.Stdout, logs.Truncated)
+	stored.Stderr, stored.Truncated = keepTail(logs.Stderr, stored.Truncated)
+
+	path := m.LogsPath(taskKey)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task logs: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write task logs: %w", err)
+	}
+	return nil
+}
+
+// GetLogs returns the output stored for taskKey's cache entry, or nil when
+// none was stored.
+func (m *Manager) GetLogs(taskKey string) (*TaskLogs, error) {
+	data, err := os.ReadFile(m.LogsPath(taskKey))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read task logs: %w", err)
+	}
+
+	var logs TaskLogs
+	if err := json.Unmarshal(data, &logs); err != nil {
+		return nil, fmt.Errorf("failed to parse task logs: %w", err)
+	}
+	return &logs, nil
+}
+
+// LogsPath returns where the output of taskKey's cached run is stored.
+func (m *Manager) LogsPath(taskKey string) string {
+	name := strings.TrimSuffix(filepath.Base(m.getCachePath(taskKey)), ".json")
+	return filepath.Join(m.cacheDir, logDir, name+".log.json")
+}
+
+// deleteLogs removes the stored output of taskKey, if any.
+func (m *Manager) deleteLogs(taskKey string) error {
+	err := os.Remove(m.LogsPath(taskKey))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove task logs: %w", err)
+	}
+	return nil
+}
+
+func keepTail(output string, truncated bool) (string, bool) {
+	if len(output) <= maxLogSize {
+		return output, truncated
+	}
+	output = output[len(output)-maxLogSize:]
+	// Start at a line, which also avoids splitting a character
+	if i := strings.IndexByte(output, '\n'); i >= 0 {
+		output = output[i+1:]
+	}
+	return output, true
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+
+	"doctrus/internal/deps"
+)
+
+func TestTaskLogs(t *testing.T) {
+	manager := NewManager(t.TempDir())
+	state := &deps.TaskState{TaskKey: "app:build", Success: true}
+	if err := manager.Set("app:build", state, 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := manager.SetLogs("app:build", &TaskLogs{Stdout: "built\n", Stderr: "warning\n"}); err != nil {
+		t.Fatalf("SetLogs() error = %v", err)
+	}
+
+	logs, err := manager.GetLogs("app:build")
+	if err != nil || logs == nil || logs.Stdout != "built\n" || logs.Stderr != "warning\n" || logs.Truncated {
+		t.Fatalf("GetLogs() = %+v, %v", logs, err)
+	}
+
+	// A new entry's run has not stored its output yet
+	if err := manager.Set("app:build", state, 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if logs, err := manager.GetLogs("app:build"); err != nil || logs != nil {
+		t.Fatalf("GetLogs() after Set = %+v, %v, want none", logs, err)
+	}
+
+	long := strings.Repeat("line of output\n", maxLogSize/10) + "summary\n"
+	if err := manager.SetLogs("app:build", &TaskLogs{Stdout: long}); err != nil {
+		t.Fatalf("SetLogs() error = %v", err)
+	}
+	logs, err = manager.GetLogs("app:build")
+	if err != nil {
+		t.Fatalf("GetLogs() error = %v", err)
+	}
+	if !logs.Truncated || len(logs.Stdout) > maxLogSize || !strings.HasSuffix(logs.Stdout, "summary\n") || !strings.HasPrefix(logs.Stdout, "line") {
+		t.Fatalf("long output not truncated to whole lines at its end: truncated %v, %d bytes", logs.Truncated, len(logs.Stdout))
+	}
+
+	if err := manager.Delete("app:build"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if logs, err := manager.GetLogs("app:build"); err != nil || logs != nil {
+		t.Fatalf("GetLogs() after Delete = %+v, %v, want none", logs, err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"doctrus/internal/deps"
@@ -13,6 +14,14 @@ import (
 
 type Manager struct {
 	cacheDir string
+	// maxSize caps the disk space of the cache for Evict and Prune, without
+	// a limit when zero
+	maxSize int64
+
+	// loaded holds entries read by Prefetch; a nil entry records a key
+	// known to have no cache
+	mu     sync.Mutex
+	loaded map[string]*CacheEntry
 }
 
 type CacheEntry struct {
@@ -53,14 +62,68 @@ func NewManager(cacheDir string) *Manager {
 	}
 }
 
+// Dir returns the cache directory.
+func (m *Manager) Dir() string {
+	return m.cacheDir
+}
+
 func (m *Manager) Initialize() error {
 	return os.MkdirAll(m.cacheDir, 0755)
 }
 
 func (m *Manager) Get(taskKey string) (*deps.TaskState, error) {
-	cachePath := m.getCachePath(taskKey)
+	m.mu.Lock()
+	entry, prefetched := m.loaded[taskKey]
+	m.mu.Unlock()
+
+	if !prefetched {
+		var err error
+		entry, err = m.readEntry(taskKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	if entry.TTL > 0 && time.Since(entry.CreatedAt) > entry.TTL {
+		m.Delete(taskKey)
+		return nil, nil
+	}
 
-	data, err := os.ReadFile(cachePath)
+	m.touch(taskKey)
+	return entry.State, nil
+}
+
+// touch records that taskKey's entry was just used in the modification time
+// of its file, which orders eviction by size. Failing to only makes the
+// entry look older.
+func (m *Manager) touch(taskKey string) {
+	now := time.Now()
+	_ = os.Chtimes(m.getCachePath(taskKey), now, now)
+}
+
+// SetMaxSize caps the disk space of the cache at size bytes, without a limit
+// when zero. Evict and Prune remove the least recently used entries beyond
+// it.
+func (m *Manager) SetMaxSize(size int64) {
+	m.maxSize = size
+}
+
+// Evict removes the least recently used entries while the cache is larger
+// than its maximum size, along with expired entries and stray files. It does
+// nothing without a maximum size.
+func (m *Manager) Evict() (PruneResult, error) {
+	if m.maxSize <= 0 {
+		return PruneResult{}, nil
+	}
+	return m.Prune(PruneOptions{})
+}
+
+// readEntry reads the task file of taskKey, returning nil when none exists.
+func (m *Manager) readEntry(taskKey string) (*CacheEntry, error) {
+	data, err := os.ReadFile(m.getCachePath(taskKey))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
@@ -72,13 +135,7 @@ func (m *Manager) Get(taskKey string) (*deps.TaskState, error) {
 	if err := json.Unmarshal(data, &entry); err != nil {
 		return nil, fmt.Errorf("failed to parse cache entry: %w", err)
 	}
-
-	if entry.TTL > 0 && time.Since(entry.CreatedAt) > entry.TTL {
-		m.Delete(taskKey)
-		return nil, nil
-	}
-
-	return entry.State, nil
+	return &entry, nil
 }
 
 func (m *Manager) Set(taskKey string, state *deps.TaskState, ttl time.Duration) error {
@@ -102,17 +159,48 @@ func (m *Manager) Set(taskKey string, state *deps.TaskState, ttl time.Duration)
 	if err := os.WriteFile(cachePath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write cache file: %w", err)
 	}
+	// An attestation and logs of earlier outputs no longer describe this
+	// entry
+	if err := m.deleteAttestation(taskKey); err != nil {
+		return err
+	}
+	if err := m.deleteLogs(taskKey); err != nil {
+		return err
+	}
 
-	return nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.loaded != nil {
+		m.loaded[taskKey] = &entry
+	}
+	return m.appendIndex(indexRecord{CacheEntry: entry})
 }
 
 func (m *Manager) Delete(taskKey string) error {
 	cachePath := m.getCachePath(taskKey)
 	err := os.Remove(cachePath)
 	if os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := m.deleteAttestation(taskKey); err != nil {
+		return err
+	}
+	if err := m.deleteLogs(taskKey); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.loaded != nil {
+		m.loaded[taskKey] = nil
+	}
+	if _, statErr := os.Stat(filepath.Join(m.cacheDir, indexFile)); statErr != nil {
 		return nil
 	}
-	return err
+	return m.appendIndex(indexRecord{CacheEntry: CacheEntry{TaskKey: taskKey}, Deleted: true})
 }
 
 func (m *Manager) Clear() error {
@@ -135,11 +223,45 @@ func (m *Manager) Clear() error {
 			return fmt.Errorf("failed to remove cache file %s: %w", filePath, err)
 		}
 	}
+	if err := os.RemoveAll(filepath.Join(m.cacheDir, attestationDir)); err != nil {
+		return fmt.Errorf("failed to remove attestations: %w", err)
+	}
+	if err := os.RemoveAll(filepath.Join(m.cacheDir, logDir)); err != nil {
+		return fmt.Errorf("failed to remove task logs: %w", err)
+	}
+
+	m.mu.Lock()
+	m.loaded = nil
+	m.mu.Unlock()
 
 	return nil
 }
 
-func (m *Manager) List() ([]CacheEntry, error) {
+// ListFilter selects cache entries: those of tasks in Workspace, created
+// longer than OlderThan ago, and recording a failed run with FailedOnly set.
+// Zero values match every entry.
+type ListFilter struct {
+	Workspace  string
+	OlderThan  time.Duration
+	FailedOnly bool
+}
+
+// Match reports whether entry is selected by the filter.
+func (f ListFilter) Match(entry CacheEntry) bool {
+	if f.Workspace != "" && !strings.HasPrefix(entry.TaskKey, f.Workspace+":") {
+		return false
+	}
+	if f.OlderThan > 0 && time.Since(entry.CreatedAt) <= f.OlderThan {
+		return false
+	}
+	if f.FailedOnly && (entry.State == nil || entry.State.Success) {
+		return false
+	}
+	return true
+}
+
+// List returns the cache entries matching filter.
+func (m *Manager) List(filter ListFilter) ([]CacheEntry, error) {
 	if _, err := os.Stat(m.cacheDir); os.IsNotExist(err) {
 		return nil, nil
 	}
@@ -151,7 +273,7 @@ func (m *Manager) List() ([]CacheEntry, error) {
 
 	var cacheEntries []CacheEntry
 	for _, entry := range entries {
-		if entry.IsDir() {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), indexFile) {
 			continue
 		}
 
@@ -165,6 +287,9 @@ func (m *Manager) List() ([]CacheEntry, error) {
 		if err := json.Unmarshal(data, &cacheEntry); err != nil {
 			continue
 		}
+		if !filter.Match(cacheEntry) {
+			continue
+		}
 
 		cacheEntries = append(cacheEntries, cacheEntry)
 	}
@@ -173,7 +298,7 @@ func (m *Manager) List() ([]CacheEntry, error) {
 }
 
 func (m *Manager) GetStats() (map[string]interface{}, error) {
-	entries, err := m.List()
+	entries, err := m.List(ListFilter{})
 	if err != nil {
 		return nil, err
 	}
@@ -199,7 +324,7 @@ func (m *Manager) GetStats() (map[string]interface{}, error) {
 }
 
 func (m *Manager) CleanExpired() error {
-	entries, err := m.List()
+	entries, err := m.List(ListFilter{})
 	if err != nil {
 		return err
 	}
@@ -224,16 +349,14 @@ func (m *Manager) getCachePath(taskKey string) string {
 }
 
 func (m *Manager) InvalidateWorkspace(workspaceName string) error {
-	entries, err := m.List()
+	entries, err := m.List(ListFilter{Workspace: workspaceName})
 	if err != nil {
 		return err
 	}
 
 	for _, entry := range entries {
-		if strings.HasPrefix(entry.TaskKey, workspaceName+":") {
-			if err := m.Delete(entry.TaskKey); err != nil {
-				return fmt.Errorf("failed to invalidate cache for %s: %w", entry.TaskKey, err)
-			}
+		if err := m.Delete(entry.TaskKey); err != nil {
+			return fmt.Errorf("failed to invalidate cache for %s: %w", entry.TaskKey, err)
 		}
 	}
 
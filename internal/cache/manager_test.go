@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -237,7 +239,7 @@ func TestManagerList(t *testing.T) {
 		}
 	}
 
-	entries, err := manager.List()
+	entries, err := manager.List(ListFilter{})
 	if err != nil {
 		t.Fatalf("List() error = %v", err)
 	}
@@ -258,6 +260,57 @@ func TestManagerList(t *testing.T) {
 	}
 }
 
+func TestManagerListFilter(t *testing.T) {
+	day := 24 * time.Hour
+	entries := []CacheEntry{
+		{TaskKey: "frontend:build", State: &deps.TaskState{Success: true}, CreatedAt: time.Now().Add(-10 * day)},
+		{TaskKey: "frontend:test", State: &deps.TaskState{Success: false}, CreatedAt: time.Now().Add(-day)},
+		{TaskKey: "frontend-legacy:build", State: &deps.TaskState{Success: false}, CreatedAt: time.Now().Add(-10 * day)},
+		{TaskKey: "backend:test", State: &deps.TaskState{Success: false}, CreatedAt: time.Now().Add(-10 * day)},
+	}
+	manager := NewManager(t.TempDir())
+	for _, entry := range entries {
+		if err := manager.Set(entry.TaskKey, entry.State, 0); err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(manager.getCachePath(entry.TaskKey), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter ListFilter
+		want   []string
+	}{
+		{"none", ListFilter{}, []string{"backend:test", "frontend-legacy:build", "frontend:build", "frontend:test"}},
+		{"workspace", ListFilter{Workspace: "frontend"}, []string{"frontend:build", "frontend:test"}},
+		{"older than", ListFilter{OlderThan: 7 * day}, []string{"backend:test", "frontend-legacy:build", "frontend:build"}},
+		{"failed only", ListFilter{FailedOnly: true}, []string{"backend:test", "frontend-legacy:build", "frontend:test"}},
+		{"combined", ListFilter{Workspace: "frontend", OlderThan: 7 * day, FailedOnly: true}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listed, err := manager.List(tt.filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var got []string
+			for _, entry := range listed {
+				got = append(got, entry.TaskKey)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("List() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestManagerGetStats(t *testing.T) {
 	tempDir := t.TempDir()
 	manager := NewManager(tempDir)
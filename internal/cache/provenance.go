@@ -0,0 +1,248 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"doctrus/internal/deps"
+)
+
+// Types and URIs of the attestations written for cached outputs
+const (
+	// StatementType is the in-toto statement format version
+	StatementType = "https://in-toto.io/Statement/v1"
+	// ProvenanceType is the SLSA provenance predicate format version
+	ProvenanceType = "https://slsa.dev/provenance/v1"
+	// BuildType identifies a doctrus task run as the build
+	BuildType = "https://github.com/SebastiaanWouters/doctrus/task@v1"
+	// BuilderID identifies doctrus as the builder
+	BuilderID = "https://github.com/SebastiaanWouters/doctrus"
+)
+
+// attestationDir holds the attestations below the cache directory, one per
+// task, named like the task's cache file.
+const attestationDir = "attestations"
+
+// Statement is an in-toto attestation whose subjects are a task's outputs
+// and whose predicate describes how they were produced.
+type Statement struct {
+	Type          string     `json:"_type"`
+	Subject       []Subject  `json:"subject"`
+	PredicateType string     `json:"predicateType"`
+	Predicate     Provenance `json:"predicate"`
+}
+
+// Subject is one attested output file, its path relative to the project.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Provenance is a SLSA provenance predicate.
+type Provenance struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition records what the task ran and what it read. Resolved
+// dependencies list the source commit, the container image and the input
+// files.
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   BuildParameters      `json:"externalParameters"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+// BuildParameters are the task settings that determine its outputs.
+// InputsDigest is a sha256 digest over every input path and hash, see
+// InputsDigest.
+type BuildParameters struct {
+	Workspace    string   `json:"workspace"`
+	Task         string   `json:"task"`
+	Command      []string `json:"command"`
+	Executor     string   `json:"executor"`
+	Image        string   `json:"image,omitempty"`
+	InputsDigest string   `json:"inputsDigest"`
+}
+
+// ResourceDescriptor names an artifact the task depended on.
+type ResourceDescriptor struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest"`
+}
+
+// RunDetails records who ran the task and when.
+type RunDetails struct {
+	Builder  Builder     `json:"builder"`
+	Metadata RunMetadata `json:"metadata"`
+}
+
+// Builder identifies the doctrus build that ran the task.
+type Builder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+// RunMetadata holds the start and end of the run.
+type RunMetadata struct {
+	StartedOn  time.Time `json:"startedOn"`
+	FinishedOn time.Time `json:"finishedOn"`
+}
+
+// BuildInfo describes a task run beyond what its TaskState records. Image
+// and ImageDigest are empty for tasks run on the host, GitCommit outside a
+// git repository.
+type BuildInfo struct {
+	Workspace   string
+	Task        string
+	Command     []string
+	Executor    string
+	Image       string
+	ImageDigest string
+	GitCommit   string
+	Version     string
+	StartedOn   time.Time
+	FinishedOn  time.Time
+}
+
+// NewStatement builds the provenance attestation of a task run whose
+// resulting state is state.
+func NewStatement(info BuildInfo, state *deps.TaskState) *Statement {
+	statement := &Statement{
+		Type:          StatementType,
+		Subject:       []Subject{},
+		PredicateType: ProvenanceType,
+		Predicate: Provenance{
+			BuildDefinition: BuildDefinition{
+				BuildType: BuildType,
+				ExternalParameters: BuildParameters{
+					Workspace:    info.Workspace,
+					Task:         info.Task,
+					Command:      info.Command,
+					Executor:     info.Executor,
+					Image:        info.Image,
+					InputsDigest: "sha256:" + InputsDigest(state.InputHashes),
+				},
+			},
+			RunDetails: RunDetails{
+				Builder: Builder{ID: BuilderID},
+				Metadata: RunMetadata{
+					StartedOn:  info.StartedOn.UTC(),
+					FinishedOn: info.FinishedOn.UTC(),
+				},
+			},
+		},
+	}
+	if info.Version != "" {
+		statement.Predicate.RunDetails.Builder.Version = map[string]string{"doctrus": info.Version}
+	}
+
+	for _, output := range state.Outputs {
+		statement.Subject = append(statement.Subject, Subject{
+			Name:   filepath.ToSlash(output.Path),
+			Digest: map[string]string{"sha256": output.Hash},
+		})
+	}
+
+	definition := &statement.Predicate.BuildDefinition
+	if info.GitCommit != "" {
+		definition.ResolvedDependencies = append(definition.ResolvedDependencies, ResourceDescriptor{
+			Name:   "source",
+			Digest: map[string]string{"gitCommit": info.GitCommit},
+		})
+	}
+	if info.ImageDigest != "" {
+		algorithm, digest, ok := strings.Cut(info.ImageDigest, ":")
+		if !ok {
+			algorithm, digest = "sha256", info.ImageDigest
+		}
+		definition.ResolvedDependencies = append(definition.ResolvedDependencies, ResourceDescriptor{
+			Name:   "image",
+			URI:    "docker-image://" + info.Image,
+			Digest: map[string]string{algorithm: digest},
+		})
+	}
+	for _, input := range state.InputHashes {
+		definition.ResolvedDependencies = append(definition.ResolvedDependencies, ResourceDescriptor{
+			Name:   filepath.ToSlash(input.Path),
+			Digest: map[string]string{"sha256": input.Hash},
+		})
+	}
+
+	return statement
+}
+
+// InputsDigest returns the hex sha256 digest of the input files, computed
+// over their sorted paths and hashes so it changes with any input.
+func InputsDigest(inputs []deps.FileInfo) string {
+	lines := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		lines = append(lines, filepath.ToSlash(input.Path)+"\x00"+input.Hash+"\n")
+	}
+	sort.Strings(lines)
+
+	hash := sha256.New()
+	for _, line := range lines {
+		hash.Write([]byte(line))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// SetAttestation stores the attestation of taskKey's cached outputs,
+// replacing any earlier one.
+func (m *Manager) SetAttestation(taskKey string, statement *Statement) error {
+	path := m.AttestationPath(taskKey)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create attestation directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(statement, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal attestation: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write attestation: %w", err)
+	}
+	return nil
+}
+
+// GetAttestation returns the attestation of taskKey's cached outputs, or nil
+// when none was recorded.
+func (m *Manager) GetAttestation(taskKey string) (*Statement, error) {
+	data, err := os.ReadFile(m.AttestationPath(taskKey))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read attestation: %w", err)
+	}
+
+	var statement Statement
+	if err := json.Unmarshal(data, &statement); err != nil {
+		return nil, fmt.Errorf("failed to parse attestation: %w", err)
+	}
+	return &statement, nil
+}
+
+// AttestationPath returns where the attestation of taskKey is stored.
+func (m *Manager) AttestationPath(taskKey string) string {
+	name := strings.TrimSuffix(filepath.Base(m.getCachePath(taskKey)), ".json")
+	return filepath.Join(m.cacheDir, attestationDir, name+".intoto.json")
+}
+
+// deleteAttestation removes the attestation of taskKey, if any.
+func (m *Manager) deleteAttestation(taskKey string) error {
+	err := os.Remove(m.AttestationPath(taskKey))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove attestation: %w", err)
+	}
+	return nil
+}
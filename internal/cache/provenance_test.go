@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"doctrus/internal/deps"
+)
+
+func TestNewStatement(t *testing.T) {
+	state := &deps.TaskState{
+		TaskKey:     "app:build",
+		InputHashes: []deps.FileInfo{{Path: "app/main.go", Hash: "aaa"}, {Path: "app/go.mod", Hash: "bbb"}},
+		Outputs:     []deps.FileInfo{{Path: "app/bin/app", Hash: "ccc"}},
+	}
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	info := BuildInfo{
+		Workspace:   "app",
+		Task:        "build",
+		Command:     []string{"go", "build", "-o", "bin/app"},
+		Executor:    "docker-run",
+		Image:       "golang:1.24",
+		ImageDigest: "sha256:ddd",
+		GitCommit:   "0123abcd",
+		Version:     "v1.2.0",
+		StartedOn:   started,
+		FinishedOn:  started.Add(time.Second),
+	}
+
+	statement := NewStatement(info, state)
+
+	if statement.Type != StatementType || statement.PredicateType != ProvenanceType {
+		t.Fatalf("types = %q, %q", statement.Type, statement.PredicateType)
+	}
+	wantSubject := []Subject{{Name: "app/bin/app", Digest: map[string]string{"sha256": "ccc"}}}
+	if !reflect.DeepEqual(statement.Subject, wantSubject) {
+		t.Fatalf("Subject = %+v, want %+v", statement.Subject, wantSubject)
+	}
+
+	params := statement.Predicate.BuildDefinition.ExternalParameters
+	if params.InputsDigest != "sha256:"+InputsDigest(state.InputHashes) || params.Image != "golang:1.24" || !reflect.DeepEqual(params.Command, info.Command) {
+		t.Fatalf("ExternalParameters = %+v", params)
+	}
+
+	wantDeps := []ResourceDescriptor{
+		{Name: "source", Digest: map[string]string{"gitCommit": "0123abcd"}},
+		{Name: "image", URI: "docker-image://golang:1.24", Digest: map[string]string{"sha256": "ddd"}},
+		{Name: "app/main.go", Digest: map[string]string{"sha256": "aaa"}},
+		{Name: "app/go.mod", Digest: map[string]string{"sha256": "bbb"}},
+	}
+	if got := statement.Predicate.BuildDefinition.ResolvedDependencies; !reflect.DeepEqual(got, wantDeps) {
+		t.Fatalf("ResolvedDependencies = %+v, want %+v", got, wantDeps)
+	}
+	if got := statement.Predicate.RunDetails.Builder.Version["doctrus"]; got != "v1.2.0" {
+		t.Fatalf("builder version = %q", got)
+	}
+}
+
+func TestInputsDigest(t *testing.T) {
+	a := []deps.FileInfo{{Path: "a", Hash: "1"}, {Path: "b", Hash: "2"}}
+	b := []deps.FileInfo{{Path: "b", Hash: "2"}, {Path: "a", Hash: "1"}}
+	changed := []deps.FileInfo{{Path: "a", Hash: "1"}, {Path: "b", Hash: "3"}}
+
+	if InputsDigest(a) != InputsDigest(b) {
+		t.Fatal("InputsDigest() should not depend on input order")
+	}
+	if InputsDigest(a) == InputsDigest(changed) {
+		t.Fatal("InputsDigest() should change with an input hash")
+	}
+}
+
+func TestManagerAttestations(t *testing.T) {
+	manager, tempDir := createTestManager(t)
+	state := createTestTaskState("app:build", true)
+
+	if err := manager.Set("app:build", state, 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	statement := NewStatement(BuildInfo{Workspace: "app", Task: "build"}, state)
+	if err := manager.SetAttestation("app:build", statement); err != nil {
+		t.Fatalf("SetAttestation() error = %v", err)
+	}
+
+	got, err := manager.GetAttestation("app:build")
+	if err != nil || got == nil || got.Subject[0].Name != "output.txt" {
+		t.Fatalf("GetAttestation() = %+v, %v", got, err)
+	}
+	if want := filepath.Join(tempDir, "attestations", "appbuild.intoto.json"); manager.AttestationPath("app:build") != want {
+		t.Fatalf("AttestationPath() = %s, want %s", manager.AttestationPath("app:build"), want)
+	}
+
+	// Attestation files are not cache entries
+	entries, err := manager.List(ListFilter{})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("List() = %d entries, %v; want 1", len(entries), err)
+	}
+
+	// Caching new outputs drops the attestation of the old ones
+	if err := manager.Set("app:build", state, 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, _ := manager.GetAttestation("app:build"); got != nil {
+		t.Fatal("Set() should remove the previous attestation")
+	}
+
+	if err := manager.SetAttestation("app:build", statement); err != nil {
+		t.Fatalf("SetAttestation() error = %v", err)
+	}
+	if err := manager.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "attestations")); !os.IsNotExist(err) {
+		t.Fatalf("Clear() should remove attestations, stat error = %v", err)
+	}
+}
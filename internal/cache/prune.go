@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PruneOptions selects the cache entries Prune removes. Entries whose TTL
+// expired are always removed. MaxAge removes entries created longer ago,
+// Keep removes entries for which it returns false, such as tasks no longer
+// in the configuration, and MaxSize evicts the least recently used
+// remaining entries until the cache fits; the manager's own maximum size
+// applies when it is smaller. Zero values leave a limit off. With DryRun set
+// nothing is deleted.
+type PruneOptions struct {
+	MaxAge  time.Duration
+	MaxSize int64
+	Keep    func(taskKey string) bool
+	DryRun  bool
+}
+
+// PruneResult lists the task keys Prune removed, by reason, along with the
+// number of stray files removed and the disk space freed.
+type PruneResult struct {
+	Expired  []string
+	Old      []string
+	Orphaned []string
+	Evicted  []string
+	Files    int
+	Freed    int64
+}
+
+// Entries returns the number of entries removed.
+func (r PruneResult) Entries() int {
+	return len(r.Expired) + len(r.Old) + len(r.Orphaned) + len(r.Evicted)
+}
+
+// prunable is a cache entry along with the disk space of its files and
+// when it was last used.
+type prunable struct {
+	entry    CacheEntry
+	size     int64
+	accessed time.Time
+}
+
+// Prune removes cache entries according to opts, along with files that
+// belong to no entry: unreadable entry files and attestations and logs of
+// deleted entries. The index is compacted afterwards.
+func (m *Manager) Prune(opts PruneOptions) (PruneResult, error) {
+	var result PruneResult
+	if _, err := os.Stat(m.cacheDir); os.IsNotExist(err) {
+		return result, nil
+	}
+
+	files, err := os.ReadDir(m.cacheDir)
+	if err != nil {
+		return result, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var live []prunable
+	names := make(map[string]bool)
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), indexFile) {
+			continue
+		}
+		path := filepath.Join(m.cacheDir, file.Name())
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+
+		var entry CacheEntry
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = json.Unmarshal(data, &entry)
+		}
+		if err != nil || entry.TaskKey == "" {
+			if err := m.removeFile(path, opts.DryRun); err != nil {
+				return result, err
+			}
+			result.Files++
+			result.Freed += info.Size()
+			continue
+		}
+
+		size := info.Size()
+		for _, sidecar := range []string{m.AttestationPath(entry.TaskKey), m.LogsPath(entry.TaskKey)} {
+			if info, err := os.Stat(sidecar); err == nil {
+				size += info.Size()
+			}
+		}
+		live = append(live, prunable{entry: entry, size: size, accessed: info.ModTime()})
+		names[strings.TrimSuffix(file.Name(), ".json")] = true
+	}
+
+	// Least recently used first, so eviction by size drops those
+	sort.Slice(live, func(i, j int) bool {
+		if !live[i].accessed.Equal(live[j].accessed) {
+			return live[i].accessed.Before(live[j].accessed)
+		}
+		return live[i].entry.CreatedAt.Before(live[j].entry.CreatedAt)
+	})
+
+	var kept []prunable
+	var total int64
+	for _, candidate := range live {
+		entry := candidate.entry
+		var reason *[]string
+		switch {
+		case entry.TTL > 0 && time.Since(entry.CreatedAt) > entry.TTL:
+			reason = &result.Expired
+		case opts.MaxAge > 0 && time.Since(entry.CreatedAt) > opts.MaxAge:
+			reason = &result.Old
+		case opts.Keep != nil && !opts.Keep(entry.TaskKey):
+			reason = &result.Orphaned
+		}
+		if reason == nil {
+			kept = append(kept, candidate)
+			total += candidate.size
+			continue
+		}
+		if err := m.pruneEntry(candidate, opts.DryRun, &result); err != nil {
+			return result, err
+		}
+		*reason = append(*reason, entry.TaskKey)
+	}
+
+	maxSize := opts.MaxSize
+	if m.maxSize > 0 && (maxSize == 0 || m.maxSize < maxSize) {
+		maxSize = m.maxSize
+	}
+	for i := 0; maxSize > 0 && total > maxSize && i < len(kept); i++ {
+		if err := m.pruneEntry(kept[i], opts.DryRun, &result); err != nil {
+			return result, err
+		}
+		result.Evicted = append(result.Evicted, kept[i].entry.TaskKey)
+		total -= kept[i].size
+	}
+
+	if err := m.pruneSidecars(attestationDir, ".intoto.json", names, opts.DryRun, &result); err != nil {
+		return result, err
+	}
+	if err := m.pruneSidecars(logDir, ".log.json", names, opts.DryRun, &result); err != nil {
+		return result, err
+	}
+	if opts.DryRun || result.Entries() == 0 {
+		return result, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := os.Stat(filepath.Join(m.cacheDir, indexFile)); err != nil {
+		return result, nil
+	}
+	index, _, err := m.readIndex()
+	if err != nil {
+		return result, err
+	}
+	return result, m.compactIndex(index)
+}
+
+// pruneEntry deletes one entry unless dryRun is set, counting the space it
+// frees.
+func (m *Manager) pruneEntry(candidate prunable, dryRun bool, result *PruneResult) error {
+	if !dryRun {
+		if err := m.Delete(candidate.entry.TaskKey); err != nil {
+			return fmt.Errorf("failed to prune cache entry %s: %w", candidate.entry.TaskKey, err)
+		}
+	}
+	result.Freed += candidate.size
+	return nil
+}
+
+// pruneSidecars removes the files kept for entries in the subdirectory
+// dirName, attestations or logs, whose entry file, named in names, no longer
+// exists.
+func (m *Manager) pruneSidecars(dirName, suffix string, names map[string]bool, dryRun bool, result *PruneResult) error {
+	dir := filepath.Join(m.cacheDir, dirName)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", dirName, err)
+	}
+
+	for _, file := range files {
+		name, ok := strings.CutSuffix(file.Name(), suffix)
+		if file.IsDir() || (ok && names[name]) {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		if err := m.removeFile(filepath.Join(dir, file.Name()), dryRun); err != nil {
+			return err
+		}
+		result.Files++
+		result.Freed += info.Size()
+	}
+	return nil
+}
+
+// removeFile removes a stray file from the cache directory unless dryRun is
+// set.
+func (m *Manager) removeFile(path string, dryRun bool) error {
+	if dryRun {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
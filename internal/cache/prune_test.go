@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"doctrus/internal/deps"
+)
+
+// writeEntry stores a cache entry created, and last used, age ago.
+func writeEntry(t *testing.T, manager *Manager, taskKey string, age, ttl time.Duration) {
+	t.Helper()
+	if err := manager.Set(taskKey, createTestTaskState(taskKey, true), ttl); err != nil {
+		t.Fatalf("Set(%s) error: %v", taskKey, err)
+	}
+	entry := CacheEntry{TaskKey: taskKey, State: createTestTaskState(taskKey, true), CreatedAt: time.Now().Add(-age), TTL: ttl}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(manager.getCachePath(taskKey), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(manager.getCachePath(taskKey), entry.CreatedAt, entry.CreatedAt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManagerPrune(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		name    string
+		opts    PruneOptions
+		pruned  map[string][]string
+		remains []string
+	}{
+		{
+			name:    "expired only",
+			pruned:  map[string][]string{"expired": {"web:test"}},
+			remains: []string{"api:build", "old:lint", "web:build"},
+		},
+		{
+			name:    "age",
+			opts:    PruneOptions{MaxAge: 5 * day},
+			pruned:  map[string][]string{"expired": {"web:test"}, "old": {"old:lint"}},
+			remains: []string{"api:build", "web:build"},
+		},
+		{
+			name:    "orphans",
+			opts:    PruneOptions{Keep: func(key string) bool { return !strings.HasPrefix(key, "old:") }},
+			pruned:  map[string][]string{"expired": {"web:test"}, "orphaned": {"old:lint"}},
+			remains: []string{"api:build", "web:build"},
+		},
+		{
+			name:    "size evicts oldest",
+			opts:    PruneOptions{MaxSize: 1},
+			pruned:  map[string][]string{"expired": {"web:test"}, "evicted": {"old:lint", "api:build", "web:build"}},
+			remains: nil,
+		},
+		{
+			name:    "dry run",
+			opts:    PruneOptions{MaxAge: 5 * day, DryRun: true},
+			pruned:  map[string][]string{"expired": {"web:test"}, "old": {"old:lint"}},
+			remains: []string{"api:build", "old:lint", "web:build", "web:test"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, _ := createTestManager(t)
+			writeEntry(t, manager, "old:lint", 10*day, 0)
+			writeEntry(t, manager, "api:build", 2*day, 0)
+			writeEntry(t, manager, "web:build", day, 0)
+			writeEntry(t, manager, "web:test", 2*day, time.Hour)
+
+			result, err := manager.Prune(tt.opts)
+			if err != nil {
+				t.Fatalf("Prune() error = %v", err)
+			}
+			got := map[string][]string{"expired": result.Expired, "old": result.Old, "orphaned": result.Orphaned, "evicted": result.Evicted}
+			for reason, want := range tt.pruned {
+				if strings.Join(got[reason], ",") != strings.Join(want, ",") {
+					t.Errorf("%s = %v, want %v", reason, got[reason], want)
+				}
+			}
+			if result.Entries() > 0 && result.Freed <= 0 {
+				t.Errorf("Freed = %d, want the size of the pruned entries", result.Freed)
+			}
+
+			entries, err := manager.List(ListFilter{})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var remains []string
+			for _, entry := range entries {
+				remains = append(remains, entry.TaskKey)
+			}
+			sort.Strings(remains)
+			if strings.Join(remains, ",") != strings.Join(tt.remains, ",") {
+				t.Fatalf("remaining entries = %v, want %v", remains, tt.remains)
+			}
+		})
+	}
+}
+
+func TestManagerPruneStrayFiles(t *testing.T) {
+	manager, dir := createTestManager(t)
+	if err := manager.Set("web:build", createTestTaskState("web:build", true), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.SetAttestation("web:build", NewStatement(BuildInfo{}, &deps.TaskState{TaskKey: "web:build"})); err != nil {
+		t.Fatal(err)
+	}
+	stray := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(stray, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	orphan := manager.AttestationPath("gone:build")
+	if err := os.WriteFile(orphan, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := manager.Prune(PruneOptions{})
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if result.Files != 2 || result.Entries() != 0 {
+		t.Fatalf("Prune() = %+v, want 2 stray files and no entries", result)
+	}
+	for _, path := range []string{stray, orphan} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s still exists", path)
+		}
+	}
+	if _, err := manager.GetAttestation("web:build"); err != nil {
+		t.Errorf("attestation of a live entry was removed: %v", err)
+	}
+}
+
+func TestManagerEvictLeastRecentlyUsed(t *testing.T) {
+	day := 24 * time.Hour
+	manager, dir := createTestManager(t)
+	if result, err := manager.Evict(); err != nil || result.Entries() != 0 {
+		t.Fatalf("Evict() without a maximum size = %+v, %v, want nothing", result, err)
+	}
+
+	writeEntry(t, manager, "old:lint", 10*day, 0)
+	writeEntry(t, manager, "api:build", 2*day, 0)
+	writeEntry(t, manager, "web:build", day, 0)
+	// Using the oldest entry makes it the most recently used
+	if state, err := manager.Get("old:lint"); err != nil || state == nil {
+		t.Fatalf("Get() = %v, %v", state, err)
+	}
+
+	var total int64
+	files, _ := os.ReadDir(dir)
+	for _, file := range files {
+		if info, err := file.Info(); err == nil && strings.HasSuffix(file.Name(), ".json") {
+			total += info.Size()
+		}
+	}
+	manager.SetMaxSize(total - 1)
+	result, err := manager.Evict()
+	if err != nil {
+		t.Fatalf("Evict() error = %v", err)
+	}
+	if strings.Join(result.Evicted, ",") != "api:build" {
+		t.Fatalf("Evicted = %v, want the least recently used entry", result.Evicted)
+	}
+
+	// A smaller limit passed to Prune wins over the manager's
+	result, err = manager.Prune(PruneOptions{MaxSize: 1})
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if strings.Join(result.Evicted, ",") != "web:build,old:lint" {
+		t.Fatalf("Evicted = %v, want web:build,old:lint", result.Evicted)
+	}
+}
@@ -0,0 +1,266 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/s3"
+)
+
+// Remote is a cache shared between machines. Its artifacts are archives of
+// a task's outputs (see WriteArchive) stored under the task's fingerprint.
+type Remote interface {
+	// Fetch returns the artifact stored under hash, or nil when there is
+	// none. The caller closes it.
+	Fetch(ctx context.Context, hash string) (io.ReadCloser, error)
+	// Store uploads an artifact of size bytes under hash. duration is how
+	// long the task took, which servers use to report time saved.
+	Store(ctx context.Context, hash string, artifact io.Reader, size int64, duration time.Duration) error
+}
+
+// Defaults of the Turborepo and Nx clients, matching the tools themselves
+const (
+	turborepoDefaultURL = "https://vercel.com/api"
+	turborepoTokenEnv   = "TURBO_TOKEN"
+	nxTokenEnv          = "NX_SELF_HOSTED_REMOTE_CACHE_ACCESS_TOKEN"
+)
+
+// NewRemote returns the client of the remote cache configured by remote.
+// Settings missing from the config are read from the environment variables
+// of the matching tool: TURBO_API, TURBO_TEAMID or TURBO_TEAM and
+// TURBO_TOKEN for turborepo; NX_SELF_HOSTED_REMOTE_CACHE_SERVER and
+// NX_SELF_HOSTED_REMOTE_CACHE_ACCESS_TOKEN for nx; the AWS variables read by
+// s3.NewClientFromEnv for s3.
+func NewRemote(remote *config.RemoteCache) (Remote, error) {
+	switch remote.Type {
+	case config.RemoteTurborepo:
+		baseURL := firstNonEmpty(remote.URL, os.Getenv("TURBO_API"), turborepoDefaultURL)
+		team := firstNonEmpty(remote.Team, os.Getenv("TURBO_TEAMID"), os.Getenv("TURBO_TEAM"))
+		token := os.Getenv(firstNonEmpty(remote.TokenEnv, turborepoTokenEnv))
+		return NewTurborepoRemote(baseURL, team, token), nil
+
+	case config.RemoteNx:
+		baseURL := firstNonEmpty(remote.URL, os.Getenv("NX_SELF_HOSTED_REMOTE_CACHE_SERVER"))
+		if baseURL == "" {
+			return nil, fmt.Errorf("cache.remote: url is required for nx (or set NX_SELF_HOSTED_REMOTE_CACHE_SERVER)")
+		}
+		token := os.Getenv(firstNonEmpty(remote.TokenEnv, nxTokenEnv))
+		return NewNxRemote(baseURL, token), nil
+
+	case config.RemoteS3:
+		bucket, prefix, err := s3.ParseLocation(remote.URL)
+		if err != nil {
+			return nil, fmt.Errorf("cache.remote: %w", err)
+		}
+		client := s3.NewClientFromEnv()
+		client.Region = firstNonEmpty(remote.Region, client.Region)
+		client.Endpoint = firstNonEmpty(remote.Endpoint, client.Endpoint)
+		return NewS3Remote(client, bucket, prefix), nil
+	}
+	return nil, fmt.Errorf("cache.remote: unknown type %q", remote.Type)
+}
+
+// HTTPRemote is a client of an HTTP remote cache that stores artifacts with
+// PUT and serves them with GET on one URL per hash, authenticated with a
+// bearer token.
+type HTTPRemote struct {
+	client   *http.Client
+	token    string
+	endpoint func(hash string) string
+	headers  func(req *http.Request, duration time.Duration)
+}
+
+// NewTurborepoRemote returns a client of the Turborepo remote cache API at
+// baseURL, such as Vercel or a self-hosted turborepo-remote-cache server.
+// team is a team ID (team_...) or slug, and may be empty for servers that
+// don't use teams.
+func NewTurborepoRemote(baseURL, team, token string) *HTTPRemote {
+	query := url.Values{}
+	if strings.HasPrefix(team, "team_") {
+		query.Set("teamId", team)
+	} else if team != "" {
+		query.Set("slug", team)
+	}
+	base := strings.TrimSuffix(baseURL, "/") + "/v8/artifacts/"
+
+	return &HTTPRemote{
+		client: http.DefaultClient,
+		token:  token,
+		endpoint: func(hash string) string {
+			if len(query) == 0 {
+				return base + url.PathEscape(hash)
+			}
+			return base + url.PathEscape(hash) + "?" + query.Encode()
+		},
+		headers: func(req *http.Request, duration time.Duration) {
+			req.Header.Set("x-artifact-duration", strconv.FormatInt(duration.Milliseconds(), 10))
+		},
+	}
+}
+
+// NewNxRemote returns a client of an Nx self-hosted remote cache server at
+// baseURL.
+func NewNxRemote(baseURL, token string) *HTTPRemote {
+	base := strings.TrimSuffix(baseURL, "/") + "/v1/cache/"
+
+	return &HTTPRemote{
+		client:   http.DefaultClient,
+		token:    token,
+		endpoint: func(hash string) string { return base + url.PathEscape(hash) },
+		headers:  func(*http.Request, time.Duration) {},
+	}
+}
+
+func (r *HTTPRemote) Fetch(ctx context.Context, hash string) (io.ReadCloser, error) {
+	req, err := r.newRequest(ctx, http.MethodGet, hash, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch from remote cache: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, nil
+	}
+	defer resp.Body.Close()
+	return nil, fmt.Errorf("failed to fetch from remote cache: %s", responseError(resp))
+}
+
+func (r *HTTPRemote) Store(ctx context.Context, hash string, artifact io.Reader, size int64, duration time.Duration) error {
+	req, err := r.newRequest(ctx, http.MethodPut, hash, artifact)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	r.headers(req, duration)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload to remote cache: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Nx answers 409 when the hash is already stored, which is as good
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent, http.StatusConflict:
+		return nil
+	}
+	return fmt.Errorf("failed to upload to remote cache: %s", responseError(resp))
+}
+
+func (r *HTTPRemote) newRequest(ctx context.Context, method, hash string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, r.endpoint(hash), body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote cache request: %w", err)
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	return req, nil
+}
+
+// responseError describes an unexpected response, including the start of
+// its body, which servers use for error messages.
+func responseError(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if message := strings.TrimSpace(string(body)); message != "" {
+		return fmt.Sprintf("%s: %s", resp.Status, message)
+	}
+	return resp.Status
+}
+
+// S3Remote stores artifacts as objects named <hash>.tar.gz below a key
+// prefix of an S3 bucket.
+type S3Remote struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Remote returns a remote cache in bucket, below prefix, accessed with
+// client.
+func NewS3Remote(client *s3.Client, bucket, prefix string) *S3Remote {
+	return &S3Remote{client: client, bucket: bucket, prefix: prefix}
+}
+
+func (r *S3Remote) Fetch(ctx context.Context, hash string) (io.ReadCloser, error) {
+	body, err := r.client.GetObject(ctx, r.bucket, r.key(hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch from remote cache: %w", err)
+	}
+	return body, nil
+}
+
+// Store uploads an artifact. S3 verifies uploads against their sha256
+// digest, so artifacts that can't be read twice are buffered in memory to
+// compute it first.
+func (r *S3Remote) Store(ctx context.Context, hash string, artifact io.Reader, size int64, _ time.Duration) error {
+	digest := sha256.New()
+	seeker, ok := artifact.(io.ReadSeeker)
+	if ok {
+		if _, err := io.Copy(digest, seeker); err != nil {
+			return fmt.Errorf("failed to upload to remote cache: %w", err)
+		}
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to upload to remote cache: %w", err)
+		}
+	} else {
+		data, err := io.ReadAll(artifact)
+		if err != nil {
+			return fmt.Errorf("failed to upload to remote cache: %w", err)
+		}
+		digest.Write(data)
+		artifact = bytes.NewReader(data)
+	}
+
+	err := r.client.PutObject(ctx, r.bucket, r.key(hash), artifact, size, hex.EncodeToString(digest.Sum(nil)))
+	if err != nil {
+		return fmt.Errorf("failed to upload to remote cache: %w", err)
+	}
+	return nil
+}
+
+func (r *S3Remote) key(hash string) string {
+	return path.Join(r.prefix, hash+".tar.gz")
+}
+
+// Fingerprint returns the key a task's artifact is stored under remotely: a
+// hex sha256 digest of the task key, its definition (see
+// deps.DefinitionHash) and its input files, so machines that agree on all
+// three share the artifact.
+func Fingerprint(taskKey, definition string, inputs []deps.FileInfo) string {
+	hash := sha256.New()
+	hash.Write([]byte(taskKey + "\x00"))
+	hash.Write([]byte(definition + "\x00"))
+	hash.Write([]byte(InputsDigest(inputs)))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
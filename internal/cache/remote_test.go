@@ -0,0 +1,264 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"doctrus/internal/deps"
+	"doctrus/internal/s3"
+)
+
+// fakeRemoteServer stores artifacts PUT to it and serves them back,
+// recording the requests it received.
+type fakeRemoteServer struct {
+	mu        sync.Mutex
+	artifacts map[string][]byte
+	requests  []*http.Request
+	conflict  bool
+}
+
+func (s *fakeRemoteServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		http.Error(w, "missing token", http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		if _, ok := s.artifacts[r.URL.Path]; ok && s.conflict {
+			http.Error(w, "already exists", http.StatusConflict)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		s.artifacts[r.URL.Path] = body
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodGet:
+		body, ok := s.artifacts[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	}
+}
+
+func TestHTTPRemote(t *testing.T) {
+	tests := []struct {
+		name      string
+		newRemote func(url string) *HTTPRemote
+		wantPath  string
+		wantQuery string
+		conflict  bool
+	}{
+		{
+			name:      "turborepo team id",
+			newRemote: func(url string) *HTTPRemote { return NewTurborepoRemote(url+"/", "team_123", "secret") },
+			wantPath:  "/v8/artifacts/abc",
+			wantQuery: "teamId=team_123",
+		},
+		{
+			name:      "turborepo team slug",
+			newRemote: func(url string) *HTTPRemote { return NewTurborepoRemote(url, "acme", "secret") },
+			wantPath:  "/v8/artifacts/abc",
+			wantQuery: "slug=acme",
+		},
+		{
+			name:      "nx",
+			newRemote: func(url string) *HTTPRemote { return NewNxRemote(url, "secret") },
+			wantPath:  "/v1/cache/abc",
+			conflict:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &fakeRemoteServer{artifacts: make(map[string][]byte), conflict: tt.conflict}
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+			remote := tt.newRemote(ts.URL)
+			ctx := context.Background()
+
+			if artifact, err := remote.Fetch(ctx, "abc"); err != nil || artifact != nil {
+				t.Fatalf("Fetch() before Store = %v, %v; want a miss", artifact, err)
+			}
+
+			for i := 0; i < 2; i++ {
+				if err := remote.Store(ctx, "abc", strings.NewReader("data"), 4, 1500*time.Millisecond); err != nil {
+					t.Fatalf("Store() error = %v", err)
+				}
+			}
+
+			artifact, err := remote.Fetch(ctx, "abc")
+			if err != nil || artifact == nil {
+				t.Fatalf("Fetch() = %v, %v", artifact, err)
+			}
+			defer artifact.Close()
+			if data, _ := io.ReadAll(artifact); string(data) != "data" {
+				t.Fatalf("Fetch() data = %q", data)
+			}
+
+			put := server.requests[1]
+			if put.URL.Path != tt.wantPath || put.URL.RawQuery != tt.wantQuery {
+				t.Fatalf("PUT %s?%s, want %s?%s", put.URL.Path, put.URL.RawQuery, tt.wantPath, tt.wantQuery)
+			}
+			if put.Header.Get("Content-Type") != "application/octet-stream" {
+				t.Fatalf("Content-Type = %q", put.Header.Get("Content-Type"))
+			}
+			if strings.HasPrefix(tt.name, "turborepo") && put.Header.Get("x-artifact-duration") != "1500" {
+				t.Fatalf("x-artifact-duration = %q", put.Header.Get("x-artifact-duration"))
+			}
+
+			unauthorized := NewNxRemote(ts.URL, "wrong")
+			if _, err := unauthorized.Fetch(ctx, "abc"); err == nil || !strings.Contains(err.Error(), "401") {
+				t.Fatalf("Fetch() with a bad token error = %v, want 401", err)
+			}
+		})
+	}
+}
+
+func TestS3Remote(t *testing.T) {
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+	var checksums []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = body
+			checksums = append(checksums, r.Header.Get("X-Amz-Checksum-Sha256"))
+		case http.MethodGet:
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+				return
+			}
+			w.Write(body)
+		}
+	}))
+	defer ts.Close()
+
+	remote := NewS3Remote(&s3.Client{Region: "us-east-1", Endpoint: ts.URL}, "builds", "doctrus/cache")
+	ctx := context.Background()
+
+	if artifact, err := remote.Fetch(ctx, "abc"); err != nil || artifact != nil {
+		t.Fatalf("Fetch() before Store = %v, %v; want a miss", artifact, err)
+	}
+
+	// A reader that can't seek is buffered to compute its digest
+	if err := remote.Store(ctx, "abc", io.MultiReader(strings.NewReader("data")), 4, time.Second); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if err := remote.Store(ctx, "def", strings.NewReader("more"), 4, time.Second); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	if _, ok := objects["/builds/doctrus/cache/abc.tar.gz"]; !ok {
+		t.Fatalf("objects = %v, want /builds/doctrus/cache/abc.tar.gz", reflect.ValueOf(objects).MapKeys())
+	}
+	// base64 of the sha256 digest of "data"
+	if checksums[0] != "Om6weQ85rIfJTzhWst0sXREOaBFgImGpqSPTuyOtyLc=" {
+		t.Errorf("X-Amz-Checksum-Sha256 = %q", checksums[0])
+	}
+
+	for hash, want := range map[string]string{"abc": "data", "def": "more"} {
+		artifact, err := remote.Fetch(ctx, hash)
+		if err != nil || artifact == nil {
+			t.Fatalf("Fetch(%s) = %v, %v", hash, artifact, err)
+		}
+		data, _ := io.ReadAll(artifact)
+		artifact.Close()
+		if string(data) != want {
+			t.Errorf("Fetch(%s) data = %q, want %q", hash, data, want)
+		}
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	source := t.TempDir()
+	files := map[string]string{"app/dist/main.js": "console.log(1)", "app/dist/css/site.css": "body{}"}
+	var infos []deps.FileInfo
+	for name, content := range files {
+		path := filepath.Join(source, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		infos = append(infos, deps.FileInfo{Path: filepath.FromSlash(name)})
+	}
+
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, source, infos); err != nil {
+		t.Fatalf("WriteArchive() error = %v", err)
+	}
+
+	target := t.TempDir()
+	written, err := ExtractArchive(&buf, target)
+	if err != nil {
+		t.Fatalf("ExtractArchive() error = %v", err)
+	}
+	if len(written) != len(files) {
+		t.Fatalf("ExtractArchive() wrote %v", written)
+	}
+	got := make(map[string]string)
+	for _, name := range written {
+		data, err := os.ReadFile(filepath.Join(target, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[name] = string(data)
+	}
+	if !reflect.DeepEqual(got, files) {
+		t.Fatalf("extracted %v, want %v", got, files)
+	}
+}
+
+func TestExtractArchiveRejectsEscapingPaths(t *testing.T) {
+	root := t.TempDir()
+	project := filepath.Join(root, "project")
+	if err := os.WriteFile(filepath.Join(root, "evil"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, project, []deps.FileInfo{{Path: "../evil"}}); err != nil {
+		t.Fatalf("WriteArchive() error = %v", err)
+	}
+	if _, err := ExtractArchive(&buf, project); err == nil || !strings.Contains(err.Error(), "outside the project") {
+		t.Fatalf("ExtractArchive() error = %v, want the entry rejected", err)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	inputs := []deps.FileInfo{{Path: "src/main.go", Hash: "aaa"}}
+	base := Fingerprint("app:build", "build", inputs)
+
+	if base != Fingerprint("app:build", "build", inputs) {
+		t.Fatal("Fingerprint() should be stable")
+	}
+	for name, other := range map[string]string{
+		"task":       Fingerprint("app:test", "build", inputs),
+		"definition": Fingerprint("app:build", "race", inputs),
+		"inputs":     Fingerprint("app:build", "build", []deps.FileInfo{{Path: "src/main.go", Hash: "bbb"}}),
+	} {
+		if other == base {
+			t.Errorf("Fingerprint() should change with the %s", name)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WorkspaceStats summarizes the cache entries of one workspace. Size is the
+// disk space of the entries and their attestations, OutputSize the total
+// size of the outputs they record. Oldest and Newest are when the least and
+// most recently written entries were created.
+type WorkspaceStats struct {
+	Workspace  string
+	Entries    int
+	Expired    int
+	Size       int64
+	OutputSize int64
+	Oldest     time.Time
+	Newest     time.Time
+}
+
+// WorkspaceStats groups the cache entries by workspace, ordered by
+// workspace name.
+func (m *Manager) WorkspaceStats() ([]WorkspaceStats, error) {
+	entries, err := m.List(ListFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	byWorkspace := make(map[string]*WorkspaceStats)
+	for _, entry := range entries {
+		workspace, _, _ := strings.Cut(entry.TaskKey, ":")
+		stats, ok := byWorkspace[workspace]
+		if !ok {
+			stats = &WorkspaceStats{Workspace: workspace}
+			byWorkspace[workspace] = stats
+		}
+
+		stats.Entries++
+		if entry.TTL > 0 && time.Since(entry.CreatedAt) > entry.TTL {
+			stats.Expired++
+		}
+		for _, path := range []string{m.getCachePath(entry.TaskKey), m.AttestationPath(entry.TaskKey), m.LogsPath(entry.TaskKey)} {
+			if info, err := os.Stat(path); err == nil {
+				stats.Size += info.Size()
+			}
+		}
+		if entry.State != nil {
+			for _, output := range entry.State.Outputs {
+				stats.OutputSize += output.Size
+			}
+		}
+		if stats.Oldest.IsZero() || entry.CreatedAt.Before(stats.Oldest) {
+			stats.Oldest = entry.CreatedAt
+		}
+		if entry.CreatedAt.After(stats.Newest) {
+			stats.Newest = entry.CreatedAt
+		}
+	}
+
+	result := make([]WorkspaceStats, 0, len(byWorkspace))
+	for _, stats := range byWorkspace {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Workspace < result[j].Workspace })
+	return result, nil
+}
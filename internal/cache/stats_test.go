@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"testing"
+
+	"doctrus/internal/deps"
+)
+
+func TestManagerWorkspaceStats(t *testing.T) {
+	manager, _ := createTestManager(t)
+
+	states := map[string][]deps.FileInfo{
+		"web:build": {{Path: "web/dist/app.js", Size: 300}, {Path: "web/dist/app.css", Size: 100}},
+		"web:lint":  nil,
+		"api:build": {{Path: "api/bin/server", Size: 5000}},
+	}
+	for _, key := range []string{"web:build", "api:build", "web:lint"} {
+		if err := manager.Set(key, &deps.TaskState{TaskKey: key, Outputs: states[key], Success: true}, 0); err != nil {
+			t.Fatalf("Set(%s) error: %v", key, err)
+		}
+	}
+
+	stats, err := manager.WorkspaceStats()
+	if err != nil {
+		t.Fatalf("WorkspaceStats() error = %v", err)
+	}
+	if len(stats) != 2 || stats[0].Workspace != "api" || stats[1].Workspace != "web" {
+		t.Fatalf("WorkspaceStats() = %+v, want api and web in order", stats)
+	}
+
+	web := stats[1]
+	if web.Entries != 2 || web.OutputSize != 400 {
+		t.Errorf("web: entries %d, outputs %d; want 2 and 400", web.Entries, web.OutputSize)
+	}
+	if web.Size <= 0 {
+		t.Errorf("web: size %d, want the size of its entry files", web.Size)
+	}
+	if web.Oldest.IsZero() || web.Newest.Before(web.Oldest) {
+		t.Errorf("web: oldest %v, newest %v", web.Oldest, web.Newest)
+	}
+	if stats[0].Entries != 1 || stats[0].OutputSize != 5000 {
+		t.Errorf("api: entries %d, outputs %d; want 1 and 5000", stats[0].Entries, stats[0].OutputSize)
+	}
+}
@@ -0,0 +1,366 @@
+// Package cas stores the outputs of tasks by content. Each file is kept once
+// as a blob named by its sha256 digest, however many tasks or runs produced
+// it, and a manifest per task fingerprint lists the blobs making up that
+// task's outputs.
+package cas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"doctrus/internal/fsutil"
+)
+
+const (
+	blobDir     = "blobs"
+	manifestDir = "manifests"
+)
+
+// gcGrace protects blobs written recently from GC, as a concurrent Put
+// writes its blobs before the manifest referencing them.
+const gcGrace = time.Hour
+
+// Store is a content-addressable store in a directory, such as
+// .doctrus/cas.
+type Store struct {
+	dir string
+}
+
+// Manifest lists the output files of a task run, by slash-separated path
+// relative to the project.
+type Manifest struct {
+	Fingerprint string    `json:"fingerprint"`
+	CreatedAt   time.Time `json:"created_at"`
+	Files       []File    `json:"files"`
+}
+
+// File is an output file and the blob holding its content.
+type File struct {
+	Path string      `json:"path"`
+	Hash string      `json:"hash"`
+	Mode fs.FileMode `json:"mode"`
+	Size int64       `json:"size"`
+}
+
+// GCOptions selects the manifests GC removes. MaxAge removes manifests not
+// stored or restored for longer; zero keeps them all. With DryRun set
+// nothing is deleted.
+type GCOptions struct {
+	MaxAge time.Duration
+	DryRun bool
+}
+
+// GCResult counts what GC removed and the disk space freed.
+type GCResult struct {
+	Manifests int
+	Blobs     int
+	Freed     int64
+}
+
+// New returns the store in dir, which is created on first use.
+func New(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the directory of the store.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Put stores the files, relative to root, as the outputs of the task run
+// with the fingerprint, replacing any stored before. Blobs already in the
+// store are not written again.
+func (s *Store) Put(fingerprint, root string, files []string) error {
+	if err := checkFingerprint(fingerprint); err != nil {
+		return err
+	}
+	manifest := Manifest{Fingerprint: fingerprint, CreatedAt: time.Now()}
+	for _, name := range files {
+		file, err := s.putBlob(filepath.Join(root, name))
+		if err != nil {
+			return fmt.Errorf("failed to store %s: %w", name, err)
+		}
+		file.Path = filepath.ToSlash(name)
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	// The manifest is written last, so it never references a missing blob
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	err = fsutil.WriteFileAtomic(s.manifestPath(fingerprint), 0644, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Get returns the manifest stored for the fingerprint, or nil when there is
+// none.
+func (s *Store) Get(fingerprint string) (*Manifest, error) {
+	if err := checkFingerprint(fingerprint); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.manifestPath(fingerprint))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", fingerprint, err)
+	}
+	return &manifest, nil
+}
+
+// Restore writes the files stored for the fingerprint below root, replacing
+// existing files, and returns their paths, or nil when nothing is stored.
+// Paths that would leave root are rejected.
+func (s *Store) Restore(fingerprint, root string) ([]string, error) {
+	manifest, err := s.Get(fingerprint)
+	if err != nil || manifest == nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, file := range manifest.Files {
+		name := path.Clean(file.Path)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return written, fmt.Errorf("manifest entry %s is outside the project", file.Path)
+		}
+		if err := s.restoreBlob(file, filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			return written, fmt.Errorf("failed to restore %s: %w", name, err)
+		}
+		written = append(written, name)
+	}
+
+	// Restoring counts as use, so GC by age keeps the manifest
+	now := time.Now()
+	_ = os.Chtimes(s.manifestPath(fingerprint), now, now)
+	return written, nil
+}
+
+// GC removes the manifests selected by opts, along with unreadable ones,
+// and then every blob no remaining manifest references.
+func (s *Store) GC(opts GCOptions) (GCResult, error) {
+	var result GCResult
+	referenced := make(map[string]bool)
+
+	manifests, err := os.ReadDir(filepath.Join(s.dir, manifestDir))
+	if err != nil && !os.IsNotExist(err) {
+		return result, fmt.Errorf("failed to read manifests: %w", err)
+	}
+	for _, entry := range manifests {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		manifestPath := filepath.Join(s.dir, manifestDir, entry.Name())
+
+		var manifest Manifest
+		data, err := os.ReadFile(manifestPath)
+		if err == nil {
+			err = json.Unmarshal(data, &manifest)
+		}
+		// Temporary files of a manifest being written are left alone
+		pending := strings.HasPrefix(entry.Name(), ".") && time.Since(info.ModTime()) < gcGrace
+		stale := opts.MaxAge > 0 && time.Since(info.ModTime()) > opts.MaxAge
+		if pending || (err == nil && !stale) {
+			for _, file := range manifest.Files {
+				referenced[file.Hash] = true
+			}
+			continue
+		}
+		if err := remove(manifestPath, opts.DryRun); err != nil {
+			return result, err
+		}
+		result.Manifests++
+		result.Freed += info.Size()
+	}
+
+	blobs := filepath.Join(s.dir, blobDir)
+	err = filepath.WalkDir(blobs, func(blobPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && blobPath == blobs {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() || referenced[entry.Name()] {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < gcGrace {
+			return nil
+		}
+		if err := remove(blobPath, opts.DryRun); err != nil {
+			return err
+		}
+		result.Blobs++
+		result.Freed += info.Size()
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to collect blobs: %w", err)
+	}
+	return result, nil
+}
+
+// Size returns the disk space used by the store.
+func (s *Store) Size() (int64, error) {
+	var size int64
+	err := filepath.WalkDir(s.dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure %s: %w", s.dir, err)
+	}
+	return size, nil
+}
+
+// Clear removes everything in the store.
+func (s *Store) Clear() error {
+	if err := os.RemoveAll(s.dir); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", s.dir, err)
+	}
+	return nil
+}
+
+// putBlob copies a file into the store, hashing it on the way, unless a
+// blob with the same content exists.
+func (s *Store) putBlob(source string) (File, error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return File{}, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return File{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return File{}, fmt.Errorf("not a regular file")
+	}
+
+	dir := filepath.Join(s.dir, blobDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return File{}, err
+	}
+	tmp, err := os.CreateTemp(dir, ".blob.*")
+	if err != nil {
+		return File{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), in)
+	if err != nil {
+		tmp.Close()
+		return File{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return File{}, err
+	}
+
+	file := File{Hash: hex.EncodeToString(hasher.Sum(nil)), Mode: info.Mode().Perm(), Size: size}
+	target := s.blobPath(file.Hash)
+	if _, err := os.Stat(target); err == nil {
+		// Deduplicated; touch it so GC's grace period covers this Put too
+		now := time.Now()
+		_ = os.Chtimes(target, now, now)
+		return file, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return File{}, err
+	}
+	if err := os.Chmod(tmp.Name(), 0444); err != nil {
+		return File{}, err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return File{}, err
+	}
+	return file, nil
+}
+
+// restoreBlob copies the blob of file to target. Files are copied rather
+// than linked, so a task changing its outputs in place can't corrupt the
+// store.
+func (s *Store) restoreBlob(file File, target string) error {
+	blob, err := os.Open(s.blobPath(file.Hash))
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	mode := file.Mode
+	if mode == 0 {
+		mode = 0644
+	}
+	return fsutil.WriteFileAtomic(target, mode, func(w io.Writer) error {
+		hasher := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(w, hasher), blob); err != nil {
+			return err
+		}
+		if hex.EncodeToString(hasher.Sum(nil)) != file.Hash {
+			return fmt.Errorf("blob %s is corrupt", file.Hash)
+		}
+		return nil
+	})
+}
+
+func (s *Store) blobPath(hash string) string {
+	if len(hash) < 2 {
+		return filepath.Join(s.dir, blobDir, hash)
+	}
+	return filepath.Join(s.dir, blobDir, hash[:2], hash)
+}
+
+func (s *Store) manifestPath(fingerprint string) string {
+	return filepath.Join(s.dir, manifestDir, fingerprint+".json")
+}
+
+// checkFingerprint rejects fingerprints that can't name a manifest file.
+func checkFingerprint(fingerprint string) error {
+	if fingerprint == "" || strings.ContainsAny(fingerprint, `/\.`) {
+		return fmt.Errorf("invalid fingerprint %q", fingerprint)
+	}
+	return nil
+}
+
+// remove deletes a file of the store unless dryRun is set.
+func remove(path string, dryRun bool) error {
+	if dryRun {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package cas
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// blobs returns the number of blobs in the store.
+func blobs(t *testing.T, s *Store) int {
+	t.Helper()
+	count := 0
+	filepath.WalkDir(filepath.Join(s.dir, blobDir), func(_ string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+func TestStorePutRestore(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "web", "dist", "app.js"), "bundle")
+	writeFile(t, filepath.Join(root, "web", "dist", "vendor.js"), "shared")
+	writeFile(t, filepath.Join(root, "api", "bin", "vendor.js"), "shared")
+
+	store := New(filepath.Join(t.TempDir(), "cas"))
+	if err := store.Put("aaa", root, []string{"web/dist/app.js", "web/dist/vendor.js"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := store.Put("bbb", root, []string{"api/bin/vendor.js"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if got := blobs(t, store); got != 2 {
+		t.Errorf("blobs = %d, want identical files stored once", got)
+	}
+
+	if err := os.RemoveAll(filepath.Join(root, "web")); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := store.Restore("aaa", root)
+	if err != nil || len(restored) != 2 {
+		t.Fatalf("Restore() = %v, %v, want two files", restored, err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "web", "dist", "vendor.js"))
+	if err != nil || string(data) != "shared" {
+		t.Errorf("restored file = %q, %v; want %q", data, err, "shared")
+	}
+	// Restored files are copies, so changing one leaves the store intact
+	writeFile(t, filepath.Join(root, "web", "dist", "vendor.js"), "edited")
+	if _, err := store.Restore("bbb", root); err != nil {
+		t.Errorf("Restore() after editing a restored file error = %v", err)
+	}
+
+	if restored, err := store.Restore("ccc", root); err != nil || restored != nil {
+		t.Errorf("Restore() of an unknown fingerprint = %v, %v, want none", restored, err)
+	}
+	for _, fingerprint := range []string{"", "../escape", "a.b"} {
+		if _, err := store.Restore(fingerprint, root); err == nil {
+			t.Errorf("Restore(%q) succeeded, want an error", fingerprint)
+		}
+	}
+}
+
+func TestStoreGC(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "old.txt"), "old")
+	writeFile(t, filepath.Join(root, "new.txt"), "new")
+	writeFile(t, filepath.Join(root, "shared.txt"), "shared")
+
+	store := New(filepath.Join(t.TempDir(), "cas"))
+	if err := store.Put("old", root, []string{"old.txt", "shared.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put("new", root, []string{"new.txt", "shared.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	// Age the old manifest and every blob past the grace period
+	past := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(store.manifestPath("old"), past, past); err != nil {
+		t.Fatal(err)
+	}
+	filepath.WalkDir(filepath.Join(store.dir, blobDir), func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			os.Chtimes(path, past, past)
+		}
+		return nil
+	})
+
+	result, err := store.GC(GCOptions{MaxAge: 24 * time.Hour, DryRun: true})
+	if err != nil || result.Manifests != 1 || result.Blobs != 1 {
+		t.Fatalf("GC() dry run = %+v, %v, want 1 manifest and 1 blob", result, err)
+	}
+	if got := blobs(t, store); got != 3 {
+		t.Fatalf("dry run removed blobs: %d left", got)
+	}
+
+	result, err = store.GC(GCOptions{MaxAge: 24 * time.Hour})
+	if err != nil || result.Manifests != 1 || result.Blobs != 1 || result.Freed == 0 {
+		t.Fatalf("GC() = %+v, %v, want 1 manifest and 1 blob", result, err)
+	}
+	if manifest, _ := store.Get("old"); manifest != nil {
+		t.Error("old manifest kept")
+	}
+	// The blob shared with a kept manifest survives
+	if restored, err := store.Restore("new", root); err != nil || len(restored) != 2 {
+		t.Errorf("Restore() after GC = %v, %v, want two files", restored, err)
+	}
+
+	if err := store.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if _, err := os.Stat(store.Dir()); !os.IsNotExist(err) {
+		t.Errorf("store still exists after Clear() (stat error = %v)", err)
+	}
+}
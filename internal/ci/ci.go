@@ -0,0 +1,108 @@
+package ci
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Options configure the generated pipeline.
+type Options struct {
+	// Version is the doctrus release CI jobs install; empty or "dev" installs
+	// the latest release
+	Version string
+	// Branch is the branch whose pushes trigger the pipeline
+	Branch string
+	// RunsOn is the runner label (GitHub) or image (GitLab) of every job
+	RunsOn string
+	// ChildPipelines splits a GitLab pipeline into a child pipeline per
+	// shard, written under ChildDir
+	ChildPipelines bool
+	// ChildDir is the directory of child pipeline files relative to the
+	// repository root
+	ChildDir string
+}
+
+// File is a generated pipeline file. The main file has an empty Path; the
+// paths of additional files are relative to the repository root.
+type File struct {
+	Path string
+	Data []byte
+}
+
+// Generator renders a plan for one CI provider.
+type Generator func(plan *Plan, opts Options) ([]File, error)
+
+var generators = map[string]Generator{
+	"github": GitHub,
+	"gitlab": GitLab,
+}
+
+// Providers lists the supported CI providers.
+func Providers() []string {
+	names := make([]string, 0, len(generators))
+	for name := range generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Generate renders plan for the named provider. The main file comes first.
+func Generate(provider string, plan *Plan, opts Options) ([]File, error) {
+	generator, ok := generators[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown CI provider %q (expected one of %s)", provider, strings.Join(Providers(), ", "))
+	}
+	return generator(plan, opts)
+}
+
+// header is the comment opening every generated file.
+func header(provider string) string {
+	return fmt.Sprintf("# Generated by doctrus ci generate --provider %s from doctrus.yml.\n# Regenerate it after changing the tasks instead of editing it.\n", provider)
+}
+
+// installScript installs doctrus into ~/.local/bin.
+func installScript(version string) string {
+	env := `INSTALL_DIR="$HOME/.local/bin"`
+	if version != "" && version != "dev" {
+		env += " VERSION=" + version
+	}
+	return strings.Join([]string{
+		`mkdir -p "$HOME/.local/bin"`,
+		"curl -sSL https://raw.githubusercontent.com/SebastiaanWouters/doctrus/main/install.sh | " + env + " bash",
+	}, "\n")
+}
+
+// runCommand runs specs, without their dependencies when noDeps is set
+// because those ran in earlier jobs.
+func runCommand(plan *Plan, specs []string, noDeps bool) string {
+	flags := " "
+	if noDeps {
+		flags = " --no-deps "
+	}
+	return fmt.Sprintf("doctrus --config %s run%s%s", plan.ConfigFile, flags, strings.Join(specs, " "))
+}
+
+// encodeYAML renders value after the generated-file header.
+func encodeYAML(provider string, value any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(header(provider))
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// artifactName names the artifact holding a shard's outputs.
+func artifactName(shardID string) string {
+	return "doctrus-outputs-" + shardID
+}
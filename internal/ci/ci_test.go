@@ -0,0 +1,274 @@
+package ci
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"lib": {
+				Path: "./packages/lib",
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"build"}, Outputs: []string{"dist/**"}},
+					"test":  {Command: []string{"test"}, DependsOn: []string{"build"}},
+				},
+			},
+			"app": {
+				Path: "./apps/app",
+				Tags: []string{"node"},
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"build"}, DependsOn: []string{"lib:build", "api:schema"}, Outputs: []string{"out/**"}},
+				},
+			},
+			// api and app depend on each other, so they share a shard
+			"api": {
+				Path: "./apps/api",
+				Tasks: map[string]config.Task{
+					"schema": {Command: []string{"schema"}},
+					"e2e":    {Command: []string{"e2e"}, DependsOn: []string{"app:build"}, Inputs: []string{"../../fixtures/**"}},
+				},
+			},
+			"docs": {
+				Path: "./docs",
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"build"}, DependsOn: []string{"api:e2e"}},
+				},
+			},
+		},
+	}
+}
+
+func newTestPlan(t *testing.T, specs ...string) *Plan {
+	t.Helper()
+	cfg := testConfig()
+	plan, err := NewPlan(cfg, workspace.NewManager(cfg, "/repo/ci"), "ci/doctrus.yml", specs)
+	if err != nil {
+		t.Fatalf("NewPlan() error = %v", err)
+	}
+	return plan
+}
+
+func TestNewPlanShardsWorkspaces(t *testing.T) {
+	plan := newTestPlan(t)
+
+	type shardSummary struct {
+		ID       string
+		Specs    []string
+		Needs    []string
+		Upstream []string
+	}
+	var got []shardSummary
+	for _, shard := range plan.Shards {
+		got = append(got, shardSummary{shard.ID, shard.Specs(), shard.Needs, shard.Upstream})
+	}
+	want := []shardSummary{
+		{ID: "lib", Specs: []string{"lib:build", "lib:test"}, Needs: []string{}, Upstream: []string{}},
+		{ID: "api-app", Specs: []string{"api:schema", "app:build", "api:e2e"}, Needs: []string{"lib"}, Upstream: []string{"lib"}},
+		{ID: "docs", Specs: []string{"docs:build"}, Needs: []string{"api-app"}, Upstream: []string{"api-app", "lib"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("shards = %+v, want %+v", got, want)
+	}
+
+	if outputs := plan.Shards[1].Outputs(); !reflect.DeepEqual(outputs, []string{"ci/apps/app/out/**"}) {
+		t.Fatalf("Outputs() = %v", outputs)
+	}
+	wantPaths := []string{"ci/apps/api/**", "ci/apps/app/**", "ci/docs/**", "ci/doctrus.yml", "ci/fixtures/**", "ci/packages/lib/**"}
+	if !reflect.DeepEqual(plan.Paths, wantPaths) {
+		t.Fatalf("Paths = %v, want %v", plan.Paths, wantPaths)
+	}
+}
+
+func TestNewPlanSpecs(t *testing.T) {
+	plan := newTestPlan(t, "*:build")
+	if len(plan.Shards) != 3 {
+		t.Fatalf("got %d shards, want 3", len(plan.Shards))
+	}
+
+	plan = newTestPlan(t, "lib:test")
+	if len(plan.Shards) != 1 || !reflect.DeepEqual(plan.Shards[0].Specs(), []string{"lib:build", "lib:test"}) {
+		t.Fatalf("shards = %+v", plan.Shards)
+	}
+	if !reflect.DeepEqual(plan.Paths, []string{"ci/doctrus.yml", "ci/packages/lib/**"}) {
+		t.Fatalf("Paths = %v", plan.Paths)
+	}
+
+	cfg := testConfig()
+	if _, err := NewPlan(cfg, workspace.NewManager(cfg, "/repo"), "doctrus.yml", []string{"lint"}); err == nil {
+		t.Fatal("NewPlan() should fail for an unknown task")
+	}
+}
+
+func TestGitHub(t *testing.T) {
+	files, err := Generate("github", newTestPlan(t), Options{Version: "v1.2.0"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	data := files[0].Data
+	if !strings.HasPrefix(string(data), "# Generated by doctrus ci generate") {
+		t.Fatalf("missing header:\n%s", data)
+	}
+
+	var workflow githubWorkflow
+	if err := yaml.Unmarshal(data, &workflow); err != nil {
+		t.Fatalf("generated workflow is not valid YAML: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(workflow.On.Push.Branches, []string{"main"}) || len(workflow.On.PullRequest.Paths) == 0 {
+		t.Fatalf("triggers = %+v", workflow.On)
+	}
+
+	docs := workflow.Jobs["docs"]
+	if docs == nil || !reflect.DeepEqual(docs.Needs, []string{"api-app"}) {
+		t.Fatalf("docs job = %+v", docs)
+	}
+	var uses, runs []string
+	for _, step := range docs.Steps {
+		if step.Uses != "" {
+			uses = append(uses, step.Uses+" "+step.With["name"])
+		}
+		if step.Run != "" {
+			runs = append(runs, step.Run)
+		}
+	}
+	wantUses := []string{
+		"actions/checkout@v4 ",
+		"actions/download-artifact@v4 doctrus-outputs-api-app",
+		"actions/download-artifact@v4 doctrus-outputs-lib",
+		"actions/cache@v4 ",
+	}
+	if !reflect.DeepEqual(uses, wantUses) {
+		t.Fatalf("docs steps use %v, want %v", uses, wantUses)
+	}
+	if !strings.Contains(runs[0], "VERSION=v1.2.0") {
+		t.Fatalf("install step = %q", runs[0])
+	}
+	if runs[1] != "doctrus --config ci/doctrus.yml run --no-deps docs:build" {
+		t.Fatalf("run step = %q", runs[1])
+	}
+
+	// Only shards with outputs that later jobs use upload them
+	for id, wantUpload := range map[string]bool{"lib": true, "api-app": true, "docs": false} {
+		uploads := false
+		for _, step := range workflow.Jobs[id].Steps {
+			uploads = uploads || step.Uses == "actions/upload-artifact@v4"
+		}
+		if uploads != wantUpload {
+			t.Fatalf("job %s uploads outputs = %v, want %v", id, uploads, wantUpload)
+		}
+	}
+
+	if _, err := Generate("jenkins", newTestPlan(t), Options{}); err == nil {
+		t.Fatal("Generate() should fail for an unknown provider")
+	}
+}
+
+// gitlabKeys parses a pipeline file into its top-level keys.
+func gitlabKeys(t *testing.T, data []byte) map[string]*yaml.Node {
+	t.Helper()
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("generated pipeline is not valid YAML: %v\n%s", err, data)
+	}
+	keys := make(map[string]*yaml.Node)
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		keys[root.Content[i].Value] = root.Content[i+1]
+	}
+	return keys
+}
+
+func TestGitLab(t *testing.T) {
+	files, err := Generate("gitlab", newTestPlan(t), Options{RunsOn: "golang:1.24"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("got %d files, want 1", len(files))
+	}
+
+	pipeline := gitlabKeys(t, files[0].Data)
+	var stages []string
+	if err := pipeline["stages"].Decode(&stages); err != nil || !reflect.DeepEqual(stages, []string{"doctrus-1", "doctrus-2", "doctrus-3"}) {
+		t.Fatalf("stages = %v, %v", stages, err)
+	}
+
+	var docs gitlabJob
+	if err := pipeline["docs"].Decode(&docs); err != nil {
+		t.Fatalf("failed to decode docs job: %v", err)
+	}
+	wantNeeds := []gitlabNeed{{Job: "api-app", Artifacts: true}, {Job: "lib", Artifacts: true}}
+	if docs.Stage != "doctrus-3" || docs.Image != "golang:1.24" || !reflect.DeepEqual(docs.Needs, wantNeeds) {
+		t.Fatalf("docs job = %+v", docs)
+	}
+	if last := docs.Script[len(docs.Script)-1]; last != "doctrus --config ci/doctrus.yml run --no-deps docs:build" {
+		t.Fatalf("docs script ends with %q", last)
+	}
+	if len(docs.Rules) != 1 || len(docs.Rules[0].Changes) == 0 {
+		t.Fatalf("docs rules = %+v", docs.Rules)
+	}
+
+	var apiApp gitlabJob
+	if err := pipeline["api-app"].Decode(&apiApp); err != nil {
+		t.Fatalf("failed to decode api-app job: %v", err)
+	}
+	if !reflect.DeepEqual(apiApp.Tags, []string{"node"}) || apiApp.Artifacts == nil {
+		t.Fatalf("api-app job = %+v", apiApp)
+	}
+}
+
+func TestGitLabChildPipelines(t *testing.T) {
+	files, err := Generate("gitlab", newTestPlan(t), Options{ChildPipelines: true})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	var paths []string
+	for _, file := range files[1:] {
+		paths = append(paths, file.Path)
+	}
+	wantPaths := []string{".gitlab/doctrus/lib.yml", ".gitlab/doctrus/api-app.yml", ".gitlab/doctrus/docs.yml"}
+	if files[0].Path != "" || !reflect.DeepEqual(paths, wantPaths) {
+		t.Fatalf("files = %q + %v, want main + %v", files[0].Path, paths, wantPaths)
+	}
+
+	parent := gitlabKeys(t, files[0].Data)
+	var docs gitlabJob
+	if err := parent["docs"].Decode(&docs); err != nil {
+		t.Fatalf("failed to decode docs trigger: %v", err)
+	}
+	if docs.Trigger == nil || docs.Trigger.Include != ".gitlab/doctrus/docs.yml" || !reflect.DeepEqual(docs.Needs, []gitlabNeed{{Job: "api-app"}}) {
+		t.Fatalf("docs trigger = %+v", docs)
+	}
+
+	child := gitlabKeys(t, files[2].Data)
+	tests := []struct {
+		job    string
+		stage  string
+		needs  []gitlabNeed
+		script string
+	}{
+		{job: "api:schema", stage: "doctrus-1", needs: []gitlabNeed{}, script: "doctrus --config ci/doctrus.yml run --no-deps api:schema"},
+		// app:build also needs lib:build from another child pipeline
+		{job: "app:build", stage: "doctrus-2", needs: []gitlabNeed{{Job: "api:schema"}}, script: "doctrus --config ci/doctrus.yml run app:build"},
+		{job: "api:e2e", stage: "doctrus-3", needs: []gitlabNeed{{Job: "api:schema"}, {Job: "app:build", Artifacts: true}}, script: "doctrus --config ci/doctrus.yml run api:e2e"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.job, func(t *testing.T) {
+			var job gitlabJob
+			if err := child[tt.job].Decode(&job); err != nil {
+				t.Fatalf("failed to decode job: %v", err)
+			}
+			if job.Stage != tt.stage || !reflect.DeepEqual(job.Needs, tt.needs) || job.Script[len(job.Script)-1] != tt.script {
+				t.Fatalf("job = %+v", job)
+			}
+		})
+	}
+}
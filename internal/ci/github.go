@@ -0,0 +1,121 @@
+package ci
+
+import "strings"
+
+type githubWorkflow struct {
+	Name string                `yaml:"name"`
+	On   githubTriggers        `yaml:"on"`
+	Jobs map[string]*githubJob `yaml:"jobs"`
+}
+
+type githubTriggers struct {
+	Push        githubTrigger `yaml:"push"`
+	PullRequest githubTrigger `yaml:"pull_request"`
+}
+
+type githubTrigger struct {
+	Branches []string `yaml:"branches,omitempty,flow"`
+	Paths    []string `yaml:"paths,omitempty"`
+}
+
+type githubJob struct {
+	Name   string       `yaml:"name"`
+	RunsOn string       `yaml:"runs-on"`
+	Needs  []string     `yaml:"needs,omitempty,flow"`
+	Steps  []githubStep `yaml:"steps"`
+}
+
+type githubStep struct {
+	Name string            `yaml:"name,omitempty"`
+	Uses string            `yaml:"uses,omitempty"`
+	With map[string]string `yaml:"with,omitempty"`
+	Run  string            `yaml:"run,omitempty"`
+}
+
+// GitHub renders plan as a GitHub Actions workflow with one job per shard.
+// Jobs restore and save .doctrus/cache together with their outputs, pass
+// outputs to later jobs as artifacts and only trigger on changes to the
+// plan's paths.
+func GitHub(plan *Plan, opts Options) ([]File, error) {
+	if opts.Branch == "" {
+		opts.Branch = "main"
+	}
+	if opts.RunsOn == "" {
+		opts.RunsOn = "ubuntu-latest"
+	}
+
+	workflow := githubWorkflow{
+		Name: "doctrus",
+		On: githubTriggers{
+			Push:        githubTrigger{Branches: []string{opts.Branch}, Paths: plan.Paths},
+			PullRequest: githubTrigger{Paths: plan.Paths},
+		},
+		Jobs: make(map[string]*githubJob),
+	}
+
+	withOutputs := make(map[string]bool)
+	for _, shard := range plan.Shards {
+		withOutputs[shard.ID] = len(shard.Outputs()) > 0
+	}
+	needed := upstreamShards(plan)
+
+	for _, shard := range plan.Shards {
+		steps := []githubStep{
+			{Uses: "actions/checkout@v4"},
+			{Name: "Install doctrus", Run: installScript(opts.Version) + "\necho \"$HOME/.local/bin\" >> \"$GITHUB_PATH\""},
+		}
+		for _, id := range shard.Upstream {
+			if !withOutputs[id] {
+				continue
+			}
+			steps = append(steps, githubStep{
+				Name: "Download outputs of " + id,
+				Uses: "actions/download-artifact@v4",
+				With: map[string]string{"name": artifactName(id), "path": plan.Root},
+			})
+		}
+
+		cachePaths := append([]string{plan.CacheDir()}, shard.Outputs()...)
+		steps = append(steps,
+			githubStep{
+				Name: "Cache doctrus state",
+				Uses: "actions/cache@v4",
+				With: map[string]string{
+					"path":         strings.Join(cachePaths, "\n"),
+					"key":          "doctrus-${{ runner.os }}-" + shard.ID + "-${{ github.sha }}",
+					"restore-keys": "doctrus-${{ runner.os }}-" + shard.ID + "-",
+				},
+			},
+			githubStep{Name: "Run tasks", Run: runCommand(plan, shard.Specs(), true)},
+		)
+
+		if withOutputs[shard.ID] && needed[shard.ID] {
+			// doctrus.yml makes its directory the artifact root, so
+			// downloading into it restores the outputs' paths
+			paths := append([]string{plan.ConfigFile}, shard.Outputs()...)
+			steps = append(steps, githubStep{
+				Name: "Upload outputs",
+				Uses: "actions/upload-artifact@v4",
+				With: map[string]string{
+					"name":              artifactName(shard.ID),
+					"path":              strings.Join(paths, "\n"),
+					"if-no-files-found": "ignore",
+					"retention-days":    "1",
+				},
+			})
+		}
+
+		workflow.Jobs[shard.ID] = &githubJob{
+			Name:   strings.Join(shard.Workspaces, ", "),
+			RunsOn: opts.RunsOn,
+			Needs:  shard.Needs,
+			Steps:  steps,
+		}
+	}
+
+	data, err := encodeYAML("github", workflow)
+	if err != nil {
+		return nil, err
+	}
+	return []File{{Data: data}}, nil
+}
@@ -0,0 +1,269 @@
+package ci
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type gitlabJob struct {
+	Stage     string           `yaml:"stage"`
+	Image     string           `yaml:"image,omitempty"`
+	Tags      []string         `yaml:"tags,omitempty,flow"`
+	Needs     []gitlabNeed     `yaml:"needs"`
+	Rules     []gitlabRule     `yaml:"rules,omitempty"`
+	Cache     *gitlabCache     `yaml:"cache,omitempty"`
+	Script    []string         `yaml:"script,omitempty"`
+	Artifacts *gitlabArtifacts `yaml:"artifacts,omitempty"`
+	Trigger   *gitlabTrigger   `yaml:"trigger,omitempty"`
+}
+
+type gitlabNeed struct {
+	Job       string `yaml:"job"`
+	Artifacts bool   `yaml:"artifacts"`
+}
+
+type gitlabRule struct {
+	Changes []string `yaml:"changes"`
+}
+
+type gitlabCache struct {
+	Key   string   `yaml:"key"`
+	Paths []string `yaml:"paths"`
+}
+
+type gitlabArtifacts struct {
+	Paths    []string `yaml:"paths"`
+	ExpireIn string   `yaml:"expire_in"`
+}
+
+type gitlabTrigger struct {
+	Include  string `yaml:"include"`
+	Strategy string `yaml:"strategy"`
+}
+
+// gitlabPipeline is a pipeline file: its stages followed by its jobs in
+// order.
+type gitlabPipeline struct {
+	stages []string
+	names  []string
+	jobs   map[string]*gitlabJob
+}
+
+func (p *gitlabPipeline) add(name string, job *gitlabJob) {
+	if p.jobs == nil {
+		p.jobs = make(map[string]*gitlabJob)
+	}
+	p.names = append(p.names, name)
+	p.jobs[name] = job
+}
+
+func (p *gitlabPipeline) MarshalYAML() (any, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	appendPair := func(key string, value any) error {
+		var node yaml.Node
+		if err := node.Encode(value); err != nil {
+			return err
+		}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &node)
+		return nil
+	}
+
+	stages := yaml.Node{}
+	if err := stages.Encode(p.stages); err != nil {
+		return nil, err
+	}
+	stages.Style = yaml.FlowStyle
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "stages"}, &stages)
+	for _, name := range p.names {
+		if err := appendPair(name, p.jobs[name]); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// GitLab renders plan as a GitLab CI pipeline. Shards become jobs in stages
+// following the dependency graph, run on runners with their workspaces'
+// tags and only when the plan's paths change. With ChildPipelines each shard
+// instead triggers a child pipeline holding a job per task.
+func GitLab(plan *Plan, opts Options) ([]File, error) {
+	if opts.ChildPipelines {
+		return gitlabChildPipelines(plan, opts)
+	}
+
+	used := upstreamShards(plan)
+	depths := shardDepths(plan)
+	pipeline := &gitlabPipeline{stages: stageNames(depths)}
+	for _, shard := range plan.Shards {
+		job := gitlabShardJob(plan, opts, shard, shard.Specs(), true)
+		job.Stage = stageName(depths[shard.ID])
+		job.Needs = []gitlabNeed{}
+		for _, id := range shard.Upstream {
+			job.Needs = append(job.Needs, gitlabNeed{Job: id, Artifacts: len(plan.shard(id).Outputs()) > 0})
+		}
+		job.Rules = gitlabRules(plan)
+		if outputs := shard.Outputs(); len(outputs) > 0 && used[shard.ID] {
+			job.Artifacts = &gitlabArtifacts{Paths: outputs, ExpireIn: "1 day"}
+		}
+		pipeline.add(shard.ID, job)
+	}
+
+	data, err := encodeYAML("gitlab", pipeline)
+	if err != nil {
+		return nil, err
+	}
+	return []File{{Data: data}}, nil
+}
+
+// gitlabChildPipelines renders a parent pipeline of trigger jobs and a child
+// pipeline per shard. GitLab cannot pass artifacts between child
+// pipelines, so tasks depending on another shard run with their
+// dependencies, relying on the doctrus cache to skip unchanged ones.
+func gitlabChildPipelines(plan *Plan, opts Options) ([]File, error) {
+	childDir := opts.ChildDir
+	if childDir == "" {
+		childDir = ".gitlab/doctrus"
+	}
+
+	depths := shardDepths(plan)
+	parent := &gitlabPipeline{stages: stageNames(depths)}
+	var children []File
+	for _, shard := range plan.Shards {
+		childPath := path.Join(childDir, shard.ID+".yml")
+		trigger := &gitlabJob{
+			Stage:   stageName(depths[shard.ID]),
+			Needs:   []gitlabNeed{},
+			Rules:   gitlabRules(plan),
+			Trigger: &gitlabTrigger{Include: childPath, Strategy: "depend"},
+		}
+		for _, id := range shard.Needs {
+			trigger.Needs = append(trigger.Needs, gitlabNeed{Job: id})
+		}
+		parent.add(shard.ID, trigger)
+
+		data, err := encodeYAML("gitlab", gitlabChildPipeline(plan, opts, shard))
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, File{Path: childPath, Data: data})
+	}
+
+	data, err := encodeYAML("gitlab", parent)
+	if err != nil {
+		return nil, err
+	}
+	return append([]File{{Data: data}}, children...), nil
+}
+
+// gitlabChildPipeline renders a job per task of shard, in stages following
+// the dependencies between them.
+func gitlabChildPipeline(plan *Plan, opts Options, shard *Shard) *gitlabPipeline {
+	inShard := make(map[string]*Task, len(shard.Tasks))
+	for _, task := range shard.Tasks {
+		inShard[task.Key] = task
+	}
+
+	// Tasks are in dependency order, so dependencies are visited first
+	depths := make(map[string]int)
+	closures := make(map[string][]string)
+	external := make(map[string]bool)
+	used := make(map[string]bool)
+	maxDepth := 0
+	for _, task := range shard.Tasks {
+		seen := make(map[string]bool)
+		for _, dep := range task.Deps {
+			if _, ok := inShard[dep]; !ok {
+				external[task.Key] = true
+				continue
+			}
+			depths[task.Key] = max(depths[task.Key], depths[dep]+1)
+			external[task.Key] = external[task.Key] || external[dep]
+			seen[dep] = true
+			for _, key := range closures[dep] {
+				seen[key] = true
+			}
+		}
+		for key := range seen {
+			used[key] = true
+		}
+		closures[task.Key] = sortedSet(seen)
+		maxDepth = max(maxDepth, depths[task.Key])
+	}
+
+	stages := make([]string, maxDepth+1)
+	for i := range stages {
+		stages[i] = stageName(i)
+	}
+	pipeline := &gitlabPipeline{stages: stages}
+	for _, task := range shard.Tasks {
+		// A task whose dependencies all ran in this pipeline receives their
+		// outputs as artifacts; otherwise it runs them itself
+		job := gitlabShardJob(plan, opts, shard, []string{task.Key}, !external[task.Key])
+		job.Stage = stageName(depths[task.Key])
+		job.Cache.Key = "doctrus-" + strings.ReplaceAll(task.Key, ":", "-")
+		job.Cache.Paths = append([]string{plan.CacheDir()}, task.Outputs...)
+		job.Needs = []gitlabNeed{}
+		for _, key := range closures[task.Key] {
+			job.Needs = append(job.Needs, gitlabNeed{Job: key, Artifacts: len(inShard[key].Outputs) > 0})
+		}
+		if len(task.Outputs) > 0 && used[task.Key] {
+			job.Artifacts = &gitlabArtifacts{Paths: task.Outputs, ExpireIn: "1 day"}
+		}
+		pipeline.add(task.Key, job)
+	}
+	return pipeline
+}
+
+// gitlabShardJob returns a job running specs on the shard's runners, caching
+// .doctrus/cache with the shard's outputs.
+func gitlabShardJob(plan *Plan, opts Options, shard *Shard, specs []string, noDeps bool) *gitlabJob {
+	script := strings.Split(installScript(opts.Version), "\n")
+	script = append(script, `export PATH="$HOME/.local/bin:$PATH"`, runCommand(plan, specs, noDeps))
+	return &gitlabJob{
+		Image:  opts.RunsOn,
+		Tags:   shard.Tags,
+		Script: script,
+		Cache: &gitlabCache{
+			Key:   "doctrus-" + shard.ID,
+			Paths: append([]string{plan.CacheDir()}, shard.Outputs()...),
+		},
+	}
+}
+
+func gitlabRules(plan *Plan) []gitlabRule {
+	if len(plan.Paths) == 0 {
+		return nil
+	}
+	return []gitlabRule{{Changes: plan.Paths}}
+}
+
+// shardDepths returns the length of the longest chain of needs before each
+// shard.
+func shardDepths(plan *Plan) map[string]int {
+	depths := make(map[string]int, len(plan.Shards))
+	for _, shard := range plan.Shards {
+		for _, id := range shard.Needs {
+			depths[shard.ID] = max(depths[shard.ID], depths[id]+1)
+		}
+	}
+	return depths
+}
+
+func stageNames(depths map[string]int) []string {
+	maxDepth := 0
+	for _, depth := range depths {
+		maxDepth = max(maxDepth, depth)
+	}
+	stages := make([]string, maxDepth+1)
+	for i := range stages {
+		stages[i] = stageName(i)
+	}
+	return stages
+}
+
+func stageName(depth int) string {
+	return fmt.Sprintf("doctrus-%d", depth+1)
+}
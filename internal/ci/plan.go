@@ -0,0 +1,414 @@
+// Package ci generates CI pipeline definitions from the task graph, so
+// pipelines stay in sync with doctrus.yml.
+package ci
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+// Task is a task of the plan.
+type Task struct {
+	Key       string
+	Workspace string
+	Name      string
+	// Deps are the workspace:task keys of the direct dependencies
+	Deps []string
+	// Outputs are the declared output globs relative to the repository root
+	Outputs []string
+}
+
+// Shard is a group of tasks run by one CI job: the tasks of one workspace,
+// or of several workspaces whose tasks depend on each other in both
+// directions. Tasks are in dependency order. Needs lists the shards that
+// must finish first and Upstream every shard before it, whose outputs the
+// job downloads.
+type Shard struct {
+	ID         string
+	Workspaces []string
+	// Tags are the tags of the shard's workspaces
+	Tags     []string
+	Tasks    []*Task
+	Needs    []string
+	Upstream []string
+}
+
+// shard returns the shard with the given ID.
+func (p *Plan) shard(id string) *Shard {
+	for _, shard := range p.Shards {
+		if shard.ID == id {
+			return shard
+		}
+	}
+	return nil
+}
+
+// upstreamShards returns the IDs of the shards other shards depend on.
+func upstreamShards(plan *Plan) map[string]bool {
+	used := make(map[string]bool)
+	for _, shard := range plan.Shards {
+		for _, id := range shard.Upstream {
+			used[id] = true
+		}
+	}
+	return used
+}
+
+// Outputs returns the output globs of the shard's tasks.
+func (s *Shard) Outputs() []string {
+	var outputs []string
+	for _, task := range s.Tasks {
+		outputs = append(outputs, task.Outputs...)
+	}
+	return outputs
+}
+
+// Specs returns the shard's tasks as workspace:task specs.
+func (s *Shard) Specs() []string {
+	specs := make([]string, len(s.Tasks))
+	for i, task := range s.Tasks {
+		specs[i] = task.Key
+	}
+	return specs
+}
+
+// Plan is the task graph split into shards, in dependency order.
+type Plan struct {
+	// Root is the directory of doctrus.yml relative to the repository root
+	Root string
+	// ConfigFile is doctrus.yml relative to the repository root
+	ConfigFile string
+	Shards     []*Shard
+	// Paths are the repository paths whose changes affect the plan; empty
+	// when any change does
+	Paths []string
+}
+
+// CacheDir is the doctrus cache directory relative to the repository root.
+func (p *Plan) CacheDir() string {
+	return path.Join(p.Root, ".doctrus", "cache")
+}
+
+// NewPlan builds the plan for the tasks named by specs and their
+// dependencies, or for every task when specs is empty. configFile is the
+// path of doctrus.yml relative to the repository root. Specs are
+// "workspace:task", or a bare "task", ":task" or "*:task" for every workspace
+// defining it.
+func NewPlan(cfg *config.Config, manager *workspace.Manager, configFile string, specs []string) (*Plan, error) {
+	configFile = filepath.ToSlash(configFile)
+	plan := &Plan{Root: path.Dir(configFile), ConfigFile: configFile}
+
+	roots, err := rootKeys(cfg, manager, specs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolving each root checks for unknown tasks and cycles; the union of
+	// the results is every task of the plan in dependency order
+	tasks := make(map[string]*Task)
+	var order []string
+	for _, key := range roots {
+		workspaceName, taskName, _ := strings.Cut(key, ":")
+		executions, err := manager.ResolveDependencies(workspaceName, taskName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve dependencies of %s: %w", key, err)
+		}
+		for _, execution := range executions {
+			taskKey := execution.WorkspaceName + ":" + execution.TaskName
+			if tasks[taskKey] != nil {
+				continue
+			}
+			deps, err := manager.Dependencies(execution.WorkspaceName, execution.TaskName)
+			if err != nil {
+				return nil, err
+			}
+			task := &Task{Key: taskKey, Workspace: execution.WorkspaceName, Name: execution.TaskName, Deps: deps}
+			for _, output := range execution.Task.Outputs {
+				task.Outputs = append(task.Outputs, plan.repoPath(execution.Workspace.Path, output))
+			}
+			tasks[taskKey] = task
+			order = append(order, taskKey)
+		}
+	}
+
+	plan.Shards = shardTasks(tasks, order)
+	for _, shard := range plan.Shards {
+		tags := make(map[string]bool)
+		for _, workspaceName := range shard.Workspaces {
+			ws, _ := cfg.GetWorkspace(workspaceName)
+			for _, tag := range ws.Tags {
+				tags[tag] = true
+			}
+		}
+		if len(tags) > 0 {
+			shard.Tags = sortedSet(tags)
+		}
+	}
+	plan.Paths = plan.triggerPaths(cfg, tasks)
+	return plan, nil
+}
+
+// rootKeys expands specs into workspace:task keys.
+func rootKeys(cfg *config.Config, manager *workspace.Manager, specs []string) ([]string, error) {
+	if len(specs) == 0 {
+		var keys []string
+		for _, workspaceName := range manager.GetWorkspaces() {
+			taskNames, err := manager.GetTasks(workspaceName)
+			if err != nil {
+				return nil, err
+			}
+			for _, taskName := range taskNames {
+				keys = append(keys, workspaceName+":"+taskName)
+			}
+		}
+		return keys, nil
+	}
+
+	var keys []string
+	for _, spec := range specs {
+		if taskName, ok := config.AllWorkspacesTask(spec); ok {
+			spec = taskName
+		}
+		if workspaceName, taskName, ok := strings.Cut(spec, ":"); ok {
+			if _, exists := cfg.GetTask(workspaceName, taskName); !exists {
+				if _, exists := cfg.GetWorkspace(workspaceName); !exists {
+					return nil, &workspace.WorkspaceNotFoundError{Workspace: workspaceName}
+				}
+				return nil, &workspace.TaskNotFoundError{Workspace: workspaceName, Task: taskName}
+			}
+			keys = append(keys, workspaceName+":"+cfg.TaskName(workspaceName, taskName))
+			continue
+		}
+		found := false
+		for _, workspaceName := range manager.GetWorkspaces() {
+			if _, exists := cfg.GetTask(workspaceName, spec); exists {
+				keys = append(keys, workspaceName+":"+cfg.TaskName(workspaceName, spec))
+				found = true
+			}
+		}
+		if !found {
+			return nil, &workspace.TaskNotFoundError{Task: spec}
+		}
+	}
+	return keys, nil
+}
+
+// shardTasks groups the tasks by workspace, merging workspaces whose tasks
+// depend on each other in both directions so the shards form a DAG. order
+// lists the tasks in dependency order.
+func shardTasks(tasks map[string]*Task, order []string) []*Shard {
+	// Workspace graph: an edge from a workspace to those it depends on
+	edges := make(map[string]map[string]bool)
+	var workspaces []string
+	for _, key := range order {
+		task := tasks[key]
+		if edges[task.Workspace] == nil {
+			edges[task.Workspace] = make(map[string]bool)
+			workspaces = append(workspaces, task.Workspace)
+		}
+		for _, dep := range task.Deps {
+			if depWorkspace := tasks[dep].Workspace; depWorkspace != task.Workspace {
+				edges[task.Workspace][depWorkspace] = true
+			}
+		}
+	}
+	sort.Strings(workspaces)
+
+	components := stronglyConnected(workspaces, edges)
+	shardOf := make(map[string]*Shard)
+	// Keys with a meaning of their own in pipeline files can't name jobs
+	usedIDs := map[string]bool{
+		"default": true, "include": true, "stages": true, "variables": true, "workflow": true,
+		"image": true, "services": true, "cache": true, "before_script": true, "after_script": true,
+		"pages": true,
+	}
+	var shards []*Shard
+	for _, component := range components {
+		shard := &Shard{Workspaces: component, ID: uniqueID(strings.Join(component, "-"), usedIDs)}
+		for _, workspaceName := range component {
+			shardOf[workspaceName] = shard
+		}
+		shards = append(shards, shard)
+	}
+
+	for _, key := range order {
+		shard := shardOf[tasks[key].Workspace]
+		shard.Tasks = append(shard.Tasks, tasks[key])
+	}
+
+	// Tarjan's algorithm returns the components dependencies first, so the
+	// upstream shards of each shard are known when it is reached
+	upstream := make(map[*Shard]map[string]bool)
+	for _, shard := range shards {
+		needs := make(map[string]bool)
+		all := make(map[string]bool)
+		for _, task := range shard.Tasks {
+			for _, dep := range task.Deps {
+				depShard := shardOf[tasks[dep].Workspace]
+				if depShard == shard {
+					continue
+				}
+				needs[depShard.ID] = true
+				all[depShard.ID] = true
+				for id := range upstream[depShard] {
+					all[id] = true
+				}
+			}
+		}
+		upstream[shard] = all
+		shard.Needs = sortedSet(needs)
+		shard.Upstream = sortedSet(all)
+	}
+
+	return shards
+}
+
+// stronglyConnected returns the strongly connected components of the
+// workspace graph with Tarjan's algorithm, each sorted, with every component
+// listed after the components it has edges to.
+func stronglyConnected(nodes []string, edges map[string]map[string]bool) [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+	next := 0
+
+	var visit func(node string)
+	visit = func(node string) {
+		index[node] = next
+		low[node] = next
+		next++
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, target := range sortedSet(edges[node]) {
+			if _, seen := index[target]; !seen {
+				visit(target)
+				low[node] = min(low[node], low[target])
+			} else if onStack[target] {
+				low[node] = min(low[node], index[target])
+			}
+		}
+
+		if low[node] == index[node] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == node {
+					break
+				}
+			}
+			sort.Strings(component)
+			components = append(components, component)
+		}
+	}
+
+	for _, node := range nodes {
+		if _, seen := index[node]; !seen {
+			visit(node)
+		}
+	}
+	return components
+}
+
+// uniqueID turns name into a job identifier of letters, digits, - and _
+// that starts with a letter or _, adding a numeric suffix on collisions.
+func uniqueID(name string, used map[string]bool) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	id := b.String()
+	if id == "" || !(id[0] == '_' || (id[0] >= 'a' && id[0] <= 'z') || (id[0] >= 'A' && id[0] <= 'Z')) {
+		id = "ws-" + id
+	}
+
+	candidate := id
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d", id, i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// triggerPaths returns the paths whose changes affect the plan: doctrus.yml
+// and the files it includes, the env files, the directory of every workspace
+// in the plan and inputs outside it. It returns nil when a workspace covers
+// the whole repository.
+func (p *Plan) triggerPaths(cfg *config.Config, tasks map[string]*Task) []string {
+	paths := map[string]bool{p.ConfigFile: true}
+	for _, file := range cfg.IncludedFiles() {
+		paths[p.repoPath("", file)] = true
+	}
+	for _, file := range cfg.EnvFile {
+		paths[p.repoPath("", file)] = true
+	}
+
+	for _, task := range tasks {
+		ws, _ := cfg.GetWorkspace(task.Workspace)
+		dir := p.repoPath(ws.Path, "")
+		if dir == "." || strings.HasPrefix(dir, "../") || dir == ".." {
+			return nil
+		}
+		paths[dir+"/**"] = true
+		for _, file := range ws.EnvFile {
+			paths[p.repoPath(ws.Path, file)] = true
+		}
+
+		taskDef, _ := cfg.GetTask(task.Workspace, task.Name)
+		for _, file := range taskDef.EnvFile {
+			paths[p.repoPath(ws.Path, file)] = true
+		}
+		for _, input := range taskDef.Inputs {
+			input = p.repoPath(ws.Path, input)
+			if strings.HasPrefix(input, "../") || input == "." || strings.HasPrefix(input, "**") {
+				return nil
+			}
+			if !strings.HasPrefix(input, dir+"/") {
+				paths[input] = true
+			}
+		}
+	}
+
+	// Drop paths inside the directories already listed
+	for candidate := range paths {
+		for dir := candidate; dir != "." && dir != "/"; {
+			dir = path.Dir(dir)
+			if covering := dir + "/**"; covering != candidate && paths[covering] {
+				delete(paths, candidate)
+				break
+			}
+		}
+	}
+	return sortedSet(paths)
+}
+
+// repoPath joins a workspace path and a pattern relative to it onto the
+// plan's root.
+func (p *Plan) repoPath(workspacePath, pattern string) string {
+	return path.Clean(path.Join(p.Root, filepath.ToSlash(workspacePath), filepath.ToSlash(pattern)))
+}
+
+func sortedSet(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/agent"
+	"doctrus/internal/docker"
+)
+
+var (
+	agentListen string
+	agentJobs   int
+)
+
+func newAgentCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Run tasks sent by other machines",
+		Long: `Start a doctrus agent, which runs the tasks of projects using executor: remote.
+Every job runs in a scratch directory holding the task's input files, and its
+output and output files are streamed back. Clients must send the token in
+DOCTRUS_AGENT_TOKEN; without one set the agent accepts every job, so it
+only listens on a loopback address.
+
+Examples:
+  doctrus agent                           # Listen on 127.0.0.1:7070
+  DOCTRUS_AGENT_TOKEN=secret doctrus agent --listen 10.0.0.5:7070 --jobs 4`,
+		Args: cobra.NoArgs,
+		RunE: runAgent,
+	}
+
+	cmd.Flags().StringVar(&agentListen, "listen", "127.0.0.1:7070", "Address to listen on; addresses other than loopback need DOCTRUS_AGENT_TOKEN")
+	cmd.Flags().IntVar(&agentJobs, "jobs", 0, "Maximum number of jobs to run at once (0 for no limit)")
+
+	return cmd
+}
+
+func runAgent(cmd *cobra.Command, args []string) error {
+	if agentJobs < 0 {
+		return fmt.Errorf("--jobs must not be negative")
+	}
+	stderr := cmd.ErrOrStderr()
+	token := os.Getenv(agent.TokenEnv)
+	if err := agent.CheckListen(agentListen, token); err != nil {
+		return err
+	}
+	if token == "" {
+		fmt.Fprintf(stderr, "Warning: %s is not set, the agent accepts jobs from every local user\n", agent.TokenEnv)
+	}
+
+	server := &http.Server{
+		Addr: agentListen,
+		Handler: agent.NewServer(token, agentJobs, func(format string, args ...any) {
+			fmt.Fprintf(stderr, format, args...)
+		}),
+	}
+
+	ctx, stop := docker.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(stderr, "Doctrus agent listening on %s\n", agentListen)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("agent failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/docker"
+	"doctrus/internal/ui"
+	"doctrus/internal/workspace"
+)
+
+var (
+	benchRuns   int
+	benchWarmup int
+)
+
+func newBenchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench workspace:task",
+		Short: "Measure how long a task takes",
+		Long: `Run a task repeatedly and report its min, median, p95 and max durations and
+how much they vary, for measuring the impact of build configuration changes.
+
+Every run executes the task's command: the cache is neither checked nor
+updated, and runs are not recorded in the history. The task's dependencies
+run once beforehand, using the cache as usual.
+
+Examples:
+  doctrus bench backend:build                     # 10 runs
+  doctrus bench backend:build --runs 30 --warmup 2
+  doctrus bench frontend:test --no-deps`,
+		Args: cobra.ExactArgs(1),
+		RunE: runBench,
+	}
+
+	cmd.Flags().IntVarP(&benchRuns, "runs", "n", 10, "Number of measured runs")
+	cmd.Flags().IntVar(&benchWarmup, "warmup", 0, "Number of runs before measuring, such as to warm up file system caches")
+	cmd.Flags().BoolVar(&noDeps, "no-deps", false, "Do not run the task's dependencies first")
+
+	return cmd
+}
+
+// benchSample is what one run of a benchmarked task took.
+type benchSample struct {
+	Duration time.Duration
+	CPUTime  time.Duration
+	PeakRSS  int64
+}
+
+// benchSummary describes the durations of a benchmark's runs.
+type benchSummary struct {
+	Runs   int
+	Min    time.Duration
+	Median time.Duration
+	P95    time.Duration
+	Max    time.Duration
+	Mean   time.Duration
+	// StdDev is the sample standard deviation of the durations
+	StdDev time.Duration
+	// AverageCPU is the average CPU time of the runs it was measured for,
+	// and PeakRSS the largest resident set size of any run
+	AverageCPU time.Duration
+	PeakRSS    int64
+}
+
+func runBench(cmd *cobra.Command, args []string) error {
+	if benchRuns < 1 {
+		return fmt.Errorf("--runs must be at least 1")
+	}
+	if benchWarmup < 0 {
+		return fmt.Errorf("--warmup must not be negative")
+	}
+
+	cli, err := newScopedCLI(args)
+	if err != nil {
+		return err
+	}
+	defer cli.cleanup()
+
+	targets, err := cli.resolveTargets(args)
+	if err != nil {
+		return err
+	}
+	if len(targets) != 1 {
+		return fmt.Errorf("%s names a task in %d workspaces; bench measures one, given as workspace:task", args[0], len(targets))
+	}
+	target := targets[0]
+	execution, err := cli.workspace.ResolveTaskExecution(target.workspace, target.task)
+	if err != nil {
+		return err
+	}
+	taskKey := target.workspace + ":" + target.task
+	if len(execution.Task.Command) == 0 {
+		return fmt.Errorf("%s has no command to measure", taskKey)
+	}
+	if err := cli.workspace.ValidateExecutions([]*workspace.TaskExecution{execution}); err != nil {
+		return fmt.Errorf("workspace validation failed: %w", err)
+	}
+
+	ctx, cancel := docker.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := cli.ensurePreRunCommands(ctx); err != nil {
+		return err
+	}
+	if !noDeps {
+		deps, err := cli.collectDependencies(target.workspace, execution.Task)
+		if err != nil {
+			return err
+		}
+		if len(deps) > 0 {
+			runner := newTaskRunner(cli)
+			if runner.Slots, err = cli.parallelSlots(); err != nil {
+				return err
+			}
+			if err := cli.runTargets(ctx, runner, deps); err != nil {
+				return err
+			}
+		}
+	}
+
+	lease, err := cli.acquireLock(ctx, []*workspace.TaskExecution{execution})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lease.Release(); err != nil {
+			cli.log.Warnf("Warning: failed to release run lock: %v\n", err)
+		}
+	}()
+
+	samples, err := cli.benchmark(ctx, execution, benchRuns, benchWarmup)
+	if err != nil {
+		return err
+	}
+	return cli.printBench(taskKey, summarizeBench(samples))
+}
+
+// benchmark runs execution warmup times and then runs times, returning what
+// the measured runs took. It stops at the first failing run.
+func (c *CLI) benchmark(ctx context.Context, execution *workspace.TaskExecution, runs, warmup int) ([]benchSample, error) {
+	taskKey := execution.WorkspaceName + ":" + execution.TaskName
+	c.log.Infof("%s\n", c.term.Fit(c.ui.Status(ui.KindHeader, fmt.Sprintf("Benchmarking %s (%d %s)", taskKey, runs, plural(runs, "run", "runs")))))
+
+	runner := c.runner()
+	samples := make([]benchSample, 0, runs)
+	for i := 0; i < warmup+runs; i++ {
+		label := fmt.Sprintf("Run %d/%d", i-warmup+1, runs)
+		if i < warmup {
+			label = fmt.Sprintf("Warmup %d/%d", i+1, warmup)
+		}
+
+		statusLabel := fmt.Sprintf("Benchmarking %s · %s", taskKey, label)
+		c.status.Start(statusLabel)
+		taskCtx, stop := c.tasks.Start(ctx, taskKey, runner.TaskTimeout(execution.Task))
+		start := time.Now()
+		result := c.executor.Execute(taskCtx, execution, nil, nil)
+		took := time.Since(start)
+		stop()
+		c.status.Done(statusLabel)
+		finallyErr := runner.Finally(ctx, execution, nil, nil, c.finallyHooks(taskKey, false, false))
+
+		if result.Error != nil && result.ExitCode == 0 {
+			return nil, fmt.Errorf("execution error: %w", result.Error)
+		}
+		if result.ExitCode != 0 {
+			if result.Stdout != "" {
+				c.printBufferedOutput(taskKey, "stdout", result.Stdout, false)
+			}
+			if result.Stderr != "" {
+				c.printBufferedOutput(taskKey, "stderr", result.Stderr, false)
+			}
+			c.log.Errorf("  %s\n", c.ui.Status(ui.KindFailure, fmt.Sprintf("%s failed with exit code %d in %v", label, result.ExitCode, benchDuration(took))))
+			return nil, &workspace.TaskFailedError{
+				Workspace: execution.WorkspaceName,
+				Task:      execution.TaskName,
+				ExitCode:  result.ExitCode,
+				Cause:     result.Cause,
+			}
+		}
+		if finallyErr != nil {
+			return nil, finallyErr
+		}
+
+		message := fmt.Sprintf("%s: %v", label, benchDuration(took))
+		if usage := formatUsage(result.Usage); usage != "" {
+			message += fmt.Sprintf(" (%s)", usage)
+		}
+		c.log.Infof("  %s\n", message)
+		if i >= warmup {
+			samples = append(samples, benchSample{Duration: took, CPUTime: result.Usage.CPUTime, PeakRSS: result.Usage.PeakRSS})
+		}
+	}
+	return samples, nil
+}
+
+// summarizeBench computes the statistics of a benchmark's samples. The p95 is
+// the nearest-rank percentile, so with fewer than 20 runs it is the slowest.
+func summarizeBench(samples []benchSample) benchSummary {
+	summary := benchSummary{Runs: len(samples)}
+	if len(samples) == 0 {
+		return summary
+	}
+
+	durations := make([]time.Duration, len(samples))
+	var total, cpuTotal time.Duration
+	cpuSamples := 0
+	for i, sample := range samples {
+		durations[i] = sample.Duration
+		total += sample.Duration
+		if sample.CPUTime > 0 {
+			cpuTotal += sample.CPUTime
+			cpuSamples++
+		}
+		summary.PeakRSS = max(summary.PeakRSS, sample.PeakRSS)
+	}
+	slices.Sort(durations)
+
+	n := len(durations)
+	summary.Min = durations[0]
+	summary.Max = durations[n-1]
+	summary.Median = durations[n/2]
+	if n%2 == 0 {
+		summary.Median = (durations[n/2-1] + durations[n/2]) / 2
+	}
+	summary.P95 = durations[int(math.Ceil(0.95*float64(n)))-1]
+	summary.Mean = total / time.Duration(n)
+	if cpuSamples > 0 {
+		summary.AverageCPU = cpuTotal / time.Duration(cpuSamples)
+	}
+
+	if n > 1 {
+		var squares float64
+		for _, d := range durations {
+			diff := float64(d - summary.Mean)
+			squares += diff * diff
+		}
+		summary.StdDev = time.Duration(math.Sqrt(squares / float64(n-1)))
+	}
+	return summary
+}
+
+// printBench prints the statistics of a benchmark of taskKey.
+func (c *CLI) printBench(taskKey string, s benchSummary) error {
+	c.outputMu.Lock()
+	defer c.outputMu.Unlock()
+
+	out := c.output()
+	fmt.Fprintf(out, "\n%s over %d %s:\n", taskKey, s.Runs, plural(s.Runs, "run", "runs"))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  min\t%v\n", benchDuration(s.Min))
+	fmt.Fprintf(w, "  median\t%v\n", benchDuration(s.Median))
+	fmt.Fprintf(w, "  p95\t%v\n", benchDuration(s.P95))
+	fmt.Fprintf(w, "  max\t%v\n", benchDuration(s.Max))
+	mean := fmt.Sprintf("%v", benchDuration(s.Mean))
+	if s.Runs > 1 && s.Mean > 0 {
+		mean += fmt.Sprintf(" ± %v (%.1f%%)", benchDuration(s.StdDev), 100*float64(s.StdDev)/float64(s.Mean))
+	}
+	fmt.Fprintf(w, "  mean\t%s\n", mean)
+	if s.AverageCPU > 0 {
+		fmt.Fprintf(w, "  cpu\t%v average\n", benchDuration(s.AverageCPU))
+	}
+	if s.PeakRSS > 0 {
+		fmt.Fprintf(w, "  memory\t%s peak\n", formatBytes(s.PeakRSS))
+	}
+	return w.Flush()
+}
+
+// benchDuration rounds d to three significant digits, which keeps short tasks
+// readable without hiding their differences.
+func benchDuration(d time.Duration) time.Duration {
+	unit := time.Duration(1)
+	for d/unit >= 1000 {
+		unit *= 10
+	}
+	return d.Round(unit)
+}
@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/docker"
+	"doctrus/internal/logging"
+	"doctrus/internal/workspace"
+)
+
+func TestSummarizeBench(t *testing.T) {
+	ms := func(values ...int) []benchSample {
+		samples := make([]benchSample, len(values))
+		for i, v := range values {
+			samples[i] = benchSample{Duration: time.Duration(v) * time.Millisecond}
+		}
+		return samples
+	}
+
+	tests := []struct {
+		name    string
+		samples []benchSample
+		want    benchSummary
+	}{
+		{
+			name:    "single run",
+			samples: ms(100),
+			want:    benchSummary{Runs: 1, Min: 100 * time.Millisecond, Median: 100 * time.Millisecond, P95: 100 * time.Millisecond, Max: 100 * time.Millisecond, Mean: 100 * time.Millisecond},
+		},
+		{
+			name:    "even number of runs",
+			samples: ms(400, 100, 300, 200),
+			want: benchSummary{
+				Runs:   4,
+				Min:    100 * time.Millisecond,
+				Median: 250 * time.Millisecond,
+				P95:    400 * time.Millisecond,
+				Max:    400 * time.Millisecond,
+				Mean:   250 * time.Millisecond,
+				StdDev: time.Duration(129099444),
+			},
+		},
+		{
+			name:    "p95 below the slowest",
+			samples: ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 100),
+			want: benchSummary{
+				Runs:   21,
+				Min:    1 * time.Millisecond,
+				Median: 11 * time.Millisecond,
+				P95:    20 * time.Millisecond,
+				Max:    100 * time.Millisecond,
+				Mean:   time.Duration(14761904),
+				StdDev: time.Duration(20363950),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarizeBench(tt.samples)
+			if got != tt.want {
+				t.Errorf("summarizeBench() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("resources", func(t *testing.T) {
+		got := summarizeBench([]benchSample{
+			{Duration: time.Second, CPUTime: 2 * time.Second, PeakRSS: 100},
+			{Duration: time.Second, PeakRSS: 300},
+			{Duration: time.Second, CPUTime: 4 * time.Second, PeakRSS: 200},
+		})
+		if got.AverageCPU != 3*time.Second || got.PeakRSS != 300 {
+			t.Errorf("AverageCPU, PeakRSS = %v, %d; want 3s, 300", got.AverageCPU, got.PeakRSS)
+		}
+	})
+}
+
+func TestBenchDuration(t *testing.T) {
+	tests := []struct {
+		in, want time.Duration
+	}{
+		{in: 999, want: 999},
+		{in: 1234567, want: 1230000},
+		{in: 202345678, want: 202 * time.Millisecond},
+		{in: 61234567890, want: 61200 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := benchDuration(tt.in); got != tt.want {
+			t.Errorf("benchDuration(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBenchmarkBypassesCache(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell commands not available on Windows")
+	}
+
+	tests := []struct {
+		name     string
+		command  string
+		wantErr  bool
+		wantRuns int
+		want     []string
+	}{
+		{
+			name:     "measures every run",
+			command:  "echo run >> runs.txt",
+			wantRuns: 4,
+			want:     []string{"Benchmarking app:build (3 runs)", "Warmup 1/1:", "Run 3/3:"},
+		},
+		{
+			name:     "stops at a failing run",
+			command:  "echo run >> runs.txt; echo broken >&2; exit 2",
+			wantErr:  true,
+			wantRuns: 1,
+			want:     []string{"broken", "Warmup 1/1 failed with exit code 2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			cfg := &config.Config{
+				Version: "1.0",
+				Workspaces: map[string]config.Workspace{
+					"app": {
+						Path: tempDir,
+						Tasks: map[string]config.Task{
+							"build": {Command: []string{"sh", "-c", tt.command}, Cache: true},
+						},
+					},
+				},
+			}
+			var out bytes.Buffer
+			c := &CLI{
+				config:    cfg,
+				workspace: workspace.NewManager(cfg, tempDir),
+				executor:  docker.NewExecutor(cfg, tempDir),
+				tracker:   deps.NewTracker(tempDir),
+				cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+				log:       logging.New(&out, logging.LevelInfo, nil),
+				stdout:    &out,
+				basePath:  tempDir,
+			}
+			execution, err := c.workspace.ResolveTaskExecution("app", "build")
+			if err != nil {
+				t.Fatalf("ResolveTaskExecution() error = %v", err)
+			}
+
+			samples, err := c.benchmark(context.Background(), execution, 3, 1)
+			if tt.wantErr {
+				var failed *workspace.TaskFailedError
+				if !errors.As(err, &failed) || failed.ExitCode != 2 {
+					t.Fatalf("benchmark() error = %v, want a task failure with exit code 2", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("benchmark() error = %v", err)
+				}
+				if len(samples) != 3 {
+					t.Errorf("benchmark() returned %d samples, want 3", len(samples))
+				}
+			}
+
+			data, err := os.ReadFile(filepath.Join(tempDir, "runs.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Count(string(data), "run\n"); got != tt.wantRuns {
+				t.Errorf("task ran %d times, want %d", got, tt.wantRuns)
+			}
+			if state, _ := c.cache.Get("app:build"); state != nil {
+				t.Error("benchmark cached the task")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
@@ -1,10 +1,21 @@
 package cli
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/history"
 )
 
 func newCacheCommand() *cobra.Command {
@@ -18,6 +29,10 @@ func newCacheCommand() *cobra.Command {
 		newCacheClearCommand(),
 		newCacheStatsCommand(),
 		newCacheListCommand(),
+		newCachePruneCommand(),
+		newCacheExportCommand(),
+		newCacheImportCommand(),
+		newCacheProvenanceCommand(),
 	)
 
 	return cmd
@@ -35,14 +50,20 @@ func newCacheClearCommand() *cobra.Command {
 	return cmd
 }
 
+var cacheStatsSort string
+
 func newCacheStatsCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "stats",
 		Short: "Show cache statistics",
-		Long:  "Display cache usage statistics",
-		RunE:  showCacheStats,
+		Long: `Display cache usage statistics, broken down per workspace: entries, disk
+space of the entries, size of the cached outputs, cache hit rate from the run
+history and when the oldest and newest entries were written.`,
+		RunE: showCacheStats,
 	}
 
+	cmd.Flags().StringVar(&cacheStatsSort, "sort", "name", "Order workspaces by name, size, outputs, entries or hits")
+
 	return cmd
 }
 
@@ -57,6 +78,24 @@ func newCacheListCommand() *cobra.Command {
 	return cmd
 }
 
+var verifyProvenance bool
+
+func newCacheProvenanceCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "provenance <workspace:task>",
+		Short: "Show the provenance attestation of cached outputs",
+		Long: `Print the in-toto provenance attestation recorded when the task's outputs
+were last cached (requires cache.provenance in doctrus.yml). With --verify,
+check instead that the output files on disk still match its subjects.`,
+		Args: cobra.ExactArgs(1),
+		RunE: showProvenance,
+	}
+
+	cmd.Flags().BoolVar(&verifyProvenance, "verify", false, "Check the output files against the attestation's digests")
+
+	return cmd
+}
+
 func clearCache(cmd *cobra.Command, args []string) error {
 	cli, err := newCLI()
 	if err != nil {
@@ -73,6 +112,9 @@ func clearCache(cmd *cobra.Command, args []string) error {
 		if err := cli.cache.Clear(); err != nil {
 			return fmt.Errorf("failed to clear cache: %w", err)
 		}
+		if err := cli.cas.Clear(); err != nil {
+			return fmt.Errorf("failed to clear stored outputs: %w", err)
+		}
 		fmt.Println("✓ Cleared all cache")
 	}
 
@@ -98,7 +140,92 @@ func showCacheStats(cmd *cobra.Command, args []string) error {
 	if size, ok := stats["cache_dir_size"]; ok {
 		fmt.Printf("  Directory size: %d bytes\n", size)
 	}
+	if size, err := cli.cas.Size(); err == nil && size > 0 {
+		fmt.Printf("  Stored outputs: %s in %s\n", formatBytes(size), cli.cas.Dir())
+	}
+
+	workspaces, err := cli.cache.WorkspaceStats()
+	if err != nil {
+		return fmt.Errorf("failed to get cache stats: %w", err)
+	}
+	if len(workspaces) == 0 {
+		return nil
+	}
+
+	// Hit rates are best effort: without history they are not shown
+	runs, err := historyStore(cli.basePath).Load()
+	if err != nil {
+		cli.log.Debugf("No cache hit rates: %v\n", err)
+	}
+	hits := workspaceHitRates(runs)
+	if err := sortWorkspaceStats(workspaces, hits, cacheStatsSort); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WORKSPACE\tENTRIES\tSIZE\tOUTPUTS\tHIT RATE\tOLDEST\tNEWEST")
+	for _, ws := range workspaces {
+		hitRate := "-"
+		if rate, ok := hits[ws.Workspace]; ok {
+			hitRate = formatPercent(rate)
+		}
+		entries := fmt.Sprintf("%d", ws.Entries)
+		if ws.Expired > 0 {
+			entries += fmt.Sprintf(" (%d expired)", ws.Expired)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			ws.Workspace,
+			entries,
+			formatBytes(ws.Size),
+			formatBytes(ws.OutputSize),
+			hitRate,
+			ws.Oldest.Local().Format("2006-01-02 15:04"),
+			ws.Newest.Local().Format("2006-01-02 15:04"))
+	}
+	return w.Flush()
+}
+
+// workspaceHitRates returns the fraction of recorded task runs per workspace
+// that were served from cache.
+func workspaceHitRates(runs []history.Run) map[string]float64 {
+	total := make(map[string]int)
+	cached := make(map[string]int)
+	for _, run := range runs {
+		for _, record := range run.Tasks {
+			total[record.Workspace]++
+			if record.Outcome == history.OutcomeCached {
+				cached[record.Workspace]++
+			}
+		}
+	}
 
+	rates := make(map[string]float64, len(total))
+	for workspace, n := range total {
+		rates[workspace] = float64(cached[workspace]) / float64(n)
+	}
+	return rates
+}
+
+// sortWorkspaceStats orders workspaces for --sort: by name, or largest
+// first by size, outputs, entries or hit rate.
+func sortWorkspaceStats(workspaces []cache.WorkspaceStats, hits map[string]float64, by string) error {
+	var less func(a, b cache.WorkspaceStats) bool
+	switch by {
+	case "", "name":
+		return nil
+	case "size":
+		less = func(a, b cache.WorkspaceStats) bool { return a.Size > b.Size }
+	case "outputs":
+		less = func(a, b cache.WorkspaceStats) bool { return a.OutputSize > b.OutputSize }
+	case "entries":
+		less = func(a, b cache.WorkspaceStats) bool { return a.Entries > b.Entries }
+	case "hits":
+		less = func(a, b cache.WorkspaceStats) bool { return hits[a.Workspace] > hits[b.Workspace] }
+	default:
+		return fmt.Errorf("invalid --sort %q (expected name, size, outputs, entries or hits)", by)
+	}
+	sort.SliceStable(workspaces, func(i, j int) bool { return less(workspaces[i], workspaces[j]) })
 	return nil
 }
 
@@ -108,7 +235,7 @@ func listCachedTasks(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	entries, err := cli.cache.List()
+	entries, err := cli.cache.List(cache.ListFilter{})
 	if err != nil {
 		return fmt.Errorf("failed to list cache entries: %w", err)
 	}
@@ -161,4 +288,68 @@ func formatDuration(d time.Duration) string {
 		return fmt.Sprintf("%.1fh", d.Hours())
 	}
 	return fmt.Sprintf("%.1fd", d.Hours()/24)
-}
\ No newline at end of file
+}
+func showProvenance(cmd *cobra.Command, args []string) error {
+	workspaceName, taskName := parseTaskSpec(args[0])
+	if workspaceName == "" {
+		return fmt.Errorf("expected workspace:task, got %q", args[0])
+	}
+
+	cli, err := newScopedCLI(args)
+	if err != nil {
+		return err
+	}
+
+	taskKey := workspaceName + ":" + cli.config.TaskName(workspaceName, taskName)
+	statement, err := cli.cache.GetAttestation(taskKey)
+	if err != nil {
+		return err
+	}
+	if statement == nil {
+		return fmt.Errorf("no provenance recorded for %s", taskKey)
+	}
+
+	if !verifyProvenance {
+		data, err := json.MarshalIndent(statement, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal attestation: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	mismatched := 0
+	for _, subject := range statement.Subject {
+		hash, err := fileSHA256(filepath.Join(cli.basePath, filepath.FromSlash(subject.Name)))
+		switch {
+		case err != nil:
+			fmt.Printf("✗ %s: %v\n", subject.Name, err)
+			mismatched++
+		case hash != subject.Digest["sha256"]:
+			fmt.Printf("✗ %s: digest does not match\n", subject.Name)
+			mismatched++
+		default:
+			fmt.Printf("✓ %s\n", subject.Name)
+		}
+	}
+	if mismatched > 0 {
+		return fmt.Errorf("%d of %d output(s) of %s do not match their provenance", mismatched, len(statement.Subject), taskKey)
+	}
+	fmt.Printf("All %d output(s) of %s match their provenance\n", len(statement.Subject), taskKey)
+	return nil
+}
+
+// fileSHA256 returns the hex sha256 digest of a file's contents.
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"testing"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/history"
+)
+
+func TestSortWorkspaceStats(t *testing.T) {
+	workspaces := func() []cache.WorkspaceStats {
+		return []cache.WorkspaceStats{
+			{Workspace: "api", Entries: 1, Size: 300},
+			{Workspace: "docs", Entries: 5, Size: 100},
+			{Workspace: "web", Entries: 2, Size: 900},
+		}
+	}
+	hits := workspaceHitRates([]history.Run{{Tasks: []history.TaskRecord{
+		{Workspace: "api", Outcome: history.OutcomeSuccess},
+		{Workspace: "docs", Outcome: history.OutcomeCached},
+		{Workspace: "web", Outcome: history.OutcomeCached},
+		{Workspace: "web", Outcome: history.OutcomeFailed},
+	}}})
+	if hits["api"] != 0 || hits["docs"] != 1 || hits["web"] != 0.5 {
+		t.Fatalf("workspaceHitRates() = %v", hits)
+	}
+
+	tests := []struct {
+		by      string
+		want    []string
+		wantErr bool
+	}{
+		{by: "name", want: []string{"api", "docs", "web"}},
+		{by: "size", want: []string{"web", "api", "docs"}},
+		{by: "entries", want: []string{"docs", "web", "api"}},
+		{by: "hits", want: []string{"docs", "web", "api"}},
+		{by: "age", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			stats := workspaces()
+			err := sortWorkspaceStats(stats, hits, tt.by)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sortWorkspaceStats(%q) error = %v, wantErr %v", tt.by, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for i, name := range tt.want {
+				if stats[i].Workspace != name {
+					t.Fatalf("sortWorkspaceStats(%q) order = %+v, want %v", tt.by, stats, tt.want)
+				}
+			}
+		})
+	}
+}
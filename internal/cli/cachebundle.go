@@ -0,0 +1,370 @@
+package cli
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/fsutil"
+)
+
+func newCacheExportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export <file>",
+		Short: "Save the whole cache to an archive",
+		Long: `Write every cache entry, with its logs and attestation, and the stored outputs
+to one archive, so a CI job can hand the cache to a later stage or pipeline
+that restores it with doctrus cache import. The file name picks the format:
+.tar, .tar.gz (or .tgz) and .tar.zst (or .tzst, which needs the zstd
+command).
+
+Examples:
+  doctrus cache export cache.tar.zst
+  doctrus cache export /tmp/doctrus-cache.tar.gz`,
+		Args: cobra.ExactArgs(1),
+		RunE: exportCache,
+	}
+
+	return cmd
+}
+
+func newCacheImportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Restore the cache from an archive",
+		Long: `Restore cache entries and stored outputs from an archive written by doctrus
+cache export. Imported entries replace local entries of the same tasks;
+other local entries are kept.
+
+Examples:
+  doctrus cache import cache.tar.zst`,
+		Args: cobra.ExactArgs(1),
+		RunE: importCache,
+	}
+
+	return cmd
+}
+
+func exportCache(cmd *cobra.Command, args []string) error {
+	cli, err := newCLI()
+	if err != nil {
+		return err
+	}
+	files, err := cli.exportCache(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ Exported %d cache %s to %s\n", files, plural(files, "file", "files"), args[0])
+	return nil
+}
+
+func importCache(cmd *cobra.Command, args []string) error {
+	cli, err := newCLI()
+	if err != nil {
+		return err
+	}
+	files, err := cli.importCache(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ Imported %d cache %s from %s\n", files, plural(files, "file", "files"), args[0])
+	return nil
+}
+
+// bundleDirs maps the top-level directories of a cache archive to the
+// directories they hold: the cache and the content-addressable store.
+func (c *CLI) bundleDirs() map[string]string {
+	dirs := map[string]string{"cache": c.cache.Dir()}
+	if c.cas != nil {
+		dirs["cas"] = c.cas.Dir()
+	}
+	return dirs
+}
+
+// exportCache writes the cache to an archive at target, returning the
+// number of files written. The archive is written next to target and
+// renamed, so a failed export never leaves a truncated archive behind.
+func (c *CLI) exportCache(target string) (int, error) {
+	compression, err := bundleCompression(target)
+	if err != nil {
+		return 0, err
+	}
+	var files int
+	err = fsutil.WriteFileAtomic(target, 0644, func(out io.Writer) error {
+		w, err := compressBundle(out, compression)
+		if err != nil {
+			return err
+		}
+		files, err = writeBundle(w, c.bundleDirs())
+		if closeErr := w.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to compress cache archive: %w", closeErr)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to export cache: %w", err)
+	}
+	return files, nil
+}
+
+// importCache restores the cache from the archive at source, returning the
+// number of files restored.
+func (c *CLI) importCache(source string) (int, error) {
+	compression, err := bundleCompression(source)
+	if err != nil {
+		return 0, err
+	}
+	file, err := os.Open(source)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open cache archive: %w", err)
+	}
+	defer file.Close()
+
+	r, err := decompressBundle(file, compression)
+	if err != nil {
+		return 0, err
+	}
+	files, err := readBundle(r, c.bundleDirs())
+	if closeErr := r.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to decompress cache archive: %w", closeErr)
+	}
+	if err != nil {
+		return files, err
+	}
+
+	// The index of the archive's cache was not exported, and the local one
+	// no longer describes the imported entries
+	if err := c.cache.Reindex(); err != nil {
+		return files, fmt.Errorf("failed to index imported cache: %w", err)
+	}
+	return files, nil
+}
+
+// bundleCompression returns the compression of a cache archive from its
+// name: "gzip", "zstd" or "" for a plain tar.
+func bundleCompression(name string) (string, error) {
+	switch {
+	case strings.HasSuffix(name, ".tar"):
+		return "", nil
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "gzip", nil
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return "zstd", nil
+	}
+	return "", fmt.Errorf("unsupported cache archive %s (expected .tar, .tar.gz or .tar.zst)", name)
+}
+
+// compressBundle wraps w to compress what is written to it. Closing the
+// result flushes it without closing w.
+func compressBundle(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case "gzip":
+		return gzip.NewWriter(w), nil
+	case "zstd":
+		cmd, err := zstdCommand("-q", "-c")
+		if err != nil {
+			return nil, err
+		}
+		cmd.Stdout = w
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to run zstd: %w", err)
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to run zstd: %w", err)
+		}
+		return &zstdWriter{WriteCloser: stdin, cmd: cmd}, nil
+	}
+	return nopWriteCloser{w}, nil
+}
+
+// decompressBundle wraps r to decompress what is read from it.
+func decompressBundle(r io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case "gzip":
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cache archive: %w", err)
+		}
+		return gz, nil
+	case "zstd":
+		cmd, err := zstdCommand("-q", "-d", "-c")
+		if err != nil {
+			return nil, err
+		}
+		cmd.Stdin = r
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to run zstd: %w", err)
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to run zstd: %w", err)
+		}
+		return &zstdReader{ReadCloser: stdout, cmd: cmd}, nil
+	}
+	return io.NopCloser(r), nil
+}
+
+// zstdCommand returns the zstd command, which compresses .tar.zst archives.
+func zstdCommand(args ...string) (*exec.Cmd, error) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		return nil, fmt.Errorf(".tar.zst archives need the zstd command, which is not installed; use .tar.gz instead")
+	}
+	cmd := exec.Command("zstd", args...)
+	cmd.Stderr = os.Stderr
+	return cmd, nil
+}
+
+// zstdWriter writes to a zstd process, which it waits for on Close.
+type zstdWriter struct {
+	io.WriteCloser
+	cmd *exec.Cmd
+}
+
+func (w *zstdWriter) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		return err
+	}
+	return w.cmd.Wait()
+}
+
+// zstdReader reads from a zstd process, which it waits for on Close.
+type zstdReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (r *zstdReader) Close() error {
+	// Drain what is left so zstd can exit
+	_, _ = io.Copy(io.Discard, r.ReadCloser)
+	return r.cmd.Wait()
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// writeBundle writes the regular files of each directory in dirs as a tar
+// stream to w, below the directory's name, returning how many it wrote.
+// Files whose names start with a dot, the cache index and files being
+// written, are skipped.
+func writeBundle(w io.Writer, dirs map[string]string) (int, error) {
+	tw := tar.NewWriter(w)
+	names := make([]string, 0, len(dirs))
+	for name := range dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := 0
+	for _, name := range names {
+		root := dirs[name]
+		err := filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && file == root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if strings.HasPrefix(entry.Name(), ".") || !entry.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return err
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			header := &tar.Header{
+				Name:    path.Join(name, filepath.ToSlash(rel)),
+				Mode:    int64(info.Mode().Perm()),
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			in, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			if _, err := io.Copy(tw, in); err != nil {
+				return err
+			}
+			files++
+			return nil
+		})
+		if err != nil {
+			return files, fmt.Errorf("failed to export %s: %w", name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return files, fmt.Errorf("failed to export cache: %w", err)
+	}
+	return files, nil
+}
+
+// readBundle extracts a tar stream written by writeBundle into dirs,
+// replacing existing files, and returns how many files it wrote. Entries
+// outside the known directories, or that are not regular files, are
+// rejected.
+func readBundle(r io.Reader, dirs map[string]string) (int, error) {
+	tr := tar.NewReader(r)
+	files := 0
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return files, fmt.Errorf("failed to read cache archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+
+		name := path.Clean(header.Name)
+		top, rel, _ := strings.Cut(name, "/")
+		root, ok := dirs[top]
+		if !ok || rel == "" || header.Typeflag != tar.TypeReg || strings.HasPrefix(rel, "../") || path.IsAbs(name) {
+			return files, fmt.Errorf("unexpected entry %s in cache archive", header.Name)
+		}
+		target := filepath.Join(root, filepath.FromSlash(rel))
+		if err := extractBundleFile(tr, target, os.FileMode(header.Mode).Perm(), header.ModTime); err != nil {
+			return files, fmt.Errorf("failed to import %s: %w", name, err)
+		}
+		files++
+	}
+}
+
+// extractBundleFile writes r next to target and renames it into place,
+// keeping the modification time, which orders cache eviction.
+func extractBundleFile(r io.Reader, target string, mode fs.FileMode, modTime time.Time) error {
+	if mode == 0 {
+		mode = 0644
+	}
+	err := fsutil.WriteFileAtomic(target, mode, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(target, modTime, modTime)
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/cas"
+	"doctrus/internal/deps"
+)
+
+func newBundleCLI(t *testing.T) *CLI {
+	t.Helper()
+	basePath := t.TempDir()
+	return &CLI{
+		cache:    cache.NewManager(filepath.Join(basePath, ".doctrus", "cache")),
+		cas:      cas.New(filepath.Join(basePath, ".doctrus", "cas")),
+		basePath: basePath,
+	}
+}
+
+func TestCacheExportImport(t *testing.T) {
+	for _, name := range []string{"cache.tar", "cache.tar.gz"} {
+		t.Run(name, func(t *testing.T) {
+			source := newBundleCLI(t)
+			if err := source.cache.Set("frontend:build", &deps.TaskState{TaskKey: "frontend:build", Success: true}, 0); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(filepath.Join(source.basePath, "dist"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(source.basePath, "dist", "app.js"), []byte("app"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := source.cas.Put("abc123", source.basePath, []string{filepath.Join("dist", "app.js")}); err != nil {
+				t.Fatal(err)
+			}
+
+			archive := filepath.Join(t.TempDir(), name)
+			exported, err := source.exportCache(archive)
+			if err != nil {
+				t.Fatalf("exportCache() error = %v", err)
+			}
+
+			target := newBundleCLI(t)
+			if err := target.cache.Set("backend:test", &deps.TaskState{TaskKey: "backend:test", Success: true}, 0); err != nil {
+				t.Fatal(err)
+			}
+			imported, err := target.importCache(archive)
+			if err != nil {
+				t.Fatalf("importCache() error = %v", err)
+			}
+			if imported != exported {
+				t.Errorf("imported %d files, exported %d", imported, exported)
+			}
+
+			entries, err := target.cache.List(cache.ListFilter{})
+			if err != nil || len(entries) != 2 {
+				t.Errorf("entries after import = %v, %v; want frontend:build and backend:test", entries, err)
+			}
+			if state, _ := target.cache.Get("frontend:build"); state == nil || !state.Success {
+				t.Error("frontend:build not imported")
+			}
+			restored, err := target.cas.Restore("abc123", target.basePath)
+			if err != nil || len(restored) != 1 {
+				t.Fatalf("Restore() after import = %v, %v", restored, err)
+			}
+			if data, _ := os.ReadFile(filepath.Join(target.basePath, "dist", "app.js")); string(data) != "app" {
+				t.Errorf("restored output = %q, want app", data)
+			}
+		})
+	}
+}
+
+func TestCacheImportRejectsUnexpectedEntries(t *testing.T) {
+	for _, name := range []string{"../escape.json", "cache/../../escape.json", "other/file.json", "/cache/x.json"} {
+		t.Run(name, func(t *testing.T) {
+			archive := filepath.Join(t.TempDir(), "cache.tar")
+			file, err := os.Create(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tw := tar.NewWriter(file)
+			if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 2}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write([]byte("{}")); err != nil {
+				t.Fatal(err)
+			}
+			tw.Close()
+			file.Close()
+
+			c := newBundleCLI(t)
+			if _, err := c.importCache(archive); err == nil {
+				t.Errorf("importCache() of %s succeeded, want an error", name)
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(c.cache.Dir()), "escape.json")); err == nil {
+				t.Errorf("%s was extracted", name)
+			}
+		})
+	}
+}
+
+func TestBundleCompression(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "cache.tar"},
+		{name: "cache.tar.gz", want: "gzip"},
+		{name: "cache.tgz", want: "gzip"},
+		{name: "cache.tar.zst", want: "zstd"},
+		{name: "cache.zip", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := bundleCompression(tt.name)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("bundleCompression(%q) = %q, %v; want %q, wantErr %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
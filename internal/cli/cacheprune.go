@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/config"
+	"doctrus/internal/ui"
+)
+
+var (
+	cachePruneOlderThan  string
+	cachePruneWorkspace  string
+	cachePruneFailedOnly bool
+)
+
+func newCachePruneCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete the cache entries matching filters",
+		Long: `Delete the cache entries selected by the filters: those created longer ago
+than --older-than, of tasks in --workspace, and recording a failed run with
+--failed-only. Entries must match every filter given, and at least one is
+required; doctrus cache clear removes every entry. With --dry-run the
+matching entries are listed without deleting them.
+
+Unlike doctrus prune, which applies the retention configured in doctrus.yml,
+this deletes exactly what the filters select.
+
+Examples:
+  doctrus cache prune --older-than 7d
+  doctrus cache prune --workspace frontend --failed-only
+  doctrus cache prune --older-than 2w --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: runCachePrune,
+	}
+
+	cmd.Flags().StringVar(&cachePruneOlderThan, "older-than", "", "Delete entries created longer ago than this age, such as 7d or 12h")
+	cmd.Flags().StringVar(&cachePruneWorkspace, "workspace", "", "Delete entries of tasks in this workspace")
+	cmd.Flags().BoolVar(&cachePruneFailedOnly, "failed-only", false, "Delete entries recording a failed run")
+
+	return cmd
+}
+
+func runCachePrune(cmd *cobra.Command, args []string) error {
+	filter, err := cachePruneFilter(cachePruneOlderThan, cachePruneWorkspace, cachePruneFailedOnly)
+	if err != nil {
+		return err
+	}
+
+	cli, err := newCLI()
+	if err != nil {
+		return err
+	}
+
+	entries, err := cli.pruneCacheEntries(filter, dryRun)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println(cli.ui.Status(ui.KindSuccess, "No cache entries matched"))
+		return nil
+	}
+
+	verb := "Pruned"
+	if dryRun {
+		verb = "Would prune"
+	}
+	fmt.Println(cli.ui.Status(ui.KindSuccess, fmt.Sprintf("%s %d cache %s", verb, len(entries), plural(len(entries), "entry", "entries"))))
+	if verbose || dryRun {
+		for _, entry := range entries {
+			status := "succeeded"
+			if entry.State != nil && !entry.State.Success {
+				status = "failed"
+			}
+			fmt.Printf("  %s (%s, %s ago)\n", entry.TaskKey, status, formatDuration(time.Since(entry.CreatedAt)))
+		}
+	}
+	return nil
+}
+
+// cachePruneFilter builds the filter of doctrus cache prune from its flags,
+// requiring at least one.
+func cachePruneFilter(olderThan, workspace string, failedOnly bool) (cache.ListFilter, error) {
+	age, err := config.ParseAge(olderThan)
+	if err != nil {
+		return cache.ListFilter{}, fmt.Errorf("invalid --older-than: %w", err)
+	}
+	filter := cache.ListFilter{Workspace: workspace, OlderThan: age, FailedOnly: failedOnly}
+	if filter == (cache.ListFilter{}) {
+		return filter, fmt.Errorf("specify --older-than, --workspace or --failed-only (doctrus cache clear removes every entry)")
+	}
+	return filter, nil
+}
+
+// pruneCacheEntries deletes the cache entries matching filter, unless
+// dryRun is set, and returns them oldest first.
+func (c *CLI) pruneCacheEntries(filter cache.ListFilter, dryRun bool) ([]cache.CacheEntry, error) {
+	entries, err := c.cache.List(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache entries: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+
+	if !dryRun {
+		for _, entry := range entries {
+			if err := c.cache.Delete(entry.TaskKey); err != nil {
+				return nil, fmt.Errorf("failed to prune cache entry %s: %w", entry.TaskKey, err)
+			}
+		}
+	}
+	return entries, nil
+}
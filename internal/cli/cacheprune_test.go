@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/deps"
+)
+
+func TestPruneCacheEntries(t *testing.T) {
+	basePath := t.TempDir()
+	c := &CLI{cache: cache.NewManager(filepath.Join(basePath, ".doctrus", "cache"))}
+	for key, success := range map[string]bool{"frontend:build": true, "frontend:test": false, "backend:test": false} {
+		if err := c.cache.Set(key, &deps.TaskState{TaskKey: key, Success: success}, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	filter := cache.ListFilter{Workspace: "frontend", FailedOnly: true}
+	entries, err := c.pruneCacheEntries(filter, true)
+	if err != nil || len(entries) != 1 || entries[0].TaskKey != "frontend:test" {
+		t.Fatalf("pruneCacheEntries() dry run = %v, %v, want frontend:test", entries, err)
+	}
+	if state, _ := c.cache.Get("frontend:test"); state == nil {
+		t.Fatal("dry run deleted frontend:test")
+	}
+
+	if _, err := c.pruneCacheEntries(filter, false); err != nil {
+		t.Fatalf("pruneCacheEntries() error = %v", err)
+	}
+	if state, _ := c.cache.Get("frontend:test"); state != nil {
+		t.Error("frontend:test still cached")
+	}
+	if remaining, _ := c.cache.List(cache.ListFilter{}); len(remaining) != 2 {
+		t.Errorf("entries left = %d, want 2", len(remaining))
+	}
+}
+
+func TestCachePruneFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		olderThan  string
+		workspace  string
+		failedOnly bool
+		wantErr    bool
+	}{
+		{name: "age", olderThan: "7d"},
+		{name: "workspace", workspace: "frontend"},
+		{name: "failed only", failedOnly: true},
+		{name: "no filter", wantErr: true},
+		{name: "invalid age", olderThan: "a week", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := cachePruneFilter(tt.olderThan, tt.workspace, tt.failedOnly)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("cachePruneFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/ci"
+	"doctrus/internal/config"
+	"doctrus/internal/hooks"
+	"doctrus/internal/ui"
+)
+
+var (
+	ciProvider string
+	ciOutput   string
+	ciBranch   string
+	ciRunsOn   string
+	ciChildren bool
+)
+
+func newCICommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ci",
+		Short: "Generate CI pipelines from the task graph",
+	}
+
+	generate := &cobra.Command{
+		Use:   "generate [workspace:]task...",
+		Short: "Generate a CI pipeline for tasks",
+		Long: `Generate a CI pipeline that runs the given tasks, or every task, with their
+dependencies. Tasks are sharded into one job per workspace, merging
+workspaces that depend on each other, and jobs wait for the jobs their
+dependencies run in. Each job caches .doctrus/cache with its outputs, passes
+outputs on to later jobs and the pipeline only triggers on changes to the
+workspaces it covers.
+
+With --provider gitlab, jobs are grouped into stages and run on runners
+with the tags of their workspaces; --child-pipelines turns every shard into a
+child pipeline with a job per task, written next to the repository root.
+
+Supported providers: ` + strings.Join(ci.Providers(), ", ") + `
+
+Examples:
+  doctrus ci generate --provider github -o .github/workflows/doctrus.yml
+  doctrus ci generate --provider github build test
+  doctrus ci generate --provider gitlab --child-pipelines -o .gitlab-ci.yml`,
+		RunE: generateCI,
+	}
+	generate.Flags().StringVar(&ciProvider, "provider", "", "CI provider: "+strings.Join(ci.Providers(), ", "))
+	generate.Flags().StringVarP(&ciOutput, "output", "o", "", "Write the pipeline to this file instead of stdout")
+	generate.Flags().StringVar(&ciBranch, "branch", "main", "Branch whose pushes trigger the pipeline")
+	generate.Flags().StringVar(&ciRunsOn, "runs-on", "", "Runner label (github) or image (gitlab) of every job (provider default if empty)")
+	generate.Flags().BoolVar(&ciChildren, "child-pipelines", false, "gitlab: trigger a child pipeline per shard, written to .gitlab/doctrus (requires --output)")
+	_ = generate.MarkFlagRequired("provider")
+
+	cmd.AddCommand(generate)
+	return cmd
+}
+
+func generateCI(cmd *cobra.Command, args []string) error {
+	cli, err := newCLI()
+	if err != nil {
+		return err
+	}
+
+	configFile, configDir, err := config.Locate(configPath)
+	if err != nil {
+		return err
+	}
+	// Outside a git repository the pipeline is rooted at doctrus.yml
+	root, relConfig := configDir, filepath.Base(configFile)
+	if topLevel, _, err := hooks.Repository(configDir); err == nil {
+		if relConfig, err = relativePath(topLevel, configFile); err != nil {
+			return err
+		}
+		root = topLevel
+	}
+	if ciChildren && ciOutput == "" {
+		return fmt.Errorf("--child-pipelines requires --output")
+	}
+
+	plan, err := ci.NewPlan(cli.config, cli.workspace, relConfig, args)
+	if err != nil {
+		return err
+	}
+	files, err := ci.Generate(ciProvider, plan, ci.Options{
+		Version:        version,
+		Branch:         ciBranch,
+		RunsOn:         ciRunsOn,
+		ChildPipelines: ciChildren,
+	})
+	if err != nil {
+		return err
+	}
+
+	if ciOutput == "" {
+		_, err := os.Stdout.Write(files[0].Data)
+		return err
+	}
+	files[0].Path = ciOutput
+	for i, file := range files {
+		if i > 0 {
+			file.Path = filepath.Join(root, filepath.FromSlash(file.Path))
+		}
+		if err := os.MkdirAll(filepath.Dir(file.Path), 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		if err := os.WriteFile(file.Path, file.Data, 0o644); err != nil {
+			return fmt.Errorf("failed to write pipeline: %w", err)
+		}
+		fmt.Println(cli.ui.Status(ui.KindSuccess, "Wrote "+file.Path))
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/ui"
+	"doctrus/internal/workspace"
+)
+
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config [workspace[:task]]",
+		Short: "Show the resolved environment of tasks",
+		Long: `Show the environment variables each task runs with after merging the
+global env, env_file, workspace env, task env and --env layers in the order
+set by env_merge, and which layers each value came from. The host
+environment is not included.
+
+Examples:
+  doctrus config                      # Every task
+  doctrus config frontend             # Tasks in the frontend workspace
+  doctrus config frontend:build -e NODE_ENV=test`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: showConfig,
+	}
+
+	cmd.Flags().StringArrayVarP(&envFlags, "env", "e", nil, "Set a task environment variable (KEY=VALUE, repeatable)")
+
+	return cmd
+}
+
+func showConfig(cmd *cobra.Command, args []string) error {
+	cli, err := newCLI()
+	if err != nil {
+		return err
+	}
+	cliEnv, err := parseEnvFlags(envFlags)
+	if err != nil {
+		return err
+	}
+
+	filterWorkspace, filterTask := "", ""
+	if len(args) == 1 {
+		filterWorkspace, filterTask = cli.resolveTaskSpec(args[0])
+		if filterTask != "" && filterWorkspace == "" {
+			// A bare argument names a workspace
+			filterWorkspace, filterTask = filterTask, ""
+		}
+		if _, exists := cli.config.GetWorkspace(filterWorkspace); !exists {
+			return &workspace.WorkspaceNotFoundError{Workspace: filterWorkspace}
+		}
+		if filterTask != "" {
+			if _, exists := cli.config.GetTask(filterWorkspace, filterTask); !exists {
+				return &workspace.TaskNotFoundError{Workspace: filterWorkspace, Task: filterTask}
+			}
+		}
+	}
+
+	fmt.Printf("Merge order: %s\n", strings.Join(cli.config.EnvOrder(), " < "))
+	if appendVars := cli.config.EnvAppend(); len(appendVars) > 0 {
+		fmt.Printf("Appended: %s\n", strings.Join(appendVars, ", "))
+	}
+	fmt.Println()
+
+	for _, workspaceName := range cli.workspace.GetWorkspaces() {
+		if filterWorkspace != "" && workspaceName != filterWorkspace {
+			continue
+		}
+		tasks, err := cli.workspace.GetTasks(workspaceName)
+		if err != nil {
+			return err
+		}
+		sort.Strings(tasks)
+
+		for _, taskName := range tasks {
+			if filterTask != "" && taskName != filterTask {
+				continue
+			}
+			if err := cli.printTaskEnv(workspaceName, taskName, cliEnv); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *CLI) printTaskEnv(workspaceName, taskName string, cliEnv map[string]string) error {
+	execution, err := c.workspace.ResolveTaskExecution(workspaceName, taskName)
+	if err != nil {
+		return err
+	}
+	vars, err := c.config.ResolveEnv(c.basePath, workspaceName, taskName, execution.AbsPath, cliEnv)
+	if err != nil {
+		return fmt.Errorf("failed to resolve environment of %s:%s: %w", workspaceName, taskName, err)
+	}
+
+	header := fmt.Sprintf("%s:%s", workspaceName, taskName)
+	if execution.Task.Hermetic {
+		header += " (hermetic)"
+	}
+	fmt.Println(c.ui.Status(ui.KindHeader, header))
+
+	if len(vars) == 0 {
+		fmt.Println("  (no variables)")
+		fmt.Println()
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, variable := range vars {
+		fmt.Fprintf(w, "  %s=%s\t%s\n", variable.Name, variable.Value, strings.Join(variable.Sources, ", "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	return nil
+}
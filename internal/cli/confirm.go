@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"doctrus/internal/config"
+	"doctrus/internal/ui"
+	"doctrus/internal/workspace"
+)
+
+// errNotConfirmed is returned when the plan shown by --confirm is declined.
+var errNotConfirmed = errors.New("run not confirmed")
+
+// confirmPlan prints the tasks the run would execute, in order, with where
+// they run and whether they are cached, and asks for approval on in. Anything
+// but yes, including no input at all, declines the run.
+func (c *CLI) confirmPlan(specs []string, in io.Reader) error {
+	executions, err := c.planExecutions(specs)
+	if err != nil {
+		return err
+	}
+
+	var plan bytes.Buffer
+	w := tabwriter.NewWriter(&plan, 0, 0, 2, ' ', 0)
+	toRun := 0
+	for i, execution := range executions {
+		status, runs := c.planStatus(execution)
+		if runs {
+			toRun++
+		}
+		fmt.Fprintf(w, "  %d.\t%s:%s\t%s\t%s\n", i+1, execution.WorkspaceName, execution.TaskName, c.planEnvironment(execution), status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	c.printOutput("%s\n%s", c.ui.Status(ui.KindHeader, "Plan for doctrus run "+strings.Join(specs, " ")), plan.String())
+	c.printOutput("Run %d task(s)? [y/N] ", toRun)
+
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return errNotConfirmed
+}
+
+// planExecutions resolves the task specs to every task the run would
+// execute, in execution order and without duplicates.
+func (c *CLI) planExecutions(specs []string) ([]*workspace.TaskExecution, error) {
+	targets, err := c.resolveTargets(specs)
+	if err != nil {
+		return nil, err
+	}
+	return c.resolveExecutions(targets)
+}
+
+// planEnvironment describes where a task's command runs: locally, in a
+// compose service, in a container of an image or on an agent.
+func (c *CLI) planEnvironment(execution *workspace.TaskExecution) string {
+	if len(execution.Task.Command) == 0 {
+		return "-"
+	}
+	executor := c.config.GetEffectiveExecutor(execution.WorkspaceName, execution.TaskName)
+	if distribute {
+		executor = config.ExecutorRemote
+	}
+	switch executor {
+	case config.ExecutorComposeExec:
+		return fmt.Sprintf("compose-exec (%s)", c.config.GetEffectiveContainer(execution.WorkspaceName, execution.TaskName))
+	case config.ExecutorDockerRun:
+		return fmt.Sprintf("docker-run (%s)", c.config.GetEffectiveImage(execution.WorkspaceName, execution.TaskName))
+	case config.ExecutorRemote:
+		switch agents := c.config.RemoteExecution.AgentList(); len(agents) {
+		case 0:
+			return config.ExecutorRemote
+		case 1:
+			return fmt.Sprintf("remote (%s)", agents[0].URL)
+		default:
+			return fmt.Sprintf("remote (%d agents)", len(agents))
+		}
+	case config.ExecutorLocal, "":
+		return config.ExecutorLocal
+	default:
+		return executor
+	}
+}
+
+// planStatus describes the cache status of a task as things stand before
+// the run, and whether its command is expected to run. Tasks whose
+// dependencies change their inputs may still run when shown as cached.
+func (c *CLI) planStatus(execution *workspace.TaskExecution) (string, bool) {
+	task := execution.Task
+	switch {
+	case len(task.Command) == 0:
+		return "compound", false
+	case forceBuild && len(task.DependsOnFiles) > 0:
+		return "runs (--force)", true
+	case len(task.DependsOnFiles) > 0:
+		reason, err := c.tracker.StaleFileTargets(execution)
+		switch {
+		case err != nil:
+			return "runs", true
+		case reason == "":
+			return "up to date", false
+		}
+		return "runs (" + reason + ")", true
+	case !task.Cache:
+		return "runs (not cached)", true
+	case forceBuild:
+		return "runs (--force)", true
+	case skipCache:
+		return "runs (--skip-cache)", true
+	}
+
+	previous, err := c.cache.Get(execution.WorkspaceName + ":" + execution.TaskName)
+	if err != nil {
+		return "runs (cache unreadable)", true
+	}
+	shouldRun, err := c.tracker.ShouldRunTask(execution, previous)
+	switch {
+	case err != nil:
+		return "runs", true
+	case !shouldRun:
+		return "cached", false
+	case previous == nil:
+		return "runs (not cached yet)", true
+	}
+	return "runs (inputs changed)", true
+}
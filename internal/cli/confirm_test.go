@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/docker"
+	"doctrus/internal/workspace"
+)
+
+func TestConfirmPlan(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"app": {
+				Path: tempDir,
+				Tasks: map[string]config.Task{
+					"build":  {Command: []string{"true"}},
+					"deploy": {Command: []string{"true"}, DependsOn: []string{"build"}, Executor: config.ExecutorDockerRun, Image: "alpine:3"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		answer  string
+		wantErr error
+	}{
+		{name: "yes", answer: "y\n", wantErr: nil},
+		{name: "yes in full", answer: "Yes\n", wantErr: nil},
+		{name: "no", answer: "n\n", wantErr: errNotConfirmed},
+		{name: "empty answer", answer: "\n", wantErr: errNotConfirmed},
+		{name: "no input", answer: "", wantErr: errNotConfirmed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			c := &CLI{
+				config:    cfg,
+				workspace: workspace.NewManager(cfg, tempDir),
+				executor:  docker.NewExecutor(cfg, tempDir),
+				tracker:   deps.NewTracker(tempDir),
+				cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+				stdout:    &out,
+				basePath:  tempDir,
+			}
+
+			err := c.confirmPlan([]string{"app:deploy"}, strings.NewReader(tt.answer))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("confirmPlan() error = %v, want %v", err, tt.wantErr)
+			}
+
+			plan := out.String()
+			build := strings.Index(plan, "app:build")
+			deploy := strings.Index(plan, "app:deploy  docker-run (alpine:3)")
+			if build < 0 || deploy < 0 || build > deploy {
+				t.Fatalf("expected app:build before app:deploy in docker-run, got:\n%s", plan)
+			}
+			if !strings.Contains(plan, "Run 2 task(s)? [y/N]") {
+				t.Fatalf("expected a prompt for 2 tasks, got:\n%s", plan)
+			}
+		})
+	}
+}
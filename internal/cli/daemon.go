@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/daemon"
+	"doctrus/internal/docker"
+)
+
+var daemonIdleTimeout time.Duration
+
+func newDaemonCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Keep file hashes in memory between runs",
+		Long: `Manage the doctrus daemon of the project, which keeps the hashes of input
+files in memory. While it runs, doctrus commands hash files through it and
+only read the files changed since it last hashed them, which speeds up
+checking the cache of large projects. Without a daemon, doctrus hashes files
+itself.
+
+Examples:
+  doctrus daemon start
+  doctrus daemon status
+  doctrus daemon stop`,
+	}
+
+	start := &cobra.Command{
+		Use:   "start",
+		Short: "Start the daemon in the background",
+		Args:  cobra.NoArgs,
+		RunE:  startDaemon,
+	}
+	start.Flags().DurationVar(&daemonIdleTimeout, "idle-timeout", 3*time.Hour, "Stop after this long without requests (0 to keep running)")
+
+	run := &cobra.Command{
+		Use:   "run",
+		Short: "Run the daemon in the foreground",
+		Args:  cobra.NoArgs,
+		RunE:  runDaemon,
+	}
+	run.Flags().DurationVar(&daemonIdleTimeout, "idle-timeout", 3*time.Hour, "Stop after this long without requests (0 to keep running)")
+
+	cmd.AddCommand(
+		start,
+		run,
+		&cobra.Command{
+			Use:   "stop",
+			Short: "Stop the daemon",
+			Args:  cobra.NoArgs,
+			RunE:  stopDaemon,
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "Show whether the daemon runs and what it holds",
+			Args:  cobra.NoArgs,
+			RunE:  showDaemonStatus,
+		},
+	)
+
+	return cmd
+}
+
+func startDaemon(cmd *cobra.Command, args []string) error {
+	cli, err := newCLI()
+	if err != nil {
+		return err
+	}
+	if client, err := daemon.Dial(cli.basePath); err == nil {
+		if status, err := client.Status(); err == nil {
+			fmt.Printf("Daemon already running (pid %d)\n", status.PID)
+			return nil
+		}
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the doctrus executable: %w", err)
+	}
+	logPath := filepath.Join(cli.basePath, ".doctrus", "daemon.log")
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return fmt.Errorf("failed to create daemon log: %w", err)
+	}
+	logFile, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create daemon log: %w", err)
+	}
+	defer logFile.Close()
+
+	process := exec.Command(executable, "--config", configPath, "daemon", "run", "--idle-timeout", daemonIdleTimeout.String())
+	process.Stdout = logFile
+	process.Stderr = logFile
+	daemon.Detach(process)
+	if err := process.Start(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+	pid := process.Process.Pid
+	process.Process.Release()
+
+	if !waitForDaemon(cli.basePath, true) {
+		return fmt.Errorf("daemon did not start, see %s", logPath)
+	}
+	fmt.Printf("✓ Daemon started (pid %d)\n", pid)
+	return nil
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	cli, err := newCLI()
+	if err != nil {
+		return err
+	}
+	listener, err := daemon.Listen(cli.basePath)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := docker.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "Doctrus daemon listening on %s\n", daemon.SocketPath(cli.basePath))
+	return daemon.NewServer(cli.basePath, daemonIdleTimeout).Serve(ctx, listener)
+}
+
+func stopDaemon(cmd *cobra.Command, args []string) error {
+	cli, err := newCLI()
+	if err != nil {
+		return err
+	}
+	client, err := daemon.Dial(cli.basePath)
+	if err != nil {
+		fmt.Println("No daemon running")
+		return nil
+	}
+	if err := client.Stop(); err != nil {
+		return err
+	}
+	if !waitForDaemon(cli.basePath, false) {
+		return fmt.Errorf("daemon did not stop")
+	}
+	fmt.Println("✓ Daemon stopped")
+	return nil
+}
+
+func showDaemonStatus(cmd *cobra.Command, args []string) error {
+	cli, err := newCLI()
+	if err != nil {
+		return err
+	}
+	client, err := daemon.Dial(cli.basePath)
+	if err != nil {
+		fmt.Println("No daemon running")
+		return nil
+	}
+	status, err := client.Status()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Daemon running (pid %d) for %s\n", status.PID, formatDuration(time.Since(status.StartedAt)))
+	fmt.Printf("  Socket:        %s\n", daemon.SocketPath(cli.basePath))
+	fmt.Printf("  Hashes held:   %d\n", status.Files)
+	fmt.Printf("  Files hashed:  %d\n", status.Hashed)
+	fmt.Printf("  Hashes reused: %d\n", status.Reused)
+	fmt.Printf("  Watched dirs:  %d\n", status.Watched)
+	return nil
+}
+
+// waitForDaemon waits up to five seconds for the project's daemon to be
+// running or, with running false, gone.
+func waitForDaemon(root string, running bool) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := daemon.Dial(root)
+		if (err == nil) == running {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// useDaemon hashes files through the project's daemon when one is running.
+func (c *CLI) useDaemon() {
+	client, err := daemon.Dial(c.basePath)
+	if err != nil {
+		return
+	}
+	c.tracker.SetHasher(client)
+	c.log.Debugf("Hashing files through the daemon on %s\n", daemon.SocketPath(c.basePath))
+}
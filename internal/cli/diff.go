@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"doctrus/internal/deps"
+	"doctrus/internal/ui"
+)
+
+// Formats of --show-diff
+const (
+	diffText = "text"
+	diffJSON = "json"
+)
+
+// maxDiffLines is how many changed files --show-diff lists per task.
+const maxDiffLines = 50
+
+// showDiffFormat is the format --show-diff prints changes in when showDiff
+// is set.
+var showDiffFormat = diffText
+
+// diffFlag is the value of --show-diff. Without a value it prints the
+// changes as text; --show-diff=json prints them as JSON instead.
+type diffFlag struct{}
+
+func (diffFlag) String() string {
+	if !showDiff {
+		return "false"
+	}
+	return showDiffFormat
+}
+
+func (diffFlag) Set(value string) error {
+	switch value {
+	case "true", diffText:
+		showDiff, showDiffFormat = true, diffText
+	case diffJSON:
+		showDiff, showDiffFormat = true, diffJSON
+	case "false":
+		showDiff = false
+	default:
+		return fmt.Errorf("invalid format %q (expected text or json)", value)
+	}
+	return nil
+}
+
+func (diffFlag) Type() string {
+	return "format"
+}
+
+// inputDiff is the JSON document --show-diff=json prints for a task.
+type inputDiff struct {
+	Task     string             `json:"task"`
+	Added    int                `json:"added"`
+	Modified int                `json:"modified"`
+	Deleted  int                `json:"deleted"`
+	Changes  []deps.InputChange `json:"changes"`
+}
+
+func newInputDiff(taskKey string, changes []deps.InputChange) inputDiff {
+	diff := inputDiff{Task: taskKey, Changes: changes}
+	if diff.Changes == nil {
+		diff.Changes = []deps.InputChange{}
+	}
+	for _, change := range changes {
+		switch change.Kind {
+		case deps.ChangeAdded:
+			diff.Added++
+		case deps.ChangeModified:
+			diff.Modified++
+		case deps.ChangeDeleted:
+			diff.Deleted++
+		}
+	}
+	return diff
+}
+
+// printInputDiff shows how a task's inputs changed since it was cached, in
+// the format of --show-diff.
+func (c *CLI) printInputDiff(taskKey string, changes []deps.InputChange) {
+	diff := newInputDiff(taskKey, changes)
+	if showDiffFormat == diffJSON {
+		data, err := json.Marshal(diff)
+		if err != nil {
+			c.log.Warnf("  Warning: failed to encode input changes: %v\n", err)
+			return
+		}
+		c.printOutput("%s\n", data)
+		return
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	c.log.Infof("  Changed inputs: %d added, %d modified, %d deleted\n", diff.Added, diff.Modified, diff.Deleted)
+	for i, change := range changes {
+		if i == maxDiffLines {
+			c.log.Infof("    ... and %d more\n", len(changes)-maxDiffLines)
+			break
+		}
+		c.log.Infof("    %s\n", c.describeChange(change))
+	}
+}
+
+// describeChange renders one changed file, such as
+// "~ src/main.go  sha256 1a2b3c4d → 5e6f7a8b, 340 B → 512 B, mtime +2h0m0s".
+func (c *CLI) describeChange(change deps.InputChange) string {
+	switch change.Kind {
+	case deps.ChangeAdded:
+		return c.ui.Paint(ui.KindSuccess, fmt.Sprintf("+ %s  (%s)", change.Path, formatBytes(change.NewSize)))
+	case deps.ChangeDeleted:
+		return c.ui.Paint(ui.KindFailure, "- "+change.Path)
+	}
+
+	details := []string{fmt.Sprintf("sha256 %s → %s", shortHash(change.OldHash), shortHash(change.NewHash))}
+	if change.OldSize != change.NewSize {
+		details = append(details, fmt.Sprintf("%s → %s", formatBytes(change.OldSize), formatBytes(change.NewSize)))
+	}
+	if !change.OldModTime.IsZero() && !change.NewModTime.IsZero() {
+		if delta := change.NewModTime.Sub(change.OldModTime).Round(time.Second); delta != 0 {
+			sign := "+"
+			if delta < 0 {
+				sign = ""
+			}
+			details = append(details, fmt.Sprintf("mtime %s%v", sign, delta))
+		}
+	}
+	return c.ui.Paint(ui.KindWarning, fmt.Sprintf("~ %s  %s", change.Path, strings.Join(details, ", ")))
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
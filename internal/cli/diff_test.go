@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"doctrus/internal/deps"
+	"doctrus/internal/logging"
+)
+
+func TestShowDiffFlag(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantShow   bool
+		wantFormat string
+		wantErr    bool
+	}{
+		{args: nil, wantShow: false, wantFormat: diffText},
+		{args: []string{"--show-diff"}, wantShow: true, wantFormat: diffText},
+		{args: []string{"--show-diff=json"}, wantShow: true, wantFormat: diffJSON},
+		{args: []string{"--show-diff=false"}, wantShow: false, wantFormat: diffText},
+		{args: []string{"--show-diff=yaml"}, wantErr: true},
+	}
+
+	origShow, origFormat := showDiff, showDiffFormat
+	t.Cleanup(func() { showDiff, showDiffFormat = origShow, origFormat })
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			showDiff, showDiffFormat = false, diffText
+			flags := pflag.NewFlagSet("run", pflag.ContinueOnError)
+			flags.Var(diffFlag{}, "show-diff", "")
+			flags.Lookup("show-diff").NoOptDefVal = diffText
+
+			err := flags.Parse(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if showDiff != tt.wantShow || showDiffFormat != tt.wantFormat {
+				t.Fatalf("showDiff = %v, format %q; want %v, %q", showDiff, showDiffFormat, tt.wantShow, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestPrintInputDiff(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	changes := []deps.InputChange{
+		{Path: "app/new.go", Kind: deps.ChangeAdded, NewHash: "ffff", NewSize: 2048},
+		{Path: "app/main.go", Kind: deps.ChangeModified, OldHash: "1a2b3c4d5e", NewHash: "5e6f7a8b9c", OldSize: 340, NewSize: 512, OldModTime: base, NewModTime: base.Add(2 * time.Hour)},
+		{Path: "app/old.go", Kind: deps.ChangeDeleted, OldHash: "eeee"},
+	}
+
+	origFormat := showDiffFormat
+	t.Cleanup(func() { showDiffFormat = origFormat })
+
+	t.Run("text", func(t *testing.T) {
+		showDiffFormat = diffText
+		var out bytes.Buffer
+		c := &CLI{stdout: &out}
+		c.log = logging.New(&out, logging.LevelInfo, nil)
+		c.printInputDiff("app:build", changes)
+
+		for _, want := range []string{
+			"Changed inputs: 1 added, 1 modified, 1 deleted",
+			"+ app/new.go  (2.0 KiB)",
+			"~ app/main.go  sha256 1a2b3c4d → 5e6f7a8b, 340 B → 512 B, mtime +2h0m0s",
+			"- app/old.go",
+		} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("output missing %q:\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		showDiffFormat = diffJSON
+		var out bytes.Buffer
+		c := &CLI{stdout: &out}
+		c.printInputDiff("app:build", changes)
+
+		var diff inputDiff
+		if err := json.Unmarshal(out.Bytes(), &diff); err != nil {
+			t.Fatalf("invalid JSON %q: %v", out.String(), err)
+		}
+		if diff.Task != "app:build" || diff.Added != 1 || diff.Modified != 1 || diff.Deleted != 1 || len(diff.Changes) != 3 {
+			t.Fatalf("unexpected diff: %+v", diff)
+		}
+	})
+}
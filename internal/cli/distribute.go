@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"doctrus/internal/config"
+	"doctrus/internal/ui"
+)
+
+// distributeTasks makes every task of the run execute on the agents under
+// remote_execution, as run --distribute does.
+func (c *CLI) distributeTasks() error {
+	if len(c.config.RemoteExecution.AgentList()) == 0 {
+		return fmt.Errorf("--distribute requires agents under remote_execution in doctrus.yml")
+	}
+	c.executor.Override(config.ExecutorRemote)
+	return nil
+}
+
+// printAgentSummary reports what each agent of a distributed run did.
+func (c *CLI) printAgentSummary() {
+	if !distribute || c.agents == nil {
+		return
+	}
+	stats := c.agents.Stats()
+	total := 0
+	for _, agent := range stats {
+		total += agent.Tasks
+	}
+	if total == 0 {
+		return
+	}
+
+	c.log.Infof("%s\n", c.ui.Status(ui.KindInfo, fmt.Sprintf("Distributed %d %s across %d %s",
+		total, plural(total, "task", "tasks"), len(stats), plural(len(stats), "agent", "agents"))))
+	width := 0
+	for _, agent := range stats {
+		width = max(width, len(agent.URL))
+	}
+	for _, agent := range stats {
+		details := []string{fmt.Sprintf("%d %s", agent.Tasks, plural(agent.Tasks, "task", "tasks"))}
+		if agent.Tasks > 0 {
+			details = append(details, fmt.Sprintf("%s busy", agent.Busy.Round(time.Millisecond)))
+		}
+		if agent.Failed > 0 {
+			details = append(details, fmt.Sprintf("%d failed", agent.Failed))
+		}
+		if agent.Unreachable {
+			details = append(details, c.ui.Paint(ui.KindWarning, "unreachable"))
+		}
+		c.log.Infof("  %-*s  %s\n", width, agent.URL, strings.Join(details, ", "))
+	}
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"doctrus/internal/history"
+	"doctrus/internal/ui"
+	"doctrus/internal/workspace"
+)
+
+// runEstimate tracks how much of a run is left according to the durations
+// recorded in history, and which tasks ran slower than usual. A nil
+// runEstimate has no estimates.
+type runEstimate struct {
+	estimator *history.Estimator
+
+	mu        sync.Mutex
+	planned   map[string]time.Duration
+	remaining time.Duration
+	slow      []slowTask
+}
+
+// slowTask is a task that took significantly longer than its median.
+type slowTask struct {
+	key    string
+	took   time.Duration
+	median time.Duration
+}
+
+// loadEstimate reads the project's history to estimate the run's tasks.
+// Without history there are no estimates.
+func (c *CLI) loadEstimate() *runEstimate {
+	runs, err := historyStore(c.basePath).Load()
+	if err != nil {
+		c.log.Debugf("No duration estimates: %v\n", err)
+		return nil
+	}
+	return &runEstimate{
+		estimator: history.NewEstimator(runs),
+		planned:   make(map[string]time.Duration),
+	}
+}
+
+// plan adds the tasks of executions not planned yet to the remaining time
+// and returns their estimated total and how many of them have an estimate.
+func (e *runEstimate) plan(executions []*workspace.TaskExecution) (time.Duration, int) {
+	if e == nil {
+		return 0, 0
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var total time.Duration
+	known := 0
+	for _, execution := range executions {
+		key := execution.WorkspaceName + ":" + execution.TaskName
+		if _, ok := e.planned[key]; ok || len(execution.Task.Command) == 0 {
+			continue
+		}
+		estimate, ok := e.estimator.Estimate(key)
+		e.planned[key] = estimate
+		if ok {
+			total += estimate
+			known++
+		}
+	}
+	e.remaining += total
+	return total, known
+}
+
+// task returns the estimate of the task with key and of the rest of the run
+// including it.
+func (e *runEstimate) task(key string) (time.Duration, time.Duration) {
+	if e == nil {
+		return 0, 0
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.planned[key], e.remaining
+}
+
+// finish removes a task, whatever its outcome, from the remaining time.
+func (e *runEstimate) finish(key string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if estimate, ok := e.planned[key]; ok {
+		e.remaining -= estimate
+		e.planned[key] = 0
+	}
+}
+
+// executed notes how long an executed task took, remembering it when that
+// is significantly slower than its median.
+func (e *runEstimate) executed(key string, took time.Duration) {
+	if e == nil {
+		return
+	}
+	median, slow := e.estimator.Slow(key, took)
+	if !slow {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.slow = append(e.slow, slowTask{key: key, took: took, median: median})
+}
+
+// slowTasks returns the tasks that ran slower than usual, in the order they
+// finished.
+func (e *runEstimate) slowTasks() []slowTask {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]slowTask(nil), e.slow...)
+}
+
+// formatEstimate renders an estimated duration such as ~3m. Estimates
+// under a second are not worth showing and render empty.
+func formatEstimate(d time.Duration) string {
+	if d < time.Second {
+		return ""
+	}
+	return "~" + formatDuration(d)
+}
+
+// printSlowTasks lists the tasks of the run that were significantly slower
+// than their historical median.
+func (c *CLI) printSlowTasks() {
+	for _, task := range c.estimate.slowTasks() {
+		message := fmt.Sprintf("%s took %s, %.1fx its median of %s",
+			task.key, task.took.Round(time.Millisecond), float64(task.took)/float64(task.median), task.median.Round(time.Millisecond))
+		c.log.Warnf("%s\n", c.ui.Status(ui.KindWarning, "Slower than usual: "+message))
+	}
+}
@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"strings"
+
+	"doctrus/internal/docker"
+	"doctrus/internal/taskrun"
+	"doctrus/internal/ui"
+)
+
+// finallyHooks print the finally steps of a task as they run, with the
+// output of failed steps unless it was streamed.
+func (c *CLI) finallyHooks(taskKey string, detailed, showTaskPrefix bool) taskrun.Hooks {
+	return taskrun.Hooks{
+		FinallyStep: func(command []string) {
+			c.detailf(detailed, "  Finally: %s\n", strings.Join(command, " "))
+		},
+		FinallyFailed: func(err error, result *docker.ExecutionResult) {
+			if !detailed {
+				c.printBufferedOutput(taskKey, "stdout", result.Stdout, showTaskPrefix)
+				c.printBufferedOutput(taskKey, "stderr", result.Stderr, showTaskPrefix)
+			}
+			c.log.Errorf("  %s\n", c.ui.Status(ui.KindFailure, err.Error()))
+		},
+	}
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/docker"
+	"doctrus/internal/workspace"
+)
+
+func TestFinallyStepsAlwaysRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell commands not available on Windows")
+	}
+
+	tests := []struct {
+		name    string
+		command []string
+		finally [][]string
+		timeout time.Duration
+		wantErr string
+		exitErr int
+	}{
+		{
+			name:    "after success",
+			command: []string{"true"},
+			finally: [][]string{{"touch", "cleaned"}},
+		},
+		{
+			name:    "after failure",
+			command: []string{"sh", "-c", "exit 3"},
+			finally: [][]string{{"touch", "cleaned"}},
+			exitErr: 3,
+		},
+		{
+			name:    "after cancellation",
+			command: []string{"sleep", "5"},
+			finally: [][]string{{"touch", "cleaned"}},
+			timeout: 100 * time.Millisecond,
+			exitErr: 124,
+		},
+		{
+			name:    "failing step fails the task and later steps still run",
+			command: []string{"true"},
+			finally: [][]string{{"sh", "-c", "exit 2"}, {"touch", "cleaned"}},
+			wantErr: `finally[0] "sh -c exit 2" failed with exit code 2`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			cfg := &config.Config{
+				Version: "1.0",
+				Workspaces: map[string]config.Workspace{
+					"app": {
+						Path: tempDir,
+						Tasks: map[string]config.Task{
+							"test": {Command: tt.command, Finally: tt.finally},
+						},
+					},
+				},
+			}
+			c := &CLI{
+				config:    cfg,
+				workspace: workspace.NewManager(cfg, tempDir),
+				executor:  docker.NewExecutor(cfg, tempDir),
+				tracker:   deps.NewTracker(tempDir),
+				cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+				basePath:  tempDir,
+			}
+			execution, err := c.workspace.ResolveTaskExecution("app", "test")
+			if err != nil {
+				t.Fatalf("ResolveTaskExecution() error = %v", err)
+			}
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			err = c.runExecution(ctx, execution, false)
+			var taskErr *workspace.TaskFailedError
+			switch {
+			case tt.exitErr != 0:
+				if !errors.As(err, &taskErr) || taskErr.ExitCode != tt.exitErr {
+					t.Fatalf("runExecution() error = %v, want exit code %d", err, tt.exitErr)
+				}
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("runExecution() error = %v, want %q", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("runExecution() error = %v", err)
+			}
+
+			if _, err := os.Stat(filepath.Join(tempDir, "cleaned")); err != nil {
+				t.Fatalf("expected the finally step to run: %v", err)
+			}
+		})
+	}
+}
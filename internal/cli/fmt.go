@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/config"
+)
+
+// fmtCheck reports unformatted files instead of rewriting them
+var fmtCheck bool
+
+func newFmtCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fmt [file]",
+		Short: "Format the configuration file",
+		Long: `Rewrite doctrus.yml in canonical form: settings in a fixed order, tasks
+sorted by name, lists of strings on one line, consistent double quoting and
+two-space indentation. Comments are preserved.
+
+Examples:
+  doctrus fmt                   # Format the project's doctrus.yml
+  doctrus fmt examples/app.yml  # Format a specific file
+  doctrus fmt --check           # Fail if doctrus.yml isn't formatted, for CI`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: formatConfig,
+	}
+
+	cmd.Flags().BoolVar(&fmtCheck, "check", false, "Don't write the file; fail if it isn't formatted")
+
+	return cmd
+}
+
+func formatConfig(cmd *cobra.Command, args []string) error {
+	path := ""
+	if len(args) == 1 {
+		path = args[0]
+	} else {
+		located, _, err := config.Locate(configPath)
+		if err != nil {
+			return err
+		}
+		path = located
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	formatted, err := config.Format(data)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(data, formatted) {
+		fmt.Printf("✓ %s is already formatted\n", path)
+		return nil
+	}
+	if fmtCheck {
+		return fmt.Errorf("%s is not formatted; run doctrus fmt to format it", path)
+	}
+
+	if err := os.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write config file %s: %w", path, err)
+	}
+
+	fmt.Printf("✓ Formatted %s\n", path)
+	return nil
+}
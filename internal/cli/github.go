@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"doctrus/internal/events"
+)
+
+// CI modes of doctrus run
+const (
+	// ciModeAuto uses the output of the CI system doctrus detects
+	ciModeAuto = "auto"
+	// ciModeGitHub folds the output of every task into a group of the
+	// GitHub Actions log and annotates failed tasks
+	ciModeGitHub = "github"
+	// ciModeNone prints the same output as on a developer machine
+	ciModeNone = "none"
+)
+
+// githubOutput writes the workflow commands of GitHub Actions: a collapsible
+// group holding the output of each task, and an error annotation for each
+// task that failed, listed on the summary page of the workflow run. A nil
+// githubOutput writes nothing.
+type githubOutput struct {
+	cli *CLI
+}
+
+// useCIMode switches the CLI to the given --ci-mode, where auto detects
+// GitHub Actions from GITHUB_ACTIONS.
+func (c *CLI) useCIMode(mode string) error {
+	switch mode {
+	case "", ciModeAuto:
+		if os.Getenv("GITHUB_ACTIONS") != "true" {
+			return nil
+		}
+	case ciModeGitHub:
+	case ciModeNone:
+		return nil
+	default:
+		return fmt.Errorf("invalid CI mode %q (expected auto, github or none)", mode)
+	}
+
+	c.github = &githubOutput{cli: c}
+	c.events.Subscribe(c.github)
+	return nil
+}
+
+func (g *githubOutput) Handle(e events.Event) {
+	if e.Type != events.TaskFinished || e.Status != events.StatusFailed {
+		return
+	}
+	message := e.Error
+	if message == "" {
+		message = fmt.Sprintf("Exited with code %d in %v", e.ExitCode, e.Duration.Round(time.Millisecond))
+	}
+	g.cli.printOutput("::error title=%s::%s\n", escapeGitHubProperty(e.Key()+" failed"), escapeGitHubData(message))
+}
+
+// group prints the output of a task run as a group titled title, in one
+// write so the groups of tasks running in parallel never mix. Nothing is
+// printed for a run without output.
+func (g *githubOutput) group(title, stdout, stderr string) {
+	if g == nil || strings.TrimSpace(stdout+stderr) == "" {
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "::group::%s\n", escapeGitHubData(title))
+	w := g.cli.ui.Writer(&b)
+	for _, output := range []string{stdout, stderr} {
+		if strings.TrimSpace(output) == "" {
+			continue
+		}
+		if !strings.HasSuffix(output, "\n") {
+			output += "\n"
+		}
+		_, _ = w.Write([]byte(output))
+	}
+	_ = w.Flush()
+	b.WriteString("::endgroup::\n")
+	g.cli.printOutput("%s", b.String())
+}
+
+// escapeGitHubData escapes the message of a workflow command.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes a property of a workflow command, such as
+// the title of an annotation.
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
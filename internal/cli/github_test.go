@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"doctrus/internal/events"
+)
+
+func TestUseCIMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		env     string
+		want    bool
+		wantErr string
+	}{
+		{name: "auto outside CI", mode: ciModeAuto},
+		{name: "auto on GitHub Actions", mode: ciModeAuto, env: "true", want: true},
+		{name: "forced", mode: ciModeGitHub, want: true},
+		{name: "disabled on GitHub Actions", mode: ciModeNone, env: "true"},
+		{name: "invalid", mode: "jenkins", wantErr: "invalid CI mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_ACTIONS", tt.env)
+			c := &CLI{events: events.NewBus()}
+			err := c.useCIMode(tt.mode)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("useCIMode(%q) error = %v, want %q", tt.mode, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("useCIMode(%q) error = %v", tt.mode, err)
+			}
+			if got := c.github != nil; got != tt.want {
+				t.Fatalf("useCIMode(%q) enabled GitHub output = %v, want %v", tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHubOutput(t *testing.T) {
+	var out bytes.Buffer
+	c := &CLI{stdout: &out, events: events.NewBus()}
+	if err := c.useCIMode(ciModeGitHub); err != nil {
+		t.Fatal(err)
+	}
+
+	c.github.group("lib:build", "compiled\n", "warning: unused")
+	c.github.group("lib:lint", "", "\n")
+	for _, e := range []events.Event{
+		{Type: events.TaskFinished, Workspace: "lib", Task: "build", Status: events.StatusSuccess},
+		{Type: events.TaskFinished, Workspace: "web", Task: "test", Status: events.StatusFailed, ExitCode: 2, Duration: 1500 * time.Millisecond},
+		{Type: events.TaskFinished, Workspace: "web", Task: "e2e", Status: events.StatusFailed, Error: "100% of\noutputs missing"},
+	} {
+		c.events.Publish(e)
+	}
+
+	want := "::group::lib:build\ncompiled\nwarning: unused\n::endgroup::\n" +
+		"::error title=web%3Atest failed::Exited with code 2 in 1.5s\n" +
+		"::error title=web%3Ae2e failed::100%25 of%0Aoutputs missing\n"
+	if got := out.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestNilGitHubOutputPrintsNothing(t *testing.T) {
+	var g *githubOutput
+	g.group("lib:build", "output\n", "")
+}
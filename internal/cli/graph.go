@@ -0,0 +1,280 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	graphFormat string
+	graphFocus  string
+)
+
+func newGraphCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph [workspace:]task...",
+		Short: "Render the task graph as DOT, Mermaid or a tree",
+		Long: `Render the resolved graph of the given tasks and their transitive
+dependencies, or of every task without arguments. Tasks are grouped by
+workspace, edges point from a task to the tasks it depends on, and compound
+tasks are drawn dashed.
+
+--focus narrows the graph to a task, the tasks it depends on and the tasks
+depending on it.
+
+Examples:
+  doctrus graph | dot -Tsvg > graph.svg        # Every task, as Graphviz DOT
+  doctrus graph web:deploy --format mermaid    # For Markdown on GitHub
+  doctrus graph --focus lib:build --format tree`,
+		Args: cobra.ArbitraryArgs,
+		RunE: runGraph,
+	}
+
+	cmd.Flags().StringVar(&graphFormat, "format", "dot", "Output format: dot, mermaid or tree")
+	cmd.Flags().StringVar(&graphFocus, "focus", "", "Only show this task with its dependencies and dependents")
+
+	return cmd
+}
+
+func runGraph(cmd *cobra.Command, args []string) error {
+	switch graphFormat {
+	case "dot", "mermaid", "tree":
+	default:
+		return fmt.Errorf("invalid format %q (expected dot, mermaid or tree)", graphFormat)
+	}
+
+	// Dependents of the focused task may be in any workspace
+	specs := args
+	if graphFocus != "" {
+		specs = nil
+	}
+	cli, err := newScopedCLI(specs)
+	if err != nil {
+		return err
+	}
+
+	graph, err := cli.taskGraph(args)
+	if err != nil {
+		return err
+	}
+	if graphFocus != "" {
+		targets, err := cli.resolveTargets([]string{graphFocus})
+		if err != nil {
+			return err
+		}
+		var focus []string
+		for _, target := range targets {
+			key := target.workspace + ":" + target.task
+			if _, ok := graph[key]; !ok {
+				return fmt.Errorf("%s is not in the graph of %s", key, strings.Join(args, ", "))
+			}
+			focus = append(focus, key)
+		}
+		graph = focusGraph(graph, focus)
+	}
+
+	return cli.writeGraph(os.Stdout, graph, graphFormat)
+}
+
+// taskGraph returns the graph of the tasks named by specs, as accepted by
+// doctrus list --tree, and their transitive dependencies, mapping each task
+// to its direct dependencies. Without specs it holds every task.
+func (c *CLI) taskGraph(specs []string) (map[string][]string, error) {
+	if len(specs) == 0 {
+		specs = []string{""}
+	}
+	graph := make(map[string][]string)
+	for _, spec := range specs {
+		roots, err := c.treeRoots(spec)
+		if err != nil {
+			return nil, err
+		}
+		for _, root := range roots {
+			if _, ok := graph[root]; ok {
+				continue
+			}
+			workspaceName, taskName, _ := strings.Cut(root, ":")
+			rootGraph, err := c.workspace.DependencyGraph(workspaceName, taskName)
+			if err != nil {
+				return nil, err
+			}
+			for key, deps := range rootGraph {
+				graph[key] = deps
+			}
+		}
+	}
+	return graph, nil
+}
+
+// focusGraph returns the part of graph holding the focused tasks and every
+// task they depend on or that depends on them, directly or not.
+func focusGraph(graph map[string][]string, focus []string) map[string][]string {
+	dependents := make(map[string][]string)
+	for key, deps := range graph {
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], key)
+		}
+	}
+
+	keep := make(map[string]bool)
+	var walk func(key string, edges map[string][]string, seen map[string]bool)
+	walk = func(key string, edges map[string][]string, seen map[string]bool) {
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		keep[key] = true
+		for _, next := range edges[key] {
+			walk(next, edges, seen)
+		}
+	}
+	for _, key := range focus {
+		walk(key, graph, make(map[string]bool))
+		walk(key, dependents, make(map[string]bool))
+	}
+
+	focused := make(map[string][]string, len(keep))
+	for key := range keep {
+		var deps []string
+		for _, dep := range graph[key] {
+			if keep[dep] {
+				deps = append(deps, dep)
+			}
+		}
+		focused[key] = deps
+	}
+	return focused
+}
+
+// writeGraph renders graph in format: dot, mermaid or tree.
+func (c *CLI) writeGraph(w io.Writer, graph map[string][]string, format string) error {
+	switch format {
+	case "mermaid":
+		c.writeMermaid(w, graph)
+	case "tree":
+		return c.printTreeOf(w, graphRoots(graph), func(key string) ([]string, error) {
+			return graph[key], nil
+		})
+	default:
+		c.writeDOT(w, graph)
+	}
+	return nil
+}
+
+// writeDOT renders graph in the Graphviz DOT language, with a cluster per
+// workspace.
+func (c *CLI) writeDOT(w io.Writer, graph map[string][]string) {
+	fmt.Fprintln(w, "digraph doctrus {")
+	fmt.Fprintln(w, "  node [shape=box];")
+	for _, group := range graphWorkspaces(graph) {
+		fmt.Fprintf(w, "  subgraph %s {\n", strconv.Quote("cluster_"+group.name))
+		fmt.Fprintf(w, "    label=%s;\n", strconv.Quote(group.name))
+		for _, key := range group.keys {
+			_, taskName, _ := strings.Cut(key, ":")
+			style := ""
+			if c.isCompound(key) {
+				style = ", style=dashed"
+			}
+			fmt.Fprintf(w, "    %s [label=%s%s];\n", strconv.Quote(key), strconv.Quote(taskName), style)
+		}
+		fmt.Fprintln(w, "  }")
+	}
+	for _, key := range sortedGraphKeys(graph) {
+		for _, dep := range graph[key] {
+			fmt.Fprintf(w, "  %s -> %s;\n", strconv.Quote(key), strconv.Quote(dep))
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// writeMermaid renders graph as a Mermaid flowchart, with a subgraph per
+// workspace. Nodes get generated IDs, as task names may hold characters
+// Mermaid IDs can't.
+func (c *CLI) writeMermaid(w io.Writer, graph map[string][]string) {
+	ids := make(map[string]string, len(graph))
+	for i, key := range sortedGraphKeys(graph) {
+		ids[key] = fmt.Sprintf("t%d", i)
+	}
+
+	fmt.Fprintln(w, "flowchart TD")
+	for i, group := range graphWorkspaces(graph) {
+		fmt.Fprintf(w, "  subgraph w%d[\"%s\"]\n", i, mermaidLabel(group.name))
+		for _, key := range group.keys {
+			_, taskName, _ := strings.Cut(key, ":")
+			shape := "[\"%s\"]"
+			if c.isCompound(key) {
+				shape = "([\"%s\"])"
+			}
+			fmt.Fprintf(w, "    %s"+shape+"\n", ids[key], mermaidLabel(taskName))
+		}
+		fmt.Fprintln(w, "  end")
+	}
+	for _, key := range sortedGraphKeys(graph) {
+		for _, dep := range graph[key] {
+			fmt.Fprintf(w, "  %s --> %s\n", ids[key], ids[dep])
+		}
+	}
+}
+
+// mermaidLabel escapes quotes, which would end a Mermaid label.
+func mermaidLabel(label string) string {
+	return strings.ReplaceAll(label, `"`, "#quot;")
+}
+
+// isCompound reports whether the task with key has no command.
+func (c *CLI) isCompound(key string) bool {
+	workspaceName, taskName, _ := strings.Cut(key, ":")
+	task, exists := c.config.GetTask(workspaceName, taskName)
+	return exists && len(task.Command) == 0
+}
+
+// graphWorkspace is the tasks of a workspace in a graph.
+type graphWorkspace struct {
+	name string
+	keys []string
+}
+
+// graphWorkspaces groups the tasks of graph by workspace, sorted by name.
+func graphWorkspaces(graph map[string][]string) []graphWorkspace {
+	var groups []graphWorkspace
+	for _, key := range sortedGraphKeys(graph) {
+		workspaceName, _, _ := strings.Cut(key, ":")
+		if len(groups) == 0 || groups[len(groups)-1].name != workspaceName {
+			groups = append(groups, graphWorkspace{name: workspaceName})
+		}
+		groups[len(groups)-1].keys = append(groups[len(groups)-1].keys, key)
+	}
+	return groups
+}
+
+// graphRoots returns the tasks of graph no other task depends on, sorted.
+func graphRoots(graph map[string][]string) []string {
+	dependedOn := make(map[string]bool)
+	for _, deps := range graph {
+		for _, dep := range deps {
+			dependedOn[dep] = true
+		}
+	}
+	var roots []string
+	for _, key := range sortedGraphKeys(graph) {
+		if !dependedOn[key] {
+			roots = append(roots, key)
+		}
+	}
+	return roots
+}
+
+func sortedGraphKeys(graph map[string][]string) []string {
+	keys := make([]string, 0, len(graph))
+	for key := range graph {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/workspace"
+)
+
+func newGraphCLI(t *testing.T) *CLI {
+	t.Helper()
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"lib": {
+				Path: tempDir,
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"true"}},
+					"lint":  {Command: []string{"true"}},
+				},
+			},
+			"web": {
+				Path: tempDir,
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"true"}, DependsOn: []string{"lib:build"}},
+					"test":  {Command: []string{"true"}, DependsOn: []string{"build"}},
+					"ci":    {DependsOn: []string{"test", "lib:lint"}},
+				},
+			},
+		},
+	}
+	return &CLI{
+		config:    cfg,
+		workspace: workspace.NewManager(cfg, tempDir),
+		tracker:   deps.NewTracker(tempDir),
+		cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+		basePath:  tempDir,
+	}
+}
+
+func TestTaskGraph(t *testing.T) {
+	c := newGraphCLI(t)
+
+	graph, err := c.taskGraph([]string{"web:test"})
+	if err != nil {
+		t.Fatalf("taskGraph() error = %v", err)
+	}
+	want := map[string][]string{
+		"web:test":  {"web:build"},
+		"web:build": {"lib:build"},
+		"lib:build": nil,
+	}
+	if !reflect.DeepEqual(graph, want) {
+		t.Errorf("taskGraph(web:test) = %v, want %v", graph, want)
+	}
+
+	all, err := c.taskGraph(nil)
+	if err != nil {
+		t.Fatalf("taskGraph() error = %v", err)
+	}
+	if len(all) != 5 {
+		t.Errorf("taskGraph() of every task has %d tasks, want 5", len(all))
+	}
+
+	focused := focusGraph(all, []string{"web:build"})
+	want = map[string][]string{
+		"web:ci":    {"web:test"},
+		"web:test":  {"web:build"},
+		"web:build": {"lib:build"},
+		"lib:build": nil,
+	}
+	if !reflect.DeepEqual(focused, want) {
+		t.Errorf("focusGraph(web:build) = %v, want %v", focused, want)
+	}
+}
+
+func TestWriteGraph(t *testing.T) {
+	c := newGraphCLI(t)
+	graph, err := c.taskGraph([]string{"web:ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "dot",
+			want: `digraph doctrus {
+  node [shape=box];
+  subgraph "cluster_lib" {
+    label="lib";
+    "lib:build" [label="build"];
+    "lib:lint" [label="lint"];
+  }
+  subgraph "cluster_web" {
+    label="web";
+    "web:build" [label="build"];
+    "web:ci" [label="ci", style=dashed];
+    "web:test" [label="test"];
+  }
+  "web:build" -> "lib:build";
+  "web:ci" -> "lib:lint";
+  "web:ci" -> "web:test";
+  "web:test" -> "web:build";
+}
+`,
+		},
+		{
+			format: "mermaid",
+			want: `flowchart TD
+  subgraph w0["lib"]
+    t0["build"]
+    t1["lint"]
+  end
+  subgraph w1["web"]
+    t2["build"]
+    t3(["ci"])
+    t4["test"]
+  end
+  t2 --> t0
+  t3 --> t1
+  t3 --> t4
+  t4 --> t2
+`,
+		},
+		{
+			format: "tree",
+			want: `web:ci [compound]
+├── lib:lint
+└── test
+    └── build
+        └── lib:build
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := c.writeGraph(&out, graph, tt.format); err != nil {
+				t.Fatalf("writeGraph() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("writeGraph() =\n%s\nwant\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/config"
+	"doctrus/internal/docker"
+	"doctrus/internal/history"
+	"doctrus/internal/ui"
+)
+
+var (
+	historyWorkspace string
+	historyTask      string
+	historySince     string
+	historyUntil     string
+	historyLimit     int
+)
+
+func newHistoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show recent runs",
+		Long: `Show recent doctrus runs with the outcome, duration and cache status of
+each task. Runs are recorded in .doctrus/history next to doctrus.yml.
+
+Examples:
+  doctrus history                       # Last 10 runs
+  doctrus history -w frontend -t build  # Runs of frontend:build
+  doctrus history --since 7d            # Runs from the last week
+  doctrus history --since 2024-05-01 --until 2024-05-31`,
+		Args: cobra.NoArgs,
+		RunE: showHistory,
+	}
+
+	addHistoryFilterFlags(cmd)
+	cmd.Flags().IntVarP(&historyLimit, "limit", "n", 10, "Maximum number of runs to show (0 for all)")
+
+	return cmd
+}
+
+func addHistoryFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&historyWorkspace, "workspace", "w", "", "Only include tasks in this workspace")
+	cmd.Flags().StringVarP(&historyTask, "task", "t", "", "Only include tasks with this name")
+	cmd.Flags().StringVar(&historySince, "since", "", "Only include runs after this time (duration like 24h or 7d, date, or RFC 3339)")
+	cmd.Flags().StringVar(&historyUntil, "until", "", "Only include runs before this time")
+}
+
+// historyStore returns the execution history of the project at basePath.
+func historyStore(basePath string) *history.Store {
+	return history.NewStore(filepath.Join(basePath, ".doctrus", "history"))
+}
+
+// loadHistory reads the project's history and applies the filter flags.
+func loadHistory() ([]history.Run, error) {
+	_, configDir, err := config.Locate(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	filter := history.Filter{Workspace: historyWorkspace, Task: historyTask}
+	if filter.Since, err = history.ParseTime(historySince, now); err != nil {
+		return nil, fmt.Errorf("invalid --since: %w", err)
+	}
+	if filter.Until, err = history.ParseTime(historyUntil, now); err != nil {
+		return nil, fmt.Errorf("invalid --until: %w", err)
+	}
+
+	runs, err := historyStore(configDir).Load()
+	if err != nil {
+		return nil, err
+	}
+	return filter.Apply(runs), nil
+}
+
+func showHistory(cmd *cobra.Command, args []string) error {
+	runs, err := loadHistory()
+	if err != nil {
+		return err
+	}
+
+	styler, err := newStyler(os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if len(runs) == 0 {
+		fmt.Println("No runs recorded")
+		return nil
+	}
+
+	if historyLimit > 0 && len(runs) > historyLimit {
+		runs = runs[len(runs)-historyLimit:]
+	}
+
+	// Newest first
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+
+		kind := ui.KindSuccess
+		if !run.Success {
+			kind = ui.KindFailure
+		}
+		summary := fmt.Sprintf("%s  doctrus run %s  (%s)",
+			run.StartedAt.Local().Format("2006-01-02 15:04:05"),
+			strings.Join(run.Args, " "),
+			run.Duration.Round(time.Millisecond))
+		fmt.Println(styler.Status(kind, summary))
+
+		for _, record := range run.Tasks {
+			fmt.Printf("  %s\n", styler.Status(recordKind(record.Outcome), describeRecord(record)))
+		}
+		if run.Error != "" && len(run.Tasks) == 0 {
+			fmt.Printf("  %s\n", run.Error)
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
+
+func recordKind(outcome history.Outcome) ui.Kind {
+	switch outcome {
+	case history.OutcomeCached:
+		return ui.KindCached
+	case history.OutcomeFailed:
+		return ui.KindFailure
+	default:
+		return ui.KindSuccess
+	}
+}
+
+func describeRecord(record history.TaskRecord) string {
+	switch record.Outcome {
+	case history.OutcomeCached:
+		return fmt.Sprintf("%s  cached", record.Key())
+	case history.OutcomeFailed:
+		return fmt.Sprintf("%s  failed with exit code %d in %v%s", record.Key(), record.ExitCode, record.Duration.Round(time.Millisecond), recordUsage(record))
+	default:
+		return fmt.Sprintf("%s  %v%s", record.Key(), record.Duration.Round(time.Millisecond), recordUsage(record))
+	}
+}
+
+// recordUsage renders the resources a recorded task used, if they were
+// measured.
+func recordUsage(record history.TaskRecord) string {
+	usage := formatUsage(docker.ResourceUsage{CPUTime: record.CPUTime, PeakRSS: record.PeakRSS})
+	if usage == "" {
+		return ""
+	}
+	return "  (" + usage + ")"
+}
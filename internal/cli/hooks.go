@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/config"
+	"doctrus/internal/hooks"
+	"doctrus/internal/ui"
+	"doctrus/internal/workspace"
+)
+
+var hooksForce bool
+
+func newHooksCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Manage git hooks that run tasks",
+		Long:  "Install or remove the git hooks configured in the hooks section of doctrus.yml",
+	}
+
+	install := &cobra.Command{
+		Use:   "install",
+		Short: "Install the configured git hooks",
+		Long: `Write a git hook for every entry of the hooks section of doctrus.yml. Each
+hook runs its task specs with doctrus run, so cached tasks whose inputs did
+not change are skipped. Hooks installed earlier but no longer configured are
+removed; existing hooks doctrus did not write are left alone unless --force
+is set.
+
+Example doctrus.yml:
+  hooks:
+    pre-commit: ["*:lint"]
+    pre-push: ["test"]`,
+		Args: cobra.NoArgs,
+		RunE: installHooks,
+	}
+	install.Flags().BoolVar(&hooksForce, "force", false, "Replace existing hooks that were not installed by doctrus")
+
+	uninstall := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the git hooks installed by doctrus",
+		Args:  cobra.NoArgs,
+		RunE:  uninstallHooks,
+	}
+
+	cmd.AddCommand(install, uninstall)
+	return cmd
+}
+
+func installHooks(cmd *cobra.Command, args []string) error {
+	cli, err := newCLI()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(cli.config.Hooks))
+	for name := range cli.config.Hooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, spec := range cli.config.Hooks[name] {
+			if err := cli.checkTaskSpec(spec); err != nil {
+				return fmt.Errorf("hook %s: %w", name, err)
+			}
+		}
+	}
+
+	configFile, _, err := config.Locate(configPath)
+	if err != nil {
+		return err
+	}
+	topLevel, hooksDir, err := hooks.Repository(cli.basePath)
+	if err != nil {
+		return err
+	}
+	relConfig, err := relativePath(topLevel, configFile)
+	if err != nil {
+		return err
+	}
+
+	results, err := hooks.Install(hooksDir, relConfig, cli.config.Hooks, hooksForce)
+	printHookResults(cli.ui, results)
+	if err != nil {
+		return err
+	}
+	if len(cli.config.Hooks) == 0 {
+		fmt.Println("No hooks configured in doctrus.yml")
+	}
+	return nil
+}
+
+func uninstallHooks(cmd *cobra.Command, args []string) error {
+	_, configDir, err := config.Locate(configPath)
+	if err != nil {
+		return err
+	}
+	_, hooksDir, err := hooks.Repository(configDir)
+	if err != nil {
+		return err
+	}
+
+	results, err := hooks.Uninstall(hooksDir)
+	styler, stylerErr := newStyler(os.Stdout)
+	if stylerErr != nil {
+		return stylerErr
+	}
+	printHookResults(styler, results)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Println("No doctrus hooks installed")
+	}
+	return nil
+}
+
+// checkTaskSpec reports a spec that names no task.
+func (c *CLI) checkTaskSpec(spec string) error {
+	workspaceName, taskName := parseTaskSpec(spec)
+	if workspaceName == "" {
+		found, err := c.findTaskInWorkspaces(taskName)
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			return &workspace.TaskNotFoundError{Task: taskName}
+		}
+		return nil
+	}
+	if _, exists := c.config.GetWorkspace(workspaceName); !exists {
+		return &workspace.WorkspaceNotFoundError{Workspace: workspaceName}
+	}
+	if _, exists := c.config.GetTask(workspaceName, taskName); !exists {
+		return &workspace.TaskNotFoundError{Workspace: workspaceName, Task: taskName}
+	}
+	return nil
+}
+
+func printHookResults(styler *ui.Styler, results []hooks.Result) {
+	for _, result := range results {
+		kind := ui.KindSuccess
+		message := fmt.Sprintf("%s %s (%s)", result.Action, result.Hook, result.Path)
+		if result.Action == hooks.ActionSkipped {
+			kind = ui.KindWarning
+			message = fmt.Sprintf("%s %s: %s", result.Action, result.Hook, result.Reason)
+		}
+		fmt.Println(styler.Status(kind, message))
+	}
+}
+
+// relativePath returns target relative to base, resolving symlinks in both
+// so that paths like /tmp and /private/tmp compare equal.
+func relativePath(base, target string) (string, error) {
+	if resolved, err := filepath.EvalSymlinks(base); err == nil {
+		base = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(target); err == nil {
+		target = resolved
+	}
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return "", fmt.Errorf("config file %s is outside the repository: %w", target, err)
+	}
+	return rel, nil
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/config"
+	"doctrus/internal/ui"
+	"doctrus/internal/workspace"
+)
+
+func newInfoCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "info <workspace:task>",
+		Short: "Show everything resolved for a task",
+		Long: `Show how doctrus resolves a single task: the command it runs, its working
+directory, where it runs and with which compose file, its merged environment
+with the layers each value came from, how many files each input and output
+pattern matches, its direct dependencies and the state of its cache entry.
+
+Examples:
+  doctrus info frontend:build
+  doctrus info frontend:build -e NODE_ENV=test`,
+		Args: cobra.ExactArgs(1),
+		RunE: showInfo,
+	}
+
+	cmd.Flags().StringArrayVarP(&envFlags, "env", "e", nil, "Set a task environment variable (KEY=VALUE, repeatable)")
+
+	return cmd
+}
+
+func showInfo(cmd *cobra.Command, args []string) error {
+	cli, err := newCLI()
+	if err != nil {
+		return err
+	}
+	cliEnv, err := parseEnvFlags(envFlags)
+	if err != nil {
+		return err
+	}
+
+	workspaceName, taskName := cli.resolveTaskSpec(args[0])
+	if workspaceName == "" {
+		return fmt.Errorf("invalid task %q (expected workspace:task)", args[0])
+	}
+	if _, exists := cli.config.GetTask(workspaceName, taskName); !exists {
+		return &workspace.TaskNotFoundError{Workspace: workspaceName, Task: taskName}
+	}
+
+	return cli.printInfo(os.Stdout, workspaceName, taskName, cliEnv)
+}
+
+// printInfo writes the resolved details of one task to w.
+func (c *CLI) printInfo(w io.Writer, workspaceName, taskName string, cliEnv map[string]string) error {
+	execution, err := c.workspace.ResolveTaskExecution(workspaceName, taskName)
+	if err != nil {
+		return err
+	}
+	task := execution.Task
+	taskKey := workspaceName + ":" + taskName
+
+	fmt.Fprintln(w, c.ui.Status(ui.KindHeader, taskKey))
+	details := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if task.Description != "" {
+		fmt.Fprintf(details, "  Description:\t%s\n", task.Description)
+	}
+	command := "(none, runs its dependencies only)"
+	if len(task.Command) > 0 {
+		command = strings.Join(c.config.WrapCommand(workspaceName, taskName, task.Command), " ")
+	}
+	fmt.Fprintf(details, "  Command:\t%s\n", command)
+	fmt.Fprintf(details, "  Working dir:\t%s\n", execution.AbsPath)
+	fmt.Fprintf(details, "  Executor:\t%s\n", c.planEnvironment(execution))
+	if len(task.Command) > 0 && c.config.GetEffectiveExecutor(workspaceName, taskName) == config.ExecutorComposeExec {
+		fmt.Fprintf(details, "  Compose file:\t%s\n", c.composeFile(workspaceName, taskName))
+	}
+
+	deps, err := c.workspace.Dependencies(workspaceName, taskName)
+	if err != nil {
+		return err
+	}
+	dependencies := "(none)"
+	if len(deps) > 0 {
+		dependencies = strings.Join(deps, ", ")
+	}
+	fmt.Fprintf(details, "  Dependencies:\t%s\n", dependencies)
+	fmt.Fprintf(details, "  Cache:\t%s\n", c.infoCacheState(execution))
+	if err := details.Flush(); err != nil {
+		return err
+	}
+
+	vars, err := c.config.ResolveEnv(c.basePath, workspaceName, taskName, execution.AbsPath, cliEnv)
+	if err != nil {
+		return fmt.Errorf("failed to resolve environment of %s: %w", taskKey, err)
+	}
+	header := "Environment"
+	if task.Hermetic {
+		header += " (hermetic)"
+	}
+	fmt.Fprintf(w, "\n%s\n", header)
+	env := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, variable := range vars {
+		fmt.Fprintf(env, "  %s=%s\t%s\n", variable.Name, variable.Value, strings.Join(variable.Sources, ", "))
+	}
+	if len(vars) == 0 {
+		fmt.Fprintln(env, "  (no variables)")
+	}
+	if err := env.Flush(); err != nil {
+		return err
+	}
+
+	for _, group := range []struct {
+		name     string
+		patterns []string
+	}{
+		{"Inputs", task.Inputs},
+		{"Outputs", task.Outputs},
+	} {
+		fmt.Fprintf(w, "\n%s\n", group.name)
+		if err := c.printPatternMatches(w, execution, group.patterns); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printPatternMatches lists glob patterns with the number of files each
+// matches.
+func (c *CLI) printPatternMatches(w io.Writer, execution *workspace.TaskExecution, patterns []string) error {
+	if len(patterns) == 0 {
+		fmt.Fprintln(w, "  (none)")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, pattern := range patterns {
+		matches, err := c.tracker.MatchFiles(execution, pattern)
+		if err != nil {
+			fmt.Fprintf(tw, "  %s\t%s\n", pattern, c.ui.Paint(ui.KindFailure, err.Error()))
+			continue
+		}
+		count := fmt.Sprintf("%d %s", len(matches), plural(len(matches), "file", "files"))
+		if len(matches) == 0 {
+			count = c.ui.Paint(ui.KindWarning, count)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", pattern, count)
+	}
+	return tw.Flush()
+}
+
+// composeFile returns the absolute path of the compose file a task runs
+// with, defaulting to docker-compose.yml in the project directory.
+func (c *CLI) composeFile(workspaceName, taskName string) string {
+	composeFile := c.config.GetEffectiveDockerConfig(workspaceName, taskName).ComposeFile
+	if composeFile == "" {
+		composeFile = "docker-compose.yml"
+	}
+	if !filepath.IsAbs(composeFile) {
+		composeFile = filepath.Join(c.basePath, composeFile)
+	}
+	return composeFile
+}
+
+// infoCacheState describes the task's cache entry and whether it is still
+// current.
+func (c *CLI) infoCacheState(execution *workspace.TaskExecution) string {
+	task := execution.Task
+	switch {
+	case len(task.Command) == 0:
+		return "compound"
+	case len(task.DependsOnFiles) > 0:
+		reason, err := c.tracker.StaleFileTargets(execution)
+		switch {
+		case err != nil:
+			return fmt.Sprintf("unknown (%v)", err)
+		case reason == "":
+			return "up to date (targets newer than sources)"
+		}
+		return "stale (" + reason + ")"
+	case !task.Cache:
+		return "disabled"
+	}
+
+	previous, err := c.cache.Get(execution.WorkspaceName + ":" + execution.TaskName)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("unreadable (%v)", err)
+	case previous == nil:
+		return "empty"
+	}
+	age := formatDuration(time.Since(previous.LastRun))
+	shouldRun, err := c.tracker.ShouldRunTask(execution, previous)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("unknown (%v)", err)
+	case shouldRun:
+		return fmt.Sprintf("stale (cached %s ago, inputs or outputs changed)", age)
+	}
+	return fmt.Sprintf("cached %s ago", age)
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/workspace"
+)
+
+func TestPrintInfo(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"src/a.ts", "src/b.ts", "package.json"} {
+		path := filepath.Join(tempDir, "web", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{
+		Version: "1.0",
+		Docker:  config.DockerConfig{ComposeFile: "compose.yml"},
+		Workspaces: map[string]config.Workspace{
+			"lib": {
+				Path:  "lib",
+				Tasks: map[string]config.Task{"build": {Command: []string{"true"}}},
+			},
+			"web": {
+				Path:      "web",
+				Container: "node",
+				Env:       map[string]string{"NODE_ENV": "production"},
+				Tasks: map[string]config.Task{
+					"build": {
+						Description: "Build the app",
+						Command:     []string{"npm", "run", "build"},
+						DependsOn:   []string{"lib:build"},
+						Inputs:      []string{"src/**", "package.json"},
+						Outputs:     []string{"dist/**"},
+						Cache:       true,
+					},
+					"ci": {DependsOn: []string{"build"}},
+				},
+			},
+		},
+	}
+	c := &CLI{
+		config:    cfg,
+		workspace: workspace.NewManager(cfg, tempDir),
+		tracker:   deps.NewTracker(tempDir),
+		cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+		basePath:  tempDir,
+	}
+
+	tests := []struct {
+		task string
+		want []string
+	}{
+		{
+			task: "build",
+			want: []string{
+				"Description:   Build the app",
+				"Command:       npm run build",
+				"Working dir:   " + filepath.Join(tempDir, "web"),
+				"Executor:      compose-exec (node)",
+				"Compose file:  " + filepath.Join(tempDir, "compose.yml"),
+				"Dependencies:  lib:build",
+				"Cache:         empty",
+				"NODE_ENV=production  workspace",
+				"src/**        2 files",
+				"package.json  1 file",
+				"dist/**  0 files",
+			},
+		},
+		{
+			task: "ci",
+			want: []string{
+				"Command:       (none, runs its dependencies only)",
+				"Dependencies:  web:build",
+				"Cache:         compound",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.task, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := c.printInfo(&out, "web", tt.task, nil); err != nil {
+				t.Fatalf("printInfo() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("printInfo() output missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
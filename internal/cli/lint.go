@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/config"
+	"doctrus/internal/lint"
+)
+
+var lintFix bool
+
+func newLintCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check configuration for best practices",
+		Long: `Check doctrus.yml against best-practice rules such as caching without
+declared inputs or containers without a compose file. Issues are reported
+with a severity; the command fails when any error is found.
+
+Examples:
+  doctrus lint          # Report issues
+  doctrus lint --fix    # Apply safe fixes to doctrus.yml`,
+		Args: cobra.NoArgs,
+		RunE: lintConfig,
+	}
+
+	cmd.Flags().BoolVar(&lintFix, "fix", false, "Apply safe fixes to the configuration file")
+
+	return cmd
+}
+
+func lintConfig(cmd *cobra.Command, args []string) error {
+	cfg, configDir, err := config.LoadProfile(configPath, nil, activeProfile())
+	if err != nil {
+		if diagnostics, ok := config.AsDiagnostics(err); ok {
+			for _, diagnostic := range diagnostics {
+				fmt.Printf("✗ %-7s %s\n", lint.SeverityError, diagnostic)
+			}
+			fmt.Printf("\nFound %d configuration error(s)\n", len(diagnostics))
+			return fmt.Errorf("lint found %d error(s)", len(diagnostics))
+		}
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	issues := lint.Run(cfg, configDir)
+
+	if lintFix && len(issues) > 0 {
+		path, _, err := config.Locate(configPath)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read config file %s: %w", path, err)
+		}
+
+		fixedData, fixed, err := lint.Fix(data, issues, configDir)
+		if err != nil {
+			return err
+		}
+		if fixed > 0 {
+			if err := os.WriteFile(path, fixedData, 0644); err != nil {
+				return fmt.Errorf("failed to write config file %s: %w", path, err)
+			}
+			fmt.Printf("✓ Applied %d fix(es) to %s\n", fixed, path)
+
+			if cfg, _, err = config.LoadProfile(path, nil, activeProfile()); err != nil {
+				return fmt.Errorf("failed to reload config: %w", err)
+			}
+			issues = lint.Run(cfg, configDir)
+		}
+	}
+
+	if len(issues) == 0 {
+		fmt.Println("✓ No lint issues found")
+		return nil
+	}
+
+	counts := make(map[lint.Severity]int)
+	for _, issue := range issues {
+		counts[issue.Severity]++
+		symbol := "ℹ️ "
+		switch issue.Severity {
+		case lint.SeverityError:
+			symbol = "✗"
+		case lint.SeverityWarning:
+			symbol = "⚠️ "
+		}
+		fmt.Printf("%s %-7s %s [%s] %s", symbol, issue.Severity, issue.Location(), issue.Rule, issue.Message)
+		if issue.Fixable && !lintFix {
+			fmt.Printf(" (fixable with --fix)")
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("\nFound %d issue(s): %d error(s), %d warning(s), %d info\n",
+		len(issues), counts[lint.SeverityError], counts[lint.SeverityWarning], counts[lint.SeverityInfo])
+
+	if counts[lint.SeverityError] > 0 {
+		return fmt.Errorf("lint found %d error(s)", counts[lint.SeverityError])
+	}
+	return nil
+}
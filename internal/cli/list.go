@@ -2,9 +2,20 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"slices"
 	"strings"
 
 	"github.com/spf13/cobra"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+var (
+	listTree bool
+	// listInternal also lists internal tasks, which are hidden by default
+	listInternal bool
 )
 
 func newListCommand() *cobra.Command {
@@ -16,19 +27,58 @@ func newListCommand() *cobra.Command {
 
 Examples:
   doctrus list                # List all workspaces and tasks
-  doctrus list frontend       # List tasks in frontend workspace`,
+  doctrus list frontend       # List tasks in frontend workspace
+  doctrus list --tree         # Dependency tree of every top-level task
+  doctrus list --tag services # Tasks tagged services or in a workspace tagged so
+  doctrus list --all          # Include internal tasks
+  doctrus list --tree web:deploy  # Dependency tree of one task
+  doctrus list --output json  # Workspaces and tasks for scripts`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: listWorkspaces,
 	}
 
+	cmd.Flags().BoolVar(&listTree, "tree", false, "Show tasks with their transitive dependencies as a tree")
+	cmd.Flags().BoolVar(&listInternal, "all", false, "Also list internal tasks, which only run as dependencies")
+	cmd.Flags().StringSliceVar(&tagFilters, "tag", nil, "Only list tasks that have one of these tags, on the task or its workspace (repeatable)")
+	cmd.Flags().StringVar(&outputFormat, "output", outputText, "Output format: text or json")
+
 	return cmd
 }
 
 func listWorkspaces(cmd *cobra.Command, args []string) error {
+	if err := parseOutputFormat(outputFormat); err != nil {
+		return err
+	}
+	if listTree && outputFormat == outputJSON {
+		return fmt.Errorf("--tree does not support --output json; use doctrus graph for the task graph")
+	}
 	cli, err := newCLI()
 	if err != nil {
 		return err
 	}
+	if outputFormat == outputJSON {
+		workspaces, err := cli.listedWorkspaces(args)
+		if err != nil {
+			return err
+		}
+		return writeJSON(os.Stdout, workspaces)
+	}
+
+	if listTree {
+		spec := ""
+		if len(args) == 1 {
+			spec = args[0]
+		}
+		roots, err := cli.treeRoots(spec)
+		if err != nil {
+			return err
+		}
+		roots = slices.DeleteFunc(roots, func(key string) bool {
+			workspaceName, taskName := parseTaskSpec(key)
+			return !cli.workspace.MatchesTags(workspaceName, taskName, tagFilters)
+		})
+		return cli.printTree(os.Stdout, roots)
+	}
 
 	if len(args) == 1 {
 		return cli.listWorkspaceTasks(args[0])
@@ -38,9 +88,13 @@ func listWorkspaces(cmd *cobra.Command, args []string) error {
 }
 
 func (c *CLI) listAllWorkspaces() error {
-	workspaces := c.workspace.GetWorkspaces()
-	
+	workspaces := c.workspace.FilterWorkspaces(tagFilters)
+
 	if len(workspaces) == 0 {
+		if len(tagFilters) > 0 {
+			fmt.Printf("No tasks have any of the tags %s\n", strings.Join(tagFilters, ", "))
+			return nil
+		}
 		fmt.Println("No workspaces found")
 		return nil
 	}
@@ -56,19 +110,21 @@ func (c *CLI) listAllWorkspaces() error {
 		if workspace.Container != "" {
 			fmt.Printf(" [%s]", workspace.Container)
 		}
+		fmt.Print(formatTags(workspace.Tags))
 		fmt.Println()
 
-		tasks, _ := c.workspace.GetTasks(workspaceName)
+		tasks, _ := c.listedTasks(workspaceName)
 		if len(tasks) > 0 {
 			for _, taskName := range tasks {
 				task, _ := c.config.GetTask(workspaceName, taskName)
-				fmt.Printf("  ├─ %s", taskName)
+				fmt.Printf("  ├─ %s%s%s", taskName, formatAliases(task.Aliases), formatInternal(task))
 				if task.Description != "" {
 					fmt.Printf(": %s", task.Description)
 				}
 				if len(task.DependsOn) > 0 {
 					fmt.Printf(" (depends: %s)", strings.Join(task.DependsOn, ", "))
 				}
+				fmt.Print(formatTags(task.Tags))
 				fmt.Println()
 			}
 		}
@@ -79,23 +135,24 @@ func (c *CLI) listAllWorkspaces() error {
 }
 
 func (c *CLI) listWorkspaceTasks(workspaceName string) error {
-	workspace, exists := c.config.GetWorkspace(workspaceName)
+	ws, exists := c.config.GetWorkspace(workspaceName)
 	if !exists {
-		return fmt.Errorf("workspace %s not found", workspaceName)
+		return &workspace.WorkspaceNotFoundError{Workspace: workspaceName}
 	}
 
-	tasks, err := c.workspace.GetTasks(workspaceName)
+	tasks, err := c.listedTasks(workspaceName)
 	if err != nil {
 		return err
 	}
 
 	fmt.Printf("Workspace: %s", workspaceName)
-	if workspace.Path != "" {
-		fmt.Printf(" (%s)", workspace.Path)
+	if ws.Path != "" {
+		fmt.Printf(" (%s)", ws.Path)
 	}
-	if workspace.Container != "" {
-		fmt.Printf(" [%s]", workspace.Container)
+	if ws.Container != "" {
+		fmt.Printf(" [%s]", ws.Container)
 	}
+	fmt.Print(formatTags(ws.Tags))
 	fmt.Println()
 
 	if len(tasks) == 0 {
@@ -106,10 +163,11 @@ func (c *CLI) listWorkspaceTasks(workspaceName string) error {
 	fmt.Printf("\nTasks (%d):\n", len(tasks))
 	for _, taskName := range tasks {
 		task, _ := c.config.GetTask(workspaceName, taskName)
-		fmt.Printf("  %s", taskName)
+		fmt.Printf("  %s%s%s", taskName, formatAliases(task.Aliases), formatInternal(task))
 		if task.Description != "" {
 			fmt.Printf(": %s", task.Description)
 		}
+		fmt.Print(formatTags(task.Tags))
 		fmt.Println()
 
 		if verbose {
@@ -131,4 +189,110 @@ func (c *CLI) listWorkspaceTasks(workspaceName string) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// listedWorkspace is a workspace as doctrus list --output json prints it.
+type listedWorkspace struct {
+	Name      string       `json:"name"`
+	Path      string       `json:"path"`
+	Container string       `json:"container,omitempty"`
+	Tags      []string     `json:"tags,omitempty"`
+	Tasks     []listedTask `json:"tasks"`
+}
+
+// listedTask is a task as doctrus list --output json prints it.
+type listedTask struct {
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Command     []string `json:"command,omitempty"`
+	DependsOn   []string `json:"depends_on,omitempty"`
+	Inputs      []string `json:"inputs,omitempty"`
+	Outputs     []string `json:"outputs,omitempty"`
+	Cache       bool     `json:"cache"`
+	Tags        []string `json:"tags,omitempty"`
+	Internal    bool     `json:"internal,omitempty"`
+}
+
+// listedWorkspaces returns the workspaces doctrus list shows, which is the
+// one named in args or every workspace with a task matching --tag.
+func (c *CLI) listedWorkspaces(args []string) ([]listedWorkspace, error) {
+	names := c.workspace.FilterWorkspaces(tagFilters)
+	if len(args) == 1 {
+		if _, exists := c.config.GetWorkspace(args[0]); !exists {
+			return nil, &workspace.WorkspaceNotFoundError{Workspace: args[0]}
+		}
+		names = []string{args[0]}
+	}
+
+	listed := make([]listedWorkspace, 0, len(names))
+	for _, workspaceName := range names {
+		ws, _ := c.config.GetWorkspace(workspaceName)
+		tasks, err := c.listedTasks(workspaceName)
+		if err != nil {
+			return nil, err
+		}
+		entry := listedWorkspace{
+			Name:      workspaceName,
+			Path:      ws.Path,
+			Container: ws.Container,
+			Tags:      ws.Tags,
+			Tasks:     make([]listedTask, 0, len(tasks)),
+		}
+		for _, taskName := range tasks {
+			task, _ := c.config.GetTask(workspaceName, taskName)
+			entry.Tasks = append(entry.Tasks, listedTask{
+				Name:        taskName,
+				Aliases:     task.Aliases,
+				Description: task.Description,
+				Command:     task.Command,
+				DependsOn:   task.DependsOn,
+				Inputs:      task.Inputs,
+				Outputs:     task.Outputs,
+				Cache:       task.Cache,
+				Tags:        task.Tags,
+				Internal:    task.Internal,
+			})
+		}
+		listed = append(listed, entry)
+	}
+	return listed, nil
+}
+
+// listedTasks returns the tasks of a workspace doctrus list shows: those
+// matching --tag, without internal tasks unless --all is given.
+func (c *CLI) listedTasks(workspaceName string) ([]string, error) {
+	tasks, err := c.workspace.FilterTasks(workspaceName, tagFilters)
+	if err != nil || listInternal {
+		return tasks, err
+	}
+	return slices.DeleteFunc(tasks, func(taskName string) bool {
+		task, _ := c.config.GetTask(workspaceName, taskName)
+		return task.Internal
+	}), nil
+}
+
+// formatInternal marks internal tasks in doctrus list --all.
+func formatInternal(task *config.Task) string {
+	if !task.Internal {
+		return ""
+	}
+	return " (internal)"
+}
+
+// formatAliases renders the aliases of a task after its name in doctrus
+// list.
+func formatAliases(aliases []string) string {
+	if len(aliases) == 0 {
+		return ""
+	}
+	return " (aliases: " + strings.Join(aliases, ", ") + ")"
+}
+
+// formatTags renders tags after a workspace or task in doctrus list.
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return " #" + strings.Join(tags, " #")
+}
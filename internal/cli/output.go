@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"doctrus/internal/events"
+)
+
+// Formats of --output
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// outputFormat is the --output of run, list and validate.
+var outputFormat string
+
+// parseOutputFormat checks the value of --output.
+func parseOutputFormat(value string) error {
+	switch value {
+	case "", outputText, outputJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid output format %q (expected text or json)", value)
+	}
+}
+
+// writeJSON writes value to w as indented JSON.
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// runFinished is the type of the record ending the output of
+// doctrus run --output json.
+const runFinished = "run_finished"
+
+// runResult is the record doctrus run --output json writes after the task
+// events, summing up the run.
+type runResult struct {
+	Type     string        `json:"type"`
+	Status   string        `json:"status"`
+	ExitCode int           `json:"exit_code"`
+	Duration time.Duration `json:"duration_ns"`
+	Error    string        `json:"error,omitempty"`
+	Tasks    []taskResult  `json:"tasks"`
+}
+
+// taskResult is how a task of the run ended.
+type taskResult struct {
+	Task     string        `json:"task"`
+	Status   string        `json:"status"`
+	Duration time.Duration `json:"duration_ns"`
+	ExitCode int           `json:"exit_code"`
+	CacheHit bool          `json:"cache_hit"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// runResults collects the finished tasks of a run for its runResult, in the
+// order they finished. A nil runResults collects nothing.
+type runResults struct {
+	out     io.Writer
+	started time.Time
+
+	mu    sync.Mutex
+	tasks []taskResult
+}
+
+func newRunResults(out io.Writer) *runResults {
+	return &runResults{out: out, started: time.Now(), tasks: []taskResult{}}
+}
+
+func (r *runResults) Handle(e events.Event) {
+	if e.Type != events.TaskFinished {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks = append(r.tasks, taskResult{
+		Task:     e.Key(),
+		Status:   e.Status,
+		Duration: e.Duration,
+		ExitCode: e.ExitCode,
+		CacheHit: e.Status == events.StatusCached,
+		Error:    e.Error,
+	})
+}
+
+// write writes the runResult of a run that ended with runErr as a line of
+// JSON.
+func (r *runResults) write(runErr error) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := runResult{
+		Type:     runFinished,
+		Status:   events.StatusSuccess,
+		Duration: time.Since(r.started),
+		Tasks:    r.tasks,
+	}
+	if runErr != nil {
+		result.Status = events.StatusFailed
+		result.ExitCode = GetExitCode(runErr)
+		if result.ExitCode == 0 {
+			result.ExitCode = 1
+		}
+		result.Error = runErr.Error()
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = r.out.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"doctrus/internal/config"
+	"doctrus/internal/events"
+	"doctrus/internal/workspace"
+)
+
+func TestRunResults(t *testing.T) {
+	tests := []struct {
+		name         string
+		runErr       error
+		wantStatus   string
+		wantExitCode int
+	}{
+		{name: "success", wantStatus: events.StatusSuccess},
+		{name: "task failed", runErr: &workspace.TaskFailedError{Workspace: "web", Task: "test", ExitCode: 2}, wantStatus: events.StatusFailed, wantExitCode: 2},
+		{name: "other error", runErr: errors.New("boom"), wantStatus: events.StatusFailed, wantExitCode: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			results := newRunResults(&out)
+			bus := events.NewBus()
+			bus.Subscribe(results)
+			bus.Publish(events.Event{Type: events.TaskStarted, Workspace: "lib", Task: "build"})
+			bus.Publish(events.Event{Type: events.TaskFinished, Workspace: "lib", Task: "build", Status: events.StatusCached, Duration: time.Millisecond})
+			bus.Publish(events.Event{Type: events.TaskFinished, Workspace: "web", Task: "test", Status: events.StatusFailed, ExitCode: 2, Error: "exit status 2"})
+
+			if err := results.write(tt.runErr); err != nil {
+				t.Fatalf("write() error = %v", err)
+			}
+			var result runResult
+			if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+				t.Fatalf("invalid result %q: %v", out.String(), err)
+			}
+			if result.Type != runFinished || result.Status != tt.wantStatus || result.ExitCode != tt.wantExitCode {
+				t.Fatalf("result = %+v, want status %s and exit code %d", result, tt.wantStatus, tt.wantExitCode)
+			}
+			want := []taskResult{
+				{Task: "lib:build", Status: events.StatusCached, Duration: time.Millisecond, CacheHit: true},
+				{Task: "web:test", Status: events.StatusFailed, ExitCode: 2, Error: "exit status 2"},
+			}
+			if len(result.Tasks) != len(want) {
+				t.Fatalf("tasks = %+v, want %+v", result.Tasks, want)
+			}
+			for i := range want {
+				if result.Tasks[i] != want[i] {
+					t.Fatalf("task %d = %+v, want %+v", i, result.Tasks[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestListedWorkspaces(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"app": {
+				Path: "app",
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"make"}, Outputs: []string{"dist/**"}, Cache: true},
+					"all":   {DependsOn: []string{"build"}, Description: "Everything"},
+				},
+			},
+			"lib": {Path: "lib", Tasks: map[string]config.Task{}},
+		},
+	}
+	c := &CLI{config: cfg, workspace: workspace.NewManager(cfg, tempDir), basePath: tempDir}
+
+	listed, err := c.listedWorkspaces([]string{"app"})
+	if err != nil {
+		t.Fatalf("listedWorkspaces() error = %v", err)
+	}
+	data, err := json.Marshal(listed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"name":"app","path":"app","tasks":[` +
+		`{"name":"all","description":"Everything","depends_on":["build"],"cache":false},` +
+		`{"name":"build","command":["make"],"outputs":["dist/**"],"cache":true}]}]`
+	if string(data) != want {
+		t.Fatalf("listed = %s, want %s", data, want)
+	}
+
+	all, err := c.listedWorkspaces(nil)
+	if err != nil {
+		t.Fatalf("listedWorkspaces(nil) error = %v", err)
+	}
+	if len(all) != 2 || all[1].Name != "lib" || all[1].Tasks == nil {
+		t.Fatalf("expected both workspaces with a task list, got %+v", all)
+	}
+
+	var notFound *workspace.WorkspaceNotFoundError
+	if _, err := c.listedWorkspaces([]string{"missing"}); !errors.As(err, &notFound) {
+		t.Fatalf("listedWorkspaces(missing) error = %v, want WorkspaceNotFoundError", err)
+	}
+}
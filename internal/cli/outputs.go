@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/deps"
+	"doctrus/internal/ui"
+	"doctrus/internal/workspace"
+)
+
+func newOutputsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "outputs [workspace[:task]]",
+		Short: "Inspect declared task outputs",
+		Long: `List the outputs declared by each task, resolve their globs and report
+which files exist, their sizes and whether they still match the hashes
+recorded in the cache.
+
+Examples:
+  doctrus outputs                  # All tasks with outputs
+  doctrus outputs frontend         # Tasks in the frontend workspace
+  doctrus outputs frontend:build   # A single task`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: showOutputs,
+	}
+
+	return cmd
+}
+
+func showOutputs(cmd *cobra.Command, args []string) error {
+	cli, err := newCLI()
+	if err != nil {
+		return err
+	}
+
+	filterWorkspace, filterTask := "", ""
+	if len(args) == 1 {
+		filterWorkspace, filterTask = cli.resolveTaskSpec(args[0])
+		if filterTask != "" && filterWorkspace == "" {
+			// A bare argument names a workspace
+			filterWorkspace, filterTask = filterTask, ""
+		}
+		if _, exists := cli.config.GetWorkspace(filterWorkspace); !exists {
+			return &workspace.WorkspaceNotFoundError{Workspace: filterWorkspace}
+		}
+		if filterTask != "" {
+			if _, exists := cli.config.GetTask(filterWorkspace, filterTask); !exists {
+				return &workspace.TaskNotFoundError{Workspace: filterWorkspace, Task: filterTask}
+			}
+		}
+	}
+
+	counts := make(map[deps.OutputStatus]int)
+	shown := 0
+	for _, workspaceName := range cli.workspace.GetWorkspaces() {
+		if filterWorkspace != "" && workspaceName != filterWorkspace {
+			continue
+		}
+		tasks, err := cli.workspace.GetTasks(workspaceName)
+		if err != nil {
+			return err
+		}
+		sort.Strings(tasks)
+
+		for _, taskName := range tasks {
+			if filterTask != "" && taskName != filterTask {
+				continue
+			}
+			task, _ := cli.config.GetTask(workspaceName, taskName)
+			if len(task.Outputs) == 0 {
+				continue
+			}
+			if err := cli.printTaskOutputs(workspaceName, taskName, counts); err != nil {
+				return err
+			}
+			shown++
+		}
+	}
+
+	if shown == 0 {
+		fmt.Println("No tasks declare outputs")
+		return nil
+	}
+
+	fmt.Printf("%d file(s): %d matching cache, %d modified, %d not cached, %d missing\n",
+		counts[deps.OutputMatches]+counts[deps.OutputModified]+counts[deps.OutputUncached]+counts[deps.OutputMissing],
+		counts[deps.OutputMatches], counts[deps.OutputModified], counts[deps.OutputUncached], counts[deps.OutputMissing])
+
+	return nil
+}
+
+func (c *CLI) printTaskOutputs(workspaceName, taskName string, counts map[deps.OutputStatus]int) error {
+	execution, err := c.workspace.ResolveTaskExecution(workspaceName, taskName)
+	if err != nil {
+		return err
+	}
+
+	taskKey := fmt.Sprintf("%s:%s", workspaceName, taskName)
+	state, err := c.cache.Get(taskKey)
+	if err != nil {
+		return fmt.Errorf("failed to load cache for %s: %w", taskKey, err)
+	}
+
+	header := taskKey
+	switch {
+	case !execution.Task.Cache:
+		header += " (cache disabled)"
+	case state == nil:
+		header += " (not cached)"
+	default:
+		header += fmt.Sprintf(" (cached %s ago)", formatDuration(time.Since(state.LastRun)))
+	}
+	fmt.Println(c.ui.Status(ui.KindHeader, header))
+
+	reports, err := c.tracker.InspectOutputs(execution, state)
+	if err != nil {
+		return fmt.Errorf("failed to inspect outputs of %s: %w", taskKey, err)
+	}
+
+	for _, report := range reports {
+		fmt.Printf("  %s\n", report.Pattern)
+		if len(report.Files) == 0 {
+			fmt.Println("    (no files)")
+			continue
+		}
+		for _, file := range report.Files {
+			counts[file.Status]++
+			kind, description := outputStatusKind(file.Status)
+			line := fmt.Sprintf("%s  %s", file.Path, description)
+			if file.Status != deps.OutputMissing {
+				line = fmt.Sprintf("%s  %s  %s", file.Path, formatBytes(file.Size), description)
+			}
+			fmt.Printf("    %s\n", c.ui.Status(kind, line))
+		}
+	}
+	fmt.Println()
+
+	return nil
+}
+
+func outputStatusKind(status deps.OutputStatus) (ui.Kind, string) {
+	switch status {
+	case deps.OutputMatches:
+		return ui.KindSuccess, "matches cache"
+	case deps.OutputModified:
+		return ui.KindWarning, "modified since cached"
+	case deps.OutputMissing:
+		return ui.KindFailure, "missing (recorded in cache)"
+	default:
+		return ui.KindInfo, "not in cache"
+	}
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
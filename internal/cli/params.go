@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"doctrus/internal/taskrun"
+)
+
+// parseParamFlags parses the NAME=VALUE pairs given with --param.
+func parseParamFlags(values []string) (map[string]string, error) {
+	params := make(map[string]string, len(values))
+	for _, value := range values {
+		name, val, ok := strings.Cut(value, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --param %q (expected NAME=VALUE)", value)
+		}
+		params[name] = val
+	}
+	return params, nil
+}
+
+// applyParams substitutes the params of every task the run executes into
+// its command and env, from values or their defaults. It fails when a task
+// misses a required param, or when a value is given for a param no task of
+// the run declares.
+func (c *CLI) applyParams(specs []string, values map[string]string) error {
+	if len(values) == 0 && !c.config.HasParams() {
+		return nil
+	}
+	targets, err := c.resolveTargets(specs)
+	if err != nil {
+		return err
+	}
+	executions, err := c.resolveExecutions(targets)
+	if err != nil {
+		return err
+	}
+
+	applied, err := taskrun.ApplyParams(executions, values)
+	if err != nil {
+		return err
+	}
+	for i, execution := range applied {
+		if execution == executions[i] {
+			continue
+		}
+		if err := c.workspace.UpdateTask(execution.WorkspaceName, execution.TaskName, *execution.Task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+func TestApplyParams(t *testing.T) {
+	newTestCLI := func() *CLI {
+		cfg := &config.Config{
+			Version: "1.0",
+			Workspaces: map[string]config.Workspace{
+				"api": {Tasks: map[string]config.Task{
+					"deploy": {
+						Command:   []string{"./deploy.sh", "{{.params.env}}"},
+						DependsOn: []string{"build"},
+						Params:    []config.TaskParam{{Name: "env", Required: true}},
+					},
+					"build": {
+						Command: []string{"go", "build", "-tags", "{{.params.tags}}"},
+						Params:  []config.TaskParam{{Name: "tags", Default: "netgo"}},
+					},
+					"lint": {
+						Command: []string{"golangci-lint", "run"},
+						Params:  []config.TaskParam{{Name: "fix", Required: true}},
+					},
+				}},
+			},
+		}
+		return &CLI{config: cfg, workspace: workspace.NewManager(cfg, t.TempDir())}
+	}
+
+	cli := newTestCLI()
+	if err := cli.applyParams([]string{"api:deploy"}, map[string]string{"env": "prod"}); err != nil {
+		t.Fatalf("applyParams() error = %v", err)
+	}
+	deploy, err := cli.workspace.ResolveTaskExecution("api", "deploy")
+	if err != nil {
+		t.Fatalf("ResolveTaskExecution() error = %v", err)
+	}
+	if !reflect.DeepEqual(deploy.Task.Command, []string{"./deploy.sh", "prod"}) {
+		t.Errorf("deploy command = %v", deploy.Task.Command)
+	}
+	if got := cli.config.Workspaces["api"].Tasks["build"].Command; !reflect.DeepEqual(got, []string{"go", "build", "-tags", "netgo"}) {
+		t.Errorf("build command = %v, want the default of its param", got)
+	}
+
+	tests := []struct {
+		name    string
+		specs   []string
+		values  map[string]string
+		wantErr string
+	}{
+		{name: "missing required", specs: []string{"api:deploy"}, wantErr: "task api:deploy: missing required param env"},
+		{name: "unknown param", specs: []string{"api:build"}, values: map[string]string{"env": "prod"}, wantErr: "unknown param env"},
+		{name: "required param of a task outside the run", specs: []string{"api:build"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestCLI().applyParams(tt.specs, tt.values)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("applyParams() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("applyParams() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := parseParamFlags([]string{"env"}); err == nil {
+		t.Error("parseParamFlags() accepted a value without =")
+	}
+}
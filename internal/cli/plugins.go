@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/config"
+	"doctrus/internal/plugin"
+)
+
+func newPluginsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugins",
+		Short: "List available plugins",
+		Long: `List plugins that can be invoked as doctrus subcommands.
+
+Plugins are executables named doctrus-<name> on PATH, or commands declared
+under the plugins section of doctrus.yml. They receive the resolved
+configuration and task graph as JSON on stdin.
+
+Examples:
+  doctrus plugins             # List discovered plugins
+  doctrus deploy --env prod   # Invoke the 'deploy' plugin`,
+		Args: cobra.NoArgs,
+		RunE: listPlugins,
+	}
+
+	return cmd
+}
+
+func listPlugins(cmd *cobra.Command, args []string) error {
+	cfg, configDir, err := config.Load(configPath)
+	if err != nil {
+		cfg = nil
+		configDir, _ = os.Getwd()
+	}
+
+	plugins := plugin.Discover(cfg, configDir)
+	if len(plugins) == 0 {
+		fmt.Println("No plugins found")
+		return nil
+	}
+
+	fmt.Printf("Available plugins (%d):\n\n", len(plugins))
+	for _, p := range plugins {
+		fmt.Printf("  %s", p.Name)
+		if p.Description != "" {
+			fmt.Printf(": %s", p.Description)
+		}
+		fmt.Printf(" (%s: %s)\n", p.Source, p.Path)
+	}
+
+	return nil
+}
+
+// dispatchPlugin runs a plugin when the first argument names one. Built-in
+// commands and tasks defined in doctrus.yml take precedence over plugins, so
+// plugins can never shadow existing behaviour.
+func dispatchPlugin(ctx context.Context, args []string) (bool, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return false, nil
+	}
+
+	name := args[0]
+	if isBuiltinCommand(name) {
+		return false, nil
+	}
+
+	cfg, configDir, err := config.Load(configPath)
+	if err != nil {
+		cfg = nil
+		configDir, _ = os.Getwd()
+	} else if taskExists(cfg, name) {
+		return false, nil
+	}
+
+	p, ok := plugin.Find(name, cfg, configDir)
+	if !ok {
+		return false, nil
+	}
+
+	payload := plugin.NewPayload(cfg, configDir, args[1:])
+	if err := plugin.Run(ctx, p, payload, os.Stdout, os.Stderr); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return true, &CommandError{
+				ExitCode: exitErr.ExitCode(),
+				Message:  fmt.Sprintf("plugin %s failed with exit code %d", name, exitErr.ExitCode()),
+			}
+		}
+		return true, fmt.Errorf("failed to run plugin %s: %w", name, err)
+	}
+
+	return true, nil
+}
+
+func isBuiltinCommand(name string) bool {
+	if name == "help" || name == "completion" {
+		return true
+	}
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func taskExists(cfg *config.Config, spec string) bool {
+	workspaceName, taskName := parseTaskSpec(spec)
+	if workspaceName != "" {
+		_, exists := cfg.GetTask(workspaceName, taskName)
+		return exists
+	}
+	for workspaceName := range cfg.Workspaces {
+		if _, exists := cfg.GetTask(workspaceName, taskName); exists {
+			return true
+		}
+	}
+	return false
+}
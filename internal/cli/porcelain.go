@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"doctrus/internal/events"
+	"doctrus/internal/workspace"
+)
+
+// porcelainVersion leads every porcelain record. It changes only when a
+// field is removed or changes meaning; new fields are appended at the end,
+// so parsers should ignore fields they do not know.
+const porcelainVersion = 1
+
+// Porcelain states besides the statuses of finished tasks, which are
+// success, failed, cached and compound.
+const (
+	porcelainQueued  = "queued"
+	porcelainRunning = "running"
+)
+
+// porcelainWriter writes a record for each change in a task's state as one
+// tab-separated line without colors:
+//
+//	<version> <workspace:task> <state> <percent> <duration_ms>
+//
+// percent is the share of the run's planned tasks finished so far and
+// duration_ms the time a finished task took, 0 for other states. A nil
+// porcelainWriter writes nothing.
+type porcelainWriter struct {
+	mu       sync.Mutex
+	out      io.Writer
+	planned  map[string]bool
+	finished map[string]bool
+}
+
+func newPorcelainWriter(out io.Writer) *porcelainWriter {
+	return &porcelainWriter{
+		out:      out,
+		planned:  make(map[string]bool),
+		finished: make(map[string]bool),
+	}
+}
+
+// plan adds the tasks of executions to those the percentage is based on.
+func (p *porcelainWriter) plan(executions []*workspace.TaskExecution) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, execution := range executions {
+		p.planned[execution.WorkspaceName+":"+execution.TaskName] = true
+	}
+}
+
+func (p *porcelainWriter) Handle(e events.Event) {
+	var state string
+	switch e.Type {
+	case events.TaskQueued:
+		state = porcelainQueued
+	case events.TaskStarted:
+		state = porcelainRunning
+	case events.TaskFinished:
+		state = e.Status
+	default:
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := e.Key()
+	// Tasks missing from the plan still count, so the percentage never
+	// exceeds 100
+	p.planned[key] = true
+	if e.Type == events.TaskFinished {
+		p.finished[key] = true
+	}
+	percent := len(p.finished) * 100 / len(p.planned)
+	fmt.Fprintf(p.out, "%d\t%s\t%s\t%d\t%d\n", porcelainVersion, key, state, percent, e.Duration.Milliseconds())
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"doctrus/internal/config"
+	"doctrus/internal/events"
+	"doctrus/internal/workspace"
+)
+
+func TestPorcelainWriter(t *testing.T) {
+	var out bytes.Buffer
+	p := newPorcelainWriter(&out)
+	p.plan([]*workspace.TaskExecution{
+		{WorkspaceName: "lib", TaskName: "build", Task: &config.Task{}},
+		{WorkspaceName: "web", TaskName: "build", Task: &config.Task{}},
+	})
+
+	bus := events.NewBus()
+	bus.Subscribe(p)
+	for _, e := range []events.Event{
+		{Type: events.TaskQueued, Workspace: "web", Task: "build"},
+		{Type: events.TaskQueued, Workspace: "lib", Task: "build"},
+		{Type: events.CacheHit, Workspace: "lib", Task: "build"},
+		{Type: events.TaskFinished, Workspace: "lib", Task: "build", Status: events.StatusCached, Duration: 3 * time.Millisecond},
+		{Type: events.TaskStarted, Workspace: "web", Task: "build"},
+		{Type: events.OutputChunk, Workspace: "web", Task: "build", Stream: "stdout", Data: "ok\n"},
+		{Type: events.TaskFinished, Workspace: "web", Task: "build", Status: events.StatusFailed, Duration: 1500 * time.Millisecond},
+	} {
+		bus.Publish(e)
+	}
+
+	want := "1\tweb:build\tqueued\t0\t0\n" +
+		"1\tlib:build\tqueued\t0\t0\n" +
+		"1\tlib:build\tcached\t50\t3\n" +
+		"1\tweb:build\trunning\t50\t0\n" +
+		"1\tweb:build\tfailed\t100\t1500\n"
+	if out.String() != want {
+		t.Errorf("porcelain output =\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"doctrus/internal/events"
+)
+
+// profileRecorder collects when every task of a run started and finished,
+// for the Chrome trace-event file written with run --profile. A nil
+// profileRecorder records nothing.
+type profileRecorder struct {
+	path    string
+	name    string
+	started time.Time
+
+	mu    sync.Mutex
+	tasks []profiledTask
+}
+
+// profiledTask is a task that finished during the run.
+type profiledTask struct {
+	key      string
+	status   string
+	exitCode int
+	start    time.Time
+	end      time.Time
+}
+
+func newProfileRecorder(path string, args []string) *profileRecorder {
+	return &profileRecorder{
+		path:    path,
+		name:    "doctrus run " + strings.Join(args, " "),
+		started: time.Now(),
+	}
+}
+
+func (p *profileRecorder) Handle(e events.Event) {
+	// Compound tasks only wait for their dependencies
+	if e.Type != events.TaskFinished || e.Status == events.StatusCompound {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tasks = append(p.tasks, profiledTask{
+		key:      e.Key(),
+		status:   e.Status,
+		exitCode: e.ExitCode,
+		start:    e.Time.Add(-e.Duration),
+		end:      e.Time,
+	})
+}
+
+// traceEvent is an event of the Chrome trace-event format, with times in
+// microseconds since the run started.
+type traceEvent struct {
+	Name     string         `json:"name"`
+	Category string         `json:"cat,omitempty"`
+	Phase    string         `json:"ph"`
+	Time     int64          `json:"ts"`
+	Duration int64          `json:"dur,omitempty"`
+	Process  int            `json:"pid"`
+	Thread   int            `json:"tid"`
+	Args     map[string]any `json:"args,omitempty"`
+}
+
+// events returns a complete event per task and a name for every worker.
+// Tasks are laid out on workers the way the scheduler ran them: each task
+// takes the first worker that was free when it started, so there are as
+// many workers as tasks ran at once.
+func (p *profileRecorder) events() []traceEvent {
+	p.mu.Lock()
+	tasks := append([]profiledTask(nil), p.tasks...)
+	p.mu.Unlock()
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].start.Before(tasks[j].start) })
+
+	trace := []traceEvent{{Name: "process_name", Phase: "M", Process: 1, Args: map[string]any{"name": p.name}}}
+	var busyUntil []time.Time
+	for _, task := range tasks {
+		worker := -1
+		for i, until := range busyUntil {
+			if !until.After(task.start) {
+				worker = i
+				break
+			}
+		}
+		if worker < 0 {
+			worker = len(busyUntil)
+			busyUntil = append(busyUntil, time.Time{})
+			trace = append(trace, traceEvent{Name: "thread_name", Phase: "M", Process: 1, Thread: worker + 1, Args: map[string]any{"name": fmt.Sprintf("worker %d", worker+1)}})
+		}
+		busyUntil[worker] = task.end
+
+		args := map[string]any{"status": task.status}
+		if task.status == events.StatusFailed {
+			args["exit_code"] = task.exitCode
+		}
+		trace = append(trace, traceEvent{
+			Name:     task.key,
+			Category: task.status,
+			Phase:    "X",
+			Time:     task.start.Sub(p.started).Microseconds(),
+			Duration: max(task.end.Sub(task.start).Microseconds(), 1),
+			Process:  1,
+			Thread:   worker + 1,
+			Args:     args,
+		})
+	}
+	return trace
+}
+
+// write writes the trace-event file.
+func (p *profileRecorder) write() error {
+	if p == nil {
+		return nil
+	}
+	data, err := json.Marshal(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{p.events(), "ms"})
+	if err != nil {
+		return fmt.Errorf("failed to encode profile: %w", err)
+	}
+	if err := os.WriteFile(p.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"doctrus/internal/events"
+)
+
+func TestProfileRecorderWritesChromeTrace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.json")
+	profile := newProfileRecorder(path, []string{"build"})
+	start := profile.started
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	bus := events.NewBus()
+	bus.Subscribe(profile)
+	for _, e := range []events.Event{
+		{Type: events.TaskStarted, Time: at(0), Workspace: "lib", Task: "build"},
+		{Type: events.TaskFinished, Time: at(100), Workspace: "lib", Task: "build", Status: events.StatusSuccess, Duration: 100 * time.Millisecond},
+		{Type: events.TaskFinished, Time: at(50), Workspace: "web", Task: "lint", Status: events.StatusCached, Duration: 40 * time.Millisecond},
+		{Type: events.TaskFinished, Time: at(300), Workspace: "web", Task: "test", Status: events.StatusFailed, ExitCode: 2, Duration: 200 * time.Millisecond},
+		{Type: events.TaskFinished, Time: at(300), Workspace: "web", Task: "all", Status: events.StatusCompound},
+	} {
+		bus.Publish(e)
+	}
+	if err := profile.write(); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trace struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(data, &trace); err != nil {
+		t.Fatalf("profile is not JSON: %v", err)
+	}
+
+	tasks := make(map[string]traceEvent)
+	threads := 0
+	for _, e := range trace.TraceEvents {
+		switch {
+		case e.Phase == "X":
+			tasks[e.Name] = e
+		case e.Name == "thread_name":
+			threads++
+		}
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("got %d task events, want 3: %+v", len(tasks), trace.TraceEvents)
+	}
+	if threads != 2 {
+		t.Fatalf("got %d workers, want 2", threads)
+	}
+
+	tests := []struct {
+		name     string
+		ts, dur  int64
+		thread   int
+		status   string
+		exitCode any
+	}{
+		// lib:build and web:lint overlap; web:test starts once both are done
+		{"lib:build", 0, 100000, 1, events.StatusSuccess, nil},
+		{"web:lint", 10000, 40000, 2, events.StatusCached, nil},
+		{"web:test", 100000, 200000, 1, events.StatusFailed, float64(2)},
+	}
+	for _, tt := range tests {
+		got := tasks[tt.name]
+		if got.Time != tt.ts || got.Duration != tt.dur || got.Thread != tt.thread {
+			t.Errorf("%s: ts=%d dur=%d tid=%d, want ts=%d dur=%d tid=%d", tt.name, got.Time, got.Duration, got.Thread, tt.ts, tt.dur, tt.thread)
+		}
+		if got.Args["status"] != tt.status || got.Args["exit_code"] != tt.exitCode {
+			t.Errorf("%s: args = %v, want status %s and exit code %v", tt.name, got.Args, tt.status, tt.exitCode)
+		}
+	}
+}
+
+func TestNilProfileRecorderWritesNothing(t *testing.T) {
+	var profile *profileRecorder
+	if err := profile.write(); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+}
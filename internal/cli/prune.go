@@ -0,0 +1,284 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/cas"
+	"doctrus/internal/config"
+	"doctrus/internal/ui"
+)
+
+var (
+	pruneHistoryAge  string
+	pruneHistoryRuns int
+	pruneCacheAge    string
+	pruneCacheSize   string
+	pruneLogAge      string
+	pruneLogRuns     int
+)
+
+func newPruneCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove old history and cache entries",
+		Long: `Remove run history and cache entries beyond the retention configured under
+retention in doctrus.yml: runs older than history_age or beyond the last
+history_runs, cache entries older than cache_age or whose TTL expired, and the
+least recently used entries while the cache exceeds cache_size, or
+cache.max_size when that is smaller. Cache entries of tasks no
+longer in doctrus.yml and stray files in the cache directory are removed too,
+as are stored outputs not used within cache_age and the blobs only they
+referenced. Task log files older than log_age or beyond the last log_runs of
+each task are removed as well.
+Flags override the configured limits; with --dry-run nothing is deleted.
+
+Examples:
+  doctrus prune                           # Apply the configured retention
+  doctrus prune --dry-run                 # Show what would be removed
+  doctrus prune --history-age 30d --cache-size 500MB`,
+		Args: cobra.NoArgs,
+		RunE: runPrune,
+	}
+
+	cmd.Flags().StringVar(&pruneHistoryAge, "history-age", "", "Remove runs older than this age, such as 30d or 12h")
+	cmd.Flags().IntVar(&pruneHistoryRuns, "history-runs", 0, "Keep at most this many runs")
+	cmd.Flags().StringVar(&pruneCacheAge, "cache-age", "", "Remove cache entries older than this age")
+	cmd.Flags().StringVar(&pruneCacheSize, "cache-size", "", "Evict the least recently used cache entries until the cache fits this size, such as 500MB")
+	cmd.Flags().StringVar(&pruneLogAge, "log-age", "", "Remove task log files older than this age")
+	cmd.Flags().IntVar(&pruneLogRuns, "log-runs", 0, "Keep at most this many log files per task")
+
+	return cmd
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	cli, err := newCLI()
+	if err != nil {
+		return err
+	}
+
+	retention := config.Retention{}
+	if cli.config.Retention != nil {
+		retention = *cli.config.Retention
+	}
+	flags := cmd.Flags()
+	if flags.Changed("history-age") {
+		retention.HistoryAge = pruneHistoryAge
+	}
+	if flags.Changed("history-runs") {
+		retention.HistoryRuns = pruneHistoryRuns
+	}
+	if flags.Changed("cache-age") {
+		retention.CacheAge = pruneCacheAge
+	}
+	if flags.Changed("cache-size") {
+		retention.CacheSize = pruneCacheSize
+	}
+	if flags.Changed("log-age") {
+		retention.LogAge = pruneLogAge
+	}
+	if flags.Changed("log-runs") {
+		retention.LogRuns = pruneLogRuns
+	}
+
+	summary, err := cli.prune(retention, cli.projectCache(), dryRun)
+	if err != nil {
+		return err
+	}
+
+	verb, freed := "Pruned", "freed"
+	if dryRun {
+		verb, freed = "Would prune", "would free"
+	}
+	if summary.empty() {
+		fmt.Println(cli.ui.Status(ui.KindSuccess, "Nothing to prune"))
+		return nil
+	}
+	fmt.Println(cli.ui.Status(ui.KindSuccess, fmt.Sprintf("%s %s (%s %s)", verb, summary, freed, formatBytes(summary.freed()))))
+	if verbose || dryRun {
+		for _, group := range []struct {
+			reason string
+			keys   []string
+		}{
+			{"expired", summary.cache.Expired},
+			{"older than cache_age", summary.cache.Old},
+			{"task no longer configured", summary.cache.Orphaned},
+			{"over the cache size limit", summary.cache.Evicted},
+		} {
+			for _, key := range group.keys {
+				fmt.Printf("  %s (%s)\n", key, group.reason)
+			}
+		}
+	}
+	return nil
+}
+
+// pruneSummary is what a prune removed.
+type pruneSummary struct {
+	runs  int
+	cache cache.PruneResult
+	cas   cas.GCResult
+	logs  int
+}
+
+func (s pruneSummary) empty() bool {
+	return s.runs == 0 && s.logs == 0 && s.cache.Entries() == 0 && s.cache.Files == 0 && s.cas.Manifests == 0 && s.cas.Blobs == 0
+}
+
+// freed returns the disk space the prune freed.
+func (s pruneSummary) freed() int64 {
+	return s.cache.Freed + s.cas.Freed
+}
+
+// String renders the summary, such as
+// "3 runs, 12 cache entries, 2 stray files, 4 output manifests, 9 blobs,
+// 6 task logs".
+func (s pruneSummary) String() string {
+	parts := []string{
+		fmt.Sprintf("%d %s", s.runs, plural(s.runs, "run", "runs")),
+		fmt.Sprintf("%d cache %s", s.cache.Entries(), plural(s.cache.Entries(), "entry", "entries")),
+	}
+	if s.cache.Files > 0 {
+		parts = append(parts, fmt.Sprintf("%d stray %s", s.cache.Files, plural(s.cache.Files, "file", "files")))
+	}
+	if s.cas.Manifests > 0 {
+		parts = append(parts, fmt.Sprintf("%d output %s", s.cas.Manifests, plural(s.cas.Manifests, "manifest", "manifests")))
+	}
+	if s.cas.Blobs > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s", s.cas.Blobs, plural(s.cas.Blobs, "blob", "blobs")))
+	}
+	if s.logs > 0 {
+		parts = append(parts, fmt.Sprintf("%d task %s", s.logs, plural(s.logs, "log", "logs")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// prune applies retention to the project's history and cache. Cache entries
+// of tasks missing from the configuration are only removed when orphans is
+// set, as a shared cache directory may hold entries of other projects.
+func (c *CLI) prune(retention config.Retention, orphans, dryRun bool) (pruneSummary, error) {
+	var summary pruneSummary
+
+	historyAge, err := config.ParseAge(retention.HistoryAge)
+	if err != nil {
+		return summary, fmt.Errorf("invalid history age: %w", err)
+	}
+	cacheAge, err := config.ParseAge(retention.CacheAge)
+	if err != nil {
+		return summary, fmt.Errorf("invalid cache age: %w", err)
+	}
+	cacheSize, err := config.ParseSize(retention.CacheSize)
+	if err != nil {
+		return summary, fmt.Errorf("invalid cache size: %w", err)
+	}
+	if retention.HistoryRuns < 0 {
+		return summary, fmt.Errorf("history runs must not be negative")
+	}
+	logAge, err := config.ParseAge(retention.LogAge)
+	if err != nil {
+		return summary, fmt.Errorf("invalid log age: %w", err)
+	}
+	if retention.LogRuns < 0 {
+		return summary, fmt.Errorf("log runs must not be negative")
+	}
+
+	var cutoff time.Time
+	if historyAge > 0 {
+		cutoff = time.Now().Add(-historyAge)
+	}
+	if summary.runs, err = historyStore(c.basePath).Prune(cutoff, retention.HistoryRuns, dryRun); err != nil {
+		return summary, fmt.Errorf("failed to prune history: %w", err)
+	}
+
+	opts := cache.PruneOptions{MaxAge: cacheAge, MaxSize: cacheSize, DryRun: dryRun}
+	if orphans {
+		opts.Keep = c.configuredTask
+	}
+	if summary.cache, err = c.cache.Prune(opts); err != nil {
+		return summary, fmt.Errorf("failed to prune cache: %w", err)
+	}
+
+	// Blobs are shared between tasks, so only those no remaining manifest
+	// references are removed
+	if c.cas != nil {
+		if summary.cas, err = c.cas.GC(cas.GCOptions{MaxAge: cacheAge, DryRun: dryRun}); err != nil {
+			return summary, fmt.Errorf("failed to collect stored outputs: %w", err)
+		}
+	}
+
+	var logCutoff time.Time
+	if logAge > 0 {
+		logCutoff = time.Now().Add(-logAge)
+	}
+	if summary.logs, err = c.taskLogStore().Prune(logCutoff, retention.LogRuns, dryRun); err != nil {
+		return summary, fmt.Errorf("failed to prune task logs: %w", err)
+	}
+	return summary, nil
+}
+
+// configuredTask reports whether taskKey names a task in the configuration.
+func (c *CLI) configuredTask(taskKey string) bool {
+	workspaceName, taskName, _ := strings.Cut(taskKey, ":")
+	ws, ok := c.config.Workspaces[workspaceName]
+	if !ok {
+		return false
+	}
+	_, ok = ws.Tasks[taskName]
+	return ok
+}
+
+// projectCache reports whether the cache directory is the project's own
+// .doctrus/cache rather than one shared through --cache-dir.
+func (c *CLI) projectCache() bool {
+	dir, err := filepath.Abs(cacheDir)
+	if err != nil {
+		return false
+	}
+	return dir == filepath.Join(c.basePath, ".doctrus", "cache")
+}
+
+// autoPrune applies the configured retention after a run when
+// retention.auto is set. Only the run's workspaces are loaded, so entries
+// of tasks missing from the configuration are left to doctrus prune.
+func (c *CLI) autoPrune() {
+	if c.config == nil {
+		return
+	}
+	retention := c.config.Retention
+	if retention == nil || !retention.Auto {
+		return
+	}
+	summary, err := c.prune(*retention, false, false)
+	if err != nil {
+		c.log.Warnf("Warning: failed to prune: %v\n", err)
+		return
+	}
+	if !summary.empty() {
+		c.log.Debugf("Pruned %s (freed %s)\n", summary, formatBytes(summary.freed()))
+	}
+}
+
+// evictCache removes the least recently used cache entries beyond
+// cache.max_size after a run.
+func (c *CLI) evictCache() {
+	result, err := c.cache.Evict()
+	if err != nil {
+		c.log.Warnf("Warning: failed to evict cache entries: %v\n", err)
+		return
+	}
+	if len(result.Evicted) > 0 {
+		c.log.Debugf("Evicted %d cache %s over cache.max_size (freed %s)\n", len(result.Evicted), plural(len(result.Evicted), "entry", "entries"), formatBytes(result.Freed))
+	}
+}
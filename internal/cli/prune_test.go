@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/cas"
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/history"
+)
+
+func TestPrune(t *testing.T) {
+	basePath := t.TempDir()
+	c := &CLI{
+		basePath: basePath,
+		cache:    cache.NewManager(filepath.Join(basePath, ".doctrus", "cache")),
+		config: &config.Config{Workspaces: map[string]config.Workspace{
+			"web": {Tasks: map[string]config.Task{"build": {}}},
+		}},
+	}
+
+	store := historyStore(basePath)
+	for i := range 5 {
+		if err := store.Append(history.Run{ID: string(rune('a' + i)), StartedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range []string{"web:build", "web:removed", "gone:build"} {
+		if err := c.cache.Set(key, &deps.TaskState{TaskKey: key}, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	summary, err := c.prune(config.Retention{HistoryRuns: 2}, true, true)
+	if err != nil {
+		t.Fatalf("prune() dry run error = %v", err)
+	}
+	if summary.runs != 3 || len(summary.cache.Orphaned) != 2 {
+		t.Fatalf("prune() dry run = %+v, want 3 runs and 2 orphaned entries", summary)
+	}
+	if runs, _ := store.Load(); len(runs) != 5 {
+		t.Fatalf("dry run removed runs: %d left", len(runs))
+	}
+
+	summary, err = c.prune(config.Retention{HistoryRuns: 2}, false, false)
+	if err != nil {
+		t.Fatalf("prune() error = %v", err)
+	}
+	if summary.runs != 3 || summary.cache.Entries() != 0 {
+		t.Fatalf("prune() without orphans = %+v, want 3 runs and no entries", summary)
+	}
+	if entries, _ := c.cache.List(cache.ListFilter{}); len(entries) != 3 {
+		t.Fatalf("entries left = %d, want 3", len(entries))
+	}
+
+	if _, err := c.prune(config.Retention{CacheSize: "huge"}, false, false); err == nil {
+		t.Fatal("prune() with an invalid cache size succeeded")
+	}
+}
+
+func TestPruneSummaryString(t *testing.T) {
+	summary := pruneSummary{runs: 1, cache: cache.PruneResult{Old: []string{"a:b", "c:d"}, Files: 1, Freed: 2048}}
+	if got, want := summary.String(), "1 run, 2 cache entries, 1 stray file"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+	summary.cas = cas.GCResult{Manifests: 2, Blobs: 1, Freed: 1024}
+	if got, want := summary.String(), "1 run, 2 cache entries, 1 stray file, 2 output manifests, 1 blob"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+	summary.logs = 3
+	if got, want := summary.String(), "1 run, 2 cache entries, 1 stray file, 2 output manifests, 1 blob, 3 task logs"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+	if summary.freed() != 3072 {
+		t.Fatalf("freed() = %d, want 3072", summary.freed())
+	}
+}
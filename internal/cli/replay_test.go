@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/docker"
+	"doctrus/internal/logging"
+	"doctrus/internal/workspace"
+)
+
+func TestReplayLogsOnCacheHit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell commands not available on Windows")
+	}
+
+	tests := []struct {
+		name   string
+		replay bool
+		want   []string
+	}{
+		{name: "replayed", replay: true, want: []string{"Cached (no changes detected)", "3 tests passed", "warning: deprecated call"}},
+		{name: "not replayed", replay: false, want: []string{"Cached (no changes detected)"}},
+	}
+
+	origReplay := replayLogs
+	t.Cleanup(func() { replayLogs = origReplay })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			cfg := &config.Config{
+				Version: "1.0",
+				Workspaces: map[string]config.Workspace{
+					"app": {
+						Path: tempDir,
+						Tasks: map[string]config.Task{
+							"test": {
+								Command: []string{"sh", "-c", "echo 3 tests passed; echo warning: deprecated call >&2"},
+								Cache:   true,
+							},
+						},
+					},
+				},
+			}
+			var out bytes.Buffer
+			c := &CLI{
+				config:    cfg,
+				workspace: workspace.NewManager(cfg, tempDir),
+				executor:  docker.NewExecutor(cfg, tempDir),
+				tracker:   deps.NewTracker(tempDir),
+				cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+				log:       logging.New(&out, logging.LevelInfo, nil),
+				stdout:    &out,
+				basePath:  tempDir,
+			}
+			execution, err := c.workspace.ResolveTaskExecution("app", "test")
+			if err != nil {
+				t.Fatalf("ResolveTaskExecution() error = %v", err)
+			}
+
+			replayLogs = tt.replay
+			if err := c.runExecution(context.Background(), execution, false); err != nil {
+				t.Fatalf("first run error = %v", err)
+			}
+			out.Reset()
+			if err := c.runExecution(context.Background(), execution, false); err != nil {
+				t.Fatalf("cached run error = %v", err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+			if !tt.replay && strings.Contains(out.String(), "tests passed") {
+				t.Errorf("output replayed without --replay-logs:\n%s", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"doctrus/internal/report"
+	"doctrus/internal/ui"
+	"doctrus/internal/workspace"
+)
+
+// reportFormats are the formats --report accepts.
+var reportFormats = []string{"html"}
+
+// parseReportFlag splits a --report value of the form format=dir.
+func parseReportFlag(value string) (string, error) {
+	format, dir, ok := strings.Cut(value, "=")
+	if !ok || dir == "" {
+		return "", fmt.Errorf("invalid --report %q (expected format=dir, such as html=report/)", value)
+	}
+	if format != "html" {
+		return "", fmt.Errorf("unknown report format %q (expected one of %s)", format, strings.Join(reportFormats, ", "))
+	}
+	return dir, nil
+}
+
+// startReport collects the run for the report --report writes to dir.
+func (c *CLI) startReport(dir string) {
+	c.report = report.New(append([]string{"doctrus"}, os.Args[1:]...))
+	c.reportDir = dir
+	c.events.Subscribe(c.report)
+}
+
+// planReport adds the tasks of executions and their dependencies to the
+// report's graph.
+func (c *CLI) planReport(executions []*workspace.TaskExecution) {
+	if c.report == nil {
+		return
+	}
+	for _, execution := range executions {
+		deps, err := c.collectDependencies(execution.WorkspaceName, execution.Task)
+		if err != nil || noDeps {
+			deps = nil
+		}
+		needs := make([]string, len(deps))
+		for i, dep := range deps {
+			needs[i] = dep.workspace + ":" + dep.task
+		}
+		c.report.Plan(execution.WorkspaceName+":"+execution.TaskName, needs)
+	}
+}
+
+// writeReport writes the report of a run that ended with runErr.
+func (c *CLI) writeReport(runErr error) {
+	if c.report == nil {
+		return
+	}
+	c.report.Finish(runErr)
+	path, err := c.report.WriteHTML(c.reportDir)
+	if err != nil {
+		c.log.Warnf("Warning: %v\n", err)
+		return
+	}
+	c.log.Infof("%s\n", c.ui.Status(ui.KindInfo, "Report written to "+path))
+}
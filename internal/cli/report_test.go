@@ -0,0 +1,29 @@
+package cli
+
+import "testing"
+
+func TestParseReportFlag(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantDir string
+		wantErr bool
+	}{
+		{value: "html=report/", wantDir: "report/"},
+		{value: "html=/tmp/run report", wantDir: "/tmp/run report"},
+		{value: "html", wantErr: true},
+		{value: "html=", wantErr: true},
+		{value: "pdf=report/", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			dir, err := parseReportFlag(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseReportFlag(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if dir != tt.wantDir {
+				t.Fatalf("parseReportFlag(%q) = %q, want %q", tt.value, dir, tt.wantDir)
+			}
+		})
+	}
+}
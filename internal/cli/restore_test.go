@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/cas"
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/docker"
+	"doctrus/internal/logging"
+	"doctrus/internal/workspace"
+)
+
+func TestRestoreOutputsOnCacheHit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell commands not available on Windows")
+	}
+
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "src.txt"), []byte("source"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"app": {
+				Path: tempDir,
+				Tasks: map[string]config.Task{
+					"build": {
+						Command: []string{"sh", "-c", "mkdir -p dist && cp src.txt dist/out.txt && echo run >> runs.log"},
+						Inputs:  []string{"src.txt"},
+						Outputs: []string{"dist/**"},
+						Cache:   true,
+					},
+				},
+			},
+		},
+	}
+	var out bytes.Buffer
+	c := &CLI{
+		config:    cfg,
+		workspace: workspace.NewManager(cfg, tempDir),
+		executor:  docker.NewExecutor(cfg, tempDir),
+		tracker:   deps.NewTracker(tempDir),
+		cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+		cas:       cas.New(filepath.Join(tempDir, ".doctrus", "cas")),
+		log:       logging.New(&out, logging.LevelInfo, nil),
+		stdout:    &out,
+		basePath:  tempDir,
+	}
+	execution, err := c.workspace.ResolveTaskExecution("app", "build")
+	if err != nil {
+		t.Fatalf("ResolveTaskExecution() error = %v", err)
+	}
+	runs := func() int {
+		data, _ := os.ReadFile(filepath.Join(tempDir, "runs.log"))
+		return strings.Count(string(data), "run")
+	}
+
+	if err := c.runExecution(context.Background(), execution, false); err != nil {
+		t.Fatalf("first run error = %v", err)
+	}
+	if err := os.RemoveAll(filepath.Join(tempDir, "dist")); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := c.runExecution(context.Background(), execution, false); err != nil {
+		t.Fatalf("cached run error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Cached (outputs restored)") {
+		t.Errorf("output missing restored cache hit:\n%s", out.String())
+	}
+	if runs() != 1 {
+		t.Errorf("task ran %d times, want the outputs restored without running", runs())
+	}
+	if data, err := os.ReadFile(filepath.Join(tempDir, "dist", "out.txt")); err != nil || string(data) != "source" {
+		t.Errorf("restored output = %q, %v; want %q", data, err, "source")
+	}
+
+	// Changed inputs still run the task rather than restoring stale outputs
+	if err := os.RemoveAll(filepath.Join(tempDir, "dist")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "src.txt"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.runExecution(context.Background(), execution, false); err != nil {
+		t.Fatalf("changed run error = %v", err)
+	}
+	if runs() != 2 {
+		t.Errorf("task ran %d times after its inputs changed, want 2", runs())
+	}
+	if data, err := os.ReadFile(filepath.Join(tempDir, "dist", "out.txt")); err != nil || string(data) != "changed" {
+		t.Errorf("output = %q, %v; want %q", data, err, "changed")
+	}
+
+	// Going back to the first inputs restores the outputs of that run
+	if err := os.WriteFile(filepath.Join(tempDir, "src.txt"), []byte("source"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := c.runExecution(context.Background(), execution, false); err != nil {
+		t.Fatalf("reverted run error = %v", err)
+	}
+	if runs() != 2 || !strings.Contains(out.String(), "Cached (outputs restored)") {
+		t.Errorf("task ran %d times after its inputs were reverted, want 2 and the outputs restored:\n%s", runs(), out.String())
+	}
+	if data, err := os.ReadFile(filepath.Join(tempDir, "dist", "out.txt")); err != nil || string(data) != "source" {
+		t.Errorf("restored output = %q, %v; want %q", data, err, "source")
+	}
+}
@@ -1,16 +1,29 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"doctrus/internal/agent"
 	"doctrus/internal/cache"
+	"doctrus/internal/cas"
 	"doctrus/internal/config"
 	"doctrus/internal/deps"
 	"doctrus/internal/docker"
+	"doctrus/internal/events"
+	"doctrus/internal/history"
+	"doctrus/internal/logging"
+	"doctrus/internal/report"
+	"doctrus/internal/tracing"
+	"doctrus/internal/ui"
 	"doctrus/internal/workspace"
 )
 
@@ -20,21 +33,63 @@ var (
 	dryRun     bool
 	cacheDir   string
 	runCmd     *cobra.Command
+	version    = "dev"
+	noColor    bool
+	themeName  string
+	logLevel   string
+	logFile    string
+	strict     bool
+	envFlags   []string
+	// profileName selects a profile of doctrus.yml, overriding
+	// DOCTRUS_PROFILE; run --profile writes a Chrome trace instead
+	profileName string
+	// taskTimeout bounds tasks without a timeout of their own
+	taskTimeout time.Duration
 )
 
 type CLI struct {
 	config         *config.Config
 	workspace      *workspace.Manager
-	executor       *docker.Executor
+	executor       *docker.Dispatcher
+	agents         *agent.Executor
+	tasks          *docker.TaskContexts
 	tracker        *deps.Tracker
 	cache          *cache.Manager
+	remote         cache.Remote
+	cas            *cas.Store
+	ui             *ui.Styler
+	log            *logging.Logger
+	history        *history.Recorder
+	estimate       *runEstimate
+	since          *sinceChanges
+	events         *events.Bus
+	porcelain      *porcelainWriter
+	report         *report.Report
+	reportDir      string
+	term           ui.Terminal
+	status         *ui.StatusLine
+	tui            *tuiView
+	github         *githubOutput
+	results        *runResults
+	tracer         *tracing.Tracer
+	stdout         io.Writer
 	basePath       string
 	preRunExecuted bool
 	outputMu       sync.Mutex
 }
 
 func newCLI() (*CLI, error) {
-	cfg, configDir, err := config.Load(configPath)
+	return newScopedCLI(nil)
+}
+
+// newScopedCLI creates a CLI whose configuration only holds the workspaces
+// reachable from the task specs, or every workspace when specs is empty or
+// --strict is set.
+func newScopedCLI(specs []string) (*CLI, error) {
+	if strict {
+		specs = nil
+	}
+	cfg, configDir, err := config.LoadProfile(configPath, specs, activeProfile())
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
@@ -50,26 +105,153 @@ func newCLI() (*CLI, error) {
 
 	workspaceManager := workspace.NewManager(cfg, basePath)
 	executor := docker.NewExecutor(cfg, basePath)
+	agents := agent.NewExecutor(cfg, basePath)
+	executor.Register(config.ExecutorRemote, agents)
+	cliEnv, err := parseEnvFlags(envFlags)
+	if err != nil {
+		return nil, err
+	}
+	executor.SetCLIEnv(cliEnv)
 	tracker := deps.NewTracker(basePath)
 
 	// Resolve cache directory
 	if cacheDir == "" {
 		cacheDir = filepath.Join(basePath, ".doctrus", "cache")
 	}
+	cacheDir = cfg.ContainerPath(cacheDir)
 	cacheManager := cache.NewManager(cacheDir)
+	if cfg.Cache != nil {
+		maxSize, err := config.ParseSize(cfg.Cache.MaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache max size: %w", err)
+		}
+		cacheManager.SetMaxSize(maxSize)
+	}
+	var remote cache.Remote
+	if remoteConfig := cfg.RemoteCache(); remoteConfig != nil {
+		remote, err = cache.NewRemote(remoteConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Workspace paths are checked when a command uses them; --strict checks
+	// every workspace up front
+	if strict {
+		if err := workspaceManager.ValidateWorkspaces(); err != nil {
+			return nil, fmt.Errorf("workspace validation failed: %w", err)
+		}
+	}
 
-	if err := workspaceManager.ValidateWorkspaces(); err != nil {
-		return nil, fmt.Errorf("workspace validation failed: %w", err)
+	level, err := logging.ParseLevel(logLevel)
+	if err != nil {
+		return nil, err
+	}
+	if verbose && !rootCmd.PersistentFlags().Changed("log-level") {
+		level = logging.LevelDebug
+	}
+
+	// With --events, --output json or --porcelain, stdout carries only the
+	// event stream or status records and everything meant for humans moves
+	// to stderr
+	humanOut := os.Stdout
+	bus := events.NewBus()
+	if eventsFormat != "" && porcelainOut {
+		return nil, fmt.Errorf("--events and --porcelain both write to stdout; use one of them")
+	}
+	if outputFormat == outputJSON && porcelainOut {
+		return nil, fmt.Errorf("--output json and --porcelain both write to stdout; use one of them")
+	}
+	var porcelain *porcelainWriter
+	if porcelainOut {
+		porcelain = newPorcelainWriter(os.Stdout)
+		bus.Subscribe(porcelain)
+		humanOut = os.Stderr
+	}
+	// --output json streams the events of a run as with --events ndjson
+	format := eventsFormat
+	if format == "" && outputFormat == outputJSON {
+		format = "ndjson"
+	}
+	if format != "" {
+		formatter, err := events.NewFormatter(format, os.Stdout)
+		if err != nil {
+			return nil, err
+		}
+		bus.Subscribe(formatter)
+		humanOut = os.Stderr
+	}
+
+	styler, err := newStyler(humanOut)
+	if err != nil {
+		return nil, err
 	}
 
-	return &CLI{
+	c := &CLI{
 		config:    cfg,
 		workspace: workspaceManager,
 		executor:  executor,
+		agents:    agents,
+		tasks:     docker.NewTaskContexts(),
 		tracker:   tracker,
 		cache:     cacheManager,
+		remote:    remote,
+		cas:       cas.New(filepath.Join(basePath, ".doctrus", "cas")),
+		ui:        styler,
+		events:    bus,
+		porcelain: porcelain,
 		basePath:  basePath,
-	}, nil
+	}
+
+	c.term = ui.DetectTerminal(humanOut)
+	c.stdout = humanOut
+	if c.term.Live {
+		c.status = ui.NewStatusLine(humanOut, &c.outputMu, styler.Frames(), c.term.Width)
+		c.stdout = c.status
+	}
+	c.log = logging.New(c.stdout, level, &c.outputMu)
+	if logFile != "" {
+		if err := c.log.OpenFile(logFile); err != nil {
+			return nil, err
+		}
+	}
+	c.useDaemon()
+
+	return c, nil
+}
+
+// parseEnvFlags parses the KEY=VALUE values of --env.
+func parseEnvFlags(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	env := make(map[string]string, len(values))
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --env %q (expected KEY=VALUE)", value)
+		}
+		env[key] = val
+	}
+	return env, nil
+}
+
+// activeProfile returns the profile --config-profile or DOCTRUS_PROFILE
+// selects.
+func activeProfile() string {
+	if profileName != "" {
+		return profileName
+	}
+	return os.Getenv(config.ProfileEnv)
+}
+
+// newStyler builds the styler for output written to out from
+// --theme/DOCTRUS_THEME and the color settings.
+func newStyler(out *os.File) (*ui.Styler, error) {
+	if themeName == "" {
+		themeName = os.Getenv("DOCTRUS_THEME")
+	}
+	return ui.New(themeName, ui.ColorEnabled(noColor, out))
 }
 
 var rootCmd = &cobra.Command{
@@ -98,7 +280,35 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// SetVersion records the build version reported by --version and self-update
+func SetVersion(v string) {
+	if v == "" {
+		return
+	}
+	version = v
+	rootCmd.Version = v
+}
+
+// terminal is the state of the terminal when doctrus started, restored after
+// runs and on panics.
+var terminal *ui.TerminalState
+
+// restoreTerminalOnPanic restores the terminal before a panic propagates. It
+// must be deferred directly by every goroutine that runs tasks.
+func restoreTerminalOnPanic() {
+	if r := recover(); r != nil {
+		terminal.Restore()
+		panic(r)
+	}
+}
+
 func Execute() error {
+	terminal = ui.SaveTerminal(os.Stdin, os.Stdout)
+	defer restoreTerminalOnPanic()
+
+	if handled, err := dispatchPlugin(context.Background(), os.Args[1:]); handled {
+		return err
+	}
 	return rootCmd.Execute()
 }
 
@@ -107,6 +317,13 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Show what would be executed without running it")
 	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "Cache directory (default: ~/.doctrus/cache)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (also honors NO_COLOR)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Minimum level of doctrus diagnostics to print: debug, info, warn, error (--verbose implies debug)")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Also append doctrus diagnostics to this file")
+	rootCmd.PersistentFlags().BoolVar(&strict, "strict", false, "Load and validate every workspace up front instead of only those a command uses")
+	rootCmd.PersistentFlags().DurationVar(&taskTimeout, "timeout", 0, "Stop and fail tasks without a timeout of their own after this long, such as 30m (0 for no limit)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "config-profile", "", "Profile of doctrus.yml to apply, such as dev, ci or prod (default: $DOCTRUS_PROFILE)")
+	rootCmd.PersistentFlags().StringVar(&themeName, "theme", "", "Output theme: "+strings.Join(ui.ThemeNames(), ", ")+" (default: $DOCTRUS_THEME or default)")
 
 	runCmd = newRunCommand()
 	rootCmd.AddCommand(
@@ -115,6 +332,25 @@ func init() {
 		newCacheCommand(),
 		newValidateCommand(),
 		newInitCommand(),
+		newSelfUpdateCommand(),
+		newPluginsCommand(),
+		newFmtCommand(),
+		newLintCommand(),
+		newHistoryCommand(),
+		newStatsCommand(),
+		newOutputsCommand(),
+		newConfigCommand(),
+		newHooksCommand(),
+		newCICommand(),
+		newPruneCommand(),
+		newInfoCommand(),
+		newAgentCommand(),
+		newServeCommand(),
+		newBenchCommand(),
+		newWatchCommand(),
+		newDaemonCommand(),
+		newGraphCommand(),
+		newSchemaCommand(),
 	)
 
 	rootCmd.Flags().AddFlagSet(runCmd.Flags())
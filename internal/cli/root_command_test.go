@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -40,7 +41,7 @@ workspaces:
 	skipCache = false
 	dryRun = false
 	showDiff = false
-	parallel = 1
+	parallel = ""
 
 	rootCmd.SetArgs([]string{"--config", cfgPath, "app:greet"})
 
@@ -99,7 +100,7 @@ workspaces:
 	skipCache = false
 	dryRun = false
 	showDiff = false
-	parallel = 1
+	parallel = ""
 
 	t.Cleanup(func() {
 		cacheDir = origCacheDir
@@ -133,3 +134,15 @@ workspaces:
 		t.Fatalf("expected task validate to create sentinel file: %v", err)
 	}
 }
+
+func TestProfileFlags(t *testing.T) {
+	if flag := rootCmd.PersistentFlags().Lookup("config-profile"); flag == nil {
+		t.Error("root command lacks --config-profile")
+	}
+	if flag := rootCmd.PersistentFlags().Lookup("profile"); flag != nil {
+		t.Error("--profile selects a config profile; it belongs to run's Chrome trace")
+	}
+	if flag := runCmd.Flags().Lookup("profile"); flag == nil || !strings.Contains(flag.Usage, "Chrome trace") {
+		t.Errorf("run --profile = %v, want the Chrome trace output", flag)
+	}
+}
@@ -9,51 +9,86 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/spf13/cobra"
 
 	"doctrus/internal/config"
 	"doctrus/internal/deps"
+	"doctrus/internal/docker"
+	"doctrus/internal/events"
+	"doctrus/internal/history"
+	"doctrus/internal/lock"
+	"doctrus/internal/logging"
+	"doctrus/internal/tasklog"
+	"doctrus/internal/taskrun"
+	"doctrus/internal/tracing"
+	"doctrus/internal/ui"
 	"doctrus/internal/workspace"
 )
 
-const (
-	// ANSI color reset sequence
-	colorReset = "\033[0m"
-)
-
 var (
 	forceBuild bool
 	skipCache  bool
-	parallel   int
+	parallel   string
 	showDiff   bool
+	noDeps     bool
+	confirmRun bool
+
+	eventsFormat string
+	porcelainOut bool
+	lockMode     string
+	distribute   bool
+	replayLogs   bool
+	reportFlag   string
+	strictInputs string
+	sinceRev     string
+	keepGoing    bool
+	outputStyle  string
+	profilePath  string
+	ciMode       string
+	quiet        bool
+	paramFlags   []string
+	// tagFilters limits run targets and listed tasks to those with one of
+	// the tags given with --tag
+	tagFilters []string
+	// allWorkspaces runs each named task in every workspace defining it
+	allWorkspaces bool
 )
 
-// TaskError represents an error from a failed task with its exit code
-type TaskError struct {
+// CommandError represents a failed pre-run command or plugin with its exit code
+type CommandError struct {
 	ExitCode int
 	Message  string
 }
 
-func (e *TaskError) Error() string {
+func (e *CommandError) Error() string {
 	return e.Message
 }
 
-// GetExitCode extracts the exit code from an error, returns 0 if not a TaskError
+// GetExitCode extracts the exit code from a failed task, pre-run command or
+// plugin, returning 0 for any other error
 func GetExitCode(err error) int {
-	var taskErr *TaskError
+	var taskErr *workspace.TaskFailedError
 	if errors.As(err, &taskErr) {
 		return taskErr.ExitCode
 	}
+	var commandErr *CommandError
+	if errors.As(err, &commandErr) {
+		return commandErr.ExitCode
+	}
 	return 0
 }
 
 func newRunCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "run [workspace:]task",
+		Use:   "run [workspace:]task... [-- args...]",
 		Short: "Run a task in a workspace",
 		Long: `Run a task in a workspace. If workspace is not specified, 
 it will try to find the task in all workspaces.
@@ -61,29 +96,167 @@ it will try to find the task in all workspaces.
 Examples:
   doctrus run build                    # Run 'build' task in any workspace
   doctrus run frontend:build           # Run 'build' task in 'frontend' workspace  
-  doctrus run frontend:test backend:test # Run multiple tasks`,
+  doctrus run --all test               # Run 'test' in every workspace defining it
+  doctrus run :test                    # The same as --all test
+  doctrus run 'front*:build'           # Run 'build' in workspaces matching front*
+  doctrus run frontend:test backend:test # Run multiple tasks
+  doctrus run frontend:test -- --watch # Append --watch to the test command
+  doctrus run --tag js test            # Run 'test' in workspaces tagged js
+  doctrus run build --distribute       # Share the graph between remote agents`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: runTask,
 	}
 
 	cmd.Flags().BoolVarP(&forceBuild, "force", "f", false, "Force rebuild, ignore cache")
 	cmd.Flags().BoolVar(&skipCache, "skip-cache", false, "Skip cache completely")
-	cmd.Flags().IntVarP(&parallel, "parallel", "p", 1, "Number of tasks to run in parallel")
-	cmd.Flags().BoolVar(&showDiff, "show-diff", false, "Show what files changed since last run")
+	cmd.Flags().StringVarP(&parallel, "parallel", "p", "", "Maximum number of tasks to run at once (0 or auto for one per CPU, auto-N to leave N CPUs free; default: parallel in doctrus.yml, or one at a time)")
+	cmd.Flags().Var(diffFlag{}, "show-diff", "Show what input files changed since the last run, as text or json")
+	cmd.Flags().Lookup("show-diff").NoOptDefVal = diffText
+	cmd.Flags().BoolVar(&confirmRun, "confirm", false, "Show the resolved plan and ask for approval before running anything")
+	cmd.Flags().BoolVar(&allWorkspaces, "all", false, "Run each named task in every workspace that defines it; the same as :task")
+	cmd.Flags().StringSliceVar(&tagFilters, "tag", nil, "Only run the named tasks that have one of these tags, on the task or its workspace (repeatable)")
+	cmd.Flags().BoolVar(&noDeps, "no-deps", false, "Run only the named tasks, assuming their dependencies already ran")
+	cmd.Flags().StringArrayVarP(&envFlags, "env", "e", nil, "Set a task environment variable (KEY=VALUE, repeatable)")
+	cmd.Flags().StringArrayVar(&paramFlags, "param", nil, "Set a task parameter used as {{.params.NAME}} (NAME=VALUE, repeatable)")
+	cmd.Flags().StringVar(&lockMode, "lock", "", "When another run uses the same workspaces: wait, fail or off (default: lock in doctrus.yml, or off)")
+	cmd.Flags().StringVar(&eventsFormat, "events", "", "Write task lifecycle events to stdout in this format (ndjson); other output moves to stderr")
+	cmd.Flags().BoolVar(&replayLogs, "replay-logs", false, "Print the output of the run that produced the cache of tasks that are cached")
+	cmd.Flags().StringVar(&reportFlag, "report", "", "Write a static report of the run, as format=dir (html=report/)")
+	cmd.Flags().BoolVar(&distribute, "distribute", false, "Run every task on the agents under remote_execution, sharing the graph between them")
+	cmd.Flags().StringVar(&strictInputs, "strict-inputs", "", "Check every task for reads of files outside its inputs, and warn or fail (overrides strict_inputs)")
+	cmd.Flags().BoolVar(&keepGoing, "keep-going", false, "After a task fails, keep running the tasks that don't depend on it and list every failure at the end")
+	cmd.Flags().StringVar(&sinceRev, "since", "", "Skip tasks whose inputs did not change since this revision, such as HEAD~1 or origin/main, whatever the cache holds")
+	cmd.Flags().StringVar(&profilePath, "profile", "", "Write the start and end of every task to this file as a Chrome trace, for chrome://tracing or Perfetto")
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Only print failed tasks with their output, and a summary of the run")
+	cmd.Flags().StringVar(&ciMode, "ci-mode", ciModeAuto, "CI system to format output for: auto detects GitHub Actions from GITHUB_ACTIONS, github or none")
+	cmd.Flags().StringVar(&outputStyle, "output-style", outputStyleStream, "How to show running tasks on a terminal: stream their output, or tui for a line per running task")
+	cmd.Flags().StringVar(&outputFormat, "output", outputText, "Output format: text, or json for task events and a result record on stdout; other output moves to stderr")
+	cmd.Flags().BoolVar(&porcelainOut, "porcelain", false, "Write stable, line-oriented task status records to stdout for scripts; other output moves to stderr")
 
 	return cmd
 }
 
-func runTask(cmd *cobra.Command, args []string) error {
-	cli, err := newCLI()
+func runTask(cmd *cobra.Command, args []string) (err error) {
+	var reportDir string
+	if reportFlag != "" {
+		if reportDir, err = parseReportFlag(reportFlag); err != nil {
+			return err
+		}
+	}
+	if err := parseStrictInputsFlag(strictInputs); err != nil {
+		return err
+	}
+	if err := parseOutputFormat(outputFormat); err != nil {
+		return err
+	}
+	params, err := parseParamFlags(paramFlags)
 	if err != nil {
 		return err
 	}
 
-	// Create a context that can be cancelled
-	ctx, cancel := context.WithCancel(context.Background())
+	var passArgs []string
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		args, passArgs = args[:dash], args[dash:]
+		if len(args) == 0 {
+			return fmt.Errorf("a task is required before --")
+		}
+	}
+	if allWorkspaces {
+		if args, err = allWorkspaceSpecs(args); err != nil {
+			return err
+		}
+	}
+
+	cli, err := newScopedCLI(args)
+	if err != nil {
+		return err
+	}
+	if err := cli.applyParams(args, params); err != nil {
+		cli.cleanup()
+		return err
+	}
+	if err := cli.appendTaskArgs(args, passArgs); err != nil {
+		cli.cleanup()
+		return err
+	}
+	if err := cli.useOutputStyle(outputStyle); err != nil {
+		cli.cleanup()
+		return err
+	}
+	if err := cli.useCIMode(ciMode); err != nil {
+		cli.cleanup()
+		return err
+	}
+	if quiet {
+		if err := cli.useQuiet(); err != nil {
+			cli.cleanup()
+			return err
+		}
+	}
+	if outputFormat == outputJSON {
+		cli.results = newRunResults(os.Stdout)
+		cli.events.Subscribe(cli.results)
+	}
+	cli.startTracing()
+	var profile *profileRecorder
+	if profilePath != "" && !dryRun {
+		profile = newProfileRecorder(profilePath, args)
+		cli.events.Subscribe(profile)
+	}
+	if reportDir != "" {
+		cli.startReport(reportDir)
+	}
+	if sinceRev != "" {
+		if cli.since, err = cli.loadSinceChanges(sinceRev); err != nil {
+			cli.cleanup()
+			return err
+		}
+	}
+
+	if distribute {
+		if err := cli.distributeTasks(); err != nil {
+			cli.cleanup()
+			return err
+		}
+	}
+
+	if confirmRun && !dryRun {
+		if err := cli.confirmPlan(args, os.Stdin); err != nil {
+			cli.cleanup()
+			return err
+		}
+	}
+
+	if !dryRun {
+		cli.history = history.NewRecorder(args)
+	}
+	cli.estimate = cli.loadEstimate()
+
+	// Tasks run in their own process groups and miss the terminal's Ctrl-C,
+	// so forward SIGINT and SIGTERM to them, including inside containers
+	ctx, cancel := docker.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, span := cli.tracer.Start(ctx, "doctrus run", tracing.String("doctrus.args", strings.Join(args, " ")))
 	defer func() {
 		cancel()
+		span.End(err)
+		cli.exportTraces()
+		cli.tui.printSummary()
+		cli.printSlowTasks()
+		cli.printAgentSummary()
+		cli.saveHistory(err)
+		cli.writeReport(err)
+		if writeErr := cli.results.write(err); writeErr != nil {
+			cli.log.Warnf("Warning: failed to write the run result: %v\n", writeErr)
+		}
+		if writeErr := profile.write(); writeErr != nil {
+			cli.log.Warnf("Warning: %v\n", writeErr)
+		} else if profile != nil {
+			cli.log.Infof("Profile written to %s\n", profilePath)
+		}
+		if !dryRun {
+			cli.evictCache()
+			cli.autoPrune()
+		}
 		// Ensure terminal is in a clean state
 		cli.cleanup()
 	}()
@@ -93,180 +266,612 @@ func runTask(cmd *cobra.Command, args []string) error {
 	}
 
 	runner := newTaskRunner(cli)
+	if runner.Slots, err = cli.parallelSlots(); err != nil {
+		return err
+	}
+	runner.KeepGoing = keepGoing
 
-	for _, taskSpec := range args {
-		if err := cli.runSingleTask(ctx, runner, taskSpec); err != nil {
-			// Cancel context to ensure cleanup
-			cancel()
-			return fmt.Errorf("failed to run task %s: %w", taskSpec, err)
-		}
+	targets, err := cli.resolveTargets(args)
+	if err != nil {
+		return err
+	}
+	if err := cli.checkInternalTargets(targets); err != nil {
+		return err
+	}
+	if err := cli.runTargets(ctx, runner, targets); err != nil {
+		// Cancel context to ensure cleanup
+		cancel()
+		return err
 	}
 
 	return nil
 }
 
-func (c *CLI) runSingleTask(ctx context.Context, runner *taskRunner, taskSpec string) error {
-	workspaceName, taskName := parseTaskSpec(taskSpec)
+// resolveTargets resolves task specs to the tasks they name, in the order
+// given and without duplicates. A spec without a workspace names the task
+// in every workspace that has it, and a spec whose workspace or task is a
+// glob pattern names every task it matches; those two skip internal tasks
+// unless they only name internal ones. With --tag, only tasks with one of the
+// tags, on the task or its workspace, are targets.
+func (c *CLI) resolveTargets(specs []string) ([]dependencySpec, error) {
+	var targets []dependencySpec
+	seen := make(map[dependencySpec]bool)
+	for _, spec := range specs {
+		workspaceName, taskName := c.resolveTaskSpec(spec)
+		candidates := []dependencySpec{{workspace: workspaceName, task: taskName}}
+		switch {
+		case config.IsTaskPattern(workspaceName) || config.IsTaskPattern(taskName):
+			keys, err := c.workspace.MatchTasks(workspaceName, taskName)
+			if err != nil {
+				return nil, err
+			}
+			candidates = candidates[:0]
+			for _, key := range keys {
+				ws, task := parseTaskSpec(key)
+				candidates = append(candidates, dependencySpec{workspace: ws, task: task})
+			}
+			candidates = c.withoutInternal(candidates)
+		case workspaceName == "":
+			found, err := c.findTaskInWorkspaces(taskName)
+			if err != nil {
+				return nil, err
+			}
+			if len(found) == 0 {
+				return nil, &workspace.TaskNotFoundError{Task: taskName}
+			}
+			candidates = candidates[:0]
+			for _, ws := range found {
+				candidates = append(candidates, dependencySpec{workspace: ws, task: c.config.TaskName(ws, taskName)})
+			}
+			candidates = c.withoutInternal(candidates)
+		}
+
+		for _, target := range candidates {
+			if !seen[target] && c.workspace.MatchesTags(target.workspace, target.task, tagFilters) {
+				seen[target] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+	if len(targets) == 0 && len(specs) > 0 && len(tagFilters) > 0 {
+		return nil, fmt.Errorf("no task of %s has any of the tags %s", strings.Join(specs, ", "), strings.Join(tagFilters, ", "))
+	}
+	return targets, nil
+}
+
+// withoutInternal drops the internal tasks from targets, unless all of them
+// are internal.
+func (c *CLI) withoutInternal(targets []dependencySpec) []dependencySpec {
+	public := slices.DeleteFunc(slices.Clone(targets), func(target dependencySpec) bool {
+		task, _ := c.config.GetTask(target.workspace, target.task)
+		return task.Internal
+	})
+	if len(public) == 0 {
+		return targets
+	}
+	return public
+}
+
+// checkInternalTargets rejects internal tasks among the targets of a run,
+// as they only run as dependencies. --no-deps runs exactly the named tasks,
+// as generated CI jobs do, so it allows them.
+func (c *CLI) checkInternalTargets(targets []dependencySpec) error {
+	if noDeps {
+		return nil
+	}
+	for _, target := range targets {
+		task, _ := c.config.GetTask(target.workspace, target.task)
+		if !task.Internal {
+			continue
+		}
+		key := target.workspace + ":" + target.task
+		dependents := c.workspace.Dependents(target.workspace, target.task)
+		if len(dependents) == 0 {
+			return fmt.Errorf("%s is an internal task and only runs as a dependency, but no task depends on it", key)
+		}
+		return fmt.Errorf("%s is an internal task and only runs as a dependency; run a task depending on it instead: %s", key, strings.Join(dependents, ", "))
+	}
+	return nil
+}
 
-	if workspaceName == "" {
-		found, err := c.findTaskInWorkspaces(taskName)
+// appendTaskArgs appends the arguments given after -- to the command of
+// every task specs name, leaving their dependencies alone. The task's cache
+// entry depends on them like on the rest of its command.
+func (c *CLI) appendTaskArgs(specs, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	targets, err := c.resolveTargets(specs)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		execution, err := c.workspace.ResolveTaskExecution(target.workspace, target.task)
 		if err != nil {
-			return err
+			// Resolving the run reports the unknown task
+			continue
 		}
-		if len(found) == 0 {
-			return fmt.Errorf("task %s not found in any workspace", taskName)
+		if len(execution.Task.Command) == 0 {
+			return fmt.Errorf("can't pass arguments to %s:%s: it is a compound task without a command", target.workspace, target.task)
 		}
+		task := *execution.Task
+		task.Command = slices.Concat(task.Command, args)
+		if err := c.workspace.UpdateTask(target.workspace, target.task, task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		for _, ws := range found {
-			if err := c.runTaskInWorkspace(ctx, runner, ws, taskName); err != nil {
-				return err
+// resolveExecutions returns every task running targets executes, in
+// execution order and without duplicates.
+func (c *CLI) resolveExecutions(targets []dependencySpec) ([]*workspace.TaskExecution, error) {
+	var executions []*workspace.TaskExecution
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		var resolved []*workspace.TaskExecution
+		if noDeps {
+			execution, err := c.workspace.ResolveTaskExecution(target.workspace, target.task)
+			if err != nil {
+				return nil, err
+			}
+			resolved = []*workspace.TaskExecution{execution}
+		} else {
+			var err error
+			resolved, err = c.workspace.ResolveDependencies(target.workspace, target.task)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve dependencies: %w", err)
+			}
+		}
+		for _, execution := range resolved {
+			key := execution.WorkspaceName + ":" + execution.TaskName
+			if !seen[key] {
+				seen[key] = true
+				executions = append(executions, execution)
 			}
 		}
-		return nil
 	}
+	return executions, nil
+}
 
-	return c.runTaskInWorkspace(ctx, runner, workspaceName, taskName)
+func (c *CLI) runTaskInWorkspace(ctx context.Context, runner *taskrun.Scheduler, workspaceName, taskName string) error {
+	return c.runTargets(ctx, runner, []dependencySpec{{workspace: workspaceName, task: taskName}})
 }
 
-func (c *CLI) runTaskInWorkspace(ctx context.Context, runner *taskRunner, workspaceName, taskName string) error {
-	executions, err := c.workspace.ResolveDependencies(workspaceName, taskName)
+// runTargets runs the targets and their dependencies as one graph: several
+// targets run at the same time under the runner's parallelism limit, and
+// dependencies they share run once. With a limit of one task at a time they
+// run in the order given.
+func (c *CLI) runTargets(ctx context.Context, runner *taskrun.Scheduler, targets []dependencySpec) error {
+	executions, err := c.resolveExecutions(targets)
 	if err != nil {
-		return fmt.Errorf("failed to resolve dependencies: %w", err)
+		return err
+	}
+	if err := c.workspace.ValidateExecutions(executions); err != nil {
+		return fmt.Errorf("workspace validation failed: %w", err)
 	}
 
-	if verbose {
-		c.printf("Resolved execution order:\n")
+	if c.log.Enabled(logging.LevelDebug) {
+		c.log.Debugf("Resolved execution order:\n")
 		for i, exec := range executions {
-			c.printf("  %d. %s:%s\n", i+1, exec.WorkspaceName, exec.TaskName)
+			c.log.Debugf("  %d. %s:%s\n", i+1, exec.WorkspaceName, exec.TaskName)
+		}
+		c.log.Debugf("\n")
+	}
+
+	names := make([]string, len(targets))
+	for i, target := range targets {
+		names[i] = target.workspace + ":" + target.task
+	}
+	c.porcelain.plan(executions)
+	c.planReport(executions)
+	if total, known := c.estimate.plan(executions); known > 0 && formatEstimate(total) != "" {
+		c.log.Infof("%s\n", c.ui.Status(ui.KindInfo, fmt.Sprintf("Estimated %s for %s based on run history", formatEstimate(total), strings.Join(names, ", "))))
+	}
+
+	lease, err := c.acquireLock(ctx, executions)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lease.Release(); err != nil {
+			c.log.Warnf("Warning: failed to release run lock: %v\n", err)
+		}
+	}()
+
+	c.prefetchCache(executions)
+
+	specs := make([]taskrun.Target, len(targets))
+	for i, target := range targets {
+		specs[i] = taskrun.Target{Workspace: target.workspace, Task: target.task}
+	}
+	failed, err := runner.RunTargets(ctx, specs)
+	c.printFailures(runner)
+	if err != nil {
+		return fmt.Errorf("failed to run task %s: %w", failed, err)
+	}
+	return nil
+}
+
+// acquireLock registers the run's workspaces in .doctrus/run.lock when
+// locking is enabled by --lock or the lock setting, waiting for or failing
+// on other runs that use them.
+func (c *CLI) acquireLock(ctx context.Context, executions []*workspace.TaskExecution) (*lock.Lease, error) {
+	value := lockMode
+	if value == "" && c.config != nil {
+		value = c.config.Lock
+	}
+	mode, err := lock.ParseMode(value)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun || mode == lock.ModeOff {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	holder := lock.Holder{
+		PID:       os.Getpid(),
+		Command:   strings.Join(append([]string{"doctrus"}, os.Args[1:]...), " "),
+		StartedAt: time.Now(),
+	}
+	for _, execution := range executions {
+		if !seen[execution.WorkspaceName] {
+			seen[execution.WorkspaceName] = true
+			holder.Workspaces = append(holder.Workspaces, execution.WorkspaceName)
 		}
-		c.printf("\n")
 	}
 
-	return runner.RunTask(ctx, workspaceName, taskName, false)
+	runLock := lock.New(filepath.Join(c.basePath, ".doctrus", "run.lock"))
+	return runLock.Acquire(ctx, holder, mode, func(conflict *lock.ConflictError) {
+		c.log.Warnf("%s\n", c.ui.Status(ui.KindWarning, "Waiting: "+conflict.Error()))
+	})
 }
 
-func (c *CLI) runExecution(ctx context.Context, execution *workspace.TaskExecution, showTaskPrefix bool) error {
+func (c *CLI) runExecution(ctx context.Context, execution *workspace.TaskExecution, showTaskPrefix bool) (err error) {
 	taskKey := fmt.Sprintf("%s:%s", execution.WorkspaceName, execution.TaskName)
 
 	task := execution.Task
 	taskVerbose := isTaskVerbose(task)
-	detailedLogging := verbose || taskVerbose
+	// The TUI shows the latest line of a task's output instead
+	detailedLogging := (verbose || taskVerbose) && c.tui == nil
+	// Grouped output is printed in one piece once the task finished
+	streamOutput := detailedLogging && c.github == nil
 
 	if len(task.Command) == 0 {
 		c.printCompoundTask(execution, detailedLogging, isTaskParallel(task))
+		c.events.Publish(events.Event{
+			Type:      events.TaskFinished,
+			Workspace: execution.WorkspaceName,
+			Task:      execution.TaskName,
+			Status:    events.StatusCompound,
+		})
 		return nil
 	}
 
-	header := fmt.Sprintf("▶ Running %s", taskKey)
+	estimate, remaining := c.estimate.task(taskKey)
+	defer c.estimate.finish(taskKey)
+
+	header := fmt.Sprintf("Running %s", taskKey)
 	if detailedLogging {
 		header += fmt.Sprintf(" in %s", execution.AbsPath)
 	}
-	c.printf("%s\n", header)
-
-	var previousState *deps.TaskState
-	if !skipCache && task.Cache {
-		var err error
-		previousState, err = c.cache.Get(taskKey)
-		if err != nil && detailedLogging {
-			c.printf("  Warning: failed to load cache: %v\n", err)
-		} else if previousState != nil && detailedLogging {
-			c.printf("  Cache found, checking for changes...\n")
-		}
+	if text := formatEstimate(estimate); text != "" {
+		header += fmt.Sprintf(" (%s)", text)
+	}
+	c.log.Infof("%s\n", c.term.Fit(c.ui.Status(ui.KindHeader, header)))
+	record := history.TaskRecord{
+		Workspace: execution.WorkspaceName,
+		Task:      execution.TaskName,
+		StartedAt: time.Now(),
 	}
+	ctx, span := tracing.Start(ctx, taskKey, tracing.String("doctrus.workspace", execution.WorkspaceName), tracing.String("doctrus.task", execution.TaskName))
+	defer func() {
+		if record.Outcome != "" {
+			span.SetAttributes(tracing.String("doctrus.outcome", string(record.Outcome)), tracing.Int("doctrus.exit_code", record.ExitCode))
+		}
+		span.End(err)
+	}()
 
-	shouldRun := forceBuild || skipCache
-	if !shouldRun {
-		var err error
-		shouldRun, err = c.tracker.ShouldRunTask(execution, previousState)
+	if c.since != nil && !forceBuild {
+		affected, err := c.since.isAffected(c.workspace, taskKey)
 		if err != nil {
-			return fmt.Errorf("failed to check if task should run: %w", err)
+			return err
+		}
+		if !affected {
+			c.log.Infof("  %s\n", c.ui.Status(ui.KindCached, "Skipped (inputs unchanged since "+c.since.revision+")"))
+			record.Outcome = history.OutcomeCached
+			record.Duration = time.Since(record.StartedAt)
+			c.events.Publish(events.Event{Type: events.CacheHit, Workspace: record.Workspace, Task: record.Task})
+			c.recordTask(record, nil)
+			return nil
 		}
 	}
 
-	if !shouldRun {
-		c.printf("  ✓ Cached (no changes detected)\n")
-		return nil
+	// Interactive tasks write straight to the terminal, leaving nothing to log
+	var taskLog *tasklog.File
+	defer func() {
+		if taskLog != nil {
+			taskLog.Close()
+		}
+	}()
+	var stdoutWriter, stderrWriter io.Writer
+	var stdoutFlusher, stderrFlusher interface{ Flush() error }
+	var status *ui.StatusLine
+	var statusLabel string
+	checked := false
+	hooks := c.finallyHooks(taskKey, streamOutput, showTaskPrefix)
+	hooks.Checked = func(check *taskrun.Check) {
+		checked = true
+		if check.Previous != nil {
+			c.detailf(detailedLogging, "  Cache found, checking for changes...\n")
+		}
+		if check.Stale != "" {
+			c.detailf(detailedLogging, "  Targets out of date: %s\n", check.Stale)
+		}
+		if check.Run && showDiff && check.Previous != nil {
+			c.printTaskDiff(taskKey, check.Execution, check.Previous)
+		}
 	}
+	hooks.Start = func() (io.Writer, io.Writer) {
+		if streamOutput {
+			stdoutWriter = newTaskLogWriter(c, taskKey, "stdout", showTaskPrefix)
+			stderrWriter = newTaskLogWriter(c, taskKey, "stderr", showTaskPrefix)
+			stdoutFlusher = stdoutWriter.(*taskLogWriter)
+			stderrFlusher = stderrWriter.(*taskLogWriter)
+		}
+		if !task.Interactive {
+			if taskLog = c.openTaskLog(execution, record.StartedAt); taskLog != nil {
+				// Colors are for terminals; the log file keeps plain text
+				stdoutWriter = withWriter(stdoutWriter, ui.NewWriter(taskLog, false))
+				stderrWriter = withWriter(stderrWriter, ui.NewWriter(taskLog, false))
+			}
+		}
 
-	if showDiff && previousState != nil {
-		changes, err := c.tracker.GetChangedInputs(execution, previousState)
-		if err == nil && len(changes) > 0 {
-			c.printf("  Changed inputs: %s\n", strings.Join(changes, ", "))
+		statusLabel = "Running " + taskKey
+		if text := formatEstimate(remaining); text != "" {
+			statusLabel += fmt.Sprintf(" · %s remaining", text)
 		}
+		// An interactive task owns the terminal, so the status line waits
+		status = c.status
+		if task.Interactive {
+			status = nil
+		}
+		status.Start(statusLabel)
+		if c.tui != nil && status != nil {
+			stdoutWriter = withWriter(stdoutWriter, status.Detail(statusLabel))
+			stderrWriter = withWriter(stderrWriter, status.Detail(statusLabel))
+		}
+		if c.events.Active() {
+			stdoutWriter = withWriter(stdoutWriter, c.events.OutputWriter(execution.WorkspaceName, execution.TaskName, "stdout"))
+			stderrWriter = withWriter(stderrWriter, c.events.OutputWriter(execution.WorkspaceName, execution.TaskName, "stderr"))
+		}
+		c.events.Publish(events.Event{Type: events.TaskStarted, Workspace: execution.WorkspaceName, Task: execution.TaskName})
+		return stdoutWriter, stderrWriter
 	}
-
-	if dryRun {
-		c.printf("  Would run: %s\n", strings.Join(task.Command, " "))
-		return nil
+	hooks.Retry = func(attempt *taskrun.Attempt, delay time.Duration) {
+		result := attempt.Result
+		if c.github != nil {
+			c.github.group(fmt.Sprintf("%s (attempt %d of %d)", taskKey, attempt.Number, attempt.Max), result.Stdout, result.Stderr)
+		} else if !streamOutput {
+			c.printBufferedOutput(taskKey, "stdout", result.Stdout, showTaskPrefix)
+			c.printBufferedOutput(taskKey, "stderr", result.Stderr, showTaskPrefix)
+		}
+		message := fmt.Sprintf("Attempt %d of %d failed with exit code %d in %v, retrying", attempt.Number, attempt.Max, result.ExitCode, attempt.Duration.Round(time.Millisecond))
+		if result.Error != nil && result.ExitCode == 0 {
+			message = fmt.Sprintf("Attempt %d of %d failed (%v), retrying", attempt.Number, attempt.Max, result.Error)
+		}
+		if delay > 0 {
+			message += fmt.Sprintf(" in %v", delay)
+		}
+		c.log.Warnf("  %s\n", c.ui.Status(ui.KindWarning, message))
+		fmt.Fprintf(taskLog, "# attempt %d of %d\n", attempt.Number+1, attempt.Max)
 	}
-
-	var stdoutWriter, stderrWriter io.Writer
-	var stdoutFlusher, stderrFlusher interface{ Flush() error }
-	if detailedLogging {
-		stdoutWriter = &colorResetWriter{dest: newTaskLogWriter(c, taskKey, "stdout", showTaskPrefix)}
-		stderrWriter = &colorResetWriter{dest: newTaskLogWriter(c, taskKey, "stderr", showTaskPrefix)}
-		stdoutFlusher = stdoutWriter.(*colorResetWriter)
-		stderrFlusher = stderrWriter.(*colorResetWriter)
+	hooks.Executed = func(attempt *taskrun.Attempt) {
+		result := attempt.Result
+		if taskLog != nil {
+			fmt.Fprintf(taskLog, "# exited with code %d in %v\n", result.ExitCode, attempt.Duration.Round(time.Millisecond))
+		}
+		status.Done(statusLabel)
+		if task.Interactive {
+			// The command may have left the terminal in raw mode
+			terminal.Restore()
+		}
+		record.CPUTime = result.Usage.CPUTime
+		record.PeakRSS = result.Usage.PeakRSS
+		if attempt.Number > 1 {
+			c.github.group(fmt.Sprintf("%s (attempt %d of %d)", taskKey, attempt.Number, attempt.Max), result.Stdout, result.Stderr)
+		} else {
+			c.github.group(taskKey, result.Stdout, result.Stderr)
+		}
 	}
 
-	startTime := time.Now()
-	result := c.executor.Execute(ctx, execution, stdoutWriter, stderrWriter)
-	duration := time.Since(startTime)
+	// report prints and records the outcome of a task whose command ran
+	report := func(result *taskrun.Result, runErr error) error {
+		// Ensure colors are reset after command execution
+		if streamOutput {
+			if err := stdoutFlusher.Flush(); err != nil {
+				c.log.Warnf("Warning: failed to flush stdout colors: %v\n", err)
+			}
+			if err := stderrFlusher.Flush(); err != nil {
+				c.log.Warnf("Warning: failed to flush stderr colors: %v\n", err)
+			}
+		}
 
-	// Ensure colors are reset after command execution
-	if detailedLogging {
-		// Flush the writers to reset colors properly
-		if err := stdoutFlusher.Flush(); err != nil {
-			c.printf("Warning: failed to flush stdout colors: %v\n", err)
+		attempt := result.Attempt
+		exec := attempt.Result
+		if exec.Error != nil && exec.ExitCode == 0 {
+			record.Outcome = history.OutcomeFailed
+			record.Duration = time.Since(record.StartedAt)
+			c.recordTask(record, exec.Error)
+			return runErr
 		}
-		if err := stderrFlusher.Flush(); err != nil {
-			c.printf("Warning: failed to flush stderr colors: %v\n", err)
+		if result.SandboxErr != nil {
+			record.Outcome = history.OutcomeFailed
+			record.Duration = time.Since(record.StartedAt)
+			c.recordTask(record, result.SandboxErr)
+			return runErr
 		}
-	}
 
-	if result.Error != nil && result.ExitCode == 0 {
-		return fmt.Errorf("execution error: %w", result.Error)
-	}
+		success := exec.ExitCode == 0
+		if success {
+			c.estimate.executed(taskKey, attempt.Duration)
+		}
+		record.Outcome = history.OutcomeSuccess
+		if runErr != nil {
+			record.Outcome = history.OutcomeFailed
+		}
+		record.ExitCode = exec.ExitCode
+		record.Duration = time.Since(record.StartedAt)
+		cause := exec.Cause
+		if result.OutputsErr != nil {
+			cause = result.OutputsErr
+		} else if result.InputsErr != nil {
+			cause = result.InputsErr
+		} else if result.PublishErr != nil {
+			cause = result.PublishErr
+		} else if result.FinallyErr != nil {
+			cause = result.FinallyErr
+		}
+		c.recordTask(record, cause)
 
-	success := result.ExitCode == 0
+		if !success && c.github == nil {
+			if !streamOutput && exec.Stdout != "" {
+				c.printBufferedOutput(taskKey, "stdout", exec.Stdout, showTaskPrefix)
+			}
+			if !streamOutput && exec.Stderr != "" {
+				c.printBufferedOutput(taskKey, "stderr", exec.Stderr, showTaskPrefix)
+			}
+		}
 
-	if !success {
-		if !detailedLogging && result.Stdout != "" {
-			c.printBufferedOutput(taskKey, "stdout", result.Stdout, showTaskPrefix)
+		if !success {
+			message := fmt.Sprintf("Failed with exit code %d in %v", exec.ExitCode, attempt.Duration.Round(time.Millisecond))
+			if attempt.Number > 1 {
+				message += fmt.Sprintf(" on attempt %d of %d", attempt.Number, attempt.Max)
+			}
+			if exec.Cause != nil {
+				message = fmt.Sprintf("Cancelled (%v) with exit code %d", exec.Cause, exec.ExitCode)
+			}
+			c.log.Errorf("  %s\n", c.ui.Status(ui.KindFailure, message))
+			if taskLog != nil {
+				c.log.Errorf("  Full output in %s\n", c.relativeLogPath(taskLog))
+			}
+			return runErr
 		}
-		if !detailedLogging && result.Stderr != "" {
-			c.printBufferedOutput(taskKey, "stderr", result.Stderr, showTaskPrefix)
+		message := fmt.Sprintf("Executed successfully in %v", attempt.Duration.Round(time.Millisecond))
+		if attempt.Number > 1 {
+			message += fmt.Sprintf(" on attempt %d of %d", attempt.Number, attempt.Max)
 		}
-	}
+		if usage := formatUsage(exec.Usage); usage != "" {
+			message += fmt.Sprintf(" (%s)", usage)
+		}
+		c.log.Infof("  %s\n", c.ui.Status(ui.KindSuccess, message))
 
-	if success {
-		c.printf("  ✓ Executed successfully in %v\n", duration.Round(time.Millisecond))
-	} else {
-		c.printf("  ✗ Failed with exit code %d in %v\n", result.ExitCode, duration.Round(time.Millisecond))
-		return &TaskError{
-			ExitCode: result.ExitCode,
-			Message:  fmt.Sprintf("task failed with exit code %d", result.ExitCode),
+		for _, pattern := range result.MissingOutputs {
+			message := fmt.Sprintf("Output %s matched no files", pattern)
+			if result.OutputsErr != nil {
+				c.log.Errorf("  %s\n", c.ui.Status(ui.KindFailure, message))
+			} else {
+				c.log.Warnf("  %s\n", c.ui.Status(ui.KindWarning, message))
+			}
+		}
+		c.printUndeclaredReads(result.UndeclaredReads, result.InputsErr != nil)
+		if result.OutputsErr != nil || result.InputsErr != nil || result.FinallyErr != nil {
+			return runErr
+		}
+		for _, message := range result.Published {
+			c.log.Infof("  %s\n", c.ui.Status(ui.KindSuccess, message))
 		}
+		if result.PublishErr != nil {
+			c.log.Errorf("  %s\n", c.ui.Status(ui.KindFailure, result.PublishErr.Error()))
+		}
+		return runErr
+	}
+	// A task that succeeded is reported before its state is cached
+	hooks.Succeeded = func(result *taskrun.Result) {
+		report(result, nil)
 	}
 
-	if task.Cache {
-		taskState, err := c.tracker.ComputeTaskState(execution, success)
-		if err != nil {
-			if detailedLogging {
-				c.printf("  Warning: failed to compute task state: %v\n", err)
-			}
-		} else {
-			if err := c.cache.Set(taskKey, taskState, 0); err != nil {
-				if detailedLogging {
-					c.printf("  Warning: failed to cache task state: %v\n", err)
-				}
-			} else if detailedLogging {
-				c.printf("  Cache updated for future runs\n")
-			}
+	result, err := c.runner().Run(ctx, execution, hooks)
+	switch {
+	case result.Status == taskrun.StatusCached:
+		c.log.Infof("  %s\n", c.ui.Status(ui.KindCached, skipMessages[result.Skip]))
+		if replayLogs && result.Skip != taskrun.SkipRemote {
+			c.replayCachedLogs(taskKey, showTaskPrefix)
+		}
+		record.Outcome = history.OutcomeCached
+		record.Duration = time.Since(record.StartedAt)
+		c.events.Publish(events.Event{Type: events.CacheHit, Workspace: record.Workspace, Task: record.Task})
+		c.recordTask(record, nil)
+		return nil
+	case result.Status == taskrun.StatusDryRun:
+		c.log.Infof("  Would run: %s\n", strings.Join(c.config.WrapCommand(execution.WorkspaceName, execution.TaskName, task.Command), " "))
+		for _, target := range task.Publish {
+			c.log.Infof("  Would publish outputs to %s\n", target)
 		}
+		return nil
+	case result.Status == taskrun.StatusSuccess:
+		if result.State != nil {
+			c.detailf(detailedLogging, "  Cache updated for future runs\n")
+		}
+		return nil
+	case !checked:
+		return err
+	case result.Attempt == nil:
+		// The sandbox could not be staged
+		record.Outcome = history.OutcomeFailed
+		record.Duration = time.Since(record.StartedAt)
+		c.recordTask(record, err)
+		return err
 	}
+	return report(result, err)
+}
 
-	return nil
+// skipMessages tell why a task did not run.
+var skipMessages = map[taskrun.Skip]string{
+	taskrun.SkipUnchanged: "Cached (no changes detected)",
+	taskrun.SkipUpToDate:  "Up to date (targets newer than sources)",
+	taskrun.SkipRestored:  "Cached (outputs restored)",
+	taskrun.SkipRemote:    "Cached (restored from remote cache)",
+}
+
+// runner returns the runner executing tasks as the run's flags ask.
+func (c *CLI) runner() *taskrun.Runner {
+	return &taskrun.Runner{
+		Config:       c.config,
+		BasePath:     c.basePath,
+		Workspace:    c.workspace,
+		Executor:     c.executor,
+		Tasks:        c.tasks,
+		Tracker:      c.tracker,
+		Cache:        c.cache,
+		CAS:          c.cas,
+		Remote:       c.remote,
+		Log:          c.log,
+		Timeout:      max(taskTimeout, 0),
+		Force:        forceBuild,
+		SkipCache:    skipCache,
+		DryRun:       dryRun,
+		StrictInputs: strictInputs,
+		Distributed:  distribute,
+		Version:      version,
+	}
+}
+
+// printTaskDiff prints why a task whose cache was found runs again.
+func (c *CLI) printTaskDiff(taskKey string, execution *workspace.TaskExecution, previous *deps.TaskState) {
+	if showDiffFormat != diffJSON && previous.Definition != deps.TaskDefinition(execution) {
+		c.log.Infof("  Task definition changed (command, env, container or executor)\n")
+	}
+	if showDiffFormat != diffJSON && execution.ImageDigest != "" && execution.ImageDigest != previous.ImageDigest {
+		c.log.Infof("  Image changed: %s\n", execution.ImageDigest)
+	}
+	changes, err := c.tracker.DiffInputs(execution, previous)
+	if err != nil {
+		c.log.Warnf("  Warning: failed to compare inputs: %v\n", err)
+	} else {
+		c.printInputDiff(taskKey, changes)
+	}
 }
 
 func (c *CLI) printCompoundTask(execution *workspace.TaskExecution, detailed bool, isParallel bool) {
@@ -276,12 +881,12 @@ func (c *CLI) printCompoundTask(execution *workspace.TaskExecution, detailed boo
 		mode = "parallel dependencies"
 	}
 
-	message := fmt.Sprintf("▶ Compound task %s (%s)", taskKey, mode)
+	message := fmt.Sprintf("Compound task %s (%s)", taskKey, mode)
 	if detailed {
 		message += fmt.Sprintf(" in %s", execution.AbsPath)
 	}
-	c.printf("%s\n", message)
-	c.printf("  ✓ Dependencies completed\n")
+	c.log.Infof("%s\n", c.term.Fit(c.ui.Status(ui.KindHeader, message)))
+	c.log.Infof("  %s\n", c.ui.Status(ui.KindSuccess, "Dependencies completed"))
 }
 
 func isTaskVerbose(task *config.Task) bool {
@@ -298,12 +903,35 @@ func isTaskParallel(task *config.Task) bool {
 	return *task.Parallel
 }
 
+// parseTaskSpec splits "workspace:task" with config.SplitTaskSpec; the
+// workspace is empty for a bare "task", ":task" or "*:task", which name the
+// task in every workspace.
 func parseTaskSpec(taskSpec string) (string, string) {
-	parts := strings.Split(taskSpec, ":")
-	if len(parts) == 1 {
-		return "", parts[0]
+	return config.SplitTaskSpec(taskSpec)
+}
+
+// resolveTaskSpec splits a spec like parseTaskSpec, replacing an alias of a
+// task in the named workspace by the task's name.
+func (c *CLI) resolveTaskSpec(spec string) (string, string) {
+	workspaceName, taskName := parseTaskSpec(spec)
+	if workspaceName != "" {
+		taskName = c.config.TaskName(workspaceName, taskName)
 	}
-	return parts[0], parts[1]
+	return workspaceName, taskName
+}
+
+// allWorkspaceSpecs rewrites task names given with --all to ":task" specs,
+// rejecting specs that name a workspace.
+func allWorkspaceSpecs(specs []string) ([]string, error) {
+	all := make([]string, len(specs))
+	for i, spec := range specs {
+		workspaceName, taskName := parseTaskSpec(spec)
+		if workspaceName != "" {
+			return nil, fmt.Errorf("--all runs %s in every workspace; name the task without a workspace, as %s", spec, taskName)
+		}
+		all[i] = ":" + taskName
+	}
+	return all, nil
 }
 
 func (c *CLI) findTaskInWorkspaces(taskName string) ([]string, error) {
@@ -314,6 +942,7 @@ func (c *CLI) findTaskInWorkspaces(taskName string) ([]string, error) {
 			found = append(found, workspaceName)
 		}
 	}
+	sort.Strings(found)
 
 	return found, nil
 }
@@ -347,11 +976,11 @@ func (c *CLI) ensurePreRunCommands(ctx context.Context) error {
 			workingDir = filepath.Join(c.basePath, workingDir)
 		}
 
-		headline := fmt.Sprintf("▶ Pre-run %d/%d: %s", idx+1, len(c.config.Pre), cmdDisplay)
+		headline := fmt.Sprintf("Pre-run %d/%d: %s", idx+1, len(c.config.Pre), cmdDisplay)
 		if detailedLogging {
 			headline += fmt.Sprintf(" (dir %s)", workingDir)
 		}
-		c.printf("%s\n", headline)
+		c.log.Infof("%s\n", c.term.Fit(c.ui.Status(ui.KindHeader, headline)))
 
 		if len(pre.Command) == 0 {
 			return fmt.Errorf("pre[%d]: command is required", idx)
@@ -370,9 +999,12 @@ func (c *CLI) ensurePreRunCommands(ctx context.Context) error {
 		execCmd.Stdout = &stdoutBuf
 		execCmd.Stderr = &stderrBuf
 
+		statusLabel := fmt.Sprintf("Pre-run %d/%d", idx+1, len(c.config.Pre))
+		c.status.Start(statusLabel)
 		start := time.Now()
 		err := execCmd.Run()
 		duration := time.Since(start)
+		c.status.Done(statusLabel)
 
 		exitCode := 0
 		if err != nil {
@@ -388,230 +1020,245 @@ func (c *CLI) ensurePreRunCommands(ctx context.Context) error {
 
 		if detailedLogging || err != nil {
 			if stdout != "" {
-				c.printf("  stdout:\n%s\n", indentOutput(stdout))
+				c.printOutput("  stdout:\n%s\n", indentOutput(stdout))
 			}
 			if stderr != "" {
-				c.printf("  stderr:\n%s\n", indentOutput(stderr))
+				c.printOutput("  stderr:\n%s\n", indentOutput(stderr))
 			}
 		}
 
 		// Ensure colors are reset after pre-run command execution
-		c.printf("%s", colorReset)
+		c.printOutput("%s", c.ui.Reset())
 
 		if err != nil {
-			c.printf("  ✗ Failed with exit code %d in %v\n", exitCode, duration.Round(time.Millisecond))
-			return &TaskError{
+			c.log.Errorf("  %s\n", c.ui.Status(ui.KindFailure, fmt.Sprintf("Failed with exit code %d in %v", exitCode, duration.Round(time.Millisecond))))
+			return &CommandError{
 				ExitCode: exitCode,
 				Message:  fmt.Sprintf("pre-run command %d failed: %v", idx+1, err),
 			}
 		}
 
-		c.printf("  ✓ Completed in %v\n", duration.Round(time.Millisecond))
+		c.log.Infof("  %s\n", c.ui.Status(ui.KindSuccess, fmt.Sprintf("Completed in %v", duration.Round(time.Millisecond))))
 	}
 
 	c.preRunExecuted = true
 	return nil
 }
 
-func (c *CLI) printf(format string, args ...interface{}) {
-	c.outputMu.Lock()
-	defer c.outputMu.Unlock()
-	fmt.Printf(format, args...)
-}
-
-// cleanup ensures the terminal is in a clean state
-func (c *CLI) cleanup() {
+// printOutput writes command output that is not a diagnostic and therefore
+// bypasses the logger.
+func (c *CLI) printOutput(format string, args ...interface{}) {
 	c.outputMu.Lock()
 	defer c.outputMu.Unlock()
-	// Reset colors and ensure we're at the beginning of a new line
-	fmt.Printf("%s\n", colorReset)
-}
-
-type dependencySpec struct {
-	workspace string
-	task      string
+	fmt.Fprintf(c.output(), format, args...)
 }
 
-type taskRunner struct {
-	cli    *CLI
-	mu     sync.Mutex
-	states map[string]*taskState
-}
-
-type taskState struct {
-	cond    *sync.Cond
-	running bool
-	done    bool
-	err     error
+// output returns the writer for everything printed to stdout, which routes
+// through the status line on a live terminal.
+func (c *CLI) output() io.Writer {
+	if c.stdout == nil {
+		return os.Stdout
+	}
+	return c.stdout
 }
 
-func newTaskRunner(cli *CLI) *taskRunner {
-	return &taskRunner{
-		cli:    cli,
-		states: make(map[string]*taskState),
+// detailf logs a message that is shown at info level for verbose tasks and
+// only at debug level otherwise.
+func (c *CLI) detailf(detailed bool, format string, args ...interface{}) {
+	if detailed {
+		c.log.Infof(format, args...)
+		return
 	}
+	c.log.Debugf(format, args...)
 }
 
-func (r *taskRunner) RunTask(ctx context.Context, workspaceName, taskName string, triggeredByCompound bool) error {
-	taskKey := fmt.Sprintf("%s:%s", workspaceName, taskName)
+// prefetchCache loads the cache entries of every cached task in the graph in
+// one pass, so checking each task does not read the cache on its own.
+func (c *CLI) prefetchCache(executions []*workspace.TaskExecution) {
+	if skipCache {
+		return
+	}
 
-	r.mu.Lock()
-	if state, exists := r.states[taskKey]; exists {
-		for state.running {
-			state.cond.Wait()
+	var keys []string
+	for _, execution := range executions {
+		if execution.Task.Cache {
+			keys = append(keys, execution.WorkspaceName+":"+execution.TaskName)
 		}
-		err := state.err
-		r.mu.Unlock()
-		return err
+	}
+	if len(keys) == 0 {
+		return
 	}
 
-	state := &taskState{}
-	state.cond = sync.NewCond(&r.mu)
-	state.running = true
-	r.states[taskKey] = state
-	r.mu.Unlock()
-
-	err := r.execute(ctx, workspaceName, taskName, triggeredByCompound)
-
-	r.mu.Lock()
-	state.running = false
-	state.done = true
-	state.err = err
-	state.cond.Broadcast()
-	r.mu.Unlock()
-
-	return err
+	if err := c.cache.Prefetch(keys); err != nil {
+		c.log.Debugf("Cache prefetch failed, reading entries individually: %v\n", err)
+	}
 }
 
-func (r *taskRunner) execute(ctx context.Context, workspaceName, taskName string, triggeredByCompound bool) error {
-	execution, err := r.cli.workspace.ResolveTaskExecution(workspaceName, taskName)
-	if err != nil {
-		return err
+// recordTask adds a finished task to the run history and publishes its
+// TaskFinished event.
+func (c *CLI) recordTask(record history.TaskRecord, err error) {
+	c.history.Add(record)
+
+	event := events.Event{
+		Type:      events.TaskFinished,
+		Workspace: record.Workspace,
+		Task:      record.Task,
+		Status:    string(record.Outcome),
+		ExitCode:  record.ExitCode,
+		Duration:  record.Duration,
+		CPUTime:   record.CPUTime,
+		PeakRSS:   record.PeakRSS,
 	}
-
-	deps, err := r.cli.collectDependencies(workspaceName, execution.Task)
 	if err != nil {
-		return err
+		event.Error = err.Error()
 	}
+	c.events.Publish(event)
+}
 
-	if len(deps) > 0 {
-		if isParallelCompound(execution.Task) {
-			if err := r.runDependenciesParallel(ctx, deps, triggeredByCompound || len(execution.Task.Command) == 0); err != nil {
-				return err
-			}
-		} else {
-			childCompoundContext := triggeredByCompound || len(execution.Task.Command) == 0
-			for _, dep := range deps {
-				if err := r.RunTask(ctx, dep.workspace, dep.task, childCompoundContext); err != nil {
-					return err
-				}
-			}
-		}
+// formatUsage renders the CPU time and peak memory of a task, such as
+// "cpu 2.1s, peak 48.0 MiB", leaving out what was not measured.
+func formatUsage(usage docker.ResourceUsage) string {
+	var parts []string
+	if usage.CPUTime > 0 {
+		parts = append(parts, fmt.Sprintf("cpu %v", usage.CPUTime.Round(time.Millisecond)))
 	}
-
-	return r.cli.runExecution(ctx, execution, triggeredByCompound)
+	if usage.PeakRSS > 0 {
+		parts = append(parts, "peak "+formatBytes(usage.PeakRSS))
+	}
+	return strings.Join(parts, ", ")
 }
 
-func (r *taskRunner) runDependenciesParallel(ctx context.Context, deps []dependencySpec, triggeredByCompound bool) error {
-	var wg sync.WaitGroup
-	errCh := make(chan error, len(deps))
-
-	for _, dep := range deps {
-		dep := dep
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if err := r.RunTask(ctx, dep.workspace, dep.task, triggeredByCompound); err != nil {
-				errCh <- err
-			}
-		}()
+// withWriter adds extra to an optional destination writer.
+func withWriter(dest, extra io.Writer) io.Writer {
+	if dest == nil {
+		return extra
 	}
+	return io.MultiWriter(dest, extra)
+}
 
-	wg.Wait()
+// saveHistory appends the recorded run to the project's execution history.
+func (c *CLI) saveHistory(runErr error) {
+	if c.history == nil {
+		return
+	}
+	run := c.history.Finish(runErr)
+	if err := historyStore(c.basePath).Append(run); err != nil {
+		c.log.Warnf("Warning: failed to record run history: %v\n", err)
+	}
+}
 
-	select {
-	case err := <-errCh:
-		return err
-	default:
-		return nil
+// cleanup ensures the terminal is in a clean state and closes the log file
+func (c *CLI) cleanup() {
+	c.status.Close()
+	// A task killed mid-way may leave the terminal in raw mode or with its
+	// cursor hidden
+	terminal.Restore()
+	// Reset colors and ensure we're at the beginning of a new line, which
+	// every line of quiet output already ends
+	if c.tui != nil && c.tui.quiet {
+		c.printOutput("%s", c.ui.Reset())
+	} else {
+		c.printOutput("%s\n", c.ui.Reset())
+	}
+	if err := c.log.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close log file: %v\n", err)
 	}
 }
 
-func (c *CLI) collectDependencies(currentWorkspace string, task *config.Task) ([]dependencySpec, error) {
-	var deps []dependencySpec
+type dependencySpec struct {
+	workspace string
+	task      string
+}
 
-	for _, dep := range task.DependsOn {
-		dep = strings.TrimSpace(dep)
-		if dep == "" {
-			continue
-		}
+// newTaskRunner returns the scheduler running the tasks of a run as the
+// run's flags ask.
+func newTaskRunner(cli *CLI) *taskrun.Scheduler {
+	runner := taskrun.NewScheduler(cli.config, cli.workspace, cli.runExecution)
+	runner.Queued = func(target taskrun.Target) {
+		cli.events.Publish(events.Event{Type: events.TaskQueued, Workspace: target.Workspace, Task: target.Task})
+	}
+	runner.Recover = restoreTerminalOnPanic
+	runner.NoDeps = noDeps
+	return runner
+}
 
-		workspaceName := currentWorkspace
-		taskName := dep
+// printFailures lists the failed and skipped tasks of a --keep-going run.
+func (c *CLI) printFailures(runner *taskrun.Scheduler) {
+	failed, skipped := runner.Failed(), runner.Skipped()
+	if !runner.KeepGoing || len(failed) == 0 {
+		return
+	}
+	c.log.Errorf("%s\n", c.ui.Status(ui.KindFailure, fmt.Sprintf("%d %s failed: %s",
+		len(failed), plural(len(failed), "task", "tasks"), strings.Join(failed, ", "))))
+	if len(skipped) > 0 {
+		c.log.Errorf("  Skipped %d %s after a failed dependency: %s\n",
+			len(skipped), plural(len(skipped), "task", "tasks"), strings.Join(skipped, ", "))
+	}
+}
 
-		parts := strings.Split(dep, ":")
-		if len(parts) == 2 {
-			workspaceName = parts[0]
-			taskName = parts[1]
-		} else if len(parts) > 2 {
-			return nil, fmt.Errorf("invalid dependency format: %s", dep)
+// parallelSlots returns the slots limiting how many tasks run at once, as
+// set by --parallel or the parallel setting, or nil without a limit. The
+// CPUs are counted once per run.
+func (c *CLI) parallelSlots() (chan struct{}, error) {
+	value := parallel
+	if value == "" && distribute {
+		// Agents limit their own jobs, so a distributed run keeps all of
+		// them busy
+		if capacity := c.agents.Capacity(); capacity > 0 {
+			c.log.Debugf("Running up to %d task(s) at once on agents\n", capacity)
+			return make(chan struct{}, capacity), nil
 		}
-
-		deps = append(deps, dependencySpec{workspace: workspaceName, task: taskName})
+		return nil, nil
 	}
-
-	return deps, nil
+	if value == "" && c.config != nil {
+		value = c.config.Parallel
+	}
+	limit, err := config.ParseParallel(value, runtime.NumCPU())
+	if err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		return nil, nil
+	}
+	c.log.Debugf("Running up to %d task(s) at once (%d CPUs)\n", limit, runtime.NumCPU())
+	return make(chan struct{}, limit), nil
 }
 
-func isParallelCompound(task *config.Task) bool {
-	return len(task.Command) == 0 && isTaskParallel(task)
+func (c *CLI) collectDependencies(currentWorkspace string, task *config.Task) ([]dependencySpec, error) {
+	targets, err := taskrun.Dependencies(c.config, currentWorkspace, task)
+	if err != nil {
+		return nil, err
+	}
+	var deps []dependencySpec
+	for _, target := range targets {
+		deps = append(deps, dependencySpec{workspace: target.Workspace, task: target.Task})
+	}
+	return deps, nil
 }
 
+// taskLogWriter copies a task's output to the terminal a line at a time,
+// optionally prefixed with the task and stream. Incomplete lines are held
+// until they end so output of parallel tasks never interleaves mid-line or
+// splits a UTF-8 sequence; Flush writes whatever is left. The output passes
+// through a ui.Writer, which keeps its colors only on a color terminal.
 type taskLogWriter struct {
 	cli         *CLI
 	dest        io.Writer
 	prefix      []byte
 	showPrefix  bool
 	atLineStart bool
+	pending     []byte
 }
 
-// colorResetWriter ensures colors are reset after output
-type colorResetWriter struct {
-	dest io.Writer
-}
-
-func (w *colorResetWriter) Write(p []byte) (int, error) {
-	n, err := w.dest.Write(p)
-	// Don't reset colors after every write - this breaks colored output formatting
-	// Colors will be reset when the writer is closed or flushed
-	return n, err
-}
-
-// Flush ensures colors are reset and any buffered output is written
-func (w *colorResetWriter) Flush() error {
-	// Reset colors at the end of output
-	_, err := w.dest.Write([]byte(colorReset))
-	if err != nil {
-		return fmt.Errorf("failed to write color reset sequence: %w", err)
-	}
-	return nil
-}
-
-// Close ensures colors are reset when the writer is closed
-func (w *colorResetWriter) Close() error {
-	// Reset colors when closing the writer
-	_, err := w.dest.Write([]byte(colorReset))
-	if err != nil {
-		return fmt.Errorf("failed to write color reset sequence on close: %w", err)
-	}
-	return nil
-}
+// maxPendingLine bounds how much of an unterminated line taskLogWriter holds
+// back before writing it anyway.
+const maxPendingLine = 64 * 1024
 
 func newTaskLogWriter(cli *CLI, taskKey, stream string, showPrefix bool) io.Writer {
 	prefix := []byte(fmt.Sprintf("[%s][%s] ", taskKey, stream))
 	return &taskLogWriter{
 		cli:         cli,
-		dest:        os.Stdout,
+		dest:        cli.ui.Writer(cli.output()),
 		prefix:      prefix,
 		showPrefix:  showPrefix,
 		atLineStart: true,
@@ -622,49 +1269,128 @@ func (w *taskLogWriter) Write(p []byte) (int, error) {
 	w.cli.outputMu.Lock()
 	defer w.cli.outputMu.Unlock()
 
-	total := 0
-	rest := p
+	w.pending = append(w.pending, p...)
+	for {
+		end := lineEnd(w.pending)
+		if end < 0 {
+			break
+		}
+		if err := w.writeSegment(w.pending[:end]); err != nil {
+			return len(p), err
+		}
+		w.pending = w.pending[end:]
+	}
 
-	for len(rest) > 0 {
-		if w.atLineStart && w.showPrefix {
-			if _, err := w.dest.Write(w.prefix); err != nil {
-				return total, err
-			}
-			w.atLineStart = false
+	if len(w.pending) >= maxPendingLine {
+		cut := completeRunes(w.pending)
+		if err := w.writeSegment(w.pending[:cut]); err != nil {
+			return len(p), err
 		}
+		w.pending = w.pending[cut:]
+	}
+	w.pending = append([]byte(nil), w.pending...)
+
+	return len(p), nil
+}
+
+// Flush writes the held back part of an unterminated line and resets the
+// colors the output may have left set.
+func (w *taskLogWriter) Flush() error {
+	w.cli.outputMu.Lock()
+	defer w.cli.outputMu.Unlock()
+
+	err := w.writeSegment(w.pending)
+	w.pending = nil
+	if err != nil {
+		return err
+	}
+	if flusher, ok := w.dest.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
 
-		newlineIndex := bytes.IndexByte(rest, '\n')
-		if newlineIndex == -1 {
-			written, err := w.dest.Write(rest)
-			total += written
-			return total, err
+// writeSegment writes part of a line, with the prefix when it starts one. A
+// carriage return starts a new line too, so progress output that redraws
+// its line keeps the prefix.
+func (w *taskLogWriter) writeSegment(segment []byte) error {
+	if len(segment) == 0 {
+		return nil
+	}
+	if w.atLineStart && w.showPrefix {
+		if _, err := w.dest.Write(w.prefix); err != nil {
+			return err
 		}
+	}
+	last := segment[len(segment)-1]
+	w.atLineStart = last == '\n' || last == '\r'
+	_, err := w.dest.Write(segment)
+	return err
+}
 
-		chunk := rest[:newlineIndex+1]
-		written, err := w.dest.Write(chunk)
-		total += written
-		if err != nil {
-			return total, err
+// lineEnd returns the length of the first line in buf, ended by \n, \r\n or
+// a lone \r, or -1 when buf holds no complete line. A trailing \r is not a
+// line end yet because a \n may follow in the next write.
+func lineEnd(buf []byte) int {
+	i := bytes.IndexAny(buf, "\r\n")
+	switch {
+	case i < 0:
+		return -1
+	case buf[i] == '\n':
+		return i + 1
+	case i+1 == len(buf):
+		return -1
+	case buf[i+1] == '\n':
+		return i + 2
+	default:
+		return i + 1
+	}
+}
+
+// completeRunes returns the length of buf without a trailing incomplete
+// UTF-8 sequence.
+func completeRunes(buf []byte) int {
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if !utf8.FullRune(buf[i:]) {
+				return i
+			}
+			break
 		}
-		w.atLineStart = true
-		rest = rest[newlineIndex+1:]
 	}
+	return len(buf)
+}
 
-	return total, nil
+// replayCachedLogs prints the output stored with a cached task, so logs
+// show its warnings and summaries even though nothing ran.
+func (c *CLI) replayCachedLogs(taskKey string, showPrefix bool) {
+	logs, err := c.cache.GetLogs(taskKey)
+	if err != nil {
+		c.log.Warnf("  Warning: failed to load cached output: %v\n", err)
+		return
+	}
+	if logs == nil {
+		c.detailf(verbose, "  No output cached for %s\n", taskKey)
+		return
+	}
+	if logs.Truncated {
+		c.log.Infof("  %s\n", c.ui.Paint(ui.KindCached, "Cached output (truncated to its end):"))
+	}
+	c.printBufferedOutput(taskKey, "stdout", logs.Stdout, showPrefix)
+	c.printBufferedOutput(taskKey, "stderr", logs.Stderr, showPrefix)
 }
 
 func (c *CLI) printBufferedOutput(taskKey, stream, output string, showPrefix bool) {
 	if strings.TrimSpace(output) == "" {
 		return
 	}
-	writer := &colorResetWriter{dest: newTaskLogWriter(c, taskKey, stream, showPrefix)}
+	writer := newTaskLogWriter(c, taskKey, stream, showPrefix).(*taskLogWriter)
 	if !strings.HasSuffix(output, "\n") {
 		output += "\n"
 	}
 	_, _ = writer.Write([]byte(output))
-	// Flush to ensure colors are reset
 	if err := writer.Flush(); err != nil {
-		c.printf("Warning: failed to flush colors for %s: %v\n", stream, err)
+		c.log.Warnf("Warning: failed to flush colors for %s: %v\n", stream, err)
 	}
 }
 
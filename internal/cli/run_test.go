@@ -3,9 +3,13 @@ package cli
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +17,7 @@ import (
 	"doctrus/internal/config"
 	"doctrus/internal/deps"
 	"doctrus/internal/docker"
+	"doctrus/internal/logging"
 	"doctrus/internal/workspace"
 )
 
@@ -112,6 +117,9 @@ func TestTaskLogWriterPrefixesOnlyWhenRequested(t *testing.T) {
 		if _, err := writer.Write([]byte(msg)); err != nil {
 			t.Fatalf("Write() error = %v", err)
 		}
+		if err := writer.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
 
 		if got, want := buf.String(), msg; got != want {
 			t.Fatalf("Write() got %q, want %q", got, want)
@@ -127,6 +135,9 @@ func TestTaskLogWriterPrefixesOnlyWhenRequested(t *testing.T) {
 		if _, err := writer.Write([]byte(msg)); err != nil {
 			t.Fatalf("Write() error = %v", err)
 		}
+		if err := writer.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
 
 		want := "[web:build][stderr] line one\n[web:build][stderr] second 🎉\n[web:build][stderr] third"
 		if got := buf.String(); got != want {
@@ -135,6 +146,60 @@ func TestTaskLogWriterPrefixesOnlyWhenRequested(t *testing.T) {
 	})
 }
 
+func TestTaskLogWriterHoldsPartialLines(t *testing.T) {
+	t.Parallel()
+
+	prefix := "[app:build][stdout] "
+	tests := []struct {
+		name   string
+		writes []string
+		before string
+		want   string
+	}{
+		{
+			name:   "multi-byte characters split across writes",
+			writes: []string{"caf\xc3", "\xa9 🎉"[:4], "\xa9 🎉"[4:], "\n"},
+			before: prefix + "café 🎉\n",
+			want:   prefix + "café 🎉\n",
+		},
+		{
+			name:   "partial line waits for its end",
+			writes: []string{"compiling", " done\nnext"},
+			before: prefix + "compiling done\n",
+			want:   prefix + "compiling done\n" + prefix + "next",
+		},
+		{
+			name:   "carriage return progress keeps the prefix",
+			writes: []string{"10%\r", "50%\r", "100%\r\n"},
+			before: prefix + "10%\r" + prefix + "50%\r" + prefix + "100%\r\n",
+			want:   prefix + "10%\r" + prefix + "50%\r" + prefix + "100%\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := newTaskLogWriter(&CLI{}, "app:build", "stdout", true).(*taskLogWriter)
+			writer.dest = &buf
+
+			for _, chunk := range tt.writes {
+				if _, err := writer.Write([]byte(chunk)); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			if got := buf.String(); got != tt.before {
+				t.Fatalf("before Flush() got %q, want %q", got, tt.before)
+			}
+			if err := writer.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Fatalf("after Flush() got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func boolPtr(v bool) *bool {
 	return &v
 }
@@ -248,7 +313,7 @@ func TestParallelCompoundRunsDependenciesConcurrently(t *testing.T) {
 	skipCache = false
 	dryRun = false
 	showDiff = false
-	parallel = 1
+	parallel = ""
 
 	start := time.Now()
 	if err := cli.runTaskInWorkspace(ctx, runner, "app", "bundle"); err != nil {
@@ -260,3 +325,672 @@ func TestParallelCompoundRunsDependenciesConcurrently(t *testing.T) {
 		t.Fatalf("expected parallel execution to finish sooner, took %v", duration)
 	}
 }
+
+func TestParallelSlotsLimitConcurrentTasks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell sleep command not available on Windows")
+	}
+
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"app": {
+				Path: tempDir,
+				Tasks: map[string]config.Task{
+					"slowA":  {Command: []string{"sh", "-c", "sleep 0.3"}},
+					"slowB":  {Command: []string{"sh", "-c", "sleep 0.3"}},
+					"bundle": {DependsOn: []string{"slowA", "slowB"}, Parallel: boolPtr(true)},
+				},
+			},
+		},
+	}
+
+	workspaceManager := workspace.NewManager(cfg, tempDir)
+	if err := workspaceManager.ValidateWorkspaces(); err != nil {
+		t.Fatalf("ValidateWorkspaces() error = %v", err)
+	}
+
+	cli := &CLI{
+		config:    cfg,
+		workspace: workspaceManager,
+		executor:  docker.NewExecutor(cfg, tempDir),
+		tracker:   deps.NewTracker(tempDir),
+		cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+		basePath:  tempDir,
+	}
+
+	origParallel := parallel
+	t.Cleanup(func() { parallel = origParallel })
+	parallel = "1"
+
+	runner := newTaskRunner(cli)
+	var err error
+	if runner.Slots, err = cli.parallelSlots(); err != nil {
+		t.Fatalf("parallelSlots() error = %v", err)
+	}
+
+	start := time.Now()
+	if err := cli.runTaskInWorkspace(context.Background(), runner, "app", "bundle"); err != nil {
+		t.Fatalf("runTaskInWorkspace() error = %v", err)
+	}
+	if duration := time.Since(start); duration < 600*time.Millisecond {
+		t.Fatalf("expected one task at a time with --parallel 1, took %v", duration)
+	}
+}
+
+func TestParallelLimitSchedulesReadyDependencies(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell sleep command not available on Windows")
+	}
+
+	tempDir := t.TempDir()
+	logPath := filepath.Join(tempDir, "order.log")
+	record := func(name string) []string {
+		return []string{"sh", "-c", "sleep 0.3; echo " + name + " >> " + logPath}
+	}
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"app": {
+				Path: tempDir,
+				Tasks: map[string]config.Task{
+					"gen":   {Command: []string{"sh", "-c", "echo gen >> " + logPath}},
+					"lint":  {Command: record("lint"), DependsOn: []string{"gen"}},
+					"test":  {Command: record("test"), DependsOn: []string{"gen"}},
+					"build": {Command: []string{"sh", "-c", "echo build >> " + logPath}, DependsOn: []string{"lint", "test"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		parallel string
+		fast     bool
+	}{
+		{name: "no limit keeps listed order", parallel: "", fast: false},
+		{name: "one at a time", parallel: "1", fast: false},
+		{name: "two at a time", parallel: "2", fast: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(logPath)
+			cli := &CLI{
+				config:    cfg,
+				workspace: workspace.NewManager(cfg, tempDir),
+				executor:  docker.NewExecutor(cfg, tempDir),
+				tracker:   deps.NewTracker(tempDir),
+				cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+				basePath:  tempDir,
+			}
+
+			origParallel := parallel
+			t.Cleanup(func() { parallel = origParallel })
+			parallel = tt.parallel
+
+			runner := newTaskRunner(cli)
+			var err error
+			if runner.Slots, err = cli.parallelSlots(); err != nil {
+				t.Fatalf("parallelSlots() error = %v", err)
+			}
+
+			start := time.Now()
+			if err := cli.runTaskInWorkspace(context.Background(), runner, "app", "build"); err != nil {
+				t.Fatalf("runTaskInWorkspace() error = %v", err)
+			}
+			duration := time.Since(start)
+			if tt.fast && duration > 550*time.Millisecond {
+				t.Fatalf("expected ready dependencies to run side by side, took %v", duration)
+			}
+			if !tt.fast && duration < 600*time.Millisecond {
+				t.Fatalf("expected one task at a time, took %v", duration)
+			}
+
+			data, err := os.ReadFile(logPath)
+			if err != nil {
+				t.Fatalf("failed to read log: %v", err)
+			}
+			lines := strings.Fields(string(data))
+			if len(lines) != 4 || lines[0] != "gen" || lines[3] != "build" {
+				t.Fatalf("order = %v, want gen first and build last", lines)
+			}
+			if !tt.fast && strings.Join(lines, " ") != "gen lint test build" {
+				t.Fatalf("order = %v, want dependencies in the order listed", lines)
+			}
+		})
+	}
+}
+
+func TestRunTargetsSchedulesSpecsTogether(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell sleep command not available on Windows")
+	}
+
+	tempDir := t.TempDir()
+	logPath := filepath.Join(tempDir, "order.log")
+	record := func(name string) []string {
+		return []string{"sh", "-c", "sleep 0.3; echo " + name + " >> " + logPath}
+	}
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"shared": {
+				Path:  tempDir,
+				Tasks: map[string]config.Task{"gen": {Command: []string{"sh", "-c", "echo gen >> " + logPath}}},
+			},
+			"a": {
+				Path:  tempDir,
+				Tasks: map[string]config.Task{"build": {Command: record("a"), DependsOn: []string{"shared:gen"}}},
+			},
+			"b": {
+				Path:  tempDir,
+				Tasks: map[string]config.Task{"build": {Command: record("b"), DependsOn: []string{"shared:gen"}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		parallel string
+		fast     bool
+	}{
+		{name: "two at a time", parallel: "2", fast: true},
+		{name: "one at a time", parallel: "1", fast: false},
+		{name: "default", parallel: "", fast: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(logPath)
+			cli := &CLI{
+				config:    cfg,
+				workspace: workspace.NewManager(cfg, tempDir),
+				executor:  docker.NewExecutor(cfg, tempDir),
+				tracker:   deps.NewTracker(tempDir),
+				cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+				basePath:  tempDir,
+			}
+
+			origParallel := parallel
+			t.Cleanup(func() { parallel = origParallel })
+			parallel = tt.parallel
+
+			runner := newTaskRunner(cli)
+			var err error
+			if runner.Slots, err = cli.parallelSlots(); err != nil {
+				t.Fatalf("parallelSlots() error = %v", err)
+			}
+			targets, err := cli.resolveTargets([]string{"b:build", "a:build", "b:build"})
+			if err != nil {
+				t.Fatalf("resolveTargets() error = %v", err)
+			}
+
+			start := time.Now()
+			if err := cli.runTargets(context.Background(), runner, targets); err != nil {
+				t.Fatalf("runTargets() error = %v", err)
+			}
+			duration := time.Since(start)
+			if tt.fast && duration > 550*time.Millisecond {
+				t.Fatalf("expected specs to run side by side, took %v", duration)
+			}
+			if !tt.fast && duration < 600*time.Millisecond {
+				t.Fatalf("expected one task at a time with --parallel %q, took %v", tt.parallel, duration)
+			}
+
+			data, err := os.ReadFile(logPath)
+			if err != nil {
+				t.Fatalf("failed to read log: %v", err)
+			}
+			lines := strings.Fields(string(data))
+			if len(lines) != 3 || lines[0] != "gen" {
+				t.Fatalf("order = %v, want the shared dependency once, first", lines)
+			}
+			if !tt.fast && strings.Join(lines, " ") != "gen b a" {
+				t.Fatalf("order = %v, want the specs in the order given", lines)
+			}
+		})
+	}
+}
+
+func TestRunTargetsKeepGoing(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell commands not available on Windows")
+	}
+
+	tempDir := t.TempDir()
+	marker := filepath.Join(tempDir, "other.txt")
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"app": {
+				Path: tempDir,
+				Tasks: map[string]config.Task{
+					"broken": {Command: []string{"sh", "-c", "exit 3"}},
+					"after":  {Command: []string{"true"}, DependsOn: []string{"broken"}},
+					"other":  {Command: []string{"sh", "-c", "touch " + marker}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		parallel    string
+		keepGoing   bool
+		wantOther   bool
+		wantSkipped []string
+	}{
+		{name: "fail fast", parallel: "1", keepGoing: false, wantOther: false, wantSkipped: []string{"app:after"}},
+		{name: "keep going", parallel: "1", keepGoing: true, wantOther: true, wantSkipped: []string{"app:after"}},
+		{name: "keep going side by side", parallel: "", keepGoing: true, wantOther: true, wantSkipped: []string{"app:after"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(marker)
+			cli := &CLI{
+				config:    cfg,
+				workspace: workspace.NewManager(cfg, tempDir),
+				executor:  docker.NewExecutor(cfg, tempDir),
+				tracker:   deps.NewTracker(tempDir),
+				cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+				basePath:  tempDir,
+			}
+
+			origParallel := parallel
+			t.Cleanup(func() { parallel = origParallel })
+			parallel = tt.parallel
+
+			runner := newTaskRunner(cli)
+			var err error
+			if runner.Slots, err = cli.parallelSlots(); err != nil {
+				t.Fatalf("parallelSlots() error = %v", err)
+			}
+			runner.KeepGoing = tt.keepGoing
+			targets, err := cli.resolveTargets([]string{"app:after", "app:other"})
+			if err != nil {
+				t.Fatalf("resolveTargets() error = %v", err)
+			}
+
+			err = cli.runTargets(context.Background(), runner, targets)
+			if GetExitCode(err) != 3 {
+				t.Fatalf("runTargets() error = %v, want the failure with exit code 3", err)
+			}
+			if _, statErr := os.Stat(marker); (statErr == nil) != tt.wantOther {
+				t.Fatalf("independent task ran = %v, want %v", statErr == nil, tt.wantOther)
+			}
+			if strings.Join(runner.Failed(), ",") != "app:broken" {
+				t.Fatalf("failed = %v, want [app:broken]", runner.Failed())
+			}
+			if strings.Join(runner.Skipped(), ",") != strings.Join(tt.wantSkipped, ",") {
+				t.Fatalf("skipped = %v, want %v", runner.Skipped(), tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestRunExecutionRetries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell commands not available on Windows")
+	}
+
+	tempDir := t.TempDir()
+	counter := filepath.Join(tempDir, "attempts")
+	// Fails until it has run the given number of times
+	flaky := func(failures int) []string {
+		return []string{"sh", "-c", fmt.Sprintf("echo x >> %s; [ $(wc -l < %s) -gt %d ]", counter, counter, failures)}
+	}
+
+	tests := []struct {
+		name         string
+		failures     int
+		attempts     int
+		wantExitCode int
+		wantAttempts int
+	}{
+		{name: "succeeds on a retry", failures: 2, attempts: 3, wantExitCode: 0, wantAttempts: 3},
+		{name: "fails after the last attempt", failures: 5, attempts: 2, wantExitCode: 1, wantAttempts: 2},
+		{name: "without retry", failures: 1, attempts: 0, wantExitCode: 1, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(counter)
+			task := config.Task{Command: flaky(tt.failures), Cache: true}
+			if tt.attempts > 0 {
+				task.Retry = &config.TaskRetry{Attempts: tt.attempts, Delay: "10ms", Backoff: config.BackoffExponential}
+			}
+			cfg := &config.Config{
+				Version: "1.0",
+				Workspaces: map[string]config.Workspace{
+					"app": {Path: tempDir, Tasks: map[string]config.Task{"flaky": task}},
+				},
+			}
+			cli := &CLI{
+				config:    cfg,
+				workspace: workspace.NewManager(cfg, tempDir),
+				executor:  docker.NewExecutor(cfg, tempDir),
+				tracker:   deps.NewTracker(tempDir),
+				cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache", tt.name)),
+				basePath:  tempDir,
+			}
+
+			err := cli.runTaskInWorkspace(context.Background(), newTaskRunner(cli), "app", "flaky")
+			if GetExitCode(err) != tt.wantExitCode || (err == nil) != (tt.wantExitCode == 0) {
+				t.Fatalf("runTaskInWorkspace() error = %v, want exit code %d", err, tt.wantExitCode)
+			}
+			data, err := os.ReadFile(counter)
+			if err != nil {
+				t.Fatalf("failed to read counter: %v", err)
+			}
+			if got := strings.Count(string(data), "x"); got != tt.wantAttempts {
+				t.Fatalf("command ran %d times, want %d", got, tt.wantAttempts)
+			}
+
+			// Only a successful final attempt is cached
+			state, err := cli.cache.Get("app:flaky")
+			if err != nil {
+				t.Fatalf("cache.Get() error = %v", err)
+			}
+			if (state != nil) != (tt.wantExitCode == 0) {
+				t.Fatalf("cached state = %v, want cached only on success", state)
+			}
+		})
+	}
+}
+
+func TestRunExecutionTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell commands not available on Windows")
+	}
+
+	tests := []struct {
+		name        string
+		taskTimeout string
+		flag        time.Duration
+		wantTimeout time.Duration
+	}{
+		{name: "task timeout", taskTimeout: "100ms", wantTimeout: 100 * time.Millisecond},
+		{name: "flag for tasks without one", flag: 150 * time.Millisecond, wantTimeout: 150 * time.Millisecond},
+		{name: "task timeout wins over the flag", taskTimeout: "100ms", flag: time.Hour, wantTimeout: 100 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			cfg := &config.Config{
+				Version: "1.0",
+				Workspaces: map[string]config.Workspace{
+					"app": {
+						Path:  tempDir,
+						Tasks: map[string]config.Task{"slow": {Command: []string{"sleep", "5"}, Timeout: tt.taskTimeout}},
+					},
+				},
+			}
+			cli := &CLI{
+				config:    cfg,
+				workspace: workspace.NewManager(cfg, tempDir),
+				executor:  docker.NewExecutor(cfg, tempDir),
+				tracker:   deps.NewTracker(tempDir),
+				cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+				basePath:  tempDir,
+			}
+
+			origTimeout := taskTimeout
+			t.Cleanup(func() { taskTimeout = origTimeout })
+			taskTimeout = tt.flag
+
+			start := time.Now()
+			err := cli.runTaskInWorkspace(context.Background(), newTaskRunner(cli), "app", "slow")
+			if time.Since(start) > 2*time.Second {
+				t.Fatalf("task was not stopped at its timeout")
+			}
+			var timeoutErr *docker.TimeoutError
+			if GetExitCode(err) != 124 || !errors.As(err, &timeoutErr) {
+				t.Fatalf("runTaskInWorkspace() error = %v, want a timeout with exit code 124", err)
+			}
+			if timeoutErr.Timeout != tt.wantTimeout {
+				t.Fatalf("timeout = %v, want %v", timeoutErr.Timeout, tt.wantTimeout)
+			}
+		})
+	}
+}
+
+func TestRunExecutionWritesTaskLog(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell commands not available on Windows")
+	}
+
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"app": {
+				Path:  tempDir,
+				Tasks: map[string]config.Task{"test": {Command: []string{"sh", "-c", `printf '\033[32mout\033[0m\n'; echo err >&2; exit 3`}}},
+			},
+		},
+	}
+	var out bytes.Buffer
+	cli := &CLI{
+		config:    cfg,
+		workspace: workspace.NewManager(cfg, tempDir),
+		executor:  docker.NewExecutor(cfg, tempDir),
+		tracker:   deps.NewTracker(tempDir),
+		cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+		stdout:    &out,
+		basePath:  tempDir,
+	}
+	cli.log = logging.New(&out, logging.LevelInfo, &cli.outputMu)
+
+	if err := cli.runTaskInWorkspace(context.Background(), newTaskRunner(cli), "app", "test"); GetExitCode(err) != 3 {
+		t.Fatalf("runTaskInWorkspace() error = %v, want exit code 3", err)
+	}
+
+	logs, err := filepath.Glob(filepath.Join(tempDir, ".doctrus", "logs", "app__test", "*.log"))
+	if err != nil || len(logs) != 1 {
+		t.Fatalf("expected one task log, got %v (%v)", logs, err)
+	}
+	data, err := os.ReadFile(logs[0])
+	if err != nil {
+		t.Fatalf("failed to read task log: %v", err)
+	}
+	for _, want := range []string{"# $ sh -c", "out\n", "err\n", "# exited with code 3"} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("task log misses %q:\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "\033[32m") {
+		t.Fatalf("task log keeps the colors of the output:\n%q", data)
+	}
+	rel, _ := filepath.Rel(tempDir, logs[0])
+	if !strings.Contains(out.String(), "Full output in "+rel) {
+		t.Fatalf("expected the log path after the failure, got:\n%s", out.String())
+	}
+}
+
+func TestAppendTaskArgs(t *testing.T) {
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"web": {Tasks: map[string]config.Task{
+				"test":    {Command: []string{"npm", "test"}, DependsOn: []string{"install"}},
+				"install": {Command: []string{"npm", "install"}},
+				"ci":      {DependsOn: []string{"test"}},
+			}},
+			"api": {Tasks: map[string]config.Task{
+				"test": {Command: []string{"go", "test", "./..."}},
+			}},
+		},
+	}
+	cli := &CLI{config: cfg, workspace: workspace.NewManager(cfg, t.TempDir())}
+
+	if err := cli.appendTaskArgs([]string{"test"}, []string{"-run", "TestFoo"}); err != nil {
+		t.Fatalf("appendTaskArgs() error = %v", err)
+	}
+	if got := cfg.Workspaces["web"].Tasks["test"].Command; !reflect.DeepEqual(got, []string{"npm", "test", "-run", "TestFoo"}) {
+		t.Errorf("web:test command = %v", got)
+	}
+	if got := cfg.Workspaces["api"].Tasks["test"].Command; !reflect.DeepEqual(got, []string{"go", "test", "./...", "-run", "TestFoo"}) {
+		t.Errorf("api:test command = %v", got)
+	}
+	if got := cfg.Workspaces["web"].Tasks["install"].Command; !reflect.DeepEqual(got, []string{"npm", "install"}) {
+		t.Errorf("web:install command = %v, want dependencies left alone", got)
+	}
+
+	if err := cli.appendTaskArgs([]string{"web:ci"}, []string{"--watch"}); err == nil || !strings.Contains(err.Error(), "compound task") {
+		t.Errorf("appendTaskArgs() error = %v, want a compound task error", err)
+	}
+}
+
+func TestAllWorkspaceSpecs(t *testing.T) {
+	t.Parallel()
+
+	got, err := allWorkspaceSpecs([]string{"test", ":lint", "*:build"})
+	if err != nil {
+		t.Fatalf("allWorkspaceSpecs() error = %v", err)
+	}
+	if want := []string{":test", ":lint", ":build"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("allWorkspaceSpecs() = %v, want %v", got, want)
+	}
+
+	if _, err := allWorkspaceSpecs([]string{"test", "api:test"}); err == nil || !strings.Contains(err.Error(), "api:test") {
+		t.Fatalf("allWorkspaceSpecs() error = %v, want one naming api:test", err)
+	}
+}
+
+func TestInternalTargets(t *testing.T) {
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"web": {Tasks: map[string]config.Task{
+				"test":  {Command: []string{"npm", "test"}, DependsOn: []string{"setup"}},
+				"setup": {Command: []string{"npm", "ci"}, Internal: true},
+			}},
+			"api": {Tasks: map[string]config.Task{
+				"setup": {Command: []string{"go", "mod", "download"}},
+			}},
+		},
+	}
+	cli := &CLI{config: cfg, workspace: workspace.NewManager(cfg, t.TempDir())}
+
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []dependencySpec
+		wantErr string
+	}{
+		{name: "bare name skips internal tasks", specs: []string{"setup"}, want: []dependencySpec{{workspace: "api", task: "setup"}}},
+		{name: "pattern skips internal tasks", specs: []string{"*:set*"}, want: []dependencySpec{{workspace: "api", task: "setup"}}},
+		{name: "explicit internal task", specs: []string{"web:setup"}, wantErr: "web:setup is an internal task and only runs as a dependency; run a task depending on it instead: web:test"},
+		{name: "dependency of a target", specs: []string{"web:test"}, want: []dependencySpec{{workspace: "web", task: "test"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := cli.resolveTargets(tt.specs)
+			if err == nil {
+				err = cli.checkInternalTargets(targets)
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if !reflect.DeepEqual(targets, tt.want) {
+				t.Errorf("targets = %v, want %v", targets, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunTargetsRunsAliasedDependencyOnce(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell commands not available on Windows")
+	}
+
+	tempDir := t.TempDir()
+	logPath := filepath.Join(tempDir, "order.log")
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"app": {
+				Path: tempDir,
+				Tasks: map[string]config.Task{
+					"gen": {
+						Command: []string{"sh", "-c", "echo gen >> " + logPath},
+						Aliases: []string{"g"},
+						Cache:   true,
+					},
+					"build": {
+						Command:   []string{"sh", "-c", "echo build >> " + logPath},
+						DependsOn: []string{"g", "gen", "app:g"},
+					},
+				},
+			},
+		},
+	}
+	cli := &CLI{
+		config:    cfg,
+		workspace: workspace.NewManager(cfg, tempDir),
+		executor:  docker.NewExecutor(cfg, tempDir),
+		tracker:   deps.NewTracker(tempDir),
+		cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+		basePath:  tempDir,
+	}
+
+	targets, err := cli.resolveTargets([]string{"app:build"})
+	if err != nil {
+		t.Fatalf("resolveTargets() error = %v", err)
+	}
+	if err := cli.runTargets(context.Background(), newTaskRunner(cli), targets); err != nil {
+		t.Fatalf("runTargets() error = %v", err)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+	if got := strings.Fields(string(data)); strings.Join(got, " ") != "gen build" {
+		t.Fatalf("order = %v, want gen once, then build", got)
+	}
+	if state, _ := cli.cache.Get("app:g"); state != nil {
+		t.Error("the alias got a cache entry of its own")
+	}
+}
+
+func TestCollectDependencies(t *testing.T) {
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"web": {Tasks: map[string]config.Task{
+				"setup":  {Command: []string{"npm", "ci"}, Aliases: []string{"s"}},
+				"gen:js": {Command: []string{"npm", "run", "gen"}},
+			}},
+		},
+	}
+	cli := &CLI{config: cfg}
+
+	got, err := cli.collectDependencies("web", &config.Task{DependsOn: []string{"s", " gen:js ", "web:gen:js", "lib:build", ""}})
+	if err != nil {
+		t.Fatalf("collectDependencies() error = %v", err)
+	}
+	want := []dependencySpec{
+		{workspace: "web", task: "setup"},
+		{workspace: "gen", task: "js"},
+		{workspace: "web", task: "gen:js"},
+		{workspace: "lib", task: "build"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collectDependencies() = %v, want %v", got, want)
+	}
+
+	if _, err := cli.collectDependencies("web", &config.Task{DependsOn: []string{"*:setup"}}); err == nil {
+		t.Error("collectDependencies() accepted a dependency on every workspace")
+	}
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/docker"
+	"doctrus/internal/logging"
+	"doctrus/internal/workspace"
+)
+
+func TestRunExecutionInSandbox(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell commands not available on Windows")
+	}
+
+	tempDir := t.TempDir()
+	for file, content := range map[string]string{
+		"app/src/main.txt":  "main",
+		"app/notes.txt":     "undeclared",
+		"lib/out/lib.txt":   "lib",
+		"lib/src/input.txt": "not an output",
+	} {
+		path := filepath.Join(tempDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	script := `test -f src/main.txt || exit 10
+test -f ../lib/out/lib.txt || exit 11
+test -f notes.txt && exit 12
+test -f ../lib/src/input.txt && exit 13
+mkdir -p dist && cat src/main.txt ../lib/out/lib.txt > dist/bundle.txt
+echo scratch > scratch.txt`
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"lib": {
+				Path: "lib",
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"true"}, Outputs: []string{"out/**"}},
+					"all":   {DependsOn: []string{"build"}},
+				},
+			},
+			"app": {
+				Path: "app",
+				Tasks: map[string]config.Task{
+					"build": {
+						Command:   []string{"sh", "-c", script},
+						DependsOn: []string{"lib:all"},
+						Inputs:    []string{"src/**"},
+						Outputs:   []string{"dist/**"},
+						Sandbox:   true,
+					},
+				},
+			},
+		},
+	}
+	var out bytes.Buffer
+	c := &CLI{
+		config:    cfg,
+		workspace: workspace.NewManager(cfg, tempDir),
+		executor:  docker.NewExecutor(cfg, tempDir),
+		tracker:   deps.NewTracker(tempDir),
+		cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+		log:       logging.New(&out, logging.LevelInfo, nil),
+		stdout:    &out,
+		basePath:  tempDir,
+	}
+	execution, err := c.workspace.ResolveTaskExecution("app", "build")
+	if err != nil {
+		t.Fatalf("ResolveTaskExecution() error = %v", err)
+	}
+
+	if err := c.runExecution(context.Background(), execution, false); err != nil {
+		t.Fatalf("runExecution() error = %v\n%s", err, out.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "app", "dist", "bundle.txt"))
+	if err != nil {
+		t.Fatalf("output not copied out of the sandbox: %v", err)
+	}
+	if string(data) != "mainlib" {
+		t.Errorf("bundle.txt = %q, want %q", data, "mainlib")
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "app", "scratch.txt")); !os.IsNotExist(err) {
+		t.Errorf("undeclared output scratch.txt was copied out of the sandbox (stat error = %v)", err)
+	}
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/config"
+)
+
+func newSchemaCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of doctrus.yml",
+		Long: `Print a JSON Schema describing doctrus.yml, for editors and other tools
+that validate and complete YAML files. It is generated from the settings this
+version of doctrus understands.
+
+Examples:
+  doctrus schema > doctrus.schema.json
+  # Then, with the YAML language server, on the first line of doctrus.yml:
+  # yaml-language-server: $schema=./doctrus.schema.json`,
+		Args: cobra.NoArgs,
+		RunE: printSchema,
+	}
+
+	return cmd
+}
+
+func printSchema(cmd *cobra.Command, args []string) error {
+	return writeJSON(os.Stdout, config.ConfigSchema())
+}
+
+// checkSchema checks the config file at path and the files it includes, as
+// listed relative to dir, against the JSON Schema of doctrus.yml.
+func checkSchema(path, dir string, included []string) (config.Diagnostics, error) {
+	schema := config.ConfigSchema()
+	var diagnostics config.Diagnostics
+	for _, file := range append([]string{path}, included...) {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, filepath.FromSlash(file))
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", file, err)
+		}
+		diagnostics = append(diagnostics, config.CheckSchema(file, data, schema)...)
+	}
+	return diagnostics, nil
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/selfupdate"
+)
+
+var (
+	updateCheckOnly bool
+	updateVersion   string
+	updateForce     bool
+)
+
+func newSelfUpdateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Update doctrus to the latest release",
+		Long: `Check the release feed for a newer doctrus version, download the binary
+for this platform, verify its SHA256 checksum and atomically replace the
+running executable.
+
+Examples:
+  doctrus self-update                  # Update to the latest release
+  doctrus self-update --check          # Only report whether an update exists
+  doctrus self-update --version v1.2.0 # Install a specific release`,
+		Args: cobra.NoArgs,
+		RunE: runSelfUpdate,
+	}
+
+	cmd.Flags().BoolVar(&updateCheckOnly, "check", false, "Only check for a newer release")
+	cmd.Flags().StringVar(&updateVersion, "version", "", "Release tag to install (default: latest)")
+	cmd.Flags().BoolVar(&updateForce, "force", false, "Reinstall even if already up to date")
+
+	return cmd
+}
+
+func runSelfUpdate(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	updater := selfupdate.NewUpdater()
+
+	release, err := updater.Release(ctx, updateVersion)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Current version: %s\n", version)
+	fmt.Printf("Release version: %s\n", release.TagName)
+
+	upToDate := release.TagName == version || (updateVersion == "" && !selfupdate.IsNewer(version, release.TagName))
+	if upToDate && !updateForce {
+		fmt.Println("✓ doctrus is up to date")
+		return nil
+	}
+
+	if updateCheckOnly {
+		fmt.Printf("⚠️  Update available: run 'doctrus self-update' to install %s\n", release.TagName)
+		return nil
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = resolved
+	}
+
+	fmt.Printf("Downloading %s...\n", selfupdate.AssetName(updater.GOOS, updater.GOARCH))
+	data, err := updater.Download(ctx, release)
+	if err != nil {
+		return err
+	}
+	fmt.Println("✓ Checksum verified")
+
+	if err := selfupdate.Replace(exePath, data); err != nil {
+		return fmt.Errorf("%w (try re-running with sufficient permissions for %s)", err, filepath.Dir(exePath))
+	}
+
+	fmt.Printf("✓ Updated %s to %s\n", exePath, release.TagName)
+	return nil
+}
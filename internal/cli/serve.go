@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/dashboard"
+	"doctrus/internal/docker"
+)
+
+var serveListen string
+
+func newServeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Open a web dashboard of the project",
+		Long: `Serve a web dashboard showing the project's workspaces and task graph,
+cache and history statistics, and runs started from the browser with their
+task status and output streamed live. The dashboard is built on a JSON API
+under /api that scripts can use as well.
+
+Anyone who can reach the dashboard can run tasks, so it listens on localhost
+unless --listen says otherwise.
+
+Examples:
+  doctrus serve                        # http://127.0.0.1:7700
+  doctrus serve --listen :8080`,
+		Args: cobra.NoArgs,
+		RunE: runServe,
+	}
+
+	cmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:7700", "Address to listen on")
+
+	return cmd
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	// newCLI fills in the default cache directory, so the command for runs
+	// is built first
+	command, err := serveCommand()
+	if err != nil {
+		return err
+	}
+
+	cli, err := newCLI()
+	if err != nil {
+		return err
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	dash := dashboard.NewServer(dashboard.Options{
+		Config:  cli.config,
+		Cache:   cli.cache,
+		History: historyStore(cli.basePath),
+		Command: command,
+		Dir:     dir,
+	})
+
+	ctx, stop := docker.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server := &http.Server{
+		Handler: dash,
+		// Event streams end with the server instead of holding up shutdown
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	listener, err := net.Listen("tcp", serveListen)
+	if err != nil {
+		return fmt.Errorf("dashboard failed: %w", err)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Doctrus dashboard at http://%s\n", dashboardHost(listener.Addr()))
+	err = server.Serve(listener)
+	dash.Close()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("dashboard failed: %w", err)
+	}
+	return nil
+}
+
+// serveCommand returns how the dashboard starts runs: this executable with
+// the global flags that affect them.
+func serveCommand() ([]string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the doctrus executable: %w", err)
+	}
+	command := []string{executable, "--config", configPath, "--no-color"}
+	flags := rootCmd.PersistentFlags()
+	if flags.Changed("cache-dir") {
+		command = append(command, "--cache-dir", cacheDir)
+	}
+	if strict {
+		command = append(command, "--strict")
+	}
+	return command, nil
+}
+
+// dashboardHost returns a browsable host:port for the listener's address,
+// which is unspecified when listening on every interface.
+func dashboardHost(addr net.Addr) string {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok || !tcp.IP.IsUnspecified() {
+		return addr.String()
+	}
+	return net.JoinHostPort("localhost", fmt.Sprint(tcp.Port))
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"doctrus/internal/config"
+	"doctrus/internal/vcs"
+	"doctrus/internal/watch"
+	"doctrus/internal/workspace"
+)
+
+// sinceChanges holds the files changed since the revision given to --since,
+// which decide what runs regardless of the cache.
+type sinceChanges struct {
+	revision string
+	files    []string
+	// config is set when a configuration file changed, which may have
+	// changed any task
+	config bool
+
+	mu       sync.Mutex
+	affected map[string]bool
+}
+
+// loadSinceChanges lists the files changed in the project's repository since
+// revision.
+func (c *CLI) loadSinceChanges(revision string) (*sinceChanges, error) {
+	repo := vcs.Detect(c.basePath)
+	files, err := repo.ChangedFiles(revision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files changed since %s: %w", revision, err)
+	}
+
+	since := &sinceChanges{revision: revision, files: files, affected: make(map[string]bool)}
+	configFile, configDir, _ := config.Locate(configPath)
+	configFiles := map[string]bool{configFile: true}
+	for _, file := range c.config.IncludedFiles() {
+		configFiles[filepath.Join(configDir, filepath.FromSlash(file))] = true
+	}
+	for _, file := range files {
+		name := filepath.Base(file)
+		if configFiles[file] || name == "doctrus.yml" || name == "doctrus.yaml" {
+			since.config = true
+		}
+	}
+	c.log.Debugf("%d file(s) changed since %s\n", len(files), revision)
+	return since, nil
+}
+
+// isAffected reports whether a task may have changed since the revision: a
+// file matching its inputs changed, a task it depends on is affected, or it
+// has no inputs to tell by. Compound tasks are affected through their
+// dependencies only.
+func (s *sinceChanges) isAffected(manager *workspace.Manager, key string) (bool, error) {
+	return s.checkAffected(manager, key, map[string]bool{key: true})
+}
+
+func (s *sinceChanges) checkAffected(manager *workspace.Manager, key string, path map[string]bool) (bool, error) {
+	s.mu.Lock()
+	affected, ok := s.affected[key]
+	s.mu.Unlock()
+	if ok {
+		return affected, nil
+	}
+
+	workspaceName, taskName, _ := strings.Cut(key, ":")
+	execution, err := manager.ResolveTaskExecution(workspaceName, taskName)
+	if err != nil {
+		return false, err
+	}
+	task := execution.Task
+
+	affected = s.config || (len(task.Command) > 0 && len(task.Inputs) == 0)
+	if !affected {
+		patterns := make([]string, len(task.Inputs))
+		for i, input := range task.Inputs {
+			patterns[i] = input
+			if !filepath.IsAbs(input) {
+				patterns[i] = filepath.Join(execution.AbsPath, input)
+			}
+		}
+		for _, file := range s.files {
+			if watch.Match(patterns, file) {
+				affected = true
+				break
+			}
+		}
+	}
+	if !affected {
+		deps, err := manager.Dependencies(workspaceName, taskName)
+		if err != nil {
+			return false, err
+		}
+		for _, dep := range deps {
+			// Cycles are reported when the graph is resolved
+			if path[dep] {
+				continue
+			}
+			path[dep] = true
+			depAffected, err := s.checkAffected(manager, dep, path)
+			delete(path, dep)
+			if err != nil {
+				return false, err
+			}
+			if depAffected {
+				affected = true
+				break
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.affected[key] = affected
+	s.mu.Unlock()
+	return affected, nil
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+func TestSinceChangesIsAffected(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"lib": {
+				Path: "lib",
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"true"}, Inputs: []string{"src/**/*.go"}},
+					"gen":   {Command: []string{"true"}},
+				},
+			},
+			"web": {
+				Path: "web",
+				Tasks: map[string]config.Task{
+					"build":  {Command: []string{"true"}, Inputs: []string{"src/**"}, DependsOn: []string{"lib:build"}},
+					"lint":   {Command: []string{"true"}, Inputs: []string{"src/**"}},
+					"bundle": {Command: []string{"true"}, Inputs: []string{"assets/**"}, DependsOn: []string{"lib:gen"}},
+					"ci":     {DependsOn: []string{"build", "lint"}},
+				},
+			},
+		},
+	}
+	manager := workspace.NewManager(cfg, tempDir)
+
+	tests := []struct {
+		name   string
+		files  []string
+		config bool
+		want   map[string]bool
+	}{
+		{
+			name:  "input of a dependency",
+			files: []string{filepath.Join(tempDir, "lib", "src", "lib.go")},
+			want:  map[string]bool{"lib:build": true, "web:build": true, "web:lint": false, "web:ci": true},
+		},
+		{
+			name:  "own input",
+			files: []string{filepath.Join(tempDir, "web", "src", "app.ts")},
+			want:  map[string]bool{"lib:build": false, "web:build": true, "web:lint": true},
+		},
+		{
+			name:  "unrelated file",
+			files: []string{filepath.Join(tempDir, "README.md")},
+			want:  map[string]bool{"lib:build": false, "web:build": false, "web:ci": false},
+		},
+		{
+			name: "dependency without inputs",
+			want: map[string]bool{"lib:gen": true, "web:bundle": true},
+		},
+		{
+			name:   "configuration",
+			config: true,
+			want:   map[string]bool{"lib:build": true, "web:lint": true, "web:ci": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			since := &sinceChanges{files: tt.files, config: tt.config, affected: make(map[string]bool)}
+			for key, want := range tt.want {
+				got, err := since.isAffected(manager, key)
+				if err != nil {
+					t.Fatalf("isAffected(%s) error = %v", key, err)
+				}
+				if got != want {
+					t.Errorf("isAffected(%s) = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
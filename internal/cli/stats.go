@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/history"
+)
+
+var statsTop int
+
+func newStatsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Summarize run history",
+		Long: `Summarize the recorded run history per task: average and slowest
+durations, how durations trend over time, average CPU time and peak memory,
+cache hit rates and failure rates. Tasks are listed slowest first.
+
+Examples:
+  doctrus stats                 # All recorded history
+  doctrus stats --since 30d     # Last 30 days
+  doctrus stats -w backend      # Only backend tasks`,
+		Args: cobra.NoArgs,
+		RunE: showStats,
+	}
+
+	addHistoryFilterFlags(cmd)
+	cmd.Flags().IntVar(&statsTop, "top", 20, "Maximum number of tasks to show (0 for all)")
+
+	return cmd
+}
+
+func showStats(cmd *cobra.Command, args []string) error {
+	runs, err := loadHistory()
+	if err != nil {
+		return err
+	}
+
+	stats := history.Summarize(runs)
+	if len(stats) == 0 {
+		fmt.Println("No runs recorded")
+		return nil
+	}
+
+	fmt.Printf("Task statistics from %d run(s), %s to %s:\n\n",
+		len(runs),
+		runs[0].StartedAt.Local().Format("2006-01-02"),
+		runs[len(runs)-1].StartedAt.Local().Format("2006-01-02"))
+
+	shown := stats
+	if statsTop > 0 && len(shown) > statsTop {
+		shown = shown[:statsTop]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TASK\tRUNS\tAVG\tMAX\tTREND\tAVG CPU\tPEAK MEM\tCACHE HITS\tFAILURES")
+	for _, s := range shown {
+		fmt.Fprintf(w, "%s\t%d\t%v\t%v\t%s\t%s\t%s\t%s\t%s\n",
+			s.Key,
+			s.Runs,
+			s.Average.Round(time.Millisecond),
+			s.Max.Round(time.Millisecond),
+			formatTrend(s),
+			formatCPU(s.AverageCPU),
+			formatMemory(s.PeakRSS),
+			formatPercent(s.CacheHitRate()),
+			formatPercent(s.FailureRate()))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if hints := statsHints(stats); len(hints) > 0 {
+		fmt.Println("\nSuggestions:")
+		for _, hint := range hints {
+			fmt.Printf("  - %s\n", hint)
+		}
+	}
+
+	return nil
+}
+
+func formatTrend(s history.TaskStats) string {
+	if s.Trend == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.0f%%", s.Trend*100)
+}
+
+func formatCPU(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+func formatMemory(size int64) string {
+	if size == 0 {
+		return "-"
+	}
+	return formatBytes(size)
+}
+
+func formatPercent(rate float64) string {
+	return fmt.Sprintf("%.0f%%", rate*100)
+}
+
+// statsHints points out where caching or splitting tasks would pay off most.
+func statsHints(stats []history.TaskStats) []string {
+	var hints []string
+	for _, s := range stats {
+		switch {
+		case s.Executed >= 3 && s.FailureRate() >= 0.25:
+			hints = append(hints, fmt.Sprintf("%s fails in %s of executions", s.Key, formatPercent(s.FailureRate())))
+		case s.Average >= 30*time.Second && s.CacheHitRate() < 0.5:
+			hints = append(hints, fmt.Sprintf("%s averages %v and is rarely cached; consider declaring inputs or splitting it", s.Key, s.Average.Round(time.Second)))
+		case s.Trend >= 0.25 && s.Executed >= 4:
+			hints = append(hints, fmt.Sprintf("%s has become %s slower", s.Key, formatPercent(s.Trend)))
+		}
+		if len(hints) == 5 {
+			break
+		}
+	}
+	return hints
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+
+	"doctrus/internal/config"
+	"doctrus/internal/ui"
+)
+
+// maxUndeclaredShown bounds how many undeclared reads are listed per task.
+const maxUndeclaredShown = 10
+
+// parseStrictInputsFlag checks the value of --strict-inputs.
+func parseStrictInputsFlag(value string) error {
+	if value != "" && value != config.StrictInputsWarn && value != config.StrictInputsFail {
+		return fmt.Errorf("invalid --strict-inputs %q (expected warn or fail)", value)
+	}
+	return nil
+}
+
+// printUndeclaredReads lists the files a task read outside its inputs, as
+// failures when they fail the task and as warnings otherwise.
+func (c *CLI) printUndeclaredReads(files []string, failed bool) {
+	shown := files
+	if len(shown) > maxUndeclaredShown {
+		shown = shown[:maxUndeclaredShown]
+	}
+	for _, file := range shown {
+		message := fmt.Sprintf("Read %s, which inputs do not cover", file)
+		if failed {
+			c.log.Errorf("  %s\n", c.ui.Status(ui.KindFailure, message))
+		} else {
+			c.log.Warnf("  %s\n", c.ui.Status(ui.KindWarning, message))
+		}
+	}
+	if more := len(files) - len(shown); more > 0 {
+		c.log.Warnf("    … and %d more %s\n", more, plural(more, "file", "files"))
+	}
+}
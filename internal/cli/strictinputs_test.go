@@ -0,0 +1,14 @@
+package cli
+
+import "testing"
+
+func TestParseStrictInputsFlag(t *testing.T) {
+	for _, value := range []string{"", "warn", "fail"} {
+		if err := parseStrictInputsFlag(value); err != nil {
+			t.Errorf("parseStrictInputsFlag(%q) error = %v", value, err)
+		}
+	}
+	if err := parseStrictInputsFlag("error"); err == nil {
+		t.Error("parseStrictInputsFlag(\"error\") succeeded, want an error")
+	}
+}
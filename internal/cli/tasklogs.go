@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"doctrus/internal/tasklog"
+	"doctrus/internal/workspace"
+)
+
+// taskLogStore returns the store of the task log files under .doctrus/logs,
+// keeping as many per task as retention.log_runs allows.
+func (c *CLI) taskLogStore() *tasklog.Store {
+	keep := 0
+	if c.config != nil && c.config.Retention != nil {
+		keep = c.config.Retention.LogRuns
+	}
+	return tasklog.NewStore(filepath.Join(c.basePath, ".doctrus", "logs"), keep)
+}
+
+// openTaskLog creates the log file of an execution that started at
+// startedAt and writes its header. A log that can't be created only costs
+// a warning, and nil is returned.
+func (c *CLI) openTaskLog(execution *workspace.TaskExecution, startedAt time.Time) *tasklog.File {
+	file, err := c.taskLogStore().Create(execution.WorkspaceName, execution.TaskName, startedAt)
+	if err != nil {
+		c.log.Warnf("  Warning: %v\n", err)
+		return nil
+	}
+	command := c.config.WrapCommand(execution.WorkspaceName, execution.TaskName, execution.Task.Command)
+	fmt.Fprintf(file, "# %s:%s started %s in %s\n", execution.WorkspaceName, execution.TaskName, startedAt.Format(time.RFC3339), execution.AbsPath)
+	fmt.Fprintf(file, "# $ %s\n", strings.Join(command, " "))
+	return file
+}
+
+// relativeLogPath returns the path of a task log relative to the project,
+// as shown to the user.
+func (c *CLI) relativeLogPath(file *tasklog.File) string {
+	if rel, err := filepath.Rel(c.basePath, file.Path()); err == nil {
+		return rel
+	}
+	return file.Path()
+}
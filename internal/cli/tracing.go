@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"doctrus/internal/tracing"
+)
+
+// startTracing records the spans of the run when an OTLP endpoint is set in
+// the environment, continuing the trace in TRACEPARENT if there is one.
+// Invalid settings only cost a warning, as they must not fail the build.
+func (c *CLI) startTracing() {
+	exporter, err := tracing.FromEnv(version)
+	if err != nil {
+		c.log.Warnf("Warning: tracing disabled: %v\n", err)
+		return
+	}
+	c.tracer = tracing.NewTracer(exporter)
+	c.tracer.Continue(os.Getenv("TRACEPARENT"))
+}
+
+// exportTraces sends the recorded spans, waiting at most ten seconds.
+func (c *CLI) exportTraces() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := c.tracer.Shutdown(ctx); err != nil {
+		c.log.Warnf("Warning: %v\n", err)
+	}
+}
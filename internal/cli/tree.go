@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+// treeRoots returns the tasks list --tree starts from: the task named by
+// spec, every task of a workspace, or a task name across workspaces. Without
+// a spec it returns every task no other task depends on.
+func (c *CLI) treeRoots(spec string) ([]string, error) {
+	if spec == "" {
+		dependedOn := make(map[string]bool)
+		var all []string
+		for workspaceName, tasks := range c.workspace.GetAllTasks() {
+			for _, taskName := range tasks {
+				key := workspaceName + ":" + taskName
+				all = append(all, key)
+				deps, err := c.workspace.Dependencies(workspaceName, taskName)
+				if err != nil {
+					return nil, err
+				}
+				for _, dep := range deps {
+					dependedOn[dep] = true
+				}
+			}
+		}
+
+		var roots []string
+		for _, key := range all {
+			if !dependedOn[key] {
+				roots = append(roots, key)
+			}
+		}
+		sort.Strings(roots)
+		return roots, nil
+	}
+
+	workspaceName, taskName := c.resolveTaskSpec(spec)
+	if workspaceName != "" {
+		if _, exists := c.config.GetTask(workspaceName, taskName); !exists {
+			return nil, &workspace.TaskNotFoundError{Workspace: workspaceName, Task: taskName}
+		}
+		return []string{workspaceName + ":" + taskName}, nil
+	}
+
+	if _, all := config.AllWorkspacesTask(spec); !all {
+		if _, exists := c.config.GetWorkspace(taskName); exists {
+			tasks, err := c.workspace.GetTasks(taskName)
+			if err != nil {
+				return nil, err
+			}
+			roots := make([]string, 0, len(tasks))
+			for _, name := range tasks {
+				roots = append(roots, taskName+":"+name)
+			}
+			sort.Strings(roots)
+			return roots, nil
+		}
+	}
+
+	found, err := c.findTaskInWorkspaces(taskName)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, &workspace.TaskNotFoundError{Task: taskName}
+	}
+	roots := make([]string, 0, len(found))
+	for _, ws := range found {
+		roots = append(roots, ws+":"+c.config.TaskName(ws, taskName))
+	}
+	return roots, nil
+}
+
+// printTree renders each root with its transitive dependencies as an
+// indented tree. Dependencies in another workspace than the task depending
+// on them are shown as workspace:task. A task whose dependencies were
+// already expanded is marked instead of repeated.
+func (c *CLI) printTree(w io.Writer, roots []string) error {
+	return c.printTreeOf(w, roots, c.taskDependencies)
+}
+
+// printTreeOf renders a tree like printTree, with dependencies returning
+// the dependencies of each task, such as those within a subgraph.
+func (c *CLI) printTreeOf(w io.Writer, roots []string, dependencies func(key string) ([]string, error)) error {
+	expanded := make(map[string]bool)
+	for i, root := range roots {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, c.treeLabel(root, ""))
+		if err := c.printTreeDeps(w, root, "", dependencies, expanded, map[string]bool{root: true}); err != nil {
+			return err
+		}
+		expanded[root] = true
+	}
+	return nil
+}
+
+// taskDependencies returns the direct dependencies of a workspace:task key.
+func (c *CLI) taskDependencies(key string) ([]string, error) {
+	workspaceName, taskName, _ := strings.Cut(key, ":")
+	return c.workspace.Dependencies(workspaceName, taskName)
+}
+
+func (c *CLI) printTreeDeps(w io.Writer, key, indent string, dependencies func(string) ([]string, error), expanded, path map[string]bool) error {
+	workspaceName, _, _ := strings.Cut(key, ":")
+	deps, err := dependencies(key)
+	if err != nil {
+		return err
+	}
+
+	for i, dep := range deps {
+		branch, childIndent := "├── ", indent+"│   "
+		if i == len(deps)-1 {
+			branch, childIndent = "└── ", indent+"    "
+		}
+
+		depDeps, _ := dependencies(dep)
+		label := c.treeLabel(dep, workspaceName)
+		switch {
+		case path[dep]:
+			fmt.Fprintf(w, "%s%s%s (cycle)\n", indent, branch, label)
+			continue
+		case expanded[dep] && len(depDeps) > 0:
+			fmt.Fprintf(w, "%s%s%s (see above)\n", indent, branch, label)
+			continue
+		}
+
+		fmt.Fprintf(w, "%s%s%s\n", indent, branch, label)
+		path[dep] = true
+		if err := c.printTreeDeps(w, dep, childIndent, dependencies, expanded, path); err != nil {
+			return err
+		}
+		delete(path, dep)
+		expanded[dep] = true
+	}
+	return nil
+}
+
+// treeLabel names a task in the tree, without its workspace when it is in
+// parentWorkspace, followed by its caching flags.
+func (c *CLI) treeLabel(key, parentWorkspace string) string {
+	workspaceName, taskName, _ := strings.Cut(key, ":")
+	label := key
+	if workspaceName == parentWorkspace {
+		label = taskName
+	}
+	if flags := c.treeFlags(workspaceName, taskName); flags != "" {
+		label += " [" + flags + "]"
+	}
+	return label
+}
+
+// treeFlags describes whether a task is cached: compound tasks have no
+// command, tasks with cache enabled show whether their cache entry is
+// current.
+func (c *CLI) treeFlags(workspaceName, taskName string) string {
+	task, _ := c.config.GetTask(workspaceName, taskName)
+	switch {
+	case len(task.Command) == 0:
+		return "compound"
+	case !task.Cache:
+		return ""
+	}
+
+	execution, err := c.workspace.ResolveTaskExecution(workspaceName, taskName)
+	if err != nil {
+		return "cache"
+	}
+	previous, err := c.cache.Get(workspaceName + ":" + taskName)
+	if err != nil || previous == nil {
+		return "cache: empty"
+	}
+	shouldRun, err := c.tracker.ShouldRunTask(execution, previous)
+	switch {
+	case err != nil:
+		return "cache"
+	case shouldRun:
+		return "cache: stale"
+	}
+	return "cache: cached"
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/workspace"
+)
+
+func TestPrintTree(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"lib": {
+				Path: tempDir,
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"true"}, Cache: true},
+				},
+			},
+			"web": {
+				Path: tempDir,
+				Tasks: map[string]config.Task{
+					"build":  {Command: []string{"true"}, DependsOn: []string{"lib:build"}},
+					"test":   {Command: []string{"true"}, DependsOn: []string{"build"}},
+					"deploy": {Command: []string{"true"}, DependsOn: []string{"build", "test"}},
+					"ci":     {DependsOn: []string{"deploy"}},
+				},
+			},
+		},
+	}
+	c := &CLI{
+		config:    cfg,
+		workspace: workspace.NewManager(cfg, tempDir),
+		tracker:   deps.NewTracker(tempDir),
+		cache:     cache.NewManager(filepath.Join(tempDir, ".doctrus", "cache")),
+		basePath:  tempDir,
+	}
+
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{
+			spec: "",
+			want: `web:ci [compound]
+└── deploy
+    ├── build
+    │   └── lib:build [cache: empty]
+    └── test
+        └── build (see above)
+`,
+		},
+		{
+			spec: "web:test",
+			want: `web:test
+└── build
+    └── lib:build [cache: empty]
+`,
+		},
+		{
+			spec: "build",
+			want: `lib:build [cache: empty]
+
+web:build
+└── lib:build [cache: empty]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			roots, err := c.treeRoots(tt.spec)
+			if err != nil {
+				t.Fatalf("treeRoots(%q) error = %v", tt.spec, err)
+			}
+			var out bytes.Buffer
+			if err := c.printTree(&out, roots); err != nil {
+				t.Fatalf("printTree() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Fatalf("printTree() =\n%s\nwant\n%s", out.String(), tt.want)
+			}
+		})
+	}
+
+	if _, err := c.treeRoots("web:missing"); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("treeRoots() of a missing task error = %v", err)
+	}
+}
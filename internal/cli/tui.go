@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"doctrus/internal/events"
+	"doctrus/internal/logging"
+	"doctrus/internal/ui"
+)
+
+// Output styles of doctrus run
+const (
+	// outputStyleStream logs every task as it runs, prefixing the lines of
+	// tasks running in parallel with their name
+	outputStyleStream = "stream"
+	// outputStyleTUI draws a line per running task and collapses it into a
+	// line of its outcome once the task finished
+	outputStyleTUI = "tui"
+)
+
+// tuiView prints a line for every task that finished while the status line
+// shows the tasks still running, and totals them at the end of the run.
+// Everything else doctrus logs below a warning only goes to the log file.
+// With --quiet only failed tasks get a line, and only errors are logged.
+type tuiView struct {
+	cli     *CLI
+	started time.Time
+	quiet   bool
+
+	mu        sync.Mutex
+	succeeded int
+	cached    int
+	failed    int
+}
+
+// useOutputStyle switches the CLI to the given --output-style. The TUI needs
+// a live terminal, so doctrus streams its output elsewhere.
+func (c *CLI) useOutputStyle(style string) error {
+	switch style {
+	case "", outputStyleStream:
+		return nil
+	case outputStyleTUI:
+	default:
+		return fmt.Errorf("invalid output style %q (expected stream or tui)", style)
+	}
+	if !c.term.Live || verbose {
+		return nil
+	}
+
+	c.status.Expand()
+	c.tui = &tuiView{cli: c, started: time.Now()}
+	c.events.Subscribe(c.tui)
+	if c.log.Level() < logging.LevelWarn {
+		c.log.SetOutputLevel(logging.LevelWarn)
+	}
+	return nil
+}
+
+// useQuiet switches the CLI to --quiet, printing the failed tasks with their
+// output and a summary of the run, and nothing about the tasks that
+// succeeded.
+func (c *CLI) useQuiet() error {
+	if verbose {
+		return fmt.Errorf("--quiet and --verbose can't be used together")
+	}
+	if c.tui == nil {
+		c.tui = &tuiView{cli: c, started: time.Now()}
+		c.events.Subscribe(c.tui)
+	}
+	c.tui.quiet = true
+	if c.log.Level() < logging.LevelError {
+		c.log.SetOutputLevel(logging.LevelError)
+	}
+	return nil
+}
+
+func (v *tuiView) Handle(e events.Event) {
+	if e.Type != events.TaskFinished {
+		return
+	}
+	c := v.cli
+	key := e.Key()
+
+	v.mu.Lock()
+	var line string
+	switch e.Status {
+	case events.StatusSuccess:
+		v.succeeded++
+		line = c.ui.Status(ui.KindSuccess, fmt.Sprintf("%s %v", key, e.Duration.Round(time.Millisecond)))
+	case events.StatusCached:
+		v.cached++
+		line = c.ui.Status(ui.KindCached, key+" cached")
+	case events.StatusFailed:
+		v.failed++
+		text := fmt.Sprintf("%s failed in %v", key, e.Duration.Round(time.Millisecond))
+		if e.ExitCode != 0 {
+			text = fmt.Sprintf("%s exited with %d in %v", key, e.ExitCode, e.Duration.Round(time.Millisecond))
+		}
+		line = c.ui.Status(ui.KindFailure, text)
+	default:
+		v.mu.Unlock()
+		return
+	}
+	v.mu.Unlock()
+
+	if v.quiet && e.Status != events.StatusFailed {
+		return
+	}
+	c.printOutput("%s\n", c.term.Fit(line))
+}
+
+// printSummary prints how many tasks ran and how they ended. A nil tuiView
+// prints nothing.
+func (v *tuiView) printSummary() {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	total := v.succeeded + v.cached + v.failed
+	if total == 0 {
+		return
+	}
+	parts := []string{fmt.Sprintf("%d succeeded", v.succeeded)}
+	if v.cached > 0 {
+		parts = append(parts, fmt.Sprintf("%d cached", v.cached))
+	}
+	if v.failed > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed", v.failed))
+	}
+	kind := ui.KindSuccess
+	if v.failed > 0 {
+		kind = ui.KindFailure
+	}
+	text := fmt.Sprintf("%d %s: %s in %v", total, plural(total, "task", "tasks"), strings.Join(parts, ", "), time.Since(v.started).Round(time.Millisecond))
+	v.cli.printOutput("%s\n", v.cli.ui.Status(kind, text))
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"doctrus/internal/events"
+	"doctrus/internal/logging"
+)
+
+func TestTUIViewCollapsesFinishedTasks(t *testing.T) {
+	var out bytes.Buffer
+	c := &CLI{stdout: &out}
+	view := &tuiView{cli: c, started: time.Now()}
+
+	bus := events.NewBus()
+	bus.Subscribe(view)
+	for _, e := range []events.Event{
+		{Type: events.TaskStarted, Workspace: "lib", Task: "build"},
+		{Type: events.OutputChunk, Workspace: "lib", Task: "build", Stream: "stdout", Data: "ok\n"},
+		{Type: events.TaskFinished, Workspace: "lib", Task: "build", Status: events.StatusSuccess, Duration: 1200 * time.Millisecond},
+		{Type: events.TaskFinished, Workspace: "lib", Task: "all", Status: events.StatusCompound},
+		{Type: events.TaskFinished, Workspace: "web", Task: "lint", Status: events.StatusCached},
+		{Type: events.TaskFinished, Workspace: "web", Task: "test", Status: events.StatusFailed, ExitCode: 2, Duration: 3 * time.Second},
+	} {
+		bus.Publish(e)
+	}
+
+	want := "✓ lib:build 1.2s\n" +
+		"✓ web:lint cached\n" +
+		"✗ web:test exited with 2 in 3s\n"
+	if got := out.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+
+	out.Reset()
+	view.printSummary()
+	if got := out.String(); !strings.HasPrefix(got, "✗ 3 tasks: 1 succeeded, 1 cached, 1 failed in ") {
+		t.Fatalf("summary = %q", got)
+	}
+}
+
+func TestUseOutputStyle(t *testing.T) {
+	c := &CLI{}
+	if err := c.useOutputStyle("fancy"); err == nil || !strings.Contains(err.Error(), "invalid output style") {
+		t.Fatalf("useOutputStyle(fancy) error = %v, want invalid output style", err)
+	}
+	// Without a live terminal the output is streamed
+	if err := c.useOutputStyle(outputStyleTUI); err != nil {
+		t.Fatalf("useOutputStyle(tui) error = %v", err)
+	}
+	if c.tui != nil {
+		t.Fatalf("expected no TUI without a live terminal")
+	}
+}
+
+func TestQuietPrintsOnlyFailures(t *testing.T) {
+	var out bytes.Buffer
+	c := &CLI{stdout: &out, events: events.NewBus()}
+	c.log = logging.New(&out, logging.LevelInfo, &c.outputMu)
+	if err := c.useQuiet(); err != nil {
+		t.Fatalf("useQuiet() error = %v", err)
+	}
+
+	c.log.Infof("Running lib:build\n")
+	for _, e := range []events.Event{
+		{Type: events.TaskFinished, Workspace: "lib", Task: "build", Status: events.StatusSuccess, Duration: time.Second},
+		{Type: events.TaskFinished, Workspace: "web", Task: "lint", Status: events.StatusCached},
+		{Type: events.TaskFinished, Workspace: "web", Task: "test", Status: events.StatusFailed, ExitCode: 2, Duration: 3 * time.Second},
+	} {
+		c.events.Publish(e)
+	}
+	c.log.Errorf("Failed with exit code 2\n")
+
+	want := "✗ web:test exited with 2 in 3s\n" +
+		"Failed with exit code 2\n"
+	if got := out.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+
+	out.Reset()
+	c.tui.printSummary()
+	if got := out.String(); !strings.HasPrefix(got, "✗ 3 tasks: 1 succeeded, 1 cached, 1 failed in ") {
+		t.Fatalf("summary = %q", got)
+	}
+}
+
+func TestUseQuietRejectsVerbose(t *testing.T) {
+	verbose = true
+	defer func() { verbose = false }()
+	c := &CLI{events: events.NewBus()}
+	if err := c.useQuiet(); err == nil || !strings.Contains(err.Error(), "--verbose") {
+		t.Fatalf("useQuiet() error = %v, want a conflict with --verbose", err)
+	}
+}
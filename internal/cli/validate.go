@@ -2,107 +2,202 @@ package cli
 
 import (
 	"fmt"
-	"strings"
+	"os"
 
 	"github.com/spf13/cobra"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
 )
 
 func newValidateCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "validate",
 		Short: "Validate configuration",
-		Long:  "Validate the doctrus configuration file and workspace setup",
-		RunE:  validateConfig,
+		Long: `Validate the doctrus configuration file and workspace setup. Fields
+doctrus.yml doesn't define and values of the wrong type are reported with
+their line, checked against the schema printed by doctrus schema.`,
+		RunE: validateConfig,
 	}
 
+	cmd.Flags().StringVar(&outputFormat, "output", outputText, "Output format: text or json")
+
 	return cmd
 }
 
+// validation is what doctrus validate found, as --output json prints it.
+type validation struct {
+	Valid             bool                 `json:"valid"`
+	Error             string               `json:"error,omitempty"`
+	Problems          []config.Diagnostic  `json:"problems,omitempty"`
+	Workspaces        []validatedWorkspace `json:"workspaces"`
+	DockerCompose     bool                 `json:"docker_compose"`
+	RunningContainers int                  `json:"running_containers"`
+	ContainersError   string               `json:"containers_error,omitempty"`
+	Cache             *validatedCache      `json:"cache,omitempty"`
+
+	err error
+}
+
+// validatedWorkspace is a workspace with the problems found in its tasks.
+type validatedWorkspace struct {
+	Name      string   `json:"name"`
+	Path      string   `json:"path"`
+	Container string   `json:"container,omitempty"`
+	Tasks     int      `json:"tasks"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+// validatedCache is the cache directory and how many entries it holds.
+type validatedCache struct {
+	Dir     string `json:"dir"`
+	Entries int    `json:"entries"`
+}
+
 func validateConfig(cmd *cobra.Command, args []string) error {
-	cli, err := newCLI()
+	if err := parseOutputFormat(outputFormat); err != nil {
+		return err
+	}
+	path, dir, err := config.Locate(configPath)
 	if err != nil {
 		return err
 	}
+	cli, loadErr := newCLI()
+	var included []string
+	if loadErr == nil {
+		included = cli.config.IncludedFiles()
+	}
+	diagnostics, err := checkSchema(path, dir, included)
+	if err != nil {
+		return err
+	}
+	if len(diagnostics) > 0 {
+		if outputFormat == outputJSON {
+			result := validation{Error: "configuration does not match the schema", Problems: diagnostics, Workspaces: []validatedWorkspace{}}
+			if err := writeJSON(os.Stdout, result); err != nil {
+				return err
+			}
+		}
+		return fmt.Errorf("configuration does not match the schema:\n%w", diagnostics)
+	}
+	if loadErr != nil {
+		return loadErr
+	}
+
+	result := cli.validate()
+	if outputFormat == outputJSON {
+		if err := writeJSON(os.Stdout, result); err != nil {
+			return err
+		}
+	} else if result.Valid {
+		printValidation(result)
+	}
+	if !result.Valid {
+		return fmt.Errorf("workspace validation failed: %w", result.err)
+	}
+	return nil
+}
 
+// validate checks the workspaces, their dependencies and the tools the
+// project needs.
+func (c *CLI) validate() validation {
+	result := validation{Workspaces: []validatedWorkspace{}}
+	if err := c.workspace.ValidateWorkspaces(); err != nil {
+		result.Error = err.Error()
+		result.err = err
+		return result
+	}
+	result.Valid = true
+	result.DockerCompose = c.executor.IsDockerComposeAvailable()
+
+	for _, workspaceName := range c.workspace.GetWorkspaces() {
+		ws, _ := c.config.GetWorkspace(workspaceName)
+		tasks, _ := c.workspace.GetTasks(workspaceName)
+		validated := validatedWorkspace{
+			Name:      workspaceName,
+			Path:      ws.Path,
+			Container: ws.Container,
+			Tasks:     len(tasks),
+		}
+		for _, taskName := range tasks {
+			task, _ := c.config.GetTask(workspaceName, taskName)
+			for _, dep := range task.DependsOn {
+				if err := c.validateDependency(workspaceName, dep); err != nil {
+					validated.Warnings = append(validated.Warnings, fmt.Sprintf("%s dependency issue: %v", taskName, err))
+				}
+			}
+		}
+		result.Workspaces = append(result.Workspaces, validated)
+	}
+
+	if result.DockerCompose {
+		containers, err := c.executor.GetRunningContainers()
+		if err != nil {
+			result.ContainersError = err.Error()
+		}
+		result.RunningContainers = len(containers)
+	}
+
+	if stats, err := c.cache.GetStats(); err == nil {
+		dir, _ := stats["cache_dir"].(string)
+		entries, _ := stats["total_entries"].(int)
+		result.Cache = &validatedCache{Dir: dir, Entries: entries}
+	}
+	return result
+}
+
+// printValidation prints the result of a successful validation.
+func printValidation(result validation) {
 	fmt.Println("✓ Configuration file is valid")
+	fmt.Printf("✓ Found %d workspace(s)\n", len(result.Workspaces))
 
-	workspaces := cli.workspace.GetWorkspaces()
-	fmt.Printf("✓ Found %d workspace(s)\n", len(workspaces))
-
-	for _, workspaceName := range workspaces {
-		workspace, _ := cli.config.GetWorkspace(workspaceName)
-		fmt.Printf("  📁 %s (%s)", workspaceName, workspace.Path)
-		
-		if workspace.Container != "" {
-			fmt.Printf(" [%s]", workspace.Container)
-			
-			if !cli.executor.IsDockerComposeAvailable() {
+	for _, ws := range result.Workspaces {
+		fmt.Printf("  📁 %s (%s)", ws.Name, ws.Path)
+		if ws.Container != "" {
+			fmt.Printf(" [%s]", ws.Container)
+			if !result.DockerCompose {
 				fmt.Printf(" ⚠️  Docker Compose not available")
 			}
 		}
 		fmt.Println()
 
-		tasks, _ := cli.workspace.GetTasks(workspaceName)
-		fmt.Printf("    Tasks: %d\n", len(tasks))
-
-		for _, taskName := range tasks {
-			task, _ := cli.config.GetTask(workspaceName, taskName)
-			if len(task.DependsOn) > 0 {
-				for _, dep := range task.DependsOn {
-					if err := cli.validateDependency(workspaceName, dep); err != nil {
-						fmt.Printf("    ⚠️  %s dependency issue: %v\n", taskName, err)
-					}
-				}
-			}
+		fmt.Printf("    Tasks: %d\n", ws.Tasks)
+		for _, warning := range ws.Warnings {
+			fmt.Printf("    ⚠️  %s\n", warning)
 		}
 	}
 
-	if cli.executor.IsDockerComposeAvailable() {
+	if result.DockerCompose {
 		fmt.Println("✓ Docker Compose is available")
-		
-		containers, err := cli.executor.GetRunningContainers()
-		if err != nil {
-			fmt.Printf("⚠️  Could not check running containers: %v\n", err)
-		} else if len(containers) > 0 {
-			fmt.Printf("✓ Found %d running container(s)\n", len(containers))
+		if result.ContainersError != "" {
+			fmt.Printf("⚠️  Could not check running containers: %s\n", result.ContainersError)
+		} else if result.RunningContainers > 0 {
+			fmt.Printf("✓ Found %d running container(s)\n", result.RunningContainers)
 		}
 	} else {
 		fmt.Println("⚠️  Docker Compose not available (tasks with containers will fail)")
 	}
 
-	stats, err := cli.cache.GetStats()
-	if err == nil {
-		fmt.Printf("✓ Cache directory: %v (%v entries)\n", stats["cache_dir"], stats["total_entries"])
+	if result.Cache != nil {
+		fmt.Printf("✓ Cache directory: %s (%d entries)\n", result.Cache.Dir, result.Cache.Entries)
 	}
 
 	fmt.Println("\n✅ Validation completed successfully!")
-	
-	return nil
 }
 
 func (c *CLI) validateDependency(currentWorkspace, dependency string) error {
-	parts := splitDependency(dependency)
-	workspaceName := parts[0]
-	taskName := parts[1]
-	
-	if workspaceName == "" {
-		workspaceName = currentWorkspace
+	workspaceName, taskName, err := config.SplitDependency(currentWorkspace, dependency)
+	if err != nil {
+		return err
 	}
 
 	if _, exists := c.config.GetWorkspace(workspaceName); !exists {
-		return fmt.Errorf("workspace %s not found", workspaceName)
+		return &workspace.WorkspaceNotFoundError{Workspace: workspaceName}
 	}
 
 	if _, exists := c.config.GetTask(workspaceName, taskName); !exists {
-		return fmt.Errorf("task %s not found in workspace %s", taskName, workspaceName)
+		return &workspace.TaskNotFoundError{Workspace: workspaceName, Task: taskName}
 	}
 
 	return nil
 }
-
-func splitDependency(dependency string) [2]string {
-	if idx := strings.Index(dependency, ":"); idx != -1 {
-		return [2]string{dependency[:idx], dependency[idx+1:]}
-	}
-	return [2]string{"", dependency}
-}
\ No newline at end of file
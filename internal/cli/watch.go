@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"doctrus/internal/docker"
+	"doctrus/internal/history"
+	"doctrus/internal/ui"
+	"doctrus/internal/watch"
+)
+
+var watchDebounce time.Duration
+
+func newWatchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch [workspace:]task...",
+		Short: "Run tasks again whenever their inputs change",
+		Long: `Run tasks, then watch the inputs of the tasks and their dependencies and run
+them again whenever input files are added, modified or deleted. Only the
+tasks whose graph contains a changed input run again; within it, the cache
+skips dependencies whose inputs did not change. A failing task does not stop
+watching. Changes to doctrus.yml need a restart of watch.
+
+Examples:
+  doctrus watch frontend:build
+  doctrus watch test --debounce 1s     # Run 'test' in every workspace that has it`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runWatch,
+	}
+
+	cmd.Flags().DurationVar(&watchDebounce, "debounce", 200*time.Millisecond, "How long inputs must stay unchanged before running again")
+	cmd.Flags().StringVarP(&parallel, "parallel", "p", "", "Maximum number of tasks to run at once (0 or auto for one per CPU, auto-N to leave N CPUs free)")
+	cmd.Flags().BoolVar(&noDeps, "no-deps", false, "Run only the named tasks, assuming their dependencies already ran")
+
+	return cmd
+}
+
+// watchTarget is a task watch runs and the input patterns of its graph.
+type watchTarget struct {
+	spec     dependencySpec
+	patterns []string
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	cli, err := newScopedCLI(args)
+	if err != nil {
+		return err
+	}
+	defer cli.cleanup()
+
+	targets, err := cli.resolveTargets(args)
+	if err != nil {
+		return err
+	}
+	if err := cli.checkInternalTargets(targets); err != nil {
+		return err
+	}
+	watched, err := cli.watchTargets(targets)
+	if err != nil {
+		return err
+	}
+	var patterns []string
+	for _, target := range watched {
+		patterns = append(patterns, target.patterns...)
+	}
+	if len(patterns) == 0 {
+		return fmt.Errorf("nothing to watch: %s and %s dependencies have no inputs", strings.Join(args, ", "), plural(len(args), "its", "their"))
+	}
+	watcher, err := watch.New(patterns, watch.Options{Debounce: watchDebounce})
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	ctx, cancel := docker.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := cli.ensurePreRunCommands(ctx); err != nil {
+		return err
+	}
+
+	pending := targets
+	for {
+		cli.runWatched(ctx, args, pending)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// Ignore what the tasks wrote themselves, such as outputs matching
+		// their inputs
+		if err := watcher.Reset(); err != nil {
+			return err
+		}
+		cli.log.Infof("%s\n", cli.ui.Status(ui.KindInfo, fmt.Sprintf("Watching %d %s for changes (Ctrl-C to stop)", watcher.Files(), plural(watcher.Files(), "file", "files"))))
+
+		changed, err := watcher.Wait(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		cli.log.Infof("%s\n", cli.ui.Status(ui.KindInfo, "Changed: "+cli.summarizeChanges(changed)))
+		pending = affectedTargets(watched, changed)
+	}
+}
+
+// watchTargets returns the input patterns of the graph of every target, as
+// absolute globs.
+func (c *CLI) watchTargets(targets []dependencySpec) ([]watchTarget, error) {
+	watched := make([]watchTarget, 0, len(targets))
+	for _, target := range targets {
+		executions, err := c.resolveExecutions([]dependencySpec{target})
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		var patterns []string
+		for _, execution := range executions {
+			for _, input := range execution.Task.Inputs {
+				pattern := input
+				if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join(execution.AbsPath, pattern)
+				}
+				if !seen[pattern] {
+					seen[pattern] = true
+					patterns = append(patterns, pattern)
+				}
+			}
+		}
+		watched = append(watched, watchTarget{spec: target, patterns: patterns})
+	}
+	return watched, nil
+}
+
+// affectedTargets returns the targets whose graph has an input among the
+// changed files.
+func affectedTargets(watched []watchTarget, changed []string) []dependencySpec {
+	var targets []dependencySpec
+	for _, target := range watched {
+		for _, path := range changed {
+			if watch.Match(target.patterns, path) {
+				targets = append(targets, target.spec)
+				break
+			}
+		}
+	}
+	return targets
+}
+
+// runWatched runs targets once, recording the run in the history like doctrus
+// run. Failures are reported and otherwise ignored, so watching goes on.
+func (c *CLI) runWatched(ctx context.Context, args []string, targets []dependencySpec) {
+	if !dryRun {
+		c.history = history.NewRecorder(args)
+	}
+	runner := newTaskRunner(c)
+	var err error
+	if runner.Slots, err = c.parallelSlots(); err == nil {
+		err = c.runTargets(ctx, runner, targets)
+	}
+	c.saveHistory(err)
+	c.history = nil
+
+	if err != nil && ctx.Err() == nil {
+		c.log.Errorf("%s\n", c.ui.Status(ui.KindFailure, err.Error()))
+	}
+}
+
+// summarizeChanges lists changed files relative to the project, eliding
+// all but the first few.
+func (c *CLI) summarizeChanges(changed []string) string {
+	const shown = 3
+	names := make([]string, 0, shown)
+	for i, path := range changed {
+		if i == shown {
+			break
+		}
+		if rel, err := filepath.Rel(c.basePath, path); err == nil {
+			path = rel
+		}
+		names = append(names, filepath.ToSlash(path))
+	}
+	summary := strings.Join(names, ", ")
+	if len(changed) > shown {
+		summary += fmt.Sprintf(" and %d more", len(changed)-shown)
+	}
+	return summary
+}
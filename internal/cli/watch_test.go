@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+func TestWatchTargets(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"lib": {
+				Path: "lib",
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"true"}, Inputs: []string{"src/**/*.go"}},
+				},
+			},
+			"web": {
+				Path: "web",
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"true"}, Inputs: []string{"src/**/*.ts"}, DependsOn: []string{"lib:build"}},
+					"lint":  {Command: []string{"true"}, Inputs: []string{"src/**/*.ts"}},
+				},
+			},
+		},
+	}
+	c := &CLI{config: cfg, workspace: workspace.NewManager(cfg, tempDir), basePath: tempDir}
+
+	watched, err := c.watchTargets([]dependencySpec{{workspace: "web", task: "build"}, {workspace: "web", task: "lint"}})
+	if err != nil {
+		t.Fatalf("watchTargets() error = %v", err)
+	}
+	wantBuild := []string{
+		filepath.Join(tempDir, "lib", "src", "**", "*.go"),
+		filepath.Join(tempDir, "web", "src", "**", "*.ts"),
+	}
+	if len(watched) != 2 || !reflect.DeepEqual(watched[0].patterns, wantBuild) {
+		t.Fatalf("watchTargets() = %+v, want web:build watching %v", watched, wantBuild)
+	}
+
+	tests := []struct {
+		name    string
+		changed []string
+		want    []dependencySpec
+	}{
+		{
+			name:    "dependency input",
+			changed: []string{filepath.Join(tempDir, "lib", "src", "lib.go")},
+			want:    []dependencySpec{{workspace: "web", task: "build"}},
+		},
+		{
+			name:    "shared input",
+			changed: []string{filepath.Join(tempDir, "web", "src", "app.ts")},
+			want:    []dependencySpec{{workspace: "web", task: "build"}, {workspace: "web", task: "lint"}},
+		},
+		{
+			name:    "unrelated file",
+			changed: []string{filepath.Join(tempDir, "web", "README.md")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := affectedTargets(watched, tt.changed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("affectedTargets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeChanges(t *testing.T) {
+	c := &CLI{basePath: "/project"}
+	changed := []string{"/project/a.go", "/project/b.go", "/project/src/c.go", "/project/d.go", "/project/e.go"}
+	if got, want := c.summarizeChanges(changed), "a.go, b.go, src/c.go and 2 more"; got != want {
+		t.Errorf("summarizeChanges() = %q, want %q", got, want)
+	}
+	if got, want := c.summarizeChanges(changed[:1]), "a.go"; got != want {
+		t.Errorf("summarizeChanges() = %q, want %q", got, want)
+	}
+}
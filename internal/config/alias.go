@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TaskName returns the name of the task called name in a workspace, which
+// is name itself unless it is one of the task's aliases.
+func (c *Config) TaskName(workspaceName, name string) string {
+	workspace, exists := c.Workspaces[workspaceName]
+	if !exists {
+		return name
+	}
+	if _, exists := workspace.Tasks[name]; exists {
+		return name
+	}
+	for _, taskName := range sortedKeys(workspace.Tasks) {
+		if slices.Contains(workspace.Tasks[taskName].Aliases, name) {
+			return taskName
+		}
+	}
+	return name
+}
+
+// aliasProblems checks that the aliases of the tasks of a workspace are
+// unique within it, among both task names and other aliases.
+func aliasProblems(name string, workspace Workspace, add func(path, format string, args ...any)) {
+	owners := make(map[string]string)
+	for _, taskName := range sortedKeys(workspace.Tasks) {
+		taskPath := joinPath("workspaces", name, "tasks", taskName)
+		prefix := fmt.Sprintf("workspace %s, task %s", name, taskName)
+		for i, alias := range workspace.Tasks[taskName].Aliases {
+			aliasPath := fmt.Sprintf("%s[%d]", joinPath(taskPath, "aliases"), i)
+			_, isTask := workspace.Tasks[alias]
+			switch {
+			case alias == "":
+				add(aliasPath, "%s: aliases[%d] is empty", prefix, i)
+			case IsTaskPattern(alias):
+				add(aliasPath, "%s: invalid alias %q (aliases can't hold pattern characters such as * or ?)", prefix, alias)
+			case isTask:
+				add(aliasPath, "%s: alias %s is the name of task %s", prefix, alias, alias)
+			case owners[alias] == taskName:
+				add(aliasPath, "%s: alias %s is listed twice", prefix, alias)
+			case owners[alias] != "":
+				add(aliasPath, "%s: alias %s is already an alias of task %s", prefix, alias, owners[alias])
+			default:
+				owners[alias] = taskName
+			}
+		}
+	}
+}
+
+// taskNode returns the name and node of the task called name, by its name or
+// one of its aliases, in the tasks mapping of a workspace, or nil.
+func taskNode(tasks *yaml.Node, name string) (string, *yaml.Node) {
+	if tasks == nil || tasks.Kind != yaml.MappingNode {
+		return "", nil
+	}
+	if task := mappingValue(tasks, name); task != nil {
+		return name, task
+	}
+	for i := 0; i+1 < len(tasks.Content); i += 2 {
+		aliases := mappingValue(tasks.Content[i+1], "aliases")
+		if aliases == nil || aliases.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, alias := range aliases.Content {
+			if alias.Value == name {
+				return tasks.Content[i].Value, tasks.Content[i+1]
+			}
+		}
+	}
+	return "", nil
+}
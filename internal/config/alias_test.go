@@ -0,0 +1,73 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigTaskAliases(t *testing.T) {
+	content := `version: "1.0"
+workspaces:
+  web:
+    path: web
+    tasks:
+      test:
+        command: ["npm", "test"]
+        aliases: [t, check]
+      build:
+        command: ["npm", "run", "build"]
+`
+	cfg, err := Parse("doctrus.yml", []byte(content))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "test", want: "test"},
+		{name: "t", want: "test"},
+		{name: "check", want: "test"},
+		{name: "build", want: "build"},
+		{name: "deploy", want: "deploy"},
+	}
+	for _, tt := range tests {
+		if got := cfg.TaskName("web", tt.name); got != tt.want {
+			t.Errorf("TaskName(web, %s) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	task, exists := cfg.GetTask("web", "check")
+	if !exists || !reflect.DeepEqual(task.Command, []string{"npm", "test"}) {
+		t.Errorf("GetTask(web, check) = %v, %v; want the test task", task, exists)
+	}
+	if _, exists := cfg.GetTask("web", "deploy"); exists {
+		t.Error("GetTask(web, deploy) should not find a task")
+	}
+}
+
+func TestParseAliasProblems(t *testing.T) {
+	tests := []struct {
+		name    string
+		aliases string
+		wantErr string
+	}{
+		{name: "task name", aliases: "[build]", wantErr: "task test: alias build is the name of task build"},
+		{name: "alias of another task", aliases: "[b]", wantErr: "task test: alias b is already an alias of task build"},
+		{name: "listed twice", aliases: "[t, t]", wantErr: "alias t is listed twice"},
+		{name: "empty", aliases: `[""]`, wantErr: "aliases[0] is empty"},
+		{name: "pattern", aliases: `["t*"]`, wantErr: `invalid alias "t*"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "version: \"1.0\"\nworkspaces:\n  app:\n    path: .\n    tasks:\n      build:\n        command: [\"make\"]\n        aliases: [b]\n      test:\n        command: [\"make\", \"test\"]\n        aliases: " + tt.aliases + "\n"
+			_, err := Parse("doctrus.yml", []byte(content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,86 +1,283 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-
-	"gopkg.in/yaml.v3"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Version    string               `yaml:"version"`
-	Workspaces map[string]Workspace `yaml:"workspaces"`
-	Docker     DockerConfig         `yaml:"docker,omitempty"`
-	Pre        []PreCommand         `yaml:"pre,omitempty"`
+	Version     string               `yaml:"version" json:"version"`
+	Include     []string             `yaml:"include,omitempty" json:"include,omitempty"`
+	Templates   map[string]Task      `yaml:"templates,omitempty" json:"templates,omitempty"`
+	Profiles    map[string]Profile   `yaml:"profiles,omitempty" json:"profiles,omitempty"`
+	Workspaces  map[string]Workspace `yaml:"workspaces" json:"workspaces"`
+	Docker      DockerConfig         `yaml:"docker,omitempty" json:"docker,omitempty"`
+	Pre         []PreCommand         `yaml:"pre,omitempty" json:"pre,omitempty"`
+	Plugins     map[string]Plugin    `yaml:"plugins,omitempty" json:"plugins,omitempty"`
+	Providers   []Provider           `yaml:"providers,omitempty" json:"providers,omitempty"`
+	Env         map[string]string    `yaml:"env,omitempty" json:"env,omitempty"`
+	EnvFile     []string             `yaml:"env_file,omitempty" json:"env_file,omitempty"`
+	EnvMerge    *EnvMerge            `yaml:"env_merge,omitempty" json:"env_merge,omitempty"`
+	Lock        string               `yaml:"lock,omitempty" json:"lock,omitempty"`
+	Parallel    string               `yaml:"parallel,omitempty" json:"parallel,omitempty"`
+	Retention   *Retention           `yaml:"retention,omitempty" json:"retention,omitempty"`
+	Hooks       map[string][]string  `yaml:"hooks,omitempty" json:"hooks,omitempty"`
+	Cache       *CacheConfig         `yaml:"cache,omitempty" json:"cache,omitempty"`
+	PathMapping []PathMapping        `yaml:"path_mapping,omitempty" json:"path_mapping,omitempty"`
+	Wrapper     []string             `yaml:"wrapper,omitempty" json:"wrapper,omitempty"`
+	Imports     []WorkspaceImport    `yaml:"import_workspaces,omitempty" json:"import_workspaces,omitempty"`
+	// RemoteExecution lists the agents tasks with executor remote run on
+	RemoteExecution *RemoteExecution `yaml:"remote_execution,omitempty" json:"remote_execution,omitempty"`
+
+	// imported holds the names of workspaces added by import_workspaces
+	imported map[string]bool
+	// includes holds the files merged through include
+	includes *includer
+}
+
+// CacheConfig holds project-wide cache settings. With Provenance set, every
+// cache write also records an in-toto provenance attestation of the task's
+// outputs. Remote shares cached outputs through a cache server. MaxSize,
+// such as 2GB, caps the disk space of the local cache, evicting the least
+// recently used entries after every run.
+type CacheConfig struct {
+	Provenance bool         `yaml:"provenance,omitempty" json:"provenance,omitempty"`
+	Remote     *RemoteCache `yaml:"remote,omitempty" json:"remote,omitempty"`
+	MaxSize    string       `yaml:"max_size,omitempty" json:"max_size,omitempty"`
+}
+
+// RemoteExecution configures the doctrus agents that run tasks with the
+// remote executor: the one at URL and those listed in Agents. TokenEnv names
+// the variable holding the agents' bearer token, DOCTRUS_AGENT_TOKEN by
+// default.
+type RemoteExecution struct {
+	URL      string        `yaml:"url,omitempty" json:"url,omitempty"`
+	Agents   []RemoteAgent `yaml:"agents,omitempty" json:"agents,omitempty"`
+	TokenEnv string        `yaml:"token_env,omitempty" json:"token_env,omitempty"`
+}
+
+// RemoteAgent is an agent of a distributed run. Jobs caps how many tasks it
+// is sent at once, without a limit when zero.
+type RemoteAgent struct {
+	URL  string `yaml:"url" json:"url"`
+	Jobs int    `yaml:"jobs,omitempty" json:"jobs,omitempty"`
+}
+
+// AgentList returns the agent at URL, if set, followed by Agents.
+func (r *RemoteExecution) AgentList() []RemoteAgent {
+	if r == nil {
+		return nil
+	}
+	var agents []RemoteAgent
+	if r.URL != "" {
+		agents = append(agents, RemoteAgent{URL: r.URL})
+	}
+	return append(agents, r.Agents...)
+}
+
+// Remote cache protocols accepted by cache.remote.type
+const (
+	// RemoteTurborepo is the Turborepo remote cache API (/v8/artifacts)
+	RemoteTurborepo = "turborepo"
+	// RemoteNx is the Nx self-hosted remote cache API (/v1/cache)
+	RemoteNx = "nx"
+	// RemoteS3 is an S3 bucket, or a bucket of an S3-compatible server
+	RemoteS3 = "s3"
+)
+
+// RemoteCacheTypes lists the supported remote cache protocols.
+func RemoteCacheTypes() []string {
+	return []string{RemoteTurborepo, RemoteNx, RemoteS3}
+}
+
+// RemoteCache configures the server cached outputs are shared through. URL,
+// Team and the token default to the environment variables the Turborepo and
+// Nx tools read; TokenEnv names a different variable holding the token so it
+// never has to be written into doctrus.yml. For s3, URL is an
+// s3://bucket/prefix location and credentials come from the AWS environment
+// variables; Region and Endpoint override theirs. With ReadOnly set, doctrus
+// restores outputs from the server but never uploads them.
+type RemoteCache struct {
+	Type     string `yaml:"type" json:"type"`
+	URL      string `yaml:"url,omitempty" json:"url,omitempty"`
+	Team     string `yaml:"team,omitempty" json:"team,omitempty"`
+	TokenEnv string `yaml:"token_env,omitempty" json:"token_env,omitempty"`
+	Region   string `yaml:"region,omitempty" json:"region,omitempty"`
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	ReadOnly bool   `yaml:"read_only,omitempty" json:"read_only,omitempty"`
+}
+
+// RemoteCache returns the remote cache settings, or nil when no remote
+// cache is configured.
+func (c *Config) RemoteCache() *RemoteCache {
+	if c.Cache == nil {
+		return nil
+	}
+	return c.Cache.Remote
+}
+
+// ProvenanceEnabled reports whether cached outputs get provenance
+// attestations.
+func (c *Config) ProvenanceEnabled() bool {
+	return c.Cache != nil && c.Cache.Provenance
 }
 
 type Workspace struct {
-	Path      string            `yaml:"path"`
-	Container string            `yaml:"container,omitempty"`
-	Tasks     map[string]Task   `yaml:"tasks"`
-	Env       map[string]string `yaml:"env,omitempty"`
+	Path      string            `yaml:"path" json:"path"`
+	Container string            `yaml:"container,omitempty" json:"container,omitempty"`
+	Executor  string            `yaml:"executor,omitempty" json:"executor,omitempty"`
+	Image     string            `yaml:"image,omitempty" json:"image,omitempty"`
+	Tasks     map[string]Task   `yaml:"tasks" json:"tasks"`
+	Env       map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	EnvFile   []string          `yaml:"env_file,omitempty" json:"env_file,omitempty"`
+	Tags      []string          `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Preset    string            `yaml:"preset,omitempty" json:"preset,omitempty"`
+	Wrapper   []string          `yaml:"wrapper,omitempty" json:"wrapper,omitempty"`
 }
 
 type Task struct {
-	Command     []string          `yaml:"command"`
-	Description string            `yaml:"description,omitempty"`
-	DependsOn   []string          `yaml:"depends_on,omitempty"`
-	Inputs      []string          `yaml:"inputs,omitempty"`
-	Outputs     []string          `yaml:"outputs,omitempty"`
-	Cache       bool              `yaml:"cache,omitempty"`
-	Env         map[string]string `yaml:"env,omitempty"`
-	Container   *string           `yaml:"container,omitempty"`
-	Docker      *TaskDockerConfig `yaml:"docker,omitempty"`
-	Verbose     *bool             `yaml:"verbose,omitempty"`
-	Parallel    *bool             `yaml:"parallel,omitempty"`
+	Extends        string            `yaml:"extends,omitempty" json:"extends,omitempty"`
+	Command        []string          `yaml:"command" json:"command"`
+	Description    string            `yaml:"description,omitempty" json:"description,omitempty"`
+	DependsOn      []string          `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	Inputs         []string          `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+	Outputs        []string          `yaml:"outputs,omitempty" json:"outputs,omitempty"`
+	StrictOutputs  bool              `yaml:"strict_outputs,omitempty" json:"strict_outputs,omitempty"`
+	StrictInputs   string            `yaml:"strict_inputs,omitempty" json:"strict_inputs,omitempty"`
+	Sandbox        bool              `yaml:"sandbox,omitempty" json:"sandbox,omitempty"`
+	Cache          bool              `yaml:"cache,omitempty" json:"cache,omitempty"`
+	Env            map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	EnvFile        []string          `yaml:"env_file,omitempty" json:"env_file,omitempty"`
+	Container      *string           `yaml:"container,omitempty" json:"container,omitempty"`
+	Docker         *TaskDockerConfig `yaml:"docker,omitempty" json:"docker,omitempty"`
+	Executor       string            `yaml:"executor,omitempty" json:"executor,omitempty"`
+	Image          string            `yaml:"image,omitempty" json:"image,omitempty"`
+	Verbose        *bool             `yaml:"verbose,omitempty" json:"verbose,omitempty"`
+	Parallel       *bool             `yaml:"parallel,omitempty" json:"parallel,omitempty"`
+	Timeout        string            `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Interactive    bool              `yaml:"interactive,omitempty" json:"interactive,omitempty"`
+	Retry          *TaskRetry        `yaml:"retry,omitempty" json:"retry,omitempty"`
+	Hermetic       bool              `yaml:"hermetic,omitempty" json:"hermetic,omitempty"`
+	PassEnv        []string          `yaml:"pass_env,omitempty" json:"pass_env,omitempty"`
+	Publish        []PublishTarget   `yaml:"publish,omitempty" json:"publish,omitempty"`
+	Finally        [][]string        `yaml:"finally,omitempty" json:"finally,omitempty"`
+	DependsOnFiles []string          `yaml:"depends_on_files,omitempty" json:"depends_on_files,omitempty"`
+	Preset         string            `yaml:"preset,omitempty" json:"preset,omitempty"`
+	Wrapper        []string          `yaml:"wrapper,omitempty" json:"wrapper,omitempty"`
+	Params         []TaskParam       `yaml:"params,omitempty" json:"params,omitempty"`
+	Tags           []string          `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Aliases        []string          `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	Internal       bool              `yaml:"internal,omitempty" json:"internal,omitempty"`
+}
+
+// TimeoutDuration returns the task's timeout, or zero when none is set.
+func (t *Task) TimeoutDuration() time.Duration {
+	timeout, err := time.ParseDuration(t.Timeout)
+	if err != nil || timeout < 0 {
+		return 0
+	}
+	return timeout
+}
+
+// Modes of the strict_inputs task setting
+const (
+	// StrictInputsWarn warns about files a task read outside its inputs
+	StrictInputsWarn = "warn"
+	// StrictInputsFail fails a task that read files outside its inputs
+	StrictInputsFail = "fail"
+)
+
+// Executor names accepted by the executor field of workspaces and tasks
+const (
+	// ExecutorLocal runs the command on the host
+	ExecutorLocal = "local"
+	// ExecutorComposeExec runs the command in a running Docker Compose service
+	ExecutorComposeExec = "compose-exec"
+	// ExecutorDockerRun runs the command in a throwaway container of an image
+	ExecutorDockerRun = "docker-run"
+	// ExecutorRemote runs the command on the doctrus agent configured under
+	// remote_execution
+	ExecutorRemote = "remote"
+)
+
+// ExecutorNames lists the supported executors.
+func ExecutorNames() []string {
+	return []string{ExecutorLocal, ExecutorComposeExec, ExecutorDockerRun, ExecutorRemote}
+}
+
+func isExecutorName(name string) bool {
+	for _, known := range ExecutorNames() {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// HookNames lists the git hooks the hooks section may configure.
+func HookNames() []string {
+	return []string{
+		"pre-commit", "prepare-commit-msg", "commit-msg", "post-commit",
+		"pre-merge-commit", "post-merge", "pre-rebase", "post-checkout",
+		"post-rewrite", "pre-push",
+	}
+}
+
+func isHookName(name string) bool {
+	for _, known := range HookNames() {
+		if name == known {
+			return true
+		}
+	}
+	return false
 }
 
 type PreCommand struct {
-	Command     []string          `yaml:"command"`
-	Description string            `yaml:"description,omitempty"`
-	Dir         string            `yaml:"dir,omitempty"`
-	Env         map[string]string `yaml:"env,omitempty"`
-	Verbose     *bool             `yaml:"verbose,omitempty"`
+	Command     []string          `yaml:"command" json:"command"`
+	Description string            `yaml:"description,omitempty" json:"description,omitempty"`
+	Dir         string            `yaml:"dir,omitempty" json:"dir,omitempty"`
+	Env         map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	Verbose     *bool             `yaml:"verbose,omitempty" json:"verbose,omitempty"`
+}
+
+// Plugin declares an external command exposed as a doctrus subcommand
+type Plugin struct {
+	Command     []string `yaml:"command" json:"command"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
 }
 
 type DockerConfig struct {
-	ComposeFile string `yaml:"compose_file,omitempty"`
+	ComposeFile string `yaml:"compose_file,omitempty" json:"compose_file,omitempty"`
 }
 
 type TaskDockerConfig struct {
-	ComposeFile string `yaml:"compose_file,omitempty"`
-	Disable     bool   `yaml:"disable,omitempty"`
+	ComposeFile string `yaml:"compose_file,omitempty" json:"compose_file,omitempty"`
+	Disable     bool   `yaml:"disable,omitempty" json:"disable,omitempty"`
 }
 
+// Load loads the configuration with the profile DOCTRUS_PROFILE selects.
 func Load(configPath string) (*Config, string, error) {
-	if configPath == "" {
-		configPath = "doctrus.yml"
-	}
-
-	// If config path is not absolute, search for it in parent directories
-	var absPath string
-	var configDir string
+	return LoadScoped(configPath, nil)
+}
 
-	if filepath.IsAbs(configPath) {
-		absPath = configPath
-		configDir = filepath.Dir(absPath)
-	} else {
-		// Search for config file in current and parent directories
-		currentDir, err := os.Getwd()
-		if err != nil {
-			return nil, "", fmt.Errorf("failed to get working directory: %w", err)
-		}
+// LoadScoped loads the configuration like Load, but when specs is non-empty
+// only the workspaces reachable from those task specs are decoded and
+// validated; the others are left out of the returned Config. Large
+// monorepos use it to avoid paying for workspaces a run never touches.
+func LoadScoped(configPath string, specs []string) (*Config, string, error) {
+	return LoadProfile(configPath, specs, os.Getenv(ProfileEnv))
+}
 
-		foundPath, foundDir := findConfigInParents(currentDir, configPath)
-		if foundPath == "" {
-			// If not found, try the original path relative to cwd
-			absPath = filepath.Join(currentDir, configPath)
-			configDir = currentDir
-		} else {
-			absPath = foundPath
-			configDir = foundDir
-		}
+// LoadProfile loads the configuration like LoadScoped with the named
+// profile applied, or none when profile is empty.
+func LoadProfile(configPath string, specs []string, profile string) (*Config, string, error) {
+	absPath, configDir, err := Locate(configPath)
+	if err != nil {
+		return nil, "", err
 	}
 
 	data, err := os.ReadFile(absPath)
@@ -88,16 +285,71 @@ func Load(configPath string) (*Config, string, error) {
 		return nil, "", fmt.Errorf("failed to read config file %s: %w", absPath, err)
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	root, err := parseDocument(absPath, data)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse config file: %w", err)
+	}
+	includes, err := applyIncludes(absPath, root)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to include config file: %w", err)
+	}
+	if err := applyProfile(absPath, root, profile); err != nil {
+		return nil, "", fmt.Errorf("failed to apply profile: %w", err)
+	}
+	applyTemplates(root)
+
+	if len(specs) > 0 {
+		if scoped := scopeDocument(root, specs); scoped != nil {
+			root = scoped
+		}
+	}
+
+	config, err := decodeNode(absPath, root)
+	if err != nil {
 		return nil, "", fmt.Errorf("failed to parse config file: %w", err)
 	}
+	config.includes = includes
+
+	if err := config.applyImports(configDir); err != nil {
+		return nil, "", err
+	}
+	if err := config.applyProviders(configDir); err != nil {
+		return nil, "", err
+	}
+	config.applyPresets()
+	config.applyPathMapping()
+
+	if diagnostics := config.diagnose(absPath, root); len(diagnostics) > 0 {
+		return nil, "", fmt.Errorf("invalid configuration:\n%w", diagnostics)
+	}
+
+	return config, configDir, nil
+}
+
+// Locate resolves the config file path and the directory containing it.
+// Relative paths are searched for in the current and parent directories.
+func Locate(configPath string) (string, string, error) {
+	if configPath == "" {
+		configPath = "doctrus.yml"
+	}
+
+	if filepath.IsAbs(configPath) {
+		return configPath, filepath.Dir(configPath), nil
+	}
+
+	// Search for config file in current and parent directories
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get working directory: %w", err)
+	}
 
-	if err := config.validate(); err != nil {
-		return nil, "", fmt.Errorf("invalid configuration: %w", err)
+	foundPath, foundDir := findConfigInParents(currentDir, configPath)
+	if foundPath == "" {
+		// If not found, try the original path relative to cwd
+		return filepath.Join(currentDir, configPath), currentDir, nil
 	}
 
-	return &config, configDir, nil
+	return foundPath, foundDir, nil
 }
 
 // findConfigInParents searches for a config file in the current and parent directories
@@ -122,41 +374,213 @@ func findConfigInParents(startDir, configName string) (string, string) {
 }
 
 func (c *Config) validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return errors.New(problems[0].message)
+	}
+	return nil
+}
+
+// problems returns every validation failure, in a stable order.
+func (c *Config) problems() []problem {
+	var problems []problem
+	add := func(path, format string, args ...any) {
+		problems = append(problems, problem{path: path, message: fmt.Sprintf(format, args...)})
+	}
+
 	if c.Version == "" {
-		return fmt.Errorf("version is required")
+		add("version", "version is required")
 	}
 
 	if len(c.Workspaces) == 0 {
-		return fmt.Errorf("at least one workspace is required")
+		add("workspaces", "at least one workspace is required")
 	}
 
 	for i, pre := range c.Pre {
 		if len(pre.Command) == 0 {
-			return fmt.Errorf("pre[%d]: command is required", i)
+			add(fmt.Sprintf("pre[%d]", i), "pre[%d]: command is required", i)
 		}
 	}
 
-	for name, workspace := range c.Workspaces {
-		if len(workspace.Tasks) == 0 {
-			return fmt.Errorf("workspace %s: at least one task is required", name)
+	c.templateProblems(add)
+	c.envMergeProblems(add)
+	c.retentionProblems(add)
+	c.pathMappingProblems(add)
+	wrapperProblems("wrapper", "wrapper", c.Wrapper, add)
+
+	switch c.Lock {
+	case "", "off", "wait", "fail":
+	default:
+		add("lock", "invalid lock %q (expected wait, fail or off)", c.Lock)
+	}
+
+	if _, err := ParseParallel(c.Parallel, 1); err != nil {
+		add("parallel", "%v", err)
+	}
+
+	if c.Cache != nil {
+		if _, err := ParseSize(c.Cache.MaxSize); err != nil {
+			add("cache.max_size", "cache.max_size: %v", err)
+		}
+	}
+
+	if remote := c.RemoteCache(); remote != nil {
+		switch remote.Type {
+		case RemoteTurborepo, RemoteNx:
+		case RemoteS3:
+			if bucket, _, _ := strings.Cut(strings.TrimPrefix(remote.URL, "s3://"), "/"); !strings.HasPrefix(remote.URL, "s3://") || bucket == "" {
+				add("cache.remote.url", "cache.remote: invalid s3 location %q (expected s3://bucket/prefix)", remote.URL)
+			}
+			if remote.TokenEnv != "" {
+				add("cache.remote.token_env", "cache.remote: token_env is not supported by the s3 type (credentials come from the AWS environment variables)")
+			}
+		case "":
+			add("cache.remote.type", "cache.remote: type is required (expected one of %s)", strings.Join(RemoteCacheTypes(), ", "))
+		default:
+			add("cache.remote.type", "cache.remote: unknown type %q (expected one of %s)", remote.Type, strings.Join(RemoteCacheTypes(), ", "))
+		}
+		if remote.Team != "" && remote.Type == RemoteNx {
+			add("cache.remote.team", "cache.remote: team is only supported by the turborepo type")
+		}
+		if remote.Type != RemoteS3 && remote.Region != "" {
+			add("cache.remote.region", "cache.remote: region is only supported by the s3 type")
 		}
+		if remote.Type != RemoteS3 && remote.Endpoint != "" {
+			add("cache.remote.endpoint", "cache.remote: endpoint is only supported by the s3 type")
+		}
+	}
+
+	if remote := c.RemoteExecution; remote != nil {
+		if remote.URL == "" && len(remote.Agents) == 0 {
+			add("remote_execution", "remote_execution: url or agents is required")
+		}
+		for i, agent := range remote.Agents {
+			agentPath := fmt.Sprintf("remote_execution.agents[%d]", i)
+			if agent.URL == "" {
+				add(agentPath, "remote_execution: agents[%d]: url is required", i)
+			}
+			if agent.Jobs < 0 {
+				add(agentPath, "remote_execution: agents[%d]: jobs must not be negative", i)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(c.Hooks) {
+		hookPath := joinPath("hooks", name)
+		if !isHookName(name) {
+			add(hookPath, "hooks: unknown git hook %q (expected one of %s)", name, strings.Join(HookNames(), ", "))
+		}
+		if len(c.Hooks[name]) == 0 {
+			add(hookPath, "hook %s: at least one task is required", name)
+		}
+		for i, spec := range c.Hooks[name] {
+			if strings.TrimSpace(spec) == "" {
+				add(fmt.Sprintf("%s[%d]", hookPath, i), "hook %s: task %d is empty", name, i)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(c.Plugins) {
+		if len(c.Plugins[name].Command) == 0 {
+			add(joinPath("plugins", name), "plugin %s: command is required", name)
+		}
+	}
+
+	for _, name := range sortedKeys(c.Workspaces) {
+		workspace := c.Workspaces[name]
+		workspacePath := joinPath("workspaces", name)
+
+		if len(workspace.Tasks) == 0 && !c.imported[name] {
+			add(workspacePath, "workspace %s: at least one task is required", name)
+		}
+		aliasProblems(name, workspace, add)
+		presetProblems("workspace "+name, joinPath(workspacePath, "preset"), workspace.Preset, add)
+		wrapperProblems("workspace "+name, joinPath(workspacePath, "wrapper"), workspace.Wrapper, add)
+		if workspace.Executor != "" && !isExecutorName(workspace.Executor) {
+			add(joinPath(workspacePath, "executor"), "workspace %s: unknown executor %q (expected one of %s)", name, workspace.Executor, strings.Join(ExecutorNames(), ", "))
+		}
+
+		for _, taskName := range sortedKeys(workspace.Tasks) {
+			task := workspace.Tasks[taskName]
+			taskPath := joinPath(workspacePath, "tasks", taskName)
+			prefix := fmt.Sprintf("workspace %s, task %s", name, taskName)
 
-		for taskName, task := range workspace.Tasks {
 			if task.Parallel != nil && *task.Parallel {
 				if len(task.Command) > 0 {
-					return fmt.Errorf("workspace %s, task %s: parallel is only supported for compound tasks without a command", name, taskName)
+					add(joinPath(taskPath, "parallel"), "%s: parallel is only supported for compound tasks without a command", prefix)
 				}
 				if len(task.DependsOn) == 0 {
-					return fmt.Errorf("workspace %s, task %s: parallel requires at least one dependency", name, taskName)
+					add(joinPath(taskPath, "parallel"), "%s: parallel requires at least one dependency", prefix)
 				}
 			}
 			if len(task.Command) == 0 && len(task.DependsOn) == 0 {
-				return fmt.Errorf("workspace %s, task %s: command is required unless task has dependencies (compound task)", name, taskName)
+				add(taskPath, "%s: command is required unless task has dependencies (compound task)", prefix)
+			}
+			if task.Timeout != "" {
+				if timeout, err := time.ParseDuration(task.Timeout); err != nil || timeout <= 0 {
+					add(joinPath(taskPath, "timeout"), "%s: invalid timeout %q (expected a positive duration such as 30s or 10m)", prefix, task.Timeout)
+				}
+			}
+			if task.StrictOutputs && len(task.Outputs) == 0 {
+				add(joinPath(taskPath, "strict_outputs"), "%s: strict_outputs requires outputs", prefix)
+			}
+			if task.StrictInputs != "" && task.StrictInputs != StrictInputsWarn && task.StrictInputs != StrictInputsFail {
+				add(joinPath(taskPath, "strict_inputs"), "%s: invalid strict_inputs %q (expected warn or fail)", prefix, task.StrictInputs)
+			}
+			if len(task.PassEnv) > 0 && !task.Hermetic {
+				add(joinPath(taskPath, "pass_env"), "%s: pass_env requires hermetic: true", prefix)
+			}
+			retryProblems(prefix, joinPath(taskPath, "retry"), task, add)
+			publishProblems(prefix, joinPath(taskPath, "publish"), task, add)
+			if task.Sandbox && len(task.Command) == 0 {
+				add(joinPath(taskPath, "sandbox"), "%s: sandbox is only supported for tasks with a command", prefix)
+			}
+			if task.Interactive && len(task.Command) == 0 {
+				add(joinPath(taskPath, "interactive"), "%s: interactive is only supported for tasks with a command", prefix)
+			}
+			if len(task.Finally) > 0 && len(task.Command) == 0 {
+				add(joinPath(taskPath, "finally"), "%s: finally is only supported for tasks with a command", prefix)
+			}
+			for i, command := range task.Finally {
+				if len(command) == 0 {
+					add(fmt.Sprintf("%s[%d]", joinPath(taskPath, "finally"), i), "%s: finally[%d]: command is required", prefix, i)
+				}
+			}
+			fileRuleProblems(prefix, joinPath(taskPath, "depends_on_files"), task, add)
+			paramProblems(prefix, taskPath, task, add)
+			presetProblems(prefix, joinPath(taskPath, "preset"), task.Preset, add)
+			wrapperProblems(prefix, joinPath(taskPath, "wrapper"), task.Wrapper, add)
+			if task.Executor != "" && !isExecutorName(task.Executor) {
+				add(joinPath(taskPath, "executor"), "%s: unknown executor %q (expected one of %s)", prefix, task.Executor, strings.Join(ExecutorNames(), ", "))
+				continue
+			}
+			if len(task.Command) == 0 {
+				continue
+			}
+			executor := c.GetEffectiveExecutor(name, taskName)
+			if task.Sandbox && executor != ExecutorLocal {
+				add(joinPath(taskPath, "sandbox"), "%s: sandbox is only supported by the local executor", prefix)
+			}
+			if task.Interactive && executor == ExecutorRemote {
+				add(joinPath(taskPath, "interactive"), "%s: interactive is not supported by the remote executor", prefix)
+			}
+			switch executor {
+			case ExecutorDockerRun:
+				if c.GetEffectiveImage(name, taskName) == "" {
+					add(taskPath, "%s: executor docker-run requires an image", prefix)
+				}
+			case ExecutorComposeExec:
+				if c.GetEffectiveContainer(name, taskName) == "" {
+					add(taskPath, "%s: executor compose-exec requires a container", prefix)
+				}
+			case ExecutorRemote:
+				if c.RemoteExecution == nil {
+					add(taskPath, "%s: executor remote requires remote_execution", prefix)
+				}
 			}
 		}
 	}
 
-	return nil
+	return problems
 }
 
 func (c *Config) GetWorkspace(name string) (*Workspace, bool) {
@@ -164,13 +588,14 @@ func (c *Config) GetWorkspace(name string) (*Workspace, bool) {
 	return &workspace, exists
 }
 
+// GetTask returns a task of a workspace by its name or one of its aliases.
 func (c *Config) GetTask(workspaceName, taskName string) (*Task, bool) {
 	workspace, exists := c.Workspaces[workspaceName]
 	if !exists {
 		return nil, false
 	}
 
-	task, exists := workspace.Tasks[taskName]
+	task, exists := workspace.Tasks[c.TaskName(workspaceName, taskName)]
 	return &task, exists
 }
 
@@ -201,6 +626,54 @@ func (c *Config) GetEffectiveContainer(workspaceName, taskName string) string {
 	return workspace.Container
 }
 
+// GetEffectiveExecutor returns the executor that runs a task. An explicit
+// executor on the task wins, then docker.disable and a task-level container,
+// then the workspace executor. Without any of those, tasks with a container
+// use compose-exec and everything else runs locally.
+func (c *Config) GetEffectiveExecutor(workspaceName, taskName string) string {
+	workspace, exists := c.Workspaces[workspaceName]
+	if !exists {
+		return ExecutorLocal
+	}
+
+	task, exists := workspace.Tasks[taskName]
+	if !exists {
+		return ExecutorLocal
+	}
+
+	switch {
+	case task.Executor != "":
+		return task.Executor
+	case task.Docker != nil && task.Docker.Disable:
+		return ExecutorLocal
+	case task.Container != nil:
+		if *task.Container == "" {
+			return ExecutorLocal
+		}
+		return ExecutorComposeExec
+	case workspace.Executor != "":
+		return workspace.Executor
+	case workspace.Container != "":
+		return ExecutorComposeExec
+	default:
+		return ExecutorLocal
+	}
+}
+
+// GetEffectiveImage returns the image used by the docker-run executor,
+// preferring the task's image over the workspace's
+func (c *Config) GetEffectiveImage(workspaceName, taskName string) string {
+	workspace, exists := c.Workspaces[workspaceName]
+	if !exists {
+		return ""
+	}
+
+	if task, exists := workspace.Tasks[taskName]; exists && task.Image != "" {
+		return task.Image
+	}
+	return workspace.Image
+}
+
 // GetEffectiveDockerConfig returns the effective Docker configuration for a task,
 // considering task-level overrides and workspace/global defaults
 func (c *Config) GetEffectiveDockerConfig(workspaceName, taskName string) DockerConfig {
@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -177,6 +179,267 @@ func TestConfigValidation(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "unknown executor",
+			config: Config{
+				Version: "1.0",
+				Workspaces: map[string]Workspace{
+					"backend": {
+						Tasks: map[string]Task{
+							"start": {Command: []string{"go", "run", "."}, Executor: "k8s"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  `workspace backend, task start: unknown executor "k8s" (expected one of local, compose-exec, docker-run, remote)`,
+		},
+		{
+			name: "docker-run without image",
+			config: Config{
+				Version: "1.0",
+				Workspaces: map[string]Workspace{
+					"backend": {
+						Executor: ExecutorDockerRun,
+						Tasks: map[string]Task{
+							"start": {Command: []string{"go", "run", "."}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "workspace backend, task start: executor docker-run requires an image",
+		},
+		{
+			name: "remote executor without remote_execution",
+			config: Config{
+				Version: "1.0",
+				Workspaces: map[string]Workspace{
+					"backend": {
+						Tasks: map[string]Task{
+							"build": {Command: []string{"go", "build"}, Executor: ExecutorRemote},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "workspace backend, task build: executor remote requires remote_execution",
+		},
+		{
+			name: "remote agent without url",
+			config: Config{
+				Version:         "1.0",
+				RemoteExecution: &RemoteExecution{Agents: []RemoteAgent{{URL: "http://a:7070"}, {Jobs: 2}}},
+				Workspaces: map[string]Workspace{
+					"backend": {
+						Tasks: map[string]Task{
+							"build": {Command: []string{"go", "build"}, Executor: ExecutorRemote},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "remote_execution: agents[1]: url is required",
+		},
+		{
+			name: "invalid timeout",
+			config: Config{
+				Version: "1.0",
+				Workspaces: map[string]Workspace{
+					"backend": {
+						Tasks: map[string]Task{
+							"start": {Command: []string{"go", "run", "."}, Timeout: "soon"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  `workspace backend, task start: invalid timeout "soon" (expected a positive duration such as 30s or 10m)`,
+		},
+		{
+			name: "invalid retry backoff",
+			config: Config{
+				Version: "1.0",
+				Workspaces: map[string]Workspace{
+					"backend": {
+						Tasks: map[string]Task{
+							"test": {Command: []string{"go", "test", "./..."}, Retry: &TaskRetry{Attempts: 3, Backoff: "linear"}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  `workspace backend, task test: invalid retry backoff "linear" (expected constant or exponential)`,
+		},
+		{
+			name: "pass_env without hermetic",
+			config: Config{
+				Version: "1.0",
+				Workspaces: map[string]Workspace{
+					"backend": {
+						Tasks: map[string]Task{
+							"start": {Command: []string{"go", "run", "."}, PassEnv: []string{"CI"}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "workspace backend, task start: pass_env requires hermetic: true",
+		},
+		{
+			name: "invalid strict_inputs",
+			config: Config{
+				Version: "1.0",
+				Workspaces: map[string]Workspace{
+					"backend": {
+						Tasks: map[string]Task{
+							"build": {Command: []string{"go", "build"}, StrictInputs: "error"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  `workspace backend, task build: invalid strict_inputs "error" (expected warn or fail)`,
+		},
+		{
+			name: "sandbox outside the local executor",
+			config: Config{
+				Version: "1.0",
+				Workspaces: map[string]Workspace{
+					"backend": {
+						Tasks: map[string]Task{
+							"build": {Command: []string{"go", "build"}, Executor: ExecutorDockerRun, Image: "golang:1.24", Sandbox: true},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "workspace backend, task build: sandbox is only supported by the local executor",
+		},
+		{
+			name: "finally on compound task",
+			config: Config{
+				Version: "1.0",
+				Workspaces: map[string]Workspace{
+					"backend": {
+						Tasks: map[string]Task{
+							"build": {Command: []string{"go", "build"}},
+							"all":   {DependsOn: []string{"build"}, Finally: [][]string{{"rm", "-rf", "tmp"}}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "workspace backend, task all: finally is only supported for tasks with a command",
+		},
+		{
+			name: "empty finally step",
+			config: Config{
+				Version: "1.0",
+				Workspaces: map[string]Workspace{
+					"backend": {
+						Tasks: map[string]Task{
+							"test": {Command: []string{"go", "test"}, Finally: [][]string{{"rm", "-rf", "tmp"}, {}}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "workspace backend, task test: finally[1]: command is required",
+		},
+		{
+			name: "depends_on_files rule without targets",
+			config: Config{
+				Version: "1.0",
+				Workspaces: map[string]Workspace{
+					"backend": {
+						Tasks: map[string]Task{
+							"proto": {Command: []string{"buf", "generate"}, DependsOnFiles: []string{"proto/*.proto -> gen/**", "schema.sql"}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  `workspace backend, task proto: depends_on_files[1]: invalid rule "schema.sql" (expected sources -> targets)`,
+		},
+		{
+			name: "compose-exec without container",
+			config: Config{
+				Version: "1.0",
+				Workspaces: map[string]Workspace{
+					"backend": {
+						Tasks: map[string]Task{
+							"start": {Command: []string{"go", "run", "."}, Executor: ExecutorComposeExec},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "workspace backend, task start: executor compose-exec requires a container",
+		},
+		{
+			name: "unknown git hook",
+			config: Config{
+				Version: "1.0",
+				Hooks:   map[string][]string{"pre-commit": {"lint"}, "precommit": {"lint"}},
+				Workspaces: map[string]Workspace{
+					"backend": {Tasks: map[string]Task{"lint": {Command: []string{"lint"}}}},
+				},
+			},
+			wantErr: true,
+			errMsg:  `hooks: unknown git hook "precommit" (expected one of pre-commit, prepare-commit-msg, commit-msg, post-commit, pre-merge-commit, post-merge, pre-rebase, post-checkout, post-rewrite, pre-push)`,
+		},
+		{
+			name: "unknown remote cache type",
+			config: Config{
+				Version: "1.0",
+				Cache:   &CacheConfig{Remote: &RemoteCache{Type: "s4"}},
+				Workspaces: map[string]Workspace{
+					"backend": {Tasks: map[string]Task{"lint": {Command: []string{"lint"}}}},
+				},
+			},
+			wantErr: true,
+			errMsg:  `cache.remote: unknown type "s4" (expected one of turborepo, nx, s3)`,
+		},
+		{
+			name: "s3 remote cache without a bucket",
+			config: Config{
+				Version: "1.0",
+				Cache:   &CacheConfig{Remote: &RemoteCache{Type: RemoteS3, URL: "https://example.com/cache"}},
+				Workspaces: map[string]Workspace{
+					"backend": {Tasks: map[string]Task{"lint": {Command: []string{"lint"}}}},
+				},
+			},
+			wantErr: true,
+			errMsg:  `cache.remote: invalid s3 location "https://example.com/cache" (expected s3://bucket/prefix)`,
+		},
+		{
+			name: "region on a turborepo remote cache",
+			config: Config{
+				Version: "1.0",
+				Cache:   &CacheConfig{Remote: &RemoteCache{Type: RemoteTurborepo, Region: "eu-west-1"}},
+				Workspaces: map[string]Workspace{
+					"backend": {Tasks: map[string]Task{"lint": {Command: []string{"lint"}}}},
+				},
+			},
+			wantErr: true,
+			errMsg:  "cache.remote: region is only supported by the s3 type",
+		},
+		{
+			name: "publish without a single destination",
+			config: Config{
+				Version: "1.0",
+				Workspaces: map[string]Workspace{
+					"backend": {Tasks: map[string]Task{"build": {
+						Command: []string{"build"},
+						Outputs: []string{"dist/**"},
+						Publish: []PublishTarget{{Dir: "releases", S3: "s3://bucket/app"}},
+					}}},
+				},
+			},
+			wantErr: true,
+			errMsg:  "workspace backend, task build: publish[0] needs exactly one of dir, http or s3",
+		},
 	}
 
 	for _, tt := range tests {
@@ -607,6 +870,67 @@ func TestGetEffectiveContainer(t *testing.T) {
 	}
 }
 
+func TestGetEffectiveExecutor(t *testing.T) {
+	config := &Config{
+		Version: "1.0",
+		Workspaces: map[string]Workspace{
+			"frontend": {
+				Container: "frontend-container",
+				Tasks: map[string]Task{
+					"build":   {Command: []string{"npm", "build"}},
+					"local":   {Command: []string{"npm", "lint"}, Docker: &TaskDockerConfig{Disable: true}},
+					"nocont":  {Command: []string{"npm", "fmt"}, Container: stringPtr("")},
+					"e2e":     {Command: []string{"npm", "e2e"}, Executor: ExecutorDockerRun, Image: "node:22"},
+					"explain": {Command: []string{"npm", "help"}, Executor: ExecutorLocal},
+				},
+			},
+			"backend": {
+				Executor: ExecutorDockerRun,
+				Image:    "golang:1.24",
+				Tasks: map[string]Task{
+					"build": {Command: []string{"go", "build"}},
+					"test":  {Command: []string{"go", "test"}, Image: "golang:1.23"},
+					"shell": {Command: []string{"sh"}, Container: stringPtr("api")},
+				},
+			},
+			"docs": {
+				Tasks: map[string]Task{
+					"build": {Command: []string{"mkdocs", "build"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		workspaceName string
+		taskName      string
+		wantExecutor  string
+		wantImage     string
+	}{
+		{"frontend", "build", ExecutorComposeExec, ""},
+		{"frontend", "local", ExecutorLocal, ""},
+		{"frontend", "nocont", ExecutorLocal, ""},
+		{"frontend", "e2e", ExecutorDockerRun, "node:22"},
+		{"frontend", "explain", ExecutorLocal, ""},
+		{"backend", "build", ExecutorDockerRun, "golang:1.24"},
+		{"backend", "test", ExecutorDockerRun, "golang:1.23"},
+		{"backend", "shell", ExecutorComposeExec, "golang:1.24"},
+		{"docs", "build", ExecutorLocal, ""},
+		{"nonexistent", "build", ExecutorLocal, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.workspaceName+":"+tt.taskName, func(t *testing.T) {
+			if got := config.GetEffectiveExecutor(tt.workspaceName, tt.taskName); got != tt.wantExecutor {
+				t.Errorf("GetEffectiveExecutor() = %v, want %v", got, tt.wantExecutor)
+			}
+			if got := config.GetEffectiveImage(tt.workspaceName, tt.taskName); got != tt.wantImage {
+				t.Errorf("GetEffectiveImage() = %v, want %v", got, tt.wantImage)
+			}
+		})
+	}
+}
+
 func TestGetEffectiveDockerConfig(t *testing.T) {
 	config := &Config{
 		Version: "1.0",
@@ -677,3 +1001,79 @@ func TestGetEffectiveDockerConfig(t *testing.T) {
 func stringPtr(s string) *string {
 	return &s
 }
+
+func TestConfigLoadProviders(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell providers not available on Windows")
+	}
+
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "doctrus.yml")
+	content := `version: "1.0"
+providers:
+  - name: scripts
+    workspace: web
+    command: ["sh", "-c", "echo '{\"tasks\": {\"lint\": {\"command\": [\"npm\", \"run\", \"lint\"]}, \"build\": {\"command\": [\"generated\"]}}}'"]
+  - name: modules
+    command: ["sh", "-c", "printf 'workspaces:\n  infra:\n    path: ./infra\n    tasks:\n      plan:\n        command: [terraform, plan]\n'"]
+workspaces:
+  web:
+    path: ./web
+    tasks:
+      build:
+        command: ["npm", "run", "build"]
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, _, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	lint, ok := cfg.GetTask("web", "lint")
+	if !ok || !reflect.DeepEqual(lint.Command, []string{"npm", "run", "lint"}) {
+		t.Fatalf("expected generated lint task, got %+v (exists=%v)", lint, ok)
+	}
+
+	build, _ := cfg.GetTask("web", "build")
+	if build.Command[0] != "npm" {
+		t.Fatalf("explicit task should win over generated task, got %v", build.Command)
+	}
+
+	infra, ok := cfg.GetWorkspace("infra")
+	if !ok || infra.Path != "./infra" {
+		t.Fatalf("expected generated infra workspace, got %+v (exists=%v)", infra, ok)
+	}
+	if _, ok := cfg.GetTask("infra", "plan"); !ok {
+		t.Fatal("expected generated infra:plan task")
+	}
+}
+
+func TestConfigLoadProviderFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell providers not available on Windows")
+	}
+
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "doctrus.yml")
+	content := `version: "1.0"
+providers:
+  - name: broken
+    command: ["sh", "-c", "echo boom >&2; exit 3"]
+workspaces:
+  web:
+    tasks:
+      build:
+        command: ["npm", "run", "build"]
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	_, _, err := Load(configPath)
+	if err == nil || !strings.Contains(err.Error(), "provider broken") || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("Load() error = %v, want provider failure with stderr", err)
+	}
+}
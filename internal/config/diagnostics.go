@@ -0,0 +1,280 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Diagnostic is a single problem found in a configuration file. Path is the
+// YAML path of the offending node, such as workspaces.app.tasks.build.command
+// or pre[0]. Line and Column are 1-based and zero when the problem has no
+// position, for example in tasks generated by a provider.
+type Diagnostic struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// String renders the diagnostic as file:line:column: message.
+func (d Diagnostic) String() string {
+	location := d.File
+	if location == "" {
+		location = "config"
+	}
+	if d.Line > 0 {
+		location += ":" + strconv.Itoa(d.Line)
+		if d.Column > 0 {
+			location += ":" + strconv.Itoa(d.Column)
+		}
+	}
+	return location + ": " + d.Message
+}
+
+// Diagnostics is the error returned when a configuration file fails to parse
+// or validate. It lists every problem found, not just the first.
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) Error() string {
+	lines := make([]string, len(d))
+	for i, diagnostic := range d {
+		lines[i] = diagnostic.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// AsDiagnostics extracts the diagnostics from an error returned by Load or
+// Parse.
+func AsDiagnostics(err error) (Diagnostics, bool) {
+	var diagnostics Diagnostics
+	if errors.As(err, &diagnostics) {
+		return diagnostics, true
+	}
+	return nil, false
+}
+
+// Parse decodes and validates a configuration document without running
+// providers. File is only used to label diagnostics. Any problems are
+// returned together as Diagnostics.
+func Parse(file string, data []byte) (*Config, error) {
+	config, root, err := decode(file, data)
+	if err != nil {
+		return nil, err
+	}
+	if diagnostics := config.diagnose(file, root); len(diagnostics) > 0 {
+		return nil, diagnostics
+	}
+	return config, nil
+}
+
+// decode parses data into a Config and also returns the document node used
+// to position later diagnostics.
+func decode(file string, data []byte) (*Config, *yaml.Node, error) {
+	doc, err := parseDocument(file, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	applyTemplates(doc)
+
+	config, err := decodeNode(file, doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, doc, nil
+}
+
+// parseDocument parses YAML syntax, reporting errors as Diagnostics.
+func parseDocument(file string, data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, Diagnostics{yamlDiagnostic(file, nil, strings.TrimPrefix(err.Error(), "yaml: "))}
+	}
+	return &doc, nil
+}
+
+// decodeNode decodes a parsed document into a Config.
+func decodeNode(file string, doc *yaml.Node) (*Config, error) {
+	var config Config
+	if err := doc.Decode(&config); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, Diagnostics{yamlDiagnostic(file, doc, err.Error())}
+		}
+		diagnostics := make(Diagnostics, 0, len(typeErr.Errors))
+		for _, message := range typeErr.Errors {
+			diagnostics = append(diagnostics, yamlDiagnostic(file, doc, message))
+		}
+		return nil, diagnostics
+	}
+
+	return &config, nil
+}
+
+var yamlLinePattern = regexp.MustCompile(`^line (\d+): `)
+
+// yamlDiagnostic converts a yaml.v3 error message, which starts with
+// "line N: " when the position is known, into a diagnostic.
+func yamlDiagnostic(file string, doc *yaml.Node, message string) Diagnostic {
+	diagnostic := Diagnostic{File: file, Message: message}
+	if match := yamlLinePattern.FindStringSubmatch(message); match != nil {
+		diagnostic.Line, _ = strconv.Atoi(match[1])
+		diagnostic.Message = message[len(match[0]):]
+		if doc != nil {
+			diagnostic.Path, diagnostic.Column = pathAtLine(doc, diagnostic.Line)
+		}
+	}
+	return diagnostic
+}
+
+// diagnose validates the config and positions each problem using the
+// document it was decoded from. Root may be nil.
+func (c *Config) diagnose(file string, root *yaml.Node) Diagnostics {
+	var diagnostics Diagnostics
+	for _, problem := range c.problems() {
+		diagnostic := Diagnostic{File: file, Path: problem.path, Message: problem.message}
+		if segments := splitPath(problem.path); len(segments) > 1 && segments[0] == "workspaces" {
+			diagnostic.File = c.includes.fileOf(segments[1], file)
+		}
+		if node := lookupPath(root, problem.path); node != nil {
+			diagnostic.Line, diagnostic.Column = node.Line, node.Column
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics
+}
+
+// problem is a validation failure at a YAML path.
+type problem struct {
+	path    string
+	message string
+}
+
+// lookupPath returns the node to report for a path built by joinPath: the
+// key of a mapping entry or the item of a sequence. When the path names a
+// missing key, the deepest existing ancestor is returned instead.
+func lookupPath(doc *yaml.Node, path string) *yaml.Node {
+	if doc == nil || len(doc.Content) == 0 {
+		return nil
+	}
+
+	node := doc.Content[0]
+	position := node
+	for _, segment := range splitPath(path) {
+		found := false
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == segment {
+					position, node = node.Content[i], node.Content[i+1]
+					found = true
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if index, err := strconv.Atoi(segment); err == nil && index >= 0 && index < len(node.Content) {
+				position, node = node.Content[index], node.Content[index]
+				found = true
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return position
+}
+
+// pathAtLine returns the path and column of the first node that starts on
+// line, searching the document depth first.
+func pathAtLine(doc *yaml.Node, line int) (string, int) {
+	if len(doc.Content) == 0 {
+		return "", 0
+	}
+
+	var walk func(node *yaml.Node, path string) (string, int, bool)
+	walk = func(node *yaml.Node, path string) (string, int, bool) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				childPath := joinPath(path, key.Value)
+				if key.Line == line {
+					return childPath, key.Column, true
+				}
+				if found, column, ok := walk(value, childPath); ok {
+					return found, column, true
+				}
+			}
+		case yaml.SequenceNode:
+			for i, item := range node.Content {
+				childPath := fmt.Sprintf("%s[%d]", path, i)
+				if item.Line == line && item.Kind == yaml.ScalarNode {
+					return childPath, item.Column, true
+				}
+				if found, column, ok := walk(item, childPath); ok {
+					return found, column, true
+				}
+			}
+		}
+		if node.Line == line {
+			return path, node.Column, true
+		}
+		return "", 0, false
+	}
+
+	path, column, _ := walk(doc.Content[0], "")
+	return path, column
+}
+
+// joinPath appends a mapping key to a YAML path.
+func joinPath(path string, keys ...string) string {
+	for _, key := range keys {
+		if path != "" {
+			path += "."
+		}
+		path += key
+	}
+	return path
+}
+
+// splitPath splits a path like workspaces.app.pre[0] into mapping keys and
+// sequence indexes.
+func splitPath(path string) []string {
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		for {
+			open := strings.IndexByte(part, '[')
+			if open < 0 {
+				break
+			}
+			if open > 0 {
+				segments = append(segments, part[:open])
+			}
+			end := strings.IndexByte(part, ']')
+			if end < open {
+				break
+			}
+			segments = append(segments, part[open+1:end])
+			part = part[end+1:]
+		}
+		if part != "" {
+			segments = append(segments, part)
+		}
+	}
+	return segments
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
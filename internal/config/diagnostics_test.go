@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseReportsAllProblems(t *testing.T) {
+	data := []byte(`version: "1.0"
+pre:
+  - description: missing command
+workspaces:
+  app:
+    executor: k8s
+    tasks:
+      build:
+        description: nothing to run
+      serve:
+        command: ["npm", "start"]
+        executor: docker-run
+  empty:
+    path: ./empty
+`)
+
+	_, err := Parse("doctrus.yml", data)
+	diagnostics, ok := AsDiagnostics(err)
+	if !ok {
+		t.Fatalf("Parse() error = %v, want Diagnostics", err)
+	}
+
+	want := Diagnostics{
+		{File: "doctrus.yml", Line: 3, Column: 5, Path: "pre[0]", Message: "pre[0]: command is required"},
+		{File: "doctrus.yml", Line: 6, Column: 5, Path: "workspaces.app.executor", Message: `workspace app: unknown executor "k8s" (expected one of local, compose-exec, docker-run, remote)`},
+		{File: "doctrus.yml", Line: 8, Column: 7, Path: "workspaces.app.tasks.build", Message: "workspace app, task build: command is required unless task has dependencies (compound task)"},
+		{File: "doctrus.yml", Line: 10, Column: 7, Path: "workspaces.app.tasks.serve", Message: "workspace app, task serve: executor docker-run requires an image"},
+		{File: "doctrus.yml", Line: 13, Column: 3, Path: "workspaces.empty", Message: "workspace empty: at least one task is required"},
+	}
+	if !reflect.DeepEqual(diagnostics, want) {
+		t.Fatalf("diagnostics =\n%v\nwant\n%v", diagnostics, want)
+	}
+
+	if got := diagnostics[0].String(); got != "doctrus.yml:3:5: pre[0]: command is required" {
+		t.Fatalf("String() = %q", got)
+	}
+}
+
+func TestParseTypeErrors(t *testing.T) {
+	data := []byte(`version: "1.0"
+workspaces:
+  app:
+    tasks:
+      build:
+        command: ["make"]
+        cache: sometimes
+      test:
+        depends_on: build
+`)
+
+	_, err := Parse("doctrus.yml", data)
+	diagnostics, ok := AsDiagnostics(err)
+	if !ok {
+		t.Fatalf("Parse() error = %v, want Diagnostics", err)
+	}
+	if len(diagnostics) != 2 {
+		t.Fatalf("got %d diagnostics, want 2: %v", len(diagnostics), diagnostics)
+	}
+
+	wantPaths := []string{"workspaces.app.tasks.build.cache", "workspaces.app.tasks.test.depends_on"}
+	wantLines := []int{7, 9}
+	for i, diagnostic := range diagnostics {
+		if diagnostic.Path != wantPaths[i] || diagnostic.Line != wantLines[i] {
+			t.Errorf("diagnostic %d = %+v, want path %s on line %d", i, diagnostic, wantPaths[i], wantLines[i])
+		}
+		if !strings.Contains(diagnostic.Message, "cannot unmarshal") {
+			t.Errorf("diagnostic %d message = %q", i, diagnostic.Message)
+		}
+	}
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	_, err := Parse("doctrus.yml", []byte("version: \"1.0\"\nworkspaces:\n  app: [\n"))
+	diagnostics, ok := AsDiagnostics(err)
+	if !ok || len(diagnostics) != 1 {
+		t.Fatalf("Parse() error = %v, want one diagnostic", err)
+	}
+	if diagnostics[0].Line == 0 {
+		t.Fatalf("diagnostic %+v has no line", diagnostics[0])
+	}
+}
+
+func TestLoadReturnsDiagnostics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doctrus.yml")
+	data := "workspaces:\n  app:\n    tasks: {}\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := Load(path)
+	diagnostics, ok := AsDiagnostics(err)
+	if !ok {
+		t.Fatalf("Load() error = %v, want Diagnostics", err)
+	}
+	if len(diagnostics) != 2 || diagnostics[0].Path != "version" || diagnostics[1].Line != 2 {
+		t.Fatalf("diagnostics = %+v", diagnostics)
+	}
+	if diagnostics[1].File != path {
+		t.Fatalf("File = %q, want %q", diagnostics[1].File, path)
+	}
+}
@@ -0,0 +1,271 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Environment layers a task's variables are merged from
+const (
+	// EnvLayerGlobal is the top-level env of doctrus.yml
+	EnvLayerGlobal = "global"
+	// EnvLayerEnvFile holds the variables read from env_file entries
+	EnvLayerEnvFile = "env_file"
+	// EnvLayerWorkspace is the env of the task's workspace
+	EnvLayerWorkspace = "workspace"
+	// EnvLayerTask is the env of the task itself
+	EnvLayerTask = "task"
+	// EnvLayerCLI holds the variables passed with --env
+	EnvLayerCLI = "cli"
+)
+
+// EnvLayerNames lists the environment layers in their default merge order,
+// from lowest to highest precedence.
+func EnvLayerNames() []string {
+	return []string{EnvLayerGlobal, EnvLayerEnvFile, EnvLayerWorkspace, EnvLayerTask, EnvLayerCLI}
+}
+
+// EnvMerge configures how the environment layers are combined. Order lists
+// every layer from lowest to highest precedence; Append names PATH-like
+// variables whose values are joined with the OS path list separator, highest
+// precedence first, instead of replaced.
+type EnvMerge struct {
+	Order  []string `yaml:"order,omitempty" json:"order,omitempty"`
+	Append []string `yaml:"append,omitempty" json:"append,omitempty"`
+}
+
+// EnvOrder returns the configured layer order, or the default one.
+func (c *Config) EnvOrder() []string {
+	if c.EnvMerge != nil && len(c.EnvMerge.Order) > 0 {
+		return c.EnvMerge.Order
+	}
+	return EnvLayerNames()
+}
+
+// EnvAppend returns the variables merged with list-append semantics.
+func (c *Config) EnvAppend() []string {
+	if c.EnvMerge == nil {
+		return nil
+	}
+	return c.EnvMerge.Append
+}
+
+// EnvLayer is one source of variables for a task.
+type EnvLayer struct {
+	Name string
+	Vars map[string]string
+}
+
+// EnvVar is a resolved task variable. Sources lists the layers that
+// contributed to its value, in merge order.
+type EnvVar struct {
+	Name    string
+	Value   string
+	Sources []string
+}
+
+// EnvLayers collects the environment layers of a task. Global env files are
+// relative to baseDir, and those of the workspace and task to workspaceDir;
+// global ones are read first and the task's last, each file overriding the
+// ones before it. Missing files are skipped, so local overrides such as
+// .env.local need not exist. cliEnv holds the --env variables.
+func (c *Config) EnvLayers(baseDir, workspaceName, taskName, workspaceDir string, cliEnv map[string]string) ([]EnvLayer, error) {
+	workspace, exists := c.GetWorkspace(workspaceName)
+	if !exists {
+		return nil, fmt.Errorf("workspace %s not found", workspaceName)
+	}
+	task, exists := c.GetTask(workspaceName, taskName)
+	if !exists {
+		return nil, fmt.Errorf("task %s not found in workspace %s", taskName, workspaceName)
+	}
+
+	fileVars := make(map[string]string)
+	for _, file := range envFiles(baseDir, c.EnvFile, workspaceDir, slices.Concat(workspace.EnvFile, task.EnvFile)) {
+		vars, err := ReadEnvFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range vars {
+			fileVars[key] = value
+		}
+	}
+
+	return []EnvLayer{
+		{Name: EnvLayerGlobal, Vars: c.Env},
+		{Name: EnvLayerEnvFile, Vars: fileVars},
+		{Name: EnvLayerWorkspace, Vars: workspace.Env},
+		{Name: EnvLayerTask, Vars: task.Env},
+		{Name: EnvLayerCLI, Vars: cliEnv},
+	}, nil
+}
+
+func envFiles(baseDir string, global []string, workspaceDir string, local []string) []string {
+	var files []string
+	for _, file := range global {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(baseDir, file)
+		}
+		files = append(files, file)
+	}
+	for _, file := range local {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(workspaceDir, file)
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// ResolveEnv merges the environment layers of a task according to
+// env_merge, sorted by variable name.
+func (c *Config) ResolveEnv(baseDir, workspaceName, taskName, workspaceDir string, cliEnv map[string]string) ([]EnvVar, error) {
+	layers, err := c.EnvLayers(baseDir, workspaceName, taskName, workspaceDir, cliEnv)
+	if err != nil {
+		return nil, err
+	}
+	return MergeEnv(layers, c.EnvOrder(), c.EnvAppend()), nil
+}
+
+// MergeEnv merges layers in order, lowest precedence first. Later layers
+// replace the values of earlier ones, except for the appendVars, whose
+// values are joined with the OS path list separator with the later value
+// first. Layers missing from order are ignored.
+func MergeEnv(layers []EnvLayer, order []string, appendVars []string) []EnvVar {
+	byName := make(map[string]EnvLayer, len(layers))
+	for _, layer := range layers {
+		byName[layer.Name] = layer
+	}
+	appends := make(map[string]bool, len(appendVars))
+	for _, name := range appendVars {
+		appends[name] = true
+	}
+
+	merged := make(map[string]*EnvVar)
+	for _, layerName := range order {
+		layer := byName[layerName]
+		for key, value := range layer.Vars {
+			variable, ok := merged[key]
+			switch {
+			case !ok:
+				merged[key] = &EnvVar{Name: key, Value: value, Sources: []string{layerName}}
+			case appends[key]:
+				variable.Value = JoinEnvList(value, variable.Value)
+				variable.Sources = append(variable.Sources, layerName)
+			default:
+				variable.Value = value
+				variable.Sources = []string{layerName}
+			}
+		}
+	}
+
+	vars := make([]EnvVar, 0, len(merged))
+	for _, variable := range merged {
+		vars = append(vars, *variable)
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
+// JoinEnvList joins the non-empty values of a PATH-like variable with the OS
+// path list separator.
+func JoinEnvList(values ...string) string {
+	var parts []string
+	for _, value := range values {
+		if value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, string(os.PathListSeparator))
+}
+
+// EnvMap returns the resolved variables as a map.
+func EnvMap(vars []EnvVar) map[string]string {
+	env := make(map[string]string, len(vars))
+	for _, variable := range vars {
+		env[variable.Name] = variable.Value
+	}
+	return env
+}
+
+// ReadEnvFile parses a dotenv file of KEY=VALUE lines. Blank lines and lines
+// starting with # are skipped, an export prefix is allowed and values may be
+// wrapped in single or double quotes.
+func ReadEnvFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	defer file.Close()
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNumber)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read env file %s: %w", path, err)
+	}
+
+	return vars, nil
+}
+
+// envMergeProblems checks that env_merge names every layer exactly once.
+func (c *Config) envMergeProblems(add func(path, format string, args ...any)) {
+	if c.EnvMerge == nil {
+		return
+	}
+
+	if len(c.EnvMerge.Order) > 0 {
+		known := make(map[string]bool)
+		for _, name := range EnvLayerNames() {
+			known[name] = true
+		}
+		seen := make(map[string]bool)
+		for i, name := range c.EnvMerge.Order {
+			path := fmt.Sprintf("env_merge.order[%d]", i)
+			switch {
+			case !known[name]:
+				add(path, "env_merge.order: unknown layer %q (expected %s)", name, strings.Join(EnvLayerNames(), ", "))
+			case seen[name]:
+				add(path, "env_merge.order: layer %q is listed more than once", name)
+			}
+			seen[name] = true
+		}
+		for _, name := range EnvLayerNames() {
+			if !seen[name] {
+				add("env_merge.order", "env_merge.order: layer %q is missing", name)
+			}
+		}
+	}
+
+	for i, name := range c.EnvMerge.Append {
+		if name == "" {
+			add(fmt.Sprintf("env_merge.append[%d]", i), "env_merge.append[%d]: variable name is required", i)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeEnv(t *testing.T) {
+	sep := string(os.PathListSeparator)
+	layers := []EnvLayer{
+		{Name: EnvLayerGlobal, Vars: map[string]string{"LEVEL": "global", "PATH": "/global/bin", "ONLY_GLOBAL": "1"}},
+		{Name: EnvLayerEnvFile, Vars: map[string]string{"LEVEL": "env_file"}},
+		{Name: EnvLayerWorkspace, Vars: map[string]string{"LEVEL": "workspace", "PATH": "/workspace/bin"}},
+		{Name: EnvLayerTask, Vars: map[string]string{"LEVEL": "task"}},
+		{Name: EnvLayerCLI, Vars: map[string]string{"LEVEL": "cli"}},
+	}
+
+	tests := []struct {
+		name       string
+		order      []string
+		appendVars []string
+		want       []EnvVar
+	}{
+		{
+			name:  "default order",
+			order: EnvLayerNames(),
+			want: []EnvVar{
+				{Name: "LEVEL", Value: "cli", Sources: []string{EnvLayerCLI}},
+				{Name: "ONLY_GLOBAL", Value: "1", Sources: []string{EnvLayerGlobal}},
+				{Name: "PATH", Value: "/workspace/bin", Sources: []string{EnvLayerWorkspace}},
+			},
+		},
+		{
+			name:       "custom order with append",
+			order:      []string{EnvLayerCLI, EnvLayerTask, EnvLayerWorkspace, EnvLayerEnvFile, EnvLayerGlobal},
+			appendVars: []string{"PATH"},
+			want: []EnvVar{
+				{Name: "LEVEL", Value: "global", Sources: []string{EnvLayerGlobal}},
+				{Name: "ONLY_GLOBAL", Value: "1", Sources: []string{EnvLayerGlobal}},
+				{Name: "PATH", Value: "/global/bin" + sep + "/workspace/bin", Sources: []string{EnvLayerWorkspace, EnvLayerGlobal}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeEnv(layers, tt.order, tt.appendVars)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("MergeEnv() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := `# comment
+PLAIN=value
+export EXPORTED=yes
+DOUBLE="with spaces"
+SINGLE='quoted'
+EMPTY=
+
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+
+	got, err := ReadEnvFile(path)
+	if err != nil {
+		t.Fatalf("ReadEnvFile() error = %v", err)
+	}
+	want := map[string]string{
+		"PLAIN":    "value",
+		"EXPORTED": "yes",
+		"DOUBLE":   "with spaces",
+		"SINGLE":   "quoted",
+		"EMPTY":    "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ReadEnvFile() = %v, want %v", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte("PLAIN=value\nnot a variable\n"), 0o644); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+	if _, err := ReadEnvFile(path); err == nil || !strings.Contains(err.Error(), ".env:2: expected KEY=VALUE") {
+		t.Fatalf("ReadEnvFile() error = %v, want line 2 reported", err)
+	}
+}
+
+func TestResolveEnvReadsEnvFiles(t *testing.T) {
+	baseDir := t.TempDir()
+	workspaceDir := filepath.Join(baseDir, "app")
+	if err := os.MkdirAll(workspaceDir, 0o755); err != nil {
+		t.Fatalf("failed to create workspace dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, ".env"), []byte("SHARED=root\nROOT_ONLY=1\n"), 0o644); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workspaceDir, ".env"), []byte("SHARED=app\nAPP_ONLY=1\n"), 0o644); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workspaceDir, ".env.local"), []byte("SHARED=local\n"), 0o644); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+
+	cfg := &Config{
+		Version: "1.0",
+		Env:     map[string]string{"SHARED": "global"},
+		EnvFile: []string{".env"},
+		Workspaces: map[string]Workspace{
+			"app": {
+				Path:    "./app",
+				EnvFile: []string{".env"},
+				Tasks: map[string]Task{
+					"build": {Command: []string{"build"}, Env: map[string]string{"TASK": "1"}, EnvFile: []string{".env.local"}},
+				},
+			},
+		},
+	}
+
+	vars, err := cfg.ResolveEnv(baseDir, "app", "build", workspaceDir, map[string]string{"TASK": "2"})
+	if err != nil {
+		t.Fatalf("ResolveEnv() error = %v", err)
+	}
+	want := map[string]string{"SHARED": "local", "ROOT_ONLY": "1", "APP_ONLY": "1", "TASK": "2"}
+	if got := EnvMap(vars); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ResolveEnv() = %v, want %v", got, want)
+	}
+
+	// Layering over a file that does not exist skips it
+	cfg.EnvFile = []string{"missing.env"}
+	vars, err = cfg.ResolveEnv(baseDir, "app", "build", workspaceDir, nil)
+	if err != nil {
+		t.Fatalf("ResolveEnv() with a missing env file error = %v", err)
+	}
+	want = map[string]string{"SHARED": "local", "APP_ONLY": "1", "TASK": "1"}
+	if got := EnvMap(vars); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ResolveEnv() with a missing env file = %v, want %v", got, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(workspaceDir, ".env.local"), []byte("not a variable\n"), 0o644); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+	if _, err := cfg.ResolveEnv(baseDir, "app", "build", workspaceDir, nil); err == nil {
+		t.Fatal("ResolveEnv() should fail for an invalid env file")
+	}
+}
+
+func TestEnvMergeValidation(t *testing.T) {
+	base := func(merge *EnvMerge) *Config {
+		return &Config{
+			Version:  "1.0",
+			EnvMerge: merge,
+			Workspaces: map[string]Workspace{
+				"app": {Tasks: map[string]Task{"build": {Command: []string{"build"}}}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		merge   *EnvMerge
+		wantErr string
+	}{
+		{name: "default", merge: nil},
+		{name: "append only", merge: &EnvMerge{Append: []string{"PATH"}}},
+		{name: "full order", merge: &EnvMerge{Order: []string{"cli", "task", "workspace", "env_file", "global"}}},
+		{
+			name:    "unknown layer",
+			merge:   &EnvMerge{Order: []string{"global", "env_file", "workspace", "task", "cli", "shell"}},
+			wantErr: `env_merge.order: unknown layer "shell" (expected global, env_file, workspace, task, cli)`,
+		},
+		{
+			name:    "missing layer",
+			merge:   &EnvMerge{Order: []string{"global", "workspace", "task", "cli"}},
+			wantErr: `env_merge.order: layer "env_file" is missing`,
+		},
+		{
+			name:    "duplicate layer",
+			merge:   &EnvMerge{Order: []string{"global", "env_file", "workspace", "task", "cli", "task"}},
+			wantErr: `env_merge.order: layer "task" is listed more than once`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := base(tt.merge).validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
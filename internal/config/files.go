@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// FileRule is a make-style rule of depends_on_files: the task produces the
+// files matching Targets from those matching Sources, and only needs to run
+// when a target is missing or older than a source. Patterns are globs
+// relative to the workspace.
+type FileRule struct {
+	Sources []string
+	Targets []string
+}
+
+// ParseFileRule parses a rule such as "proto/*.proto -> gen/**". Either side
+// may list several space-separated globs.
+func ParseFileRule(rule string) (FileRule, error) {
+	sources, targets, ok := strings.Cut(rule, "->")
+	if !ok {
+		return FileRule{}, fmt.Errorf("invalid rule %q (expected sources -> targets)", rule)
+	}
+	parsed := FileRule{Sources: strings.Fields(sources), Targets: strings.Fields(targets)}
+	if len(parsed.Sources) == 0 || len(parsed.Targets) == 0 {
+		return FileRule{}, fmt.Errorf("invalid rule %q (expected sources -> targets)", rule)
+	}
+	for _, pattern := range append(parsed.Sources, parsed.Targets...) {
+		if !doublestar.ValidatePattern(pattern) {
+			return FileRule{}, fmt.Errorf("invalid rule %q: bad glob %q", rule, pattern)
+		}
+	}
+	return parsed, nil
+}
+
+// FileRules parses the task's depends_on_files rules.
+func (t *Task) FileRules() ([]FileRule, error) {
+	rules := make([]FileRule, 0, len(t.DependsOnFiles))
+	for _, rule := range t.DependsOnFiles {
+		parsed, err := ParseFileRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, parsed)
+	}
+	return rules, nil
+}
+
+// fileRuleProblems reports depends_on_files rules that cannot be parsed.
+func fileRuleProblems(prefix, path string, task Task, add func(path, format string, args ...any)) {
+	if len(task.DependsOnFiles) > 0 && len(task.Command) == 0 {
+		add(path, "%s: depends_on_files is only supported for tasks with a command", prefix)
+	}
+	for i, rule := range task.DependsOnFiles {
+		if _, err := ParseFileRule(rule); err != nil {
+			add(fmt.Sprintf("%s[%d]", path, i), "%s: depends_on_files[%d]: %v", prefix, i, err)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFileRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    FileRule
+		wantErr bool
+	}{
+		{rule: "proto/*.proto -> gen/**", want: FileRule{Sources: []string{"proto/*.proto"}, Targets: []string{"gen/**"}}},
+		{rule: "a.y b.l->parser.c lexer.c", want: FileRule{Sources: []string{"a.y", "b.l"}, Targets: []string{"parser.c", "lexer.c"}}},
+		{rule: "proto/*.proto", wantErr: true},
+		{rule: " -> gen/**", wantErr: true},
+		{rule: "src/** ->", wantErr: true},
+		{rule: "src/[a -> out", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := ParseFileRule(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFileRule(%q) error = %v, wantErr %v", tt.rule, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseFileRule(%q) = %+v, want %+v", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
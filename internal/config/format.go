@@ -0,0 +1,215 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format rewrites a doctrus.yml document in canonical form: settings in the
+// order of the fields of Config and the types it holds, with the blocks of
+// named workspaces, templates, profiles, plugins and tasks after the others;
+// tasks and templates sorted by name; lists of strings on one line, quoted
+// alike; single-quoted strings converted to double quotes; and two-space
+// indentation. Comments are preserved.
+func Format(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	if len(doc.Content) == 0 {
+		return data, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config root must be a mapping")
+	}
+
+	orderKeys(root, reflect.TypeOf(Config{}))
+	if templates := mappingValue(root, "templates"); templates != nil {
+		sortMapping(templates)
+	}
+	if workspaces := mappingValue(root, "workspaces"); workspaces != nil && workspaces.Kind == yaml.MappingNode {
+		for i := 1; i < len(workspaces.Content); i += 2 {
+			if tasks := mappingValue(workspaces.Content[i], "tasks"); tasks != nil {
+				sortMapping(tasks)
+			}
+		}
+	}
+
+	normalizeLists(&doc)
+	normalizeQuoting(&doc)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to format config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to format config: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// mappingValue returns the value node for key in a mapping node.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// orderKeys orders the settings of node, a value of type t, like the fields
+// of t, recursing into their values. Fields holding maps of named blocks,
+// such as workspaces or tasks, follow the other settings, a merge key comes
+// first and keys t doesn't define keep their order at the end.
+func orderKeys(node *yaml.Node, t reflect.Type) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		rank := make(map[string]int)
+		fields := make(map[string]reflect.Type)
+		var named []string
+		for i := 0; i < t.NumField(); i++ {
+			name := yamlName(t.Field(i))
+			if name == "" {
+				continue
+			}
+			fields[name] = t.Field(i).Type
+			if isNamedBlocks(t.Field(i).Type) {
+				named = append(named, name)
+				continue
+			}
+			rank[name] = len(rank) + 1
+		}
+		for _, name := range named {
+			rank[name] = len(rank) + 1
+		}
+		rank["<<"] = 0
+
+		order := func(key string) int {
+			if position, known := rank[key]; known {
+				return position
+			}
+			return len(rank) + 1
+		}
+		sortPairs(node, func(a, b *yaml.Node) bool { return order(a.Value) < order(b.Value) })
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if field, known := fields[node.Content[i].Value]; known {
+				orderKeys(node.Content[i+1], field)
+			}
+		}
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Map:
+		for i := 1; i < len(node.Content); i += 2 {
+			orderKeys(node.Content[i], t.Elem())
+		}
+	case node.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+		for _, item := range node.Content {
+			orderKeys(item, t.Elem())
+		}
+	}
+}
+
+// isNamedBlocks reports whether t maps names to settings, like workspaces.
+func isNamedBlocks(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Elem().Kind() == reflect.Struct
+}
+
+// sortMapping orders a mapping node's entries by key, keeping each key's
+// comments attached to it.
+func sortMapping(node *yaml.Node) {
+	sortPairs(node, func(a, b *yaml.Node) bool { return a.Value < b.Value })
+}
+
+// sortPairs stably orders a mapping node's entries by their keys with less.
+func sortPairs(node *yaml.Node, less func(a, b *yaml.Node) bool) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	type pair struct{ key, value *yaml.Node }
+	pairs := make([]pair, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, pair{node.Content[i], node.Content[i+1]})
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return less(pairs[i].key, pairs[j].key)
+	})
+
+	content := make([]*yaml.Node, 0, len(node.Content))
+	for _, p := range pairs {
+		content = append(content, p.key, p.value)
+	}
+	node.Content = content
+}
+
+// normalizeLists writes lists of one-line strings, numbers and booleans in
+// flow style, as ["npm", "test"], and double-quotes every string of such a
+// list when one of them is quoted or needs quotes in a flow list. Lists
+// holding comments keep their style.
+func normalizeLists(node *yaml.Node) {
+	for _, child := range node.Content {
+		normalizeLists(child)
+	}
+	if node.Kind != yaml.SequenceNode || len(node.Content) == 0 {
+		return
+	}
+
+	quoted := false
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode || item.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 ||
+			strings.Contains(item.Value, "\n") || item.HeadComment != "" || item.LineComment != "" || item.FootComment != "" {
+			return
+		}
+		quoted = quoted || item.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) != 0 ||
+			item.Tag == "!!str" && needsQuotes(item.Value)
+	}
+
+	node.Style = yaml.FlowStyle
+	for _, item := range node.Content {
+		if quoted && item.Tag == "!!str" {
+			item.Style = yaml.DoubleQuotedStyle
+		}
+	}
+}
+
+// needsQuotes reports whether the encoder would quote value as a plain
+// string in a flow list, such as "a, b" or "true".
+func needsQuotes(value string) bool {
+	list := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: value},
+	}}
+	out, err := yaml.Marshal(list)
+	return err != nil || !bytes.HasPrefix(out, []byte("["+value))
+}
+
+// normalizeQuoting converts single-quoted strings to double quotes. It also
+// clears the tag of merge keys, which the encoder would otherwise write out
+// as !!merge <<.
+func normalizeQuoting(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.Style&yaml.SingleQuotedStyle != 0 {
+		node.Style = node.Style&^yaml.SingleQuotedStyle | yaml.DoubleQuotedStyle
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!merge" {
+		node.Tag = ""
+	}
+	for _, child := range node.Content {
+		normalizeQuoting(child)
+	}
+}
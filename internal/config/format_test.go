@@ -0,0 +1,145 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	input := `version: '1.0'   # schema version
+workspaces:
+    web:
+        path: ./web
+        tasks:
+            # runs last alphabetically
+            test:
+                command: ['npm', "test"]
+            build:
+                command: ["npm", "run", "build"]
+                description: 'Build it'
+`
+
+	want := `version: "1.0" # schema version
+workspaces:
+  web:
+    path: ./web
+    tasks:
+      build:
+        command: ["npm", "run", "build"]
+        description: "Build it"
+      # runs last alphabetically
+      test:
+        command: ["npm", "test"]
+`
+
+	got, err := Format([]byte(input))
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("Format() =\n%s\nwant:\n%s", got, want)
+	}
+
+	again, err := Format(got)
+	if err != nil {
+		t.Fatalf("Format() second pass error = %v", err)
+	}
+	if string(again) != string(got) {
+		t.Fatalf("Format() is not idempotent:\n%s", again)
+	}
+}
+
+func TestFormatInvalid(t *testing.T) {
+	if _, err := Format([]byte("- just\n- a list\n")); err == nil || !strings.Contains(err.Error(), "mapping") {
+		t.Fatalf("Format() error = %v, want mapping error", err)
+	}
+	if _, err := Format([]byte("version: [unclosed")); err == nil {
+		t.Fatal("Format() expected parse error")
+	}
+}
+
+func TestFormatOrdersSettings(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "task fields",
+			input: "workspaces:\n  web:\n    tasks:\n      build:\n        description: Build it\n        command: [\"npm\", \"run\", \"build\"]\n    path: ./web\nversion: \"1.0\"\n",
+			want:  "version: \"1.0\"\nworkspaces:\n  web:\n    path: ./web\n    tasks:\n      build:\n        command: [\"npm\", \"run\", \"build\"]\n        description: Build it\n",
+		},
+		{
+			name:  "merge key first",
+			input: "templates:\n  npm: &npm\n    cache: true\nworkspaces:\n  web:\n    tasks:\n      build:\n        command: [\"make\"]\n        <<: *npm\n",
+			want:  "templates:\n  npm: &npm\n    cache: true\nworkspaces:\n  web:\n    tasks:\n      build:\n        <<: *npm\n        command: [\"make\"]\n",
+		},
+		{
+			name:  "unknown keys last",
+			input: "x-notes: keep me\nversion: \"1.0\"\n",
+			want:  "version: \"1.0\"\nx-notes: keep me\n",
+		},
+		{
+			name:  "block lists",
+			input: "workspaces:\n  web:\n    tasks:\n      test:\n        command:\n          - npm\n          - test\n        tags:\n          - fast\n",
+			want:  "workspaces:\n  web:\n    tasks:\n      test:\n        command: [npm, test]\n        tags: [fast]\n",
+		},
+		{
+			name:  "mixed quoting",
+			input: "workspaces:\n  web:\n    tasks:\n      test:\n        command: ['npm', test, --watch=false]\n",
+			want:  "workspaces:\n  web:\n    tasks:\n      test:\n        command: [\"npm\", \"test\", \"--watch=false\"]\n",
+		},
+		{
+			name:  "items needing quotes",
+			input: "workspaces:\n  web:\n    tasks:\n      test:\n        command:\n          - sh\n          - -c\n          - echo a, b\n        tags:\n          - \"yes\"\n",
+			want:  "workspaces:\n  web:\n    tasks:\n      test:\n        command: [\"sh\", \"-c\", \"echo a, b\"]\n        tags: [\"yes\"]\n",
+		},
+		{
+			name:  "lists with comments",
+			input: "workspaces:\n  web:\n    tasks:\n      test:\n        command:\n          - npm\n          - test # the unit tests\n",
+			want:  "workspaces:\n  web:\n    tasks:\n      test:\n        command:\n          - npm\n          - test # the unit tests\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("Format() =\n%s\nwant:\n%s", got, tt.want)
+			}
+			again, err := Format(got)
+			if err != nil || string(again) != string(got) {
+				t.Fatalf("Format() is not idempotent:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestFormatIsIdempotent(t *testing.T) {
+	inputs := []string{
+		"command:\n  - sh\n  - -c\n  - echo a, b\n",
+		"command:\n  - sh\n  - -c\n  - echo a: b # a comment\n",
+		"command:\n  - echo\n  - x # y\n  - '{z}'\n",
+		"tags:\n  - on\n  - no\n  - 'null'\n  - \"\"\n",
+		"inputs:\n  - src/**/*.go\n  - '!vendor/**'\n  - '{a,b}.txt'\n",
+		"args:\n  - 1\n  - 1.5\n  - true\n  - it's\n  - say \"hi\"\n",
+		"command: [sh, -c, 'echo [x]']\n",
+	}
+
+	for _, input := range inputs {
+		once, err := Format([]byte(input))
+		if err != nil {
+			t.Fatalf("Format(%q) error = %v", input, err)
+		}
+		twice, err := Format(once)
+		if err != nil {
+			t.Fatalf("Format(Format(%q)) error = %v", input, err)
+		}
+		if string(twice) != string(once) {
+			t.Errorf("Format(Format(%q)) =\n%s\nwant Format(x) =\n%s", input, twice, once)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// WorkspaceImport registers the packages of a package manager's workspace
+// definition as doctrus workspaces when the config is loaded, so doctrus.yml
+// follows the repository layout. From names the definition file, relative to
+// doctrus.yml: pnpm-workspace.yaml, a package.json with a workspaces field,
+// or go.work. Every imported workspace gets Preset and a copy of Tasks.
+type WorkspaceImport struct {
+	From   string          `yaml:"from" json:"from"`
+	Preset string          `yaml:"preset,omitempty" json:"preset,omitempty"`
+	Tasks  map[string]Task `yaml:"tasks,omitempty" json:"tasks,omitempty"`
+}
+
+// importedPackage is a directory a workspace definition lists.
+type importedPackage struct {
+	name string
+	dir  string
+}
+
+// applyImports adds a workspace for every package of the import_workspaces
+// definitions. Workspaces written in doctrus.yml win over imported ones,
+// whether they share the name or the directory, and two definitions
+// importing the same name is an error. Imported workspaces may have no
+// tasks, which providers can still add.
+func (c *Config) applyImports(configDir string) error {
+	if len(c.Imports) == 0 {
+		return nil
+	}
+
+	configuredDirs := make(map[string]bool)
+	for _, workspace := range c.Workspaces {
+		configuredDirs[resolveDir(configDir, workspace.Path)] = true
+	}
+	if c.Workspaces == nil {
+		c.Workspaces = make(map[string]Workspace)
+	}
+
+	importedBy := make(map[string]string)
+	for _, imp := range c.Imports {
+		if imp.From == "" {
+			return fmt.Errorf("import_workspaces: from is required")
+		}
+		packages, err := readWorkspaceDefinition(filepath.Join(configDir, imp.From))
+		if err != nil {
+			return fmt.Errorf("import_workspaces %s: %w", imp.From, err)
+		}
+
+		for _, pkg := range packages {
+			if previous, dup := importedBy[pkg.name]; dup {
+				return fmt.Errorf("import_workspaces %s: workspace %s already imported from %s", imp.From, pkg.name, previous)
+			}
+			importedBy[pkg.name] = imp.From
+			if _, exists := c.Workspaces[pkg.name]; exists || configuredDirs[pkg.dir] {
+				continue
+			}
+
+			path := pkg.dir
+			if rel, err := filepath.Rel(configDir, pkg.dir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				path = filepath.ToSlash(rel)
+			}
+			tasks := make(map[string]Task, len(imp.Tasks))
+			maps.Copy(tasks, imp.Tasks)
+			c.Workspaces[pkg.name] = Workspace{Path: path, Preset: imp.Preset, Tasks: tasks}
+			if c.imported == nil {
+				c.imported = make(map[string]bool)
+			}
+			c.imported[pkg.name] = true
+		}
+	}
+	return nil
+}
+
+func resolveDir(configDir, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(configDir, path)
+}
+
+// readWorkspaceDefinition lists the packages of a workspace definition file,
+// sorted by name. Its format follows from the file name.
+func readWorkspaceDefinition(file string) ([]importedPackage, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace definition: %w", err)
+	}
+	dir := filepath.Dir(file)
+
+	var packages []importedPackage
+	switch name := filepath.Base(file); name {
+	case "pnpm-workspace.yaml", "pnpm-workspace.yml":
+		var definition struct {
+			Packages []string `yaml:"packages"`
+		}
+		if err := yaml.Unmarshal(data, &definition); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		packages, err = nodePackages(dir, definition.Packages)
+	case "package.json":
+		var patterns []string
+		patterns, err = packageJSONWorkspaces(data)
+		if err == nil {
+			packages, err = nodePackages(dir, patterns)
+		}
+	case "go.work":
+		packages, err = goWorkModules(dir, data)
+	default:
+		return nil, fmt.Errorf("unsupported workspace definition %s (expected pnpm-workspace.yaml, package.json or go.work)", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(packages, func(i, j int) bool { return packages[i].name < packages[j].name })
+	return packages, nil
+}
+
+// packageJSONWorkspaces returns the workspaces globs of a package.json, given
+// as a list (npm, Yarn, Bun) or under packages (Yarn classic).
+func packageJSONWorkspaces(data []byte) ([]string, error) {
+	var manifest struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+	if len(manifest.Workspaces) == 0 {
+		return nil, fmt.Errorf("package.json has no workspaces field")
+	}
+
+	var patterns []string
+	if err := json.Unmarshal(manifest.Workspaces, &patterns); err == nil {
+		return patterns, nil
+	}
+	var yarn struct {
+		Packages []string `json:"packages"`
+	}
+	if err := json.Unmarshal(manifest.Workspaces, &yarn); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json workspaces: %w", err)
+	}
+	return yarn.Packages, nil
+}
+
+// nodePackages resolves workspace globs, where patterns starting with !
+// exclude directories, to the directories with a package.json. Packages are
+// named by their package.json name, or their directory when unnamed.
+func nodePackages(dir string, patterns []string) ([]importedPackage, error) {
+	var include, exclude []string
+	for _, pattern := range patterns {
+		if negated, ok := strings.CutPrefix(pattern, "!"); ok {
+			exclude = append(exclude, strings.TrimPrefix(negated, "./"))
+		} else {
+			include = append(include, strings.TrimPrefix(pattern, "./"))
+		}
+	}
+
+	seen := make(map[string]bool)
+	var packages []importedPackage
+	for _, pattern := range include {
+		matches, err := doublestar.Glob(os.DirFS(dir), strings.TrimSuffix(pattern, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspace pattern %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			if seen[match] || excluded(match, exclude) || strings.Contains(match, "node_modules") {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, match, "package.json"))
+			if err != nil {
+				continue
+			}
+			seen[match] = true
+
+			var manifest struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				return nil, fmt.Errorf("failed to parse %s/package.json: %w", match, err)
+			}
+			name := manifest.Name
+			if name == "" {
+				name = match
+			}
+			packages = append(packages, importedPackage{name: name, dir: filepath.Join(dir, filepath.FromSlash(match))})
+		}
+	}
+	return packages, nil
+}
+
+func excluded(dir string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := doublestar.Match(strings.TrimSuffix(pattern, "/"), dir); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// goWorkModules returns the modules of the use directives of a go.work file,
+// named by their directory relative to it, or by the directory's base name
+// for the go.work directory itself.
+func goWorkModules(dir string, data []byte) ([]importedPackage, error) {
+	var packages []importedPackage
+	inBlock := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+
+		var use string
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock:
+			use = line
+		case line == "use (":
+			inBlock = true
+		case strings.HasPrefix(line, "use "):
+			use = strings.TrimSpace(strings.TrimPrefix(line, "use "))
+		}
+		if use == "" {
+			continue
+		}
+
+		use = strings.Trim(use, "\"`")
+		name := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(use)), "./")
+		if name == "." {
+			name = filepath.Base(dir)
+		}
+		packages = append(packages, importedPackage{name: name, dir: resolveDir(dir, use)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read go.work: %w", err)
+	}
+	return packages, nil
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadWorkspaceDefinition(t *testing.T) {
+	packages := map[string]string{
+		"packages/ui/package.json":             `{"name": "@acme/ui"}`,
+		"packages/utils/package.json":          `{}`,
+		"packages/fixtures/package.json":       `{"name": "fixtures"}`,
+		"packages/docs/README.md":              "no package.json",
+		"apps/web/package.json":                `{"name": "web"}`,
+		"apps/web/node_modules/x/package.json": `{"name": "x"}`,
+	}
+
+	tests := []struct {
+		name  string
+		file  string
+		files map[string]string
+		want  map[string]string
+	}{
+		{
+			name: "pnpm",
+			file: "pnpm-workspace.yaml",
+			files: map[string]string{
+				"pnpm-workspace.yaml": "packages:\n  - 'packages/*'\n  - 'apps/**'\n  - '!packages/fixtures'\n",
+			},
+			want: map[string]string{"@acme/ui": "packages/ui", "packages/utils": "packages/utils", "web": "apps/web"},
+		},
+		{
+			name: "package.json",
+			file: "package.json",
+			files: map[string]string{
+				"package.json": `{"name": "root", "workspaces": ["./apps/*"]}`,
+			},
+			want: map[string]string{"web": "apps/web"},
+		},
+		{
+			name: "yarn classic",
+			file: "package.json",
+			files: map[string]string{
+				"package.json": `{"workspaces": {"packages": ["packages/ui"]}}`,
+			},
+			want: map[string]string{"@acme/ui": "packages/ui"},
+		},
+		{
+			name: "go.work",
+			file: "go.work",
+			files: map[string]string{
+				"go.work": "go 1.24\n\nuse . // the root module\n\nuse (\n\t./cmd/tool\n\t\"./services/api\"\n)\n",
+			},
+			want: map[string]string{"repo": ".", "cmd/tool": "cmd/tool", "services/api": "services/api"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "repo")
+			writeFiles(t, dir, packages)
+			writeFiles(t, dir, tt.files)
+
+			found, err := readWorkspaceDefinition(filepath.Join(dir, tt.file))
+			if err != nil {
+				t.Fatalf("readWorkspaceDefinition() error = %v", err)
+			}
+			got := make(map[string]string)
+			for _, pkg := range found {
+				rel, _ := filepath.Rel(dir, pkg.dir)
+				got[pkg.name] = filepath.ToSlash(rel)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("packages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := readWorkspaceDefinition(filepath.Join(t.TempDir(), "lerna.json")); err == nil {
+		t.Error("readWorkspaceDefinition() of a missing file succeeded")
+	}
+}
+
+func TestConfigLoadImports(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"pnpm-workspace.yaml":         "packages: ['packages/*']\n",
+		"packages/ui/package.json":    `{"name": "ui"}`,
+		"packages/api/package.json":   `{"name": "api"}`,
+		"packages/admin/package.json": `{"name": "admin"}`,
+		"doctrus.yml": `version: "1.0"
+import_workspaces:
+  - from: pnpm-workspace.yaml
+    preset: node
+    tasks:
+      build:
+        command: ["pnpm", "run", "build"]
+        cache: true
+workspaces:
+  ui:
+    path: packages/ui
+    tasks:
+      build:
+        command: ["vite", "build"]
+  backoffice:
+    path: packages/admin
+    tasks:
+      build:
+        command: ["make"]
+`,
+	})
+
+	cfg, _, err := Load(filepath.Join(dir, "doctrus.yml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	names := make([]string, 0, len(cfg.Workspaces))
+	for name := range cfg.Workspaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if want := []string{"api", "backoffice", "ui"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("workspaces = %v, want %v (configured workspaces win by name and path)", names, want)
+	}
+
+	api := cfg.Workspaces["api"]
+	if api.Path != "packages/api" || api.Preset != "node" {
+		t.Errorf("api = path %q, preset %q", api.Path, api.Preset)
+	}
+	if build := api.Tasks["build"]; strings.Join(build.Command, " ") != "pnpm run build" || len(build.Outputs) == 0 {
+		t.Errorf("api build = %+v, want the default task with preset outputs", build)
+	}
+	if build := cfg.Workspaces["ui"].Tasks["build"]; strings.Join(build.Command, " ") != "vite build" {
+		t.Errorf("ui build = %v, want the task from doctrus.yml", build.Command)
+	}
+}
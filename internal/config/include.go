@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// includeKeys are the keys an included file may set.
+var includeKeys = map[string]bool{"include": true, "workspaces": true}
+
+// includer merges the files listed under include into the document of
+// doctrus.yml, recording which file defined each workspace.
+type includer struct {
+	rootDir    string
+	workspaces *yaml.Node
+	// definedIn maps each workspace to the file defining it
+	definedIn map[string]string
+	// seen holds the files already merged, so each is read once
+	seen map[string]bool
+	// files lists the merged files relative to doctrus.yml
+	files []string
+}
+
+// applyIncludes merges the workspaces of every file listed under include
+// into doc, the document of the config file at path, following the include
+// lists of the included files too. Patterns may be globs and are relative to
+// the file listing them; a workspace path in an included file is relative to
+// that file's directory, which is also the path of a workspace without one.
+// The returned includer holds the merged files and the file each of their
+// workspaces came from, and is nil without include.
+func applyIncludes(path string, doc *yaml.Node) (*includer, error) {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if mappingValue(root, "include") == nil {
+		return nil, nil
+	}
+
+	workspaces := mappingValue(root, "workspaces")
+	switch {
+	case workspaces == nil || workspaces.Tag == "!!null":
+		workspaces = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(root, "workspaces", workspaces)
+	case workspaces.Kind != yaml.MappingNode:
+		// Decoding reports the invalid workspaces
+		return nil, nil
+	}
+
+	inc := &includer{
+		rootDir:    filepath.Dir(path),
+		workspaces: workspaces,
+		definedIn:  make(map[string]string),
+		seen:       map[string]bool{path: true},
+	}
+	for i := 0; i+1 < len(workspaces.Content); i += 2 {
+		inc.definedIn[workspaces.Content[i].Value] = path
+	}
+	if err := inc.follow(path, root); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(inc.files)
+	return inc, nil
+}
+
+// follow merges the files listed under include in node, the root mapping of
+// the config file at path.
+func (inc *includer) follow(path string, node *yaml.Node) error {
+	list := mappingValue(node, "include")
+	if list == nil || list.Tag == "!!null" {
+		return nil
+	}
+	if list.Kind != yaml.SequenceNode {
+		return fmt.Errorf("%s: include must be a list of files", path)
+	}
+
+	dir := filepath.Dir(path)
+	for _, item := range list.Content {
+		pattern := item.Value
+		if pattern == "" {
+			return fmt.Errorf("%s:%d: include: file is required", path, item.Line)
+		}
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		files, err := doublestar.FilepathGlob(pattern)
+		if err != nil {
+			return fmt.Errorf("%s:%d: include %s: %w", path, item.Line, item.Value, err)
+		}
+		if len(files) == 0 && !hasGlobMeta(item.Value) {
+			return fmt.Errorf("%s:%d: include %s: file not found", path, item.Line, item.Value)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			if err := inc.merge(file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// merge adds the workspaces of the included file at path.
+func (inc *includer) merge(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if inc.seen[path] {
+		return nil
+	}
+	inc.seen[path] = true
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read included config file %s: %w", path, err)
+	}
+	doc, err := parseDocument(path, data)
+	if err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: an included file must be a mapping with workspaces", path)
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if key := root.Content[i]; !includeKeys[key.Value] {
+			return fmt.Errorf("%s:%d: %s can only be set in the main config file; included files hold include and workspaces", path, key.Line, key.Value)
+		}
+	}
+	// Report type errors against the file they are in
+	if _, err := decodeNode(path, doc); err != nil {
+		return err
+	}
+
+	relFile, err := filepath.Rel(inc.rootDir, path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve included config file %s: %w", path, err)
+	}
+	inc.files = append(inc.files, filepath.ToSlash(relFile))
+	rel := filepath.Dir(relFile)
+	workspaces := mappingValue(root, "workspaces")
+	if workspaces != nil && workspaces.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(workspaces.Content); i += 2 {
+			key, value := workspaces.Content[i], workspaces.Content[i+1]
+			if other, exists := inc.definedIn[key.Value]; exists {
+				return fmt.Errorf("%s:%d: workspace %s is already defined in %s", path, key.Line, key.Value, other)
+			}
+			inc.definedIn[key.Value] = path
+			if value.Kind == yaml.MappingNode {
+				rebaseWorkspacePath(value, rel)
+			}
+			inc.workspaces.Content = append(inc.workspaces.Content, key, value)
+		}
+	}
+	return inc.follow(path, root)
+}
+
+// fileOf returns the file defining a workspace, which is main unless the
+// workspace was included. A nil includer returns main.
+func (inc *includer) fileOf(workspace, main string) string {
+	if inc == nil || inc.definedIn[workspace] == "" {
+		return main
+	}
+	return inc.definedIn[workspace]
+}
+
+// hasGlobMeta reports whether an include pattern is a glob, which may match
+// no files.
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[{")
+}
+
+// IncludedFiles returns the files merged through include, relative to the
+// directory of doctrus.yml with forward slashes.
+func (c *Config) IncludedFiles() []string {
+	if c.includes == nil {
+		return nil
+	}
+	return c.includes.files
+}
+
+// rebaseWorkspacePath makes the path of a workspace from an included file
+// relative to doctrus.yml, where rel is the included file's directory.
+func rebaseWorkspacePath(workspace *yaml.Node, rel string) {
+	path := mappingValue(workspace, "path")
+	if path == nil {
+		setMappingValue(workspace, "path", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: filepath.ToSlash(rel)})
+		return
+	}
+	if path.Kind == yaml.ScalarNode && path.Value != "" && !filepath.IsAbs(path.Value) {
+		path.Value = filepath.ToSlash(filepath.Join(rel, path.Value))
+	}
+}
+
+// setMappingValue sets key in a mapping node, adding it when missing.
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
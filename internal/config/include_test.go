@@ -0,0 +1,120 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"doctrus.yml": `version: "1.0"
+include: ["services/*/doctrus.yml", "shared.yml"]
+workspaces:
+  web:
+    path: web
+    tasks:
+      build: {command: ["vite", "build"]}
+`,
+		"services/api/doctrus.yml": `workspaces:
+  api:
+    tasks:
+      build:
+        command: ["go", "build"]
+        depends_on: ["web:build"]
+`,
+		"services/worker/doctrus.yml": `include: ["../../shared.yml"]
+workspaces:
+  worker:
+    path: ./src
+    tasks:
+      build: {command: ["make"]}
+`,
+		"shared.yml": `workspaces:
+  tools:
+    path: tools
+    tasks:
+      lint: {command: ["lint"]}
+`,
+	})
+
+	cfg, _, err := Load(filepath.Join(dir, "doctrus.yml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	paths := make(map[string]string)
+	for name, workspace := range cfg.Workspaces {
+		paths[name] = workspace.Path
+	}
+	want := map[string]string{"web": "web", "api": "services/api", "worker": "services/worker/src", "tools": "tools"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("workspace paths = %v, want %v", paths, want)
+	}
+	if task, ok := cfg.GetTask("api", "build"); !ok || !reflect.DeepEqual(task.DependsOn, []string{"web:build"}) {
+		t.Fatalf("api:build = %+v, want it to depend on web:build", task)
+	}
+	if got, want := cfg.IncludedFiles(), []string{"services/api/doctrus.yml", "services/worker/doctrus.yml", "shared.yml"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("IncludedFiles() = %v, want %v", got, want)
+	}
+
+	// Scoped loads see included workspaces too
+	scoped, _, err := LoadScoped(filepath.Join(dir, "doctrus.yml"), []string{"api:build"})
+	if err != nil {
+		t.Fatalf("LoadScoped() error = %v", err)
+	}
+	if _, ok := scoped.GetWorkspace("web"); !ok {
+		t.Fatalf("LoadScoped() left out web, which api:build depends on")
+	}
+	if _, ok := scoped.GetWorkspace("tools"); ok {
+		t.Fatalf("LoadScoped() kept tools, which api:build does not reach")
+	}
+}
+
+func TestLoadIncludeErrors(t *testing.T) {
+	main := "version: \"1.0\"\ninclude: [\"more.yml\"]\nworkspaces:\n  web:\n    path: .\n    tasks:\n      build: {command: [\"build\"]}\n"
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name:    "missing file",
+			files:   map[string]string{"doctrus.yml": strings.Replace(main, "more.yml", "missing.yml", 1)},
+			wantErr: "include missing.yml: file not found",
+		},
+		{
+			name:    "duplicate workspace",
+			files:   map[string]string{"doctrus.yml": main, "more.yml": "workspaces:\n  web:\n    tasks: {}\n"},
+			wantErr: "more.yml:2: workspace web is already defined in ",
+		},
+		{
+			name:    "setting of the main file",
+			files:   map[string]string{"doctrus.yml": main, "more.yml": "env:\n  A: b\n"},
+			wantErr: "more.yml:1: env can only be set in the main config file",
+		},
+		{
+			name:    "problem reported in its file",
+			files:   map[string]string{"doctrus.yml": main, "more.yml": "workspaces:\n  api:\n    executor: bogus\n    tasks:\n      build: {command: [\"build\"]}\n"},
+			wantErr: "more.yml:3:5: ",
+		},
+		{
+			name:    "type error reported in its file",
+			files:   map[string]string{"doctrus.yml": main, "more.yml": "workspaces:\n  api:\n    tasks: [build]\n"},
+			wantErr: "more.yml:3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			_, _, err := Load(filepath.Join(dir, "doctrus.yml"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParallelAuto runs one task per CPU. It may be followed by a reserve of
+// CPUs to leave free, as in auto-2.
+const ParallelAuto = "auto"
+
+// ParseParallel resolves a parallelism setting to the number of tasks that
+// may run at once on a machine with cpus CPUs: a positive number is used as
+// is, while 0 and auto mean cpus and auto-N means cpus minus N, but always at
+// least one. An empty setting returns 0, meaning no limit.
+func ParseParallel(value string, cpus int) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	reserve := 0
+	if rest, ok := strings.CutPrefix(value, ParallelAuto); ok {
+		if rest != "" {
+			n, err := strconv.Atoi(strings.TrimPrefix(rest, "-"))
+			if err != nil || !strings.HasPrefix(rest, "-") || n < 0 {
+				return 0, fmt.Errorf("invalid parallel %q (expected a number, auto or auto-N)", value)
+			}
+			reserve = n
+		}
+	} else {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid parallel %q (expected a number, auto or auto-N)", value)
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+
+	return max(cpus-reserve, 1), nil
+}
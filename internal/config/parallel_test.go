@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseParallel(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "4", want: 4},
+		{value: "0", want: 8},
+		{value: "auto", want: 8},
+		{value: "auto-2", want: 6},
+		{value: "auto-20", want: 1},
+		{value: "-1", wantErr: true},
+		{value: "auto2", wantErr: true},
+		{value: "auto-x", wantErr: true},
+		{value: "many", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseParallel(tt.value, 8)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseParallel(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseParallel(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigLoadParallel(t *testing.T) {
+	for _, value := range []string{"3", "auto-1"} {
+		t.Run(value, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "doctrus.yml")
+			content := "version: \"1.0\"\nparallel: " + value + "\nworkspaces:\n  app:\n    path: .\n    tasks:\n      build:\n        command: [\"true\"]\n"
+			if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			cfg, _, err := Load(configPath)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.Parallel != value {
+				t.Fatalf("Parallel = %q, want %q", cfg.Parallel, value)
+			}
+		})
+	}
+}
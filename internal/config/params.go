@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// TaskParam declares a parameter of a task, set with --param name=value on
+// run and used as {{.params.name}} in the task's command and env. A
+// parameter that is not required falls back to Default.
+type TaskParam struct {
+	Name        string `yaml:"name" json:"name"`
+	Default     string `yaml:"default,omitempty" json:"default,omitempty"`
+	Required    bool   `yaml:"required,omitempty" json:"required,omitempty"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+var paramNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// HasParams reports whether any task declares params.
+func (c *Config) HasParams() bool {
+	for _, workspace := range c.Workspaces {
+		for _, task := range workspace.Tasks {
+			if len(task.Params) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParamNames returns the names of the task's parameters.
+func (t *Task) ParamNames() []string {
+	names := make([]string, len(t.Params))
+	for i, param := range t.Params {
+		names[i] = param.Name
+	}
+	return names
+}
+
+// ApplyParams substitutes the parameters of the task into its command and
+// env, taking each from values or its default. It fails when a required
+// parameter has no value or a template can't be rendered. Tasks without
+// params are left alone, so commands of other tasks may hold {{ verbatim.
+func (t *Task) ApplyParams(values map[string]string) error {
+	if len(t.Params) == 0 {
+		return nil
+	}
+
+	params := make(map[string]string, len(t.Params))
+	var missing []string
+	for _, param := range t.Params {
+		value, ok := values[param.Name]
+		switch {
+		case ok:
+			params[param.Name] = value
+		case param.Required:
+			missing = append(missing, param.Name)
+		default:
+			params[param.Name] = param.Default
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required param %s (set with --param NAME=VALUE)", strings.Join(missing, ", "))
+	}
+
+	data := map[string]any{"params": params}
+	command := make([]string, len(t.Command))
+	for i, arg := range t.Command {
+		rendered, err := renderParams(arg, data)
+		if err != nil {
+			return fmt.Errorf("command: %w", err)
+		}
+		command[i] = rendered
+	}
+	env := maps.Clone(t.Env)
+	for key, value := range env {
+		rendered, err := renderParams(value, data)
+		if err != nil {
+			return fmt.Errorf("env %s: %w", key, err)
+		}
+		env[key] = rendered
+	}
+	t.Command, t.Env = command, env
+	return nil
+}
+
+// renderParams executes text as a template over data, failing on references
+// to undeclared parameters.
+func renderParams(text string, data map[string]any) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("param").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// paramProblems checks the params of the task at taskPath and that its
+// command and env templates parse.
+func paramProblems(prefix, taskPath string, task Task, add func(path, format string, args ...any)) {
+	if len(task.Params) == 0 {
+		return
+	}
+	if len(task.Command) == 0 {
+		add(joinPath(taskPath, "params"), "%s: params are only supported for tasks with a command", prefix)
+	}
+
+	seen := make(map[string]bool)
+	for i, param := range task.Params {
+		paramPath := fmt.Sprintf("%s[%d]", joinPath(taskPath, "params"), i)
+		switch {
+		case param.Name == "":
+			add(paramPath, "%s: params[%d]: name is required", prefix, i)
+		case !paramNamePattern.MatchString(param.Name):
+			add(joinPath(paramPath, "name"), "%s: invalid param name %q (expected letters, digits and underscores)", prefix, param.Name)
+		case seen[param.Name]:
+			add(joinPath(paramPath, "name"), "%s: param %s is declared twice", prefix, param.Name)
+		}
+		seen[param.Name] = true
+		if param.Required && param.Default != "" {
+			add(joinPath(paramPath, "default"), "%s: param %s is required and can't have a default", prefix, param.Name)
+		}
+	}
+
+	parse := func(text string) error {
+		_, err := template.New("param").Parse(text)
+		return err
+	}
+	for i, arg := range task.Command {
+		if err := parse(arg); err != nil {
+			add(fmt.Sprintf("%s[%d]", joinPath(taskPath, "command"), i), "%s: invalid command template: %v", prefix, err)
+		}
+	}
+	for _, key := range sortedKeys(task.Env) {
+		if err := parse(task.Env[key]); err != nil {
+			add(joinPath(taskPath, "env", key), "%s: invalid env %s template: %v", prefix, key, err)
+		}
+	}
+}
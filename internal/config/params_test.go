@@ -0,0 +1,123 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTaskApplyParams(t *testing.T) {
+	task := Task{
+		Command: []string{"./deploy.sh", "--env={{.params.env}}", "{{.params.region}}"},
+		Env:     map[string]string{"TARGET": "{{.params.env}}", "LEVEL": "debug"},
+		Params: []TaskParam{
+			{Name: "env", Required: true},
+			{Name: "region", Default: "eu-west-1"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		values      map[string]string
+		wantCommand []string
+		wantEnv     map[string]string
+		wantErr     string
+	}{
+		{
+			name:        "defaults",
+			values:      map[string]string{"env": "prod"},
+			wantCommand: []string{"./deploy.sh", "--env=prod", "eu-west-1"},
+			wantEnv:     map[string]string{"TARGET": "prod", "LEVEL": "debug"},
+		},
+		{
+			name:        "overridden default",
+			values:      map[string]string{"env": "staging", "region": "us-east-1"},
+			wantCommand: []string{"./deploy.sh", "--env=staging", "us-east-1"},
+			wantEnv:     map[string]string{"TARGET": "staging", "LEVEL": "debug"},
+		},
+		{
+			name:    "missing required",
+			values:  map[string]string{"region": "us-east-1"},
+			wantErr: "missing required param env",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := task
+			err := got.ApplyParams(tt.values)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ApplyParams() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyParams() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Command, tt.wantCommand) {
+				t.Errorf("command = %v, want %v", got.Command, tt.wantCommand)
+			}
+			if !reflect.DeepEqual(got.Env, tt.wantEnv) {
+				t.Errorf("env = %v, want %v", got.Env, tt.wantEnv)
+			}
+			if task.Env["TARGET"] != "{{.params.env}}" {
+				t.Errorf("original env changed to %v", task.Env)
+			}
+		})
+	}
+}
+
+func TestTaskApplyParamsLeavesTasksWithoutParams(t *testing.T) {
+	task := Task{Command: []string{"docker", "inspect", "--format", "{{.Id}}"}}
+	if err := task.ApplyParams(map[string]string{"env": "prod"}); err != nil {
+		t.Fatalf("ApplyParams() error = %v", err)
+	}
+	if task.Command[3] != "{{.Id}}" {
+		t.Errorf("command = %v, want it unchanged", task.Command)
+	}
+}
+
+func TestParseParamProblems(t *testing.T) {
+	tests := []struct {
+		name    string
+		task    string
+		wantErr string
+	}{
+		{
+			name:    "invalid name",
+			task:    "command: [\"echo\"]\n        params:\n          - name: my-env\n",
+			wantErr: `invalid param name "my-env"`,
+		},
+		{
+			name:    "required with default",
+			task:    "command: [\"echo\"]\n        params:\n          - name: env\n            required: true\n            default: dev\n",
+			wantErr: "param env is required and can't have a default",
+		},
+		{
+			name:    "declared twice",
+			task:    "command: [\"echo\"]\n        params:\n          - name: env\n          - name: env\n",
+			wantErr: "param env is declared twice",
+		},
+		{
+			name:    "invalid template",
+			task:    "command: [\"echo\", \"{{.params.env\"]\n        params:\n          - name: env\n",
+			wantErr: "invalid command template",
+		},
+		{
+			name:    "compound task",
+			task:    "depends_on: [\"build\"]\n        params:\n          - name: env\n",
+			wantErr: "params are only supported for tasks with a command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "version: \"1.0\"\nworkspaces:\n  app:\n    path: .\n    tasks:\n      build:\n        command: [\"make\"]\n      deploy:\n        " + tt.task
+			_, err := Parse("doctrus.yml", []byte(content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PathMappingEnv adds path mappings from the environment, as comma-separated
+// container=host pairs, for dev containers that know the host path only at
+// runtime.
+const PathMappingEnv = "DOCTRUS_PATH_MAPPING"
+
+// PathMapping relates a directory as doctrus sees it, when it runs inside a
+// dev container, to the same directory on the host whose Docker daemon it
+// talks to. Paths given to the daemon, such as bind mounts, are translated
+// to host paths, and host paths in doctrus.yml or --cache-dir to container
+// paths.
+type PathMapping struct {
+	Container string `yaml:"container" json:"container"`
+	Host      string `yaml:"host" json:"host"`
+}
+
+// ParsePathMappings parses the value of DOCTRUS_PATH_MAPPING, such as
+// "/workspaces/app=/home/me/app".
+func ParsePathMappings(value string) ([]PathMapping, error) {
+	var mappings []PathMapping
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		container, host, ok := strings.Cut(pair, "=")
+		mapping := PathMapping{Container: strings.TrimSpace(container), Host: strings.TrimSpace(host)}
+		if !ok || !isAbsPath(mapping.Container) || !isAbsPath(mapping.Host) {
+			return nil, fmt.Errorf("invalid path mapping %q (expected container=host with absolute paths)", pair)
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
+
+// isAbsPath accepts absolute paths of either platform, as the host may run
+// another operating system than the container.
+func isAbsPath(path string) bool {
+	return strings.HasPrefix(path, "/") || filepath.IsAbs(path) || (len(path) > 2 && path[1] == ':' && (path[2] == '\\' || path[2] == '/'))
+}
+
+// PathMappings returns the mappings of path_mapping followed by those of
+// DOCTRUS_PATH_MAPPING.
+func (c *Config) PathMappings() []PathMapping {
+	mappings := c.PathMapping
+	if env, err := ParsePathMappings(os.Getenv(PathMappingEnv)); err == nil {
+		mappings = append(mappings[:len(mappings):len(mappings)], env...)
+	}
+	return mappings
+}
+
+// HostPath translates a path doctrus sees to the host's, using the mapping
+// with the longest matching container directory. Paths outside every
+// mapping are returned unchanged.
+func (c *Config) HostPath(path string) string {
+	return mapPath(c.PathMappings(), path, false)
+}
+
+// ContainerPath translates a host path to the path doctrus sees, the inverse
+// of HostPath.
+func (c *Config) ContainerPath(path string) string {
+	return mapPath(c.PathMappings(), path, true)
+}
+
+func mapPath(mappings []PathMapping, path string, fromHost bool) string {
+	best, replacement := "", ""
+	for _, mapping := range mappings {
+		from, to := mapping.Container, mapping.Host
+		if fromHost {
+			from, to = mapping.Host, mapping.Container
+		}
+		from = strings.TrimRight(from, `/\`)
+		if len(from) <= len(best) {
+			continue
+		}
+		if path == from || strings.HasPrefix(path, from+"/") || strings.HasPrefix(path, from+`\`) {
+			best, replacement = from, strings.TrimRight(to, `/\`)
+		}
+	}
+	if best == "" {
+		return path
+	}
+
+	rest := path[len(best):]
+	// Separators follow the platform of the side translated to
+	if strings.Contains(replacement, `\`) {
+		rest = strings.ReplaceAll(rest, "/", `\`)
+	} else {
+		rest = strings.ReplaceAll(rest, `\`, "/")
+	}
+	return replacement + rest
+}
+
+// applyPathMapping translates absolute host paths of workspaces and compose
+// files in doctrus.yml to the paths doctrus sees.
+func (c *Config) applyPathMapping() {
+	if len(c.PathMappings()) == 0 {
+		return
+	}
+	if c.Docker.ComposeFile != "" {
+		c.Docker.ComposeFile = c.ContainerPath(c.Docker.ComposeFile)
+	}
+	for workspaceName, workspace := range c.Workspaces {
+		workspace.Path = c.ContainerPath(workspace.Path)
+		for taskName, task := range workspace.Tasks {
+			if task.Docker != nil && task.Docker.ComposeFile != "" {
+				docker := *task.Docker
+				docker.ComposeFile = c.ContainerPath(docker.ComposeFile)
+				task.Docker = &docker
+				workspace.Tasks[taskName] = task
+			}
+		}
+		c.Workspaces[workspaceName] = workspace
+	}
+}
+
+// pathMappingProblems reports path mappings that are not absolute.
+func (c *Config) pathMappingProblems(add func(path, format string, args ...any)) {
+	for i, mapping := range c.PathMapping {
+		path := fmt.Sprintf("path_mapping[%d]", i)
+		if !isAbsPath(mapping.Container) {
+			add(path+".container", "%s: container must be an absolute path", path)
+		}
+		if !isAbsPath(mapping.Host) {
+			add(path+".host", "%s: host must be an absolute path", path)
+		}
+	}
+	if _, err := ParsePathMappings(os.Getenv(PathMappingEnv)); err != nil {
+		add("path_mapping", "%s: %v", PathMappingEnv, err)
+	}
+}
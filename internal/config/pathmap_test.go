@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePathMappings(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "/workspaces/app=/home/me/app", want: 1},
+		{value: "/workspaces/app=/home/me/app, /cache=C:\\Users\\me\\cache", want: 2},
+		{value: "/workspaces/app", wantErr: true},
+		{value: "app=/home/me/app", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParsePathMappings(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePathMappings(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("ParsePathMappings(%q) = %+v, want %d mappings", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathMappingTranslation(t *testing.T) {
+	t.Setenv(PathMappingEnv, "/cache=C:\\Users\\me\\cache")
+	cfg := &Config{PathMapping: []PathMapping{
+		{Container: "/workspaces/app", Host: "/home/me/app"},
+		{Container: "/workspaces/app/vendor/", Host: "/srv/vendor"},
+	}}
+
+	tests := []struct {
+		path string
+		host string
+	}{
+		{path: "/workspaces/app", host: "/home/me/app"},
+		{path: "/workspaces/app/web", host: "/home/me/app/web"},
+		{path: "/workspaces/app/vendor/lib", host: "/srv/vendor/lib"},
+		{path: "/workspaces/application", host: "/workspaces/application"},
+		{path: "/cache/doctrus", host: "C:\\Users\\me\\cache\\doctrus"},
+		{path: "relative/path", host: "relative/path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := cfg.HostPath(tt.path); got != tt.host {
+				t.Fatalf("HostPath(%q) = %q, want %q", tt.path, got, tt.host)
+			}
+			if got := cfg.ContainerPath(tt.host); got != tt.path {
+				t.Fatalf("ContainerPath(%q) = %q, want %q", tt.host, got, tt.path)
+			}
+		})
+	}
+}
+
+func TestConfigLoadPathMapping(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "doctrus.yml")
+	content := `version: "1.0"
+path_mapping:
+  - container: ` + dir + `
+    host: /home/me/app
+docker:
+  compose_file: /home/me/app/docker-compose.yml
+workspaces:
+  api:
+    path: /home/me/app/api
+    tasks:
+      build:
+        command: ["go", "build"]
+  web:
+    path: ./web
+    tasks:
+      build:
+        command: ["npm", "run", "build"]
+        docker:
+          compose_file: /home/me/app/web/compose.yml
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, _, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := cfg.Workspaces["api"].Path; got != dir+"/api" {
+		t.Errorf("api path = %q, want %q", got, dir+"/api")
+	}
+	if got := cfg.Workspaces["web"].Path; got != "./web" {
+		t.Errorf("web path = %q, want relative paths unchanged", got)
+	}
+	if got := cfg.Docker.ComposeFile; got != dir+"/docker-compose.yml" {
+		t.Errorf("compose file = %q", got)
+	}
+	if got := cfg.Workspaces["web"].Tasks["build"].Docker.ComposeFile; got != dir+"/web/compose.yml" {
+		t.Errorf("task compose file = %q", got)
+	}
+
+	bad := strings.Replace(content, "host: /home/me/app", "host: me/app", 1)
+	if err := os.WriteFile(configPath, []byte(bad), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	if _, _, err := Load(configPath); err == nil || !strings.Contains(err.Error(), "path_mapping[0]: host must be an absolute path") {
+		t.Fatalf("Load() error = %v, want a path_mapping error", err)
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// PresetNone disables a workspace's preset for one task.
+const PresetNone = "none"
+
+// preset holds the inputs and outputs a language preset contributes to
+// tasks with common names. Manifests, the project files and lockfiles that
+// pin dependencies, are inputs of every such task.
+type preset struct {
+	manifests []string
+	tasks     map[string]presetTask
+}
+
+type presetTask struct {
+	inputs  []string
+	outputs []string
+}
+
+var presets = map[string]preset{
+	"node": {
+		manifests: []string{"package.json", "package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "bun.lockb"},
+		tasks: map[string]presetTask{
+			"install":   {},
+			"build":     {inputs: []string{"src/**", "public/**", "tsconfig*.json", "*.config.{js,cjs,mjs,ts}"}, outputs: []string{"dist/**", "build/**"}},
+			"test":      {inputs: []string{"src/**", "test/**", "tests/**", "tsconfig*.json", "*.config.{js,cjs,mjs,ts}"}},
+			"lint":      {inputs: []string{"src/**", "test/**", "tests/**", ".eslintrc*", "eslint.config.*", ".prettierrc*"}},
+			"typecheck": {inputs: []string{"src/**", "test/**", "tests/**", "tsconfig*.json"}},
+		},
+	},
+	"go": {
+		manifests: []string{"go.mod", "go.sum", "go.work", "go.work.sum"},
+		tasks: map[string]presetTask{
+			"install": {},
+			"build":   {inputs: []string{"**/*.go"}, outputs: []string{"bin/**"}},
+			"test":    {inputs: []string{"**/*.go", "**/testdata/**"}},
+			"lint":    {inputs: []string{"**/*.go", ".golangci.*"}},
+			"vet":     {inputs: []string{"**/*.go"}},
+		},
+	},
+	"php": {
+		manifests: []string{"composer.json", "composer.lock"},
+		tasks: map[string]presetTask{
+			"install": {outputs: []string{"vendor/autoload.php"}},
+			"build":   {inputs: []string{"src/**", "app/**", "config/**"}},
+			"test":    {inputs: []string{"src/**", "app/**", "config/**", "tests/**", "phpunit.xml*"}},
+			"lint":    {inputs: []string{"src/**", "app/**", "tests/**", "phpstan.neon*", ".php-cs-fixer*", "phpcs.xml*"}},
+		},
+	},
+	"python": {
+		manifests: []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements*.txt", "poetry.lock", "uv.lock", "Pipfile", "Pipfile.lock"},
+		tasks: map[string]presetTask{
+			"install":   {},
+			"build":     {inputs: []string{"**/*.py"}, outputs: []string{"dist/**"}},
+			"test":      {inputs: []string{"**/*.py", "pytest.ini", "tox.ini"}},
+			"lint":      {inputs: []string{"**/*.py", "ruff.toml", ".ruff.toml", ".flake8"}},
+			"typecheck": {inputs: []string{"**/*.py", "mypy.ini"}},
+		},
+	},
+}
+
+// PresetNames lists the supported presets.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyPresets adds the inputs of each task's preset, from the task or its
+// workspace, to tasks with a command whose name the preset knows, and the
+// preset's outputs to those with cache enabled that declare none. Unknown
+// presets are left to validation.
+func (c *Config) applyPresets() {
+	for workspaceName, workspace := range c.Workspaces {
+		for taskName, task := range workspace.Tasks {
+			name := task.Preset
+			if name == "" {
+				name = workspace.Preset
+			}
+			p, ok := presets[name]
+			if !ok || len(task.Command) == 0 {
+				continue
+			}
+			contributed, ok := p.tasks[taskName]
+			if !ok {
+				continue
+			}
+
+			inputs := slices.Clone(task.Inputs)
+			for _, pattern := range append(slices.Clone(p.manifests), contributed.inputs...) {
+				if !slices.Contains(inputs, pattern) {
+					inputs = append(inputs, pattern)
+				}
+			}
+			task.Inputs = inputs
+			if task.Cache && len(task.Outputs) == 0 {
+				task.Outputs = slices.Clone(contributed.outputs)
+			}
+			workspace.Tasks[taskName] = task
+		}
+		c.Workspaces[workspaceName] = workspace
+	}
+}
+
+// presetProblems reports unknown presets.
+func presetProblems(prefix, path, name string, add func(path, format string, args ...any)) {
+	if _, ok := presets[name]; ok || name == "" || name == PresetNone {
+		return
+	}
+	add(path, "%s: unknown preset %q (expected one of %s, or %s)", prefix, name, strings.Join(PresetNames(), ", "), PresetNone)
+}
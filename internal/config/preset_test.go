@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestConfigLoadPresets(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "doctrus.yml")
+	content := `version: "1.0"
+workspaces:
+  web:
+    path: web
+    preset: node
+    tasks:
+      build:
+        command: ["npm", "run", "build"]
+        inputs: ["src/**", "vite.config.ts"]
+        cache: true
+      lint:
+        command: ["npm", "run", "lint"]
+      deploy:
+        command: ["./deploy.sh"]
+  api:
+    path: api
+    preset: go
+    tasks:
+      test:
+        command: ["go", "test", "./..."]
+        preset: none
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, _, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	build := cfg.Workspaces["web"].Tasks["build"]
+	if build.Inputs[0] != "src/**" || build.Inputs[1] != "vite.config.ts" || !slices.Contains(build.Inputs, "package-lock.json") {
+		t.Errorf("build inputs = %v, want its own inputs first plus the lockfiles", build.Inputs)
+	}
+	if strings.Count(strings.Join(build.Inputs, " "), "src/**") != 1 {
+		t.Errorf("build inputs = %v, want src/** once", build.Inputs)
+	}
+	if !reflect.DeepEqual(build.Outputs, []string{"dist/**", "build/**"}) {
+		t.Errorf("build outputs = %v", build.Outputs)
+	}
+	if lint := cfg.Workspaces["web"].Tasks["lint"]; !slices.Contains(lint.Inputs, "eslint.config.*") || len(lint.Outputs) != 0 {
+		t.Errorf("lint = inputs %v, outputs %v; want preset inputs and no outputs without cache", lint.Inputs, lint.Outputs)
+	}
+	if deploy := cfg.Workspaces["web"].Tasks["deploy"]; len(deploy.Inputs) != 0 {
+		t.Errorf("deploy inputs = %v, want none for a task name the preset does not know", deploy.Inputs)
+	}
+	if test := cfg.Workspaces["api"].Tasks["test"]; len(test.Inputs) != 0 {
+		t.Errorf("test inputs = %v, want none with preset: none", test.Inputs)
+	}
+}
+
+func TestConfigLoadUnknownPreset(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "doctrus.yml")
+	content := "version: \"1.0\"\nworkspaces:\n  app:\n    path: .\n    preset: rust\n    tasks:\n      build:\n        command: [\"cargo\", \"build\"]\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	_, _, err := Load(configPath)
+	if err == nil || !strings.Contains(err.Error(), `workspace app: unknown preset "rust"`) {
+		t.Fatalf("Load() error = %v, want an unknown preset error", err)
+	}
+}
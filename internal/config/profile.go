@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileEnv is the environment variable selecting a profile when none is
+// given explicitly.
+const ProfileEnv = "DOCTRUS_PROFILE"
+
+// Profile is an overlay of settings, such as those of dev, ci or prod,
+// applied to the configuration when it is selected with --config-profile or
+// DOCTRUS_PROFILE.
+type Profile struct {
+	Env        map[string]string           `yaml:"env,omitempty" json:"env,omitempty"`
+	Docker     *DockerConfig               `yaml:"docker,omitempty" json:"docker,omitempty"`
+	Cache      *CacheConfig                `yaml:"cache,omitempty" json:"cache,omitempty"`
+	Workspaces map[string]ProfileWorkspace `yaml:"workspaces,omitempty" json:"workspaces,omitempty"`
+}
+
+// ProfileWorkspace overrides settings of a workspace and its tasks.
+type ProfileWorkspace struct {
+	Env       map[string]string      `yaml:"env,omitempty" json:"env,omitempty"`
+	Container string                 `yaml:"container,omitempty" json:"container,omitempty"`
+	Executor  string                 `yaml:"executor,omitempty" json:"executor,omitempty"`
+	Image     string                 `yaml:"image,omitempty" json:"image,omitempty"`
+	Tasks     map[string]ProfileTask `yaml:"tasks,omitempty" json:"tasks,omitempty"`
+}
+
+// ProfileTask overrides settings of a task.
+type ProfileTask struct {
+	Env       map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	Container *string           `yaml:"container,omitempty" json:"container,omitempty"`
+	Executor  string            `yaml:"executor,omitempty" json:"executor,omitempty"`
+	Image     string            `yaml:"image,omitempty" json:"image,omitempty"`
+	Docker    *TaskDockerConfig `yaml:"docker,omitempty" json:"docker,omitempty"`
+	Cache     *bool             `yaml:"cache,omitempty" json:"cache,omitempty"`
+}
+
+// profileKeys lists the settings a profile may override at each level.
+var profileKeys = map[string][]string{
+	"profile":   {"cache", "docker", "env", "workspaces"},
+	"workspace": {"container", "env", "executor", "image", "tasks"},
+	"task":      {"cache", "container", "docker", "env", "executor", "image"},
+}
+
+// applyProfile overlays the profile name of the profiles section onto doc,
+// the document of the config file at path, before it is decoded. Mappings
+// such as env are merged key by key and any other setting replaces the one
+// it overrides. A profile may only override workspaces and tasks that
+// exist. An empty name applies nothing.
+func applyProfile(path string, doc *yaml.Node, name string) error {
+	if name == "" {
+		return nil
+	}
+	var root *yaml.Node
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+	profiles := mappingValue(root, "profiles")
+	profile := mappingValue(profiles, name)
+	if profile == nil {
+		var names []string
+		if profiles != nil {
+			for i := 0; i+1 < len(profiles.Content); i += 2 {
+				names = append(names, profiles.Content[i].Value)
+			}
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("unknown profile %q (no profiles are defined)", name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown profile %q (expected one of %s)", name, strings.Join(names, ", "))
+	}
+	if profile.Kind != yaml.MappingNode {
+		// Decoding reports the invalid profile
+		return nil
+	}
+
+	fail := func(key *yaml.Node, format string, args ...any) error {
+		return fmt.Errorf("%s:%d: profile %s: %s", path, key.Line, name, fmt.Sprintf(format, args...))
+	}
+	if key := unknownProfileKey(profile, "profile"); key != nil {
+		return fail(key, "%s can't be overridden by a profile (expected %s)", key.Value, strings.Join(profileKeys["profile"], ", "))
+	}
+	workspaces := mappingValue(root, "workspaces")
+	overlays := mappingValue(profile, "workspaces")
+	for i := 0; overlays != nil && i+1 < len(overlays.Content); i += 2 {
+		wsKey, overlay := overlays.Content[i], overlays.Content[i+1]
+		workspace := mappingValue(workspaces, wsKey.Value)
+		if workspace == nil {
+			return fail(wsKey, "workspace %s is not defined", wsKey.Value)
+		}
+		if key := unknownProfileKey(overlay, "workspace"); key != nil {
+			return fail(key, "workspace %s: %s can't be overridden by a profile (expected %s)", wsKey.Value, key.Value, strings.Join(profileKeys["workspace"], ", "))
+		}
+		tasks := mappingValue(overlay, "tasks")
+		for j := 0; tasks != nil && j+1 < len(tasks.Content); j += 2 {
+			taskKey := tasks.Content[j]
+			if mappingValue(mappingValue(workspace, "tasks"), taskKey.Value) == nil {
+				return fail(taskKey, "workspace %s: task %s is not defined", wsKey.Value, taskKey.Value)
+			}
+			if key := unknownProfileKey(tasks.Content[j+1], "task"); key != nil {
+				return fail(key, "workspace %s, task %s: %s can't be overridden by a profile (expected %s)", wsKey.Value, taskKey.Value, key.Value, strings.Join(profileKeys["task"], ", "))
+			}
+		}
+	}
+
+	overlayNode(root, profile)
+	return nil
+}
+
+// unknownProfileKey returns the key of the first setting in node a profile
+// can't override at level, or nil.
+func unknownProfileKey(node *yaml.Node, level string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if key := node.Content[i]; !slices.Contains(profileKeys[level], key.Value) {
+			return key
+		}
+	}
+	return nil
+}
+
+// overlayNode merges the entries of overlay into the mapping node, replacing
+// everything but mappings.
+func overlayNode(node, overlay *yaml.Node) {
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		if own := mappingValue(node, key.Value); own != nil && own.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			overlayNode(own, value)
+			continue
+		}
+		setMappingValue(node, key.Value, cloneNode(value))
+	}
+}
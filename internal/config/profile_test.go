@@ -0,0 +1,133 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const profileConfig = `version: "1.0"
+env:
+  LOG_LEVEL: info
+  APP_ENV: development
+docker:
+  compose_file: docker-compose.yml
+profiles:
+  ci:
+    env:
+      APP_ENV: ci
+    docker:
+      compose_file: docker-compose.ci.yml
+    cache:
+      max_size: 1GB
+    workspaces:
+      api:
+        container: api-ci
+        tasks:
+          test:
+            env:
+              DB: postgres
+            cache: false
+workspaces:
+  api:
+    path: .
+    container: api
+    tasks:
+      test:
+        command: ["go", "test", "./..."]
+        cache: true
+        env:
+          GOFLAGS: -race
+`
+
+func TestLoadProfile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "doctrus.yml")
+	if err := os.WriteFile(configPath, []byte(profileConfig), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, _, err := LoadProfile(configPath, nil, "")
+	if err != nil {
+		t.Fatalf("LoadProfile() error = %v", err)
+	}
+	if cfg.Env["APP_ENV"] != "development" || cfg.Workspaces["api"].Container != "api" || cfg.Cache != nil {
+		t.Errorf("config without a profile = env %v, container %q, cache %v; want it unchanged", cfg.Env, cfg.Workspaces["api"].Container, cfg.Cache)
+	}
+
+	cfg, _, err = LoadProfile(configPath, nil, "ci")
+	if err != nil {
+		t.Fatalf("LoadProfile() error = %v", err)
+	}
+	if want := map[string]string{"LOG_LEVEL": "info", "APP_ENV": "ci"}; !reflect.DeepEqual(cfg.Env, want) {
+		t.Errorf("env = %v, want %v", cfg.Env, want)
+	}
+	if cfg.Docker.ComposeFile != "docker-compose.ci.yml" {
+		t.Errorf("compose file = %q", cfg.Docker.ComposeFile)
+	}
+	if cfg.Cache == nil || cfg.Cache.MaxSize != "1GB" {
+		t.Errorf("cache = %+v, want the profile's max_size", cfg.Cache)
+	}
+	if cfg.Workspaces["api"].Container != "api-ci" {
+		t.Errorf("container = %q", cfg.Workspaces["api"].Container)
+	}
+	test := cfg.Workspaces["api"].Tasks["test"]
+	if want := map[string]string{"GOFLAGS": "-race", "DB": "postgres"}; !reflect.DeepEqual(test.Env, want) || test.Cache {
+		t.Errorf("test = env %v, cache %v; want merged env and cache off", test.Env, test.Cache)
+	}
+
+	t.Setenv(ProfileEnv, "ci")
+	if cfg, _, err = Load(configPath); err != nil || cfg.Env["APP_ENV"] != "ci" {
+		t.Errorf("Load() with %s = env %v, error %v; want the ci profile", ProfileEnv, cfg.Env, err)
+	}
+}
+
+func TestLoadProfileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		overlay string
+		wantErr string
+	}{
+		{
+			name:    "unknown profile",
+			profile: "prod",
+			overlay: "  ci:\n    env:\n      CI: \"true\"\n",
+			wantErr: `unknown profile "prod" (expected one of ci)`,
+		},
+		{
+			name:    "unknown workspace",
+			profile: "ci",
+			overlay: "  ci:\n    workspaces:\n      web:\n        container: web\n",
+			wantErr: "doctrus.yml:11: profile ci: workspace web is not defined",
+		},
+		{
+			name:    "unknown task",
+			profile: "ci",
+			overlay: "  ci:\n    workspaces:\n      api:\n        tasks:\n          lint:\n            cache: false\n",
+			wantErr: "profile ci: workspace api: task lint is not defined",
+		},
+		{
+			name:    "setting a profile can't override",
+			profile: "ci",
+			overlay: "  ci:\n    workspaces:\n      api:\n        tasks:\n          test:\n            command: [\"true\"]\n",
+			wantErr: "profile ci: workspace api, task test: command can't be overridden by a profile",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "doctrus.yml")
+			content := "version: \"1.0\"\nworkspaces:\n  api:\n    path: .\n    tasks:\n      test:\n        command: [\"go\", \"test\"]\nprofiles:\n" + tt.overlay
+			if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			_, _, err := LoadProfile(configPath, nil, tt.profile)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadProfile() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Provider is a command that emits additional tasks when the config is loaded.
+// Its stdout must be a YAML or JSON document shaped like the workspaces
+// section of doctrus.yml:
+//
+//	workspaces:
+//	  frontend:
+//	    tasks:
+//	      lint:
+//	        command: ["npm", "run", "lint"]
+//
+// When Workspace is set the document may instead contain just a tasks map,
+// which is merged into that workspace.
+type Provider struct {
+	Name      string            `yaml:"name" json:"name"`
+	Command   []string          `yaml:"command" json:"command"`
+	Dir       string            `yaml:"dir,omitempty" json:"dir,omitempty"`
+	Workspace string            `yaml:"workspace,omitempty" json:"workspace,omitempty"`
+	Env       map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+}
+
+type providerOutput struct {
+	Workspaces map[string]Workspace `yaml:"workspaces"`
+	Tasks      map[string]Task      `yaml:"tasks"`
+}
+
+// applyProviders runs every provider and merges the emitted tasks into the
+// workspace task maps. Tasks written in doctrus.yml always win over generated
+// ones; two providers generating the same task is an error.
+func (c *Config) applyProviders(configDir string) error {
+	generatedBy := make(map[string]string)
+
+	for i, provider := range c.Providers {
+		label := provider.Name
+		if label == "" {
+			label = fmt.Sprintf("providers[%d]", i)
+		}
+
+		if len(provider.Command) == 0 {
+			return fmt.Errorf("provider %s: command is required", label)
+		}
+
+		output, err := runProvider(provider, configDir)
+		if err != nil {
+			return fmt.Errorf("provider %s: %w", label, err)
+		}
+
+		workspaces := output.Workspaces
+		if len(output.Tasks) > 0 {
+			if provider.Workspace == "" {
+				return fmt.Errorf("provider %s: emitted top-level tasks but no workspace is configured", label)
+			}
+			if workspaces == nil {
+				workspaces = make(map[string]Workspace)
+			}
+			target := workspaces[provider.Workspace]
+			if target.Tasks == nil {
+				target.Tasks = make(map[string]Task)
+			}
+			for name, task := range output.Tasks {
+				target.Tasks[name] = task
+			}
+			workspaces[provider.Workspace] = target
+		}
+
+		if c.Workspaces == nil && len(workspaces) > 0 {
+			c.Workspaces = make(map[string]Workspace)
+		}
+
+		for workspaceName, generated := range workspaces {
+			existing, exists := c.Workspaces[workspaceName]
+			if !exists {
+				existing = generated
+				existing.Tasks = nil
+			}
+			if existing.Tasks == nil {
+				existing.Tasks = make(map[string]Task)
+			}
+
+			for taskName, task := range generated.Tasks {
+				key := workspaceName + ":" + taskName
+				if previous, dup := generatedBy[key]; dup {
+					return fmt.Errorf("provider %s: task %s already generated by provider %s", label, key, previous)
+				}
+				if _, defined := existing.Tasks[taskName]; defined {
+					continue
+				}
+				existing.Tasks[taskName] = task
+				generatedBy[key] = label
+			}
+
+			c.Workspaces[workspaceName] = existing
+		}
+	}
+
+	return nil
+}
+
+func runProvider(provider Provider, configDir string) (*providerOutput, error) {
+	dir := configDir
+	if provider.Dir != "" {
+		dir = provider.Dir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(configDir, dir)
+		}
+	}
+
+	cmd := exec.Command(provider.Command[0], provider.Command[1:]...)
+	cmd.Dir = dir
+
+	env := append(os.Environ(), fmt.Sprintf("DOCTRUS_ROOT=%s", configDir))
+	for key, value := range provider.Env {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+	cmd.Env = env
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("command failed: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("command failed: %w", err)
+	}
+
+	var output providerOutput
+	if err := yaml.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("failed to parse provider output: %w", err)
+	}
+
+	return &output, nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PublishTarget is a destination a task's outputs are uploaded to after its
+// command succeeds. Exactly one of Dir, HTTP and S3 is set:
+//
+//   - Dir copies the files below a directory, relative to the project root
+//   - HTTP uploads every file with PUT to the URL joined with its path
+//   - S3 uploads every file below an s3://bucket/prefix
+//
+// TokenEnv names the variable holding a bearer token for HTTP. Attempts is
+// how often each upload is tried before publishing fails, 3 by default.
+type PublishTarget struct {
+	Dir      string `yaml:"dir,omitempty" json:"dir,omitempty"`
+	HTTP     string `yaml:"http,omitempty" json:"http,omitempty"`
+	S3       string `yaml:"s3,omitempty" json:"s3,omitempty"`
+	TokenEnv string `yaml:"token_env,omitempty" json:"token_env,omitempty"`
+	Attempts int    `yaml:"attempts,omitempty" json:"attempts,omitempty"`
+}
+
+// DefaultPublishAttempts is how often an upload is tried when a publish
+// target doesn't set attempts.
+const DefaultPublishAttempts = 3
+
+// MaxAttempts returns how often each upload to the target is tried.
+func (p PublishTarget) MaxAttempts() int {
+	if p.Attempts > 0 {
+		return p.Attempts
+	}
+	return DefaultPublishAttempts
+}
+
+// String describes the destination, such as s3://bucket/prefix.
+func (p PublishTarget) String() string {
+	switch {
+	case p.Dir != "":
+		return p.Dir
+	case p.HTTP != "":
+		return p.HTTP
+	default:
+		return p.S3
+	}
+}
+
+// publishProblems checks the publish targets of a task.
+func publishProblems(prefix, publishPath string, task Task, add func(path, format string, args ...any)) {
+	if len(task.Publish) > 0 && len(task.Outputs) == 0 {
+		add(publishPath, "%s: publish requires outputs", prefix)
+	}
+
+	for i, target := range task.Publish {
+		path := fmt.Sprintf("%s[%d]", publishPath, i)
+		set := 0
+		for _, value := range []string{target.Dir, target.HTTP, target.S3} {
+			if value != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			add(path, "%s: publish[%d] needs exactly one of dir, http or s3", prefix, i)
+			continue
+		}
+
+		if target.HTTP != "" {
+			if u, err := url.Parse(target.HTTP); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				add(path, "%s: publish[%d]: invalid http URL %q", prefix, i, target.HTTP)
+			}
+		} else if target.TokenEnv != "" {
+			add(path, "%s: publish[%d]: token_env is only supported for http", prefix, i)
+		}
+		if target.S3 != "" {
+			if bucket, _, _ := strings.Cut(strings.TrimPrefix(target.S3, "s3://"), "/"); !strings.HasPrefix(target.S3, "s3://") || bucket == "" {
+				add(path, "%s: publish[%d]: invalid s3 location %q (expected s3://bucket/prefix)", prefix, i, target.S3)
+			}
+		}
+		if target.Attempts < 0 {
+			add(path, "%s: publish[%d]: attempts must not be negative", prefix, i)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retention limits what doctrus keeps under .doctrus. HistoryAge and
+// CacheAge are ages such as 30d or 12h; older runs and cache entries are
+// pruned. HistoryRuns caps the number of recorded runs and CacheSize the
+// disk space of the cache, such as 100MB, evicting the least recently used
+// entries first. LogAge and LogRuns limit the log files kept of each task's
+// runs; the last LogRuns are kept as tasks run, 20 by default. With Auto
+// set, doctrus prunes after every run.
+type Retention struct {
+	HistoryAge  string `yaml:"history_age,omitempty" json:"history_age,omitempty"`
+	HistoryRuns int    `yaml:"history_runs,omitempty" json:"history_runs,omitempty"`
+	CacheAge    string `yaml:"cache_age,omitempty" json:"cache_age,omitempty"`
+	CacheSize   string `yaml:"cache_size,omitempty" json:"cache_size,omitempty"`
+	LogAge      string `yaml:"log_age,omitempty" json:"log_age,omitempty"`
+	LogRuns     int    `yaml:"log_runs,omitempty" json:"log_runs,omitempty"`
+	Auto        bool   `yaml:"auto,omitempty" json:"auto,omitempty"`
+}
+
+// ParseAge parses an age such as 30d, 2w or a Go duration like 12h. An empty
+// age returns 0, meaning no limit.
+func ParseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(value, suffix); ok {
+			if count, err := strconv.Atoi(n); err == nil && count > 0 {
+				return time.Duration(count) * unit, nil
+			}
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid age %q (expected a duration such as 12h, 30d or 2w)", value)
+}
+
+// sizeUnits are the suffixes ParseSize accepts, decimal and binary.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as 500MB, 2GiB or a number of bytes. An empty
+// size returns 0, meaning no limit.
+func ParseSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	number, unit := value, int64(1)
+	for _, candidate := range sizeUnits {
+		if n, ok := strings.CutSuffix(value, candidate.suffix); ok {
+			number, unit = strings.TrimSpace(n), candidate.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q (expected a size such as 500MB or 2GiB)", value)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// retentionProblems reports invalid retention settings.
+func (c *Config) retentionProblems(add func(path, format string, args ...any)) {
+	r := c.Retention
+	if r == nil {
+		return
+	}
+	if _, err := ParseAge(r.HistoryAge); err != nil {
+		add("retention.history_age", "retention.history_age: %v", err)
+	}
+	if _, err := ParseAge(r.CacheAge); err != nil {
+		add("retention.cache_age", "retention.cache_age: %v", err)
+	}
+	if _, err := ParseSize(r.CacheSize); err != nil {
+		add("retention.cache_size", "retention.cache_size: %v", err)
+	}
+	if r.HistoryRuns < 0 {
+		add("retention.history_runs", "retention.history_runs: must not be negative")
+	}
+	if _, err := ParseAge(r.LogAge); err != nil {
+		add("retention.log_age", "retention.log_age: %v", err)
+	}
+	if r.LogRuns < 0 {
+		add("retention.log_runs", "retention.log_runs: must not be negative")
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "12h", want: 12 * time.Hour},
+		{value: "30d", want: 30 * 24 * time.Hour},
+		{value: "2w", want: 14 * 24 * time.Hour},
+		{value: "0d", wantErr: true},
+		{value: "-1h", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseAge(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAge(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseAge(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "1024", want: 1024},
+		{value: "500MB", want: 500_000_000},
+		{value: "2GiB", want: 2 << 30},
+		{value: "1.5K", want: 1536},
+		{value: "10 MiB", want: 10 << 20},
+		{value: "0", wantErr: true},
+		{value: "big", wantErr: true},
+		{value: "5XB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseSize(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseSize(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigLoadRetention(t *testing.T) {
+	const workspaces = "workspaces:\n  app:\n    path: .\n    tasks:\n      build:\n        command: [\"true\"]\n"
+	tests := []struct {
+		name    string
+		block   string
+		wantErr string
+	}{
+		{name: "valid", block: "retention:\n  history_age: 30d\n  history_runs: 200\n  cache_age: 2w\n  cache_size: 1GiB\n  log_age: 14d\n  log_runs: 5\n  auto: true\ncache:\n  max_size: 2GB\n"},
+		{name: "invalid age", block: "retention:\n  cache_age: forever\n", wantErr: "retention.cache_age"},
+		{name: "invalid size", block: "retention:\n  cache_size: lots\n", wantErr: "retention.cache_size"},
+		{name: "negative runs", block: "retention:\n  history_runs: -1\n", wantErr: "retention.history_runs"},
+		{name: "invalid log age", block: "retention:\n  log_age: soon\n", wantErr: "retention.log_age"},
+		{name: "negative log runs", block: "retention:\n  log_runs: -2\n", wantErr: "retention.log_runs"},
+		{name: "invalid cache max size", block: "cache:\n  max_size: big\n", wantErr: "cache.max_size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "doctrus.yml")
+			content := "version: \"1.0\"\n" + tt.block + workspaces
+			if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			cfg, _, err := Load(configPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want it to mention %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.Retention == nil || cfg.Retention.HistoryRuns != 200 || cfg.Retention.CacheSize != "1GiB" || !cfg.Retention.Auto {
+				t.Fatalf("Retention = %+v", cfg.Retention)
+			}
+			if cfg.Cache == nil || cfg.Cache.MaxSize != "2GB" {
+				t.Fatalf("Cache = %+v, want max_size 2GB", cfg.Cache)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"time"
+)
+
+// Backoff strategies of a task's retry setting
+const (
+	// BackoffConstant waits the same delay before every attempt
+	BackoffConstant = "constant"
+	// BackoffExponential doubles the delay after every attempt
+	BackoffExponential = "exponential"
+)
+
+// TaskRetry reruns a task whose command failed. Attempts counts every run of
+// the command, including the first. Delay is waited before the second
+// attempt, and Backoff says whether later attempts wait as long (constant,
+// the default) or twice as long as the one before (exponential).
+type TaskRetry struct {
+	Attempts int    `yaml:"attempts" json:"attempts"`
+	Delay    string `yaml:"delay,omitempty" json:"delay,omitempty"`
+	Backoff  string `yaml:"backoff,omitempty" json:"backoff,omitempty"`
+}
+
+// MaxAttempts returns how often the task's command runs before it fails,
+// which is once without a retry setting.
+func (t *Task) MaxAttempts() int {
+	if t.Retry == nil || t.Retry.Attempts < 1 {
+		return 1
+	}
+	return t.Retry.Attempts
+}
+
+// RetryDelay returns how long to wait before the given attempt, counting
+// from 1 for the first run of the command.
+func (t *Task) RetryDelay(attempt int) time.Duration {
+	if t.Retry == nil || attempt < 2 {
+		return 0
+	}
+	delay, err := time.ParseDuration(t.Retry.Delay)
+	if err != nil || delay < 0 {
+		return 0
+	}
+	if t.Retry.Backoff == BackoffExponential {
+		for i := 2; i < attempt; i++ {
+			delay *= 2
+		}
+	}
+	return delay
+}
+
+// retryProblems checks the retry setting of a task.
+func retryProblems(prefix, path string, task Task, add func(path, format string, args ...any)) {
+	retry := task.Retry
+	if retry == nil {
+		return
+	}
+	if len(task.Command) == 0 {
+		add(path, "%s: retry is only supported for tasks with a command", prefix)
+	}
+	if retry.Attempts < 1 {
+		add(joinPath(path, "attempts"), "%s: retry attempts must be at least 1", prefix)
+	}
+	if retry.Delay != "" {
+		if delay, err := time.ParseDuration(retry.Delay); err != nil || delay < 0 {
+			add(joinPath(path, "delay"), "%s: invalid retry delay %q (expected a duration such as 5s)", prefix, retry.Delay)
+		}
+	}
+	if retry.Backoff != "" && retry.Backoff != BackoffConstant && retry.Backoff != BackoffExponential {
+		add(joinPath(path, "backoff"), "%s: invalid retry backoff %q (expected constant or exponential)", prefix, retry.Backoff)
+	}
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaskRetryDelay(t *testing.T) {
+	tests := []struct {
+		name         string
+		retry        *TaskRetry
+		wantAttempts int
+		wantDelays   []time.Duration
+	}{
+		{name: "no retry", retry: nil, wantAttempts: 1, wantDelays: []time.Duration{0, 0}},
+		{name: "no delay", retry: &TaskRetry{Attempts: 2}, wantAttempts: 2, wantDelays: []time.Duration{0, 0}},
+		{
+			name:         "constant",
+			retry:        &TaskRetry{Attempts: 4, Delay: "5s"},
+			wantAttempts: 4,
+			wantDelays:   []time.Duration{0, 5 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			name:         "exponential",
+			retry:        &TaskRetry{Attempts: 4, Delay: "5s", Backoff: BackoffExponential},
+			wantAttempts: 4,
+			wantDelays:   []time.Duration{0, 5 * time.Second, 10 * time.Second, 20 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &Task{Command: []string{"true"}, Retry: tt.retry}
+			if got := task.MaxAttempts(); got != tt.wantAttempts {
+				t.Fatalf("MaxAttempts() = %d, want %d", got, tt.wantAttempts)
+			}
+			for i, want := range tt.wantDelays {
+				if got := task.RetryDelay(i + 1); got != want {
+					t.Errorf("RetryDelay(%d) = %v, want %v", i+1, got, want)
+				}
+			}
+		})
+	}
+}
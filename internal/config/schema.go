@@ -0,0 +1,290 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaURL identifies the JSON Schema dialect of Schema.
+const SchemaURL = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema describing doctrus.yml, or one of its values.
+// Settings are objects of named properties that allow no others, maps of
+// names such as workspaces are objects whose additional properties share a
+// schema, and struct types shared between settings are referenced from Defs.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+
+	// closed forbids properties other than Properties
+	closed bool
+}
+
+// MarshalJSON renders a closed schema with additionalProperties: false.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema
+	if !s.closed {
+		return json.Marshal((*plain)(s))
+	}
+	return json.Marshal(struct {
+		*plain
+		AdditionalProperties bool `json:"additionalProperties"`
+	}{(*plain)(s), false})
+}
+
+// ConfigSchema returns the JSON Schema of doctrus.yml, derived from the yaml
+// tags of Config and the types it holds.
+func ConfigSchema() *Schema {
+	defs := make(map[string]*Schema)
+	root := structSchema(reflect.TypeOf(Config{}), defs)
+	root.Schema = SchemaURL
+	root.Title = "doctrus.yml"
+	root.Defs = defs
+	return root
+}
+
+// typeSchema returns the schema of values of type t, adding the schemas of
+// the struct types it references to defs.
+func typeSchema(t reflect.Type, defs map[string]*Schema) *Schema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem(), defs)
+	case reflect.Struct:
+		if _, exists := defs[t.Name()]; !exists {
+			// Reserve the name first so recursive types terminate
+			defs[t.Name()] = nil
+			defs[t.Name()] = structSchema(t, defs)
+		}
+		return &Schema{Ref: "#/$defs/" + t.Name()}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: typeSchema(t.Elem(), defs)}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: typeSchema(t.Elem(), defs)}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	default:
+		return &Schema{Type: "string"}
+	}
+}
+
+// structSchema returns the closed object schema of struct type t, with a
+// property for each exported field named by its yaml tag.
+func structSchema(t reflect.Type, defs map[string]*Schema) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema), closed: true}
+	for i := 0; i < t.NumField(); i++ {
+		if name := yamlName(t.Field(i)); name != "" {
+			schema.Properties[name] = typeSchema(t.Field(i).Type, defs)
+		}
+	}
+	return schema
+}
+
+// yamlName returns the key of a struct field in YAML, or "" when the field
+// isn't decoded.
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	switch {
+	case !field.IsExported() || name == "-":
+		return ""
+	case name == "":
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// CheckSchema checks the document in data, the config file labelled file,
+// against schema, reporting unknown fields and values of the wrong type with
+// their position. It follows YAML aliases and merge keys. Syntax errors are
+// returned as a single diagnostic.
+func CheckSchema(file string, data []byte, schema *Schema) Diagnostics {
+	doc, err := parseDocument(file, data)
+	if err != nil {
+		diagnostics, _ := AsDiagnostics(err)
+		return diagnostics
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	var diagnostics Diagnostics
+	add := func(node *yaml.Node, path, format string, args ...any) {
+		diagnostics = append(diagnostics, Diagnostic{
+			File:    file,
+			Line:    node.Line,
+			Column:  node.Column,
+			Path:    path,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+	checkNode(doc.Content[0], "", schema, schema.Defs, add)
+	return diagnostics
+}
+
+// checkNode checks node, at path, against schema.
+func checkNode(node *yaml.Node, path string, schema *Schema, defs map[string]*Schema, add func(node *yaml.Node, path, format string, args ...any)) {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	if schema.Ref != "" {
+		schema = defs[strings.TrimPrefix(schema.Ref, "#/$defs/")]
+	}
+	if schema == nil || node.Tag == "!!null" {
+		return
+	}
+
+	label := path
+	if label == "" {
+		label = "config"
+	}
+	if want, got := schema.Type, nodeType(node); !typeAccepts(want, got) {
+		add(node, path, "%s: expected %s, got %s", label, withArticle(want), withArticle(got))
+		return
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Tag == "!!merge" {
+				for _, merged := range mergedNodes(value) {
+					checkNode(merged, path, schema, defs, add)
+				}
+				continue
+			}
+			property := schema.Properties[key.Value]
+			switch {
+			case property != nil:
+			case schema.AdditionalProperties != nil:
+				property = schema.AdditionalProperties
+			case schema.closed:
+				message := fmt.Sprintf("%s: unknown field %s", label, key.Value)
+				if suggestion := closestName(key.Value, sortedKeys(schema.Properties)); suggestion != "" {
+					message += fmt.Sprintf(" (did you mean %s?)", suggestion)
+				}
+				add(key, joinPath(path, key.Value), "%s", message)
+				continue
+			default:
+				continue
+			}
+			checkNode(value, joinPath(path, key.Value), property, defs, add)
+		}
+	case yaml.SequenceNode:
+		if schema.Items == nil {
+			return
+		}
+		for i, item := range node.Content {
+			checkNode(item, fmt.Sprintf("%s[%d]", path, i), schema.Items, defs, add)
+		}
+	}
+}
+
+// mergedNodes returns the mappings a merge key merges: its value, or each
+// item of a list of them.
+func mergedNodes(value *yaml.Node) []*yaml.Node {
+	for value.Kind == yaml.AliasNode && value.Alias != nil {
+		value = value.Alias
+	}
+	if value.Kind == yaml.SequenceNode {
+		return value.Content
+	}
+	return []*yaml.Node{value}
+}
+
+// nodeType returns the JSON Schema type of a YAML node.
+func nodeType(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch node.Tag {
+	case "!!bool":
+		return "boolean"
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// typeAccepts reports whether a value of type got decodes into a setting of
+// type want. Like the YAML decoder, strings accept any scalar and numbers
+// accept integers.
+func typeAccepts(want, got string) bool {
+	switch want {
+	case "", got:
+		return true
+	case "string":
+		return got != "object" && got != "array"
+	case "number":
+		return got == "integer"
+	default:
+		return false
+	}
+}
+
+// withArticle names a schema type for messages, such as "a list".
+func withArticle(schemaType string) string {
+	switch schemaType {
+	case "object":
+		return "a mapping"
+	case "array":
+		return "a list"
+	case "integer":
+		return "an integer"
+	default:
+		return "a " + schemaType
+	}
+}
+
+// closestName returns the name in names nearest to name, if it is close
+// enough to be a likely typo.
+func closestName(name string, names []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range names {
+		if distance := editDistance(name, candidate); distance < bestDistance && distance < len(name) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = slices.Min([]int{previous[j] + 1, current[j-1] + 1, previous[j-1] + cost})
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
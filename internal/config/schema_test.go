@@ -0,0 +1,128 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConfigSchema(t *testing.T) {
+	schema := ConfigSchema()
+
+	task := schema.Defs["Task"]
+	if task == nil {
+		t.Fatal("ConfigSchema() has no Task definition")
+	}
+	for _, name := range []string{"command", "depends_on", "extends", "params", "tags", "aliases", "internal"} {
+		if task.Properties[name] == nil {
+			t.Errorf("Task schema lacks %s", name)
+		}
+	}
+	if got := task.Properties["cache"].Type; got != "boolean" {
+		t.Errorf("cache type = %q, want boolean", got)
+	}
+	if got := schema.Properties["templates"].AdditionalProperties.Ref; got != "#/$defs/Task" {
+		t.Errorf("templates = %q, want references to Task", got)
+	}
+	if got := schema.Properties["profiles"].AdditionalProperties.Ref; got != "#/$defs/Profile" {
+		t.Errorf("profiles = %q, want references to Profile", got)
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded["$schema"] != SchemaURL || decoded["additionalProperties"] != false {
+		t.Errorf("schema = $schema %v, additionalProperties %v; want a closed draft 2020-12 schema", decoded["$schema"], decoded["additionalProperties"])
+	}
+	env := decoded["properties"].(map[string]any)["env"].(map[string]any)
+	if _, ok := env["additionalProperties"].(map[string]any); !ok {
+		t.Errorf("env additionalProperties = %v, want a schema", env["additionalProperties"])
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name: "valid",
+			content: `version: 1.0
+templates:
+  npm: &npm
+    command: ["npm", "run", "build"]
+    cache: true
+workspaces:
+  web:
+    path: web
+    tags: [js]
+    tasks:
+      build:
+        <<: *npm
+        aliases: [b]
+        params:
+          - name: mode
+            default: production
+        env:
+          PORT: 3000
+profiles:
+  ci:
+    workspaces:
+      web:
+        tasks:
+          build:
+            container: ~
+`,
+		},
+		{
+			name:    "unknown fields",
+			content: "version: \"1.0\"\nworkspace:\n  web: {}\nworkspaces:\n  web:\n    path: web\n    tasks:\n      build:\n        comand: [\"make\"]\n        frobnicate: true\n",
+			want: []string{
+				"doctrus.yml:2:1: config: unknown field workspace (did you mean workspaces?)",
+				"doctrus.yml:9:9: workspaces.web.tasks.build: unknown field comand (did you mean command?)",
+				"doctrus.yml:10:9: workspaces.web.tasks.build: unknown field frobnicate",
+			},
+		},
+		{
+			name:    "type mismatches",
+			content: "version: \"1.0\"\nworkspaces:\n  web:\n    path: web\n    tasks:\n      build:\n        command: make\n        cache: sometimes\n        retry:\n          attempts: many\n        env: [A=1]\n",
+			want: []string{
+				"doctrus.yml:7:18: workspaces.web.tasks.build.command: expected a list, got a string",
+				"doctrus.yml:8:16: workspaces.web.tasks.build.cache: expected a boolean, got a string",
+				"doctrus.yml:10:21: workspaces.web.tasks.build.retry.attempts: expected an integer, got a string",
+				"doctrus.yml:11:14: workspaces.web.tasks.build.env: expected a mapping, got a list",
+			},
+		},
+		{
+			name:    "merged mapping",
+			content: "version: \"1.0\"\ntemplates:\n  base: &base\n    cach: true\nworkspaces:\n  web:\n    path: web\n    tasks:\n      build:\n        <<: *base\n        command: [\"make\"]\n",
+			want: []string{
+				"doctrus.yml:4:5: templates.base: unknown field cach (did you mean cache?)",
+				"doctrus.yml:4:5: workspaces.web.tasks.build: unknown field cach (did you mean cache?)",
+			},
+		},
+		{
+			name:    "syntax error",
+			content: "version: \"1.0\"\nworkspaces: [\n",
+			want:    []string{"doctrus.yml:2: did not find expected node content"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, diagnostic := range CheckSchema("doctrus.yml", []byte(tt.content), ConfigSchema()) {
+				got = append(got, diagnostic.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("CheckSchema() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// scopeDocument returns a copy of doc whose workspaces section only holds the
+// workspaces reachable from specs through depends_on, so the rest of the
+// document is never decoded or validated. Specs are "workspace:task", or a
+// bare "task", ":task" or "*:task", which reach every workspace defining it;
+// the workspace and task may be glob patterns such as "front*:build". It
+// returns nil when the document has to be loaded in full: when it uses
+// providers or import_workspaces, whose generated workspaces and tasks may
+// add dependencies, or when nothing is
+// reachable, so that lookups of unknown tasks report against the complete
+// configuration.
+func scopeDocument(doc *yaml.Node, specs []string) *yaml.Node {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	root := doc.Content[0]
+
+	for _, key := range []string{"providers", "import_workspaces"} {
+		if generators := mappingValue(root, key); generators != nil && len(generators.Content) > 0 {
+			return nil
+		}
+	}
+	workspaces := mappingValue(root, "workspaces")
+	if workspaces == nil || workspaces.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	reached := reachableWorkspaces(workspaces, specs)
+	if len(reached) == 0 {
+		return nil
+	}
+
+	scoped := *workspaces
+	scoped.Content = nil
+	for i := 0; i+1 < len(workspaces.Content); i += 2 {
+		if reached[workspaces.Content[i].Value] {
+			scoped.Content = append(scoped.Content, workspaces.Content[i], workspaces.Content[i+1])
+		}
+	}
+
+	scopedRoot := *root
+	scopedRoot.Content = make([]*yaml.Node, len(root.Content))
+	copy(scopedRoot.Content, root.Content)
+	for i := 0; i+1 < len(scopedRoot.Content); i += 2 {
+		if scopedRoot.Content[i].Value == "workspaces" {
+			scopedRoot.Content[i+1] = &scoped
+		}
+	}
+
+	scopedDoc := *doc
+	scopedDoc.Content = []*yaml.Node{&scopedRoot}
+	return &scopedDoc
+}
+
+// AllWorkspacesTask returns the task named by a spec of the forms ":task"
+// and "*:task", which name the task in every workspace defining it, and
+// whether spec is one of them.
+func AllWorkspacesTask(spec string) (string, bool) {
+	for _, prefix := range []string{"*:", ":"} {
+		if taskName, ok := strings.CutPrefix(spec, prefix); ok {
+			return taskName, true
+		}
+	}
+	return "", false
+}
+
+// SplitTaskSpec splits a task spec at its first colon into a workspace and a
+// task, so task names may contain colons. The workspace is empty for a bare
+// "task", and for ":task" and "*:task", which name the task in every
+// workspace.
+func SplitTaskSpec(spec string) (string, string) {
+	if taskName, ok := AllWorkspacesTask(spec); ok {
+		return "", taskName
+	}
+	workspaceName, taskName, ok := strings.Cut(spec, ":")
+	if !ok {
+		return "", spec
+	}
+	return workspaceName, taskName
+}
+
+// SplitDependency splits an entry of the depends_on of a task in
+// workspaceName like SplitTaskSpec, a bare task naming one of the
+// workspace's own. Entries naming a task in every workspace are invalid.
+func SplitDependency(workspaceName, dep string) (string, string, error) {
+	if _, ok := AllWorkspacesTask(dep); ok {
+		return "", "", fmt.Errorf("invalid dependency format: %s", dep)
+	}
+	depWorkspace, depTask := SplitTaskSpec(dep)
+	if depWorkspace == "" {
+		depWorkspace = workspaceName
+	}
+	return depWorkspace, depTask, nil
+}
+
+// IsTaskPattern reports whether the workspace or task name of a spec is a
+// glob pattern, matched with the syntax of path.Match.
+func IsTaskPattern(name string) bool {
+	return strings.ContainsAny(name, "*?[\\")
+}
+
+// reachableWorkspaces walks depends_on from the tasks named by specs without
+// decoding the workspaces, returning the name of every workspace visited.
+func reachableWorkspaces(workspaces *yaml.Node, specs []string) map[string]bool {
+	type taskRef struct{ workspace, task string }
+
+	tasksByWorkspace := make(map[string]*yaml.Node)
+	var order []string
+	for i := 0; i+1 < len(workspaces.Content); i += 2 {
+		name := workspaces.Content[i].Value
+		tasksByWorkspace[name] = mappingValue(workspaces.Content[i+1], "tasks")
+		order = append(order, name)
+	}
+
+	var queue []taskRef
+	for _, spec := range specs {
+		workspaceName, taskName := SplitTaskSpec(spec)
+		if workspaceName == "" {
+			workspaceName = "*"
+		}
+		if !IsTaskPattern(workspaceName) && !IsTaskPattern(taskName) {
+			queue = append(queue, taskRef{workspaceName, taskName})
+			continue
+		}
+		for _, name := range order {
+			if matched, _ := path.Match(workspaceName, name); !matched {
+				continue
+			}
+			tasks := tasksByWorkspace[name]
+			if !IsTaskPattern(taskName) {
+				if canonical, task := taskNode(tasks, taskName); task != nil {
+					queue = append(queue, taskRef{name, canonical})
+				}
+				continue
+			}
+			for i := 0; tasks != nil && i+1 < len(tasks.Content); i += 2 {
+				if matched, _ := path.Match(taskName, tasks.Content[i].Value); matched {
+					queue = append(queue, taskRef{name, tasks.Content[i].Value})
+				}
+			}
+		}
+	}
+
+	reached := make(map[string]bool)
+	seen := make(map[taskRef]bool)
+	for len(queue) > 0 {
+		ref := queue[0]
+		queue = queue[1:]
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+
+		tasks, exists := tasksByWorkspace[ref.workspace]
+		if !exists {
+			continue
+		}
+		reached[ref.workspace] = true
+
+		_, task := taskNode(tasks, ref.task)
+		dependsOn := mappingValue(task, "depends_on")
+		if dependsOn == nil || dependsOn.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, dep := range dependsOn.Content {
+			if workspaceName, taskName, err := SplitDependency(ref.workspace, dep.Value); err == nil {
+				queue = append(queue, taskRef{workspaceName, taskName})
+			}
+		}
+	}
+
+	return reached
+}
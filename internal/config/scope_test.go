@@ -0,0 +1,151 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const scopeTestConfig = `version: "1.0"
+workspaces:
+  app:
+    tasks:
+      build:
+        command: ["make"]
+        depends_on: ["lib:build", "gen"]
+        aliases: [compile]
+      gen:
+        command: ["gen"]
+      lint:
+        command: ["lint"]
+  lib:
+    tasks:
+      build:
+        command: ["make"]
+        depends_on: ["proto:gen"]
+  proto:
+    tasks:
+      gen:
+        command: ["protoc"]
+  docs:
+    tasks:
+      build:
+        command: ["mkdocs", "build"]
+      lint:
+        command: ["vale"]
+        aliases: [check]
+  broken:
+    path: ./missing
+    tasks:
+      build:
+        cache: sometimes
+`
+
+func writeScopeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "doctrus.yml")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadScoped(t *testing.T) {
+	path := writeScopeConfig(t, scopeTestConfig)
+
+	tests := []struct {
+		name  string
+		specs []string
+		want  []string
+	}{
+		{name: "dependencies across workspaces", specs: []string{"app:build"}, want: []string{"app", "lib", "proto"}},
+		{name: "single task", specs: []string{"docs:lint"}, want: []string{"docs"}},
+		{name: "bare task name", specs: []string{"lint"}, want: []string{"app", "docs"}},
+		{name: "task in every workspace", specs: []string{":lint"}, want: []string{"app", "docs"}},
+		{name: "task in any workspace", specs: []string{"*:lint"}, want: []string{"app", "docs"}},
+		{name: "alias", specs: []string{"app:compile"}, want: []string{"app", "lib", "proto"}},
+		{name: "bare alias", specs: []string{"check"}, want: []string{"docs"}},
+		{name: "workspace pattern", specs: []string{"d*:build"}, want: []string{"docs"}},
+		{name: "task pattern", specs: []string{"proto:g?n", "docs:l*"}, want: []string{"docs", "proto"}},
+		{name: "several specs", specs: []string{"proto:gen", "docs:build"}, want: []string{"docs", "proto"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, _, err := LoadScoped(path, tt.specs)
+			if err != nil {
+				t.Fatalf("LoadScoped() error = %v", err)
+			}
+
+			var got []string
+			for name := range cfg.Workspaces {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("workspaces = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadScopedFallsBackToFullLoad(t *testing.T) {
+	path := writeScopeConfig(t, scopeTestConfig)
+
+	for _, specs := range [][]string{nil, {"unknown:task"}, {"broken:build"}} {
+		if _, _, err := LoadScoped(path, specs); err == nil {
+			t.Fatalf("LoadScoped(%v) should decode the broken workspace", specs)
+		}
+	}
+	if _, _, err := Load(path); err == nil {
+		t.Fatal("Load() should decode every workspace")
+	}
+}
+
+func TestSplitTaskSpec(t *testing.T) {
+	tests := []struct {
+		spec      string
+		workspace string
+		task      string
+	}{
+		{spec: "build", task: "build"},
+		{spec: "web:build", workspace: "web", task: "build"},
+		{spec: ":build", task: "build"},
+		{spec: "*:build", task: "build"},
+		{spec: "web:gen:js", workspace: "web", task: "gen:js"},
+		{spec: "*:gen:js", task: "gen:js"},
+	}
+	for _, tt := range tests {
+		workspace, task := SplitTaskSpec(tt.spec)
+		if workspace != tt.workspace || task != tt.task {
+			t.Errorf("SplitTaskSpec(%q) = %q, %q; want %q, %q", tt.spec, workspace, task, tt.workspace, tt.task)
+		}
+	}
+}
+
+func TestSplitDependency(t *testing.T) {
+	tests := []struct {
+		dep       string
+		workspace string
+		task      string
+		wantErr   bool
+	}{
+		{dep: "build", workspace: "app", task: "build"},
+		{dep: "lib:build", workspace: "lib", task: "build"},
+		{dep: "lib:gen:js", workspace: "lib", task: "gen:js"},
+		{dep: ":build", wantErr: true},
+		{dep: "*:build", wantErr: true},
+	}
+	for _, tt := range tests {
+		workspace, task, err := SplitDependency("app", tt.dep)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SplitDependency(app, %q) error = %v, want error %v", tt.dep, err, tt.wantErr)
+			continue
+		}
+		if workspace != tt.workspace || task != tt.task {
+			t.Errorf("SplitDependency(app, %q) = %q, %q; want %q, %q", tt.dep, workspace, task, tt.workspace, tt.task)
+		}
+	}
+}
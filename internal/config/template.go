@@ -0,0 +1,148 @@
+package config
+
+import (
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// appendedTemplateKeys are the task settings whose lists are joined with the
+// template's rather than replacing them.
+var appendedTemplateKeys = map[string]bool{"inputs": true, "outputs": true}
+
+// applyTemplates merges the template each task extends into the task before
+// the document is decoded, so tasks only spell out what differs. Mappings
+// such as env are merged key by key, inputs and outputs are appended to the
+// template's and any other setting of the task replaces the template's.
+// Templates may extend other templates. Unknown templates and cycles are
+// left to validation.
+func applyTemplates(doc *yaml.Node) {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return
+	}
+	root := doc.Content[0]
+	templates := mappingValue(root, "templates")
+	if templates == nil || templates.Kind != yaml.MappingNode {
+		return
+	}
+
+	resolved := make(map[string]bool)
+	resolving := make(map[string]bool)
+	var resolve func(name string) *yaml.Node
+	resolve = func(name string) *yaml.Node {
+		template := mappingValue(templates, name)
+		if template == nil || template.Kind != yaml.MappingNode || resolving[name] {
+			return nil
+		}
+		if !resolved[name] {
+			resolving[name] = true
+			if base := resolve(extendsOf(template)); base != nil {
+				mergeTemplate(template, base)
+			}
+			resolving[name] = false
+			resolved[name] = true
+		}
+		return template
+	}
+
+	workspaces := mappingValue(root, "workspaces")
+	if workspaces == nil || workspaces.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 1; i < len(workspaces.Content); i += 2 {
+		tasks := mappingValue(workspaces.Content[i], "tasks")
+		if tasks == nil || tasks.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 1; j < len(tasks.Content); j += 2 {
+			task := tasks.Content[j]
+			if task.Kind != yaml.MappingNode {
+				continue
+			}
+			if template := resolve(extendsOf(task)); template != nil {
+				mergeTemplate(task, template)
+			}
+		}
+	}
+}
+
+// extendsOf returns the template a task or template extends.
+func extendsOf(node *yaml.Node) string {
+	if extends := mappingValue(node, "extends"); extends != nil && extends.Kind == yaml.ScalarNode {
+		return extends.Value
+	}
+	return ""
+}
+
+// mergeTemplate adds the settings of template to the mapping node.
+func mergeTemplate(node, template *yaml.Node) {
+	for i := 0; i+1 < len(template.Content); i += 2 {
+		key, value := template.Content[i], template.Content[i+1]
+		if key.Value == "extends" {
+			continue
+		}
+		own := mappingValue(node, key.Value)
+		switch {
+		case own == nil:
+			node.Content = append(node.Content, cloneNode(key), cloneNode(value))
+		case own.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeTemplate(own, value)
+		case own.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode && appendedTemplateKeys[key.Value]:
+			items := make([]*yaml.Node, 0, len(value.Content)+len(own.Content))
+			seen := make(map[string]bool)
+			for _, item := range slices.Concat(value.Content, own.Content) {
+				if item.Kind == yaml.ScalarNode {
+					if seen[item.Value] {
+						continue
+					}
+					seen[item.Value] = true
+				}
+				items = append(items, cloneNode(item))
+			}
+			own.Content = items
+		}
+	}
+}
+
+// cloneNode returns a deep copy of node, so tasks extending one template
+// never share nodes.
+func cloneNode(node *yaml.Node) *yaml.Node {
+	clone := *node
+	clone.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		clone.Content[i] = cloneNode(child)
+	}
+	return &clone
+}
+
+// templateProblems reports tasks and templates extending unknown templates,
+// and templates extending themselves.
+func (c *Config) templateProblems(add func(path, format string, args ...any)) {
+	for _, name := range sortedKeys(c.Templates) {
+		path := joinPath("templates", name, "extends")
+		extends := c.Templates[name].Extends
+		if _, exists := c.Templates[extends]; extends != "" && !exists {
+			add(path, "template %s: unknown template %q", name, extends)
+			continue
+		}
+		// Chains longer than the number of templates loop without name
+		chain := []string{name}
+		for next := extends; next != "" && len(chain) <= len(c.Templates); next = c.Templates[next].Extends {
+			if next == name {
+				add(path, "template %s: extends itself through %s", name, strings.Join(append(chain, name), " -> "))
+				break
+			}
+			chain = append(chain, next)
+		}
+	}
+
+	for _, name := range sortedKeys(c.Workspaces) {
+		for _, taskName := range sortedKeys(c.Workspaces[name].Tasks) {
+			extends := c.Workspaces[name].Tasks[taskName].Extends
+			if _, exists := c.Templates[extends]; extends != "" && !exists {
+				add(joinPath("workspaces", name, "tasks", taskName, "extends"), "workspace %s, task %s: unknown template %q", name, taskName, extends)
+			}
+		}
+	}
+}
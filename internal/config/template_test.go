@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigLoadTemplates(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "doctrus.yml")
+	content := `version: "1.0"
+templates:
+  npm:
+    command: ["npm", "run", "build"]
+    inputs: ["package.json", "src/**"]
+    outputs: ["dist/**"]
+    env:
+      NODE_ENV: production
+      CI: "true"
+    cache: true
+  npm-test:
+    extends: npm
+    command: ["npm", "test"]
+workspaces:
+  web:
+    path: web
+    tasks:
+      build:
+        extends: npm
+        inputs: ["vite.config.ts", "src/**"]
+        env:
+          NODE_ENV: staging
+      test:
+        extends: npm-test
+        depends_on: ["build"]
+        cache: false
+  docs:
+    path: docs
+    tasks:
+      build:
+        extends: npm
+        command: ["npm", "run", "docs"]
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, _, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	build := cfg.Workspaces["web"].Tasks["build"]
+	if !reflect.DeepEqual(build.Command, []string{"npm", "run", "build"}) {
+		t.Errorf("build command = %v, want the template's", build.Command)
+	}
+	if want := []string{"package.json", "src/**", "vite.config.ts"}; !reflect.DeepEqual(build.Inputs, want) {
+		t.Errorf("build inputs = %v, want %v", build.Inputs, want)
+	}
+	if want := map[string]string{"NODE_ENV": "staging", "CI": "true"}; !reflect.DeepEqual(build.Env, want) {
+		t.Errorf("build env = %v, want %v", build.Env, want)
+	}
+	if !build.Cache || !reflect.DeepEqual(build.Outputs, []string{"dist/**"}) {
+		t.Errorf("build = cache %v, outputs %v; want the template's", build.Cache, build.Outputs)
+	}
+
+	test := cfg.Workspaces["web"].Tasks["test"]
+	if !reflect.DeepEqual(test.Command, []string{"npm", "test"}) || test.Cache {
+		t.Errorf("test = command %v, cache %v; want its template's command and its own cache", test.Command, test.Cache)
+	}
+	if !reflect.DeepEqual(test.Outputs, []string{"dist/**"}) || test.Env["NODE_ENV"] != "production" {
+		t.Errorf("test = outputs %v, env %v; want the settings of npm through npm-test", test.Outputs, test.Env)
+	}
+
+	docs := cfg.Workspaces["docs"].Tasks["build"]
+	if !reflect.DeepEqual(docs.Command, []string{"npm", "run", "docs"}) || docs.Env["NODE_ENV"] != "production" {
+		t.Errorf("docs build = command %v, env %v", docs.Command, docs.Env)
+	}
+}
+
+func TestConfigLoadTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "unknown template",
+			content: "version: \"1.0\"\nworkspaces:\n  app:\n    path: .\n    tasks:\n      build:\n        extends: npm\n",
+			wantErr: `workspace app, task build: unknown template "npm"`,
+		},
+		{
+			name:    "unknown base template",
+			content: "version: \"1.0\"\ntemplates:\n  npm:\n    extends: node\n    command: [\"npm\"]\nworkspaces:\n  app:\n    path: .\n    tasks:\n      build:\n        extends: npm\n",
+			wantErr: `template npm: unknown template "node"`,
+		},
+		{
+			name:    "cycle",
+			content: "version: \"1.0\"\ntemplates:\n  a:\n    extends: b\n  b:\n    extends: a\n    command: [\"make\"]\nworkspaces:\n  app:\n    path: .\n    tasks:\n      build:\n        extends: a\n",
+			wantErr: "template a: extends itself through a -> b -> a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "doctrus.yml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			_, _, err := Load(configPath)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// GetEffectiveWrapper returns the command prefixed to a task's commands, such
+// as ["nix", "develop", "-c"]. A wrapper on the task wins, then the
+// workspace's, then the global one; an empty list on a task or workspace
+// turns off the wrapper it would inherit.
+func (c *Config) GetEffectiveWrapper(workspaceName, taskName string) []string {
+	workspace, exists := c.Workspaces[workspaceName]
+	if !exists {
+		return c.Wrapper
+	}
+	if task, exists := workspace.Tasks[taskName]; exists && task.Wrapper != nil {
+		return task.Wrapper
+	}
+	if workspace.Wrapper != nil {
+		return workspace.Wrapper
+	}
+	return c.Wrapper
+}
+
+// WrapCommand prefixes command with the task's wrapper. Commands are
+// returned unchanged when the task has no wrapper.
+func (c *Config) WrapCommand(workspaceName, taskName string, command []string) []string {
+	wrapper := c.GetEffectiveWrapper(workspaceName, taskName)
+	if len(wrapper) == 0 || len(command) == 0 {
+		return command
+	}
+	return append(slices.Clone(wrapper), command...)
+}
+
+// wrapperProblems reports empty arguments in a wrapper.
+func wrapperProblems(prefix, path string, wrapper []string, add func(path, format string, args ...any)) {
+	for i, arg := range wrapper {
+		if arg == "" {
+			add(fmt.Sprintf("%s[%d]", path, i), "%s: wrapper[%d] is empty", prefix, i)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigWrapper(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "doctrus.yml")
+	content := `version: "1.0"
+wrapper: ["nix", "develop", "-c"]
+workspaces:
+  web:
+    path: web
+    wrapper: ["mise", "x", "--"]
+    tasks:
+      build:
+        command: ["npm", "run", "build"]
+      lint:
+        command: ["npm", "run", "lint"]
+        wrapper: ["asdf", "exec"]
+      deploy:
+        command: ["./deploy.sh"]
+        wrapper: []
+  api:
+    path: api
+    tasks:
+      test:
+        command: ["go", "test", "./..."]
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, _, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		workspace string
+		task      string
+		want      []string
+	}{
+		{"web", "build", []string{"mise", "x", "--", "npm", "run", "build"}},
+		{"web", "lint", []string{"asdf", "exec", "npm", "run", "lint"}},
+		{"web", "deploy", []string{"./deploy.sh"}},
+		{"api", "test", []string{"nix", "develop", "-c", "go", "test", "./..."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.workspace+":"+tt.task, func(t *testing.T) {
+			task, _ := cfg.GetTask(tt.workspace, tt.task)
+			got := cfg.WrapCommand(tt.workspace, tt.task, task.Command)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WrapCommand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := cfg.WrapCommand("api", "test", nil); got != nil {
+		t.Errorf("WrapCommand() of a compound task = %v, want nil", got)
+	}
+}
+
+func TestConfigValidateWrapper(t *testing.T) {
+	cfg := &Config{
+		Version: "1.0",
+		Workspaces: map[string]Workspace{
+			"web": {
+				Path: "web",
+				Tasks: map[string]Task{
+					"build": {Command: []string{"make"}, Wrapper: []string{"nix", ""}},
+				},
+			},
+		},
+	}
+
+	err := cfg.validate()
+	if err == nil || !strings.Contains(err.Error(), "wrapper[1] is empty") {
+		t.Fatalf("validate() error = %v, want empty wrapper argument", err)
+	}
+}
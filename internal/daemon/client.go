@@ -0,0 +1,87 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Client talks to the daemon of a project.
+type Client struct {
+	http *http.Client
+}
+
+// Dial connects to the daemon of the project at root, failing quickly when
+// none is running.
+func Dial(root string) (*Client, error) {
+	path := SocketPath(root)
+	conn, err := net.DialTimeout("unix", path, 200*time.Millisecond)
+	if err != nil {
+		return nil, fmt.Errorf("no daemon running: %w", err)
+	}
+	conn.Close()
+
+	dialer := net.Dialer{Timeout: time.Second}
+	return &Client{http: &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", path)
+			},
+		},
+	}}, nil
+}
+
+// HashFiles returns the sha256 hashes in hex of the files at the absolute
+// paths. Files the daemon can't hash are left out.
+func (c *Client) HashFiles(paths []string) (map[string]string, error) {
+	body, err := json.Marshal(HashRequest{Paths: paths})
+	if err != nil {
+		return nil, err
+	}
+	var response HashResponse
+	if err := c.do(http.MethodPost, HashPath, bytes.NewReader(body), &response); err != nil {
+		return nil, err
+	}
+	return response.Hashes, nil
+}
+
+// Status returns the state of the daemon.
+func (c *Client) Status() (Status, error) {
+	var status Status
+	err := c.do(http.MethodGet, StatusPath, nil, &status)
+	return status, err
+}
+
+// Stop asks the daemon to shut down.
+func (c *Client) Stop() error {
+	return c.do(http.MethodPost, StopPath, nil, nil)
+}
+
+func (c *Client) do(method, path string, body io.Reader, result any) error {
+	req, err := http.NewRequest(method, "http://daemon"+path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("daemon request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("daemon returned %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("invalid daemon response: %w", err)
+	}
+	return nil
+}
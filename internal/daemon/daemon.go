@@ -0,0 +1,453 @@
+// Package daemon keeps the hashes of a project's files in memory between
+// doctrus invocations. The daemon, started with doctrus daemon start, serves
+// HTTP on a unix socket in the project's .doctrus directory; the client
+// hashes files through it, so a run only reads the files changed since the
+// daemon last hashed them instead of every input of every task. The
+// directories of hashed files are watched, and a hash is forgotten when its
+// file changes, so hashes of unchanged files are served without touching
+// them.
+//
+// POST /v1/hash takes a JSON HashRequest and returns a HashResponse. GET
+// /v1/status returns a Status, and POST /v1/stop shuts the daemon down.
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	HashPath   = "/v1/hash"
+	StatusPath = "/v1/status"
+	StopPath   = "/v1/stop"
+)
+
+// syncTimeout bounds the wait for the watcher to catch up with the changes
+// made before a request; hashes are checked against their files after it.
+const syncTimeout = time.Second
+
+// racyWindow is how recently a file may have been modified for its hash not
+// to be remembered: a write in the same timestamp tick as the hashing could
+// change the file without changing its size or modification time.
+const racyWindow = 2 * time.Second
+
+// HashRequest lists absolute paths of files to hash.
+type HashRequest struct {
+	Paths []string `json:"paths"`
+}
+
+// HashResponse maps the requested paths to their sha256 hashes in hex.
+// Files that can't be hashed, such as missing ones, are left out.
+type HashResponse struct {
+	Hashes map[string]string `json:"hashes"`
+}
+
+// Status describes a running daemon.
+type Status struct {
+	PID       int       `json:"pid"`
+	Root      string    `json:"root"`
+	StartedAt time.Time `json:"started_at"`
+	// Files is the number of file hashes held in memory, Hashed how many
+	// files were read and Reused how many hashes were served from memory
+	Files  int   `json:"files"`
+	Hashed int64 `json:"hashed"`
+	Reused int64 `json:"reused"`
+	// Watched is the number of directories watched for changes
+	Watched int `json:"watched"`
+}
+
+// SocketPath returns the socket of the daemon of the project at root. Paths
+// too long for a unix socket fall back to the temporary directory.
+func SocketPath(root string) string {
+	path := filepath.Join(root, ".doctrus", "daemon.sock")
+	if len(path) < 100 {
+		return path
+	}
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(os.TempDir(), "doctrus-"+hex.EncodeToString(sum[:8])+".sock")
+}
+
+// Server remembers file hashes by path, size and modification time. Hashes
+// of files in watched directories are forgotten when the files change, and
+// served without checking the files until then; the others are checked
+// against the size and modification time of their files.
+type Server struct {
+	root        string
+	idleTimeout time.Duration
+	startedAt   time.Time
+	// events is nil when the platform can't watch files
+	events *fsnotify.Watcher
+	// sentinel is written by every request and synced receives its event,
+	// which comes after the events of every change made before the request
+	sentinel string
+	synced   chan struct{}
+	syncMu   sync.Mutex
+
+	mu      sync.Mutex
+	files   map[string]fileHash
+	watched map[string]bool
+	// generation counts file changes, so a hash computed while its file
+	// might have changed is not trusted
+	generation uint64
+	// inSync is set while the watcher has caught up with the changes made
+	// before the request being answered
+	inSync   bool
+	hashed   int64
+	reused   int64
+	lastUsed time.Time
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// fileHash is a remembered hash and the file state it was computed for.
+// trusted is set when no change to the file can have been missed since.
+type fileHash struct {
+	size    int64
+	modTime time.Time
+	hash    string
+	trusted bool
+}
+
+// NewServer returns the daemon of the project at root. It stops after
+// idleTimeout without requests; zero keeps it running.
+func NewServer(root string, idleTimeout time.Duration) *Server {
+	now := time.Now()
+	s := &Server{
+		root:        root,
+		idleTimeout: idleTimeout,
+		startedAt:   now,
+		sentinel:    filepath.Join(root, ".doctrus", "daemon.sync"),
+		synced:      make(chan struct{}, 1),
+		files:       make(map[string]fileHash),
+		watched:     make(map[string]bool),
+		lastUsed:    now,
+		stop:        make(chan struct{}),
+	}
+	if events, err := fsnotify.NewWatcher(); err == nil {
+		s.events = events
+		if err := os.MkdirAll(filepath.Dir(s.sentinel), 0755); err != nil || events.Add(filepath.Dir(s.sentinel)) != nil {
+			events.Close()
+			s.events = nil
+		}
+	}
+	return s
+}
+
+// Listen listens on the project's socket, replacing the socket of a daemon
+// that is no longer running. It fails when a daemon is already running.
+func Listen(root string) (net.Listener, error) {
+	path := SocketPath(root)
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a daemon is already running on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	// Only the user running the daemon may talk to it
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict socket: %w", err)
+	}
+	return listener, nil
+}
+
+// Serve answers requests on listener until ctx is done, a client asks the
+// daemon to stop or it has been idle for the idle timeout.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+HashPath, s.handleHash)
+	mux.HandleFunc("GET "+StatusPath, s.handleStatus)
+	mux.HandleFunc("POST "+StopPath, s.handleStop)
+	server := &http.Server{Handler: mux}
+
+	if s.events != nil {
+		defer s.events.Close()
+		defer os.Remove(s.sentinel)
+		go s.watch()
+	}
+
+	go func() {
+		var idle <-chan time.Time
+		if s.idleTimeout > 0 {
+			ticker := time.NewTicker(min(s.idleTimeout, time.Minute))
+			defer ticker.Stop()
+			idle = ticker.C
+		}
+		for {
+			select {
+			case <-ctx.Done():
+			case <-s.stop:
+			case <-idle:
+				if s.idleFor() < s.idleTimeout {
+					continue
+				}
+			}
+			shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			server.Shutdown(shutdown)
+			return
+		}
+	}()
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("daemon failed: %w", err)
+	}
+	return nil
+}
+
+// Hash returns the hashes of the files at paths, reading only files that
+// changed since they were last hashed.
+func (s *Server) Hash(paths []string) map[string]string {
+	s.sync()
+	hashes := make(map[string]string, len(paths))
+	for _, path := range paths {
+		if hash, ok := s.hash(path); ok {
+			hashes[path] = hash
+		}
+	}
+	return hashes
+}
+
+func (s *Server) hash(path string) (string, bool) {
+	s.mu.Lock()
+	remembered, ok := s.files[path]
+	if ok && remembered.trusted && s.inSync {
+		s.reused++
+		s.mu.Unlock()
+		return remembered.hash, true
+	}
+	s.mu.Unlock()
+
+	// Watch the directory first, so a change made from now on is seen
+	watched := s.watchDir(filepath.Dir(path))
+	s.mu.Lock()
+	generation := s.generation
+	s.mu.Unlock()
+
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	if ok && remembered.size == info.Size() && remembered.modTime.Equal(info.ModTime()) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.reused++
+		remembered.trusted = watched && s.inSync && s.generation == generation
+		s.files[path] = remembered
+		return remembered.hash, true
+	}
+
+	hash, err := hashFile(path)
+	if err != nil {
+		return "", false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hashed++
+	if time.Since(info.ModTime()) > racyWindow {
+		s.files[path] = fileHash{
+			size:    info.Size(),
+			modTime: info.ModTime(),
+			hash:    hash,
+			trusted: watched && s.inSync && s.generation == generation,
+		}
+	} else {
+		delete(s.files, path)
+	}
+	return hash, true
+}
+
+// watchDir watches dir for changes, reporting whether it is watched.
+func (s *Server) watchDir(dir string) bool {
+	if s.events == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.watched[dir] {
+		return true
+	}
+	if err := s.events.Add(dir); err != nil {
+		return false
+	}
+	s.watched[dir] = true
+	return true
+}
+
+// sync waits until the watcher has seen every change made before it was
+// called, by writing the sentinel and waiting for its event. Until it has,
+// hashes are checked against their files.
+func (s *Server) sync() {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	s.setInSync(false)
+	if s.events == nil {
+		return
+	}
+	select {
+	case <-s.synced:
+	default:
+	}
+	if err := os.WriteFile(s.sentinel, []byte(time.Now().String()), 0644); err != nil {
+		return
+	}
+	select {
+	case <-s.synced:
+		s.setInSync(true)
+	case <-time.After(syncTimeout):
+	}
+}
+
+func (s *Server) setInSync(inSync bool) {
+	s.mu.Lock()
+	s.inSync = inSync
+	s.mu.Unlock()
+}
+
+// watch forgets the hashes of changed files until the watcher is closed.
+// When events were lost, every hash is checked against its file again.
+func (s *Server) watch() {
+	for {
+		select {
+		case event, ok := <-s.events.Events:
+			if !ok {
+				return
+			}
+			if event.Name == s.sentinel {
+				select {
+				case s.synced <- struct{}{}:
+				default:
+				}
+				continue
+			}
+			s.forget(event)
+		case _, ok := <-s.events.Errors:
+			if !ok {
+				return
+			}
+			s.mu.Lock()
+			s.generation++
+			for path, file := range s.files {
+				file.trusted = false
+				s.files[path] = file
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// forget drops the hash of the file an event changed, and those of the
+// files below a removed or renamed directory.
+func (s *Server) forget(event fsnotify.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+	delete(s.files, event.Name)
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		prefix := event.Name + string(filepath.Separator)
+		for path := range s.files {
+			if strings.HasPrefix(path, prefix) {
+				delete(s.files, path)
+			}
+		}
+		for dir := range s.watched {
+			if dir == event.Name || strings.HasPrefix(dir, prefix) {
+				delete(s.watched, dir)
+			}
+		}
+	}
+}
+
+// Status returns the state of the daemon.
+func (s *Server) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Status{
+		PID:       os.Getpid(),
+		Root:      s.root,
+		StartedAt: s.startedAt,
+		Files:     len(s.files),
+		Hashed:    s.hashed,
+		Reused:    s.reused,
+		Watched:   len(s.watched),
+	}
+}
+
+func (s *Server) handleHash(w http.ResponseWriter, r *http.Request) {
+	s.touch()
+	var request HashRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	for _, path := range request.Paths {
+		if !filepath.IsAbs(path) {
+			http.Error(w, fmt.Sprintf("path %s is not absolute", path), http.StatusBadRequest)
+			return
+		}
+	}
+	writeJSON(w, HashResponse{Hashes: s.Hash(request.Paths)})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.Status())
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+func (s *Server) touch() {
+	s.mu.Lock()
+	s.lastUsed = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *Server) idleFor() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Since(s.lastUsed)
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,171 @@
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sha(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDaemon(t *testing.T) {
+	root := t.TempDir()
+	listener, err := Listen(root)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	server := NewServer(root, 0)
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(context.Background(), listener) }()
+
+	if _, err := Listen(root); err == nil {
+		t.Error("Listen() with a daemon running succeeded")
+	}
+
+	old := time.Now().Add(-time.Hour)
+	a := filepath.Join(root, "a.txt")
+	b := filepath.Join(root, "b.txt")
+	writeFile(t, a, "a", old)
+	writeFile(t, b, "b", old)
+
+	client, err := Dial(root)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	missing := filepath.Join(root, "missing.txt")
+	for i := 0; i < 2; i++ {
+		hashes, err := client.HashFiles([]string{a, b, missing})
+		if err != nil {
+			t.Fatalf("HashFiles() error = %v", err)
+		}
+		if hashes[a] != sha("a") || hashes[b] != sha("b") || len(hashes) != 2 {
+			t.Fatalf("HashFiles() = %v, want hashes of a and b", hashes)
+		}
+	}
+
+	// A modified file is hashed again; one modified just now is not
+	// remembered, as it could change again within its timestamp
+	writeFile(t, a, "changed", old.Add(time.Minute))
+	writeFile(t, b, "B", time.Now())
+	for i := 0; i < 2; i++ {
+		hashes, err := client.HashFiles([]string{a, b})
+		if err != nil {
+			t.Fatalf("HashFiles() error = %v", err)
+		}
+		if hashes[a] != sha("changed") || hashes[b] != sha("B") {
+			t.Fatalf("HashFiles() after changes = %v", hashes)
+		}
+	}
+
+	status, err := client.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.PID != os.Getpid() || status.Files != 1 || status.Hashed != 5 || status.Reused != 3 {
+		t.Errorf("Status() = %+v, want 1 file held, 5 hashed and 3 reused", status)
+	}
+
+	if _, err := client.HashFiles([]string{"relative.txt"}); err == nil {
+		t.Error("HashFiles() of a relative path succeeded")
+	}
+
+	if err := client.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon did not stop")
+	}
+	if _, err := Dial(root); err == nil {
+		t.Error("Dial() after Stop() succeeded")
+	}
+}
+
+func TestDaemonWatchesFiles(t *testing.T) {
+	root := t.TempDir()
+	listener, err := Listen(root)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	server := NewServer(root, 0)
+	go server.Serve(context.Background(), listener)
+	client, err := Dial(root)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer client.Stop()
+
+	old := time.Now().Add(-time.Hour)
+	a := filepath.Join(root, "src", "a.txt")
+	if err := os.Mkdir(filepath.Dir(a), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, a, "a", old)
+	if hashes, err := client.HashFiles([]string{a}); err != nil || hashes[a] != sha("a") {
+		t.Fatalf("HashFiles() = %v, %v", hashes, err)
+	}
+
+	// A change keeping the size and modification time is only seen by the
+	// watcher
+	for _, content := range []string{"b", "c"} {
+		writeFile(t, a, content, old)
+		hashes, err := client.HashFiles([]string{a})
+		if err != nil {
+			t.Fatalf("HashFiles() error = %v", err)
+		}
+		if hashes[a] != sha(content) {
+			t.Errorf("HashFiles() after writing %q = %v, want its hash", content, hashes)
+		}
+	}
+
+	// A file restored in a removed directory is hashed again
+	if err := os.RemoveAll(filepath.Dir(a)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Dir(a), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, a, "d", old)
+	if hashes, err := client.HashFiles([]string{a}); err != nil || hashes[a] != sha("d") {
+		t.Errorf("HashFiles() after recreating the directory = %v, %v", hashes, err)
+	}
+
+	status, err := client.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.Watched == 0 {
+		t.Errorf("Status() = %+v, want watched directories", status)
+	}
+}
+
+func TestSocketPath(t *testing.T) {
+	if got, want := SocketPath("/project"), filepath.Join("/project", ".doctrus", "daemon.sock"); got != want {
+		t.Errorf("SocketPath() = %s, want %s", got, want)
+	}
+	long := filepath.Join("/home", "user", "projects", "a-very-long-directory-name-for-a-project", "with", "nested", "directories")
+	if got := SocketPath(long); filepath.Dir(got) != filepath.Clean(os.TempDir()) {
+		t.Errorf("SocketPath() of a long root = %s, want a socket in the temporary directory", got)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package daemon
+
+import "os/exec"
+
+// Detach is a no-op where sessions are unavailable.
+func Detach(cmd *exec.Cmd) {}
@@ -0,0 +1,14 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package daemon
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Detach starts cmd in a new session, so the daemon outlives the terminal
+// that started it.
+func Detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
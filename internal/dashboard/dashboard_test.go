@@ -0,0 +1,272 @@
+package dashboard
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/config"
+	"doctrus/internal/history"
+)
+
+func newTestServer(t *testing.T, script string) *httptest.Server {
+	t.Helper()
+	cfg := &config.Config{Workspaces: map[string]config.Workspace{
+		"app": {Path: "app", Tasks: map[string]config.Task{
+			"gen":   {Command: []string{"gen"}},
+			"build": {Command: []string{"build"}, Description: "Build the app", DependsOn: []string{"gen", "lib:build"}},
+		}},
+		"lib": {Path: "lib", Tasks: map[string]config.Task{
+			"build": {Command: []string{"build"}},
+		}},
+	}}
+
+	store := history.NewStore(t.TempDir())
+	err := store.Append(history.Run{
+		ID:        "1",
+		Args:      []string{"run", "app:build"},
+		StartedAt: time.Now(),
+		Success:   true,
+		Tasks: []history.TaskRecord{
+			{Workspace: "app", Task: "gen", Outcome: history.OutcomeCached},
+			{Workspace: "app", Task: "build", Outcome: history.OutcomeSuccess, Duration: time.Second},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dash := NewServer(Options{
+		Config:  cfg,
+		Cache:   cache.NewManager(t.TempDir()),
+		History: store,
+		Command: []string{"sh", "-c", script, "doctrus"},
+		Dir:     t.TempDir(),
+	})
+	server := httptest.NewServer(dash)
+	t.Cleanup(func() {
+		dash.Close()
+		server.Close()
+	})
+	return server
+}
+
+func getJSON(t *testing.T, url string, v any) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s = %d", url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func postJSON(t *testing.T, url, body string, v any) int {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServerDescribesProject(t *testing.T) {
+	server := newTestServer(t, "")
+
+	var workspaces []workspaceView
+	getJSON(t, server.URL+"/api/workspaces", &workspaces)
+	if len(workspaces) != 2 || workspaces[0].Name != "app" || workspaces[1].Name != "lib" {
+		t.Fatalf("workspaces = %+v, want app and lib", workspaces)
+	}
+	if build := workspaces[0].Tasks[0]; build.Key != "app:build" || build.Description != "Build the app" {
+		t.Errorf("first task = %+v, want app:build with its description", build)
+	}
+
+	var graph struct {
+		Nodes []struct {
+			Key   string `json:"key"`
+			Class string `json:"class"`
+		} `json:"nodes"`
+		Edges []string `json:"edges"`
+	}
+	getJSON(t, server.URL+"/api/graph", &graph)
+	classes := make(map[string]string)
+	for _, node := range graph.Nodes {
+		classes[node.Key] = node.Class
+	}
+	want := map[string]string{"app:build": "success", "app:gen": "cached", "lib:build": "pending"}
+	for key, class := range want {
+		if classes[key] != class {
+			t.Errorf("graph class of %s = %q, want %q", key, classes[key], class)
+		}
+	}
+	if len(graph.Edges) != 2 {
+		t.Errorf("graph has %d edges, want 2", len(graph.Edges))
+	}
+
+	var stats statsView
+	getJSON(t, server.URL+"/api/stats", &stats)
+	if stats.Error != "" {
+		t.Errorf("stats error = %q", stats.Error)
+	}
+	if len(stats.Runs) != 1 || stats.Runs[0].Tasks != 2 {
+		t.Errorf("stats runs = %+v, want the recorded run", stats.Runs)
+	}
+	if len(stats.Tasks) != 2 {
+		t.Errorf("stats tasks = %+v, want app:build and app:gen", stats.Tasks)
+	}
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("index Content-Type = %q, want text/html", ct)
+	}
+}
+
+func TestRunStreamsEvents(t *testing.T) {
+	script := `echo "args: $*" >&2
+echo '{"type":"task_started","workspace":"app","task":"gen"}'
+echo 'not an event'
+printf '%s\n' '{"type":"output_chunk","workspace":"app","task":"gen","stream":"stdout","data":"hi\n"}'
+echo '{"type":"task_finished","workspace":"app","task":"gen","status":"failed","exit_code":3}'
+exit 3`
+	server := newTestServer(t, script)
+
+	var run RunView
+	if status := postJSON(t, server.URL+"/api/runs", `{"tasks":["app:gen"],"force":true}`, &run); status != http.StatusAccepted {
+		t.Fatalf("POST /api/runs = %d", status)
+	}
+
+	resp, err := http.Get(server.URL + "/api/runs/" + run.ID + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var received []string
+	var end string
+	scanner := bufio.NewScanner(resp.Body)
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			event = name
+		} else if data, ok := strings.CutPrefix(line, "data: "); ok {
+			received = append(received, event+" "+data)
+			if event == "end" {
+				end = data
+			}
+		}
+	}
+
+	joined := strings.Join(received, "\n")
+	for _, want := range []string{
+		`console "args: run --events ndjson --force app:gen"`,
+		`task {"type":"task_started","workspace":"app","task":"gen"}`,
+		`"data":"hi\n"`,
+		`"status":"failed"`,
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("stream does not contain %s:\n%s", want, joined)
+		}
+	}
+	if strings.Contains(joined, "not an event") {
+		t.Errorf("stream contains stdout that is not an event:\n%s", joined)
+	}
+
+	var finished RunView
+	if err := json.Unmarshal([]byte(end), &finished); err != nil {
+		t.Fatalf("end event %q: %v", end, err)
+	}
+	if finished.Status != RunFailed || finished.ExitCode != 3 || finished.Results["app:gen"] != "failed" {
+		t.Errorf("finished run = %+v, want failed with exit code 3", finished)
+	}
+
+	var runs []RunView
+	getJSON(t, server.URL+"/api/runs", &runs)
+	if len(runs) != 1 || runs[0].Status != RunFailed {
+		t.Errorf("runs = %+v, want the failed run", runs)
+	}
+}
+
+func TestStartRunRejectsRequests(t *testing.T) {
+	server := newTestServer(t, "exec sleep 10")
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"form", "application/x-www-form-urlencoded", `{"tasks":["app:gen"]}`, http.StatusUnsupportedMediaType},
+		{"invalid JSON", "application/json", `{`, http.StatusBadRequest},
+		{"no tasks", "application/json", `{"tasks":[]}`, http.StatusBadRequest},
+		{"unknown task", "application/json", `{"tasks":["app:deploy"]}`, http.StatusBadRequest},
+		{"task without workspace", "application/json", `{"tasks":["gen"]}`, http.StatusBadRequest},
+		{"flag", "application/json", `{"tasks":["--parallel"]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(server.URL+"/api/runs", tt.contentType, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+
+	t.Run("run in progress", func(t *testing.T) {
+		var run RunView
+		if status := postJSON(t, server.URL+"/api/runs", `{"tasks":["app:gen"]}`, &run); status != http.StatusAccepted {
+			t.Fatalf("first run = %d", status)
+		}
+		if status := postJSON(t, server.URL+"/api/runs", `{"tasks":["lib:build"]}`, nil); status != http.StatusConflict {
+			t.Errorf("second run = %d, want %d", status, http.StatusConflict)
+		}
+
+		if status := postJSON(t, server.URL+"/api/runs/"+run.ID+"/cancel", `{}`, nil); status != http.StatusAccepted {
+			t.Fatalf("cancel = %d", status)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for run.Status == RunRunning && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+			getJSON(t, server.URL+"/api/runs/"+run.ID, &run)
+		}
+		if run.Status != RunCanceled {
+			t.Errorf("status after cancel = %q, want %q", run.Status, RunCanceled)
+		}
+	})
+
+	t.Run("unknown run", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/runs/42/events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+		}
+	})
+}
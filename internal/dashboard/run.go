@@ -0,0 +1,297 @@
+package dashboard
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"doctrus/internal/events"
+)
+
+var errRunInProgress = errors.New("a run is already in progress")
+
+// maxBuffered bounds the output kept per run. Later output is dropped, while
+// task status events are always kept.
+const maxBuffered = 16 << 20
+
+// Run statuses
+const (
+	RunRunning  = "running"
+	RunSuccess  = "success"
+	RunFailed   = "failed"
+	RunCanceled = "canceled"
+)
+
+// Run is a doctrus run started from the dashboard. It records the events the
+// run writes to stdout and the messages it writes to stderr for streaming.
+type Run struct {
+	id      string
+	tasks   []string
+	force   bool
+	started time.Time
+	cmd     *exec.Cmd
+	done    chan struct{}
+
+	mu        sync.Mutex
+	messages  []message
+	size      int
+	truncated bool
+	results   map[string]string
+	changed   chan struct{}
+	status    string
+	finished  time.Time
+	exitCode  int
+	err       string
+	canceled  bool
+}
+
+// message is a server-sent event: task carries an events.Event, console a
+// line doctrus printed for humans, and end the finished run.
+type message struct {
+	event string
+	data  []byte
+}
+
+// RunView is a run as the API returns it.
+type RunView struct {
+	ID        string        `json:"id"`
+	Tasks     []string      `json:"tasks"`
+	Force     bool          `json:"force"`
+	Status    string        `json:"status"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration_ns"`
+	ExitCode  int           `json:"exit_code,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	// Results maps each task seen so far to queued, running or the status
+	// of its task_finished event
+	Results map[string]string `json:"results"`
+	// Truncated is set when task output was dropped from the stream
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// startRun runs the tasks with doctrus in the background.
+func (s *Server) startRun(tasks []string, force bool) (*Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, run := range s.runs {
+		if !run.Finished() {
+			return nil, errRunInProgress
+		}
+	}
+	if len(s.opts.Command) == 0 {
+		return nil, fmt.Errorf("no command to start runs with")
+	}
+
+	args := append(slices.Clone(s.opts.Command[1:]), "run", "--events", "ndjson")
+	if force {
+		args = append(args, "--force")
+	}
+	args = append(args, tasks...)
+	cmd := exec.Command(s.opts.Command[0], args...)
+	cmd.Dir = s.opts.Dir
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start run: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start run: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start run: %w", err)
+	}
+
+	s.nextID++
+	run := &Run{
+		id:      strconv.Itoa(s.nextID),
+		tasks:   slices.Clone(tasks),
+		force:   force,
+		started: time.Now(),
+		cmd:     cmd,
+		done:    make(chan struct{}),
+		results: make(map[string]string),
+		changed: make(chan struct{}),
+		status:  RunRunning,
+	}
+	go run.wait(stdout, stderr)
+
+	s.runs = append(s.runs, run)
+	if len(s.runs) > maxRuns {
+		s.runs = slices.Delete(s.runs, 0, len(s.runs)-maxRuns)
+	}
+	return run, nil
+}
+
+// View returns the run's current state.
+func (r *Run) View() RunView {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.view()
+}
+
+func (r *Run) view() RunView {
+	finished := r.finished
+	if finished.IsZero() {
+		finished = time.Now()
+	}
+	results := make(map[string]string, len(r.results))
+	for key, status := range r.results {
+		results[key] = status
+	}
+	return RunView{
+		ID:        r.id,
+		Tasks:     r.tasks,
+		Force:     r.force,
+		Status:    r.status,
+		StartedAt: r.started,
+		Duration:  finished.Sub(r.started),
+		ExitCode:  r.exitCode,
+		Error:     r.err,
+		Results:   results,
+		Truncated: r.truncated,
+	}
+}
+
+// Finished reports whether the run has ended.
+func (r *Run) Finished() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Cancel interrupts the run, which stops its tasks the way Ctrl+C would.
+func (r *Run) Cancel() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Finished() || r.canceled {
+		return
+	}
+	r.canceled = true
+	if err := r.cmd.Process.Signal(os.Interrupt); err != nil {
+		// Interrupts cannot be sent on every platform
+		r.cmd.Process.Kill()
+	}
+}
+
+// since returns the messages after the first n, whether the run has ended,
+// and a channel closed when more arrive.
+func (r *Run) since(n int) ([]message, bool, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.messages[n:]), r.Finished(), r.changed
+}
+
+func (r *Run) wait(stdout, stderr io.Reader) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		r.readEvents(stdout)
+	}()
+	go func() {
+		defer wg.Done()
+		r.readConsole(stderr)
+	}()
+	// Wait closes the pipes, so everything is read first
+	wg.Wait()
+	r.finish(r.cmd.Wait())
+}
+
+func (r *Run) readEvents(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e events.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.Type == "" {
+			continue
+		}
+		r.addEvent(e, slices.Clone(scanner.Bytes()))
+	}
+	io.Copy(io.Discard, stdout)
+}
+
+func (r *Run) readConsole(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		data, _ := json.Marshal(scanner.Text())
+		r.mu.Lock()
+		r.addOutput(message{event: "console", data: data})
+		r.mu.Unlock()
+	}
+	io.Copy(io.Discard, stderr)
+}
+
+func (r *Run) addEvent(e events.Event, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch e.Type {
+	case events.TaskQueued:
+		if _, seen := r.results[e.Key()]; !seen {
+			r.results[e.Key()] = "queued"
+		}
+	case events.TaskStarted:
+		r.results[e.Key()] = RunRunning
+	case events.TaskFinished:
+		r.results[e.Key()] = e.Status
+	case events.OutputChunk:
+		r.addOutput(message{event: "task", data: data})
+		return
+	}
+	r.add(message{event: "task", data: data})
+}
+
+// addOutput records output unless the run's output budget is spent. The
+// caller holds r.mu.
+func (r *Run) addOutput(m message) {
+	if r.size+len(m.data) > maxBuffered {
+		r.truncated = true
+		return
+	}
+	r.size += len(m.data)
+	r.add(m)
+}
+
+// add records a message and wakes the streams waiting for it. The caller
+// holds r.mu.
+func (r *Run) add(m message) {
+	r.messages = append(r.messages, m)
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+func (r *Run) finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = time.Now()
+	switch {
+	case r.canceled:
+		r.status = RunCanceled
+	case err == nil:
+		r.status = RunSuccess
+	default:
+		r.status = RunFailed
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		r.exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		r.err = err.Error()
+	}
+
+	// done is closed before the end message so streams stop after it
+	close(r.done)
+	data, _ := json.Marshal(r.view())
+	r.add(message{event: "end", data: data})
+}
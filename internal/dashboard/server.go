@@ -0,0 +1,434 @@
+// Package dashboard serves a local web UI for a project: its workspaces and
+// task graph, cache and history statistics, and runs started from the browser
+// with their task status and output streamed live. The page is built on a
+// small JSON API that scripts can use as well.
+package dashboard
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/config"
+	"doctrus/internal/history"
+	"doctrus/internal/report"
+)
+
+//go:embed index.html
+var indexPage []byte
+
+// maxRuns is how many runs started from the dashboard are kept in memory.
+const maxRuns = 20
+
+// maxHistoryRuns is how many recorded runs /api/stats returns.
+const maxHistoryRuns = 20
+
+// Options configure a Server.
+type Options struct {
+	Config  *config.Config
+	Cache   *cache.Manager
+	History *history.Store
+	// Command starts doctrus, such as the running executable followed by
+	// its global flags. Runs append the run command and its arguments.
+	Command []string
+	// Dir is the directory runs start in
+	Dir string
+}
+
+// Server serves the dashboard page and its API. It is an http.Handler.
+type Server struct {
+	opts Options
+	mux  *http.ServeMux
+
+	mu     sync.Mutex
+	runs   []*Run
+	nextID int
+}
+
+// NewServer returns a dashboard for the project described by opts.
+func NewServer(opts Options) *Server {
+	s := &Server{opts: opts, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /{$}", s.handleIndex)
+	s.mux.HandleFunc("GET /api/workspaces", s.handleWorkspaces)
+	s.mux.HandleFunc("GET /api/graph", s.handleGraph)
+	s.mux.HandleFunc("GET /api/stats", s.handleStats)
+	s.mux.HandleFunc("GET /api/runs", s.handleRuns)
+	s.mux.HandleFunc("POST /api/runs", s.handleStartRun)
+	s.mux.HandleFunc("GET /api/runs/{id}", s.handleRun)
+	s.mux.HandleFunc("POST /api/runs/{id}/cancel", s.handleCancelRun)
+	s.mux.HandleFunc("GET /api/runs/{id}/events", s.handleRunEvents)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close interrupts the run in progress, if any, and waits for it to end.
+func (s *Server) Close() {
+	if run := s.activeRun(); run != nil {
+		run.Cancel()
+		<-run.done
+	}
+}
+
+type workspaceView struct {
+	Name  string     `json:"name"`
+	Path  string     `json:"path"`
+	Tasks []taskView `json:"tasks"`
+}
+
+type taskView struct {
+	Name        string   `json:"name"`
+	Key         string   `json:"key"`
+	Description string   `json:"description,omitempty"`
+	Command     []string `json:"command,omitempty"`
+	DependsOn   []string `json:"depends_on,omitempty"`
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexPage)
+}
+
+func (s *Server) handleWorkspaces(w http.ResponseWriter, r *http.Request) {
+	var workspaces []workspaceView
+	for _, name := range sortedKeys(s.opts.Config.Workspaces) {
+		ws := s.opts.Config.Workspaces[name]
+		view := workspaceView{Name: name, Path: ws.Path, Tasks: []taskView{}}
+		for _, taskName := range sortedKeys(ws.Tasks) {
+			task := ws.Tasks[taskName]
+			view.Tasks = append(view.Tasks, taskView{
+				Name:        taskName,
+				Key:         name + ":" + taskName,
+				Description: task.Description,
+				Command:     task.Command,
+				DependsOn:   task.DependsOn,
+			})
+		}
+		workspaces = append(workspaces, view)
+	}
+	writeJSON(w, http.StatusOK, workspaces)
+}
+
+// handleGraph lays out every task of the project, colored by how it ended
+// the last time it ran.
+func (s *Server) handleGraph(w http.ResponseWriter, r *http.Request) {
+	last := make(map[string]string)
+	if runs, err := s.opts.History.Load(); err == nil {
+		for _, run := range runs {
+			for _, record := range run.Tasks {
+				last[record.Key()] = string(record.Outcome)
+			}
+		}
+	}
+
+	var tasks []report.Task
+	for _, name := range sortedKeys(s.opts.Config.Workspaces) {
+		ws := s.opts.Config.Workspaces[name]
+		for _, taskName := range sortedKeys(ws.Tasks) {
+			key := name + ":" + taskName
+			tasks = append(tasks, report.Task{
+				Key:    key,
+				Needs:  dependencyKeys(name, ws.Tasks[taskName].DependsOn),
+				Status: last[key],
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, report.Layout(tasks))
+}
+
+type statsView struct {
+	Cache []cacheView   `json:"cache"`
+	Tasks []taskStats   `json:"tasks"`
+	Runs  []historicRun `json:"runs"`
+	Error string        `json:"error,omitempty"`
+}
+
+type cacheView struct {
+	Workspace  string    `json:"workspace"`
+	Entries    int       `json:"entries"`
+	Expired    int       `json:"expired"`
+	Size       int64     `json:"size_bytes"`
+	OutputSize int64     `json:"output_size_bytes"`
+	Newest     time.Time `json:"newest"`
+}
+
+type taskStats struct {
+	Key          string        `json:"key"`
+	Runs         int           `json:"runs"`
+	Executed     int           `json:"executed"`
+	Cached       int           `json:"cached"`
+	Failed       int           `json:"failed"`
+	Average      time.Duration `json:"average_ns"`
+	Max          time.Duration `json:"max_ns"`
+	CacheHitRate float64       `json:"cache_hit_rate"`
+	FailureRate  float64       `json:"failure_rate"`
+}
+
+type historicRun struct {
+	ID        string        `json:"id"`
+	Args      []string      `json:"args"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration_ns"`
+	Success   bool          `json:"success"`
+	Error     string        `json:"error,omitempty"`
+	Tasks     int           `json:"tasks"`
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	stats := statsView{Cache: []cacheView{}, Tasks: []taskStats{}, Runs: []historicRun{}}
+	var errs []error
+
+	workspaces, err := s.opts.Cache.WorkspaceStats()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, ws := range workspaces {
+		stats.Cache = append(stats.Cache, cacheView{
+			Workspace:  ws.Workspace,
+			Entries:    ws.Entries,
+			Expired:    ws.Expired,
+			Size:       ws.Size,
+			OutputSize: ws.OutputSize,
+			Newest:     ws.Newest,
+		})
+	}
+
+	runs, err := s.opts.History.Load()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, task := range history.Summarize(runs) {
+		stats.Tasks = append(stats.Tasks, taskStats{
+			Key:          task.Key,
+			Runs:         task.Runs,
+			Executed:     task.Executed,
+			Cached:       task.Cached,
+			Failed:       task.Failed,
+			Average:      task.Average,
+			Max:          task.Max,
+			CacheHitRate: task.CacheHitRate(),
+			FailureRate:  task.FailureRate(),
+		})
+	}
+	for i := len(runs) - 1; i >= 0 && len(stats.Runs) < maxHistoryRuns; i-- {
+		run := runs[i]
+		stats.Runs = append(stats.Runs, historicRun{
+			ID:        run.ID,
+			Args:      run.Args,
+			StartedAt: run.StartedAt,
+			Duration:  run.Duration,
+			Success:   run.Success,
+			Error:     run.Error,
+			Tasks:     len(run.Tasks),
+		})
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		stats.Error = err.Error()
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	runs := slices.Clone(s.runs)
+	s.mu.Unlock()
+
+	views := make([]RunView, 0, len(runs))
+	for i := len(runs) - 1; i >= 0; i-- {
+		views = append(views, runs[i].View())
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+// runRequest is the body of POST /api/runs.
+type runRequest struct {
+	Tasks []string `json:"tasks"`
+	Force bool     `json:"force"`
+}
+
+func (s *Server) handleStartRun(w http.ResponseWriter, r *http.Request) {
+	if !requireJSON(w, r) {
+		return
+	}
+	var req runRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	if len(req.Tasks) == 0 {
+		writeError(w, http.StatusBadRequest, "no tasks to run")
+		return
+	}
+	for _, task := range req.Tasks {
+		if !s.isTask(task) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown task %q (expected workspace:task)", task))
+			return
+		}
+	}
+
+	run, err := s.startRun(req.Tasks, req.Force)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errRunInProgress) {
+			status = http.StatusConflict
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, run.View())
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	run := s.findRun(r.PathValue("id"))
+	if run == nil {
+		writeError(w, http.StatusNotFound, "unknown run")
+		return
+	}
+	writeJSON(w, http.StatusOK, run.View())
+}
+
+func (s *Server) handleCancelRun(w http.ResponseWriter, r *http.Request) {
+	if !requireJSON(w, r) {
+		return
+	}
+	run := s.findRun(r.PathValue("id"))
+	if run == nil {
+		writeError(w, http.StatusNotFound, "unknown run")
+		return
+	}
+	run.Cancel()
+	writeJSON(w, http.StatusAccepted, run.View())
+}
+
+// handleRunEvents streams a run as server-sent events: everything that
+// happened so far, then new events as they arrive, ending with an end event
+// holding the finished run.
+func (s *Server) handleRunEvents(w http.ResponseWriter, r *http.Request) {
+	run := s.findRun(r.PathValue("id"))
+	if run == nil {
+		writeError(w, http.StatusNotFound, "unknown run")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	sent := 0
+	for {
+		messages, finished, changed := run.since(sent)
+		for _, message := range messages {
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", message.event, message.data)
+		}
+		sent += len(messages)
+		flusher.Flush()
+		if finished {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// isTask reports whether spec names a task of the project as workspace:task.
+func (s *Server) isTask(spec string) bool {
+	workspaceName, taskName, ok := strings.Cut(spec, ":")
+	if !ok {
+		return false
+	}
+	ws, exists := s.opts.Config.Workspaces[workspaceName]
+	if !exists {
+		return false
+	}
+	_, exists = ws.Tasks[taskName]
+	return exists
+}
+
+func (s *Server) findRun(id string) *Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, run := range s.runs {
+		if run.id == id {
+			return run
+		}
+	}
+	return nil
+}
+
+func (s *Server) activeRun() *Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, run := range s.runs {
+		if !run.Finished() {
+			return run
+		}
+	}
+	return nil
+}
+
+// dependencyKeys returns the depends_on entries of a task in workspace as
+// workspace:task keys.
+func dependencyKeys(workspace string, dependsOn []string) []string {
+	var keys []string
+	for _, dep := range dependsOn {
+		dep = strings.TrimSpace(dep)
+		if dep == "" {
+			continue
+		}
+		if !strings.Contains(dep, ":") {
+			dep = workspace + ":" + dep
+		}
+		keys = append(keys, dep)
+	}
+	return keys
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// requireJSON rejects requests that do not send JSON. Browsers only send
+// JSON to another origin after a CORS preflight this server never answers,
+// so other sites cannot start or cancel runs.
+func requireJSON(w http.ResponseWriter, r *http.Request) bool {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "expected a JSON body")
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,85 @@
+package deps
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"doctrus/internal/workspace"
+)
+
+// DefinitionHash digests the parts of a task's definition that change what
+// it produces besides its input files: its command, including any wrapper,
+// its environment, the container it runs in and its executor. Changing any
+// of them invalidates the task's cache entry.
+//
+// The execution should be resolved by the executor (see
+// docker.Dispatcher.Resolve), so the environment covers every layer: the
+// global env, env files, profiles and --env. The environment of an
+// unresolved execution is the env set on its task and workspace.
+func DefinitionHash(execution *workspace.TaskExecution) string {
+	hash := sha256.New()
+	field := func(value string) {
+		hash.Write([]byte(value + "\x00"))
+	}
+
+	field("command")
+	for _, arg := range execution.Task.Command {
+		field(arg)
+	}
+
+	env := execution.Env
+	if env == nil {
+		env = make(map[string]string)
+		if execution.Workspace != nil {
+			for key, value := range execution.Workspace.Env {
+				env[key] = value
+			}
+		}
+		for key, value := range execution.Task.Env {
+			env[key] = value
+		}
+	}
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	field("env")
+	for _, key := range keys {
+		field(key + "=" + env[key])
+	}
+
+	field("container")
+	field(container(execution))
+
+	field("executor")
+	field(execution.Executor)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// TaskDefinition returns the definition digest recorded on an execution, or
+// computes it when none was.
+func TaskDefinition(execution *workspace.TaskExecution) string {
+	if execution.Definition != "" {
+		return execution.Definition
+	}
+	return DefinitionHash(execution)
+}
+
+// container returns the container a task runs in, as
+// config.GetEffectiveContainer does.
+func container(execution *workspace.TaskExecution) string {
+	task := execution.Task
+	switch {
+	case task.Docker != nil && task.Docker.Disable:
+		return ""
+	case task.Container != nil:
+		return *task.Container
+	case execution.Workspace != nil:
+		return execution.Workspace.Container
+	default:
+		return ""
+	}
+}
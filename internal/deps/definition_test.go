@@ -0,0 +1,84 @@
+package deps
+
+import (
+	"testing"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+func TestDefinitionHash(t *testing.T) {
+	web, php := "web", "php"
+	execution := func(edit func(task *config.Task, ws *config.Workspace)) *workspace.TaskExecution {
+		task := &config.Task{Command: []string{"npm", "run", "build"}, Env: map[string]string{"NODE_ENV": "production"}}
+		ws := &config.Workspace{Container: "web", Env: map[string]string{"CI": "1"}}
+		if edit != nil {
+			edit(task, ws)
+		}
+		return &workspace.TaskExecution{WorkspaceName: "web", TaskName: "build", Task: task, Workspace: ws}
+	}
+	base := DefinitionHash(execution(nil))
+
+	tests := []struct {
+		name    string
+		edit    func(task *config.Task, ws *config.Workspace)
+		changed bool
+	}{
+		{"unchanged", func(task *config.Task, ws *config.Workspace) {}, false},
+		{"command", func(task *config.Task, ws *config.Workspace) { task.Command = []string{"npm", "run", "dev"} }, true},
+		{"argument boundaries", func(task *config.Task, ws *config.Workspace) { task.Command = []string{"npm", "run build"} }, true},
+		{"task env", func(task *config.Task, ws *config.Workspace) { task.Env["NODE_ENV"] = "development" }, true},
+		{"workspace env", func(task *config.Task, ws *config.Workspace) { ws.Env["DEBUG"] = "1" }, true},
+		{"env overridden by the task", func(task *config.Task, ws *config.Workspace) { ws.Env["NODE_ENV"] = "test" }, false},
+		{"workspace container", func(task *config.Task, ws *config.Workspace) { ws.Container = "app" }, true},
+		{"task container", func(task *config.Task, ws *config.Workspace) { task.Container = &php }, true},
+		{"same container on the task", func(task *config.Task, ws *config.Workspace) { task.Container = &web }, false},
+		{"docker disabled", func(task *config.Task, ws *config.Workspace) { task.Docker = &config.TaskDockerConfig{Disable: true} }, true},
+		{"description", func(task *config.Task, ws *config.Workspace) { task.Description = "Build the app" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if changed := DefinitionHash(execution(tt.edit)) != base; changed != tt.changed {
+				t.Errorf("DefinitionHash() changed = %v, want %v", changed, tt.changed)
+			}
+		})
+	}
+}
+
+func TestDefinitionHashResolved(t *testing.T) {
+	execution := func(env map[string]string, executor string) *workspace.TaskExecution {
+		return &workspace.TaskExecution{
+			WorkspaceName: "web",
+			TaskName:      "build",
+			Task:          &config.Task{Command: []string{"npm", "run", "build"}, Env: map[string]string{"NODE_ENV": "production"}},
+			Workspace:     &config.Workspace{},
+			Env:           env,
+			Executor:      executor,
+		}
+	}
+	base := DefinitionHash(execution(map[string]string{"NODE_ENV": "production", "TOKEN": "one"}, config.ExecutorLocal))
+
+	tests := []struct {
+		name      string
+		execution *workspace.TaskExecution
+		changed   bool
+	}{
+		{"unchanged", execution(map[string]string{"NODE_ENV": "production", "TOKEN": "one"}, config.ExecutorLocal), false},
+		{"resolved env", execution(map[string]string{"NODE_ENV": "production", "TOKEN": "two"}, config.ExecutorLocal), true},
+		{"unresolved", execution(nil, config.ExecutorLocal), true},
+		{"executor", execution(map[string]string{"NODE_ENV": "production", "TOKEN": "one"}, config.ExecutorDockerRun), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if changed := DefinitionHash(tt.execution) != base; changed != tt.changed {
+				t.Errorf("DefinitionHash() changed = %v, want %v", changed, tt.changed)
+			}
+		})
+	}
+
+	recorded := execution(nil, "")
+	recorded.Definition = base
+	if got := TaskDefinition(recorded); got != base {
+		t.Errorf("TaskDefinition() = %s, want the recorded definition", got)
+	}
+}
@@ -0,0 +1,88 @@
+package deps
+
+import (
+	"sort"
+	"time"
+
+	"doctrus/internal/workspace"
+)
+
+// ChangeKind says how an input file differs from the cached state.
+type ChangeKind string
+
+// Kinds of input changes
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeModified ChangeKind = "modified"
+	ChangeDeleted  ChangeKind = "deleted"
+)
+
+// InputChange is an input file that was added, modified or deleted since the
+// cached state was recorded. Old fields are empty for added files and New
+// fields for deleted ones.
+type InputChange struct {
+	Path       string     `json:"path"`
+	Kind       ChangeKind `json:"kind"`
+	OldHash    string     `json:"old_hash,omitempty"`
+	NewHash    string     `json:"new_hash,omitempty"`
+	OldSize    int64      `json:"old_size,omitempty"`
+	NewSize    int64      `json:"new_size,omitempty"`
+	OldModTime time.Time  `json:"old_mod_time,omitzero"`
+	NewModTime time.Time  `json:"new_mod_time,omitzero"`
+}
+
+// DiffInputs compares the current inputs of execution with those recorded
+// in previousState, returning the changes ordered by path. Every input
+// counts as added when there is no previous state.
+func (t *Tracker) DiffInputs(execution *workspace.TaskExecution, previousState *TaskState) ([]InputChange, error) {
+	current, err := t.computeInputHashes(execution)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := make(map[string]FileInfo)
+	if previousState != nil {
+		for _, prev := range previousState.InputHashes {
+			previous[prev.Path] = prev
+		}
+	}
+
+	var changes []InputChange
+	for _, curr := range current {
+		prev, exists := previous[curr.Path]
+		delete(previous, curr.Path)
+		switch {
+		case !exists:
+			changes = append(changes, InputChange{
+				Path:       curr.Path,
+				Kind:       ChangeAdded,
+				NewHash:    curr.Hash,
+				NewSize:    curr.Size,
+				NewModTime: curr.ModTime,
+			})
+		case prev.Hash != curr.Hash:
+			changes = append(changes, InputChange{
+				Path:       curr.Path,
+				Kind:       ChangeModified,
+				OldHash:    prev.Hash,
+				NewHash:    curr.Hash,
+				OldSize:    prev.Size,
+				NewSize:    curr.Size,
+				OldModTime: prev.ModTime,
+				NewModTime: curr.ModTime,
+			})
+		}
+	}
+	for _, prev := range previous {
+		changes = append(changes, InputChange{
+			Path:       prev.Path,
+			Kind:       ChangeDeleted,
+			OldHash:    prev.Hash,
+			OldSize:    prev.Size,
+			OldModTime: prev.ModTime,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
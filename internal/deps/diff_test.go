@@ -0,0 +1,62 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+func TestDiffInputs(t *testing.T) {
+	tempDir := t.TempDir()
+	tracker := NewTracker(tempDir)
+	for name, content := range map[string]string{"kept.txt": "same", "changed.txt": "new content", "added.txt": "added"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	execution := &workspace.TaskExecution{
+		WorkspaceName: "test",
+		TaskName:      "build",
+		Task:          &config.Task{Inputs: []string{"*.txt"}},
+		AbsPath:       tempDir,
+	}
+
+	current, err := tracker.InputHashes(execution)
+	if err != nil {
+		t.Fatalf("InputHashes() error = %v", err)
+	}
+	previous := &TaskState{InputHashes: []FileInfo{{Path: "removed.txt", Hash: "gone", Size: 4}}}
+	for _, info := range current {
+		switch filepath.Base(info.Path) {
+		case "kept.txt":
+			previous.InputHashes = append(previous.InputHashes, info)
+		case "changed.txt":
+			info.Hash, info.Size = "oldhash", 3
+			previous.InputHashes = append(previous.InputHashes, info)
+		}
+	}
+
+	changes, err := tracker.DiffInputs(execution, previous)
+	if err != nil {
+		t.Fatalf("DiffInputs() error = %v", err)
+	}
+
+	want := map[string]ChangeKind{"added.txt": ChangeAdded, "changed.txt": ChangeModified, "removed.txt": ChangeDeleted}
+	if len(changes) != len(want) {
+		t.Fatalf("DiffInputs() = %+v, want %d changes", changes, len(want))
+	}
+	for i, change := range changes {
+		if i > 0 && changes[i-1].Path > change.Path {
+			t.Errorf("changes not ordered by path: %s before %s", changes[i-1].Path, change.Path)
+		}
+		if kind := want[filepath.Base(change.Path)]; change.Kind != kind {
+			t.Errorf("%s: kind = %s, want %s", change.Path, change.Kind, kind)
+		}
+		if change.Kind == ChangeModified && (change.OldHash != "oldhash" || change.OldSize != 3 || change.NewSize != int64(len("new content"))) {
+			t.Errorf("unexpected modification details: %+v", change)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package deps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"doctrus/internal/workspace"
+)
+
+// StaleFileTargets checks the task's depends_on_files rules by modification
+// time, returning why the task needs to run, or "" when the targets of every
+// rule exist and none is older than a source.
+func (t *Tracker) StaleFileTargets(execution *workspace.TaskExecution) (string, error) {
+	rules, err := execution.Task.FileRules()
+	if err != nil {
+		return "", err
+	}
+
+	for _, rule := range rules {
+		var oldest, newest fileTime
+		for _, pattern := range rule.Targets {
+			matches, err := t.fileTimes(execution, pattern)
+			if err != nil {
+				return "", err
+			}
+			if len(matches) == 0 {
+				return fmt.Sprintf("no files match target %s", pattern), nil
+			}
+			for _, match := range matches {
+				if oldest.path == "" || match.modTime.Before(oldest.modTime) {
+					oldest = match
+				}
+			}
+		}
+
+		for _, pattern := range rule.Sources {
+			matches, err := t.fileTimes(execution, pattern)
+			if err != nil {
+				return "", err
+			}
+			for _, match := range matches {
+				if newest.path == "" || match.modTime.After(newest.modTime) {
+					newest = match
+				}
+			}
+		}
+
+		if newest.path != "" && newest.modTime.After(oldest.modTime) {
+			return fmt.Sprintf("%s is newer than %s", newest.path, oldest.path), nil
+		}
+	}
+	return "", nil
+}
+
+// fileTime is a file, relative to its workspace, and when it was modified.
+type fileTime struct {
+	path    string
+	modTime time.Time
+}
+
+// fileTimes resolves a glob of the task's workspace to the files it matches.
+func (t *Tracker) fileTimes(execution *workspace.TaskExecution, pattern string) ([]fileTime, error) {
+	matches, err := t.resolveGlobPattern(execution.AbsPath, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]fileTime, 0, len(matches))
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			continue
+		}
+		path, err := filepath.Rel(execution.AbsPath, match)
+		if err != nil {
+			path = match
+		}
+		files = append(files, fileTime{path: filepath.ToSlash(path), modTime: info.ModTime()})
+	}
+	return files, nil
+}
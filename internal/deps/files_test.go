@@ -0,0 +1,74 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+func TestStaleFileTargets(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	recent := time.Now()
+	tests := []struct {
+		name  string
+		files map[string]time.Time
+		want  string
+	}{
+		{
+			name:  "targets newer",
+			files: map[string]time.Time{"proto/a.proto": old, "gen/a.pb.go": recent},
+			want:  "",
+		},
+		{
+			name:  "source newer",
+			files: map[string]time.Time{"proto/a.proto": old, "proto/b.proto": recent, "gen/a.pb.go": old.Add(time.Minute)},
+			want:  "proto/b.proto is newer than gen/a.pb.go",
+		},
+		{
+			name:  "targets missing",
+			files: map[string]time.Time{"proto/a.proto": old},
+			want:  "no files match target gen/**",
+		},
+		{
+			name:  "no sources",
+			files: map[string]time.Time{"gen/a.pb.go": old},
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			for name, modTime := range tt.files {
+				path := filepath.Join(tempDir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(path, modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+			}
+			execution := &workspace.TaskExecution{
+				WorkspaceName: "api",
+				TaskName:      "proto",
+				Task:          &config.Task{DependsOnFiles: []string{"proto/*.proto -> gen/**"}},
+				AbsPath:       tempDir,
+			}
+
+			got, err := NewTracker(tempDir).StaleFileTargets(execution)
+			if err != nil {
+				t.Fatalf("StaleFileTargets() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("StaleFileTargets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
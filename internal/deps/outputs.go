@@ -0,0 +1,123 @@
+package deps
+
+import (
+	"path/filepath"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"doctrus/internal/workspace"
+)
+
+// OutputStatus compares an output file on disk with the cached task state.
+type OutputStatus string
+
+const (
+	// OutputMatches means the file exists with the hash recorded in the cache
+	OutputMatches OutputStatus = "matches"
+	// OutputModified means the file exists but its hash differs from the cache
+	OutputModified OutputStatus = "modified"
+	// OutputUncached means the file exists but the cache does not know it
+	OutputUncached OutputStatus = "uncached"
+	// OutputMissing means the cache recorded the file but it no longer exists
+	OutputMissing OutputStatus = "missing"
+)
+
+// OutputFile is a single file produced by a task.
+type OutputFile struct {
+	FileInfo
+	Status OutputStatus
+}
+
+// OutputPattern groups the files matched by one declared output glob.
+type OutputPattern struct {
+	Pattern string
+	Files   []OutputFile
+}
+
+// InspectOutputs resolves the task's output globs and compares every match
+// with previousState, which may be nil when the task has never been cached.
+// Cached files that no longer exist are reported as missing under the first
+// pattern that covers them.
+func (t *Tracker) InspectOutputs(execution *workspace.TaskExecution, previousState *TaskState) ([]OutputPattern, error) {
+	cached := make(map[string]FileInfo)
+	if previousState != nil {
+		for _, output := range previousState.Outputs {
+			cached[output.Path] = output
+		}
+	}
+
+	seen := make(map[string]bool)
+	var reports []OutputPattern
+	for _, pattern := range execution.Task.Outputs {
+		report := OutputPattern{Pattern: pattern}
+
+		matches, err := t.resolveGlobPattern(execution.AbsPath, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			info, err := t.computeFileInfo(match)
+			if err != nil || seen[info.Path] {
+				continue
+			}
+			seen[info.Path] = true
+
+			status := OutputUncached
+			if previous, ok := cached[info.Path]; ok {
+				status = OutputModified
+				if previous.Hash == info.Hash {
+					status = OutputMatches
+				}
+			}
+			report.Files = append(report.Files, OutputFile{FileInfo: *info, Status: status})
+		}
+
+		reports = append(reports, report)
+	}
+
+	if previousState != nil {
+		for _, output := range previousState.Outputs {
+			if seen[output.Path] {
+				continue
+			}
+			for i := range reports {
+				if t.patternCovers(execution, reports[i].Pattern, output.Path) {
+					reports[i].Files = append(reports[i].Files, OutputFile{FileInfo: output, Status: OutputMissing})
+					seen[output.Path] = true
+					break
+				}
+			}
+		}
+	}
+
+	return reports, nil
+}
+
+// MissingOutputs returns the task's output patterns that match no files.
+func (t *Tracker) MissingOutputs(execution *workspace.TaskExecution) ([]string, error) {
+	var missing []string
+	for _, pattern := range execution.Task.Outputs {
+		matches, err := t.resolveGlobPattern(execution.AbsPath, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			missing = append(missing, pattern)
+		}
+	}
+	return missing, nil
+}
+
+// patternCovers reports whether a cached path, relative to the tracker's base
+// path, falls under an output pattern declared relative to the workspace.
+func (t *Tracker) patternCovers(execution *workspace.TaskExecution, pattern, path string) bool {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(execution.AbsPath, pattern)
+	}
+	rel, err := filepath.Rel(t.basePath, pattern)
+	if err != nil {
+		return false
+	}
+	matched, err := doublestar.Match(filepath.ToSlash(rel), filepath.ToSlash(path))
+	return err == nil && matched
+}
@@ -0,0 +1,129 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+func TestInspectOutputs(t *testing.T) {
+	tempDir := t.TempDir()
+	tracker := NewTracker(tempDir)
+	appDir := filepath.Join(tempDir, "app")
+
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(appDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	write("dist/same.js", "same")
+	write("dist/changed.js", "before")
+	write("dist/removed.js", "removed")
+
+	execution := &workspace.TaskExecution{
+		WorkspaceName: "app",
+		TaskName:      "build",
+		Task: &config.Task{
+			Command: []string{"build"},
+			Outputs: []string{"dist/**/*.js", "coverage/*"},
+		},
+		AbsPath: appDir,
+	}
+
+	state, err := tracker.ComputeTaskState(execution, true)
+	if err != nil {
+		t.Fatalf("ComputeTaskState() error = %v", err)
+	}
+
+	write("dist/changed.js", "after")
+	write("dist/new.js", "new")
+	if err := os.Remove(filepath.Join(appDir, "dist", "removed.js")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+
+	reports, err := tracker.InspectOutputs(execution, state)
+	if err != nil {
+		t.Fatalf("InspectOutputs() error = %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("expected a report per pattern, got %d", len(reports))
+	}
+	if len(reports[1].Files) != 0 {
+		t.Errorf("coverage/* should match nothing, got %v", reports[1].Files)
+	}
+
+	got := make(map[string]OutputStatus)
+	for _, file := range reports[0].Files {
+		got[filepath.Base(file.Path)] = file.Status
+	}
+	want := map[string]OutputStatus{
+		"same.js":    OutputMatches,
+		"changed.js": OutputModified,
+		"new.js":     OutputUncached,
+		"removed.js": OutputMissing,
+	}
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("%s status = %q, want %q", name, got[name], status)
+		}
+	}
+}
+
+func TestInspectOutputsWithoutCache(t *testing.T) {
+	tempDir := t.TempDir()
+	tracker := NewTracker(tempDir)
+	if err := os.WriteFile(filepath.Join(tempDir, "out.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+
+	execution := &workspace.TaskExecution{
+		Task:    &config.Task{Outputs: []string{"out.txt"}},
+		AbsPath: tempDir,
+	}
+
+	reports, err := tracker.InspectOutputs(execution, nil)
+	if err != nil {
+		t.Fatalf("InspectOutputs() error = %v", err)
+	}
+	if len(reports) != 1 || len(reports[0].Files) != 1 || reports[0].Files[0].Status != OutputUncached {
+		t.Fatalf("unexpected reports: %+v", reports)
+	}
+	if reports[0].Files[0].Size != 1 {
+		t.Errorf("Size = %d, want 1", reports[0].Files[0].Size)
+	}
+}
+
+func TestMissingOutputs(t *testing.T) {
+	tempDir := t.TempDir()
+	tracker := NewTracker(tempDir)
+	if err := os.MkdirAll(filepath.Join(tempDir, "dist"), 0755); err != nil {
+		t.Fatalf("Failed to create dist: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "dist", "app.js"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+
+	execution := &workspace.TaskExecution{
+		Task:    &config.Task{Outputs: []string{"dist/**/*.js", "build/**/*", "dist/*.css"}},
+		AbsPath: tempDir,
+	}
+
+	missing, err := tracker.MissingOutputs(execution)
+	if err != nil {
+		t.Fatalf("MissingOutputs() error = %v", err)
+	}
+	want := []string{"build/**/*", "dist/*.css"}
+	if !reflect.DeepEqual(missing, want) {
+		t.Fatalf("MissingOutputs() = %v, want %v", missing, want)
+	}
+}
@@ -15,6 +15,14 @@ import (
 
 type Tracker struct {
 	basePath string
+	hasher   Hasher
+}
+
+// Hasher hashes files on behalf of a Tracker, such as the doctrus daemon,
+// which remembers the hashes of unchanged files between runs. It returns
+// sha256 hashes in hex by path, leaving out files it couldn't hash.
+type Hasher interface {
+	HashFiles(paths []string) (map[string]string, error)
 }
 
 type FileInfo struct {
@@ -30,6 +38,8 @@ type TaskState struct {
 	Outputs     []FileInfo `json:"outputs"`
 	LastRun     time.Time  `json:"last_run"`
 	Success     bool       `json:"success"`
+	Definition  string     `json:"definition,omitempty"`
+	ImageDigest string     `json:"image_digest,omitempty"`
 }
 
 func NewTracker(basePath string) *Tracker {
@@ -41,6 +51,12 @@ func NewTracker(basePath string) *Tracker {
 	}
 }
 
+// SetHasher hashes files through hasher, falling back to reading them when
+// it fails.
+func (t *Tracker) SetHasher(hasher Hasher) {
+	t.hasher = hasher
+}
+
 func (t *Tracker) ShouldRunTask(execution *workspace.TaskExecution, previousState *TaskState) (bool, error) {
 	if previousState == nil {
 		return true, nil
@@ -50,6 +66,16 @@ func (t *Tracker) ShouldRunTask(execution *workspace.TaskExecution, previousStat
 		return true, nil
 	}
 
+	if previousState.Definition != TaskDefinition(execution) {
+		return true, nil
+	}
+
+	// An image that can't be inspected, such as of a stopped container,
+	// doesn't invalidate the cache
+	if execution.ImageDigest != "" && execution.ImageDigest != previousState.ImageDigest {
+		return true, nil
+	}
+
 	currentInputs, err := t.computeInputHashes(execution)
 	if err != nil {
 		return true, fmt.Errorf("failed to compute input hashes: %w", err)
@@ -82,28 +108,39 @@ func (t *Tracker) ComputeTaskState(execution *workspace.TaskExecution, success b
 	return &TaskState{
 		TaskKey:     taskKey,
 		InputHashes: inputs,
+		Definition:  TaskDefinition(execution),
+		ImageDigest: execution.ImageDigest,
 		Outputs:     outputs,
 		LastRun:     time.Now(),
 		Success:     success,
 	}, nil
 }
 
+// InputHashes hashes the files matching the task's input patterns, sorted
+// by path.
+func (t *Tracker) InputHashes(execution *workspace.TaskExecution) ([]FileInfo, error) {
+	return t.computeInputHashes(execution)
+}
+
 func (t *Tracker) computeInputHashes(execution *workspace.TaskExecution) ([]FileInfo, error) {
 	var fileInfos []FileInfo
+	var matches []string
 
 	for _, pattern := range execution.Task.Inputs {
-		matches, err := t.resolveGlobPattern(execution.AbsPath, pattern)
+		patternMatches, err := t.resolveGlobPattern(execution.AbsPath, pattern)
 		if err != nil {
 			return nil, fmt.Errorf("failed to resolve input pattern %s: %w", pattern, err)
 		}
+		matches = append(matches, patternMatches...)
+	}
 
-		for _, match := range matches {
-			info, err := t.computeFileInfo(match)
-			if err != nil {
-				return nil, fmt.Errorf("failed to compute hash for %s: %w", match, err)
-			}
-			fileInfos = append(fileInfos, *info)
+	hashes := t.hashFiles(matches)
+	for _, match := range matches {
+		info, err := t.fileInfo(match, hashes[match])
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute hash for %s: %w", match, err)
 		}
+		fileInfos = append(fileInfos, *info)
 	}
 
 	sort.Slice(fileInfos, func(i, j int) bool {
@@ -113,6 +150,12 @@ func (t *Tracker) computeInputHashes(execution *workspace.TaskExecution) ([]File
 	return fileInfos, nil
 }
 
+// OutputHashes hashes the files matching the task's output patterns, sorted
+// by path.
+func (t *Tracker) OutputHashes(execution *workspace.TaskExecution) ([]FileInfo, error) {
+	return t.computeOutputHashes(execution)
+}
+
 func (t *Tracker) computeOutputHashes(execution *workspace.TaskExecution) ([]FileInfo, error) {
 	var fileInfos []FileInfo
 
@@ -138,6 +181,12 @@ func (t *Tracker) computeOutputHashes(execution *workspace.TaskExecution) ([]Fil
 	return fileInfos, nil
 }
 
+// MatchFiles returns the regular files matching a task's input or output
+// pattern, relative patterns being resolved against the task's directory.
+func (t *Tracker) MatchFiles(execution *workspace.TaskExecution, pattern string) ([]string, error) {
+	return t.resolveGlobPattern(execution.AbsPath, pattern)
+}
+
 func (t *Tracker) resolveGlobPattern(basePath, pattern string) ([]string, error) {
 	// Handle absolute patterns
 	if filepath.IsAbs(pattern) {
@@ -170,7 +219,19 @@ func (t *Tracker) globFiles(pattern string) ([]string, error) {
 	return files, nil
 }
 
+// HashFile returns the FileInfo of a file, with its path relative to the
+// tracker's base path.
+func (t *Tracker) HashFile(filePath string) (*FileInfo, error) {
+	return t.computeFileInfo(filePath)
+}
+
 func (t *Tracker) computeFileInfo(filePath string) (*FileInfo, error) {
+	return t.fileInfo(filePath, "")
+}
+
+// fileInfo returns the FileInfo of a file, hashing it unless its hash is
+// given.
+func (t *Tracker) fileInfo(filePath, hash string) (*FileInfo, error) {
 	stat, err := os.Stat(filePath)
 	if err != nil {
 		return nil, err
@@ -180,9 +241,11 @@ func (t *Tracker) computeFileInfo(filePath string) (*FileInfo, error) {
 		return nil, fmt.Errorf("path is a directory: %s", filePath)
 	}
 
-	hash, err := t.computeFileHash(filePath)
-	if err != nil {
-		return nil, err
+	if hash == "" {
+		hash, err = t.computeFileHash(filePath)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	relPath, err := filepath.Rel(t.basePath, filePath)
@@ -198,6 +261,19 @@ func (t *Tracker) computeFileInfo(filePath string) (*FileInfo, error) {
 	}, nil
 }
 
+// hashFiles returns the hashes the hasher knows of files, or nil without a
+// hasher or when it fails, leaving the files to be hashed one by one.
+func (t *Tracker) hashFiles(files []string) map[string]string {
+	if t.hasher == nil || len(files) == 0 {
+		return nil
+	}
+	hashes, err := t.hasher.HashFiles(files)
+	if err != nil {
+		return nil
+	}
+	return hashes
+}
+
 func (t *Tracker) computeFileHash(filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -336,6 +336,7 @@ func TestShouldRunTask(t *testing.T) {
 	tests := []struct {
 		name          string
 		previousState *TaskState
+		imageDigest   string
 		want          bool
 	}{
 		{
@@ -356,6 +357,46 @@ func TestShouldRunTask(t *testing.T) {
 				Success:     true,
 				InputHashes: []FileInfo{},
 				Outputs:     []FileInfo{},
+				Definition:  DefinitionHash(execution),
+			},
+			want: false,
+		},
+		{
+			name: "changed definition",
+			previousState: &TaskState{
+				Success:     true,
+				InputHashes: []FileInfo{},
+				Outputs:     []FileInfo{},
+				Definition:  "stale",
+			},
+			want: true,
+		},
+		{
+			name: "same image",
+			previousState: &TaskState{
+				Success:     true,
+				Definition:  DefinitionHash(execution),
+				ImageDigest: "sha256:aaa",
+			},
+			imageDigest: "sha256:aaa",
+			want:        false,
+		},
+		{
+			name: "rebuilt image",
+			previousState: &TaskState{
+				Success:     true,
+				Definition:  DefinitionHash(execution),
+				ImageDigest: "sha256:aaa",
+			},
+			imageDigest: "sha256:bbb",
+			want:        true,
+		},
+		{
+			name: "image unknown",
+			previousState: &TaskState{
+				Success:     true,
+				Definition:  DefinitionHash(execution),
+				ImageDigest: "sha256:aaa",
 			},
 			want: false,
 		},
@@ -363,6 +404,7 @@ func TestShouldRunTask(t *testing.T) {
 	
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			execution.ImageDigest = tt.imageDigest
 			result, err := tracker.ShouldRunTask(execution, tt.previousState)
 			if err != nil {
 				t.Fatalf("ShouldRunTask() error = %v", err)
@@ -621,4 +663,52 @@ func findSubstring(s, substr string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+// fakeHasher answers for the files it knows and fails when err is set.
+type fakeHasher struct {
+	hashes map[string]string
+	err    error
+}
+
+func (h *fakeHasher) HashFiles(paths []string) (map[string]string, error) {
+	return h.hashes, h.err
+}
+
+func TestInputHashesWithHasher(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	execution := &workspace.TaskExecution{
+		Task:    &config.Task{Inputs: []string{"*.txt"}},
+		AbsPath: tempDir,
+	}
+	local, err := NewTracker(tempDir).InputHashes(execution)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		hasher *fakeHasher
+		want   string
+	}{
+		{"hashes from the hasher", &fakeHasher{hashes: map[string]string{filepath.Join(tempDir, "a.txt"): "remembered"}}, "remembered"},
+		{"failing hasher", &fakeHasher{err: os.ErrNotExist}, local[0].Hash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker(tempDir)
+			tracker.SetHasher(tt.hasher)
+			infos, err := tracker.InputHashes(execution)
+			if err != nil {
+				t.Fatalf("InputHashes() error = %v", err)
+			}
+			if len(infos) != 2 || infos[0].Hash != tt.want || infos[1].Hash != local[1].Hash {
+				t.Errorf("InputHashes() = %+v, want a.txt hashed %s and b.txt hashed locally", infos, tt.want)
+			}
+		})
+	}
+}
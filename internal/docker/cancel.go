@@ -0,0 +1,128 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TimeoutError is the cancellation cause of a task that ran longer than its
+// timeout. It matches context.DeadlineExceeded with errors.Is.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %v", e.Timeout)
+}
+
+func (e *TimeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// signalGracePeriod is how long a command gets to exit after a forwarded
+// signal before it is killed.
+var signalGracePeriod = 10 * time.Second
+
+// SignalError is the cancellation cause of tasks stopped because doctrus
+// received Signal. Their commands, including processes inside containers,
+// receive the same signal and are killed if they do not exit within a grace
+// period.
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e *SignalError) Error() string {
+	return "received " + e.Signal.String()
+}
+
+// interactiveCommands counts the interactive commands running, which get
+// Ctrl-C from the terminal themselves.
+var interactiveCommands atomic.Int32
+
+// NotifyContext returns a copy of parent that is cancelled with a
+// *SignalError when one of signals arrives. Calling stop releases the
+// signal handler. An interrupt while an interactive command runs is left to
+// that command, so Ctrl-C in a REPL doesn't stop the run.
+func NotifyContext(parent context.Context, signals ...os.Signal) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+	go func() {
+		for {
+			select {
+			case sig := <-received:
+				if sig == os.Interrupt && interactiveCommands.Load() > 0 {
+					continue
+				}
+				cancel(&SignalError{Signal: sig})
+			case <-ctx.Done():
+			}
+			return
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(received)
+		cancel(nil)
+	}
+}
+
+// TaskContexts gives every running task its own context, so one task and the
+// command it runs can be cancelled with a cause while the rest of the run
+// carries on. A nil TaskContexts still applies timeouts but cannot cancel
+// tasks by key.
+type TaskContexts struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelCauseFunc
+}
+
+func NewTaskContexts() *TaskContexts {
+	return &TaskContexts{cancels: make(map[string]context.CancelCauseFunc)}
+}
+
+// Start derives the context of task key from parent. A positive timeout
+// cancels it with a *TimeoutError once elapsed. The returned stop function
+// releases the context and must be called when the task ends.
+func (t *TaskContexts) Start(parent context.Context, key string, timeout time.Duration) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	stopTimeout := func() {}
+	if timeout > 0 {
+		ctx, stopTimeout = context.WithTimeoutCause(ctx, timeout, &TimeoutError{Timeout: timeout})
+	}
+
+	if t != nil {
+		t.mu.Lock()
+		t.cancels[key] = cancel
+		t.mu.Unlock()
+	}
+
+	return ctx, func() {
+		if t != nil {
+			t.mu.Lock()
+			delete(t.cancels, key)
+			t.mu.Unlock()
+		}
+		stopTimeout()
+		cancel(nil)
+	}
+}
+
+// Cancel stops the running task key with cause, which defaults to
+// context.Canceled. It reports whether the task was running.
+func (t *TaskContexts) Cancel(key string, cause error) bool {
+	if t == nil {
+		return false
+	}
+
+	t.mu.Lock()
+	cancel, ok := t.cancels[key]
+	t.mu.Unlock()
+	if ok {
+		cancel(cause)
+	}
+	return ok
+}
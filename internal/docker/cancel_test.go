@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+func sleepExecution(t *testing.T) *workspace.TaskExecution {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("sleep command not available on Windows")
+	}
+	return &workspace.TaskExecution{
+		WorkspaceName: "app",
+		TaskName:      "serve",
+		Task:          &config.Task{Command: []string{"sleep", "10"}},
+		Workspace:     &config.Workspace{},
+		AbsPath:       t.TempDir(),
+	}
+}
+
+func TestTaskContextsTimeout(t *testing.T) {
+	execution := sleepExecution(t)
+	tasks := NewTaskContexts()
+
+	ctx, stop := tasks.Start(context.Background(), "app:serve", 50*time.Millisecond)
+	defer stop()
+
+	result := NewLocalExecutor().Execute(ctx, execution, nil, nil)
+	if result.ExitCode != 124 {
+		t.Fatalf("ExitCode = %d, want 124", result.ExitCode)
+	}
+	var timeoutErr *TimeoutError
+	if !errors.As(result.Cause, &timeoutErr) || timeoutErr.Timeout != 50*time.Millisecond {
+		t.Fatalf("Cause = %v, want *TimeoutError", result.Cause)
+	}
+	if !errors.Is(result.Cause, context.DeadlineExceeded) {
+		t.Fatal("TimeoutError should match context.DeadlineExceeded")
+	}
+}
+
+func TestTaskContextsCancelOneTask(t *testing.T) {
+	execution := sleepExecution(t)
+	tasks := NewTaskContexts()
+	parent, cancelParent := context.WithCancel(context.Background())
+	defer cancelParent()
+
+	ctx, stop := tasks.Start(parent, "app:serve", 0)
+	other, stopOther := tasks.Start(parent, "app:watch", 0)
+	defer stopOther()
+
+	restart := errors.New("inputs changed")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		if !tasks.Cancel("app:serve", restart) {
+			t.Error("Cancel() = false for a running task")
+		}
+	}()
+
+	result := NewLocalExecutor().Execute(ctx, execution, nil, nil)
+	stop()
+	if result.ExitCode != 130 || !errors.Is(result.Cause, restart) {
+		t.Fatalf("result = %+v, want exit code 130 caused by %v", result, restart)
+	}
+	if other.Err() != nil || parent.Err() != nil {
+		t.Fatal("cancelling one task must not cancel the others")
+	}
+	if tasks.Cancel("app:serve", restart) {
+		t.Fatal("Cancel() = true for a stopped task")
+	}
+}
+
+func TestNilTaskContextsAppliesTimeout(t *testing.T) {
+	var tasks *TaskContexts
+	ctx, stop := tasks.Start(context.Background(), "app:serve", time.Millisecond)
+	defer stop()
+
+	<-ctx.Done()
+	if !errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+		t.Fatalf("Cause = %v, want deadline exceeded", context.Cause(ctx))
+	}
+	if tasks.Cancel("app:serve", nil) {
+		t.Fatal("nil TaskContexts cannot cancel tasks")
+	}
+}
@@ -0,0 +1,168 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"doctrus/internal/config"
+	"doctrus/internal/ui"
+	"doctrus/internal/workspace"
+)
+
+// ComposeExecutor runs tasks with docker compose exec in the task's
+// container, which must already be running. Signals and cancellation reach
+// the task's process inside the container.
+type ComposeExecutor struct {
+	config     *config.Config
+	workingDir string
+}
+
+func NewComposeExecutor(cfg *config.Config, workingDir string) *ComposeExecutor {
+	return &ComposeExecutor{
+		config:     cfg,
+		workingDir: workingDir,
+	}
+}
+
+func (e *ComposeExecutor) Execute(ctx context.Context, execution *workspace.TaskExecution, stdoutWriter, stderrWriter io.Writer) *ExecutionResult {
+	containerName := e.config.GetEffectiveContainer(execution.WorkspaceName, execution.TaskName)
+	if containerName == "" {
+		return &ExecutionResult{
+			ExitCode: 1,
+			Error:    fmt.Errorf("no container configured for %s:%s", execution.WorkspaceName, execution.TaskName),
+		}
+	}
+
+	dockerConfig := e.config.GetEffectiveDockerConfig(execution.WorkspaceName, execution.TaskName)
+	composeFile := composeFilePath(dockerConfig.ComposeFile, e.workingDir)
+
+	if _, err := os.Stat(composeFile); os.IsNotExist(err) {
+		return &ExecutionResult{
+			ExitCode: 1,
+			Error:    fmt.Errorf("docker-compose file not found: %s", composeFile),
+		}
+	}
+
+	// Check if container is running before attempting to exec
+	if !e.isContainerRunning(composeFile, containerName) {
+		return &ExecutionResult{
+			ExitCode: 1,
+			Error: fmt.Errorf("container '%s' is not running\n\nTo start containers, run:\n  docker compose -f %s up -d %s\n\nOr start all containers:\n  docker compose -f %s up -d",
+				containerName, composeFile, containerName, composeFile),
+		}
+	}
+
+	// Use exec for running containers. Interactive tasks get a TTY when
+	// doctrus runs in a terminal
+	args := []string{
+		"compose",
+		"-f", composeFile,
+		"exec",
+	}
+	if !execution.Task.Interactive || !ui.IsTerminal(os.Stdin) {
+		args = append(args, "-T")
+	}
+
+	env := buildEnvVars(execution)
+	for _, key := range sortedEnvKeys(env) {
+		args = append(args, "-e", key+"="+env[key])
+	}
+
+	workDir, isAbsolute := e.containerWorkDir(execution)
+	if workDir != "" && workDir != "." && isAbsolute {
+		args = append(args, "--workdir", workDir)
+	}
+
+	args = append(args, containerName)
+
+	// Signals to the local docker CLI do not reach the process in the
+	// container, so record its pid to signal it directly
+	pidFile := "/tmp/doctrus-" + newRunID() + ".pid"
+	if isAbsolute {
+		workDir = ""
+	}
+	args = append(args, containerCommand(pidFile, workDir, execution.Task.Command)...)
+
+	forward := func(sig os.Signal) {
+		e.signalContainerProcess(composeFile, containerName, pidFile, signalName(sig))
+	}
+	result := runCommand(ctx, "docker", args, execution.AbsPath, commandEnviron(os.Environ(), env, nil), stdoutWriter, stderrWriter, forward, execution.Task.Interactive)
+
+	// The service container is shared with other tasks and its own
+	// processes, and the docker CLI's usage says nothing about the task
+	result.Usage = ResourceUsage{WallTime: result.Usage.WallTime}
+	return result
+}
+
+// containerCommand wraps command so the shell running it in the container
+// writes its pid to pidFile, changes to workDir when it is a relative
+// directory, and then execs the command in its place. Everything is passed
+// as positional parameters rather than spliced into the script, so
+// arguments with spaces, quotes, $, globs or newlines arrive unchanged.
+func containerCommand(pidFile, workDir string, command []string) []string {
+	if workDir == "" || workDir == "." {
+		return append([]string{"sh", "-c", `echo $$ > "$0" && exec "$@"`, pidFile}, command...)
+	}
+	args := []string{"sh", "-lc", `echo $$ > "$0" && cd -- "$1" && shift && exec "$@"`, pidFile, workDir}
+	return append(args, command...)
+}
+
+// signalContainerProcess sends sig to the process recorded in pidFile inside
+// the container, and to its process group when it leads one.
+func (e *ComposeExecutor) signalContainerProcess(composeFile, containerName, pidFile, sig string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+	defer cancel()
+
+	script := `pid=$(cat "$0" 2>/dev/null) || exit 0; kill -s "$1" -- "-$pid" 2>/dev/null || kill -s "$1" "$pid" 2>/dev/null; [ "$1" = KILL ] && rm -f "$0"; exit 0`
+	cmd := exec.CommandContext(ctx, "docker", "compose", "-f", composeFile, "exec", "-T", containerName, "sh", "-c", script, pidFile, sig)
+	_ = cmd.Run()
+}
+
+func (e *ComposeExecutor) containerWorkDir(execution *workspace.TaskExecution) (string, bool) {
+	workspacePath := execution.Workspace.Path
+	if workspacePath == "" {
+		return "", false
+	}
+
+	if filepath.IsAbs(workspacePath) {
+		return filepath.ToSlash(workspacePath), true
+	}
+
+	relPath, err := filepath.Rel(e.workingDir, execution.AbsPath)
+	if err == nil {
+		relPath = filepath.ToSlash(relPath)
+		if relPath == "" {
+			return ".", false
+		}
+		return relPath, false
+	}
+
+	clean := strings.TrimPrefix(filepath.ToSlash(workspacePath), "./")
+	if clean == "" {
+		return ".", false
+	}
+	return clean, false
+}
+
+func (e *ComposeExecutor) isContainerRunning(composeFile, containerName string) bool {
+	cmd := exec.Command("docker", "compose", "-f", composeFile, "ps", "--format", "json", containerName)
+	output, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+
+	// Parse the JSON output to check if container is running
+	outputStr := strings.TrimSpace(string(output))
+	if outputStr == "" {
+		return false
+	}
+
+	// Simple check: if we got output, assume container exists and is running
+	// The docker compose ps command returns info for running containers
+	return true
+}
@@ -3,20 +3,32 @@ package docker
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"doctrus/internal/config"
+	"doctrus/internal/tracing"
 	"doctrus/internal/workspace"
 )
 
-type Executor struct {
-	config     *config.Config
-	workingDir string
+// Executor runs a task's command in one execution environment. Output is
+// streamed to stdoutWriter and stderrWriter when they are non-nil and is also
+// collected in the result.
+type Executor interface {
+	Execute(ctx context.Context, execution *workspace.TaskExecution, stdoutWriter, stderrWriter io.Writer) *ExecutionResult
 }
 
 type ExecutionResult struct {
@@ -24,108 +36,194 @@ type ExecutionResult struct {
 	Stdout   string
 	Stderr   string
 	Error    error
+	// Cause is why the task's context was cancelled while the command ran,
+	// such as a *TimeoutError, and nil when it ran to completion
+	Cause error
+	// Usage is what the command consumed while it ran
+	Usage ResourceUsage
+}
+
+// Dispatcher is the Executor used by doctrus. It picks the executor
+// configured for each task (see config.GetEffectiveExecutor) and delegates
+// to the one registered under that name.
+type Dispatcher struct {
+	config     *config.Config
+	workingDir string
+
+	mu        sync.RWMutex
+	executors map[string]Executor
+	cliEnv    map[string]string
+	override  string
 }
 
-func NewExecutor(cfg *config.Config, workingDir string) *Executor {
+// NewExecutor returns a Dispatcher with the local, compose-exec and
+// docker-run executors registered.
+func NewExecutor(cfg *config.Config, workingDir string) *Dispatcher {
 	if workingDir == "" {
 		workingDir, _ = os.Getwd()
 	}
-	return &Executor{
+	d := &Dispatcher{
 		config:     cfg,
 		workingDir: workingDir,
+		executors:  make(map[string]Executor),
 	}
+	d.Register(config.ExecutorLocal, NewLocalExecutor())
+	d.Register(config.ExecutorComposeExec, NewComposeExecutor(cfg, workingDir))
+	d.Register(config.ExecutorDockerRun, NewRunExecutor(cfg, workingDir))
+	return d
 }
 
-func (e *Executor) Execute(ctx context.Context, execution *workspace.TaskExecution, stdoutWriter, stderrWriter io.Writer) *ExecutionResult {
-	effectiveContainer := e.config.GetEffectiveContainer(execution.WorkspaceName, execution.TaskName)
-	if effectiveContainer != "" {
-		return e.executeInContainer(ctx, execution, effectiveContainer, stdoutWriter, stderrWriter)
-	}
-	return e.executeLocal(ctx, execution, stdoutWriter, stderrWriter)
+// Register makes executor available under name, replacing any executor
+// already registered with that name.
+func (d *Dispatcher) Register(name string, executor Executor) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.executors[name] = executor
 }
 
-func (e *Executor) executeInContainer(ctx context.Context, execution *workspace.TaskExecution, containerName string, stdoutWriter, stderrWriter io.Writer) *ExecutionResult {
-	dockerConfig := e.config.GetEffectiveDockerConfig(execution.WorkspaceName, execution.TaskName)
-	composeFile := dockerConfig.ComposeFile
-	if composeFile == "" {
-		composeFile = "docker-compose.yml"
-	}
-
-	if !filepath.IsAbs(composeFile) {
-		composeFile = filepath.Join(e.workingDir, composeFile)
-	}
-
-	if _, err := os.Stat(composeFile); os.IsNotExist(err) {
-		return &ExecutionResult{
-			ExitCode: 1,
-			Error:    fmt.Errorf("docker-compose file not found: %s", composeFile),
-		}
-	}
+// SetCLIEnv sets the variables passed with --env, the cli layer of every
+// task's environment.
+func (d *Dispatcher) SetCLIEnv(env map[string]string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cliEnv = env
+}
 
-	// Check if container is running before attempting to exec
-	if !e.isContainerRunning(composeFile, containerName) {
-		return &ExecutionResult{
-			ExitCode: 1,
-			Error: fmt.Errorf("container '%s' is not running\n\nTo start containers, run:\n  docker compose -f %s up -d %s\n\nOr start all containers:\n  docker compose -f %s up -d",
-				containerName, composeFile, containerName, composeFile),
-		}
-	}
+// Override runs every task with the executor registered under name instead
+// of its configured one, as run --distribute does with the remote executor.
+// An empty name restores the configured executors.
+func (d *Dispatcher) Override(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.override = name
+}
 
-	// Use exec for running containers
-	args := []string{
-		"compose",
-		"-f", composeFile,
-		"exec",
-		"-T",
-	}
+// Resolve returns a copy of execution as it runs: with its environment
+// resolved (see config.ResolveEnv), its command prefixed with the task's
+// wrapper and the name of its configured executor.
+func (d *Dispatcher) Resolve(execution *workspace.TaskExecution) (*workspace.TaskExecution, error) {
+	d.mu.RLock()
+	cliEnv := d.cliEnv
+	d.mu.RUnlock()
 
-	env := e.buildEnvVars(execution)
-	for key, value := range env {
-		args = append(args, "-e", fmt.Sprintf("%s=%s", key, value))
-	}
+	vars, err := d.config.ResolveEnv(d.workingDir, execution.WorkspaceName, execution.TaskName, execution.AbsPath, cliEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve environment: %w", err)
+	}
+	resolved := *execution
+	resolved.Env = config.EnvMap(vars)
+	resolved.AppendEnv = d.config.EnvAppend()
+	resolved.Executor = d.config.GetEffectiveExecutor(execution.WorkspaceName, execution.TaskName)
+	if execution.Task != nil {
+		task := *execution.Task
+		task.Command = d.config.WrapCommand(execution.WorkspaceName, execution.TaskName, task.Command)
+		resolved.Task = &task
+	}
+	return &resolved, nil
+}
 
-	workDir, isAbsolute := e.containerWorkDir(execution)
-	if workDir != "" && workDir != "." && isAbsolute {
-		args = append(args, "--workdir", workDir)
+// Execute resolves the task (see Resolve) and runs it with its configured
+// executor.
+func (d *Dispatcher) Execute(ctx context.Context, execution *workspace.TaskExecution, stdoutWriter, stderrWriter io.Writer) *ExecutionResult {
+	resolved, err := d.Resolve(execution)
+	if err != nil {
+		return &ExecutionResult{ExitCode: 1, Error: err}
 	}
+	name := resolved.Executor
 
-	args = append(args, containerName)
-
-	commandArgs := execution.Task.Command
-	if workDir != "" && workDir != "." && !isAbsolute {
-		shellCommand := buildShellCommand(workDir, execution.Task.Command)
-		commandArgs = []string{"sh", "-lc", shellCommand}
+	d.mu.RLock()
+	if d.override != "" {
+		name = d.override
 	}
-
-	args = append(args, commandArgs...)
-
-	return e.runCommand(ctx, "docker", args, execution.AbsPath, env, stdoutWriter, stderrWriter)
-}
-
-func (e *Executor) executeLocal(ctx context.Context, execution *workspace.TaskExecution, stdoutWriter, stderrWriter io.Writer) *ExecutionResult {
-	if len(execution.Task.Command) == 0 {
+	executor, ok := d.executors[name]
+	d.mu.RUnlock()
+	if !ok {
 		return &ExecutionResult{
 			ExitCode: 1,
-			Error:    fmt.Errorf("no command specified"),
+			Error:    fmt.Errorf("unknown executor %q", name),
 		}
 	}
 
-	command := execution.Task.Command[0]
-	args := execution.Task.Command[1:]
-	env := e.buildEnvVars(execution)
-
-	return e.runCommand(ctx, command, args, execution.AbsPath, env, stdoutWriter, stderrWriter)
+	ctx, span := tracing.Start(ctx, "exec", tracing.String("doctrus.executor", name))
+	if span != nil {
+		if resolved.Task != nil {
+			span.SetAttributes(tracing.String("process.command_line", strings.Join(resolved.Task.Command, " ")))
+		}
+		// Commands that trace themselves join the run's trace
+		if _, ok := resolved.Env["TRACEPARENT"]; !ok {
+			resolved.Env["TRACEPARENT"] = span.Traceparent()
+		}
+	}
+	result := executor.Execute(ctx, resolved, stdoutWriter, stderrWriter)
+	span.SetAttributes(tracing.Int("process.exit.code", result.ExitCode))
+	switch {
+	case result.Error != nil:
+		span.End(result.Error)
+	case result.ExitCode != 0:
+		span.End(fmt.Errorf("exit code %d", result.ExitCode))
+	default:
+		span.End(nil)
+	}
+	return result
 }
 
-func (e *Executor) runCommand(ctx context.Context, command string, args []string, workDir string, env map[string]string, stdoutWriter, stderrWriter io.Writer) *ExecutionResult {
+// runCommand runs a command with the environment environ in its own process
+// group. When ctx is cancelled
+// the whole group is killed. forward, if set, delivers signals to what the
+// command started outside the group, such as a process inside a container.
+//
+// When ctx is cancelled with a *SignalError, the signal goes to forward
+// instead, or to the group without one, and the command is only killed if it
+// is still running after signalGracePeriod.
+//
+// An interactive command is attached to doctrus's stdin, stdout and stderr
+// instead, and its output is not collected. It stays in doctrus's process
+// group, the terminal's foreground group, so it may read from the terminal
+// and gets Ctrl-C from it directly; cancelling ctx only signals the command
+// itself.
+func runCommand(ctx context.Context, command string, args []string, workDir string, environ []string, stdoutWriter, stderrWriter io.Writer, forward func(os.Signal), interactive bool) *ExecutionResult {
 	cmd := exec.CommandContext(ctx, command, args...)
 	cmd.Dir = workDir
+	signalCommand := func(sig os.Signal) error { return signalProcessGroup(cmd, sig) }
+	if interactive {
+		signalCommand = func(sig os.Signal) error { return cmd.Process.Signal(sig) }
+		interactiveCommands.Add(1)
+		defer interactiveCommands.Add(-1)
+	} else {
+		setProcessGroup(cmd)
+	}
 
-	envList := os.Environ()
-	for key, value := range env {
-		envList = append(envList, fmt.Sprintf("%s=%s", key, value))
+	kill := func() error {
+		if forward != nil {
+			forward(os.Kill)
+		}
+		return signalCommand(os.Kill)
+	}
+	// Read before the command starts, as the grace period outlives the call
+	grace := signalGracePeriod
+	done := make(chan struct{})
+	defer close(done)
+	cmd.Cancel = func() error {
+		var signalErr *SignalError
+		if !errors.As(context.Cause(ctx), &signalErr) {
+			return kill()
+		}
+
+		go func() {
+			select {
+			case <-done:
+			case <-time.After(grace):
+				kill()
+			}
+		}()
+		if forward != nil {
+			forward(signalErr.Signal)
+			return nil
+		}
+		return signalCommand(signalErr.Signal)
 	}
-	cmd.Env = envList
+
+	cmd.Env = environ
 
 	var stdout, stderr bytes.Buffer
 	if stdoutWriter != nil {
@@ -140,14 +238,28 @@ func (e *Executor) runCommand(ctx context.Context, command string, args []string
 		cmd.Stderr = &stderr
 	}
 
+	if interactive {
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	}
+
+	start := time.Now()
 	err := cmd.Run()
+	usage := processUsage(cmd.ProcessState)
+	usage.WallTime = time.Since(start)
+
 	exitCode := 0
+	var cause error
 	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			exitCode = exitError.ExitCode()
-		} else if ctx.Err() == context.Canceled {
-			// Command was cancelled, return appropriate exit code
+		if ctx.Err() != nil {
+			// Command was cancelled, return the exit code timeout(1) and
+			// shells use for the reason
+			cause = context.Cause(ctx)
 			exitCode = 130 // SIGINT exit code
+			if errors.Is(cause, context.DeadlineExceeded) {
+				exitCode = 124
+			}
+		} else if exitError, ok := err.(*exec.ExitError); ok {
+			exitCode = exitError.ExitCode()
 		} else {
 			exitCode = 1
 		}
@@ -158,11 +270,21 @@ func (e *Executor) runCommand(ctx context.Context, command string, args []string
 		Stdout:   stdout.String(),
 		Stderr:   stderr.String(),
 		Error:    err,
+		Cause:    cause,
+		Usage:    usage,
 	}
 }
 
-func (e *Executor) buildEnvVars(execution *workspace.TaskExecution) map[string]string {
+// buildEnvVars returns the task's variables: the environment resolved by the
+// Dispatcher, or the workspace and task env for executions that bypass it.
+func buildEnvVars(execution *workspace.TaskExecution) map[string]string {
 	env := make(map[string]string)
+	if execution.Env != nil {
+		for key, value := range execution.Env {
+			env[key] = value
+		}
+		return env
+	}
 
 	for key, value := range execution.Workspace.Env {
 		env[key] = value
@@ -175,74 +297,120 @@ func (e *Executor) buildEnvVars(execution *workspace.TaskExecution) map[string]s
 	return env
 }
 
-func (e *Executor) containerWorkDir(execution *workspace.TaskExecution) (string, bool) {
-	workspacePath := execution.Workspace.Path
-	if workspacePath == "" {
-		return "", false
+// hermeticEnvVars are the host variables hermetic tasks keep besides those
+// listed in pass_env.
+var hermeticEnvVars = []string{"PATH", "HOME"}
+
+// hostEnviron returns the host environment a task's command starts from:
+// all of it, or for hermetic tasks only hermeticEnvVars and pass_env.
+func hostEnviron(task *config.Task) []string {
+	environ := os.Environ()
+	if !task.Hermetic {
+		return environ
+	}
+
+	allowed := append(append([]string(nil), hermeticEnvVars...), task.PassEnv...)
+	var kept []string
+	for _, entry := range environ {
+		name, _, _ := strings.Cut(entry, "=")
+		for _, allow := range allowed {
+			if name == allow || (runtime.GOOS == "windows" && strings.EqualFold(name, allow)) {
+				kept = append(kept, entry)
+				break
+			}
+		}
 	}
+	return kept
+}
 
-	if filepath.IsAbs(workspacePath) {
-		return filepath.ToSlash(workspacePath), true
-	}
+// commandEnviron adds env, sorted by name, to the base environment. The
+// appendVars present in both are joined, with the value from env first.
+func commandEnviron(base []string, env map[string]string, appendVars []string) []string {
+	keys := sortedEnvKeys(env)
 
-	relPath, err := filepath.Rel(e.workingDir, execution.AbsPath)
-	if err == nil {
-		relPath = filepath.ToSlash(relPath)
-		if relPath == "" {
-			return ".", false
-		}
-		return relPath, false
+	baseValues := make(map[string]string, len(base))
+	for _, entry := range base {
+		name, value, _ := strings.Cut(entry, "=")
+		baseValues[name] = value
+	}
+	appends := make(map[string]bool, len(appendVars))
+	for _, name := range appendVars {
+		appends[name] = true
 	}
 
-	clean := strings.TrimPrefix(filepath.ToSlash(workspacePath), "./")
-	if clean == "" {
-		return ".", false
+	environ := append([]string(nil), base...)
+	for _, key := range keys {
+		value := env[key]
+		if appends[key] {
+			value = config.JoinEnvList(value, baseValues[key])
+		}
+		environ = append(environ, fmt.Sprintf("%s=%s", key, value))
 	}
-	return clean, false
+	return environ
 }
 
-func buildShellCommand(workDir string, command []string) string {
-	target := workDir
-	if target == "" {
-		target = "."
+// sortedEnvKeys returns the names in env in sorted order, so -e flags are
+// passed to docker deterministically.
+func sortedEnvKeys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
 	}
-
-	return fmt.Sprintf("cd %s && %s", shellEscape(target), shellJoin(command))
+	sort.Strings(keys)
+	return keys
 }
 
-func shellJoin(args []string) string {
-	if len(args) == 0 {
-		return ""
-	}
+// cancelTimeout bounds the docker commands that signal a cancelled task's
+// container process
+const cancelTimeout = 10 * time.Second
 
-	quoted := make([]string, len(args))
-	for i, arg := range args {
-		quoted[i] = shellEscape(arg)
-	}
-	return strings.Join(quoted, " ")
+// killProcessGroup kills the process group started by setProcessGroup.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return signalProcessGroup(cmd, os.Kill)
 }
 
-func shellEscape(value string) string {
-	if value == "" {
-		return "''"
+// signalName returns the name kill(1) and docker kill use for sig.
+func signalName(sig os.Signal) string {
+	switch sig {
+	case os.Interrupt:
+		return "INT"
+	case syscall.SIGTERM:
+		return "TERM"
+	default:
+		return "KILL"
 	}
-	return "'" + strings.ReplaceAll(value, "'", "'\\''") + "'"
 }
 
-func (e *Executor) IsDockerComposeAvailable() bool {
-	cmd := exec.Command("docker", "compose", "version")
-	return cmd.Run() == nil
+// newRunID returns a random identifier for naming a task's container or
+// pid file.
+func newRunID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b[:])
 }
 
-func (e *Executor) GetRunningContainers() ([]string, error) {
-	composeFile := e.config.Docker.ComposeFile
+// composeFilePath returns the absolute path of a compose file, defaulting to
+// docker-compose.yml in workingDir.
+func composeFilePath(composeFile, workingDir string) string {
 	if composeFile == "" {
 		composeFile = "docker-compose.yml"
 	}
 
 	if !filepath.IsAbs(composeFile) {
-		composeFile = filepath.Join(e.workingDir, composeFile)
+		composeFile = filepath.Join(workingDir, composeFile)
 	}
+	return composeFile
+}
+
+func (d *Dispatcher) IsDockerComposeAvailable() bool {
+	cmd := exec.Command("docker", "compose", "version")
+	return cmd.Run() == nil
+}
+
+func (d *Dispatcher) GetRunningContainers() ([]string, error) {
+	composeFile := composeFilePath(d.config.Docker.ComposeFile, d.workingDir)
 
 	cmd := exec.Command("docker", "compose", "-f", composeFile, "ps", "--format", "json")
 	output, err := cmd.Output()
@@ -260,21 +428,3 @@ func (e *Executor) GetRunningContainers() ([]string, error) {
 
 	return containers, nil
 }
-
-func (e *Executor) isContainerRunning(composeFile, containerName string) bool {
-	cmd := exec.Command("docker", "compose", "-f", composeFile, "ps", "--format", "json", containerName)
-	output, err := cmd.Output()
-	if err != nil {
-		return false
-	}
-
-	// Parse the JSON output to check if container is running
-	outputStr := strings.TrimSpace(string(output))
-	if outputStr == "" {
-		return false
-	}
-
-	// Simple check: if we got output, assume container exists and is running
-	// The docker compose ps command returns info for running containers
-	return true
-}
@@ -2,8 +2,11 @@ package docker
 
 import (
 	"context"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
@@ -12,9 +15,9 @@ import (
 	"doctrus/internal/workspace"
 )
 
-func TestExecutorContainerWorkDir(t *testing.T) {
+func TestComposeExecutorContainerWorkDir(t *testing.T) {
 	baseDir := t.TempDir()
-	executor := &Executor{config: &config.Config{}, workingDir: baseDir}
+	executor := &ComposeExecutor{config: &config.Config{}, workingDir: baseDir}
 
 	tests := []struct {
 		name          string
@@ -71,17 +74,57 @@ func TestExecutorContainerWorkDir(t *testing.T) {
 	}
 }
 
-func TestBuildShellCommand(t *testing.T) {
-	got := buildShellCommand("frontend", []string{"npm", "run", "build"})
-	want := "cd 'frontend' && 'npm' 'run' 'build'"
-	if got != want {
-		t.Fatalf("buildShellCommand() = %q, want %q", got, want)
+func TestContainerCommandPreservesArguments(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	baseDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(baseDir, "my app"), 0o755); err != nil {
+		t.Fatalf("failed to create workspace dir: %v", err)
+	}
+
+	args := []string{"a b", `it's "quoted"`, "$HOME", "*", "line1\nline2", "-n", "", "&&", "pwd"}
+	var want strings.Builder
+	for _, arg := range args {
+		want.WriteString("[" + arg + "]\n")
+	}
+
+	tests := []struct {
+		name    string
+		workDir string
+		wantDir string
+	}{
+		{name: "project root", workDir: "", wantDir: baseDir},
+		{name: "relative workdir", workDir: "my app", wantDir: filepath.Join(baseDir, "my app")},
 	}
 
-	gotRoot := buildShellCommand("", []string{"echo", "hello world"})
-	wantRoot := "cd '.' && 'echo' 'hello world'"
-	if gotRoot != wantRoot {
-		t.Fatalf("buildShellCommand() root = %q, want %q", gotRoot, wantRoot)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pidFile := filepath.Join(t.TempDir(), "task.pid")
+			run := func(command ...string) string {
+				wrapped := containerCommand(pidFile, tt.workDir, command)
+				cmd := exec.Command(wrapped[0], wrapped[1:]...)
+				cmd.Dir = baseDir
+				out, err := cmd.Output()
+				if err != nil {
+					t.Fatalf("wrapped %q failed: %v", command, err)
+				}
+				return string(out)
+			}
+
+			if got := run(append([]string{"printf", "[%s]\\n"}, args...)...); got != want.String() {
+				t.Fatalf("output = %q, want %q", got, want.String())
+			}
+			if got := strings.TrimSpace(run("pwd", "-P")); got != tt.wantDir {
+				if resolved, err := filepath.EvalSymlinks(tt.wantDir); err != nil || got != resolved {
+					t.Fatalf("ran in %q, want %q", got, tt.wantDir)
+				}
+			}
+			if _, err := os.Stat(pidFile); err != nil {
+				t.Fatalf("pid file was not written: %v", err)
+			}
+		})
 	}
 }
 
@@ -96,7 +139,7 @@ func TestExecuteLocalUsesWorkspacePath(t *testing.T) {
 		t.Fatalf("failed to create workspace dir: %v", err)
 	}
 
-	executor := NewExecutor(&config.Config{}, baseDir)
+	executor := NewLocalExecutor()
 	execution := &workspace.TaskExecution{
 		WorkspaceName: "app",
 		TaskName:      "pwd",
@@ -107,13 +150,360 @@ func TestExecuteLocalUsesWorkspacePath(t *testing.T) {
 		AbsPath:   workspaceDir,
 	}
 
-	result := executor.executeLocal(context.Background(), execution, nil, nil)
+	result := executor.Execute(context.Background(), execution, nil, nil)
 	if result.Error != nil {
-		t.Fatalf("executeLocal() error = %v", result.Error)
+		t.Fatalf("Execute() error = %v", result.Error)
 	}
 
 	pwd := strings.TrimSpace(result.Stdout)
 	if pwd != workspaceDir {
-		t.Fatalf("executeLocal() ran in %q, want %q", pwd, workspaceDir)
+		t.Fatalf("Execute() ran in %q, want %q", pwd, workspaceDir)
+	}
+}
+
+func TestExecuteLocalInteractive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input")
+	if err := os.WriteFile(input, []byte("hello\n"), 0o644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	stdin, err := os.Open(input)
+	if err != nil {
+		t.Fatalf("failed to open input: %v", err)
+	}
+	defer stdin.Close()
+	origStdin := os.Stdin
+	os.Stdin = stdin
+	t.Cleanup(func() { os.Stdin = origStdin })
+
+	execution := &workspace.TaskExecution{
+		WorkspaceName: "app",
+		TaskName:      "prompt",
+		Task: &config.Task{
+			Command:     []string{"sh", "-c", "read line && echo \"$line\" > answer"},
+			Interactive: true,
+		},
+		Workspace: &config.Workspace{},
+		AbsPath:   dir,
+	}
+
+	result := NewLocalExecutor().Execute(context.Background(), execution, io.Discard, io.Discard)
+	if result.Error != nil {
+		t.Fatalf("Execute() error = %v", result.Error)
+	}
+	answer, err := os.ReadFile(filepath.Join(dir, "answer"))
+	if err != nil {
+		t.Fatalf("failed to read answer: %v", err)
+	}
+	if strings.TrimSpace(string(answer)) != "hello" {
+		t.Fatalf("command read %q from stdin, want hello", answer)
+	}
+}
+
+func TestExecuteLocalHermeticEnvironment(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("env command not available on Windows")
+	}
+
+	t.Setenv("DOCTRUS_TEST_HOST", "host")
+	t.Setenv("DOCTRUS_TEST_PASSED", "passed")
+
+	tests := []struct {
+		name string
+		task config.Task
+		want []string
+		skip []string
+	}{
+		{
+			name: "inherits host environment",
+			task: config.Task{Env: map[string]string{"DECLARED": "yes"}},
+			want: []string{"DOCTRUS_TEST_HOST=host", "DOCTRUS_TEST_PASSED=passed", "DECLARED=yes"},
+		},
+		{
+			name: "hermetic keeps allowlist and declared vars",
+			task: config.Task{
+				Env:      map[string]string{"DECLARED": "yes"},
+				Hermetic: true,
+				PassEnv:  []string{"DOCTRUS_TEST_PASSED"},
+			},
+			want: []string{"DOCTRUS_TEST_PASSED=passed", "DECLARED=yes", "PATH="},
+			skip: []string{"DOCTRUS_TEST_HOST="},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := tt.task
+			task.Command = []string{"env"}
+			execution := &workspace.TaskExecution{
+				WorkspaceName: "app",
+				TaskName:      "env",
+				Task:          &task,
+				Workspace:     &config.Workspace{},
+				AbsPath:       t.TempDir(),
+			}
+
+			result := NewLocalExecutor().Execute(context.Background(), execution, nil, nil)
+			if result.Error != nil {
+				t.Fatalf("Execute() error = %v", result.Error)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(result.Stdout, want) {
+					t.Errorf("environment is missing %q:\n%s", want, result.Stdout)
+				}
+			}
+			for _, skip := range tt.skip {
+				if strings.Contains(result.Stdout, skip) {
+					t.Errorf("environment should not contain %q:\n%s", skip, result.Stdout)
+				}
+			}
+		})
+	}
+}
+
+func TestDispatcherResolvesEnvironment(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Version:  "1.0",
+		Env:      map[string]string{"LEVEL": "global"},
+		EnvMerge: &config.EnvMerge{Append: []string{"PATH"}},
+		Workspaces: map[string]config.Workspace{
+			"app": {
+				Path: ".",
+				Tasks: map[string]config.Task{
+					"env": {
+						Command: []string{"sh", "-c", `echo "$LEVEL $PATH"`},
+						Env:     map[string]string{"PATH": "/tools/bin"},
+					},
+				},
+			},
+		},
+	}
+	execution := &workspace.TaskExecution{
+		WorkspaceName: "app",
+		TaskName:      "env",
+		Task:          &config.Task{Command: cfg.Workspaces["app"].Tasks["env"].Command},
+		Workspace:     &config.Workspace{},
+		AbsPath:       dir,
+	}
+
+	dispatcher := NewExecutor(cfg, dir)
+	dispatcher.SetCLIEnv(map[string]string{"LEVEL": "cli"})
+	result := dispatcher.Execute(context.Background(), execution, nil, nil)
+	if result.Error != nil {
+		t.Fatalf("Execute() error = %v", result.Error)
+	}
+
+	want := "cli /tools/bin" + string(os.PathListSeparator) + os.Getenv("PATH")
+	if got := strings.TrimSpace(result.Stdout); got != want {
+		t.Fatalf("environment = %q, want %q", got, want)
+	}
+	if execution.Env != nil {
+		t.Fatal("Execute() must not modify the shared execution")
+	}
+}
+
+type recordingExecutor struct {
+	name  string
+	calls *[]string
+}
+
+func (e recordingExecutor) Execute(ctx context.Context, execution *workspace.TaskExecution, stdoutWriter, stderrWriter io.Writer) *ExecutionResult {
+	*e.calls = append(*e.calls, e.name+" "+execution.TaskName)
+	return &ExecutionResult{}
+}
+
+func TestDispatcherSelectsConfiguredExecutor(t *testing.T) {
+	cfg := &config.Config{
+		Workspaces: map[string]config.Workspace{
+			"app": {
+				Container: "web",
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"make"}},
+					"lint":  {Command: []string{"lint"}, Executor: config.ExecutorLocal},
+					"test":  {Command: []string{"test"}, Executor: config.ExecutorDockerRun, Image: "golang:1.24"},
+					"ship":  {Command: []string{"ship"}, Executor: "ssh"},
+				},
+			},
+		},
+	}
+
+	var calls []string
+	dispatcher := NewExecutor(cfg, t.TempDir())
+	for _, name := range []string{config.ExecutorLocal, config.ExecutorComposeExec, config.ExecutorDockerRun, "ssh"} {
+		dispatcher.Register(name, recordingExecutor{name: name, calls: &calls})
+	}
+
+	for _, task := range []string{"build", "lint", "test", "ship"} {
+		execution := &workspace.TaskExecution{WorkspaceName: "app", TaskName: task}
+		if result := dispatcher.Execute(context.Background(), execution, nil, nil); result.Error != nil {
+			t.Fatalf("Execute(%s) error = %v", task, result.Error)
+		}
+	}
+
+	want := []string{"compose-exec build", "local lint", "docker-run test", "ssh ship"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestDispatcherUnknownExecutor(t *testing.T) {
+	cfg := &config.Config{
+		Workspaces: map[string]config.Workspace{
+			"app": {Tasks: map[string]config.Task{"ship": {Command: []string{"ship"}, Executor: "k8s"}}},
+		},
+	}
+
+	execution := &workspace.TaskExecution{WorkspaceName: "app", TaskName: "ship"}
+	result := NewExecutor(cfg, t.TempDir()).Execute(context.Background(), execution, nil, nil)
+	if result.Error == nil || result.ExitCode != 1 {
+		t.Fatalf("Execute() = %+v, want unknown executor error", result)
+	}
+}
+
+func TestRunExecutorArgs(t *testing.T) {
+	baseDir := t.TempDir()
+	executor := NewRunExecutor(&config.Config{}, baseDir)
+
+	tests := []struct {
+		name    string
+		absPath string
+		want    []string
+	}{
+		{
+			name:    "workspace in project",
+			absPath: filepath.Join(baseDir, "services", "api"),
+			want:    []string{"run", "--rm", "--name", "doctrus-test", "-v", baseDir + ":/workspace", "-w", "/workspace/services/api", "-e", "A=1", "-e", "B=2", "golang:1.24", "go", "test"},
+		},
+		{
+			name:    "project root",
+			absPath: baseDir,
+			want:    []string{"run", "--rm", "--name", "doctrus-test", "-v", baseDir + ":/workspace", "-w", "/workspace", "-e", "A=1", "-e", "B=2", "golang:1.24", "go", "test"},
+		},
+		{
+			name:    "workspace outside project",
+			absPath: filepath.Join(filepath.Dir(baseDir), "shared"),
+			want:    []string{"run", "--rm", "--name", "doctrus-test", "-v", filepath.Join(filepath.Dir(baseDir), "shared") + ":/workspace", "-w", "/workspace", "-e", "A=1", "-e", "B=2", "golang:1.24", "go", "test"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execution := &workspace.TaskExecution{
+				Task:    &config.Task{Command: []string{"go", "test"}},
+				AbsPath: tt.absPath,
+			}
+			got := executor.runArgs(execution, "doctrus-test", "golang:1.24", map[string]string{"B": "2", "A": "1"})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("runArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunExecutorArgsInteractive(t *testing.T) {
+	baseDir := t.TempDir()
+	executor := NewRunExecutor(&config.Config{}, baseDir)
+	execution := &workspace.TaskExecution{
+		Task:    &config.Task{Command: []string{"psql"}, Interactive: true},
+		AbsPath: baseDir,
+	}
+
+	// Without a terminal, stdin is attached without a TTY
+	stdin, err := os.Create(filepath.Join(baseDir, "stdin"))
+	if err != nil {
+		t.Fatalf("failed to create stdin: %v", err)
+	}
+	defer stdin.Close()
+	origStdin := os.Stdin
+	os.Stdin = stdin
+	t.Cleanup(func() { os.Stdin = origStdin })
+
+	got := executor.runArgs(execution, "doctrus-test", "postgres:16", nil)
+	want := []string{"run", "--rm", "--name", "doctrus-test", "-v", baseDir + ":/workspace", "-w", "/workspace", "-i", "postgres:16", "psql"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("runArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestRunExecutorArgsPathMapping(t *testing.T) {
+	cfg := &config.Config{PathMapping: []config.PathMapping{{Container: "/workspaces/app", Host: "/home/me/app"}}}
+	executor := NewRunExecutor(cfg, "/workspaces/app")
+	execution := &workspace.TaskExecution{
+		Task:    &config.Task{Command: []string{"go", "test"}},
+		AbsPath: "/workspaces/app/api",
+	}
+
+	got := executor.runArgs(execution, "doctrus-test", "golang:1.24", nil)
+	want := []string{"run", "--rm", "--name", "doctrus-test", "-v", "/home/me/app:/workspace", "-w", "/workspace/api", "golang:1.24", "go", "test"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("runArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestImageDigest(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{name: "empty", output: "", want: ""},
+		{name: "local image", output: "sha256:aaa", want: "sha256:aaa"},
+		{name: "registry digest", output: "golang@sha256:bbb\nghcr.io/org/golang@sha256:ccc\nsha256:aaa", want: "sha256:bbb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := imageDigest(tt.output); got != tt.want {
+				t.Fatalf("imageDigest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatcherAppliesWrapper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command not available on Windows")
+	}
+
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Version: "1.0",
+		Wrapper: []string{"env", "WRAPPED=yes"},
+		Workspaces: map[string]config.Workspace{
+			"app": {
+				Path: ".",
+				Tasks: map[string]config.Task{
+					"check": {Command: []string{"sh", "-c", `echo "wrapped=$WRAPPED"`}},
+				},
+			},
+		},
+	}
+	task := cfg.Workspaces["app"].Tasks["check"]
+	execution := &workspace.TaskExecution{
+		WorkspaceName: "app",
+		TaskName:      "check",
+		Task:          &task,
+		Workspace:     &config.Workspace{},
+		AbsPath:       dir,
+	}
+
+	result := NewExecutor(cfg, dir).Execute(context.Background(), execution, nil, nil)
+	if result.Error != nil {
+		t.Fatalf("Execute() error = %v", result.Error)
+	}
+	if got := strings.TrimSpace(result.Stdout); got != "wrapped=yes" {
+		t.Fatalf("output = %q, want the command run through the wrapper", got)
+	}
+	if len(execution.Task.Command) != 3 {
+		t.Fatalf("Execute() must not modify the task's command, got %v", execution.Task.Command)
 	}
 }
@@ -0,0 +1,77 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+// ImageDigest returns the image a task runs in and its content digest, such
+// as sha256:..., for provenance records. Tasks run on the host have neither.
+// For compose-exec tasks the image is the one of the task's running
+// container.
+func (d *Dispatcher) ImageDigest(ctx context.Context, execution *workspace.TaskExecution) (string, string, error) {
+	switch d.config.GetEffectiveExecutor(execution.WorkspaceName, execution.TaskName) {
+	case config.ExecutorDockerRun:
+		image := d.config.GetEffectiveImage(execution.WorkspaceName, execution.TaskName)
+		if image == "" {
+			return "", "", fmt.Errorf("no image configured for %s:%s", execution.WorkspaceName, execution.TaskName)
+		}
+		output, err := dockerOutput(ctx, "image", "inspect", "--format", "{{range .RepoDigests}}{{println .}}{{end}}{{.Id}}", image)
+		if err != nil {
+			return image, "", fmt.Errorf("failed to inspect image %s: %w", image, err)
+		}
+		return image, imageDigest(output), nil
+
+	case config.ExecutorComposeExec:
+		service := d.config.GetEffectiveContainer(execution.WorkspaceName, execution.TaskName)
+		if service == "" {
+			return "", "", fmt.Errorf("no container configured for %s:%s", execution.WorkspaceName, execution.TaskName)
+		}
+		dockerConfig := d.config.GetEffectiveDockerConfig(execution.WorkspaceName, execution.TaskName)
+		composeFile := composeFilePath(dockerConfig.ComposeFile, d.workingDir)
+		id, err := dockerOutput(ctx, "compose", "-f", composeFile, "ps", "-q", service)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to find the container of service %s: %w", service, err)
+		}
+		if id == "" {
+			return "", "", fmt.Errorf("service %s has no running container", service)
+		}
+		output, err := dockerOutput(ctx, "inspect", "--format", "{{.Config.Image}}\n{{.Image}}", strings.Fields(id)[0])
+		if err != nil {
+			return "", "", fmt.Errorf("failed to inspect the container of service %s: %w", service, err)
+		}
+		image, digest, _ := strings.Cut(output, "\n")
+		return image, strings.TrimSpace(digest), nil
+	}
+	return "", "", nil
+}
+
+// imageDigest picks the digest from docker image inspect output listing the
+// image's repo digests, one per line, followed by its local ID. A registry
+// digest is preferred because it identifies the image beyond this host.
+func imageDigest(output string) string {
+	lines := strings.Fields(output)
+	if len(lines) == 0 {
+		return ""
+	}
+	for _, line := range lines[:len(lines)-1] {
+		if _, digest, ok := strings.Cut(line, "@"); ok {
+			return digest
+		}
+	}
+	return lines[len(lines)-1]
+}
+
+// dockerOutput runs docker with args and returns its trimmed output.
+func dockerOutput(ctx context.Context, args ...string) (string, error) {
+	output, err := exec.CommandContext(ctx, "docker", args...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,38 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"doctrus/internal/workspace"
+)
+
+// LocalExecutor runs tasks directly on the host, in the workspace directory.
+// Cancelling a task kills every process it started. Hermetic tasks only see
+// PATH, HOME and their pass_env variables from the host environment.
+// Interactive tasks are attached to the terminal.
+type LocalExecutor struct{}
+
+func NewLocalExecutor() *LocalExecutor {
+	return &LocalExecutor{}
+}
+
+func (e *LocalExecutor) Execute(ctx context.Context, execution *workspace.TaskExecution, stdoutWriter, stderrWriter io.Writer) *ExecutionResult {
+	if len(execution.Task.Command) == 0 {
+		return &ExecutionResult{
+			ExitCode: 1,
+			Error:    fmt.Errorf("no command specified"),
+		}
+	}
+
+	command := execution.Task.Command[0]
+	args := execution.Task.Command[1:]
+	environ := commandEnviron(hostEnviron(execution.Task), buildEnvVars(execution), execution.AppendEnv)
+
+	dir := execution.AbsPath
+	if execution.WorkDir != "" {
+		dir = execution.WorkDir
+	}
+	return runCommand(ctx, command, args, dir, environ, stdoutWriter, stderrWriter, nil, execution.Task.Interactive)
+}
@@ -0,0 +1,28 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package docker
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op where process groups are not supported.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalProcessGroup signals the process itself where process groups are
+// not supported.
+func signalProcessGroup(cmd *exec.Cmd, sig os.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	if sig == os.Kill {
+		return cmd.Process.Kill()
+	}
+	return cmd.Process.Signal(sig)
+}
+
+// maxRSS is unknown where rusage is not available.
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,163 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+// processGone reports whether pid has exited, counting zombies as exited.
+func processGone(pid int) bool {
+	if errors.Is(syscall.Kill(pid, 0), syscall.ESRCH) {
+		return true
+	}
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	return err == nil && strings.Contains(string(stat), ") Z ")
+}
+
+func TestLocalExecutorKillsProcessTreeOnCancel(t *testing.T) {
+	dir := t.TempDir()
+	execution := &workspace.TaskExecution{
+		WorkspaceName: "app",
+		TaskName:      "serve",
+		Task:          &config.Task{Command: []string{"sh", "-c", "sleep 30 & echo $! > child.pid; wait"}},
+		Workspace:     &config.Workspace{},
+		AbsPath:       dir,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result := NewLocalExecutor().Execute(ctx, execution, nil, nil)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Execute took %v after cancellation", elapsed)
+	}
+	if result.ExitCode != 124 {
+		t.Fatalf("ExitCode = %d, want 124", result.ExitCode)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "child.pid"))
+	if err != nil {
+		t.Fatalf("failed to read child pid: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatalf("invalid child pid %q", data)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !processGone(pid) {
+		if time.Now().After(deadline) {
+			syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("child process %d still running after the task was cancelled", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSignalCauseIsForwardedBeforeKill(t *testing.T) {
+	grace := signalGracePeriod
+	signalGracePeriod = 200 * time.Millisecond
+	defer func() { signalGracePeriod = grace }()
+
+	tests := []struct {
+		name   string
+		script string
+		output string
+	}{
+		{
+			name:   "command handles the signal",
+			script: `trap 'echo stopping; exit 3' TERM; echo ready; sleep 30 & wait`,
+			output: "stopping",
+		},
+		{
+			name:   "command ignoring the signal is killed",
+			script: `trap '' TERM; echo ready; sleep 30`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execution := &workspace.TaskExecution{
+				WorkspaceName: "app",
+				TaskName:      "serve",
+				Task:          &config.Task{Command: []string{"sh", "-c", tt.script}},
+				Workspace:     &config.Workspace{},
+				AbsPath:       t.TempDir(),
+			}
+
+			ctx, cancel := context.WithCancelCause(context.Background())
+			defer cancel(nil)
+			ready := make(chan struct{})
+			stdout := &lineNotifier{line: "ready", notify: ready}
+			go func() {
+				<-ready
+				cancel(&SignalError{Signal: syscall.SIGTERM})
+			}()
+
+			start := time.Now()
+			result := NewLocalExecutor().Execute(ctx, execution, stdout, nil)
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Fatalf("Execute took %v after the signal", elapsed)
+			}
+
+			var signalErr *SignalError
+			if result.ExitCode != 130 || !errors.As(result.Cause, &signalErr) {
+				t.Fatalf("result = %+v, want exit code 130 caused by a *SignalError", result)
+			}
+			if !strings.Contains(result.Stdout, tt.output) {
+				t.Fatalf("Stdout = %q, want %q", result.Stdout, tt.output)
+			}
+		})
+	}
+}
+
+// lineNotifier closes notify once line has been written.
+type lineNotifier struct {
+	line   string
+	notify chan struct{}
+	seen   strings.Builder
+	closed bool
+}
+
+func (w *lineNotifier) Write(p []byte) (int, error) {
+	w.seen.Write(p)
+	if !w.closed && strings.Contains(w.seen.String(), w.line) {
+		w.closed = true
+		close(w.notify)
+	}
+	return len(p), nil
+}
+
+func TestLocalExecutorReportsResourceUsage(t *testing.T) {
+	execution := &workspace.TaskExecution{
+		WorkspaceName: "app",
+		TaskName:      "build",
+		Task:          &config.Task{Command: []string{"sh", "-c", "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done"}},
+		Workspace:     &config.Workspace{},
+		AbsPath:       t.TempDir(),
+	}
+
+	result := NewLocalExecutor().Execute(context.Background(), execution, nil, nil)
+	if result.ExitCode != 0 {
+		t.Fatalf("ExitCode = %d: %s", result.ExitCode, result.Stderr)
+	}
+	if result.Usage.WallTime <= 0 || result.Usage.CPUTime <= 0 {
+		t.Fatalf("expected wall and CPU time, got %+v", result.Usage)
+	}
+	if result.Usage.PeakRSS < 1024 {
+		t.Fatalf("PeakRSS = %d, want the shell's resident set size in bytes", result.Usage.PeakRSS)
+	}
+}
@@ -0,0 +1,107 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"doctrus/internal/config"
+	"doctrus/internal/ui"
+	"doctrus/internal/workspace"
+)
+
+// containerMount is where RunExecutor mounts the project inside the container
+const containerMount = "/workspace"
+
+// RunExecutor runs tasks in a fresh container of the task's image with
+// docker run. The project directory is mounted at /workspace and the
+// command starts in the workspace directory below it. Signals and
+// cancellation are sent to the task's container, and its CPU and memory
+// usage are sampled with docker stats.
+type RunExecutor struct {
+	config     *config.Config
+	workingDir string
+}
+
+func NewRunExecutor(cfg *config.Config, workingDir string) *RunExecutor {
+	return &RunExecutor{
+		config:     cfg,
+		workingDir: workingDir,
+	}
+}
+
+func (e *RunExecutor) Execute(ctx context.Context, execution *workspace.TaskExecution, stdoutWriter, stderrWriter io.Writer) *ExecutionResult {
+	if len(execution.Task.Command) == 0 {
+		return &ExecutionResult{
+			ExitCode: 1,
+			Error:    fmt.Errorf("no command specified"),
+		}
+	}
+
+	image := e.config.GetEffectiveImage(execution.WorkspaceName, execution.TaskName)
+	if image == "" {
+		return &ExecutionResult{
+			ExitCode: 1,
+			Error:    fmt.Errorf("no image configured for %s:%s", execution.WorkspaceName, execution.TaskName),
+		}
+	}
+
+	env := buildEnvVars(execution)
+	name := "doctrus-" + newRunID()
+	args := e.runArgs(execution, name, image, env)
+
+	// Signals to the local docker CLI do not reach the container
+	forward := func(sig os.Signal) {
+		killCtx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+		defer cancel()
+		_ = exec.CommandContext(killCtx, "docker", "kill", "-s", signalName(sig), name).Run()
+	}
+
+	// The docker CLI's own usage says nothing about the container, so
+	// measure the container instead
+	monitor := monitorContainer(name)
+	result := runCommand(ctx, "docker", args, execution.AbsPath, commandEnviron(os.Environ(), env, nil), stdoutWriter, stderrWriter, forward, execution.Task.Interactive)
+	usage := monitor.Stop()
+	usage.WallTime = result.Usage.WallTime
+	result.Usage = usage
+	return result
+}
+
+// runArgs builds the docker run arguments for execution in a container
+// called name. Workspaces outside the project directory are mounted on their
+// own. The mounted directory is translated by path_mapping, as the Docker
+// daemon resolves it on the host.
+func (e *RunExecutor) runArgs(execution *workspace.TaskExecution, name, image string, env map[string]string) []string {
+	hostDir := e.workingDir
+	workDir := containerMount
+	if rel, err := filepath.Rel(e.workingDir, execution.AbsPath); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		workDir = path.Join(containerMount, filepath.ToSlash(rel))
+	} else {
+		hostDir = execution.AbsPath
+	}
+
+	args := []string{
+		"run",
+		"--rm",
+		"--name", name,
+		"-v", e.config.HostPath(hostDir) + ":" + containerMount,
+		"-w", workDir,
+	}
+	if execution.Task.Interactive {
+		args = append(args, "-i")
+		if ui.IsTerminal(os.Stdin) {
+			args = append(args, "-t")
+		}
+	}
+	for _, key := range sortedEnvKeys(env) {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", key, env[key]))
+	}
+
+	args = append(args, image)
+	return append(args, execution.Task.Command...)
+}
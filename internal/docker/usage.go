@@ -0,0 +1,132 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// containerStatsInterval is how often the container of a docker-run task is
+// sampled with docker stats.
+var containerStatsInterval = time.Second
+
+// ResourceUsage is what a task's processes consumed. CPUTime is user plus
+// system time and PeakRSS the largest resident set size in bytes; they are
+// zero when they could not be measured.
+type ResourceUsage struct {
+	WallTime time.Duration
+	CPUTime  time.Duration
+	PeakRSS  int64
+}
+
+// processUsage returns the CPU time and peak RSS of an exited process,
+// including the descendants it waited for.
+func processUsage(state *os.ProcessState) ResourceUsage {
+	if state == nil {
+		return ResourceUsage{}
+	}
+	return ResourceUsage{
+		CPUTime: state.UserTime() + state.SystemTime(),
+		PeakRSS: maxRSS(state),
+	}
+}
+
+// containerMonitor samples the CPU and memory of a running container with
+// docker stats. CPU time is integrated from the CPU percentages between
+// samples, so it is an approximation for containers that run briefly.
+type containerMonitor struct {
+	cancel  context.CancelFunc
+	stopped chan struct{}
+	usage   ResourceUsage
+}
+
+// monitorContainer starts sampling the container called name until Stop.
+// Samples taken before the container exists are skipped.
+func monitorContainer(name string) *containerMonitor {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &containerMonitor{cancel: cancel, stopped: make(chan struct{})}
+
+	go func() {
+		defer close(m.stopped)
+		last := time.Now()
+		for {
+			output, err := exec.CommandContext(ctx, "docker", "stats", "--no-stream", "--format", "{{.CPUPerc}}|{{.MemUsage}}", name).Output()
+			if ctx.Err() != nil {
+				return
+			}
+			now := time.Now()
+			if err == nil {
+				if cpu, memory, err := parseContainerStats(string(output)); err == nil {
+					m.usage.CPUTime += time.Duration(cpu / 100 * float64(now.Sub(last)))
+					m.usage.PeakRSS = max(m.usage.PeakRSS, memory)
+				}
+			}
+			last = now
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(containerStatsInterval):
+			}
+		}
+	}()
+	return m
+}
+
+// Stop ends sampling and returns the usage measured so far.
+func (m *containerMonitor) Stop() ResourceUsage {
+	m.cancel()
+	<-m.stopped
+	return m.usage
+}
+
+// parseContainerStats parses a "{{.CPUPerc}}|{{.MemUsage}}" line of docker
+// stats such as "12.50%|45.3MiB / 1.94GiB" into the CPU percentage and the
+// memory in use in bytes.
+func parseContainerStats(line string) (float64, int64, error) {
+	cpuField, memField, ok := strings.Cut(strings.TrimSpace(line), "|")
+	if !ok {
+		return 0, 0, fmt.Errorf("unexpected docker stats output %q", line)
+	}
+	cpu, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(cpuField), "%"), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid CPU percentage %q", cpuField)
+	}
+	used, _, _ := strings.Cut(memField, "/")
+	memory, err := parseSize(strings.TrimSpace(used))
+	if err != nil {
+		return 0, 0, err
+	}
+	return cpu, memory, nil
+}
+
+// sizeUnits are the units docker prints sizes in, both binary and decimal.
+var sizeUnits = map[string]float64{
+	"B":   1,
+	"kB":  1e3,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+}
+
+// parseSize parses a size such as 45.3MiB into bytes.
+func parseSize(size string) (int64, error) {
+	number := strings.TrimRightFunc(size, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	unit, ok := sizeUnits[strings.TrimSpace(size[len(number):])]
+	value, err := strconv.ParseFloat(number, 64)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return int64(value * unit), nil
+}
@@ -0,0 +1,38 @@
+package docker
+
+import "testing"
+
+func TestParseContainerStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		cpu     float64
+		memory  int64
+		wantErr bool
+	}{
+		{name: "binary units", line: "12.50%|45.5MiB / 1.94GiB\n", cpu: 12.5, memory: 45.5 * (1 << 20)},
+		{name: "decimal units", line: "150.00%|2GB / 8GB", cpu: 150, memory: 2e9},
+		{name: "bytes", line: "0.00%|0B / 0B", cpu: 0, memory: 0},
+		{name: "no separator", line: "12.50% 45MiB", wantErr: true},
+		{name: "invalid cpu", line: "--|45MiB / 1GiB", wantErr: true},
+		{name: "unknown unit", line: "1.00%|45XB / 1GiB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu, memory, err := parseContainerStats(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got cpu %v and memory %d", cpu, memory)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseContainerStats(%q) failed: %v", tt.line, err)
+			}
+			if cpu != tt.cpu || memory != tt.memory {
+				t.Fatalf("parseContainerStats(%q) = %v, %d; want %v, %d", tt.line, cpu, memory, tt.cpu, tt.memory)
+			}
+		})
+	}
+}
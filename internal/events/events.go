@@ -0,0 +1,214 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Type identifies a task lifecycle event.
+type Type string
+
+const (
+	// TaskQueued is published when a task is scheduled, before its
+	// dependencies run
+	TaskQueued Type = "task_queued"
+	// TaskStarted is published right before a task's command runs
+	TaskStarted Type = "task_started"
+	// OutputChunk carries a piece of a running task's stdout or stderr
+	OutputChunk Type = "output_chunk"
+	// CacheHit is published instead of TaskStarted when a task is skipped
+	// because its cache is up to date
+	CacheHit Type = "cache_hit"
+	// TaskFinished is published when a task completes, fails or is skipped
+	TaskFinished Type = "task_finished"
+)
+
+// Status values carried by TaskFinished events.
+const (
+	StatusSuccess  = "success"
+	StatusFailed   = "failed"
+	StatusCached   = "cached"
+	StatusCompound = "compound"
+)
+
+// Event is a single task lifecycle notification. Fields that do not apply to
+// the event type are left empty.
+type Event struct {
+	Type      Type          `json:"type"`
+	Time      time.Time     `json:"time"`
+	Workspace string        `json:"workspace"`
+	Task      string        `json:"task"`
+	Stream    string        `json:"stream,omitempty"`
+	Data      string        `json:"data,omitempty"`
+	Status    string        `json:"status,omitempty"`
+	ExitCode  int           `json:"exit_code,omitempty"`
+	Duration  time.Duration `json:"duration_ns,omitempty"`
+	CPUTime   time.Duration `json:"cpu_time_ns,omitempty"`
+	PeakRSS   int64         `json:"peak_rss_bytes,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Key returns the task in workspace:task form.
+func (e Event) Key() string {
+	return e.Workspace + ":" + e.Task
+}
+
+// Subscriber receives events. Handle is called synchronously from the
+// publishing goroutine, possibly from several goroutines at once when tasks
+// run in parallel, so implementations must be quick and safe for concurrent
+// use.
+type Subscriber interface {
+	Handle(Event)
+}
+
+// SubscriberFunc adapts a function to the Subscriber interface.
+type SubscriberFunc func(Event)
+
+func (f SubscriberFunc) Handle(e Event) {
+	f(e)
+}
+
+// Bus fans events out to its subscribers. A nil Bus drops every event.
+type Bus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]Subscriber
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[int]Subscriber)}
+}
+
+// Subscribe registers s and returns a function that unregisters it.
+func (b *Bus) Subscribe(s Subscriber) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = s
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+// Channel subscribes a buffered channel. Events are dropped rather than
+// blocking the publisher when the channel is full. The returned function
+// unsubscribes and closes the channel.
+func (b *Bus) Channel(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+	var mu sync.Mutex
+	closed := false
+	unsubscribe := b.Subscribe(SubscriberFunc(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	}))
+	return ch, func() {
+		unsubscribe()
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			closed = true
+			close(ch)
+		}
+	}
+}
+
+// Active reports whether anyone is listening, so publishers can skip work
+// such as capturing output chunks.
+func (b *Bus) Active() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs) > 0
+}
+
+// Publish stamps e with the current time when unset and delivers it.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.mu.RLock()
+	subs := make([]Subscriber, 0, len(b.subs))
+	for _, s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		s.Handle(e)
+	}
+}
+
+// OutputWriter returns a writer publishing everything written to it as
+// OutputChunk events for the given task and stream.
+func (b *Bus) OutputWriter(workspace, task, stream string) io.Writer {
+	return &outputWriter{bus: b, workspace: workspace, task: task, stream: stream}
+}
+
+type outputWriter struct {
+	bus       *Bus
+	workspace string
+	task      string
+	stream    string
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.bus.Publish(Event{
+		Type:      OutputChunk,
+		Workspace: w.workspace,
+		Task:      w.task,
+		Stream:    w.stream,
+		Data:      string(p),
+	})
+	return len(p), nil
+}
+
+// NDJSON is a subscriber writing each event as a line of JSON.
+type NDJSON struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func NewNDJSON(out io.Writer) *NDJSON {
+	return &NDJSON{out: out}
+}
+
+func (n *NDJSON) Handle(e Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, _ = n.out.Write(append(data, '\n'))
+}
+
+// Formats lists the supported --events formats.
+var Formats = []string{"ndjson"}
+
+// NewFormatter returns the subscriber for an --events format.
+func NewFormatter(format string, out io.Writer) (Subscriber, error) {
+	switch format {
+	case "ndjson":
+		return NewNDJSON(out), nil
+	default:
+		return nil, fmt.Errorf("unsupported events format %q (supported: ndjson)", format)
+	}
+}
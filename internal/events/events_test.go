@@ -0,0 +1,99 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBusSubscribe(t *testing.T) {
+	bus := NewBus()
+	if bus.Active() {
+		t.Fatal("new bus should have no subscribers")
+	}
+
+	var got []Type
+	unsubscribe := bus.Subscribe(SubscriberFunc(func(e Event) {
+		got = append(got, e.Type)
+		if e.Time.IsZero() {
+			t.Error("published event has no time")
+		}
+	}))
+	if !bus.Active() {
+		t.Fatal("bus should be active after Subscribe")
+	}
+
+	bus.Publish(Event{Type: TaskStarted})
+	unsubscribe()
+	bus.Publish(Event{Type: TaskFinished})
+
+	if len(got) != 1 || got[0] != TaskStarted {
+		t.Fatalf("received %v, want [%s]", got, TaskStarted)
+	}
+}
+
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	bus.Publish(Event{Type: TaskStarted})
+	if bus.Active() {
+		t.Fatal("nil bus should not be active")
+	}
+}
+
+func TestBusChannel(t *testing.T) {
+	bus := NewBus()
+	ch, cancel := bus.Channel(1)
+
+	bus.Publish(Event{Type: TaskQueued, Task: "build"})
+	bus.Publish(Event{Type: TaskStarted, Task: "build"}) // dropped, buffer full
+
+	event := <-ch
+	if event.Type != TaskQueued {
+		t.Fatalf("received %s, want %s", event.Type, TaskQueued)
+	}
+
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel should be closed after cancel")
+	}
+	bus.Publish(Event{Type: TaskFinished})
+}
+
+func TestOutputWriterAndNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	bus := NewBus()
+	bus.Subscribe(NewNDJSON(&buf))
+
+	writer := bus.OutputWriter("app", "build", "stdout")
+	if _, err := io.WriteString(writer, "hello\n"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	bus.Publish(Event{Type: TaskFinished, Workspace: "app", Task: "build", Status: StatusSuccess})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), buf.String())
+	}
+
+	var chunk Event
+	if err := json.Unmarshal([]byte(lines[0]), &chunk); err != nil {
+		t.Fatalf("invalid JSON %q: %v", lines[0], err)
+	}
+	if chunk.Type != OutputChunk || chunk.Key() != "app:build" || chunk.Stream != "stdout" || chunk.Data != "hello\n" {
+		t.Fatalf("unexpected chunk event: %+v", chunk)
+	}
+	if !strings.Contains(lines[1], `"status":"success"`) {
+		t.Fatalf("unexpected finished event: %s", lines[1])
+	}
+}
+
+func TestNewFormatter(t *testing.T) {
+	if _, err := NewFormatter("ndjson", io.Discard); err != nil {
+		t.Fatalf("NewFormatter(ndjson) error = %v", err)
+	}
+	if _, err := NewFormatter("xml", io.Discard); err == nil {
+		t.Fatal("expected an error for an unsupported format")
+	}
+}
@@ -0,0 +1,36 @@
+// Package fsutil holds file system helpers shared by the packages writing
+// into the project and the cache.
+package fsutil
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes the content produced by write to a temporary file
+// next to target and renames it into place with the given permissions, so a
+// failed write never leaves a truncated file behind. It creates target's
+// directory when missing.
+func WriteFileAtomic(target string, perm fs.FileMode, write func(w io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
@@ -0,0 +1,45 @@
+package fsutil
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "out", "file.txt")
+
+	err := WriteFileAtomic(target, 0640, func(w io.Writer) error {
+		_, err := io.WriteString(w, "content")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+	data, err := os.ReadFile(target)
+	if err != nil || string(data) != "content" {
+		t.Fatalf("ReadFile() = %q, %v, want content", data, err)
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+
+	failure := errors.New("write failed")
+	err = WriteFileAtomic(target, 0640, func(w io.Writer) error {
+		io.WriteString(w, "trunc")
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("WriteFileAtomic() error = %v, want %v", err, failure)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "content" {
+		t.Errorf("target after a failed write = %q, want content", data)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(target))
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want only the target", len(entries))
+	}
+}
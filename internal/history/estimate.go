@@ -0,0 +1,81 @@
+package history
+
+import (
+	"sort"
+	"time"
+)
+
+// estimateSamples is how many of a task's most recent executions its
+// estimate is based on, so it follows the task as it gets faster or slower.
+const estimateSamples = 20
+
+// A task is slow when it took slowFactor times its median and at least
+// slowMargin longer, judged once it has minSlowSamples executions.
+const (
+	slowFactor     = 1.5
+	slowMargin     = time.Second
+	minSlowSamples = 3
+)
+
+// Estimator predicts how long tasks take from the durations of their
+// successful executions. A nil Estimator has no estimates.
+type Estimator struct {
+	medians map[string]time.Duration
+	samples map[string]int
+}
+
+// NewEstimator builds estimates from runs, which are ordered oldest first as
+// returned by Store.Load. Cached and failed executions are ignored.
+func NewEstimator(runs []Run) *Estimator {
+	durations := make(map[string][]time.Duration)
+	for _, run := range runs {
+		for _, record := range run.Tasks {
+			if record.Outcome == OutcomeSuccess {
+				durations[record.Key()] = append(durations[record.Key()], record.Duration)
+			}
+		}
+	}
+
+	e := &Estimator{
+		medians: make(map[string]time.Duration, len(durations)),
+		samples: make(map[string]int, len(durations)),
+	}
+	for key, samples := range durations {
+		if len(samples) > estimateSamples {
+			samples = samples[len(samples)-estimateSamples:]
+		}
+		e.medians[key] = median(samples)
+		e.samples[key] = len(samples)
+	}
+	return e
+}
+
+// Estimate returns the median duration of the task with key
+// workspace:task, and false when it never ran successfully.
+func (e *Estimator) Estimate(key string) (time.Duration, bool) {
+	if e == nil {
+		return 0, false
+	}
+	d, ok := e.medians[key]
+	return d, ok
+}
+
+// Slow reports whether a task that took took is significantly slower than
+// its median, which is returned as well.
+func (e *Estimator) Slow(key string, took time.Duration) (time.Duration, bool) {
+	if e == nil || e.samples[key] < minSlowSamples {
+		return 0, false
+	}
+	m := e.medians[key]
+	return m, float64(took) > slowFactor*float64(m) && took-m >= slowMargin
+}
+
+func median(samples []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,53 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimator(t *testing.T) {
+	record := func(task string, outcome Outcome, d time.Duration) TaskRecord {
+		return TaskRecord{Workspace: "app", Task: task, Outcome: outcome, Duration: d}
+	}
+
+	runs := []Run{
+		{Tasks: []TaskRecord{record("build", OutcomeSuccess, 10*time.Second), record("lint", OutcomeSuccess, time.Second)}},
+		{Tasks: []TaskRecord{record("build", OutcomeSuccess, 12*time.Second), record("lint", OutcomeCached, 0)}},
+		{Tasks: []TaskRecord{record("build", OutcomeFailed, time.Second), record("test", OutcomeFailed, time.Minute)}},
+		{Tasks: []TaskRecord{record("build", OutcomeSuccess, 30*time.Second)}},
+	}
+	estimator := NewEstimator(runs)
+
+	if got, ok := estimator.Estimate("app:build"); !ok || got != 12*time.Second {
+		t.Fatalf("Estimate(app:build) = %v, %v; want the median 12s", got, ok)
+	}
+	if got, ok := estimator.Estimate("app:lint"); !ok || got != time.Second {
+		t.Fatalf("Estimate(app:lint) = %v, %v; want 1s ignoring cached runs", got, ok)
+	}
+	if _, ok := estimator.Estimate("app:test"); ok {
+		t.Fatal("Estimate(app:test) should have no estimate from failed runs only")
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		took time.Duration
+		want bool
+	}{
+		{name: "twice the median", key: "app:build", took: 24 * time.Second, want: true},
+		{name: "within the usual range", key: "app:build", took: 15 * time.Second},
+		{name: "too few samples", key: "app:lint", took: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, slow := estimator.Slow(tt.key, tt.took); slow != tt.want {
+				t.Fatalf("Slow(%s, %v) = %v, want %v", tt.key, tt.took, slow, tt.want)
+			}
+		})
+	}
+
+	var empty *Estimator
+	if _, ok := empty.Estimate("app:build"); ok {
+		t.Fatal("a nil Estimator should have no estimates")
+	}
+}
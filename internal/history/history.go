@@ -0,0 +1,286 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxRuns is the number of runs kept in the history file; older runs are
+// dropped when new ones are appended.
+const MaxRuns = 1000
+
+// Outcome is how a single task ended within a run.
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailed  Outcome = "failed"
+	OutcomeCached  Outcome = "cached"
+)
+
+// TaskRecord is the result of one task executed as part of a run.
+type TaskRecord struct {
+	Workspace string        `json:"workspace"`
+	Task      string        `json:"task"`
+	Outcome   Outcome       `json:"outcome"`
+	ExitCode  int           `json:"exit_code,omitempty"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	// CPUTime and PeakRSS (in bytes) are what the task's processes
+	// consumed, and zero when it was cached or they were not measured
+	CPUTime time.Duration `json:"cpu_time,omitempty"`
+	PeakRSS int64         `json:"peak_rss,omitempty"`
+}
+
+// Key returns the task in workspace:task form.
+func (r TaskRecord) Key() string {
+	return r.Workspace + ":" + r.Task
+}
+
+// Run is a single doctrus invocation and the tasks it executed.
+type Run struct {
+	ID        string        `json:"id"`
+	Args      []string      `json:"args"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Success   bool          `json:"success"`
+	Error     string        `json:"error,omitempty"`
+	Tasks     []TaskRecord  `json:"tasks"`
+}
+
+// Store persists runs as JSON lines in a file under the project's .doctrus
+// directory.
+type Store struct {
+	path string
+}
+
+// NewStore creates a store keeping its history file in dir.
+func NewStore(dir string) *Store {
+	return &Store{path: filepath.Join(dir, "runs.jsonl")}
+}
+
+// Path returns the location of the history file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Append records a run, trimming the file to the last MaxRuns runs.
+func (s *Store) Append(run Run) error {
+	runs, err := s.Load()
+	if err != nil {
+		return err
+	}
+	runs = append(runs, run)
+	if len(runs) > MaxRuns {
+		runs = runs[len(runs)-MaxRuns:]
+	}
+
+	return s.write(runs)
+}
+
+// Prune drops runs that started before cutoff and all but the last maxRuns
+// runs, returning how many were dropped. A zero cutoff or maxRuns leaves
+// that limit off. With dryRun set nothing is written.
+func (s *Store) Prune(cutoff time.Time, maxRuns int, dryRun bool) (int, error) {
+	runs, err := s.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	kept := runs[:0:0]
+	for _, run := range runs {
+		if cutoff.IsZero() || !run.StartedAt.Before(cutoff) {
+			kept = append(kept, run)
+		}
+	}
+	if maxRuns > 0 && len(kept) > maxRuns {
+		kept = kept[len(kept)-maxRuns:]
+	}
+
+	pruned := len(runs) - len(kept)
+	if dryRun || pruned == 0 {
+		return pruned, nil
+	}
+	return pruned, s.write(kept)
+}
+
+// write replaces the history file with runs.
+func (s *Store) write(runs []Run) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, r := range runs {
+		if err := encoder.Encode(r); err != nil {
+			return fmt.Errorf("failed to encode run: %w", err)
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// Load returns all recorded runs, oldest first. Lines that cannot be parsed
+// are skipped so a truncated write does not lose the whole history.
+func (s *Store) Load() ([]Run, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var run Run
+		if err := json.Unmarshal(line, &run); err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	return runs, nil
+}
+
+// Recorder collects task records while a run is in progress. It is safe for
+// concurrent use, and a nil Recorder discards everything.
+type Recorder struct {
+	mu  sync.Mutex
+	run Run
+}
+
+// NewRecorder starts recording a run invoked with args.
+func NewRecorder(args []string) *Recorder {
+	now := time.Now()
+	return &Recorder{run: Run{
+		ID:        strconv.FormatInt(now.UnixNano(), 36),
+		Args:      append([]string(nil), args...),
+		StartedAt: now,
+	}}
+}
+
+// Add records the result of a task.
+func (r *Recorder) Add(record TaskRecord) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.run.Tasks = append(r.run.Tasks, record)
+}
+
+// Finish completes the run with the error it ended with, if any.
+func (r *Recorder) Finish(err error) Run {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run := r.run
+	run.Tasks = append([]TaskRecord(nil), r.run.Tasks...)
+	run.Duration = time.Since(run.StartedAt)
+	run.Success = err == nil
+	if err != nil {
+		run.Error = err.Error()
+	}
+	return run
+}
+
+// Filter selects runs and task records. Zero fields match everything.
+type Filter struct {
+	Workspace string
+	Task      string
+	Since     time.Time
+	Until     time.Time
+}
+
+// MatchTask reports whether a task record satisfies the workspace and task
+// filters.
+func (f Filter) MatchTask(record TaskRecord) bool {
+	if f.Workspace != "" && record.Workspace != f.Workspace {
+		return false
+	}
+	if f.Task != "" && record.Task != f.Task {
+		return false
+	}
+	return true
+}
+
+// Apply returns the runs within the time range, keeping only matching task
+// records. Runs left without tasks are dropped when a workspace or task filter
+// is set.
+func (f Filter) Apply(runs []Run) []Run {
+	var selected []Run
+	for _, run := range runs {
+		if !f.Since.IsZero() && run.StartedAt.Before(f.Since) {
+			continue
+		}
+		if !f.Until.IsZero() && run.StartedAt.After(f.Until) {
+			continue
+		}
+
+		var tasks []TaskRecord
+		for _, record := range run.Tasks {
+			if f.MatchTask(record) {
+				tasks = append(tasks, record)
+			}
+		}
+		if len(tasks) == 0 && (f.Workspace != "" || f.Task != "") {
+			continue
+		}
+		run.Tasks = tasks
+		selected = append(selected, run)
+	}
+	return selected
+}
+
+// ParseTime interprets a --since/--until value relative to now. It accepts
+// durations ("90m", "24h", "7d") meaning that long ago, dates (2006-01-02) and
+// RFC 3339 timestamps.
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if strings.HasSuffix(value, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q (use a duration like 24h or 7d, a date like 2006-01-02, or RFC 3339)", value)
+}
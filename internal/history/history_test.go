@@ -0,0 +1,191 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreAppendAndLoad(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	runs, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() on empty store error = %v", err)
+	}
+	if len(runs) != 0 {
+		t.Fatalf("expected no runs, got %d", len(runs))
+	}
+
+	recorder := NewRecorder([]string{"build"})
+	recorder.Add(TaskRecord{Workspace: "app", Task: "build", Outcome: OutcomeSuccess, Duration: time.Second})
+	recorder.Add(TaskRecord{Workspace: "lib", Task: "build", Outcome: OutcomeCached})
+	if err := store.Append(recorder.Finish(nil)); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	failed := NewRecorder([]string{"test"})
+	failed.Add(TaskRecord{Workspace: "app", Task: "test", Outcome: OutcomeFailed, ExitCode: 2})
+	if err := store.Append(failed.Finish(errors.New("task failed"))); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	runs, err = store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs, got %d", len(runs))
+	}
+	if !runs[0].Success || len(runs[0].Tasks) != 2 || runs[0].Tasks[0].Duration != time.Second {
+		t.Fatalf("unexpected first run: %+v", runs[0])
+	}
+	if runs[1].Success || runs[1].Error != "task failed" || runs[1].Tasks[0].ExitCode != 2 {
+		t.Fatalf("unexpected second run: %+v", runs[1])
+	}
+}
+
+func TestStoreSkipsCorruptLines(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if err := store.Append(NewRecorder(nil).Finish(nil)); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	file, err := os.OpenFile(store.Path(), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("failed to open history: %v", err)
+	}
+	_, _ = file.WriteString("{\"id\": \"trunc")
+	file.Close()
+
+	runs, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("expected the corrupt line to be skipped, got %d runs", len(runs))
+	}
+}
+
+func TestFilterApply(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	runs := []Run{
+		{ID: "1", StartedAt: base, Tasks: []TaskRecord{{Workspace: "app", Task: "build"}, {Workspace: "lib", Task: "build"}}},
+		{ID: "2", StartedAt: base.Add(24 * time.Hour), Tasks: []TaskRecord{{Workspace: "app", Task: "test"}}},
+		{ID: "3", StartedAt: base.Add(48 * time.Hour), Tasks: []TaskRecord{{Workspace: "lib", Task: "build"}}},
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+		tasks  int
+	}{
+		{name: "no filter", filter: Filter{}, want: []string{"1", "2", "3"}, tasks: 4},
+		{name: "workspace", filter: Filter{Workspace: "app"}, want: []string{"1", "2"}, tasks: 2},
+		{name: "workspace and task", filter: Filter{Workspace: "lib", Task: "build"}, want: []string{"1", "3"}, tasks: 2},
+		{name: "since", filter: Filter{Since: base.Add(time.Hour)}, want: []string{"2", "3"}, tasks: 2},
+		{name: "until", filter: Filter{Until: base.Add(25 * time.Hour)}, want: []string{"1", "2"}, tasks: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.filter.Apply(runs)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Apply() returned %d runs, want %d", len(got), len(tt.want))
+			}
+			tasks := 0
+			for i, run := range got {
+				if run.ID != tt.want[i] {
+					t.Fatalf("run %d = %s, want %s", i, run.ID, tt.want[i])
+				}
+				tasks += len(run.Tasks)
+			}
+			if tasks != tt.tasks {
+				t.Fatalf("Apply() kept %d tasks, want %d", tasks, tt.tasks)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "", want: time.Time{}},
+		{value: "24h", want: now.Add(-24 * time.Hour)},
+		{value: "7d", want: now.AddDate(0, 0, -7)},
+		{value: "2024-05-01", want: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-05-01T08:00:00Z", want: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)},
+		{value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTime(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTime(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Fatalf("ParseTime(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		cutoff  time.Time
+		maxRuns int
+		dryRun  bool
+		want    []string
+	}{
+		{name: "no limits", want: []string{"a", "b", "c", "d"}},
+		{name: "age", cutoff: now.Add(-36 * time.Hour), want: []string{"c", "d"}},
+		{name: "count", maxRuns: 3, want: []string{"b", "c", "d"}},
+		{name: "age and count", cutoff: now.Add(-72 * time.Hour), maxRuns: 1, want: []string{"d"}},
+		{name: "dry run", maxRuns: 1, dryRun: true, want: []string{"a", "b", "c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore(t.TempDir())
+			for i, id := range []string{"a", "b", "c", "d"} {
+				run := Run{ID: id, StartedAt: now.Add(-time.Duration(3-i) * 24 * time.Hour)}
+				if err := store.Append(run); err != nil {
+					t.Fatalf("Append() error = %v", err)
+				}
+			}
+
+			pruned, err := store.Prune(tt.cutoff, tt.maxRuns, tt.dryRun)
+			if err != nil {
+				t.Fatalf("Prune() error = %v", err)
+			}
+			runs, err := store.Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			var ids []string
+			for _, run := range runs {
+				ids = append(ids, run.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("runs after Prune() = %v, want %v", ids, tt.want)
+			}
+			if !tt.dryRun && pruned != 4-len(tt.want) {
+				t.Fatalf("Prune() = %d, want %d", pruned, 4-len(tt.want))
+			}
+			if tt.dryRun && pruned != 3 {
+				t.Fatalf("Prune() dry run = %d, want 3", pruned)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package history
+
+import (
+	"sort"
+	"time"
+)
+
+// TaskStats aggregates every recorded execution of one task.
+type TaskStats struct {
+	Key      string
+	Runs     int
+	Executed int
+	Cached   int
+	Failed   int
+	// Average and Max only consider executions that were not cached
+	Average time.Duration
+	Max     time.Duration
+	// Trend compares the average duration of the newer half of executions with
+	// the older half: 0.2 means 20% slower, -0.1 means 10% faster. It is 0 when
+	// there are fewer than four executions.
+	Trend float64
+	// AverageCPU is the average CPU time of the executions it was measured
+	// for, and PeakRSS the largest resident set size of any execution
+	AverageCPU time.Duration
+	PeakRSS    int64
+}
+
+// CacheHitRate is the fraction of runs served from cache.
+func (s TaskStats) CacheHitRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Cached) / float64(s.Runs)
+}
+
+// FailureRate is the fraction of executions that failed.
+func (s TaskStats) FailureRate() float64 {
+	if s.Executed == 0 {
+		return 0
+	}
+	return float64(s.Failed) / float64(s.Executed)
+}
+
+// Summarize aggregates task records across runs, ordered by average duration
+// with the slowest task first.
+func Summarize(runs []Run) []TaskStats {
+	durations := make(map[string][]time.Duration)
+	cpuTimes := make(map[string][]time.Duration)
+	stats := make(map[string]*TaskStats)
+
+	for _, run := range runs {
+		for _, record := range run.Tasks {
+			key := record.Key()
+			s, ok := stats[key]
+			if !ok {
+				s = &TaskStats{Key: key}
+				stats[key] = s
+			}
+			s.Runs++
+
+			if record.Outcome == OutcomeCached {
+				s.Cached++
+				continue
+			}
+			s.Executed++
+			if record.Outcome == OutcomeFailed {
+				s.Failed++
+			}
+			if record.Duration > s.Max {
+				s.Max = record.Duration
+			}
+			durations[key] = append(durations[key], record.Duration)
+			if record.CPUTime > 0 {
+				cpuTimes[key] = append(cpuTimes[key], record.CPUTime)
+			}
+			s.PeakRSS = max(s.PeakRSS, record.PeakRSS)
+		}
+	}
+
+	result := make([]TaskStats, 0, len(stats))
+	for key, s := range stats {
+		samples := durations[key]
+		s.Average = average(samples)
+		s.AverageCPU = average(cpuTimes[key])
+		if len(samples) >= 4 {
+			half := len(samples) / 2
+			older := average(samples[:half])
+			newer := average(samples[len(samples)-half:])
+			if older > 0 {
+				s.Trend = float64(newer-older) / float64(older)
+			}
+		}
+		result = append(result, *s)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Average != result[j].Average {
+			return result[i].Average > result[j].Average
+		}
+		return result[i].Key < result[j].Key
+	})
+
+	return result
+}
+
+func average(samples []time.Duration) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range samples {
+		total += d
+	}
+	return total / time.Duration(len(samples))
+}
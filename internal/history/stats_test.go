@@ -0,0 +1,67 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	record := func(task string, outcome Outcome, d time.Duration) TaskRecord {
+		return TaskRecord{Workspace: "app", Task: task, Outcome: outcome, Duration: d}
+	}
+
+	runs := []Run{
+		{Tasks: []TaskRecord{record("build", OutcomeSuccess, 2*time.Second), record("lint", OutcomeSuccess, time.Second)}},
+		{Tasks: []TaskRecord{record("build", OutcomeSuccess, 2*time.Second), record("lint", OutcomeCached, 0)}},
+		{Tasks: []TaskRecord{record("build", OutcomeFailed, 4*time.Second), record("lint", OutcomeCached, 0)}},
+		{Tasks: []TaskRecord{record("build", OutcomeSuccess, 4*time.Second), record("lint", OutcomeCached, 0)}},
+	}
+
+	stats := Summarize(runs)
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 tasks, got %d", len(stats))
+	}
+
+	build := stats[0]
+	if build.Key != "app:build" {
+		t.Fatalf("expected slowest task first, got %s", build.Key)
+	}
+	if build.Runs != 4 || build.Executed != 4 || build.Failed != 1 {
+		t.Fatalf("unexpected counts: %+v", build)
+	}
+	if build.Average != 3*time.Second || build.Max != 4*time.Second {
+		t.Fatalf("unexpected durations: average %v, max %v", build.Average, build.Max)
+	}
+	if build.Trend != 1 {
+		t.Fatalf("Trend = %v, want 1 (twice as slow)", build.Trend)
+	}
+	if build.FailureRate() != 0.25 {
+		t.Fatalf("FailureRate() = %v, want 0.25", build.FailureRate())
+	}
+
+	lint := stats[1]
+	if lint.CacheHitRate() != 0.75 || lint.Average != time.Second || lint.Trend != 0 {
+		t.Fatalf("unexpected lint stats: %+v", lint)
+	}
+}
+
+func TestSummarizeResourceUsage(t *testing.T) {
+	runs := []Run{
+		{Tasks: []TaskRecord{{Workspace: "app", Task: "build", Outcome: OutcomeSuccess, CPUTime: 2 * time.Second, PeakRSS: 100}}},
+		{Tasks: []TaskRecord{{Workspace: "app", Task: "build", Outcome: OutcomeFailed, CPUTime: 4 * time.Second, PeakRSS: 300}}},
+		// Not measured, such as with the compose-exec executor
+		{Tasks: []TaskRecord{{Workspace: "app", Task: "build", Outcome: OutcomeSuccess}}},
+		{Tasks: []TaskRecord{{Workspace: "app", Task: "build", Outcome: OutcomeCached}}},
+	}
+
+	stats := Summarize(runs)
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 task, got %d", len(stats))
+	}
+	if stats[0].AverageCPU != 3*time.Second {
+		t.Errorf("AverageCPU = %v, want 3s", stats[0].AverageCPU)
+	}
+	if stats[0].PeakRSS != 300 {
+		t.Errorf("PeakRSS = %d, want 300", stats[0].PeakRSS)
+	}
+}
@@ -0,0 +1,222 @@
+// Package hooks writes git hooks that run doctrus tasks, as configured in
+// the hooks section of doctrus.yml.
+package hooks
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// marker identifies hooks written by doctrus; only those are overwritten or
+// removed without --force.
+const marker = "# Installed by doctrus hooks install."
+
+// Action is what Install or Uninstall did with a hook.
+type Action string
+
+const (
+	ActionInstalled Action = "installed"
+	ActionUpdated   Action = "updated"
+	ActionRemoved   Action = "removed"
+	ActionSkipped   Action = "skipped"
+)
+
+// Result reports the action taken for one hook. Reason explains skipped
+// hooks.
+type Result struct {
+	Hook   string
+	Path   string
+	Action Action
+	Reason string
+}
+
+// Repository locates the git repository containing dir, returning its top
+// level directory and its hooks directory (honouring core.hooksPath).
+func Repository(dir string) (topLevel, hooksDir string, err error) {
+	output, err := git(dir, "rev-parse", "--show-toplevel", "--git-path", "hooks")
+	if err != nil {
+		return "", "", err
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 2 {
+		return "", "", fmt.Errorf("unexpected output of git rev-parse: %q", output)
+	}
+	topLevel, hooksDir = strings.TrimSpace(lines[0]), strings.TrimSpace(lines[1])
+	if !filepath.IsAbs(hooksDir) {
+		hooksDir = filepath.Join(dir, hooksDir)
+	}
+	return topLevel, hooksDir, nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), message)
+		}
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return string(output), nil
+}
+
+// Script returns a hook that runs specs with doctrus run. configPath is the
+// config file relative to the repository's top level, where git runs hooks.
+// $DOCTRUS overrides the doctrus binary.
+func Script(configPath string, specs []string) string {
+	args := []string{`"${DOCTRUS:-doctrus}"`, "--config", quote(filepath.ToSlash(configPath)), "run"}
+	for _, spec := range specs {
+		args = append(args, quote(spec))
+	}
+
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString(marker + "\n")
+	b.WriteString("# Edit the hooks section of doctrus.yml and reinstall instead of this file.\n")
+	b.WriteString("exec " + strings.Join(args, " ") + "\n")
+	return b.String()
+}
+
+// quote single-quotes value for sh when it holds anything but safe
+// characters.
+func quote(value string) string {
+	if value != "" && strings.Trim(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.,:/@+=") == "" {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// Install writes a hook into hooksDir for every entry of hooks and removes
+// doctrus hooks that are no longer configured. Existing hooks that doctrus
+// did not write are skipped unless force is set.
+func Install(hooksDir, configPath string, hooks map[string][]string, force bool) ([]Result, error) {
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+
+	var results []Result
+	for _, name := range sortedHooks(hooks) {
+		path := filepath.Join(hooksDir, name)
+		result := Result{Hook: name, Path: path, Action: ActionInstalled}
+
+		managed, exists, err := inspect(path)
+		if err != nil {
+			return results, err
+		}
+		if exists {
+			result.Action = ActionUpdated
+			if !managed && !force {
+				result.Action = ActionSkipped
+				result.Reason = "existing hook was not installed by doctrus (use --force to replace it)"
+				results = append(results, result)
+				continue
+			}
+		}
+
+		if err := os.WriteFile(path, []byte(Script(configPath, hooks[name])), 0o755); err != nil {
+			return results, fmt.Errorf("failed to write %s hook: %w", name, err)
+		}
+		// WriteFile keeps the mode of an existing file
+		if err := os.Chmod(path, 0o755); err != nil {
+			return results, fmt.Errorf("failed to make %s hook executable: %w", name, err)
+		}
+		results = append(results, result)
+	}
+
+	stale, err := managedHooks(hooksDir)
+	if err != nil {
+		return results, err
+	}
+	for _, name := range stale {
+		if _, configured := hooks[name]; configured {
+			continue
+		}
+		removed, err := remove(hooksDir, name)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, removed)
+	}
+
+	return results, nil
+}
+
+// Uninstall removes every hook in hooksDir that doctrus wrote.
+func Uninstall(hooksDir string) ([]Result, error) {
+	names, err := managedHooks(hooksDir)
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	for _, name := range names {
+		result, err := remove(hooksDir, name)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func remove(hooksDir, name string) (Result, error) {
+	path := filepath.Join(hooksDir, name)
+	if err := os.Remove(path); err != nil {
+		return Result{}, fmt.Errorf("failed to remove %s hook: %w", name, err)
+	}
+	return Result{Hook: name, Path: path, Action: ActionRemoved}, nil
+}
+
+// managedHooks lists the hooks in hooksDir written by doctrus.
+func managedHooks(hooksDir string) ([]string, error) {
+	entries, err := os.ReadDir(hooksDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hooks directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		managed, _, err := inspect(filepath.Join(hooksDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if managed {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// inspect reports whether the hook at path exists and was written by doctrus.
+func inspect(path string) (managed, exists bool, err error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to read hook %s: %w", path, err)
+	}
+	return bytes.Contains(data, []byte(marker)), true, nil
+}
+
+func sortedHooks(hooks map[string][]string) []string {
+	names := make([]string, 0, len(hooks))
+	for name := range hooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
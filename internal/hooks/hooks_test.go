@@ -0,0 +1,133 @@
+package hooks
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestScriptQuotesSpecs(t *testing.T) {
+	script := Script("configs/doctrus.yml", []string{"*:lint", "app:test", "it's"})
+
+	for _, want := range []string{
+		"#!/bin/sh\n",
+		marker,
+		`exec "${DOCTRUS:-doctrus}" --config configs/doctrus.yml run '*:lint' app:test 'it'\''s'` + "\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Fatalf("Script() = %q, want it to contain %q", script, want)
+		}
+	}
+}
+
+func TestInstall(t *testing.T) {
+	hooksDir := filepath.Join(t.TempDir(), "hooks")
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		t.Fatalf("failed to create hooks dir: %v", err)
+	}
+	custom := "#!/bin/sh\necho custom\n"
+	if err := os.WriteFile(filepath.Join(hooksDir, "pre-push"), []byte(custom), 0o755); err != nil {
+		t.Fatalf("failed to write hook: %v", err)
+	}
+
+	results, err := Install(hooksDir, "doctrus.yml", map[string][]string{
+		"pre-commit":    {"lint"},
+		"pre-push":      {"test"},
+		"post-checkout": {"install"},
+	}, false)
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	assertActions(t, results, map[string]Action{
+		"post-checkout": ActionInstalled,
+		"pre-commit":    ActionInstalled,
+		"pre-push":      ActionSkipped,
+	})
+	if data, _ := os.ReadFile(filepath.Join(hooksDir, "pre-push")); string(data) != custom {
+		t.Fatalf("unmanaged pre-push hook was overwritten: %q", data)
+	}
+	info, err := os.Stat(filepath.Join(hooksDir, "pre-commit"))
+	if err != nil {
+		t.Fatalf("pre-commit hook missing: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o111 == 0 {
+		t.Fatalf("pre-commit hook mode = %v, want executable", info.Mode())
+	}
+
+	// Reinstalling replaces doctrus hooks, removes unconfigured ones and,
+	// with force, replaces the custom hook
+	results, err = Install(hooksDir, "doctrus.yml", map[string][]string{
+		"pre-commit": {"*:lint"},
+		"pre-push":   {"test"},
+	}, true)
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	assertActions(t, results, map[string]Action{
+		"pre-commit":    ActionUpdated,
+		"pre-push":      ActionUpdated,
+		"post-checkout": ActionRemoved,
+	})
+	if data, _ := os.ReadFile(filepath.Join(hooksDir, "pre-commit")); !strings.Contains(string(data), "run '*:lint'") {
+		t.Fatalf("pre-commit hook not updated: %q", data)
+	}
+
+	results, err = Uninstall(hooksDir)
+	if err != nil {
+		t.Fatalf("Uninstall() error = %v", err)
+	}
+	assertActions(t, results, map[string]Action{
+		"pre-commit": ActionRemoved,
+		"pre-push":   ActionRemoved,
+	})
+}
+
+func assertActions(t *testing.T, results []Result, want map[string]Action) {
+	t.Helper()
+	got := make(map[string]Action, len(results))
+	for _, result := range results {
+		got[result.Hook] = result.Action
+	}
+	if len(got) != len(want) {
+		t.Fatalf("results = %v, want %v", got, want)
+	}
+	for hook, action := range want {
+		if got[hook] != action {
+			t.Fatalf("%s: action = %q, want %q (results %v)", hook, got[hook], action, got)
+		}
+	}
+}
+
+func TestRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	if output, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v: %s", err, output)
+	}
+	sub := filepath.Join(repo, "services")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+
+	topLevel, hooksDir, err := Repository(sub)
+	if err != nil {
+		t.Fatalf("Repository() error = %v", err)
+	}
+	resolved, _ := filepath.EvalSymlinks(repo)
+	if got, _ := filepath.EvalSymlinks(topLevel); got != resolved {
+		t.Fatalf("topLevel = %q, want %q", topLevel, repo)
+	}
+	if got, _ := filepath.EvalSymlinks(filepath.Dir(hooksDir)); got != filepath.Join(resolved, ".git") || filepath.Base(hooksDir) != "hooks" {
+		t.Fatalf("hooksDir = %q, want %s", hooksDir, filepath.Join(repo, ".git", "hooks"))
+	}
+
+	if _, _, err := Repository(t.TempDir()); err == nil {
+		t.Fatal("Repository() outside a repository should fail")
+	}
+}
@@ -0,0 +1,340 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"doctrus/internal/config"
+)
+
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// Issue is a single rule violation found in the configuration.
+type Issue struct {
+	Rule      string
+	Severity  Severity
+	Workspace string
+	Task      string
+	Message   string
+	Fixable   bool
+}
+
+// Location renders the workspace/task the issue belongs to.
+func (i Issue) Location() string {
+	switch {
+	case i.Workspace != "" && i.Task != "":
+		return i.Workspace + ":" + i.Task
+	case i.Workspace != "":
+		return i.Workspace
+	default:
+		return "config"
+	}
+}
+
+// Rule checks one best practice. Fix is optional and only provided when the
+// change cannot alter how tasks behave.
+type Rule struct {
+	ID          string
+	Severity    Severity
+	Description string
+	Check       func(cfg *config.Config, basePath string) []Issue
+	Fix         func(root *yaml.Node, issue Issue, basePath string) bool
+}
+
+// Rules returns the built-in lint rules.
+func Rules() []Rule {
+	return []Rule{
+		{
+			ID:          "unknown-dependency",
+			Severity:    SeverityError,
+			Description: "depends_on references a task that does not exist",
+			Check:       checkUnknownDependency,
+		},
+		{
+			ID:          "cache-without-inputs",
+			Severity:    SeverityWarning,
+			Description: "cache enabled but no inputs declared",
+			Check:       checkCacheWithoutInputs,
+		},
+		{
+			ID:          "outputs-without-cache",
+			Severity:    SeverityWarning,
+			Description: "outputs declared but cache disabled",
+			Check:       checkOutputsWithoutCache,
+		},
+		{
+			ID:          "container-without-compose-file",
+			Severity:    SeverityWarning,
+			Description: "container set but no compose file found",
+			Check:       checkContainerWithoutComposeFile,
+			Fix:         fixComposeFile,
+		},
+		{
+			ID:          "dependency-without-description",
+			Severity:    SeverityInfo,
+			Description: "dependency on a task with no description",
+			Check:       checkDependencyWithoutDescription,
+		},
+	}
+}
+
+// Run applies every rule and returns the issues sorted by location and rule.
+func Run(cfg *config.Config, basePath string) []Issue {
+	var issues []Issue
+	for _, rule := range Rules() {
+		for _, issue := range rule.Check(cfg, basePath) {
+			issue.Rule = rule.ID
+			issue.Severity = rule.Severity
+			issue.Fixable = rule.Fix != nil
+			issues = append(issues, issue)
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Location() != issues[j].Location() {
+			return issues[i].Location() < issues[j].Location()
+		}
+		return issues[i].Rule < issues[j].Rule
+	})
+
+	return issues
+}
+
+// Fix applies the safe fixes for issues to the config document and returns the
+// rewritten document along with the number of fixes applied.
+func Fix(data []byte, issues []Issue, basePath string) ([]byte, int, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return data, 0, nil
+	}
+
+	fixers := make(map[string]Rule)
+	for _, rule := range Rules() {
+		if rule.Fix != nil {
+			fixers[rule.ID] = rule
+		}
+	}
+
+	fixed := 0
+	for _, issue := range issues {
+		rule, ok := fixers[issue.Rule]
+		if !ok {
+			continue
+		}
+		if rule.Fix(doc.Content[0], issue, basePath) {
+			fixed++
+		}
+	}
+
+	if fixed == 0 {
+		return data, 0, nil
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to write fixes: %w", err)
+	}
+
+	formatted, err := config.Format(out)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return formatted, fixed, nil
+}
+
+func eachTask(cfg *config.Config, fn func(workspaceName, taskName string, workspace config.Workspace, task config.Task)) {
+	workspaceNames := make([]string, 0, len(cfg.Workspaces))
+	for name := range cfg.Workspaces {
+		workspaceNames = append(workspaceNames, name)
+	}
+	sort.Strings(workspaceNames)
+
+	for _, workspaceName := range workspaceNames {
+		workspace := cfg.Workspaces[workspaceName]
+		taskNames := make([]string, 0, len(workspace.Tasks))
+		for name := range workspace.Tasks {
+			taskNames = append(taskNames, name)
+		}
+		sort.Strings(taskNames)
+
+		for _, taskName := range taskNames {
+			fn(workspaceName, taskName, workspace, workspace.Tasks[taskName])
+		}
+	}
+}
+
+func checkUnknownDependency(cfg *config.Config, basePath string) []Issue {
+	var issues []Issue
+	eachTask(cfg, func(workspaceName, taskName string, _ config.Workspace, task config.Task) {
+		for _, dep := range task.DependsOn {
+			depWorkspace, depTask, _ := config.SplitDependency(workspaceName, dep)
+			if _, exists := cfg.GetTask(depWorkspace, depTask); !exists {
+				issues = append(issues, Issue{
+					Workspace: workspaceName,
+					Task:      taskName,
+					Message:   fmt.Sprintf("dependency %s does not exist", dep),
+				})
+			}
+		}
+	})
+	return issues
+}
+
+func checkCacheWithoutInputs(cfg *config.Config, basePath string) []Issue {
+	var issues []Issue
+	eachTask(cfg, func(workspaceName, taskName string, _ config.Workspace, task config.Task) {
+		if task.Cache && len(task.Inputs) == 0 && len(task.Command) > 0 {
+			issues = append(issues, Issue{
+				Workspace: workspaceName,
+				Task:      taskName,
+				Message:   "cache is enabled but no inputs are declared, so the task never re-runs after its first success",
+			})
+		}
+	})
+	return issues
+}
+
+func checkOutputsWithoutCache(cfg *config.Config, basePath string) []Issue {
+	var issues []Issue
+	eachTask(cfg, func(workspaceName, taskName string, _ config.Workspace, task config.Task) {
+		if !task.Cache && len(task.Outputs) > 0 {
+			issues = append(issues, Issue{
+				Workspace: workspaceName,
+				Task:      taskName,
+				Message:   "outputs are declared but cache is disabled, so they are never checked",
+			})
+		}
+	})
+	return issues
+}
+
+func checkDependencyWithoutDescription(cfg *config.Config, basePath string) []Issue {
+	var issues []Issue
+	seen := make(map[string]bool)
+	eachTask(cfg, func(workspaceName, taskName string, _ config.Workspace, task config.Task) {
+		for _, dep := range task.DependsOn {
+			depWorkspace, depTask, _ := config.SplitDependency(workspaceName, dep)
+			depDef, exists := cfg.GetTask(depWorkspace, depTask)
+			// Tasks depended on by an alias are reported by their names
+			depTask = cfg.TaskName(depWorkspace, depTask)
+			key := depWorkspace + ":" + depTask
+			if !exists || depDef.Description != "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			issues = append(issues, Issue{
+				Workspace: depWorkspace,
+				Task:      depTask,
+				Message:   fmt.Sprintf("task is used as a dependency (by %s:%s) but has no description", workspaceName, taskName),
+			})
+		}
+	})
+	return issues
+}
+
+// composeCandidates are the file names docker compose looks for by default
+var composeCandidates = []string{"docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml"}
+
+func checkContainerWithoutComposeFile(cfg *config.Config, basePath string) []Issue {
+	if cfg.Docker.ComposeFile != "" {
+		return nil
+	}
+	// The executor falls back to docker-compose.yml, so that file is fine implicitly
+	if fileExists(filepath.Join(basePath, "docker-compose.yml")) {
+		return nil
+	}
+
+	var issues []Issue
+	reported := make(map[string]bool)
+	eachTask(cfg, func(workspaceName, taskName string, _ config.Workspace, task config.Task) {
+		container := cfg.GetEffectiveContainer(workspaceName, taskName)
+		if container == "" || reported[workspaceName] {
+			return
+		}
+		if task.Docker != nil && task.Docker.ComposeFile != "" {
+			return
+		}
+		reported[workspaceName] = true
+
+		message := fmt.Sprintf("container %s is used but docker.compose_file is not set and docker-compose.yml does not exist", container)
+		if found := detectComposeFile(basePath); found != "" {
+			message += fmt.Sprintf(" (found %s)", found)
+		}
+		issues = append(issues, Issue{Workspace: workspaceName, Message: message})
+	})
+	return issues
+}
+
+func fixComposeFile(root *yaml.Node, issue Issue, basePath string) bool {
+	found := detectComposeFile(basePath)
+	if found == "" {
+		return false
+	}
+
+	docker := lookup(root, "docker")
+	if docker == nil {
+		docker = &yaml.Node{Kind: yaml.MappingNode}
+		root.Content = append(root.Content, scalar("docker"), docker)
+	}
+	if docker.Kind != yaml.MappingNode {
+		return false
+	}
+	if existing := lookup(docker, "compose_file"); existing != nil {
+		return false
+	}
+
+	docker.Content = append(docker.Content, scalar("compose_file"), scalar(found))
+	return true
+}
+
+func detectComposeFile(basePath string) string {
+	for _, name := range composeCandidates {
+		if fileExists(filepath.Join(basePath, name)) {
+			return name
+		}
+	}
+	return ""
+}
+
+func lookup(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,144 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"doctrus/internal/config"
+)
+
+func TestRun(t *testing.T) {
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"web": {
+				Tasks: map[string]config.Task{
+					"install": {Command: []string{"npm", "install"}, Cache: true},
+					"build": {
+						Command:     []string{"npm", "run", "build"},
+						Description: "Build",
+						DependsOn:   []string{"install", "api:missing"},
+						Inputs:      []string{"src/**/*"},
+						Outputs:     []string{"dist/**/*"},
+					},
+				},
+			},
+		},
+	}
+
+	issues := Run(cfg, t.TempDir())
+
+	got := make(map[string]Issue)
+	for _, issue := range issues {
+		got[issue.Location()+" "+issue.Rule] = issue
+	}
+
+	tests := []struct {
+		key      string
+		severity Severity
+	}{
+		{"web:build unknown-dependency", SeverityError},
+		{"web:build outputs-without-cache", SeverityWarning},
+		{"web:install cache-without-inputs", SeverityWarning},
+		{"web:install dependency-without-description", SeverityInfo},
+	}
+
+	for _, tt := range tests {
+		issue, ok := got[tt.key]
+		if !ok {
+			t.Errorf("expected issue %q, got %+v", tt.key, issues)
+			continue
+		}
+		if issue.Severity != tt.severity {
+			t.Errorf("issue %q severity = %v, want %v", tt.key, issue.Severity, tt.severity)
+		}
+	}
+
+	if len(issues) != len(tests) {
+		t.Errorf("Run() returned %d issues, want %d: %+v", len(issues), len(tests), issues)
+	}
+}
+
+func TestDependencyWithoutDescriptionResolvesAliases(t *testing.T) {
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"a": {
+				Tasks: map[string]config.Task{
+					"gen":   {Command: []string{"gen"}, Aliases: []string{"g"}},
+					"build": {Command: []string{"build"}, Description: "Build", DependsOn: []string{"g"}},
+					"test":  {Command: []string{"test"}, Description: "Test", DependsOn: []string{"gen", "a:g"}},
+				},
+			},
+		},
+	}
+
+	var found []string
+	for _, issue := range Run(cfg, t.TempDir()) {
+		if issue.Rule == "dependency-without-description" {
+			found = append(found, issue.Location())
+		}
+	}
+	if strings.Join(found, ",") != "a:gen" {
+		t.Errorf("dependency-without-description issues = %v, want [a:gen]", found)
+	}
+}
+
+func TestContainerWithoutComposeFileFix(t *testing.T) {
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, "compose.yaml"), []byte("services: {}\n"), 0o644); err != nil {
+		t.Fatalf("failed to write compose file: %v", err)
+	}
+
+	data := []byte(`version: "1.0"
+workspaces:
+  api:
+    container: php
+    tasks:
+      test:
+        command: ["phpunit"]
+        description: "Run tests"
+  web:
+    container: node
+    tasks:
+      test:
+        command: ["npm", "test"]
+        description: "Run tests"
+`)
+
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"api": {Container: "php", Tasks: map[string]config.Task{"test": {Command: []string{"phpunit"}, Description: "Run tests"}}},
+			"web": {Container: "node", Tasks: map[string]config.Task{"test": {Command: []string{"npm", "test"}, Description: "Run tests"}}},
+		},
+	}
+
+	issues := Run(cfg, baseDir)
+	if len(issues) != 2 {
+		t.Fatalf("Run() returned %d issues, want 2: %+v", len(issues), issues)
+	}
+	for _, issue := range issues {
+		if issue.Rule != "container-without-compose-file" || !issue.Fixable {
+			t.Fatalf("unexpected issue %+v", issue)
+		}
+	}
+
+	fixed, count, err := Fix(data, issues, baseDir)
+	if err != nil {
+		t.Fatalf("Fix() error = %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("Fix() applied %d fixes, want 1", count)
+	}
+	if !strings.Contains(string(fixed), "docker:\n  compose_file: compose.yaml\n") {
+		t.Fatalf("Fix() did not set compose_file:\n%s", fixed)
+	}
+
+	cfg.Docker.ComposeFile = "compose.yaml"
+	if remaining := Run(cfg, baseDir); len(remaining) != 0 {
+		t.Fatalf("expected no issues after fix, got %+v", remaining)
+	}
+}
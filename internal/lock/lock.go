@@ -0,0 +1,217 @@
+// Package lock keeps a project-wide registry of the doctrus invocations that
+// are running tasks, so runs touching the same workspaces can wait for each
+// other or fail fast instead of writing the same outputs and cache entries
+// concurrently.
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Mode is how an invocation reacts to another one using its workspaces.
+type Mode string
+
+const (
+	// ModeOff runs without taking the lock
+	ModeOff Mode = "off"
+	// ModeWait queues behind conflicting runs
+	ModeWait Mode = "wait"
+	// ModeFail stops with a *ConflictError
+	ModeFail Mode = "fail"
+)
+
+// ParseMode parses a lock mode; an empty string means ModeOff.
+func ParseMode(value string) (Mode, error) {
+	switch Mode(value) {
+	case "", ModeOff:
+		return ModeOff, nil
+	case ModeWait, ModeFail:
+		return Mode(value), nil
+	default:
+		return "", fmt.Errorf("invalid lock mode %q (expected wait, fail or off)", value)
+	}
+}
+
+// ErrConflict matches a *ConflictError with errors.Is.
+var ErrConflict = errors.New("workspaces in use by another run")
+
+// Holder is a doctrus invocation registered in the lock file.
+type Holder struct {
+	PID        int       `json:"pid"`
+	Command    string    `json:"command,omitempty"`
+	Workspaces []string  `json:"workspaces"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+func (h Holder) same(other Holder) bool {
+	return h.PID == other.PID && h.StartedAt.Equal(other.StartedAt)
+}
+
+// ConflictError reports workspaces held by another running invocation.
+type ConflictError struct {
+	Holder     Holder
+	Workspaces []string
+}
+
+func (e *ConflictError) Error() string {
+	by := fmt.Sprintf("pid %d", e.Holder.PID)
+	if e.Holder.Command != "" {
+		by = fmt.Sprintf("%q, pid %d", e.Holder.Command, e.Holder.PID)
+	}
+	return fmt.Sprintf("workspace(s) %s in use by another doctrus run (%s, started %s)",
+		strings.Join(e.Workspaces, ", "), by, e.Holder.StartedAt.Local().Format("15:04:05"))
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// File is the lock file of a project, usually .doctrus/run.lock.
+type File struct {
+	path         string
+	pollInterval time.Duration
+}
+
+func New(path string) *File {
+	return &File{path: path, pollInterval: 250 * time.Millisecond}
+}
+
+// Lease is a registration returned by Acquire.
+type Lease struct {
+	file   *File
+	holder Holder
+}
+
+// Acquire registers holder for its workspaces. When a live invocation holds
+// any of them, ModeFail returns a *ConflictError and ModeWait retries until
+// they are free or ctx is done, calling onWait (if set) before the first
+// wait. ModeOff returns a nil Lease, which is safe to Release.
+func (f *File) Acquire(ctx context.Context, holder Holder, mode Mode, onWait func(*ConflictError)) (*Lease, error) {
+	if mode == ModeOff || mode == "" {
+		return nil, nil
+	}
+
+	holder.Workspaces = append([]string(nil), holder.Workspaces...)
+	sort.Strings(holder.Workspaces)
+
+	waited := false
+	for {
+		var conflict *ConflictError
+		err := f.update(func(holders []Holder) []Holder {
+			for _, other := range holders {
+				if shared := intersect(holder.Workspaces, other.Workspaces); len(shared) > 0 {
+					conflict = &ConflictError{Holder: other, Workspaces: shared}
+					return holders
+				}
+			}
+			return append(holders, holder)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if conflict == nil {
+			return &Lease{file: f, holder: holder}, nil
+		}
+		if mode == ModeFail {
+			return nil, conflict
+		}
+
+		if !waited && onWait != nil {
+			onWait(conflict)
+		}
+		waited = true
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for %w", conflict)
+		case <-time.After(f.pollInterval):
+		}
+	}
+}
+
+// Release removes the lease's registration.
+func (l *Lease) Release() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.update(func(holders []Holder) []Holder {
+		kept := holders[:0]
+		for _, other := range holders {
+			if !other.same(l.holder) {
+				kept = append(kept, other)
+			}
+		}
+		return kept
+	})
+}
+
+// update rewrites the registry under an exclusive file lock. fn receives the
+// holders whose processes are still running.
+func (f *File) update(fn func([]Holder) []Holder) error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	file, err := os.OpenFile(f.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file: %w", err)
+	}
+	defer file.Close()
+
+	if err := lockFile(file); err != nil {
+		return fmt.Errorf("failed to lock %s: %w", f.path, err)
+	}
+	defer unlockFile(file)
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to read lock file: %w", err)
+	}
+	var holders []Holder
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := json.Unmarshal(data, &holders); err != nil {
+			// A corrupt registry only loses stale entries
+			holders = nil
+		}
+	}
+
+	live := holders[:0]
+	for _, holder := range holders {
+		if processAlive(holder.PID) {
+			live = append(live, holder)
+		}
+	}
+
+	data, err = json.MarshalIndent(fn(live), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if _, err := file.WriteAt(data, 0); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// intersect returns the names in both sorted a and b.
+func intersect(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, name := range b {
+		in[name] = true
+	}
+	var shared []string
+	for _, name := range a {
+		if in[name] {
+			shared = append(shared, name)
+		}
+	}
+	return shared
+}
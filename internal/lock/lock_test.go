@@ -0,0 +1,140 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestFile(t *testing.T) *File {
+	t.Helper()
+	f := New(filepath.Join(t.TempDir(), ".doctrus", "run.lock"))
+	f.pollInterval = 10 * time.Millisecond
+	return f
+}
+
+func holder(offset time.Duration, workspaces ...string) Holder {
+	return Holder{
+		PID:        os.Getpid(),
+		Command:    "doctrus test",
+		Workspaces: workspaces,
+		StartedAt:  time.Unix(1700000000, 0).Add(offset),
+	}
+}
+
+func TestAcquireConflicts(t *testing.T) {
+	f := newTestFile(t)
+	ctx := context.Background()
+
+	first, err := f.Acquire(ctx, holder(0, "app", "lib"), ModeFail, nil)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	disjoint, err := f.Acquire(ctx, holder(time.Second, "web"), ModeFail, nil)
+	if err != nil {
+		t.Fatalf("Acquire() for other workspaces error = %v", err)
+	}
+	defer disjoint.Release()
+
+	_, err = f.Acquire(ctx, holder(2*time.Second, "lib", "web2"), ModeFail, nil)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Acquire() error = %v, want ErrConflict", err)
+	}
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !reflect.DeepEqual(conflict.Workspaces, []string{"lib"}) {
+		t.Fatalf("Acquire() error = %#v, want conflict on lib", err)
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	again, err := f.Acquire(ctx, holder(3*time.Second, "lib"), ModeFail, nil)
+	if err != nil {
+		t.Fatalf("Acquire() after release error = %v", err)
+	}
+	again.Release()
+}
+
+func TestAcquireWaitsForRelease(t *testing.T) {
+	f := newTestFile(t)
+	ctx := context.Background()
+
+	first, err := f.Acquire(ctx, holder(0, "app"), ModeWait, nil)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	waiting := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		lease, err := f.Acquire(ctx, holder(time.Second, "app"), ModeWait, func(*ConflictError) { close(waiting) })
+		lease.Release()
+		done <- err
+	}()
+
+	select {
+	case <-waiting:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second run did not report waiting")
+	}
+	if err := first.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("waiting Acquire() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second run did not acquire the lock after release")
+	}
+
+	// A cancelled wait reports the conflict
+	blocker, err := f.Acquire(ctx, holder(2*time.Second, "app"), ModeWait, nil)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer blocker.Release()
+	cancelled, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := f.Acquire(cancelled, holder(3*time.Second, "app"), ModeWait, nil); !errors.Is(err, ErrConflict) {
+		t.Fatalf("cancelled Acquire() error = %v, want ErrConflict", err)
+	}
+}
+
+func TestAcquireIgnoresDeadHolders(t *testing.T) {
+	cmd := exec.Command("go", "version")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("cannot start a child process: %v", err)
+	}
+
+	f := newTestFile(t)
+	dead := holder(0, "app")
+	dead.PID = cmd.Process.Pid
+	if err := f.update(func(holders []Holder) []Holder { return append(holders, dead) }); err != nil {
+		t.Fatalf("update() error = %v", err)
+	}
+
+	lease, err := f.Acquire(context.Background(), holder(time.Second, "app"), ModeFail, nil)
+	if err != nil {
+		t.Fatalf("Acquire() with a dead holder error = %v", err)
+	}
+	lease.Release()
+}
+
+func TestParseMode(t *testing.T) {
+	for value, want := range map[string]Mode{"": ModeOff, "off": ModeOff, "wait": ModeWait, "fail": ModeFail} {
+		if got, err := ParseMode(value); err != nil || got != want {
+			t.Fatalf("ParseMode(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseMode("block"); err == nil {
+		t.Fatal("ParseMode(\"block\") should fail")
+	}
+}
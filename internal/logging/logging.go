@@ -0,0 +1,170 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"doctrus/internal/ui"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+// ParseLevel converts a --log-level value into a Level.
+func ParseLevel(value string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("invalid log level %q (expected debug, info, warn or error)", value)
+	}
+}
+
+// Logger writes doctrus's own diagnostics, as opposed to the output of the
+// tasks it runs. Messages below the configured level are dropped. When a log
+// file is attached, every emitted message is also appended to it with a
+// timestamp and level, stripped of ANSI sequences.
+type Logger struct {
+	mu    *sync.Mutex
+	level Level
+	// outLevel is the minimum level written to out, which may hide messages
+	// that still go to the log file
+	outLevel Level
+	out      io.Writer
+	file     io.WriteCloser
+}
+
+var defaultLogger = New(os.Stdout, LevelInfo, nil)
+
+// New creates a logger writing to out. mu serializes writes with other
+// producers sharing out (such as task output writers); when nil the logger
+// uses its own mutex.
+func New(out io.Writer, level Level, mu *sync.Mutex) *Logger {
+	if mu == nil {
+		mu = &sync.Mutex{}
+	}
+	return &Logger{mu: mu, level: level, outLevel: level, out: out}
+}
+
+// SetOutputLevel hides messages below level from the logger's output while
+// still appending them to the log file.
+func (l *Logger) SetOutputLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.outLevel = level
+}
+
+// OpenFile attaches a log file, appending to it if it already exists.
+func (l *Logger) OpenFile(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	l.mu.Lock()
+	l.file = file
+	l.mu.Unlock()
+	return nil
+}
+
+// Close closes the attached log file, if any.
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Level returns the minimum level that is emitted.
+func (l *Logger) Level() Level {
+	return l.orDefault().level
+}
+
+// Enabled reports whether messages at level are emitted.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.orDefault().level
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.orDefault().logf(LevelDebug, format, args...)
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.orDefault().logf(LevelInfo, format, args...)
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.orDefault().logf(LevelWarn, format, args...)
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.orDefault().logf(LevelError, format, args...)
+}
+
+func (l *Logger) orDefault() *Logger {
+	if l == nil {
+		return defaultLogger
+	}
+	return l
+}
+
+func (l *Logger) logf(level Level, format string, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+
+	message := fmt.Sprintf(format, args...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if level >= l.outLevel {
+		fmt.Fprint(l.out, message)
+	}
+
+	if l.file != nil {
+		plain := strings.TrimSpace(ui.StripEscapes(message))
+		if plain == "" {
+			return
+		}
+		timestamp := time.Now().Format(time.RFC3339)
+		for _, line := range strings.Split(plain, "\n") {
+			fmt.Fprintf(l.file, "%s %-5s %s\n", timestamp, strings.ToUpper(level.String()), line)
+		}
+	}
+}
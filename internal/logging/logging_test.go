@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Level
+		wantErr bool
+	}{
+		{value: "debug", want: LevelDebug},
+		{value: "INFO", want: LevelInfo},
+		{value: "", want: LevelInfo},
+		{value: "warning", want: LevelWarn},
+		{value: "error", want: LevelError},
+		{value: "trace", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseLevel(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Fatalf("ParseLevel(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggerFiltersByLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, LevelWarn, nil)
+
+	logger.Debugf("debug\n")
+	logger.Infof("info\n")
+	logger.Warnf("warn\n")
+	logger.Errorf("error\n")
+
+	if got, want := buf.String(), "warn\nerror\n"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestLoggerFileStripsColors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, LevelInfo, nil)
+
+	path := filepath.Join(t.TempDir(), "doctrus.log")
+	if err := logger.OpenFile(path); err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+
+	logger.Debugf("hidden\n")
+	logger.Infof("\033[32m✓ done\033[0m\n")
+	logger.Errorf("first\nsecond\n")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "\033[32m") {
+		t.Fatalf("terminal output should keep colors, got %q", buf.String())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 log lines, got %d: %q", len(lines), data)
+	}
+
+	want := []string{"INFO  ✓ done", "ERROR first", "ERROR second"}
+	for i, line := range lines {
+		if strings.Contains(line, "\033[") {
+			t.Fatalf("log line contains ANSI sequence: %q", line)
+		}
+		if !strings.HasSuffix(line, want[i]) {
+			t.Fatalf("log line %d = %q, want suffix %q", i, line, want[i])
+		}
+	}
+}
+
+func TestLoggerOutputLevelKeepsFile(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, LevelInfo, nil)
+	logger.SetOutputLevel(LevelWarn)
+
+	path := filepath.Join(t.TempDir(), "doctrus.log")
+	if err := logger.OpenFile(path); err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	logger.Infof("info\n")
+	logger.Warnf("warn\n")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if got, want := buf.String(), "warn\n"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(data), "INFO  info") || !strings.Contains(string(data), "WARN  warn") {
+		t.Fatalf("log file should hold every message, got %q", data)
+	}
+}
+
+func TestNilLoggerIsUsable(t *testing.T) {
+	var logger *Logger
+	if !logger.Enabled(LevelInfo) || logger.Enabled(LevelDebug) {
+		t.Fatalf("nil logger should default to info level")
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"doctrus/internal/config"
+)
+
+const (
+	// ProtocolVersion is bumped whenever the stdin payload changes incompatibly
+	ProtocolVersion = 1
+	// Prefix is the executable name prefix for plugins discovered on PATH
+	Prefix = "doctrus-"
+)
+
+type Plugin struct {
+	Name        string
+	Path        string
+	Args        []string
+	Description string
+	Source      string
+}
+
+// Payload is the JSON document written to a plugin's stdin.
+type Payload struct {
+	Protocol int                 `json:"protocol"`
+	Root     string              `json:"root,omitempty"`
+	Args     []string            `json:"args"`
+	Config   *config.Config      `json:"config,omitempty"`
+	Graph    map[string][]string `json:"graph,omitempty"`
+}
+
+// Discover returns all plugins declared in the config or found on PATH,
+// sorted by name. Config declarations take precedence over PATH executables.
+func Discover(cfg *config.Config, basePath string) []Plugin {
+	found := make(map[string]Plugin)
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := pluginName(entry.Name())
+			if !ok {
+				continue
+			}
+			if _, exists := found[name]; exists {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if !isExecutable(path) {
+				continue
+			}
+			found[name] = Plugin{Name: name, Path: path, Source: "path"}
+		}
+	}
+
+	if cfg != nil {
+		for name, declared := range cfg.Plugins {
+			if len(declared.Command) == 0 {
+				continue
+			}
+			found[name] = Plugin{
+				Name:        name,
+				Path:        resolveCommand(declared.Command[0], basePath),
+				Args:        declared.Command[1:],
+				Description: declared.Description,
+				Source:      "config",
+			}
+		}
+	}
+
+	plugins := make([]Plugin, 0, len(found))
+	for _, p := range found {
+		plugins = append(plugins, p)
+	}
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Name < plugins[j].Name
+	})
+	return plugins
+}
+
+// Find looks up a single plugin by name.
+func Find(name string, cfg *config.Config, basePath string) (*Plugin, bool) {
+	if name == "" || strings.ContainsAny(name, `:/\`) {
+		return nil, false
+	}
+
+	if cfg != nil {
+		if declared, exists := cfg.Plugins[name]; exists && len(declared.Command) > 0 {
+			return &Plugin{
+				Name:        name,
+				Path:        resolveCommand(declared.Command[0], basePath),
+				Args:        declared.Command[1:],
+				Description: declared.Description,
+				Source:      "config",
+			}, true
+		}
+	}
+
+	path, err := exec.LookPath(Prefix + name)
+	if err != nil {
+		return nil, false
+	}
+	return &Plugin{Name: name, Path: path, Source: "path"}, true
+}
+
+// NewPayload builds the stdin document for a plugin invocation.
+func NewPayload(cfg *config.Config, basePath string, args []string) *Payload {
+	payload := &Payload{
+		Protocol: ProtocolVersion,
+		Root:     basePath,
+		Args:     args,
+	}
+	if args == nil {
+		payload.Args = []string{}
+	}
+	if cfg != nil {
+		payload.Config = cfg
+		payload.Graph = BuildGraph(cfg)
+	}
+	return payload
+}
+
+// BuildGraph maps every task key to the fully qualified keys of its direct dependencies.
+func BuildGraph(cfg *config.Config) map[string][]string {
+	graph := make(map[string][]string)
+	for workspaceName, workspace := range cfg.Workspaces {
+		for taskName, task := range workspace.Tasks {
+			deps := make([]string, 0, len(task.DependsOn))
+			for _, dep := range task.DependsOn {
+				dep = strings.TrimSpace(dep)
+				if dep == "" {
+					continue
+				}
+				if !strings.Contains(dep, ":") {
+					dep = workspaceName + ":" + dep
+				}
+				deps = append(deps, dep)
+			}
+			graph[workspaceName+":"+taskName] = deps
+		}
+	}
+	return graph
+}
+
+// Run executes the plugin with the payload on stdin and the given output streams.
+func Run(ctx context.Context, p *Plugin, payload *Payload, stdout, stderr io.Writer) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode plugin payload: %w", err)
+	}
+
+	args := append(append([]string{}, p.Args...), payload.Args...)
+	cmd := exec.CommandContext(ctx, p.Path, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("DOCTRUS_PLUGIN_PROTOCOL=%d", ProtocolVersion),
+		fmt.Sprintf("DOCTRUS_PLUGIN_NAME=%s", p.Name),
+	)
+	if payload.Root != "" {
+		cmd.Dir = payload.Root
+		cmd.Env = append(cmd.Env, fmt.Sprintf("DOCTRUS_ROOT=%s", payload.Root))
+	}
+
+	return cmd.Run()
+}
+
+func pluginName(filename string) (string, bool) {
+	if !strings.HasPrefix(filename, Prefix) {
+		return "", false
+	}
+	name := strings.TrimPrefix(filename, Prefix)
+	if runtime.GOOS == "windows" {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if name == "" {
+		return "", false
+	}
+	return name, true
+}
+
+func resolveCommand(command, basePath string) string {
+	if filepath.IsAbs(command) || !strings.ContainsAny(command, `/\`) {
+		return command
+	}
+	return filepath.Join(basePath, command)
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	return info.Mode().Perm()&0111 != 0
+}
@@ -0,0 +1,121 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"doctrus/internal/config"
+)
+
+func writeExecutable(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell plugins not available on Windows")
+	}
+
+	binDir := t.TempDir()
+	writeExecutable(t, filepath.Join(binDir, "doctrus-hello"), "#!/bin/sh\necho hello\n")
+	writeExecutable(t, filepath.Join(binDir, "doctrus-deploy"), "#!/bin/sh\necho path\n")
+	if err := os.WriteFile(filepath.Join(binDir, "doctrus-notexec"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	writeExecutable(t, filepath.Join(binDir, "other-tool"), "#!/bin/sh\n")
+	t.Setenv("PATH", binDir)
+
+	cfg := &config.Config{
+		Plugins: map[string]config.Plugin{
+			"deploy": {Command: []string{"./scripts/deploy.sh", "--verbose"}, Description: "Deploy services"},
+		},
+	}
+
+	plugins := Discover(cfg, "/repo")
+	if len(plugins) != 2 {
+		t.Fatalf("Discover() returned %d plugins, want 2: %+v", len(plugins), plugins)
+	}
+
+	deploy := plugins[0]
+	if deploy.Name != "deploy" || deploy.Source != "config" {
+		t.Fatalf("expected config plugin to win, got %+v", deploy)
+	}
+	if deploy.Path != filepath.Join("/repo", "scripts", "deploy.sh") {
+		t.Fatalf("deploy path = %q, want path relative to config dir", deploy.Path)
+	}
+	if !reflect.DeepEqual(deploy.Args, []string{"--verbose"}) {
+		t.Fatalf("deploy args = %v", deploy.Args)
+	}
+
+	if plugins[1].Name != "hello" || plugins[1].Source != "path" {
+		t.Fatalf("expected PATH plugin hello, got %+v", plugins[1])
+	}
+}
+
+func TestFindRejectsTaskSpecs(t *testing.T) {
+	if _, ok := Find("frontend:build", nil, ""); ok {
+		t.Fatal("Find() should not treat task specs as plugin names")
+	}
+}
+
+func TestRunPassesPayloadOnStdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell plugins not available on Windows")
+	}
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "doctrus-echo")
+	writeExecutable(t, script, "#!/bin/sh\ncat\n")
+
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"web": {
+				Tasks: map[string]config.Task{
+					"build":   {Command: []string{"npm", "run", "build"}, DependsOn: []string{"install", "api:build"}},
+					"install": {Command: []string{"npm", "install"}},
+				},
+			},
+			"api": {
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"go", "build"}},
+				},
+			},
+		},
+	}
+
+	var stdout, stderr bytes.Buffer
+	p := &Plugin{Name: "echo", Path: script}
+	if err := Run(context.Background(), p, NewPayload(cfg, dir, []string{"--flag"}), &stdout, &stderr); err != nil {
+		t.Fatalf("Run() error = %v (stderr: %s)", err, stderr.String())
+	}
+
+	var got Payload
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("plugin did not receive JSON payload: %v (%q)", err, stdout.String())
+	}
+
+	if got.Protocol != ProtocolVersion {
+		t.Fatalf("payload protocol = %d, want %d", got.Protocol, ProtocolVersion)
+	}
+	if !reflect.DeepEqual(got.Args, []string{"--flag"}) {
+		t.Fatalf("payload args = %v", got.Args)
+	}
+	if got.Config == nil || got.Config.Workspaces["web"].Tasks["build"].Command[0] != "npm" {
+		t.Fatalf("payload config missing workspaces: %+v", got.Config)
+	}
+
+	wantDeps := []string{"web:install", "api:build"}
+	if !reflect.DeepEqual(got.Graph["web:build"], wantDeps) {
+		t.Fatalf("payload graph web:build = %v, want %v", got.Graph["web:build"], wantDeps)
+	}
+}
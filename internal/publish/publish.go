@@ -0,0 +1,227 @@
+// Package publish uploads a task's outputs to the destinations of its
+// publish block: a local directory, an HTTP server accepting PUT or an S3
+// prefix.
+package publish
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"doctrus/internal/config"
+	"doctrus/internal/fsutil"
+	"doctrus/internal/s3"
+)
+
+// ChecksumFile is the manifest published next to the files, listing their
+// sha256 digests in the format of sha256sum.
+const ChecksumFile = "SHA256SUMS"
+
+// retryDelay is the wait before the second attempt of an upload; it doubles
+// with every further attempt.
+var retryDelay = time.Second
+
+// File is one file to publish. Name is its slash-separated path below the
+// destination and SHA256 the hex digest of its contents.
+type File struct {
+	Name   string
+	Path   string
+	SHA256 string
+	Size   int64
+}
+
+// Uploader puts files at one destination.
+type Uploader interface {
+	Upload(ctx context.Context, file File) error
+}
+
+// NewUploader returns the uploader of target. Relative directories are
+// resolved against baseDir.
+func NewUploader(target config.PublishTarget, baseDir string) (Uploader, error) {
+	switch {
+	case target.Dir != "":
+		dir := target.Dir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(baseDir, dir)
+		}
+		return &dirUploader{dir: dir}, nil
+
+	case target.HTTP != "":
+		var token string
+		if target.TokenEnv != "" {
+			token = os.Getenv(target.TokenEnv)
+		}
+		return &httpUploader{base: strings.TrimSuffix(target.HTTP, "/"), token: token}, nil
+
+	case target.S3 != "":
+		bucket, prefix, err := s3.ParseLocation(target.S3)
+		if err != nil {
+			return nil, err
+		}
+		return &s3Uploader{client: s3.NewClientFromEnv(), bucket: bucket, prefix: prefix}, nil
+	}
+	return nil, fmt.Errorf("publish target needs one of dir, http or s3")
+}
+
+// Publish uploads files followed by a SHA256SUMS manifest of them. Each
+// upload is tried up to attempts times, waiting longer after every failure;
+// onRetry, if set, is called before each retry.
+func Publish(ctx context.Context, uploader Uploader, files []File, attempts int, onRetry func(file File, attempt int, err error)) error {
+	manifest, err := writeManifest(files)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(manifest.Path)
+
+	for _, file := range append(append([]File(nil), files...), manifest) {
+		if err := upload(ctx, uploader, file, attempts, onRetry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func upload(ctx context.Context, uploader Uploader, file File, attempts int, onRetry func(File, int, error)) error {
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		err := uploader.Upload(ctx, file)
+		if err == nil {
+			return nil
+		}
+		if attempt >= attempts || ctx.Err() != nil {
+			return fmt.Errorf("failed to publish %s after %d attempt(s): %w", file.Name, attempt, err)
+		}
+		if onRetry != nil {
+			onRetry(file, attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to publish %s: %w", file.Name, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// writeManifest writes the SHA256SUMS of files to a temporary file.
+func writeManifest(files []File) (File, error) {
+	lines := make([]string, 0, len(files))
+	for _, file := range files {
+		lines = append(lines, file.SHA256+"  "+file.Name+"\n")
+	}
+	sort.Strings(lines)
+	content := []byte(strings.Join(lines, ""))
+
+	tmp, err := os.CreateTemp("", "doctrus-"+ChecksumFile+"-*")
+	if err != nil {
+		return File{}, fmt.Errorf("failed to write %s: %w", ChecksumFile, err)
+	}
+	defer tmp.Close()
+	if _, err := tmp.Write(content); err != nil {
+		os.Remove(tmp.Name())
+		return File{}, fmt.Errorf("failed to write %s: %w", ChecksumFile, err)
+	}
+
+	sum := sha256.Sum256(content)
+	return File{Name: ChecksumFile, Path: tmp.Name(), SHA256: hex.EncodeToString(sum[:]), Size: int64(len(content))}, nil
+}
+
+// dirUploader copies files below a local directory and checks the copies
+// against their digests.
+type dirUploader struct {
+	dir string
+}
+
+func (u *dirUploader) Upload(ctx context.Context, file File) error {
+	target := filepath.Join(u.dir, filepath.FromSlash(file.Name))
+	source, err := os.Open(file.Path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	return fsutil.WriteFileAtomic(target, 0644, func(w io.Writer) error {
+		hash := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(w, hash), source); err != nil {
+			return err
+		}
+		if got := hex.EncodeToString(hash.Sum(nil)); got != file.SHA256 {
+			return fmt.Errorf("checksum mismatch: copied sha256 %s, expected %s", got, file.SHA256)
+		}
+		return nil
+	})
+}
+
+// httpUploader PUTs files below a base URL with their digests in the
+// X-Checksum-Sha256 and Digest headers, which artifact servers such as
+// Artifactory and Nexus verify.
+type httpUploader struct {
+	base  string
+	token string
+}
+
+func (u *httpUploader) Upload(ctx context.Context, file File) error {
+	body, err := os.Open(file.Path)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	var escaped []string
+	for _, segment := range strings.Split(file.Name, "/") {
+		escaped = append(escaped, url.PathEscape(segment))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.base+"/"+strings.Join(escaped, "/"), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = file.Size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Checksum-Sha256", file.SHA256)
+	if raw, err := hex.DecodeString(file.SHA256); err == nil {
+		req.Header.Set("Digest", "sha-256="+base64.StdEncoding.EncodeToString(raw))
+	}
+	if u.token != "" {
+		req.Header.Set("Authorization", "Bearer "+u.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("PUT %s: %s %s", req.URL, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// s3Uploader uploads files below a key prefix of a bucket.
+type s3Uploader struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func (u *s3Uploader) Upload(ctx context.Context, file File) error {
+	body, err := os.Open(file.Path)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	return u.client.PutObject(ctx, u.bucket, path.Join(u.prefix, file.Name), body, file.Size, file.SHA256)
+}
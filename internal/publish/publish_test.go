@@ -0,0 +1,120 @@
+package publish
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"doctrus/internal/config"
+)
+
+// writeFiles creates files below dir and returns them ready to publish.
+func writeFiles(t *testing.T, dir string, contents map[string]string) []File {
+	t.Helper()
+	var files []File
+	for name, content := range contents {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256([]byte(content))
+		files = append(files, File{Name: name, Path: path, SHA256: hex.EncodeToString(sum[:]), Size: int64(len(content))})
+	}
+	return files
+}
+
+func TestPublishToDirectory(t *testing.T) {
+	base := t.TempDir()
+	files := writeFiles(t, filepath.Join(base, "app"), map[string]string{"dist/app.js": "js", "dist/app.css": "css"})
+
+	uploader, err := NewUploader(config.PublishTarget{Dir: "releases"}, base)
+	if err != nil {
+		t.Fatalf("NewUploader() error = %v", err)
+	}
+	if err := Publish(context.Background(), uploader, files, 1, nil); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(base, "releases", "dist", "app.js"))
+	if err != nil || string(data) != "js" {
+		t.Fatalf("published app.js = %q, %v", data, err)
+	}
+	sums, err := os.ReadFile(filepath.Join(base, "releases", ChecksumFile))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", ChecksumFile, err)
+	}
+	want := files[0].SHA256 + "  " + files[0].Name
+	if !strings.Contains(string(sums), want) || strings.Count(string(sums), "\n") != 2 {
+		t.Fatalf("%s = %q, want a line per file", ChecksumFile, sums)
+	}
+
+	// A file that changed since it was hashed is not published
+	files[0].SHA256 = strings.Repeat("0", 64)
+	if err := Publish(context.Background(), uploader, files, 1, nil); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Publish() error = %v, want a checksum mismatch", err)
+	}
+}
+
+func TestPublishOverHTTPRetries(t *testing.T) {
+	original := retryDelay
+	retryDelay = 0
+	t.Cleanup(func() { retryDelay = original })
+
+	var mu sync.Mutex
+	uploads := make(map[string]string)
+	failures := map[string]int{"/artifacts/app/dist/app.js": 2}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method != http.MethodPut || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if failures[r.URL.Path] > 0 {
+			failures[r.URL.Path]--
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		if r.Header.Get("X-Checksum-Sha256") != hex.EncodeToString(sum[:]) {
+			http.Error(w, "checksum mismatch", http.StatusBadRequest)
+			return
+		}
+		uploads[r.URL.Path] = string(body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	t.Setenv("PUBLISH_TOKEN", "secret")
+	files := writeFiles(t, t.TempDir(), map[string]string{"dist/app.js": "js"})
+	uploader, err := NewUploader(config.PublishTarget{HTTP: server.URL + "/artifacts/app/", TokenEnv: "PUBLISH_TOKEN"}, "")
+	if err != nil {
+		t.Fatalf("NewUploader() error = %v", err)
+	}
+
+	var retries []int
+	onRetry := func(file File, attempt int, err error) { retries = append(retries, attempt) }
+	if err := Publish(context.Background(), uploader, files, 3, onRetry); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if len(retries) != 2 || uploads["/artifacts/app/dist/app.js"] != "js" || uploads["/artifacts/app/"+ChecksumFile] == "" {
+		t.Fatalf("retries = %v, uploads = %v", retries, uploads)
+	}
+
+	failures["/artifacts/app/dist/app.js"] = 5
+	if err := Publish(context.Background(), uploader, files, 2, nil); err == nil || !strings.Contains(err.Error(), "after 2 attempt(s)") {
+		t.Fatalf("Publish() error = %v, want failure after 2 attempts", err)
+	}
+}
@@ -0,0 +1,258 @@
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"doctrus/internal/events"
+	"doctrus/internal/ui"
+)
+
+//go:embed report.html
+var pageTemplate string
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.2f%%", f) },
+}).Parse(pageTemplate))
+
+// Graph layout, in SVG units
+const (
+	NodeWidth  = 200
+	NodeHeight = 32
+	columnGap  = 64
+	rowGap     = 16
+	margin     = 8
+)
+
+// IndexFile is the name of the report's page in its directory.
+const IndexFile = "index.html"
+
+type pageData struct {
+	Command  string
+	Started  string
+	Duration string
+	Error    string
+	Summary  []string
+	Graph    Graph
+	Tasks    []taskData
+}
+
+type taskData struct {
+	Task
+	ID      string
+	Class   string
+	Label   string
+	Offset  float64
+	Width   float64
+	Start   string
+	Elapsed string
+	CPU     string
+	Memory  string
+}
+
+// Graph is a task graph laid out for SVG. Edges are SVG path data.
+type Graph struct {
+	Width  int      `json:"width"`
+	Height int      `json:"height"`
+	Nodes  []Node   `json:"nodes"`
+	Edges  []string `json:"edges"`
+}
+
+// Node is a task of a Graph, a box of NodeWidth by NodeHeight at X, Y.
+// Class is the task's status: success, failed, cached, compound or pending.
+type Node struct {
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Class string `json:"class"`
+}
+
+// WriteHTML writes the report to index.html in dir, creating dir if needed.
+func (r *Report) WriteHTML(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create report directory: %w", err)
+	}
+	path := filepath.Join(dir, IndexFile)
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+	defer file.Close()
+
+	if err := page.Execute(file, r.pageData()); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+	return path, nil
+}
+
+func (r *Report) pageData() pageData {
+	tasks := r.Tasks()
+	r.mu.Lock()
+	started, finished, runErr := r.started, r.finished, r.err
+	r.mu.Unlock()
+	if finished.IsZero() {
+		finished = time.Now()
+	}
+	total := finished.Sub(started)
+
+	data := pageData{
+		Command:  strings.Join(r.command, " "),
+		Started:  started.Format(time.RFC1123),
+		Duration: total.Round(time.Millisecond).String(),
+		Error:    runErr,
+		Graph:    Layout(tasks),
+	}
+
+	counts := make(map[string]int)
+	for i, task := range tasks {
+		// The page shows output as plain text
+		for j := range task.Output {
+			task.Output[j].Data = ui.StripEscapes(task.Output[j].Data)
+		}
+		view := taskData{
+			Task:  task,
+			ID:    fmt.Sprintf("task-%d", i),
+			Class: statusClass(task.Status),
+			Label: statusLabel(task.Status),
+		}
+		counts[view.Class]++
+		if !task.Started.IsZero() && total > 0 {
+			view.Offset = 100 * float64(task.Started.Sub(started)) / float64(total)
+			view.Width = max(100*float64(task.Duration)/float64(total), 0.5)
+			view.Start = "+" + task.Started.Sub(started).Round(time.Millisecond).String()
+			view.Elapsed = task.Duration.Round(time.Millisecond).String()
+		}
+		if task.CPUTime > 0 {
+			view.CPU = task.CPUTime.Round(time.Millisecond).String()
+		}
+		if task.PeakRSS > 0 {
+			view.Memory = formatBytes(task.PeakRSS)
+		}
+		data.Tasks = append(data.Tasks, view)
+	}
+	for _, class := range []string{events.StatusSuccess, events.StatusFailed, events.StatusCached, events.StatusCompound, "pending"} {
+		if counts[class] > 0 {
+			data.Summary = append(data.Summary, fmt.Sprintf("%d %s", counts[class], statusLabel(classStatus(class))))
+		}
+	}
+	return data
+}
+
+// Layout places every task in the column after the last task it needs, so
+// the graph reads from left to right.
+func Layout(tasks []Task) Graph {
+	byKey := make(map[string]*Task, len(tasks))
+	for i := range tasks {
+		byKey[tasks[i].Key] = &tasks[i]
+	}
+
+	levels := make(map[string]int)
+	var level func(key string, visiting map[string]bool) int
+	level = func(key string, visiting map[string]bool) int {
+		if l, ok := levels[key]; ok {
+			return l
+		}
+		task, ok := byKey[key]
+		if !ok || visiting[key] {
+			return 0
+		}
+		visiting[key] = true
+		l := 0
+		for _, need := range task.Needs {
+			l = max(l, level(need, visiting)+1)
+		}
+		delete(visiting, key)
+		levels[key] = l
+		return l
+	}
+
+	var graph Graph
+	rows := make(map[int]int)
+	positions := make(map[string]Node)
+	for _, task := range tasks {
+		l := level(task.Key, make(map[string]bool))
+		node := Node{
+			X:     margin + l*(NodeWidth+columnGap),
+			Y:     margin + rows[l]*(NodeHeight+rowGap),
+			Key:   task.Key,
+			Label: truncateLabel(task.Key),
+			Class: statusClass(task.Status),
+		}
+		rows[l]++
+		positions[task.Key] = node
+		graph.Nodes = append(graph.Nodes, node)
+		graph.Width = max(graph.Width, node.X+NodeWidth+margin)
+		graph.Height = max(graph.Height, node.Y+NodeHeight+margin)
+	}
+
+	for _, task := range tasks {
+		to := positions[task.Key]
+		for _, need := range task.Needs {
+			from, ok := positions[need]
+			if !ok {
+				continue
+			}
+			x1, y1 := from.X+NodeWidth, from.Y+NodeHeight/2
+			x2, y2 := to.X, to.Y+NodeHeight/2
+			mid := (x1 + x2) / 2
+			graph.Edges = append(graph.Edges, fmt.Sprintf("M%d %d C%d %d %d %d %d %d", x1, y1, mid, y1, mid, y2, x2, y2))
+		}
+	}
+	return graph
+}
+
+// truncateLabel shortens a task key to fit its node.
+func truncateLabel(key string) string {
+	const maxRunes = 26
+	if runes := []rune(key); len(runes) > maxRunes {
+		return string(runes[:maxRunes-1]) + "…"
+	}
+	return key
+}
+
+func statusClass(status string) string {
+	if status == "" {
+		return "pending"
+	}
+	return status
+}
+
+func classStatus(class string) string {
+	if class == "pending" {
+		return ""
+	}
+	return class
+}
+
+func statusLabel(status string) string {
+	switch status {
+	case events.StatusSuccess:
+		return "executed"
+	case "":
+		return "not run"
+	default:
+		return status
+	}
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,166 @@
+// Package report builds a static HTML report of a run for readers without a
+// terminal, such as a CI artifact: the task graph, per-task timings, cache
+// hits and each task's output.
+package report
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"doctrus/internal/events"
+)
+
+// maxOutput is how much output is kept per task. Longer output keeps its
+// end, where failures and summaries are.
+const maxOutput = 1 << 20
+
+// Report collects a run from its events. It is an events.Subscriber and safe
+// for concurrent use.
+type Report struct {
+	mu       sync.Mutex
+	command  []string
+	started  time.Time
+	finished time.Time
+	err      string
+	order    []string
+	tasks    map[string]*Task
+}
+
+// Task is what the report knows about one task of the run.
+type Task struct {
+	Key   string
+	Needs []string
+	// Status is empty until the task finishes, then one of the statuses
+	// of events.TaskFinished
+	Status   string
+	Started  time.Time
+	Duration time.Duration
+	CPUTime  time.Duration
+	PeakRSS  int64
+	ExitCode int
+	Error    string
+	Output   []Chunk
+	// Truncated is set when the start of the output was dropped
+	Truncated bool
+
+	outputSize int
+}
+
+// Chunk is a piece of a task's output on stdout or stderr.
+type Chunk struct {
+	Stream string
+	Data   string
+}
+
+// New returns a report of a run of command starting now.
+func New(command []string) *Report {
+	return &Report{
+		command: command,
+		started: time.Now(),
+		tasks:   make(map[string]*Task),
+	}
+}
+
+// Plan adds a task of the run's graph along with the tasks it needs.
+func (r *Report) Plan(key string, needs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	task := r.task(key)
+	for _, need := range needs {
+		r.task(need)
+		if !slices.Contains(task.Needs, need) {
+			task.Needs = append(task.Needs, need)
+		}
+	}
+}
+
+func (r *Report) Handle(e events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch e.Type {
+	case events.TaskQueued:
+		r.task(e.Key())
+	case events.TaskStarted:
+		r.task(e.Key()).Started = e.Time
+	case events.OutputChunk:
+		r.task(e.Key()).addOutput(Chunk{Stream: e.Stream, Data: e.Data})
+	case events.TaskFinished:
+		task := r.task(e.Key())
+		task.Status = e.Status
+		task.Duration = e.Duration
+		task.CPUTime = e.CPUTime
+		task.PeakRSS = e.PeakRSS
+		task.ExitCode = e.ExitCode
+		task.Error = e.Error
+		// The duration covers the cache check before the command started
+		task.Started = e.Time.Add(-e.Duration)
+	}
+}
+
+// Finish marks the end of the run, which failed with err unless it is nil.
+func (r *Report) Finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = time.Now()
+	if err != nil {
+		r.err = err.Error()
+	}
+}
+
+// Tasks returns copies of the run's tasks, those that ran first and those
+// that did not run in the order they were planned.
+func (r *Report) Tasks() []Task {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tasks := make([]Task, len(r.order))
+	for i, key := range r.order {
+		tasks[i] = *r.tasks[key]
+		tasks[i].Needs = slices.Clone(tasks[i].Needs)
+		tasks[i].Output = slices.Clone(tasks[i].Output)
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Started.IsZero() || tasks[j].Started.IsZero() {
+			return !tasks[i].Started.IsZero() && tasks[j].Started.IsZero()
+		}
+		return tasks[i].Started.Before(tasks[j].Started)
+	})
+	return tasks
+}
+
+func (r *Report) task(key string) *Task {
+	task, ok := r.tasks[key]
+	if !ok {
+		task = &Task{Key: key}
+		r.tasks[key] = task
+		r.order = append(r.order, key)
+	}
+	return task
+}
+
+func (t *Task) addOutput(chunk Chunk) {
+	if n := len(t.Output); n > 0 && t.Output[n-1].Stream == chunk.Stream {
+		t.Output[n-1].Data += chunk.Data
+	} else {
+		t.Output = append(t.Output, chunk)
+	}
+	t.outputSize += len(chunk.Data)
+
+	for t.outputSize > maxOutput {
+		t.Truncated = true
+		excess := t.outputSize - maxOutput
+		if first := &t.Output[0]; len(first.Data) > excess {
+			// Start at a whole character
+			for excess < len(first.Data) && !utf8.RuneStart(first.Data[excess]) {
+				excess++
+			}
+			first.Data = first.Data[excess:]
+			t.outputSize -= excess
+		} else {
+			t.outputSize -= len(first.Data)
+			t.Output = t.Output[1:]
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"doctrus/internal/events"
+)
+
+func TestWriteHTML(t *testing.T) {
+	r := New([]string{"doctrus", "run", "app:all"})
+	r.Plan("app:gen", nil)
+	r.Plan("app:build", []string{"app:gen"})
+	r.Plan("app:lint", []string{"app:gen"})
+
+	start := time.Now()
+	r.Handle(events.Event{Type: events.TaskFinished, Time: start.Add(10 * time.Millisecond), Workspace: "app", Task: "gen", Status: events.StatusCached, Duration: 5 * time.Millisecond})
+	r.Handle(events.Event{Type: events.TaskStarted, Time: start, Workspace: "app", Task: "build"})
+	r.Handle(events.Event{Type: events.OutputChunk, Workspace: "app", Task: "build", Stream: "stdout", Data: "compiling <main>\n"})
+	r.Handle(events.Event{Type: events.OutputChunk, Workspace: "app", Task: "build", Stream: "stderr", Data: "\033[31merror:\033[0m boom\n"})
+	r.Handle(events.Event{Type: events.TaskFinished, Time: start.Add(time.Second), Workspace: "app", Task: "build", Status: events.StatusFailed, ExitCode: 2, Duration: time.Second, PeakRSS: 48 << 20})
+	r.Finish(os.ErrInvalid)
+
+	path, err := r.WriteHTML(filepath.Join(t.TempDir(), "report"))
+	if err != nil {
+		t.Fatalf("WriteHTML() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+
+	for _, want := range []string{
+		"<title>doctrus doctrus run app:all</title>",
+		"<span>1 failed</span><span>1 cached</span><span>1 not run</span>",
+		`<g class="cached"><title>app:gen</title>`,
+		`<g class="failed"><title>app:build</title>`,
+		`<g class="pending"><title>app:lint</title>`,
+		`<td class="status">failed (2)</td>`,
+		"<td>48.0 MiB</td>",
+		"compiling &lt;main&gt;",
+		`<span class="stderr">error: boom`,
+		`needs app:gen`,
+		`<p class="error">invalid argument</p>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report missing %q", want)
+		}
+	}
+	if strings.Count(page, "<path ") != 2 {
+		t.Errorf("report has %d edges, want 2", strings.Count(page, "<path "))
+	}
+}
+
+func TestOutputKeepsItsEnd(t *testing.T) {
+	r := New(nil)
+	line := strings.Repeat("x", 1023) + "\n"
+	for range 2 * maxOutput / len(line) {
+		r.Handle(events.Event{Type: events.OutputChunk, Workspace: "app", Task: "test", Stream: "stdout", Data: line})
+	}
+	r.Handle(events.Event{Type: events.OutputChunk, Workspace: "app", Task: "test", Stream: "stderr", Data: "FAIL\n"})
+
+	task := r.Tasks()[0]
+	size := 0
+	for _, chunk := range task.Output {
+		size += len(chunk.Data)
+	}
+	if !task.Truncated || size != maxOutput {
+		t.Fatalf("output truncated = %v, %d bytes; want truncated to %d", task.Truncated, size, maxOutput)
+	}
+	if last := task.Output[len(task.Output)-1]; last.Stream != "stderr" || last.Data != "FAIL\n" {
+		t.Fatalf("last chunk = %+v, want the end of the output kept", last)
+	}
+}
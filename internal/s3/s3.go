@@ -0,0 +1,254 @@
+// Package s3 is a minimal S3 client for uploading and downloading objects.
+// Requests are signed with AWS Signature Version 4, so it works with AWS and
+// with S3-compatible servers such as MinIO without an SDK.
+package s3
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EmptyPayloadHash is the sha256 digest of an empty request body
+const EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Client talks to one S3 region. With Endpoint set, requests go to that
+// server using path-style URLs; otherwise to AWS using virtual-hosted
+// buckets. Requests are sent unsigned when AccessKey is empty, which works
+// for public buckets.
+type Client struct {
+	Region       string
+	Endpoint     string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	HTTPClient   *http.Client
+
+	now func() time.Time
+}
+
+// NewClientFromEnv returns a client configured from the standard AWS
+// environment variables: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// AWS_SESSION_TOKEN, AWS_REGION or AWS_DEFAULT_REGION (default us-east-1)
+// and AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL.
+func NewClientFromEnv() *Client {
+	return &Client{
+		Region:       firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1"),
+		Endpoint:     firstNonEmpty(os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL")),
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// ParseLocation splits an s3://bucket/prefix location into its bucket and
+// key prefix, which has no leading or trailing slash.
+func ParseLocation(location string) (string, string, error) {
+	rest, ok := strings.CutPrefix(location, "s3://")
+	if !ok {
+		return "", "", fmt.Errorf("invalid s3 location %q (expected s3://bucket/prefix)", location)
+	}
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("invalid s3 location %q (expected s3://bucket/prefix)", location)
+	}
+	return bucket, strings.Trim(prefix, "/"), nil
+}
+
+// PutObject uploads size bytes from body to key in bucket. payloadHash is
+// the hex sha256 digest of the body; S3 rejects the upload when the body
+// does not match it.
+func (c *Client) PutObject(ctx context.Context, bucket, key string, body io.Reader, size int64, payloadHash string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.objectURL(bucket, key), body)
+	if err != nil {
+		return fmt.Errorf("invalid s3 request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if raw, err := hex.DecodeString(payloadHash); err == nil {
+		req.Header.Set("X-Amz-Checksum-Sha256", base64.StdEncoding.EncodeToString(raw))
+	}
+
+	resp, err := c.do(req, payloadHash)
+	if err != nil {
+		return fmt.Errorf("failed to upload s3://%s/%s: %w", bucket, key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to upload s3://%s/%s: %s", bucket, key, responseError(resp))
+	}
+	return nil
+}
+
+// GetObject downloads key from bucket, returning nil when it does not
+// exist. The caller closes the body.
+func (c *Client) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.objectURL(bucket, key), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 request: %w", err)
+	}
+
+	resp, err := c.do(req, EmptyPayloadHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download s3://%s/%s: %w", bucket, key, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, nil
+	}
+	defer resp.Body.Close()
+	return nil, fmt.Errorf("failed to download s3://%s/%s: %s", bucket, key, responseError(resp))
+}
+
+func (c *Client) objectURL(bucket, key string) string {
+	path := uriEncode(key, false)
+	if c.Endpoint != "" {
+		return strings.TrimSuffix(c.Endpoint, "/") + "/" + bucket + "/" + path
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, c.Region, path)
+}
+
+func (c *Client) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	if c.AccessKey != "" {
+		c.sign(req, payloadHash, now().UTC())
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// sign adds a Signature Version 4 Authorization header to req, covering
+// the host and every header already set.
+func (c *Client) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + c.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretKey), date)
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.AccessKey, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes every byte but the unreserved characters, and
+// slashes unless encodeSlash is set, as Signature Version 4 requires.
+func uriEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		switch {
+		case ch >= 'A' && ch <= 'Z', ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9',
+			ch == '-', ch == '_', ch == '.', ch == '~':
+			b.WriteByte(ch)
+		case ch == '/' && !encodeSlash:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// responseError describes an unexpected response with the start of the XML
+// error document S3 sends.
+func responseError(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if message := strings.TrimSpace(string(body)); message != "" {
+		return fmt.Sprintf("%s: %s", resp.Status, message)
+	}
+	return resp.Status
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
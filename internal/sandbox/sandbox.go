@@ -0,0 +1,103 @@
+// Package sandbox stages the files a task declares in a temporary copy of
+// the project, so its command can only read the inputs it lists and only the
+// outputs it lists reach the project.
+package sandbox
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"doctrus/internal/fsutil"
+)
+
+// Sandbox is a temporary directory mirroring the layout of a project.
+type Sandbox struct {
+	// Dir stands in for the project's root directory
+	Dir  string
+	root string
+}
+
+// New creates an empty sandbox for the project at root.
+func New(root string) (*Sandbox, error) {
+	dir, err := os.MkdirTemp("", "doctrus-sandbox-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox: %w", err)
+	}
+	return &Sandbox{Dir: dir, root: root}, nil
+}
+
+// Path returns where path, a path in the project, is in the sandbox. It
+// reports false for paths outside the project.
+func (s *Sandbox) Path(path string) (string, bool) {
+	rel, ok := relativeTo(s.root, path)
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(s.Dir, rel), true
+}
+
+// Stage copies files of the project into the sandbox, keeping their modes.
+// Files outside the project are left where they are, as the sandbox only
+// replaces the project's directory.
+func (s *Sandbox) Stage(files []string) error {
+	for _, file := range files {
+		target, ok := s.Path(file)
+		if !ok {
+			continue
+		}
+		if err := copyFile(file, target); err != nil {
+			return fmt.Errorf("failed to stage %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// Collect copies files from the sandbox back to the project, replacing what
+// is there. It returns the project paths it wrote.
+func (s *Sandbox) Collect(files []string) ([]string, error) {
+	var written []string
+	for _, file := range files {
+		rel, ok := relativeTo(s.Dir, file)
+		if !ok {
+			return written, fmt.Errorf("%s is not in the sandbox", file)
+		}
+		target := filepath.Join(s.root, rel)
+		if err := copyFile(file, target); err != nil {
+			return written, fmt.Errorf("failed to copy %s out of the sandbox: %w", rel, err)
+		}
+		written = append(written, target)
+	}
+	return written, nil
+}
+
+// Remove deletes the sandbox and everything in it.
+func (s *Sandbox) Remove() error {
+	return os.RemoveAll(s.Dir)
+}
+
+func relativeTo(dir, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(target, info.Mode().Perm(), func(w io.Writer) error {
+		_, err := io.Copy(w, in)
+		return err
+	})
+}
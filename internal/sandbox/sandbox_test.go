@@ -0,0 +1,75 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSandbox(t *testing.T) {
+	root := t.TempDir()
+	input := filepath.Join(root, "app", "run.sh")
+	if err := os.MkdirAll(filepath.Dir(input), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(input, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(outside, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	box, err := New(root)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer box.Remove()
+
+	if err := box.Stage([]string{input, outside}); err != nil {
+		t.Fatalf("Stage() error = %v", err)
+	}
+	staged, ok := box.Path(input)
+	if !ok {
+		t.Fatalf("Path(%s) reported it outside the project", input)
+	}
+	info, err := os.Stat(staged)
+	if err != nil {
+		t.Fatalf("input not staged: %v", err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("staged mode = %v, want 0755", info.Mode().Perm())
+	}
+	if _, ok := box.Path(outside); ok {
+		t.Errorf("Path(%s) = ok, want a file outside the project", outside)
+	}
+
+	produced := filepath.Join(box.Dir, "app", "dist", "out.txt")
+	if err := os.MkdirAll(filepath.Dir(produced), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(produced, []byte("built"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	written, err := box.Collect([]string{produced})
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	target := filepath.Join(root, "app", "dist", "out.txt")
+	if len(written) != 1 || written[0] != target {
+		t.Errorf("Collect() = %v, want [%s]", written, target)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "built" {
+		t.Errorf("collected file = %q, %v; want %q", data, err, "built")
+	}
+	if _, err := box.Collect([]string{outside}); err == nil {
+		t.Error("Collect() of a file outside the sandbox succeeded")
+	}
+
+	if err := box.Remove(); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := os.Stat(box.Dir); !os.IsNotExist(err) {
+		t.Errorf("sandbox still exists after Remove() (stat error = %v)", err)
+	}
+}
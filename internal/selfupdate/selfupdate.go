@@ -0,0 +1,276 @@
+package selfupdate
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRepository is the GitHub repository publishing doctrus releases
+	DefaultRepository = "SebastiaanWouters/doctrus"
+	// DefaultAPIURL is the base URL of the GitHub REST API
+	DefaultAPIURL = "https://api.github.com"
+)
+
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
+}
+
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Updater fetches releases from the release feed and replaces the running binary.
+type Updater struct {
+	APIURL     string
+	Repository string
+	Client     *http.Client
+	GOOS       string
+	GOARCH     string
+}
+
+func NewUpdater() *Updater {
+	return &Updater{
+		APIURL:     DefaultAPIURL,
+		Repository: DefaultRepository,
+		Client:     &http.Client{Timeout: 5 * time.Minute},
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+	}
+}
+
+// AssetName returns the release asset name for a platform, matching the
+// filenames produced by the CI build matrix.
+func AssetName(goos, goarch string) string {
+	name := fmt.Sprintf("doctrus-%s-%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Release fetches the release for the given tag, or the latest release when
+// tag is empty.
+func (u *Updater) Release(ctx context.Context, tag string) (*Release, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/releases/latest", strings.TrimSuffix(u.APIURL, "/"), u.Repository)
+	if tag != "" {
+		endpoint = fmt.Sprintf("%s/repos/%s/releases/tags/%s", strings.TrimSuffix(u.APIURL, "/"), u.Repository, tag)
+	}
+
+	data, err := u.get(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release feed: %w", err)
+	}
+
+	var release Release
+	if err := json.Unmarshal(data, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse release feed: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("release feed did not contain a tag name")
+	}
+
+	return &release, nil
+}
+
+// Download fetches the binary for the updater's platform from the release and
+// verifies it against the published SHA256 checksum.
+func (u *Updater) Download(ctx context.Context, release *Release) ([]byte, error) {
+	name := AssetName(u.GOOS, u.GOARCH)
+
+	binary, ok := release.Find(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no binary for %s/%s", release.TagName, u.GOOS, u.GOARCH)
+	}
+	checksum, ok := release.Find(name + ".sha256")
+	if !ok {
+		return nil, fmt.Errorf("release %s has no checksum for %s", release.TagName, name)
+	}
+
+	sumData, err := u.get(ctx, checksum.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download checksum: %w", err)
+	}
+	want, err := ParseChecksum(sumData, name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := u.get(ctx, binary.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download binary: %w", err)
+	}
+
+	if err := VerifyChecksum(data, want); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (u *Updater) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" && strings.HasPrefix(url, u.APIURL) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: unexpected status %s", url, resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func (r *Release) Find(name string) (*Asset, bool) {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i], true
+		}
+	}
+	return nil, false
+}
+
+// ParseChecksum extracts the hex digest for name from sha256sum-formatted data.
+// A bare digest without a filename is accepted as well.
+func ParseChecksum(data []byte, name string) (string, error) {
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) == 1 || strings.TrimPrefix(fields[1], "*") == name {
+			digest := strings.ToLower(fields[0])
+			if _, err := hex.DecodeString(digest); err != nil || len(digest) != sha256.Size*2 {
+				return "", fmt.Errorf("invalid checksum for %s: %q", name, fields[0])
+			}
+			return digest, nil
+		}
+	}
+	return "", fmt.Errorf("checksum for %s not found", name)
+}
+
+func VerifyChecksum(data []byte, want string) error {
+	sum := sha256.Sum256(data)
+	got := hex.EncodeToString(sum[:])
+	if got != want {
+		return fmt.Errorf("checksum mismatch: got %s, want %s", got, want)
+	}
+	return nil
+}
+
+// Replace atomically swaps the executable at exePath for data. The new binary
+// is written next to the old one and renamed over it, so a failed update never
+// leaves a partially written executable behind.
+func Replace(exePath string, data []byte) error {
+	dir := filepath.Dir(exePath)
+
+	info, err := os.Stat(exePath)
+	if err != nil {
+		return fmt.Errorf("failed to stat executable: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".doctrus-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := io.Copy(tmp, bytes.NewReader(data)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := os.Chmod(tmpPath, info.Mode().Perm()|0111); err != nil {
+		return fmt.Errorf("failed to make new binary executable: %w", err)
+	}
+
+	// Windows refuses to overwrite a running executable, but allows renaming it
+	if runtime.GOOS == "windows" {
+		oldPath := exePath + ".old"
+		_ = os.Remove(oldPath)
+		if err := os.Rename(exePath, oldPath); err != nil {
+			return fmt.Errorf("failed to move current binary aside: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmpPath, exePath); err != nil {
+		return fmt.Errorf("failed to replace binary: %w", err)
+	}
+
+	return nil
+}
+
+// IsNewer reports whether latest is a newer semantic version than current.
+// Development builds (anything that is not a version) are always considered older.
+func IsNewer(current, latest string) bool {
+	cur, ok := parseVersion(current)
+	if !ok {
+		return true
+	}
+	lat, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+
+	for i := range cur {
+		if lat[i] != cur[i] {
+			return lat[i] > cur[i]
+		}
+	}
+	return false
+}
+
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if idx := strings.IndexAny(v, "-+"); idx != -1 {
+		v = v[:idx]
+	}
+
+	fields := strings.Split(v, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false
+	}
+
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+
+	return parts, true
+}
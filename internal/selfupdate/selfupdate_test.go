@@ -0,0 +1,188 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T, binary []byte, checksum string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	name := AssetName("linux", "amd64")
+	mux.HandleFunc("/repos/acme/doctrus/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Release{
+			TagName: "v1.2.0",
+			Assets: []Asset{
+				{Name: name, URL: server.URL + "/download/" + name},
+				{Name: name + ".sha256", URL: server.URL + "/download/" + name + ".sha256"},
+			},
+		})
+	})
+	mux.HandleFunc("/download/"+name, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(binary)
+	})
+	mux.HandleFunc("/download/"+name+".sha256", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(checksum + "  " + name + "\n"))
+	})
+
+	return server
+}
+
+func testUpdater(server *httptest.Server) *Updater {
+	return &Updater{
+		APIURL:     server.URL,
+		Repository: "acme/doctrus",
+		Client:     server.Client(),
+		GOOS:       "linux",
+		GOARCH:     "amd64",
+	}
+}
+
+func TestUpdaterDownloadVerifiesChecksum(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho new\n")
+	sum := sha256.Sum256(binary)
+
+	t.Run("valid checksum", func(t *testing.T) {
+		server := newTestServer(t, binary, hex.EncodeToString(sum[:]))
+		updater := testUpdater(server)
+
+		release, err := updater.Release(context.Background(), "")
+		if err != nil {
+			t.Fatalf("Release() error = %v", err)
+		}
+		if release.TagName != "v1.2.0" {
+			t.Fatalf("Release() tag = %q, want v1.2.0", release.TagName)
+		}
+
+		data, err := updater.Download(context.Background(), release)
+		if err != nil {
+			t.Fatalf("Download() error = %v", err)
+		}
+		if string(data) != string(binary) {
+			t.Fatalf("Download() = %q, want %q", data, binary)
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		server := newTestServer(t, binary, strings.Repeat("0", 64))
+		updater := testUpdater(server)
+
+		release, err := updater.Release(context.Background(), "")
+		if err != nil {
+			t.Fatalf("Release() error = %v", err)
+		}
+		if _, err := updater.Download(context.Background(), release); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+			t.Fatalf("Download() error = %v, want checksum mismatch", err)
+		}
+	})
+
+	t.Run("missing platform asset", func(t *testing.T) {
+		server := newTestServer(t, binary, hex.EncodeToString(sum[:]))
+		updater := testUpdater(server)
+		updater.GOARCH = "riscv64"
+
+		release, err := updater.Release(context.Background(), "")
+		if err != nil {
+			t.Fatalf("Release() error = %v", err)
+		}
+		if _, err := updater.Download(context.Background(), release); err == nil {
+			t.Fatal("Download() expected error for missing asset")
+		}
+	})
+}
+
+func TestReplace(t *testing.T) {
+	dir := t.TempDir()
+	exePath := filepath.Join(dir, "doctrus")
+	if err := os.WriteFile(exePath, []byte("old"), 0o755); err != nil {
+		t.Fatalf("failed to write executable: %v", err)
+	}
+
+	if err := Replace(exePath, []byte("new")); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+
+	data, err := os.ReadFile(exePath)
+	if err != nil {
+		t.Fatalf("failed to read executable: %v", err)
+	}
+	if string(data) != "new" {
+		t.Fatalf("Replace() content = %q, want %q", data, "new")
+	}
+
+	info, err := os.Stat(exePath)
+	if err != nil {
+		t.Fatalf("failed to stat executable: %v", err)
+	}
+	if info.Mode().Perm()&0111 == 0 {
+		t.Fatalf("Replace() left binary non-executable: %v", info.Mode())
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("Replace() left temporary files behind: %v", entries)
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{name: "sha256sum format", data: digest + "  doctrus-linux-amd64\n", want: digest},
+		{name: "binary marker", data: digest + " *doctrus-linux-amd64\n", want: digest},
+		{name: "bare digest", data: digest + "\n", want: digest},
+		{name: "other file", data: digest + "  doctrus-darwin-arm64\n", wantErr: true},
+		{name: "invalid digest", data: "xyz  doctrus-linux-amd64\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseChecksum([]byte(tt.data), "doctrus-linux-amd64")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseChecksum() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		current string
+		latest  string
+		want    bool
+	}{
+		{"v1.0.0", "v1.0.1", true},
+		{"v1.2.0", "v1.10.0", true},
+		{"v2.0.0", "v1.9.9", false},
+		{"v1.0.0", "v1.0.0", false},
+		{"dev", "v0.1.0", true},
+		{"dev-abc123", "v0.1.0", true},
+		{"v1.0.0", "nightly", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsNewer(tt.current, tt.latest); got != tt.want {
+			t.Errorf("IsNewer(%q, %q) = %v, want %v", tt.current, tt.latest, got, tt.want)
+		}
+	}
+}
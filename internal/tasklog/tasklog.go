@@ -0,0 +1,195 @@
+// Package tasklog keeps the output of every task run in a file of its own, so
+// a failed run can be looked into after the terminal or CI job is gone.
+package tasklog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultKeep is the number of log files kept per task when no limit is
+// configured; older files are removed when a new one is created.
+const DefaultKeep = 20
+
+// timeLayout names log files so they sort by when the task started.
+const timeLayout = "20060102T150405.000Z"
+
+// Store holds a directory per task, named <workspace>__<task>, with a log
+// file per run named after when it started.
+type Store struct {
+	dir  string
+	keep int
+}
+
+// NewStore creates a store in dir keeping the last keep log files of each
+// task, or DefaultKeep when keep is 0.
+func NewStore(dir string, keep int) *Store {
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+	return &Store{dir: dir, keep: keep}
+}
+
+// Dir returns the directory holding the log files of a task.
+func (s *Store) Dir(workspace, task string) string {
+	return filepath.Join(s.dir, safeName(workspace)+"__"+safeName(task))
+}
+
+// Create starts the log file of a task run that started at startedAt,
+// removing the task's oldest files beyond the store's limit.
+func (s *Store) Create(workspace, task string, startedAt time.Time) (*File, error) {
+	dir := s.Dir(workspace, task)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create task log directory: %w", err)
+	}
+
+	name := startedAt.UTC().Format(timeLayout)
+	var file *os.File
+	var err error
+	// Runs of the same task may start within the same millisecond
+	for i := 1; ; i++ {
+		path := filepath.Join(dir, name+".log")
+		if i > 1 {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d.log", name, i))
+		}
+		file, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if !errors.Is(err, os.ErrExist) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task log: %w", err)
+	}
+
+	if _, err := prune(dir, time.Time{}, s.keep, false); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &File{file: file}, nil
+}
+
+// Prune removes log files older than cutoff and all but the last keep files
+// of every task, returning how many were removed. A zero cutoff leaves the
+// age limit off, and keep 0 applies the store's limit. With dryRun set
+// nothing is removed.
+func (s *Store) Prune(cutoff time.Time, keep int, dryRun bool) (int, error) {
+	if keep <= 0 {
+		keep = s.keep
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read task logs: %w", err)
+	}
+
+	pruned := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(s.dir, entry.Name())
+		n, err := prune(dir, cutoff, keep, dryRun)
+		pruned += n
+		if err != nil {
+			return pruned, err
+		}
+		if !dryRun {
+			// Only succeeds once the last file is gone
+			_ = os.Remove(dir)
+		}
+	}
+	return pruned, nil
+}
+
+// prune removes the log files in dir older than cutoff and all but the last
+// keep.
+func prune(dir string, cutoff time.Time, keep int, dryRun bool) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read task logs: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".log") {
+			names = append(names, entry.Name())
+		}
+	}
+	// Names start with the time the run started, so they sort oldest first
+	sort.Strings(names)
+
+	pruned := 0
+	for i, name := range names {
+		old := len(names)-i > keep
+		if !old && !cutoff.IsZero() {
+			if info, err := os.Stat(filepath.Join(dir, name)); err == nil && info.ModTime().Before(cutoff) {
+				old = true
+			}
+		}
+		if !old {
+			continue
+		}
+		pruned++
+		if dryRun {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return pruned, fmt.Errorf("failed to remove task log: %w", err)
+		}
+	}
+	return pruned, nil
+}
+
+// File is the log of a task run. Writes are serialized, as a task's stdout
+// and stderr are written from different goroutines. A nil File discards
+// everything written to it.
+type File struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Path returns the location of the log file.
+func (f *File) Path() string {
+	if f == nil {
+		return ""
+	}
+	return f.file.Name()
+}
+
+func (f *File) Write(p []byte) (int, error) {
+	if f == nil {
+		return len(p), nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Write(p)
+}
+
+// Close closes the log file.
+func (f *File) Close() error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// safeName replaces the characters of a workspace or task name that can't be
+// part of a file name.
+func safeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,122 @@
+package tasklog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCreateKeepsLastFiles(t *testing.T) {
+	store := NewStore(t.TempDir(), 2)
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var paths []string
+	for i := 0; i < 3; i++ {
+		file, err := store.Create("web/app", "build", start.Add(time.Duration(i)*time.Second))
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if _, err := file.Write([]byte("output\n")); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if err := file.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		paths = append(paths, file.Path())
+	}
+
+	if got, want := filepath.Base(filepath.Dir(paths[0])), "web_app__build"; got != want {
+		t.Fatalf("task directory = %q, want %q", got, want)
+	}
+	if got, want := filepath.Base(paths[0]), "20260102T030405.000Z.log"; got != want {
+		t.Fatalf("log file = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Fatalf("expected the oldest log to be removed, got %v", err)
+	}
+	for _, path := range paths[1:] {
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != "output\n" {
+			t.Fatalf("log %s = %q, %v", path, data, err)
+		}
+	}
+}
+
+func TestCreateSameStartTime(t *testing.T) {
+	store := NewStore(t.TempDir(), 0)
+	start := time.Now()
+	first, err := store.Create("app", "build", start)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer first.Close()
+	second, err := store.Create("app", "build", start)
+	if err != nil {
+		t.Fatalf("second Create() error = %v", err)
+	}
+	defer second.Close()
+	if first.Path() == second.Path() {
+		t.Fatalf("both runs log to %s", first.Path())
+	}
+}
+
+func TestPrune(t *testing.T) {
+	tests := []struct {
+		name       string
+		cutoff     time.Duration
+		keep       int
+		dryRun     bool
+		wantPruned int
+		wantLeft   int
+	}{
+		{name: "store limit", wantPruned: 0, wantLeft: 3},
+		{name: "keep", keep: 1, wantPruned: 2, wantLeft: 1},
+		{name: "age", cutoff: 90 * time.Minute, wantPruned: 1, wantLeft: 2},
+		{name: "dry run", keep: 1, dryRun: true, wantPruned: 2, wantLeft: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore(t.TempDir(), 5)
+			now := time.Now()
+			for i := 0; i < 3; i++ {
+				started := now.Add(-time.Duration(2-i) * time.Hour)
+				file, err := store.Create("app", "test", started)
+				if err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+				file.Close()
+				if err := os.Chtimes(file.Path(), started, started); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var cutoff time.Time
+			if tt.cutoff > 0 {
+				cutoff = now.Add(-tt.cutoff)
+			}
+			pruned, err := store.Prune(cutoff, tt.keep, tt.dryRun)
+			if err != nil {
+				t.Fatalf("Prune() error = %v", err)
+			}
+			if pruned != tt.wantPruned {
+				t.Fatalf("pruned = %d, want %d", pruned, tt.wantPruned)
+			}
+			entries, _ := os.ReadDir(store.Dir("app", "test"))
+			if len(entries) != tt.wantLeft {
+				t.Fatalf("%d logs left, want %d", len(entries), tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestNilFile(t *testing.T) {
+	var file *File
+	if n, err := file.Write([]byte("x")); n != 1 || err != nil {
+		t.Fatalf("Write() = %d, %v", n, err)
+	}
+	if err := file.Close(); err != nil || file.Path() != "" {
+		t.Fatalf("nil file should do nothing")
+	}
+}
@@ -0,0 +1,41 @@
+package taskrun
+
+import (
+	"context"
+
+	"doctrus/internal/deps"
+	"doctrus/internal/workspace"
+)
+
+// resolveDefinition records the digest of a task as it runs on its
+// execution: its wrapped command, its environment from every layer, its
+// container and its executor. Tasks whose environment can't be resolved,
+// such as for a missing env file, fail when they run; their definition is
+// left to the task's own settings.
+func (r *Runner) resolveDefinition(execution *workspace.TaskExecution) {
+	if r.Executor == nil {
+		return
+	}
+	resolved, err := r.Executor.Resolve(execution)
+	if err != nil {
+		r.Log.Debugf("  Task definition unresolved: %v\n", err)
+		return
+	}
+	execution.Definition = deps.DefinitionHash(resolved)
+}
+
+// resolveImageDigest records the digest of the image a container task runs
+// in on its execution, so rebuilding the image invalidates the task's
+// cache. Tasks run on the host have none; images that can't be inspected
+// are left unknown and don't invalidate the cache.
+func (r *Runner) resolveImageDigest(ctx context.Context, execution *workspace.TaskExecution) {
+	if r.Executor == nil {
+		return
+	}
+	_, digest, err := r.Executor.ImageDigest(ctx, execution)
+	if err != nil {
+		r.Log.Debugf("  Image digest unknown: %v\n", err)
+		return
+	}
+	execution.ImageDigest = digest
+}
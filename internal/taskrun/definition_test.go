@@ -0,0 +1,90 @@
+package taskrun
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"doctrus/internal/config"
+	"doctrus/internal/docker"
+	"doctrus/internal/logging"
+	"doctrus/internal/workspace"
+)
+
+func TestResolveDefinition(t *testing.T) {
+	tempDir := t.TempDir()
+	envFile := filepath.Join(tempDir, ".env")
+
+	definition := func(edit func(cfg *config.Config, executor *docker.Dispatcher)) string {
+		t.Helper()
+		if err := os.WriteFile(envFile, []byte("TOKEN=one\n"), 0644); err != nil {
+			t.Fatalf("failed to write env file: %v", err)
+		}
+		cfg := &config.Config{
+			Version: "1.0",
+			Env:     map[string]string{"LEVEL": "global"},
+			Workspaces: map[string]config.Workspace{
+				"app": {
+					Path: tempDir,
+					Tasks: map[string]config.Task{
+						"build": {Command: []string{"make"}, EnvFile: []string{".env"}, Cache: true},
+					},
+				},
+			},
+		}
+		executor := docker.NewExecutor(cfg, tempDir)
+		if edit != nil {
+			edit(cfg, executor)
+		}
+		runner := &Runner{
+			Config:    cfg,
+			BasePath:  tempDir,
+			Workspace: workspace.NewManager(cfg, tempDir),
+			Executor:  executor,
+			Log:       logging.New(io.Discard, logging.LevelInfo, nil),
+		}
+
+		execution, err := runner.Workspace.ResolveTaskExecution("app", "build")
+		if err != nil {
+			t.Fatalf("ResolveTaskExecution() error = %v", err)
+		}
+		runner.resolveDefinition(execution)
+		if execution.Definition == "" {
+			t.Fatal("resolveDefinition() left the definition unknown")
+		}
+		return execution.Definition
+	}
+	base := definition(nil)
+
+	tests := []struct {
+		name    string
+		edit    func(cfg *config.Config, executor *docker.Dispatcher)
+		changed bool
+	}{
+		{"unchanged", func(cfg *config.Config, executor *docker.Dispatcher) {}, false},
+		{"global env", func(cfg *config.Config, executor *docker.Dispatcher) { cfg.Env["LEVEL"] = "changed" }, true},
+		{"env file", func(cfg *config.Config, executor *docker.Dispatcher) {
+			if err := os.WriteFile(envFile, []byte("TOKEN=two\n"), 0644); err != nil {
+				t.Fatalf("failed to write env file: %v", err)
+			}
+		}, true},
+		{"--env", func(cfg *config.Config, executor *docker.Dispatcher) {
+			executor.SetCLIEnv(map[string]string{"DEBUG": "1"})
+		}, true},
+		{"wrapper", func(cfg *config.Config, executor *docker.Dispatcher) { cfg.Wrapper = []string{"nice"} }, true},
+		{"executor", func(cfg *config.Config, executor *docker.Dispatcher) {
+			ws := cfg.Workspaces["app"]
+			task := ws.Tasks["build"]
+			task.Executor = config.ExecutorDockerRun
+			ws.Tasks["build"] = task
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if changed := definition(tt.edit) != base; changed != tt.changed {
+				t.Errorf("definition changed = %v, want %v", changed, tt.changed)
+			}
+		})
+	}
+}
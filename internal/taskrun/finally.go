@@ -0,0 +1,60 @@
+package taskrun
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"doctrus/internal/workspace"
+)
+
+// finallyTimeout bounds each finally step of a task without a timeout, so a
+// hanging cleanup cannot block the run forever.
+const finallyTimeout = 5 * time.Minute
+
+// Finally runs the finally steps of a task after its command, whatever
+// its outcome, with the task's executor, workspace and environment. Steps
+// still run when the run is cancelled, each bounded by the task's timeout
+// or finallyTimeout. Every step runs even after one fails; the first
+// failure is returned.
+func (r *Runner) Finally(ctx context.Context, execution *workspace.TaskExecution, stdout, stderr io.Writer, hooks Hooks) error {
+	if len(execution.Task.Finally) == 0 {
+		return nil
+	}
+	timeout := r.TaskTimeout(execution.Task)
+	if timeout == 0 {
+		timeout = finallyTimeout
+	}
+
+	var firstErr error
+	for i, command := range execution.Task.Finally {
+		task := *execution.Task
+		task.Command = command
+		step := *execution
+		step.Task = &task
+
+		if hooks.FinallyStep != nil {
+			hooks.FinallyStep(command)
+		}
+		stepCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		result := r.Executor.Execute(stepCtx, &step, stdout, stderr)
+		cancel()
+		if result.Error == nil && result.ExitCode == 0 {
+			continue
+		}
+
+		err := fmt.Errorf("finally[%d] %q failed with exit code %d", i, strings.Join(command, " "), result.ExitCode)
+		if result.ExitCode == 0 {
+			err = fmt.Errorf("finally[%d] %q failed: %w", i, strings.Join(command, " "), result.Error)
+		}
+		if hooks.FinallyFailed != nil {
+			hooks.FinallyFailed(err, result)
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,47 @@
+package taskrun
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"doctrus/internal/workspace"
+)
+
+// ApplyParams returns executions with the params of their tasks substituted
+// into command and env, from values or their defaults. Executions of tasks
+// with params are copies; the others are returned as they are. It fails when
+// a task misses a required param, or when a value is given for a param none
+// of the tasks declares.
+func ApplyParams(executions []*workspace.TaskExecution, values map[string]string) ([]*workspace.TaskExecution, error) {
+	applied := make([]*workspace.TaskExecution, len(executions))
+	declared := make(map[string]bool)
+	for i, execution := range executions {
+		applied[i] = execution
+		if len(execution.Task.Params) == 0 {
+			continue
+		}
+		for _, name := range execution.Task.ParamNames() {
+			declared[name] = true
+		}
+		task := *execution.Task
+		if err := task.ApplyParams(values); err != nil {
+			return nil, fmt.Errorf("task %s:%s: %w", execution.WorkspaceName, execution.TaskName, err)
+		}
+		copied := *execution
+		copied.Task = &task
+		applied[i] = &copied
+	}
+
+	var unknown []string
+	for name := range values {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown param %s (no task of the run declares it)", strings.Join(unknown, ", "))
+	}
+	return applied, nil
+}
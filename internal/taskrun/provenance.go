@@ -0,0 +1,50 @@
+package taskrun
+
+import (
+	"context"
+	"time"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/deps"
+	"doctrus/internal/vcs"
+	"doctrus/internal/workspace"
+)
+
+// recordProvenance stores the provenance attestation of a task whose outputs
+// were just cached. Failing to record it only warns, like failing to cache.
+func (r *Runner) recordProvenance(ctx context.Context, execution *workspace.TaskExecution, state *deps.TaskState, started, finished time.Time) {
+	info := cache.BuildInfo{
+		Workspace:  execution.WorkspaceName,
+		Task:       execution.TaskName,
+		Command:    execution.Task.Command,
+		Executor:   r.Config.GetEffectiveExecutor(execution.WorkspaceName, execution.TaskName),
+		GitCommit:  gitCommit(r.BasePath),
+		Version:    r.Version,
+		StartedOn:  started,
+		FinishedOn: finished,
+	}
+
+	image, digest, err := r.Executor.ImageDigest(ctx, execution)
+	if err != nil {
+		r.Log.Warnf("  Warning: failed to resolve image digest for provenance: %v\n", err)
+	}
+	info.Image, info.ImageDigest = image, digest
+
+	if err := r.Cache.SetAttestation(state.TaskKey, cache.NewStatement(info, state)); err != nil {
+		r.Log.Warnf("  Warning: failed to record provenance: %v\n", err)
+	}
+}
+
+// gitCommit returns the git commit checked out in dir, or "" outside a git
+// repository. Jujutsu repositories count, as they store their commits in git.
+func gitCommit(dir string) string {
+	repo := vcs.Detect(dir)
+	if repo.Name() != "git" && repo.Name() != "jj" {
+		return ""
+	}
+	revision, err := repo.Revision()
+	if err != nil {
+		return ""
+	}
+	return revision
+}
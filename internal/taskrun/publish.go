@@ -0,0 +1,52 @@
+package taskrun
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"doctrus/internal/publish"
+	"doctrus/internal/workspace"
+)
+
+// publishOutputs uploads the outputs of a task that just succeeded to each
+// target of its publish block, returning a message per finished target.
+// Files keep their paths relative to the workspace directory.
+func (r *Runner) publishOutputs(ctx context.Context, execution *workspace.TaskExecution) ([]string, error) {
+	outputs, err := r.Tracker.OutputHashes(execution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash outputs for publishing: %w", err)
+	}
+
+	files := make([]publish.File, 0, len(outputs))
+	for _, output := range outputs {
+		path := filepath.Join(r.BasePath, output.Path)
+		name, err := filepath.Rel(execution.AbsPath, path)
+		if err != nil || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			name = output.Path
+		}
+		files = append(files, publish.File{
+			Name:   filepath.ToSlash(name),
+			Path:   path,
+			SHA256: output.Hash,
+			Size:   output.Size,
+		})
+	}
+
+	var messages []string
+	for _, target := range execution.Task.Publish {
+		uploader, err := publish.NewUploader(target, r.BasePath)
+		if err != nil {
+			return messages, err
+		}
+		onRetry := func(file publish.File, attempt int, err error) {
+			r.Log.Warnf("  Warning: publishing %s to %s failed (attempt %d of %d): %v\n", file.Name, target, attempt, target.MaxAttempts(), err)
+		}
+		if err := publish.Publish(ctx, uploader, files, target.MaxAttempts(), onRetry); err != nil {
+			return messages, fmt.Errorf("publish to %s: %w", target, err)
+		}
+		messages = append(messages, fmt.Sprintf("Published %d file(s) to %s", len(files), target))
+	}
+	return messages, nil
+}
@@ -0,0 +1,149 @@
+package taskrun
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"doctrus/internal/sandbox"
+	"doctrus/internal/workspace"
+)
+
+// stageSandbox creates a sandbox holding the files execution may read: its
+// inputs, the sources of its depends_on_files rules and the outputs of its
+// dependencies, looking through compound tasks to the tasks they run.
+func (r *Runner) stageSandbox(execution *workspace.TaskExecution) (*sandbox.Sandbox, error) {
+	taskKey := execution.WorkspaceName + ":" + execution.TaskName
+	if r.Distributed {
+		return nil, fmt.Errorf("%s: sandbox is not supported with --distribute", taskKey)
+	}
+
+	box, err := sandbox.New(r.BasePath)
+	if err != nil {
+		return nil, err
+	}
+	dir, ok := box.Path(execution.AbsPath)
+	if !ok {
+		box.Remove()
+		return nil, fmt.Errorf("%s: sandbox requires the workspace to be inside the project", taskKey)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		box.Remove()
+		return nil, fmt.Errorf("failed to create sandbox: %w", err)
+	}
+
+	files, err := r.sandboxFiles(execution)
+	if err == nil {
+		err = box.Stage(files)
+	}
+	if err != nil {
+		box.Remove()
+		return nil, err
+	}
+	return box, nil
+}
+
+// sandboxFiles returns the files stageSandbox copies for execution.
+func (r *Runner) sandboxFiles(execution *workspace.TaskExecution) ([]string, error) {
+	rules, err := execution.Task.FileRules()
+	if err != nil {
+		return nil, err
+	}
+	patterns := append([]string(nil), execution.Task.Inputs...)
+	for _, rule := range rules {
+		patterns = append(patterns, rule.Sources...)
+	}
+	files, err := r.matchAll(execution, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	visited := make(map[string]bool)
+	var addOutputs func(workspaceName, taskName string) error
+	addOutputs = func(workspaceName, taskName string) error {
+		keys, err := r.Workspace.Dependencies(workspaceName, taskName)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+			depWorkspace, depTask, _ := strings.Cut(key, ":")
+			dep, err := r.Workspace.ResolveTaskExecution(depWorkspace, depTask)
+			if err != nil {
+				return err
+			}
+			if len(dep.Task.Command) == 0 {
+				if err := addOutputs(dep.WorkspaceName, dep.TaskName); err != nil {
+					return err
+				}
+				continue
+			}
+			outputs, err := r.matchAll(dep, dep.Task.Outputs)
+			if err != nil {
+				return err
+			}
+			files = append(files, outputs...)
+		}
+		return nil
+	}
+	if err := addOutputs(execution.WorkspaceName, execution.TaskName); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// collectSandbox copies the files matching execution's outputs, and the
+// targets of its depends_on_files rules, from the sandbox to the project.
+// Absolute output patterns are not collected, as the command wrote those
+// files in place.
+func (r *Runner) collectSandbox(execution *workspace.TaskExecution, box *sandbox.Sandbox) error {
+	rules, err := execution.Task.FileRules()
+	if err != nil {
+		return err
+	}
+	var patterns []string
+	for _, pattern := range execution.Task.Outputs {
+		if !filepath.IsAbs(pattern) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	for _, rule := range rules {
+		patterns = append(patterns, rule.Targets...)
+	}
+
+	staged := *execution
+	staged.AbsPath, _ = box.Path(execution.AbsPath)
+	files, err := r.matchAll(&staged, patterns)
+	if err != nil {
+		return err
+	}
+	if _, err := box.Collect(files); err != nil {
+		return fmt.Errorf("failed to collect outputs from sandbox: %w", err)
+	}
+	return nil
+}
+
+// matchAll returns the files matching any of patterns, resolved against
+// execution's directory.
+func (r *Runner) matchAll(execution *workspace.TaskExecution, patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := r.Tracker.MatchFiles(execution, pattern)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// sandboxed returns a copy of execution that runs in box.
+func sandboxed(execution *workspace.TaskExecution, box *sandbox.Sandbox) *workspace.TaskExecution {
+	copied := *execution
+	copied.WorkDir, _ = box.Path(execution.AbsPath)
+	return &copied
+}
@@ -0,0 +1,300 @@
+package taskrun
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+// ErrStopped is returned for tasks that did not start because another task
+// failed and KeepGoing was not set.
+var ErrStopped = errors.New("not started after another task failed")
+
+// Target names a task as workspace and task.
+type Target struct {
+	Workspace string
+	Task      string
+}
+
+func (t Target) String() string {
+	return t.Workspace + ":" + t.Task
+}
+
+// Dependencies returns the tasks task of workspaceName depends on, in the
+// order listed. Aliased tasks are named by their names, so they run once.
+func Dependencies(cfg *config.Config, workspaceName string, task *config.Task) ([]Target, error) {
+	var deps []Target
+	for _, dep := range task.DependsOn {
+		dep = strings.TrimSpace(dep)
+		if dep == "" {
+			continue
+		}
+		depWorkspace, depTask, err := config.SplitDependency(workspaceName, dep)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, Target{Workspace: depWorkspace, Task: cfg.TaskName(depWorkspace, depTask)})
+	}
+	return deps, nil
+}
+
+// Scheduler runs tasks after their dependencies, each task once however
+// many tasks need it.
+type Scheduler struct {
+	config    *config.Config
+	workspace *workspace.Manager
+	run       func(ctx context.Context, execution *workspace.TaskExecution, prefixed bool) error
+
+	// Queued is called when a task is first needed
+	Queued func(target Target)
+	// Recover is deferred by every goroutine running tasks side by side,
+	// such as to restore the terminal on a panic
+	Recover func()
+	// Slots holds a token for every task running its command when the
+	// number of tasks running at once is limited, and is nil otherwise
+	Slots chan struct{}
+	// KeepGoing runs the tasks that don't depend on a failed task, where
+	// otherwise no task starts after one failed
+	KeepGoing bool
+	// NoDeps runs only the tasks asked for, assuming their dependencies
+	// already ran
+	NoDeps bool
+
+	mu     sync.Mutex
+	states map[string]*taskState
+	// failed and skipped list the tasks whose command failed and those that
+	// did not run because of a failure, in the order it happened
+	failed  []string
+	skipped []string
+	// terminal is held exclusively by interactive tasks and shared by the
+	// others while they run their commands
+	terminal sync.RWMutex
+}
+
+type taskState struct {
+	cond    *sync.Cond
+	running bool
+	err     error
+}
+
+// NewScheduler returns a scheduler for the tasks of manager that runs each
+// task with run once its dependencies succeeded. prefixed is set when the
+// task's output may interleave with the output of other tasks.
+func NewScheduler(cfg *config.Config, manager *workspace.Manager, run func(ctx context.Context, execution *workspace.TaskExecution, prefixed bool) error) *Scheduler {
+	return &Scheduler{
+		config:    cfg,
+		workspace: manager,
+		run:       run,
+		states:    make(map[string]*taskState),
+	}
+}
+
+// RunTargets runs targets and their dependencies as one graph: several
+// targets run at the same time when Concurrent, and dependencies they share
+// run once. Otherwise they run in the order given. It returns the target
+// that failed and its error, preferring a failure over a target that was
+// only stopped by one.
+func (s *Scheduler) RunTargets(ctx context.Context, targets []Target) (Target, error) {
+	errs := make([]error, len(targets))
+	if len(targets) == 1 || !s.Concurrent() {
+		for i, target := range targets {
+			errs[i] = s.RunTask(ctx, target, false)
+			if errs[i] != nil && !s.KeepGoing {
+				break
+			}
+		}
+	} else {
+		// Output of targets running side by side is prefixed with their task
+		var wg sync.WaitGroup
+		for i, target := range targets {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if s.Recover != nil {
+					defer s.Recover()
+				}
+				errs[i] = s.RunTask(ctx, target, true)
+			}()
+		}
+		wg.Wait()
+	}
+
+	failed := -1
+	for i, err := range errs {
+		if err != nil && (failed < 0 || errors.Is(errs[failed], ErrStopped) && !errors.Is(err, ErrStopped)) {
+			failed = i
+		}
+	}
+	if failed < 0 {
+		return Target{}, nil
+	}
+	return targets[failed], errs[failed]
+}
+
+// RunTask runs target after its dependencies, or waits for the run of it
+// already started and returns its error.
+func (s *Scheduler) RunTask(ctx context.Context, target Target, prefixed bool) error {
+	key := target.String()
+
+	s.mu.Lock()
+	if state, exists := s.states[key]; exists {
+		for state.running {
+			state.cond.Wait()
+		}
+		err := state.err
+		s.mu.Unlock()
+		return err
+	}
+	state := &taskState{cond: sync.NewCond(&s.mu), running: true}
+	s.states[key] = state
+	s.mu.Unlock()
+
+	if s.Queued != nil {
+		s.Queued(target)
+	}
+	err := s.execute(ctx, target, prefixed)
+
+	s.mu.Lock()
+	state.running = false
+	state.err = err
+	state.cond.Broadcast()
+	s.mu.Unlock()
+	return err
+}
+
+func (s *Scheduler) execute(ctx context.Context, target Target, prefixed bool) error {
+	execution, err := s.workspace.ResolveTaskExecution(target.Workspace, target.Task)
+	if err != nil {
+		return err
+	}
+	deps, err := Dependencies(s.config, target.Workspace, execution.Task)
+	if err != nil {
+		return err
+	}
+
+	key := target.String()
+	compound := len(execution.Task.Command) == 0
+	if len(deps) > 0 && !s.NoDeps {
+		var err error
+		if compound && execution.Task.Parallel != nil && *execution.Task.Parallel || s.Concurrent() {
+			// Dependencies of several tasks may be ready at once, so their
+			// output is prefixed with the task
+			err = s.runParallel(ctx, deps, prefixed || compound || s.Concurrent())
+		} else {
+			for _, dep := range deps {
+				depErr := s.RunTask(ctx, dep, prefixed || compound)
+				if depErr != nil && err == nil {
+					err = depErr
+				}
+				if err != nil && !s.KeepGoing {
+					break
+				}
+			}
+		}
+		if err != nil {
+			s.skip(key, err)
+			return err
+		}
+	}
+
+	if s.Slots != nil && !compound {
+		select {
+		case s.Slots <- struct{}{}:
+			defer func() { <-s.Slots }()
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+	// Interactive tasks run alone, as they read from the terminal
+	if execution.Task.Interactive {
+		s.terminal.Lock()
+		defer s.terminal.Unlock()
+	} else if !compound {
+		s.terminal.RLock()
+		defer s.terminal.RUnlock()
+	}
+	if s.stopped() {
+		s.skip(key, ErrStopped)
+		return ErrStopped
+	}
+
+	if err := s.run(ctx, execution, prefixed); err != nil {
+		s.mu.Lock()
+		s.failed = append(s.failed, key)
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (s *Scheduler) runParallel(ctx context.Context, deps []Target, prefixed bool) error {
+	var wg sync.WaitGroup
+	errCh := make(chan error, len(deps))
+
+	for _, dep := range deps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.Recover != nil {
+				defer s.Recover()
+			}
+			if err := s.RunTask(ctx, dep, prefixed); err != nil {
+				errCh <- err
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	select {
+	case err := <-errCh:
+		return err
+	default:
+		return nil
+	}
+}
+
+// Concurrent reports whether a parallelism limit above one was set, in which
+// case every task whose dependencies are done may start, as far as slots
+// are free, rather than the targets of a run and the dependencies of a task
+// running one at a time in the order they are listed. Without a limit, nil
+// slots, only the dependencies of parallel compound tasks run at once.
+func (s *Scheduler) Concurrent() bool {
+	return cap(s.Slots) > 1
+}
+
+// Failed returns the tasks whose command failed, in the order they did.
+func (s *Scheduler) Failed() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.failed...)
+}
+
+// Skipped returns the tasks that did not run because of a failure.
+func (s *Scheduler) Skipped() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.skipped...)
+}
+
+// stopped reports whether a task failed and, without KeepGoing, no other
+// task may start.
+func (s *Scheduler) stopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.KeepGoing && len(s.failed) > 0
+}
+
+// skip records that the task with key did not run because of err, a failed
+// dependency or ErrStopped.
+func (s *Scheduler) skip(key string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if errors.Is(err, ErrStopped) || len(s.failed) > 0 {
+		s.skipped = append(s.skipped, key)
+	}
+}
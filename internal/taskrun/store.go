@@ -0,0 +1,133 @@
+package taskrun
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/deps"
+	"doctrus/internal/workspace"
+)
+
+// storeOutputs stores the outputs of a task that just succeeded in the
+// content-addressable store, under the fingerprint of its inputs.
+func (r *Runner) storeOutputs(execution *workspace.TaskExecution, state *deps.TaskState) {
+	files := make([]string, len(state.Outputs))
+	for i, output := range state.Outputs {
+		files[i] = output.Path
+	}
+	if err := r.CAS.Put(outputsKey(execution, state.InputHashes), r.BasePath, files); err != nil {
+		r.Log.Warnf("  Warning: failed to store outputs: %v\n", err)
+	}
+}
+
+// restoreOutputs restores the outputs a task produced in an earlier run with
+// the same inputs, such as after a clean or switching back to a branch, and
+// caches its state, reporting whether the task can be skipped.
+func (r *Runner) restoreOutputs(execution *workspace.TaskExecution) bool {
+	inputs, err := r.Tracker.InputHashes(execution)
+	if err != nil {
+		r.Log.Warnf("  Warning: failed to hash inputs: %v\n", err)
+		return false
+	}
+
+	restored, err := r.CAS.Restore(outputsKey(execution, inputs), r.BasePath)
+	if err != nil {
+		r.Log.Warnf("  Warning: failed to restore outputs: %v\n", err)
+		return false
+	}
+	if len(restored) == 0 {
+		return false
+	}
+	r.Log.Debugf("  Restored %d output(s)\n", len(restored))
+	r.cacheRestored(execution)
+	return true
+}
+
+// outputsKey returns the fingerprint a task's outputs are stored under.
+// Unlike the remote cache key it covers the image digest, which for locally
+// built images only identifies the image on this machine.
+func outputsKey(execution *workspace.TaskExecution, inputs []deps.FileInfo) string {
+	taskKey := execution.WorkspaceName + ":" + execution.TaskName
+	return cache.Fingerprint(taskKey, deps.TaskDefinition(execution)+execution.ImageDigest, inputs)
+}
+
+// restoreFromRemote extracts the outputs of a task from the remote cache and
+// caches its state locally, reporting whether the task can be skipped.
+// Remote cache problems only warn: the task then simply runs.
+func (r *Runner) restoreFromRemote(ctx context.Context, execution *workspace.TaskExecution) bool {
+	taskKey := execution.WorkspaceName + ":" + execution.TaskName
+	inputs, err := r.Tracker.InputHashes(execution)
+	if err != nil {
+		r.Log.Warnf("  Warning: failed to hash inputs for the remote cache: %v\n", err)
+		return false
+	}
+
+	artifact, err := r.Remote.Fetch(ctx, cache.Fingerprint(taskKey, deps.TaskDefinition(execution), inputs))
+	if err != nil {
+		r.Log.Warnf("  Warning: %v\n", err)
+		return false
+	}
+	if artifact == nil {
+		r.Log.Debugf("  No remote cache entry for %s\n", taskKey)
+		return false
+	}
+	defer artifact.Close()
+
+	if _, err := cache.ExtractArchive(artifact, r.BasePath); err != nil {
+		r.Log.Warnf("  Warning: failed to restore outputs from the remote cache: %v\n", err)
+		return false
+	}
+	r.cacheRestored(execution)
+	return true
+}
+
+// cacheRestored caches the state of a task whose outputs were restored.
+func (r *Runner) cacheRestored(execution *workspace.TaskExecution) {
+	state, err := r.Tracker.ComputeTaskState(execution, true)
+	if err != nil {
+		r.Log.Warnf("  Warning: failed to compute task state: %v\n", err)
+		return
+	}
+	if err := r.Cache.Set(execution.WorkspaceName+":"+execution.TaskName, state, 0); err != nil {
+		r.Log.Warnf("  Warning: failed to cache task state: %v\n", err)
+	}
+}
+
+// uploadToRemote stores the outputs of a task that just succeeded in the
+// remote cache.
+func (r *Runner) uploadToRemote(ctx context.Context, execution *workspace.TaskExecution, state *deps.TaskState, duration time.Duration) {
+	if err := r.storeArtifact(ctx, execution, state, duration); err != nil {
+		r.Log.Warnf("  Warning: %v\n", err)
+		return
+	}
+	r.Log.Debugf("  Uploaded %d output(s) to the remote cache\n", len(state.Outputs))
+}
+
+func (r *Runner) storeArtifact(ctx context.Context, execution *workspace.TaskExecution, state *deps.TaskState, duration time.Duration) error {
+	// Outputs such as node_modules can be large, so the archive is staged
+	// on disk rather than in memory
+	file, err := os.CreateTemp("", "doctrus-artifact-*.tar.gz")
+	if err != nil {
+		return fmt.Errorf("failed to archive outputs: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := cache.WriteArchive(file, r.BasePath, state.Outputs); err != nil {
+		return err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to archive outputs: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to archive outputs: %w", err)
+	}
+
+	hash := cache.Fingerprint(state.TaskKey, deps.TaskDefinition(execution), state.InputHashes)
+	return r.Remote.Store(ctx, hash, file, size, duration)
+}
@@ -0,0 +1,123 @@
+package taskrun
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"doctrus/internal/access"
+	"doctrus/internal/config"
+	"doctrus/internal/workspace"
+)
+
+// inputTrace is a task execution whose command runs under strace, so the
+// files it reads can be checked against its inputs.
+type inputTrace struct {
+	mode      string
+	execution *workspace.TaskExecution
+	logPath   string
+}
+
+// strictInputsMode returns how reads outside task's inputs are treated: the
+// runner's StrictInputs when set, otherwise the task's strict_inputs.
+func (r *Runner) strictInputsMode(task *config.Task) string {
+	if r.StrictInputs != "" {
+		return r.StrictInputs
+	}
+	return task.StrictInputs
+}
+
+// traceInputs returns execution prepared to record the files it opens when
+// its reads are checked, or nil when they are not or cannot be.
+func (r *Runner) traceInputs(execution *workspace.TaskExecution) *inputTrace {
+	mode := r.strictInputsMode(execution.Task)
+	if mode == "" || len(execution.Task.Command) == 0 {
+		return nil
+	}
+	taskKey := execution.WorkspaceName + ":" + execution.TaskName
+	if r.Distributed || r.Config.GetEffectiveExecutor(execution.WorkspaceName, execution.TaskName) != config.ExecutorLocal {
+		r.Log.Warnf("  Warning: reads of %s are not checked, as only tasks run by the local executor are traced\n", taskKey)
+		return nil
+	}
+	if !access.Available() {
+		r.Log.Warnf("  Warning: reads of %s are not checked, as tracing them requires strace on Linux\n", taskKey)
+		return nil
+	}
+
+	file, err := os.CreateTemp("", "doctrus-access-*.log")
+	if err != nil {
+		r.Log.Warnf("  Warning: reads of %s are not checked: %v\n", taskKey, err)
+		return nil
+	}
+	file.Close()
+
+	task := *execution.Task
+	task.Command = access.Command(file.Name(), task.Command)
+	traced := *execution
+	traced.Task = &task
+	return &inputTrace{mode: mode, execution: &traced, logPath: file.Name()}
+}
+
+// Close removes the trace's log.
+func (t *inputTrace) Close() {
+	if t != nil {
+		os.Remove(t.logPath)
+	}
+}
+
+// undeclaredReads returns the project files the traced execution read that
+// its inputs and outputs do not cover, relative to its directory. Files it
+// created itself and doctrus's own files under .doctrus are left out, as
+// are files outside the project such as toolchains.
+func (r *Runner) undeclaredReads(execution *workspace.TaskExecution, trace *inputTrace) ([]string, error) {
+	accesses, err := access.ReadLog(trace.logPath)
+	if err != nil {
+		return nil, err
+	}
+
+	declared := make(map[string]bool)
+	for _, patterns := range [][]string{execution.Task.Inputs, execution.Task.Outputs} {
+		for _, pattern := range patterns {
+			matches, err := r.Tracker.MatchFiles(execution, pattern)
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range matches {
+				declared[realPath(match)] = true
+			}
+		}
+	}
+
+	// strace reports paths with symlinks resolved
+	root := realPath(r.BasePath)
+	internal := filepath.Join(root, ".doctrus")
+	dir := realPath(execution.AbsPath)
+	var files []string
+	for _, a := range accesses {
+		if a.Write || declared[a.Path] || !isWithin(root, a.Path) || isWithin(internal, a.Path) {
+			continue
+		}
+		if info, err := os.Stat(a.Path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		rel, err := filepath.Rel(dir, a.Path)
+		if err != nil {
+			rel = a.Path
+		}
+		files = append(files, rel)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func realPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+func isWithin(dir, path string) bool {
+	return strings.HasPrefix(path, dir+string(filepath.Separator))
+}
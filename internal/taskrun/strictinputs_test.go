@@ -0,0 +1,119 @@
+package taskrun
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/logging"
+	"doctrus/internal/workspace"
+)
+
+func TestUndeclaredReads(t *testing.T) {
+	tempDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := filepath.Join(tempDir, "app")
+	for _, file := range []string{
+		"app/src/main.go",
+		"app/config.json",
+		"app/dist/app.js",
+		"app/generated.go",
+		"shared/util.go",
+		".doctrus/cache/app_build.json",
+	} {
+		path := filepath.Join(tempDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var lines []string
+	for _, access := range []struct{ path, flags string }{
+		{"app/src/main.go", "O_RDONLY"},
+		{"app/config.json", "O_RDONLY"},
+		{"app/dist/app.js", "O_RDONLY"},
+		{"app/generated.go", "O_WRONLY|O_CREAT"},
+		{"shared/util.go", "O_RDONLY"},
+		{".doctrus/cache/app_build.json", "O_RDONLY"},
+	} {
+		path := filepath.Join(tempDir, access.path)
+		lines = append(lines, `openat(AT_FDCWD, "x", `+access.flags+`) = 3<`+path+`>`)
+	}
+	lines = append(lines, `openat(AT_FDCWD, "/usr/lib/libc.so.6", O_RDONLY|O_CLOEXEC) = 3</usr/lib/libc.so.6>`)
+	logPath := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(logPath, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Runner{
+		BasePath: tempDir,
+		Tracker:  deps.NewTracker(tempDir),
+		Log:      logging.New(&bytes.Buffer{}, logging.LevelInfo, nil),
+	}
+	execution := &workspace.TaskExecution{
+		WorkspaceName: "app",
+		TaskName:      "build",
+		AbsPath:       app,
+		Task: &config.Task{
+			Inputs:  []string{"src/**/*.go"},
+			Outputs: []string{"dist/**"},
+		},
+	}
+
+	got, err := r.undeclaredReads(execution, &inputTrace{mode: config.StrictInputsFail, logPath: logPath})
+	if err != nil {
+		t.Fatalf("undeclaredReads() error = %v", err)
+	}
+	want := []string{"../shared/util.go", "config.json"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("undeclaredReads() = %v, want %v", got, want)
+	}
+}
+
+func TestTraceInputsWarnsForContainerTasks(t *testing.T) {
+	container := "app"
+	tests := []struct {
+		name        string
+		task        config.Task
+		distributed bool
+	}{
+		{name: "compose exec", task: config.Task{Command: []string{"go", "build"}, Container: &container}},
+		{name: "docker run", task: config.Task{Command: []string{"go", "build"}, Executor: config.ExecutorDockerRun}},
+		{name: "distributed", task: config.Task{Command: []string{"go", "build"}}, distributed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := tt.task
+			task.StrictInputs = config.StrictInputsWarn
+			cfg := &config.Config{Workspaces: map[string]config.Workspace{
+				"app": {Tasks: map[string]config.Task{"build": task}},
+			}}
+			var log bytes.Buffer
+			r := &Runner{
+				Config:      cfg,
+				Distributed: tt.distributed,
+				Log:         logging.New(&log, logging.LevelInfo, nil),
+			}
+			execution := &workspace.TaskExecution{WorkspaceName: "app", TaskName: "build", Task: &task}
+
+			if trace := r.traceInputs(execution); trace != nil {
+				trace.Close()
+				t.Fatal("traceInputs() traced a task the local executor does not run")
+			}
+			if want := "reads of app:build are not checked"; !strings.Contains(log.String(), want) {
+				t.Errorf("log = %q, want a warning containing %q", log.String(), want)
+			}
+		})
+	}
+}
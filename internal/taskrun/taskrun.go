@@ -0,0 +1,441 @@
+// Package taskrun runs one resolved task the way doctrus run does, for both
+// the CLI and the embeddable Engine: it checks the task's cache, restores
+// its outputs from the content-addressable store or the remote cache, runs
+// its command with retries, timeouts and finally steps, checks and
+// publishes its outputs and caches its state. Presenting the task's
+// progress is left to the caller, through Hooks.
+package taskrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"doctrus/internal/cache"
+	"doctrus/internal/cas"
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/docker"
+	"doctrus/internal/logging"
+	"doctrus/internal/sandbox"
+	"doctrus/internal/tracing"
+	"doctrus/internal/workspace"
+)
+
+// Runner runs tasks of one configuration.
+type Runner struct {
+	Config    *config.Config
+	BasePath  string
+	Workspace *workspace.Manager
+	Executor  *docker.Dispatcher
+	Tasks     *docker.TaskContexts
+	Tracker   *deps.Tracker
+	Cache     *cache.Manager
+	// CAS keeps the outputs of cached tasks to restore them later; nil
+	// disables it
+	CAS *cas.Store
+	// Remote shares cached outputs between machines; nil disables it
+	Remote cache.Remote
+	// Log receives warnings about problems that don't fail a task, such as
+	// a cache that can't be written
+	Log *logging.Logger
+
+	// Timeout bounds tasks without a timeout of their own; zero means no
+	// limit
+	Timeout time.Duration
+	// Force runs tasks even when their cache is up to date; the cache is
+	// still read and written
+	Force bool
+	// SkipCache ignores cached state, still caching the state of the tasks
+	// that run
+	SkipCache bool
+	// NoCache neither reads nor writes the cache
+	NoCache bool
+	// DryRun checks the cache of tasks without running them
+	DryRun bool
+	// StrictInputs overrides the strict_inputs setting of every task
+	StrictInputs string
+	// Distributed is set when tasks run on agents, which can't sandbox or
+	// trace them
+	Distributed bool
+	// Version is the doctrus version recorded in provenance attestations
+	Version string
+}
+
+// Status is the outcome of a task.
+type Status string
+
+const (
+	StatusSuccess Status = "success"
+	StatusFailed  Status = "failed"
+	StatusCached  Status = "cached"
+	// StatusCompound marks tasks without a command
+	StatusCompound Status = "compound"
+	// StatusDryRun marks tasks a dry run would run
+	StatusDryRun Status = "dry-run"
+)
+
+// Skip is why a task with StatusCached did not run.
+type Skip int
+
+const (
+	// SkipUnchanged means its inputs and definition did not change
+	SkipUnchanged Skip = iota + 1
+	// SkipUpToDate means the targets of its depends_on_files rules are
+	// newer than their sources
+	SkipUpToDate
+	// SkipRestored means its outputs were restored from the
+	// content-addressable store
+	SkipRestored
+	// SkipRemote means its outputs were restored from the remote cache
+	SkipRemote
+)
+
+// Check is the outcome of checking a task's cache.
+type Check struct {
+	// Execution is the task as checked, with its definition and image
+	// digest resolved
+	Execution *workspace.TaskExecution
+	// Previous is the state cached by the task's last run, or nil
+	Previous *deps.TaskState
+	// Run reports whether the task runs
+	Run bool
+	// Stale names the depends_on_files targets that are out of date
+	Stale string
+}
+
+// Attempt is one run of a task's command.
+type Attempt struct {
+	Result   *docker.ExecutionResult
+	Number   int
+	Max      int
+	Start    time.Time
+	Duration time.Duration
+}
+
+// Result is the outcome of running a task.
+type Result struct {
+	Status Status
+	Skip   Skip
+	// Attempt is the final attempt of the command, or nil when it did not
+	// run
+	Attempt *Attempt
+	// MissingOutputs lists the output patterns that matched no files after
+	// the command succeeded
+	MissingOutputs []string
+	// UndeclaredReads lists the project files the command read outside its
+	// inputs, when its reads were traced
+	UndeclaredReads []string
+	// Published has a message per publish target the outputs went to
+	Published []string
+	// The errors that failed a task whose command succeeded: its outputs
+	// could not be collected from its sandbox, matched no files with
+	// strict_outputs, its reads were not declared with strict_inputs fail,
+	// a finally step failed or publishing failed
+	SandboxErr error
+	OutputsErr error
+	InputsErr  error
+	FinallyErr error
+	PublishErr error
+	// State is the state cached for the task, or nil when it was not
+	// cached
+	State *deps.TaskState
+}
+
+// Hooks present the progress of a task. Every hook is optional.
+type Hooks struct {
+	// Checked is called once the task's cache was checked
+	Checked func(check *Check)
+	// Start is called when the command is about to run and returns the
+	// writers receiving its output; nil writers discard it
+	Start func() (stdout, stderr io.Writer)
+	// Retry is called when an attempt failed and the command runs again
+	// after delay
+	Retry func(attempt *Attempt, delay time.Duration)
+	// Executed is called when the final attempt of the command ended
+	Executed func(attempt *Attempt)
+	// FinallyStep is called before each finally step runs
+	FinallyStep func(command []string)
+	// FinallyFailed is called when a finally step failed with err
+	FinallyFailed func(err error, result *docker.ExecutionResult)
+	// Succeeded is called when the task succeeded, before its state is
+	// cached
+	Succeeded func(result *Result)
+}
+
+// Run runs execution unless its cache is up to date. It returns the
+// *workspace.TaskFailedError of a failed command, or the first error that
+// failed the task otherwise, together with the result.
+func (r *Runner) Run(ctx context.Context, execution *workspace.TaskExecution, hooks Hooks) (*Result, error) {
+	task := execution.Task
+	if len(task.Command) == 0 {
+		return &Result{Status: StatusCompound}, nil
+	}
+	// The execution is shared with other runs of the task, so recording its
+	// definition and image digest works on a copy
+	copied := *execution
+	execution = &copied
+
+	if task.Cache {
+		r.resolveDefinition(execution)
+		r.resolveImageDigest(ctx, execution)
+	}
+
+	check, skip, err := r.check(ctx, execution)
+	if err != nil {
+		return &Result{Status: StatusFailed}, err
+	}
+	if hooks.Checked != nil {
+		hooks.Checked(check)
+	}
+	if !check.Run {
+		return &Result{Status: StatusCached, Skip: skip}, nil
+	}
+	if r.DryRun {
+		return &Result{Status: StatusDryRun}, nil
+	}
+	if r.Remote != nil && task.Cache && !r.Force && !r.SkipCache && !r.NoCache && r.restoreFromRemote(ctx, execution) {
+		return &Result{Status: StatusCached, Skip: SkipRemote}, nil
+	}
+
+	trace := r.traceInputs(execution)
+	defer trace.Close()
+	executed := execution
+	if trace != nil {
+		executed = trace.execution
+	}
+	var box *sandbox.Sandbox
+	if task.Sandbox {
+		if box, err = r.stageSandbox(execution); err != nil {
+			return &Result{Status: StatusFailed}, err
+		}
+		defer box.Remove()
+		executed = sandboxed(executed, box)
+	}
+
+	var stdout, stderr io.Writer
+	if hooks.Start != nil {
+		stdout, stderr = hooks.Start()
+	}
+	attempt := r.execute(ctx, executed, stdout, stderr, hooks.Retry)
+	if hooks.Executed != nil {
+		hooks.Executed(attempt)
+	}
+	result := &Result{Status: StatusFailed, Attempt: attempt}
+	exec := attempt.Result
+
+	if box != nil && exec.Error == nil && exec.ExitCode == 0 {
+		result.SandboxErr = r.collectSandbox(execution, box)
+	}
+	result.FinallyErr = r.Finally(ctx, execution, stdout, stderr, hooks)
+
+	if exec.Error != nil && exec.ExitCode == 0 {
+		return result, fmt.Errorf("execution error: %w", exec.Error)
+	}
+	if result.SandboxErr != nil {
+		return result, result.SandboxErr
+	}
+	if exec.ExitCode != 0 {
+		return result, &workspace.TaskFailedError{
+			Workspace: execution.WorkspaceName,
+			Task:      execution.TaskName,
+			ExitCode:  exec.ExitCode,
+			Cause:     exec.Cause,
+		}
+	}
+
+	result.MissingOutputs = r.missingOutputs(execution)
+	if len(result.MissingOutputs) > 0 && task.StrictOutputs {
+		result.OutputsErr = &workspace.MissingOutputsError{
+			Workspace: execution.WorkspaceName,
+			Task:      execution.TaskName,
+			Patterns:  result.MissingOutputs,
+		}
+	}
+	if trace != nil {
+		undeclared, err := r.undeclaredReads(execution, trace)
+		if err != nil {
+			r.Log.Warnf("  Warning: failed to check file reads: %v\n", err)
+		} else if len(undeclared) > 0 && trace.mode == config.StrictInputsFail {
+			result.InputsErr = &workspace.UndeclaredInputsError{
+				Workspace: execution.WorkspaceName,
+				Task:      execution.TaskName,
+				Files:     undeclared,
+			}
+		}
+		result.UndeclaredReads = undeclared
+	}
+	// Publish before caching, so a failed upload is retried next run
+	if result.OutputsErr == nil && result.InputsErr == nil && result.FinallyErr == nil && len(task.Publish) > 0 {
+		result.Published, result.PublishErr = r.publishOutputs(ctx, execution)
+	}
+	for _, err := range []error{result.OutputsErr, result.InputsErr, result.FinallyErr, result.PublishErr} {
+		if err != nil {
+			return result, err
+		}
+	}
+
+	result.Status = StatusSuccess
+	if hooks.Succeeded != nil {
+		hooks.Succeeded(result)
+	}
+	if task.Cache && !r.NoCache {
+		result.State = r.save(ctx, execution, attempt)
+	}
+	return result, nil
+}
+
+// check decides whether execution runs, restoring its outputs from the
+// content-addressable store when they are there, and returns why it does
+// not.
+func (r *Runner) check(ctx context.Context, execution *workspace.TaskExecution) (*Check, Skip, error) {
+	task := execution.Task
+	key := execution.WorkspaceName + ":" + execution.TaskName
+	_, span := tracing.Start(ctx, "cache check")
+
+	check := &Check{Execution: execution}
+	if !r.SkipCache && !r.NoCache && task.Cache {
+		previous, err := r.Cache.Get(key)
+		if err != nil {
+			r.Log.Warnf("  Warning: failed to load cache: %v\n", err)
+		}
+		check.Previous = previous
+	}
+
+	// Tasks with depends_on_files run when their targets are out of date,
+	// whatever their cache says
+	check.Run = r.Force || r.SkipCache || r.NoCache
+	skip := SkipUnchanged
+	switch {
+	case !r.Force && len(task.DependsOnFiles) > 0:
+		stale, err := r.Tracker.StaleFileTargets(execution)
+		if err != nil {
+			span.End(err)
+			return nil, 0, fmt.Errorf("failed to check depends_on_files: %w", err)
+		}
+		check.Run = stale != ""
+		check.Stale = stale
+		skip = SkipUpToDate
+	case !check.Run:
+		run, err := r.Tracker.ShouldRunTask(execution, check.Previous)
+		if err != nil {
+			span.End(err)
+			return nil, 0, fmt.Errorf("failed to check if task should run: %w", err)
+		}
+		check.Run = run
+		if run && !r.DryRun && task.Cache && r.CAS != nil && r.restoreOutputs(execution) {
+			check.Run = false
+			skip = SkipRestored
+		}
+	}
+
+	span.SetAttributes(tracing.Bool("doctrus.cache_hit", !check.Run))
+	span.End(nil)
+	return check, skip, nil
+}
+
+// execute runs the command of execution until it succeeds or runs out of
+// attempts. Only the final attempt counts: its result is reported and
+// cached.
+func (r *Runner) execute(ctx context.Context, execution *workspace.TaskExecution, stdout, stderr io.Writer, retry func(*Attempt, time.Duration)) *Attempt {
+	task := execution.Task
+	key := execution.WorkspaceName + ":" + execution.TaskName
+	attempt := &Attempt{Max: task.MaxAttempts()}
+	for attempt.Number = 1; ; attempt.Number++ {
+		taskCtx, stop := r.Tasks.Start(ctx, key, r.TaskTimeout(task))
+		attempt.Start = time.Now()
+		attempt.Result = r.Executor.Execute(taskCtx, execution, stdout, stderr)
+		attempt.Duration = time.Since(attempt.Start)
+		stop()
+		if !shouldRetry(ctx, attempt) {
+			return attempt
+		}
+
+		delay := task.RetryDelay(attempt.Number + 1)
+		if retry != nil {
+			retry(attempt, delay)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+	}
+}
+
+// shouldRetry reports whether a task whose command ended with attempt runs
+// again: it failed, has attempts left, and neither the run nor the task was
+// cancelled. Tasks that timed out are retried.
+func shouldRetry(ctx context.Context, attempt *Attempt) bool {
+	if attempt.Number >= attempt.Max || ctx.Err() != nil {
+		return false
+	}
+	result := attempt.Result
+	if result.Error == nil && result.ExitCode == 0 {
+		return false
+	}
+	var timeoutErr *docker.TimeoutError
+	return result.Cause == nil || errors.As(result.Cause, &timeoutErr)
+}
+
+// TaskTimeout returns how long a task may run: its own timeout, or the
+// runner's for tasks without one. Zero means no limit.
+func (r *Runner) TaskTimeout(task *config.Task) time.Duration {
+	if timeout := task.TimeoutDuration(); timeout > 0 {
+		return timeout
+	}
+	return r.Timeout
+}
+
+// missingOutputs returns the output patterns of a finished task that matched
+// no files, so wrong paths don't silently defeat caching.
+func (r *Runner) missingOutputs(execution *workspace.TaskExecution) []string {
+	if len(execution.Task.Outputs) == 0 {
+		return nil
+	}
+	missing, err := r.Tracker.MissingOutputs(execution)
+	if err != nil {
+		r.Log.Warnf("  Warning: failed to check outputs: %v\n", err)
+		return nil
+	}
+	return missing
+}
+
+// save caches the state of a task that succeeded, stores its outputs and
+// output and records its provenance. Failing to cache only warns.
+func (r *Runner) save(ctx context.Context, execution *workspace.TaskExecution, attempt *Attempt) *deps.TaskState {
+	key := execution.WorkspaceName + ":" + execution.TaskName
+	// An image pulled or a container started by the run can be inspected
+	// now
+	if execution.ImageDigest == "" {
+		r.resolveImageDigest(ctx, execution)
+	}
+	_, span := tracing.Start(ctx, "hash")
+	state, err := r.Tracker.ComputeTaskState(execution, true)
+	span.End(err)
+	if err != nil {
+		r.Log.Warnf("  Warning: failed to compute task state: %v\n", err)
+		return nil
+	}
+	if err := r.Cache.Set(key, state, 0); err != nil {
+		r.Log.Warnf("  Warning: failed to cache task state: %v\n", err)
+		return nil
+	}
+
+	if r.CAS != nil && len(state.Outputs) > 0 {
+		r.storeOutputs(execution, state)
+	}
+	if result := attempt.Result; result.Stdout != "" || result.Stderr != "" {
+		if err := r.Cache.SetLogs(key, &cache.TaskLogs{Stdout: result.Stdout, Stderr: result.Stderr}); err != nil {
+			r.Log.Warnf("  Warning: failed to cache task output: %v\n", err)
+		}
+	}
+	if r.Config.ProvenanceEnabled() {
+		r.recordProvenance(ctx, execution, state, attempt.Start, attempt.Start.Add(attempt.Duration))
+	}
+	if r.Remote != nil && !r.Config.RemoteCache().ReadOnly {
+		r.uploadToRemote(ctx, execution, state, attempt.Duration)
+	}
+	return state
+}
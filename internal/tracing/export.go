@@ -0,0 +1,261 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exporter sends spans to an OTLP/HTTP traces endpoint as JSON.
+type Exporter struct {
+	endpoint string
+	headers  map[string]string
+	resource []Attribute
+	version  string
+	client   *http.Client
+}
+
+// NewExporter creates an exporter posting to the traces endpoint, such as
+// http://localhost:4318/v1/traces, with the given headers. service and
+// version describe doctrus in the exported resource.
+func NewExporter(endpoint string, headers map[string]string, service, version string) *Exporter {
+	return &Exporter{
+		endpoint: endpoint,
+		headers:  headers,
+		resource: []Attribute{String("service.name", service), String("service.version", version)},
+		version:  version,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// FromEnv configures an exporter from the standard OpenTelemetry
+// environment variables, returning nil when tracing is off: when no OTLP
+// endpoint is set, OTEL_TRACES_EXPORTER is none or OTEL_SDK_DISABLED is
+// true. Only the http/json and http/protobuf protocols are accepted, and
+// both are sent as JSON, which OTLP/HTTP collectors accept on the same
+// endpoint.
+func FromEnv(version string) (*Exporter, error) {
+	if disabled, _ := strconv.ParseBool(os.Getenv("OTEL_SDK_DISABLED")); disabled {
+		return nil, nil
+	}
+	if exporter := os.Getenv("OTEL_TRACES_EXPORTER"); exporter != "" && exporter != "otlp" {
+		if exporter == "none" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q (expected otlp or none)", exporter)
+	}
+
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" {
+			return nil, nil
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+	if _, err := url.ParseRequestURI(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: %w", endpoint, err)
+	}
+
+	protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+	if protocol == "" {
+		protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+	switch protocol {
+	case "", "http/json", "http/protobuf":
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q (doctrus exports over http/json)", protocol)
+	}
+
+	headers, err := parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	if err != nil {
+		return nil, err
+	}
+	traceHeaders, err := parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS"))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range traceHeaders {
+		headers[key] = value
+	}
+
+	service := os.Getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = "doctrus"
+	}
+	exporter := NewExporter(endpoint, headers, service, version)
+	resource, err := parseHeaders(os.Getenv("OTEL_RESOURCE_ATTRIBUTES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTEL_RESOURCE_ATTRIBUTES: %w", err)
+	}
+	for key, value := range resource {
+		if key != "service.name" {
+			exporter.resource = append(exporter.resource, String(key, value))
+		}
+	}
+	return exporter, nil
+}
+
+// parseHeaders parses a list of key=value pairs separated by commas, with
+// URL-encoded values, as in OTEL_EXPORTER_OTLP_HEADERS.
+func parseHeaders(value string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid key=value pair %q", pair)
+		}
+		decoded, err := url.QueryUnescape(strings.TrimSpace(val))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s: %w", key, err)
+		}
+		headers[key] = decoded
+	}
+	return headers, nil
+}
+
+// Export posts spans to the endpoint.
+func (e *Exporter) Export(ctx context.Context, spans []*Span) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("failed to export spans: %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// The OTLP/JSON encoding of an ExportTraceServiceRequest, leaving out what
+// doctrus does not use.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		BoolValue   *bool   `json:"boolValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+	}
+)
+
+// Span kind and status codes of OTLP
+const (
+	spanKindInternal = 1
+	statusCodeError  = 2
+)
+
+func (e *Exporter) request(spans []*Span) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		span.mu.Lock()
+		s := otlpSpan{
+			TraceID:           span.traceID,
+			SpanID:            span.spanID,
+			ParentSpanID:      span.parent,
+			Name:              span.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        attributes(span.attrs),
+		}
+		if span.failed {
+			s.Status = otlpStatus{Code: statusCodeError, Message: span.err}
+		}
+		span.mu.Unlock()
+		encoded = append(encoded, s)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: attributes(e.resource)},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "doctrus", Version: e.version},
+			Spans: encoded,
+		}},
+	}}}
+}
+
+func attributes(attrs []Attribute) []otlpAttribute {
+	encoded := make([]otlpAttribute, 0, len(attrs))
+	for _, attr := range attrs {
+		var value otlpValue
+		switch v := attr.Value.(type) {
+		case string:
+			value.StringValue = &v
+		case bool:
+			value.BoolValue = &v
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case int:
+			s := strconv.Itoa(v)
+			value.IntValue = &s
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		encoded = append(encoded, otlpAttribute{Key: attr.Key, Value: value})
+	}
+	return encoded
+}
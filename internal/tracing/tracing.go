@@ -0,0 +1,221 @@
+// Package tracing records spans of a doctrus run and exports them to an
+// OpenTelemetry collector over OTLP/HTTP with JSON encoding, configured
+// through the standard OTEL_* environment variables.
+//
+// Spans are kept in memory and exported together when the tracer shuts
+// down at the end of the run. Every function accepts a nil tracer or span
+// and then does nothing, so code paths run the same with tracing off.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Attribute is a key and value attached to a span. Values are strings,
+// booleans, ints or int64s.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Tracer collects the spans of a run for its exporter.
+type Tracer struct {
+	exporter *Exporter
+	// parent is the trace and span the run continues, from TRACEPARENT
+	parent spanContext
+
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// NewTracer creates a tracer exporting through exporter. A nil exporter
+// returns a nil tracer, which records nothing.
+func NewTracer(exporter *Exporter) *Tracer {
+	if exporter == nil {
+		return nil
+	}
+	return &Tracer{exporter: exporter}
+}
+
+// Continue makes the spans started without a parent children of the span
+// in a W3C traceparent header, such as the TRACEPARENT of a CI job. An
+// invalid header is ignored.
+func (t *Tracer) Continue(traceparent string) {
+	if t == nil {
+		return
+	}
+	if parent, ok := parseTraceparent(traceparent); ok {
+		t.parent = parent
+	}
+}
+
+// Start starts a span without a parent in ctx, returning a context that
+// makes it the parent of spans started from it.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	span := t.newSpan(name, t.parent, attrs)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Shutdown exports the finished spans.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+	return t.exporter.Export(ctx, spans)
+}
+
+func (t *Tracer) newSpan(name string, parent spanContext, attrs []Attribute) *Span {
+	span := &Span{
+		tracer: t,
+		name:   name,
+		parent: parent.spanID,
+		start:  time.Now(),
+		attrs:  attrs,
+	}
+	span.traceID = parent.traceID
+	if span.traceID == "" {
+		span.traceID = randomHex(16)
+	}
+	span.spanID = randomHex(8)
+	return span
+}
+
+type spanKey struct{}
+
+// Start starts a child of the span in ctx. Without a span in ctx, tracing
+// is off and the returned span is nil.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	span := parent.tracer.newSpan(name, spanContext{traceID: parent.traceID, spanID: parent.spanID}, attrs)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// FromContext returns the span started last in ctx, or nil.
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Span is an operation of the run, such as running a task.
+type Span struct {
+	tracer  *Tracer
+	name    string
+	traceID string
+	spanID  string
+	parent  string
+	start   time.Time
+
+	mu      sync.Mutex
+	end     time.Time
+	attrs   []Attribute
+	err     string
+	failed  bool
+	stopped bool
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// End finishes the span, marking it failed when err is not nil. Only the
+// first call counts.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.stopped = true
+	s.end = time.Now()
+	if err != nil {
+		s.failed = true
+		s.err = err.Error()
+	}
+	s.mu.Unlock()
+
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.spans = append(s.tracer.spans, s)
+}
+
+// Traceparent returns the W3C traceparent header naming the span, which
+// lets the commands a task runs join the trace.
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", s.traceID, s.spanID)
+}
+
+// spanContext identifies a span another span may be a child of.
+type spanContext struct {
+	traceID string
+	spanID  string
+}
+
+// parseTraceparent parses a W3C traceparent header, such as
+// 00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01.
+func parseTraceparent(value string) (spanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return spanContext{}, false
+	}
+	for _, part := range parts {
+		if _, err := hex.DecodeString(part); err != nil {
+			return spanContext{}, false
+		}
+	}
+	if parts[0] == "ff" || parts[1] == strings.Repeat("0", 32) || parts[2] == strings.Repeat("0", 16) {
+		return spanContext{}, false
+	}
+	return spanContext{traceID: strings.ToLower(parts[1]), spanID: strings.ToLower(parts[2])}, true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
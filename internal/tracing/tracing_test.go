@@ -0,0 +1,141 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantEndpoint string
+		wantErr      string
+	}{
+		{name: "no endpoint"},
+		{name: "base endpoint", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318/"}, wantEndpoint: "http://collector:4318/v1/traces"},
+		{name: "traces endpoint wins", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://a:4318", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://b:4318/traces"}, wantEndpoint: "http://b:4318/traces"},
+		{name: "disabled", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://a:4318", "OTEL_SDK_DISABLED": "true"}},
+		{name: "exporter none", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://a:4318", "OTEL_TRACES_EXPORTER": "none"}},
+		{name: "grpc", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://a:4317", "OTEL_EXPORTER_OTLP_PROTOCOL": "grpc"}, wantErr: "unsupported OTLP protocol"},
+		{name: "invalid headers", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://a:4318", "OTEL_EXPORTER_OTLP_HEADERS": "token"}, wantErr: "invalid key=value pair"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"OTEL_SDK_DISABLED", "OTEL_TRACES_EXPORTER", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_EXPORTER_OTLP_PROTOCOL", "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TRACES_HEADERS", "OTEL_SERVICE_NAME", "OTEL_RESOURCE_ATTRIBUTES"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			exporter, err := FromEnv("1.0.0")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("FromEnv() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FromEnv() error = %v", err)
+			}
+			if tt.wantEndpoint == "" {
+				if exporter != nil {
+					t.Fatalf("expected tracing off, got endpoint %s", exporter.endpoint)
+				}
+				return
+			}
+			if exporter == nil || exporter.endpoint != tt.wantEndpoint {
+				t.Fatalf("exporter = %+v, want endpoint %s", exporter, tt.wantEndpoint)
+			}
+		})
+	}
+}
+
+func TestExportSpans(t *testing.T) {
+	var body []byte
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	tracer := NewTracer(NewExporter(server.URL+"/v1/traces", map[string]string{"Authorization": "Bearer secret"}, "doctrus", "1.0.0"))
+	tracer.Continue("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	ctx, run := tracer.Start(context.Background(), "doctrus run")
+	_, task := Start(ctx, "app:build", String("doctrus.task", "build"), Int("doctrus.exit_code", 2), Bool("doctrus.cache_hit", false))
+	task.End(errors.New("exit code 2"))
+	run.End(nil)
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if auth != "Bearer secret" {
+		t.Fatalf("Authorization = %q", auth)
+	}
+
+	var request otlpRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		t.Fatalf("invalid request %s: %v", body, err)
+	}
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %s", body)
+	}
+	taskSpan, runSpan := spans[0], spans[1]
+	if runSpan.TraceID != "0af7651916cd43dd8448eb211c80319c" || runSpan.ParentSpanID != "b7ad6b7169203331" {
+		t.Fatalf("run span does not continue TRACEPARENT: %+v", runSpan)
+	}
+	if taskSpan.TraceID != runSpan.TraceID || taskSpan.ParentSpanID != runSpan.SpanID {
+		t.Fatalf("task span is not a child of the run span: %+v", taskSpan)
+	}
+	if taskSpan.Status.Code != statusCodeError || taskSpan.Status.Message != "exit code 2" {
+		t.Fatalf("task span status = %+v", taskSpan.Status)
+	}
+	for _, want := range []string{`"stringValue":"build"`, `"intValue":"2"`, `"boolValue":false`, `"key":"service.name"`} {
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("request misses %s: %s", want, body)
+		}
+	}
+}
+
+func TestTracingOff(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "doctrus run")
+	if span != nil || FromContext(ctx) != nil {
+		t.Fatal("expected no span without a tracer")
+	}
+	_, child := Start(ctx, "exec")
+	child.SetAttributes(String("a", "b"))
+	child.End(nil)
+	if child.Traceparent() != "" {
+		t.Fatal("expected no traceparent without a span")
+	}
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		value string
+		ok    bool
+	}{
+		{value: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", ok: true},
+		{value: "00-00000000000000000000000000000000-b7ad6b7169203331-01"},
+		{value: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b71692033-01"},
+		{value: "garbage"},
+		{value: ""},
+	}
+	for _, tt := range tests {
+		if _, ok := parseTraceparent(tt.value); ok != tt.ok {
+			t.Errorf("parseTraceparent(%q) ok = %v, want %v", tt.value, ok, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"io"
+	"os"
+)
+
+// restoreSequence resets colors and shows the cursor.
+const restoreSequence = "\033[0m\033[?25h"
+
+// TerminalState remembers the mode of the controlling terminal so it can be
+// put back when a task, or doctrus itself, dies while the terminal is in raw
+// mode, has its cursor hidden or still has colors set. A nil TerminalState
+// ignores Restore.
+type TerminalState struct {
+	in   *os.File
+	mode *terminalMode
+	out  io.Writer
+}
+
+// SaveTerminal records the current mode of in and remembers out for the
+// escape sequences that undo cursor and color changes. Files that are not
+// terminals are left alone by Restore.
+func SaveTerminal(in, out *os.File) *TerminalState {
+	state := &TerminalState{}
+	if IsTerminal(in) {
+		if mode, ok := getTerminalMode(in); ok {
+			state.in = in
+			state.mode = mode
+		}
+	}
+	if IsTerminal(out) {
+		state.out = out
+	}
+	return state
+}
+
+// Restore puts the terminal back into the saved mode, resets colors and shows
+// the cursor.
+func (s *TerminalState) Restore() {
+	if s == nil {
+		return
+	}
+	if s.mode != nil {
+		setTerminalMode(s.in, s.mode)
+	}
+	if s.out != nil {
+		_, _ = io.WriteString(s.out, restoreSequence)
+	}
+}
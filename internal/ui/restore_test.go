@@ -0,0 +1,35 @@
+package ui
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestSaveTerminalIgnoresRedirectedFiles(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	state := SaveTerminal(r, w)
+	if state.mode != nil || state.out != nil {
+		t.Fatalf("SaveTerminal() = %+v, want nothing to restore for pipes", state)
+	}
+	state.Restore()
+
+	var nilState *TerminalState
+	nilState.Restore()
+}
+
+func TestRestoreResetsColorsAndCursor(t *testing.T) {
+	var buf bytes.Buffer
+	state := &TerminalState{out: &buf}
+
+	state.Restore()
+	if got := buf.String(); got != restoreSequence {
+		t.Fatalf("Restore() wrote %q, want %q", got, restoreSequence)
+	}
+}
@@ -0,0 +1,259 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	clearLine = "\r\033[K"
+	// clearLineAbove moves to the line above and clears it
+	clearLineAbove = "\033[A\033[K"
+)
+
+// maxExpandedRows bounds the lines an expanded status line draws, so it
+// fits on small terminals; the rest is counted on the last line.
+const maxExpandedRows = 8
+
+// StatusLine is a redrawable line kept below the regular output of an
+// interactive terminal. It animates a spinner next to the work in progress and
+// is erased whenever other output is written through it, then redrawn on the
+// next tick. It must only be used when the terminal is live; a nil StatusLine
+// ignores all calls.
+//
+// Once expanded, it draws a line for every label in progress instead, with
+// how long it has been in progress and the latest line written to its
+// Detail writer.
+type StatusLine struct {
+	out      io.Writer
+	mu       *sync.Mutex
+	frames   []string
+	width    int
+	interval time.Duration
+
+	active      []string
+	frame       int
+	drawn       int
+	atLineStart bool
+	stop        chan struct{}
+
+	expanded bool
+	started  map[string]time.Time
+	details  map[string]string
+}
+
+// NewStatusLine creates a status line drawing to out. mu must be the mutex
+// that serializes every write to out.
+func NewStatusLine(out io.Writer, mu *sync.Mutex, frames []string, width int) *StatusLine {
+	if len(frames) == 0 {
+		frames = unicodeFrames
+	}
+	return &StatusLine{
+		out:         out,
+		mu:          mu,
+		frames:      frames,
+		width:       width,
+		interval:    100 * time.Millisecond,
+		atLineStart: true,
+		started:     make(map[string]time.Time),
+		details:     make(map[string]string),
+	}
+}
+
+// Expand switches to a line per label in progress.
+func (l *StatusLine) Expand() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expanded = true
+}
+
+// Detail returns a writer whose latest non-blank line is shown next to label
+// while it is in progress and the status line is expanded, such as the
+// output of a running task.
+func (l *StatusLine) Detail(label string) io.Writer {
+	return &detailWriter{line: l, label: label}
+}
+
+// Write erases the status line and passes p through to the underlying writer.
+// Callers must hold the status line's mutex.
+func (l *StatusLine) Write(p []byte) (int, error) {
+	if err := l.erase(); err != nil {
+		return 0, err
+	}
+	n, err := l.out.Write(p)
+	if n > 0 {
+		l.atLineStart = p[n-1] == '\n'
+	}
+	return n, err
+}
+
+// Start shows label as in progress until Done is called with the same label.
+func (l *StatusLine) Start(label string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active = append(l.active, label)
+	l.started[label] = time.Now()
+	if l.stop == nil {
+		l.stop = make(chan struct{})
+		go l.animate(l.stop)
+	}
+	l.draw()
+}
+
+// Done removes label from the status line, erasing it when nothing is left in
+// progress.
+func (l *StatusLine) Done(label string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, active := range l.active {
+		if active == label {
+			l.active = append(l.active[:i], l.active[i+1:]...)
+			break
+		}
+	}
+	if !slices.Contains(l.active, label) {
+		delete(l.started, label)
+		delete(l.details, label)
+	}
+	if len(l.active) == 0 {
+		l.stopLocked()
+		return
+	}
+	l.draw()
+}
+
+// Close erases the status line and stops the animation.
+func (l *StatusLine) Close() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active = nil
+	l.stopLocked()
+}
+
+func (l *StatusLine) stopLocked() {
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+	_ = l.erase()
+}
+
+// erase clears the lines drawn last, leaving the cursor at the start of
+// the first one.
+func (l *StatusLine) erase() error {
+	if l.drawn == 0 {
+		return nil
+	}
+	if _, err := io.WriteString(l.out, clearLine+strings.Repeat(clearLineAbove, l.drawn-1)); err != nil {
+		return err
+	}
+	l.drawn = 0
+	return nil
+}
+
+func (l *StatusLine) animate(stop chan struct{}) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			l.frame++
+			l.draw()
+			l.mu.Unlock()
+		}
+	}
+}
+
+// draw renders the line; it never draws over a partially written line.
+func (l *StatusLine) draw() {
+	if len(l.active) == 0 || !l.atLineStart {
+		return
+	}
+	spinner := l.frames[l.frame%len(l.frames)]
+	var lines []string
+	if l.expanded {
+		for i, label := range l.active {
+			if i == maxExpandedRows-1 && len(l.active) > maxExpandedRows {
+				lines = append(lines, fmt.Sprintf("  … %d more", len(l.active)-i))
+				break
+			}
+			line := fmt.Sprintf("%s %s  %s", spinner, label, time.Since(l.started[label]).Truncate(time.Second))
+			if detail := l.details[label]; detail != "" {
+				line += "  " + detail
+			}
+			lines = append(lines, Truncate(line, l.width))
+		}
+	} else {
+		text := l.active[0]
+		if len(l.active) > 1 {
+			text = fmt.Sprintf("%s (+%d more)", text, len(l.active)-1)
+		}
+		lines = []string{Truncate(spinner+" "+text, l.width)}
+	}
+
+	// Clear the lines above the last one drawn, then write over the first
+	erase := ""
+	if l.drawn > 1 {
+		erase = clearLine + strings.Repeat(clearLineAbove, l.drawn-1)
+	}
+	if _, err := io.WriteString(l.out, erase+clearLine+strings.Join(lines, "\n")); err == nil {
+		l.drawn = len(lines)
+	}
+}
+
+// detailWriter keeps the latest non-blank line written to it as the detail
+// of a label.
+type detailWriter struct {
+	line    *StatusLine
+	label   string
+	partial []byte
+}
+
+func (w *detailWriter) Write(p []byte) (int, error) {
+	if w.line == nil {
+		return len(p), nil
+	}
+	w.partial = append(w.partial, p...)
+	latest := ""
+	for {
+		i := strings.IndexAny(string(w.partial), "\r\n")
+		if i < 0 {
+			break
+		}
+		if line := strings.TrimSpace(StripEscapes(string(w.partial[:i]))); line != "" {
+			latest = line
+		}
+		w.partial = w.partial[i+1:]
+	}
+	if latest == "" {
+		return len(p), nil
+	}
+
+	w.line.mu.Lock()
+	defer w.line.mu.Unlock()
+	if _, ok := w.line.started[w.label]; ok {
+		w.line.details[w.label] = latest
+	}
+	return len(p), nil
+}
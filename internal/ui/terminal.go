@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Terminal describes how output to a stream should be laid out. The zero
+// value means plain, append-only output with no width limit, which is what
+// redirected output gets.
+type Terminal struct {
+	// Live allows redrawing the current line (spinners, status updates)
+	Live bool
+	// Width is the number of columns, or 0 when unknown
+	Width int
+}
+
+// DetectTerminal inspects f and the environment. Redirected output is never
+// truncated or redrawn; on a terminal, live output is used unless TERM=dumb or
+// CI is set, and COLUMNS overrides the detected width.
+func DetectTerminal(f *os.File) Terminal {
+	var term Terminal
+	if !IsTerminal(f) {
+		return term
+	}
+
+	term.Live = os.Getenv("TERM") != "dumb" && os.Getenv("CI") == ""
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		term.Width = columns
+	} else {
+		term.Width = terminalWidth(f)
+	}
+
+	return term
+}
+
+// Fit truncates text to the terminal width, leaving ANSI sequences intact.
+func (t Terminal) Fit(text string) string {
+	return Truncate(text, t.Width)
+}
+
+// Truncate shortens text so that at most width columns are visible, ending it
+// with an ellipsis. Escape sequences do not count towards the width and are
+// preserved so styles are still terminated. A width of 0 or less disables
+// truncation.
+func Truncate(text string, width int) string {
+	if width <= 0 || VisibleWidth(text) <= width {
+		return text
+	}
+
+	var b strings.Builder
+	visible := 0
+	truncated := false
+	for i := 0; i < len(text); {
+		if seq := escapeSequence(text[i:]); seq != "" {
+			b.WriteString(seq)
+			i += len(seq)
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if truncated {
+			continue
+		}
+		if visible == width-1 {
+			b.WriteString("…")
+			truncated = true
+			continue
+		}
+		b.WriteRune(r)
+		visible++
+	}
+
+	return b.String()
+}
+
+// VisibleWidth returns the number of runes in text excluding ANSI sequences.
+func VisibleWidth(text string) int {
+	width := 0
+	for i := 0; i < len(text); {
+		if seq := escapeSequence(text[i:]); seq != "" {
+			i += len(seq)
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		width++
+	}
+	return width
+}
+
+// StripEscapes removes the ANSI sequences from text, for output that is
+// kept in files or read by tools rather than shown on a terminal.
+func StripEscapes(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		if seq := escapeSequence(text[i:]); seq != "" {
+			i += len(seq)
+			continue
+		}
+		b.WriteByte(text[i])
+		i++
+	}
+	return b.String()
+}
+
+// escapeSequence returns the CSI sequence at the start of s, if any.
+func escapeSequence(s string) string {
+	if len(s) < 2 || s[0] != '\033' || s[1] != '[' {
+		return ""
+	}
+	for i := 2; i < len(s); i++ {
+		if c := s[i]; c >= 0x40 && c <= 0x7e {
+			return s[:i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,19 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package ui
+
+import "os"
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalWidth is unknown on platforms without TIOCGWINSZ; COLUMNS can still
+// provide it.
+func terminalWidth(f *os.File) int {
+	return 0
+}
@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  string
+	}{
+		{name: "no limit", text: "Running app:build", width: 0, want: "Running app:build"},
+		{name: "fits", text: "Running app:build", width: 17, want: "Running app:build"},
+		{name: "truncated", text: "Running app:build", width: 10, want: "Running a…"},
+		{name: "multibyte", text: "▶ Running", width: 5, want: "▶ Ru…"},
+		{name: "keeps escapes", text: green + "✓ Executed successfully" + reset, width: 6, want: green + "✓ Exe…" + reset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Truncate(tt.text, tt.width); got != tt.want {
+				t.Fatalf("Truncate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectTerminalPiped(t *testing.T) {
+	t.Setenv("COLUMNS", "40")
+	if term := DetectTerminal(nil); term.Live || term.Width != 0 {
+		t.Fatalf("expected plain output for a non-terminal, got %+v", term)
+	}
+}
+
+func TestStatusLineErasedBeforeOutput(t *testing.T) {
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	line := NewStatusLine(&buf, &mu, []string{"-"}, 0)
+
+	line.Start("Running app:build")
+	mu.Lock()
+	_, _ = line.Write([]byte("hello\n"))
+	mu.Unlock()
+	line.Done("Running app:build")
+
+	want := clearLine + "- Running app:build" + clearLine + "hello\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestStatusLineWaitsForLineStart(t *testing.T) {
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	line := NewStatusLine(&buf, &mu, []string{"-"}, 0)
+
+	mu.Lock()
+	_, _ = line.Write([]byte("partial"))
+	mu.Unlock()
+	line.Start("Running app:build")
+	line.Close()
+
+	if strings.Contains(buf.String(), "Running") {
+		t.Fatalf("status line drawn over a partial line: %q", buf.String())
+	}
+}
+
+func TestNilStatusLine(t *testing.T) {
+	var line *StatusLine
+	line.Start("x")
+	line.Done("x")
+	line.Close()
+}
+
+func TestExpandedStatusLine(t *testing.T) {
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	line := NewStatusLine(&buf, &mu, []string{"-"}, 0)
+	line.Expand()
+
+	line.Start("app:build")
+	_, _ = line.Detail("app:build").Write([]byte("compiling\n\033[32mlinking\033[0m\npartial"))
+	line.Start("app:test")
+
+	mu.Lock()
+	buf.Reset()
+	line.draw()
+	mu.Unlock()
+	want := clearLine + clearLineAbove + clearLine + "- app:build  0s  linking\n- app:test  0s"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	line.Done("app:build")
+	if got, want := buf.String(), clearLine+clearLineAbove+clearLine+"- app:test  0s"; got != want {
+		t.Fatalf("output after Done = %q, want %q", got, want)
+	}
+	line.Close()
+}
@@ -0,0 +1,37 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package ui
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+type winsize struct {
+	rows    uint16
+	columns uint16
+	xpixel  uint16
+	ypixel  uint16
+}
+
+func getWinsize(f *os.File) (winsize, bool) {
+	var ws winsize
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	return ws, errno == 0
+}
+
+// isTerminal asks the terminal driver rather than trusting the file mode, as
+// character devices such as /dev/null are not terminals.
+func isTerminal(f *os.File) bool {
+	_, ok := getWinsize(f)
+	return ok
+}
+
+func terminalWidth(f *os.File) int {
+	ws, ok := getWinsize(f)
+	if !ok {
+		return 0
+	}
+	return int(ws.columns)
+}
@@ -0,0 +1,19 @@
+package ui
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+type terminalMode = syscall.Termios
+
+func getTerminalMode(f *os.File) (*terminalMode, bool) {
+	var mode terminalMode
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TCGETS), uintptr(unsafe.Pointer(&mode)))
+	return &mode, errno == 0
+}
+
+func setTerminalMode(f *os.File, mode *terminalMode) {
+	_, _, _ = syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TCSETS), uintptr(unsafe.Pointer(mode)))
+}
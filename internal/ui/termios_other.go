@@ -0,0 +1,15 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package ui
+
+import "os"
+
+// terminalMode is empty where doctrus cannot read the terminal mode; Restore
+// still resets colors and the cursor.
+type terminalMode struct{}
+
+func getTerminalMode(f *os.File) (*terminalMode, bool) {
+	return nil, false
+}
+
+func setTerminalMode(f *os.File, mode *terminalMode) {}
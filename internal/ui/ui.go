@@ -0,0 +1,179 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	reset  = "\033[0m"
+	bold   = "\033[1m"
+	dim    = "\033[2m"
+	red    = "\033[31m"
+	green  = "\033[32m"
+	yellow = "\033[33m"
+	blue   = "\033[34m"
+	cyan   = "\033[36m"
+)
+
+// Kind identifies a class of status line so themes can style it.
+type Kind int
+
+const (
+	KindHeader Kind = iota
+	KindSuccess
+	KindFailure
+	KindCached
+	KindWarning
+	KindInfo
+)
+
+// Theme maps each status kind to a symbol and an ANSI style.
+type Theme struct {
+	Name    string
+	Symbols map[Kind]string
+	Colors  map[Kind]string
+	Frames  []string
+}
+
+var unicodeSymbols = map[Kind]string{
+	KindHeader:  "▶",
+	KindSuccess: "✓",
+	KindFailure: "✗",
+	KindCached:  "✓",
+	KindWarning: "⚠️ ",
+	KindInfo:    "ℹ",
+}
+
+var asciiSymbols = map[Kind]string{
+	KindHeader:  "==>",
+	KindSuccess: "[ok]",
+	KindFailure: "[fail]",
+	KindCached:  "[cached]",
+	KindWarning: "[warn]",
+	KindInfo:    "[info]",
+}
+
+var unicodeFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+var asciiFrames = []string{"|", "/", "-", "\\"}
+
+var defaultColors = map[Kind]string{
+	KindHeader:  bold + cyan,
+	KindSuccess: green,
+	KindFailure: bold + red,
+	KindCached:  blue,
+	KindWarning: yellow,
+	KindInfo:    dim,
+}
+
+var themes = map[string]Theme{
+	"default": {Name: "default", Symbols: unicodeSymbols, Colors: defaultColors, Frames: unicodeFrames},
+	"mono":    {Name: "mono", Symbols: unicodeSymbols, Frames: unicodeFrames},
+	"ascii":   {Name: "ascii", Symbols: asciiSymbols, Colors: defaultColors, Frames: asciiFrames},
+}
+
+// ThemeNames returns the names of the built-in themes.
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Styler renders status lines using a theme. A nil Styler renders the
+// default symbols without colors.
+type Styler struct {
+	theme Theme
+	color bool
+}
+
+// New returns a Styler for the named theme. An empty name selects the default theme.
+func New(themeName string, color bool) (*Styler, error) {
+	if themeName == "" {
+		themeName = "default"
+	}
+	theme, ok := themes[themeName]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q (available: %s)", themeName, strings.Join(ThemeNames(), ", "))
+	}
+	return &Styler{theme: theme, color: color}, nil
+}
+
+// Color reports whether ANSI escape sequences are emitted.
+func (s *Styler) Color() bool {
+	return s != nil && s.color
+}
+
+// Symbol returns the theme's symbol for kind.
+func (s *Styler) Symbol(kind Kind) string {
+	if s == nil {
+		return unicodeSymbols[kind]
+	}
+	return s.theme.Symbols[kind]
+}
+
+// Frames returns the theme's spinner animation frames.
+func (s *Styler) Frames() []string {
+	if s == nil {
+		return unicodeFrames
+	}
+	return s.theme.Frames
+}
+
+// Status renders "<symbol> <text>" styled for kind.
+func (s *Styler) Status(kind Kind, text string) string {
+	return s.Paint(kind, s.Symbol(kind)+" "+text)
+}
+
+// Paint wraps text in the theme's style for kind when colors are enabled.
+func (s *Styler) Paint(kind Kind, text string) string {
+	if !s.Color() {
+		return text
+	}
+	style := s.theme.Colors[kind]
+	if style == "" {
+		return text
+	}
+	return style + text + reset
+}
+
+// Reset returns the sequence restoring default terminal colors, or an empty
+// string when colors are disabled.
+func (s *Styler) Reset() string {
+	if !s.Color() {
+		return ""
+	}
+	return reset
+}
+
+// ColorEnabled decides whether output to f should be colored. NO_COLOR and
+// the --no-color flag always disable colors; FORCE_COLOR enables them even
+// when f is not a terminal.
+func ColorEnabled(noColor bool, f *os.File) bool {
+	if noColor {
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force := os.Getenv("FORCE_COLOR"); force != "" && force != "0" {
+		return true
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return IsTerminal(f)
+}
+
+// IsTerminal reports whether f is connected to a terminal.
+func IsTerminal(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	return isTerminal(f)
+}
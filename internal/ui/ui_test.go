@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStylerStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		theme string
+		color bool
+		kind  Kind
+		want  string
+	}{
+		{name: "default without color", theme: "default", kind: KindSuccess, want: "✓ done"},
+		{name: "default with color", theme: "default", color: true, kind: KindFailure, want: bold + red + "✗ done" + reset},
+		{name: "ascii cached", theme: "ascii", kind: KindCached, want: "[cached] done"},
+		{name: "mono ignores color", theme: "mono", color: true, kind: KindHeader, want: "▶ done"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			styler, err := New(tt.theme, tt.color)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := styler.Status(tt.kind, "done"); got != tt.want {
+				t.Fatalf("Status() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilStyler(t *testing.T) {
+	var styler *Styler
+	if got := styler.Status(KindHeader, "Running app:build"); got != "▶ Running app:build" {
+		t.Fatalf("Status() = %q", got)
+	}
+	if styler.Reset() != "" {
+		t.Fatal("nil Styler should not emit escape sequences")
+	}
+}
+
+func TestNewUnknownTheme(t *testing.T) {
+	if _, err := New("neon", true); err == nil {
+		t.Fatal("New() expected error for unknown theme")
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	defer file.Close()
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "")
+
+	if ColorEnabled(false, file) {
+		t.Fatal("regular files should not be colored")
+	}
+
+	t.Setenv("FORCE_COLOR", "1")
+	if !ColorEnabled(false, file) {
+		t.Fatal("FORCE_COLOR should enable colors")
+	}
+	if ColorEnabled(true, file) {
+		t.Fatal("--no-color should win over FORCE_COLOR")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if ColorEnabled(false, file) {
+		t.Fatal("NO_COLOR should disable colors")
+	}
+}
@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"io"
+	"strings"
+)
+
+// Writer passes the output of a command on to where doctrus prints it. With
+// colors the command's ANSI sequences are kept and Flush resets the colors,
+// so a command that leaves a color set does not tint what follows. Without
+// colors, as when output is redirected or kept in a file, the sequences are
+// stripped; one split across writes is held back until it is complete.
+type Writer struct {
+	dest    io.Writer
+	color   bool
+	pending []byte
+}
+
+// NewWriter returns a Writer to dest, keeping ANSI sequences when color is
+// set.
+func NewWriter(dest io.Writer, color bool) *Writer {
+	return &Writer{dest: dest, color: color}
+}
+
+// Writer returns a Writer to dest that keeps colors when the styler emits
+// them.
+func (s *Styler) Writer(dest io.Writer) *Writer {
+	return NewWriter(dest, s.Color())
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.color {
+		if _, err := w.dest.Write(p); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	data := append(w.pending, p...)
+	end := len(data) - incompleteEscape(data)
+	w.pending = append([]byte(nil), data[end:]...)
+	if plain := StripEscapes(string(data[:end])); plain != "" {
+		if _, err := io.WriteString(w.dest, plain); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush drops an unfinished ANSI sequence, flushes dest if it buffers and
+// then resets the colors.
+func (w *Writer) Flush() error {
+	w.pending = nil
+	if flusher, ok := w.dest.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			return err
+		}
+	}
+	if !w.color {
+		return nil
+	}
+	_, err := io.WriteString(w.dest, reset)
+	return err
+}
+
+// incompleteEscape returns the length of the ANSI sequence data ends with
+// that is not complete yet, or 0.
+func incompleteEscape(data []byte) int {
+	i := strings.LastIndexByte(string(data), '\033')
+	if i < 0 {
+		return 0
+	}
+	tail := string(data[i:])
+	if tail == "\033" || (tail[1] == '[' && escapeSequence(tail) == "") {
+		return len(tail)
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		color  bool
+		writes []string
+		before string
+		want   string
+	}{
+		{
+			name:   "colors kept and reset on a terminal",
+			color:  true,
+			writes: []string{red + "FAIL" + reset + " app\n", yellow + "warn\n"},
+			before: red + "FAIL" + reset + " app\n" + yellow + "warn\n",
+			want:   red + "FAIL" + reset + " app\n" + yellow + "warn\n" + reset,
+		},
+		{
+			name:   "colors stripped elsewhere",
+			writes: []string{red + "FAIL" + reset + " app\n"},
+			before: "FAIL app\n",
+			want:   "FAIL app\n",
+		},
+		{
+			name:   "sequence split across writes",
+			writes: []string{"ok \033", "[1;3", "2mdone" + reset + "\n"},
+			before: "ok done\n",
+			want:   "ok done\n",
+		},
+		{
+			name:   "unfinished sequence dropped",
+			writes: []string{"progress 50%\033[2"},
+			before: "progress 50%",
+			want:   "progress 50%",
+		},
+		{
+			name:   "escape without a sequence kept",
+			writes: []string{"a\033(Bb\n"},
+			before: "a\033(Bb\n",
+			want:   "a\033(Bb\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, tt.color)
+			for _, chunk := range tt.writes {
+				if n, err := w.Write([]byte(chunk)); err != nil || n != len(chunk) {
+					t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+				}
+			}
+			if got := buf.String(); got != tt.before {
+				t.Fatalf("before Flush() got %q, want %q", got, tt.before)
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Fatalf("after Flush() got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
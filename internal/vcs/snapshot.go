@@ -0,0 +1,144 @@
+package vcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SnapshotDir is where snapshots are saved, relative to the project.
+const SnapshotDir = ".doctrus/snapshots"
+
+// skippedDirs are never part of a snapshot.
+var skippedDirs = map[string]bool{".doctrus": true, ".git": true, ".hg": true, ".jj": true}
+
+// Snapshot detects changes in projects outside version control by comparing
+// the size and modification time of every file with a snapshot saved
+// earlier under a name, which stands in for a revision.
+type Snapshot struct {
+	root string
+}
+
+// snapshotFile is the state of a file in a saved snapshot.
+type snapshotFile struct {
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mod_time"`
+}
+
+// NewSnapshot returns the snapshots of the project at root.
+func NewSnapshot(root string) *Snapshot {
+	return &Snapshot{root: root}
+}
+
+func (s *Snapshot) Name() string { return "snapshot" }
+func (s *Snapshot) Root() string { return s.root }
+
+// Revision returns "", as a project outside version control has none.
+func (s *Snapshot) Revision() (string, error) {
+	return "", nil
+}
+
+// Save records the current state of the project as the snapshot name,
+// replacing any saved before.
+func (s *Snapshot) Save(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	files, err := s.scan()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(files)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return nil
+}
+
+// ChangedFiles returns the files added, modified or deleted since the
+// snapshot since was saved.
+func (s *Snapshot) ChangedFiles(since string) ([]string, error) {
+	path, err := s.path(since)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no snapshot named %s", since)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var saved map[string]snapshotFile
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", since, err)
+	}
+
+	current, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	for rel, file := range current {
+		if previous, ok := saved[rel]; !ok || previous != file {
+			changed = append(changed, rel)
+		}
+	}
+	for rel := range saved {
+		if _, ok := current[rel]; !ok {
+			changed = append(changed, rel)
+		}
+	}
+	return absolute(s.root, changed), nil
+}
+
+func (s *Snapshot) path(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid snapshot name %q", name)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(SnapshotDir), name+".json"), nil
+}
+
+// scan returns the state of the project's regular files by slash-separated
+// path relative to the root.
+func (s *Snapshot) scan() (map[string]snapshotFile, error) {
+	files := make(map[string]snapshotFile)
+	err := filepath.WalkDir(s.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != s.root && skippedDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = snapshotFile{Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", s.root, err)
+	}
+	return files, nil
+}
@@ -0,0 +1,177 @@
+// Package vcs tells which files of a project changed since a revision, for
+// git, Mercurial and Jujutsu repositories, and through saved snapshots of the
+// file system for projects outside version control.
+package vcs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// VCS is the version control system of a project.
+type VCS interface {
+	// Name identifies the system: git, hg, jj or snapshot
+	Name() string
+	// Root is the directory paths are relative to, such as the top level
+	// of the repository
+	Root() string
+	// Revision returns the identifier of the checked out revision, or ""
+	// when the system has none
+	Revision() (string, error)
+	// ChangedFiles returns the absolute paths of the files added, modified
+	// or deleted since the revision since, sorted, including changes that
+	// are not committed yet
+	ChangedFiles(since string) ([]string, error)
+}
+
+// markers are the directories identifying a repository of each system.
+// Jujutsu comes first, as it may share its directory with a git repository.
+var markers = []struct {
+	dir     string
+	command string
+	open    func(root string) VCS
+}{
+	{".jj", "jj", func(root string) VCS { return &Jujutsu{root: root} }},
+	{".git", "git", func(root string) VCS { return &Git{root: root} }},
+	{".hg", "hg", func(root string) VCS { return &Mercurial{root: root} }},
+}
+
+// Detect returns the version control system of the repository containing
+// dir. Outside a repository, or when the system's command is not
+// installed, it returns a Snapshot of dir.
+func Detect(dir string) VCS {
+	for current := dir; ; {
+		for _, marker := range markers {
+			if _, err := os.Stat(filepath.Join(current, marker.dir)); err != nil {
+				continue
+			}
+			if _, err := exec.LookPath(marker.command); err == nil {
+				return marker.open(current)
+			}
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return NewSnapshot(dir)
+		}
+		current = parent
+	}
+}
+
+// Git is a git repository.
+type Git struct {
+	root string
+}
+
+func (g *Git) Name() string { return "git" }
+func (g *Git) Root() string { return g.root }
+
+func (g *Git) Revision() (string, error) {
+	output, err := run(g.root, nil, "git", "rev-parse", "HEAD")
+	return strings.TrimSpace(output), err
+}
+
+func (g *Git) ChangedFiles(since string) ([]string, error) {
+	changed, err := run(g.root, nil, "git", "diff", "--name-only", "--no-renames", "-z", since, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := run(g.root, nil, "git", "ls-files", "--others", "--exclude-standard", "--full-name", "-z")
+	if err != nil {
+		return nil, err
+	}
+	return absolute(g.root, splitNUL(changed+untracked)), nil
+}
+
+// Mercurial is a Mercurial repository.
+type Mercurial struct {
+	root string
+}
+
+// hgEnv keeps user configuration from changing hg's output.
+var hgEnv = []string{"HGPLAIN=1"}
+
+func (m *Mercurial) Name() string { return "hg" }
+func (m *Mercurial) Root() string { return m.root }
+
+func (m *Mercurial) Revision() (string, error) {
+	output, err := run(m.root, hgEnv, "hg", "log", "--rev", ".", "--template", "{node}")
+	return strings.TrimSpace(output), err
+}
+
+func (m *Mercurial) ChangedFiles(since string) ([]string, error) {
+	// Paths are printed relative to the working directory, which is the root
+	output, err := run(m.root, hgEnv, "hg", "status", "--rev", since, "--modified", "--added", "--removed", "--deleted", "--unknown", "--no-status", "--print0")
+	if err != nil {
+		return nil, err
+	}
+	return absolute(m.root, splitNUL(output)), nil
+}
+
+// Jujutsu is a Jujutsu repository. Its working copy is a commit, so changes
+// not described yet are part of the checked out revision.
+type Jujutsu struct {
+	root string
+}
+
+func (j *Jujutsu) Name() string { return "jj" }
+func (j *Jujutsu) Root() string { return j.root }
+
+func (j *Jujutsu) Revision() (string, error) {
+	output, err := run(j.root, nil, "jj", "log", "--no-graph", "--revisions", "@", "--template", "commit_id")
+	return strings.TrimSpace(output), err
+}
+
+func (j *Jujutsu) ChangedFiles(since string) ([]string, error) {
+	output, err := run(j.root, nil, "jj", "diff", "--from", since, "--to", "@", "--name-only")
+	if err != nil {
+		return nil, err
+	}
+	return absolute(j.root, strings.Split(strings.TrimSpace(output), "\n")), nil
+}
+
+// run runs a version control command in dir, returning its standard output.
+func run(dir string, env []string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%s %s: %s", name, strings.Join(args, " "), message)
+		}
+		return "", fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return string(output), nil
+}
+
+func splitNUL(output string) []string {
+	return strings.Split(strings.TrimRight(output, "\x00"), "\x00")
+}
+
+// absolute joins the non-empty paths to root, sorted and without
+// duplicates.
+func absolute(root string, paths []string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		file := filepath.Join(root, filepath.FromSlash(path))
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
@@ -0,0 +1,140 @@
+package vcs
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitChangedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	root := t.TempDir()
+	gitRun := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	gitRun("init", "-q")
+	writeFile(t, filepath.Join(root, "app", "main.go"), "package main")
+	writeFile(t, filepath.Join(root, "lib", "lib.go"), "package lib")
+	writeFile(t, filepath.Join(root, "old.txt"), "old")
+	writeFile(t, filepath.Join(root, ".gitignore"), "build/\n")
+	gitRun("add", ".")
+	gitRun("commit", "-q", "-m", "initial")
+
+	writeFile(t, filepath.Join(root, "app", "main.go"), "package main // changed")
+	writeFile(t, filepath.Join(root, "app", "new.go"), "package main")
+	writeFile(t, filepath.Join(root, "build", "out.bin"), "ignored")
+	if err := os.Remove(filepath.Join(root, "old.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := Detect(filepath.Join(root, "app"))
+	if repo.Name() != "git" || repo.Root() != root {
+		t.Fatalf("Detect() = %s at %s, want git at %s", repo.Name(), repo.Root(), root)
+	}
+	revision, err := repo.Revision()
+	if err != nil || len(revision) != 40 {
+		t.Errorf("Revision() = %q, %v; want a commit hash", revision, err)
+	}
+
+	changed, err := repo.ChangedFiles("HEAD")
+	if err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+	want := []string{
+		filepath.Join(root, "app", "main.go"),
+		filepath.Join(root, "app", "new.go"),
+		filepath.Join(root, "old.txt"),
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("ChangedFiles() = %v, want %v", changed, want)
+	}
+
+	if _, err := repo.ChangedFiles("no-such-ref"); err == nil {
+		t.Error("ChangedFiles() of an unknown revision succeeded")
+	}
+}
+
+func TestSnapshotChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a.txt"), "a")
+	writeFile(t, filepath.Join(root, "src", "b.txt"), "b")
+	writeFile(t, filepath.Join(root, "old.txt"), "old")
+
+	snapshot := NewSnapshot(root)
+	if err := snapshot.Save("base"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	changed, err := snapshot.ChangedFiles("base")
+	if err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("ChangedFiles() right after Save() = %v, want none", changed)
+	}
+
+	later := time.Now().Add(time.Minute)
+	writeFile(t, filepath.Join(root, "src", "b.txt"), "b")
+	if err := os.Chtimes(filepath.Join(root, "src", "b.txt"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(root, "c.txt"), "c")
+	writeFile(t, filepath.Join(root, ".doctrus", "cache", "x.json"), "{}")
+	if err := os.Remove(filepath.Join(root, "old.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err = snapshot.ChangedFiles("base")
+	if err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+	want := []string{
+		filepath.Join(root, "c.txt"),
+		filepath.Join(root, "old.txt"),
+		filepath.Join(root, "src", "b.txt"),
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("ChangedFiles() = %v, want %v", changed, want)
+	}
+
+	for _, name := range []string{"missing", "../escape", ""} {
+		if _, err := snapshot.ChangedFiles(name); err == nil {
+			t.Errorf("ChangedFiles(%q) succeeded, want an error", name)
+		}
+	}
+}
+
+func TestDetectOutsideRepository(t *testing.T) {
+	dir := t.TempDir()
+	repo := Detect(dir)
+	// The temporary directory may itself be inside a repository
+	if _, ok := repo.(*Snapshot); ok && repo.Root() != dir {
+		t.Errorf("Detect() snapshot root = %s, want %s", repo.Root(), dir)
+	}
+	if revision, err := NewSnapshot(dir).Revision(); revision != "" || err != nil {
+		t.Errorf("Snapshot.Revision() = %q, %v; want no revision", revision, err)
+	}
+}
@@ -0,0 +1,275 @@
+// Package watch detects changes to the files matching glob patterns, using
+// file system events. The directories that may hold matching files are
+// watched, as events are not recursive, and directories created later are
+// added as they appear.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/fsnotify/fsnotify"
+)
+
+const defaultDebounce = 200 * time.Millisecond
+
+// Options tune a Watcher. Zero values pick the defaults.
+type Options struct {
+	// Debounce is how long the files must stay unchanged before changes
+	// are reported, so saving many files at once reports them together
+	Debounce time.Duration
+}
+
+// Watcher watches the files matching absolute glob patterns.
+type Watcher struct {
+	patterns []string
+	opts     Options
+	events   *fsnotify.Watcher
+	dirs     map[string]bool
+	files    map[string]fileState
+}
+
+// fileState is what a change to a file is detected by.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// New returns a watcher for the files matching patterns, taking their
+// current state as the baseline changes are detected against. Close it when
+// done.
+func New(patterns []string, opts Options) (*Watcher, error) {
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			return nil, fmt.Errorf("watch pattern %s is not absolute", pattern)
+		}
+		if !doublestar.ValidatePathPattern(pattern) {
+			return nil, fmt.Errorf("invalid watch pattern %s", pattern)
+		}
+	}
+	if opts.Debounce < 0 {
+		opts.Debounce = 0
+	} else if opts.Debounce == 0 {
+		opts.Debounce = defaultDebounce
+	}
+
+	events, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch files: %w", err)
+	}
+	w := &Watcher{patterns: patterns, opts: opts, events: events, dirs: make(map[string]bool)}
+	if err := w.Reset(); err != nil {
+		events.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	return w.events.Close()
+}
+
+// Files returns the number of files watched.
+func (w *Watcher) Files() int {
+	return len(w.files)
+}
+
+// Reset takes the current state of the files as the new baseline, such as to
+// ignore what a task wrote while it ran.
+func (w *Watcher) Reset() error {
+	if err := w.watchDirs(); err != nil {
+		return err
+	}
+	files, err := w.scan()
+	if err != nil {
+		return err
+	}
+	w.files = files
+
+	// Drop the events of changes the new baseline already has
+	for {
+		select {
+		case <-w.events.Events:
+		case <-w.events.Errors:
+		default:
+			return nil
+		}
+	}
+}
+
+// Wait blocks until files are added, modified or deleted and have then
+// stayed unchanged for the debounce period, and returns their paths sorted.
+// It returns the cause of ctx once ctx is done.
+func (w *Watcher) Wait(ctx context.Context) ([]string, error) {
+	changed := make(map[string]bool)
+	debounce := time.NewTimer(w.opts.Debounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		case err, ok := <-w.events.Errors:
+			if !ok {
+				return nil, errors.New("file watcher closed")
+			}
+			return nil, fmt.Errorf("failed to watch files: %w", err)
+		case <-debounce.C:
+			paths := make([]string, 0, len(changed))
+			for path := range changed {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			return paths, nil
+		case event, ok := <-w.events.Events:
+			if !ok {
+				return nil, errors.New("file watcher closed")
+			}
+			paths, err := w.handle(event)
+			if err != nil {
+				return nil, err
+			}
+			if len(paths) == 0 {
+				continue
+			}
+			for _, path := range paths {
+				changed[path] = true
+			}
+			debounce.Reset(w.opts.Debounce)
+		}
+	}
+}
+
+// handle returns the watched files an event added, modified or deleted,
+// updating the baseline. A created directory is watched, and the files
+// already in it count as added.
+func (w *Watcher) handle(event fsnotify.Event) ([]string, error) {
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		// A directory that comes back has to be watched again
+		delete(w.dirs, event.Name)
+	}
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if err := w.watchDirs(); err != nil {
+				return nil, err
+			}
+			files, err := w.scan()
+			if err != nil {
+				return nil, err
+			}
+			paths := diff(w.files, files)
+			w.files = files
+			return paths, nil
+		}
+	}
+	if !Match(w.patterns, event.Name) {
+		return nil, nil
+	}
+
+	old, existed := w.files[event.Name]
+	info, err := os.Stat(event.Name)
+	if err != nil || !info.Mode().IsRegular() {
+		if !existed {
+			return nil, nil
+		}
+		delete(w.files, event.Name)
+		return []string{event.Name}, nil
+	}
+	file := fileState{size: info.Size(), modTime: info.ModTime()}
+	if existed && old.modTime.Equal(file.modTime) && old.size == file.size {
+		return nil, nil
+	}
+	w.files[event.Name] = file
+	return []string{event.Name}, nil
+}
+
+// watchDirs watches the directories that may hold files matching the
+// patterns, and the closest existing parent of each pattern's base
+// directory, so its creation is noticed.
+func (w *Watcher) watchDirs() error {
+	for _, pattern := range w.patterns {
+		base, _ := doublestar.SplitPattern(filepath.ToSlash(pattern))
+		dir := filepath.FromSlash(base)
+		for {
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+		dirs, err := doublestar.FilepathGlob(filepath.Dir(pattern))
+		if err != nil {
+			return fmt.Errorf("invalid watch pattern %s: %w", pattern, err)
+		}
+		for _, dir := range append(dirs, dir) {
+			if w.dirs[dir] {
+				continue
+			}
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				continue
+			}
+			if err := w.events.Add(dir); err != nil {
+				return fmt.Errorf("failed to watch %s: %w", dir, err)
+			}
+			w.dirs[dir] = true
+		}
+	}
+	return nil
+}
+
+// scan returns the state of the regular files matching the patterns.
+func (w *Watcher) scan() (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	for _, pattern := range w.patterns {
+		matches, err := doublestar.FilepathGlob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid watch pattern %s: %w", pattern, err)
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			files[match] = fileState{size: info.Size(), modTime: info.ModTime()}
+		}
+	}
+	return files, nil
+}
+
+// diff returns the files added, modified or deleted between two scans.
+func diff(previous, current map[string]fileState) []string {
+	var paths []string
+	for path, file := range current {
+		if old, ok := previous[path]; !ok || !old.modTime.Equal(file.modTime) || old.size != file.size {
+			paths = append(paths, path)
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Match reports whether path matches one of the absolute glob patterns.
+func Match(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := doublestar.PathMatch(pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcherWait(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "src", "a.go"), "a")
+	writeFile(t, filepath.Join(root, "src", "old.go"), "old")
+	writeFile(t, filepath.Join(root, "README.md"), "not watched")
+
+	w, err := New([]string{filepath.Join(root, "src", "**", "*.go")}, Options{Debounce: 30 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer w.Close()
+	if w.Files() != 2 {
+		t.Errorf("Files() = %d, want 2", w.Files())
+	}
+
+	writeFile(t, filepath.Join(root, "README.md"), "changed")
+	writeFile(t, filepath.Join(root, "src", "a.go"), "changed")
+	writeFile(t, filepath.Join(root, "src", "lib", "b.go"), "new")
+	if err := os.Remove(filepath.Join(root, "src", "old.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	changed, err := w.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	want := []string{
+		filepath.Join(root, "src", "a.go"),
+		filepath.Join(root, "src", "lib", "b.go"),
+		filepath.Join(root, "src", "old.go"),
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("Wait() = %v, want %v", changed, want)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if changed, err := w.Wait(ctx); err == nil {
+		t.Errorf("Wait() without changes = %v, want the context's error", changed)
+	}
+}
+
+func TestWatcherReset(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a.txt"), "a")
+	w, err := New([]string{filepath.Join(root, "*.txt")}, Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer w.Close()
+
+	writeFile(t, filepath.Join(root, "b.txt"), "written by a task")
+	if err := w.Reset(); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if changed, err := w.Wait(ctx); err == nil {
+		t.Errorf("Wait() after Reset() = %v, want no changes", changed)
+	}
+}
+
+func TestWatcherWatchesNewDirectories(t *testing.T) {
+	root := t.TempDir()
+	w, err := New([]string{filepath.Join(root, "gen", "**", "*.txt")}, Options{Debounce: 30 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer w.Close()
+	if w.Files() != 0 {
+		t.Errorf("Files() = %d, want 0", w.Files())
+	}
+
+	writeFile(t, filepath.Join(root, "gen", "out", "a.txt"), "a")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	changed, err := w.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if want := []string{filepath.Join(root, "gen", "out", "a.txt")}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("Wait() = %v, want %v", changed, want)
+	}
+
+	// Files in the new directory are watched from now on
+	writeFile(t, filepath.Join(root, "gen", "out", "b.txt"), "b")
+	changed, err = w.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if want := []string{filepath.Join(root, "gen", "out", "b.txt")}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("Wait() = %v, want %v", changed, want)
+	}
+}
+
+func TestNewRejectsInvalidPatterns(t *testing.T) {
+	for _, pattern := range []string{"src/*.go", "/src/[.go"} {
+		if _, err := New([]string{pattern}, Options{}); err == nil {
+			t.Errorf("New(%q) succeeded, want an error", pattern)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	patterns := []string{"/project/web/src/**/*.ts", "/project/web/package.json"}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/project/web/src/app.ts", true},
+		{"/project/web/src/components/button.ts", true},
+		{"/project/web/package.json", true},
+		{"/project/web/src/app.css", false},
+		{"/project/api/src/app.ts", false},
+	}
+	for _, tt := range tests {
+		if got := Match(patterns, filepath.FromSlash(tt.path)); got != tt.want {
+			t.Errorf("Match(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel errors for use with errors.Is. The concrete error types below
+// match them and carry the details.
+var (
+	ErrWorkspaceNotFound  = errors.New("workspace not found")
+	ErrTaskNotFound       = errors.New("task not found")
+	ErrCircularDependency = errors.New("circular dependency")
+	ErrTaskFailed         = errors.New("task failed")
+	ErrOutputsMissing     = errors.New("outputs missing")
+	ErrUndeclaredInputs   = errors.New("undeclared inputs")
+)
+
+// WorkspaceNotFoundError reports a reference to an undefined workspace.
+type WorkspaceNotFoundError struct {
+	Workspace string
+}
+
+func (e *WorkspaceNotFoundError) Error() string {
+	return fmt.Sprintf("workspace %s not found", e.Workspace)
+}
+
+func (e *WorkspaceNotFoundError) Is(target error) bool {
+	return target == ErrWorkspaceNotFound
+}
+
+// TaskNotFoundError reports a reference to an undefined task. Workspace is
+// empty when the task was looked up in every workspace, and RequiredBy names
+// the task whose depends_on referenced it, if any.
+type TaskNotFoundError struct {
+	Workspace  string
+	Task       string
+	RequiredBy string
+}
+
+func (e *TaskNotFoundError) Error() string {
+	switch {
+	case e.RequiredBy != "":
+		return fmt.Sprintf("dependency %s:%s not found (required by %s)", e.Workspace, e.Task, e.RequiredBy)
+	case e.Workspace == "":
+		return fmt.Sprintf("task %s not found in any workspace", e.Task)
+	default:
+		return fmt.Sprintf("task %s not found in workspace %s", e.Task, e.Workspace)
+	}
+}
+
+func (e *TaskNotFoundError) Is(target error) bool {
+	return target == ErrTaskNotFound
+}
+
+// CycleError reports a dependency cycle. Cycle lists the tasks as
+// workspace:task, each depending on the next, and ends with the first task
+// again.
+type CycleError struct {
+	Cycle []string
+}
+
+func (e *CycleError) Error() string {
+	if len(e.Cycle) == 0 {
+		return "circular dependency detected in dependency graph"
+	}
+	return fmt.Sprintf("circular dependency detected: %s", strings.Join(e.Cycle, " -> "))
+}
+
+func (e *CycleError) Is(target error) bool {
+	return target == ErrCircularDependency
+}
+
+// TaskFailedError reports a task whose command exited with a non-zero code.
+// Cause is set when the task was cancelled, for example by its timeout, and
+// is returned by Unwrap.
+type TaskFailedError struct {
+	Workspace string
+	Task      string
+	ExitCode  int
+	Cause     error
+}
+
+func (e *TaskFailedError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("task %s:%s failed with exit code %d: %v", e.Workspace, e.Task, e.ExitCode, e.Cause)
+	}
+	return fmt.Sprintf("task %s:%s failed with exit code %d", e.Workspace, e.Task, e.ExitCode)
+}
+
+func (e *TaskFailedError) Is(target error) bool {
+	return target == ErrTaskFailed
+}
+
+func (e *TaskFailedError) Unwrap() error {
+	return e.Cause
+}
+
+// MissingOutputsError reports a task with strict_outputs that succeeded
+// without producing files for some of its output patterns.
+type MissingOutputsError struct {
+	Workspace string
+	Task      string
+	Patterns  []string
+}
+
+func (e *MissingOutputsError) Error() string {
+	return fmt.Sprintf("task %s:%s produced no files for outputs %s", e.Workspace, e.Task, strings.Join(e.Patterns, ", "))
+}
+
+func (e *MissingOutputsError) Is(target error) bool {
+	return target == ErrOutputsMissing
+}
+
+// UndeclaredInputsError reports a task with strict_inputs: fail that read
+// project files its inputs do not cover. Files are relative to the task's
+// directory.
+type UndeclaredInputsError struct {
+	Workspace string
+	Task      string
+	Files     []string
+}
+
+func (e *UndeclaredInputsError) Error() string {
+	files := e.Files
+	more := ""
+	if len(files) > 5 {
+		files, more = files[:5], fmt.Sprintf(" and %d more", len(e.Files)-5)
+	}
+	return fmt.Sprintf("task %s:%s read files not covered by its inputs: %s%s", e.Workspace, e.Task, strings.Join(files, ", "), more)
+}
+
+func (e *UndeclaredInputsError) Is(target error) bool {
+	return target == ErrUndeclaredInputs
+}
@@ -0,0 +1,127 @@
+package workspace
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"doctrus/internal/config"
+)
+
+func TestResolveDependenciesCycleError(t *testing.T) {
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"app": {
+				Tasks: map[string]config.Task{
+					"build":  {Command: []string{"build"}, DependsOn: []string{"lib:build"}},
+					"deploy": {Command: []string{"deploy"}, DependsOn: []string{"build"}},
+				},
+			},
+			"lib": {
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"build"}, DependsOn: []string{"gen"}},
+					"gen":   {Command: []string{"gen"}, DependsOn: []string{"app:build"}},
+				},
+			},
+		},
+	}
+
+	manager := NewManager(cfg, "/test")
+	_, err := manager.ResolveDependencies("app", "deploy")
+	if !errors.Is(err, ErrCircularDependency) {
+		t.Fatalf("expected ErrCircularDependency, got %v", err)
+	}
+
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("expected *CycleError, got %T", err)
+	}
+
+	// Each task depends on the next; the cycle may start at any of its tasks
+	want := []string{"app:build", "lib:build", "lib:gen", "app:build"}
+	got := cycleErr.Cycle
+	if len(got) != len(want) {
+		t.Fatalf("Cycle = %v, want a rotation of %v", got, want)
+	}
+	for shift := 0; shift < len(want)-1; shift++ {
+		rotated := append(append([]string(nil), want[shift:len(want)-1]...), want[:shift+1]...)
+		if reflect.DeepEqual(got, rotated) {
+			return
+		}
+	}
+	t.Fatalf("Cycle = %v, want a rotation of %v", got, want)
+}
+
+func TestNotFoundErrors(t *testing.T) {
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"app": {
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"build"}, DependsOn: []string{"missing"}},
+				},
+			},
+		},
+	}
+	manager := NewManager(cfg, "/test")
+
+	tests := []struct {
+		name     string
+		run      func() error
+		sentinel error
+		message  string
+	}{
+		{
+			name:     "unknown workspace",
+			run:      func() error { _, err := manager.ResolveTaskExecution("web", "build"); return err },
+			sentinel: ErrWorkspaceNotFound,
+			message:  "workspace web not found",
+		},
+		{
+			name:     "unknown task",
+			run:      func() error { _, err := manager.ResolveTaskExecution("app", "test"); return err },
+			sentinel: ErrTaskNotFound,
+			message:  "task test not found in workspace app",
+		},
+		{
+			name:     "unknown dependency",
+			run:      func() error { _, err := manager.ResolveDependencies("app", "build"); return err },
+			sentinel: ErrTaskNotFound,
+			message:  "dependency app:missing not found (required by app:build)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if !errors.Is(err, tt.sentinel) {
+				t.Fatalf("expected %v, got %v", tt.sentinel, err)
+			}
+			if err.Error() != tt.message {
+				t.Fatalf("Error() = %q, want %q", err.Error(), tt.message)
+			}
+		})
+	}
+}
+
+func TestTaskFailedError(t *testing.T) {
+	err := error(&TaskFailedError{Workspace: "app", Task: "test", ExitCode: 2})
+	if !errors.Is(err, ErrTaskFailed) {
+		t.Fatal("TaskFailedError should match ErrTaskFailed")
+	}
+	if err.Error() != "task app:test failed with exit code 2" {
+		t.Fatalf("Error() = %q", err.Error())
+	}
+}
+
+func TestTaskFailedErrorCause(t *testing.T) {
+	cause := errors.New("timed out after 1s")
+	err := error(&TaskFailedError{Workspace: "app", Task: "test", ExitCode: 124, Cause: cause})
+	if !errors.Is(err, ErrTaskFailed) || !errors.Is(err, cause) {
+		t.Fatal("TaskFailedError should match ErrTaskFailed and its cause")
+	}
+	if err.Error() != "task app:test failed with exit code 124: timed out after 1s" {
+		t.Fatalf("Error() = %q", err.Error())
+	}
+}
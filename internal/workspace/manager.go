@@ -1,11 +1,17 @@
 package workspace
 
 import (
+	"container/heap"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"doctrus/internal/config"
 )
@@ -13,6 +19,12 @@ import (
 type Manager struct {
 	config   *config.Config
 	basePath string
+
+	// executions memoizes ResolveTaskExecution; the config only changes
+	// through UpdateTask, so the dependency graph and the task runner share
+	// results
+	mu         sync.Mutex
+	executions map[string]*TaskExecution
 }
 
 type TaskExecution struct {
@@ -21,6 +33,22 @@ type TaskExecution struct {
 	Task          *config.Task
 	Workspace     *config.Workspace
 	AbsPath       string
+	// WorkDir is where the local executor runs the command instead of
+	// AbsPath, such as a sandbox holding a copy of the workspace
+	WorkDir string
+	// Env is the task's resolved environment, filled in by the executor,
+	// AppendEnv the PATH-like variables in it that extend the host's value
+	// rather than replace it, and Executor the name of the executor
+	// configured to run the task
+	Env       map[string]string
+	AppendEnv []string
+	Executor  string
+	// ImageDigest is the digest of the image a container task runs in,
+	// filled in before its cache is checked, or "" when unknown
+	ImageDigest string
+	// Definition digests the task as it runs (see deps.DefinitionHash),
+	// filled in before its cache is checked, or "" when unknown
+	Definition string
 }
 
 func NewManager(cfg *config.Config, basePath string) *Manager {
@@ -45,7 +73,7 @@ func (m *Manager) GetWorkspaces() []string {
 func (m *Manager) GetTasks(workspaceName string) ([]string, error) {
 	workspace, exists := m.config.GetWorkspace(workspaceName)
 	if !exists {
-		return nil, fmt.Errorf("workspace %s not found", workspaceName)
+		return nil, &WorkspaceNotFoundError{Workspace: workspaceName}
 	}
 
 	var names []string
@@ -65,15 +93,171 @@ func (m *Manager) GetAllTasks() map[string][]string {
 	return result
 }
 
+// TaskTags returns the tags of a task, its own followed by those of its
+// workspace, without duplicates.
+func (m *Manager) TaskTags(workspaceName, taskName string) []string {
+	workspace, exists := m.config.Workspaces[workspaceName]
+	if !exists {
+		return nil
+	}
+	var tags []string
+	for _, tag := range slices.Concat(workspace.Tasks[taskName].Tags, workspace.Tags) {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// MatchesTags reports whether a task or its workspace has any of tags.
+// Every task matches an empty list.
+func (m *Manager) MatchesTags(workspaceName, taskName string, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, tag := range m.TaskTags(workspaceName, taskName) {
+		if slices.Contains(tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterTasks returns the tasks of a workspace that match tags, sorted by
+// name.
+func (m *Manager) FilterTasks(workspaceName string, tags []string) ([]string, error) {
+	tasks, err := m.GetTasks(workspaceName)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(tasks, func(taskName string) bool {
+		return !m.MatchesTags(workspaceName, taskName, tags)
+	}), nil
+}
+
+// FilterWorkspaces returns the workspaces with a task that matches tags,
+// sorted by name. Every workspace matches an empty list.
+func (m *Manager) FilterWorkspaces(tags []string) []string {
+	return slices.DeleteFunc(m.GetWorkspaces(), func(workspaceName string) bool {
+		if len(tags) == 0 {
+			return false
+		}
+		tasks, _ := m.FilterTasks(workspaceName, tags)
+		return len(tasks) == 0
+	})
+}
+
+// MatchTasks returns the tasks whose workspace matches workspacePattern and
+// whose name matches taskPattern, as "workspace:task" sorted by workspace and
+// task. Patterns use the syntax of path.Match, and an empty workspacePattern
+// matches every workspace. It fails when a pattern is malformed or nothing
+// matches.
+func (m *Manager) MatchTasks(workspacePattern, taskPattern string) ([]string, error) {
+	if workspacePattern == "" {
+		workspacePattern = "*"
+	}
+	for _, pattern := range []string{workspacePattern, taskPattern} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+	}
+
+	var keys []string
+	for _, workspaceName := range m.GetWorkspaces() {
+		if matched, _ := path.Match(workspacePattern, workspaceName); !matched {
+			continue
+		}
+		tasks, _ := m.GetTasks(workspaceName)
+		for _, taskName := range tasks {
+			if matched, _ := path.Match(taskPattern, taskName); matched {
+				keys = append(keys, workspaceName+":"+taskName)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		if workspacePattern == "*" {
+			workspacePattern = ""
+		}
+		return nil, &TaskNotFoundError{Workspace: workspacePattern, Task: taskPattern}
+	}
+	return keys, nil
+}
+
+// Dependents returns the tasks whose depends_on names a task, as
+// workspace:task keys sorted by workspace and task.
+func (m *Manager) Dependents(workspaceName, taskName string) []string {
+	var keys []string
+	for _, name := range m.GetWorkspaces() {
+		tasks, _ := m.GetTasks(name)
+		for _, dependent := range tasks {
+			task, _ := m.config.GetTask(name, dependent)
+			for _, dep := range task.DependsOn {
+				depWorkspace, depTask, err := config.SplitDependency(name, dep)
+				if err == nil && depWorkspace == workspaceName && m.config.TaskName(depWorkspace, depTask) == taskName {
+					keys = append(keys, name+":"+dependent)
+					break
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// UpdateTask replaces the definition of a task before a run, such as after
+// command-line arguments were substituted into it, and forgets the
+// executions resolved from the old one.
+func (m *Manager) UpdateTask(workspaceName, taskName string, task config.Task) error {
+	workspace, exists := m.config.Workspaces[workspaceName]
+	if !exists {
+		return &WorkspaceNotFoundError{Workspace: workspaceName}
+	}
+	if _, exists := workspace.Tasks[taskName]; !exists {
+		return &TaskNotFoundError{Workspace: workspaceName, Task: taskName}
+	}
+	workspace.Tasks[taskName] = task
+
+	m.mu.Lock()
+	m.executions = nil
+	m.mu.Unlock()
+	return nil
+}
+
+// ResolveTaskExecution returns the execution of a task. Results are cached,
+// so callers must not modify the returned execution.
 func (m *Manager) ResolveTaskExecution(workspaceName, taskName string) (*TaskExecution, error) {
+	key := workspaceName + ":" + taskName
+
+	m.mu.Lock()
+	execution, ok := m.executions[key]
+	m.mu.Unlock()
+	if ok {
+		return execution, nil
+	}
+
+	execution, err := m.resolveTaskExecution(workspaceName, taskName)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	if m.executions == nil {
+		m.executions = make(map[string]*TaskExecution)
+	}
+	m.executions[key] = execution
+	m.mu.Unlock()
+
+	return execution, nil
+}
+
+func (m *Manager) resolveTaskExecution(workspaceName, taskName string) (*TaskExecution, error) {
 	workspace, exists := m.config.GetWorkspace(workspaceName)
 	if !exists {
-		return nil, fmt.Errorf("workspace %s not found", workspaceName)
+		return nil, &WorkspaceNotFoundError{Workspace: workspaceName}
 	}
 
 	task, exists := m.config.GetTask(workspaceName, taskName)
 	if !exists {
-		return nil, fmt.Errorf("task %s not found in workspace %s", taskName, workspaceName)
+		return nil, &TaskNotFoundError{Workspace: workspaceName, Task: taskName}
 	}
 
 	absPath, err := m.resolveWorkspacePath(workspace.Path)
@@ -91,105 +275,199 @@ func (m *Manager) ResolveTaskExecution(workspaceName, taskName string) (*TaskExe
 }
 
 func (m *Manager) ResolveDependencies(workspaceName, taskName string) ([]*TaskExecution, error) {
-	_, exists := m.config.GetTask(workspaceName, taskName)
-	if !exists {
-		return nil, fmt.Errorf("task %s not found in workspace %s", taskName, workspaceName)
+	if _, exists := m.config.GetWorkspace(workspaceName); !exists {
+		return nil, &WorkspaceNotFoundError{Workspace: workspaceName}
+	}
+	if _, exists := m.config.GetTask(workspaceName, taskName); !exists {
+		return nil, &TaskNotFoundError{Workspace: workspaceName, Task: taskName}
 	}
 
 	// Build dependency graph
-	graph, indegrees, err := m.buildDependencyGraph(workspaceName, taskName)
+	graph, indegrees, executions, err := m.buildDependencyGraph(workspaceName, taskName)
 	if err != nil {
 		return nil, err
 	}
 
 	// Perform topological sort using Kahn's algorithm
-	executions, err := m.topologicalSort(graph, indegrees)
+	return m.topologicalSort(graph, indegrees, executions)
+}
+
+// Dependencies returns the direct dependencies of a task as
+// workspace:task keys, in depends_on order.
+func (m *Manager) Dependencies(workspaceName, taskName string) ([]string, error) {
+	node := m.resolveGraphNode(workspaceName + ":" + taskName)
+	if node.err != nil {
+		return nil, node.err
+	}
+	return node.deps, nil
+}
+
+// DependencyGraph returns a task and its transitive dependencies, mapping
+// the workspace:task key of each to the sorted keys of its direct
+// dependencies.
+func (m *Manager) DependencyGraph(workspaceName, taskName string) (map[string][]string, error) {
+	if _, exists := m.config.GetTask(workspaceName, taskName); !exists {
+		return nil, &TaskNotFoundError{Workspace: workspaceName, Task: taskName}
+	}
+	dependents, indegrees, _, err := m.buildDependencyGraph(workspaceName, taskName)
 	if err != nil {
 		return nil, err
 	}
 
-	return executions, nil
+	graph := make(map[string][]string, len(indegrees))
+	for key := range indegrees {
+		graph[key] = nil
+	}
+	for dep, keys := range dependents {
+		for _, key := range keys {
+			graph[key] = append(graph[key], dep)
+		}
+	}
+	for key, deps := range graph {
+		sort.Strings(deps)
+		graph[key] = slices.Compact(deps)
+	}
+	return graph, nil
+}
+
+// graphNode is a task discovered while building the dependency graph,
+// together with the keys of the tasks it depends on.
+type graphNode struct {
+	key       string
+	execution *TaskExecution
+	deps      []string
+	err       error
 }
 
 // buildDependencyGraph constructs a dependency graph for the given task.
 // Uses BFS traversal to discover all dependencies and builds:
 // - Adjacency list: maps each task to its dependents (tasks that depend on it)
 // - Indegree map: counts how many dependencies each task has
+// - The resolved execution of every task in the graph
+// Each BFS level is resolved concurrently; results are merged in queue
+// order so errors are reported deterministically.
 // This enables efficient topological sorting with Kahn's algorithm.
-func (m *Manager) buildDependencyGraph(workspaceName, taskName string) (map[string][]string, map[string]int, error) {
-	graph := make(map[string][]string) // task -> list of tasks that depend on it
-	indegrees := make(map[string]int)  // task -> number of dependencies
-	visited := make(map[string]bool)   // to avoid processing the same task multiple times
+func (m *Manager) buildDependencyGraph(workspaceName, taskName string) (map[string][]string, map[string]int, map[string]*TaskExecution, error) {
+	graph := make(map[string][]string)            // task -> list of tasks that depend on it
+	indegrees := make(map[string]int)             // task -> number of dependencies
+	executions := make(map[string]*TaskExecution) // task -> resolved execution
+	rootKey := fmt.Sprintf("%s:%s", workspaceName, taskName)
+	visited := map[string]bool{rootKey: true} // to avoid processing the same task multiple times
 
 	// Start with the target task
-	queue := []string{fmt.Sprintf("%s:%s", workspaceName, taskName)}
+	level := []string{rootKey}
 
-	for len(queue) > 0 {
-		currentKey := queue[0]
-		queue = queue[1:]
+	for len(level) > 0 {
+		var next []string
 
-		if visited[currentKey] {
-			continue
-		}
-		visited[currentKey] = true
+		for _, node := range m.resolveGraphNodes(level) {
+			if node.err != nil {
+				return nil, nil, nil, node.err
+			}
 
-		// Parse the current task key
-		parts := strings.Split(currentKey, ":")
-		if len(parts) != 2 {
-			return nil, nil, fmt.Errorf("invalid task key format: %s", currentKey)
-		}
-		currWorkspace, currTask := parts[0], parts[1]
+			executions[node.key] = node.execution
+			indegrees[node.key] += 0
+
+			for _, depKey := range node.deps {
+				// Add edge: dependency -> current task (dependency must run before current)
+				graph[depKey] = append(graph[depKey], node.key)
 
-		// Get the task definition
-		task, exists := m.config.GetTask(currWorkspace, currTask)
-		if !exists {
-			return nil, nil, fmt.Errorf("task %s not found in workspace %s", currTask, currWorkspace)
+				// Increment indegree of current task
+				indegrees[node.key]++
+
+				// Add dependency to the next level if not visited
+				if !visited[depKey] {
+					visited[depKey] = true
+					next = append(next, depKey)
+				}
+			}
 		}
 
-		// Initialize indegree for this task if not already done
-		if _, exists := indegrees[currentKey]; !exists {
-			indegrees[currentKey] = 0
+		level = next
+	}
+
+	return graph, indegrees, executions, nil
+}
+
+// resolveGraphNodes resolves the given tasks and their dependency keys,
+// using up to GOMAXPROCS goroutines. The result follows the order of keys.
+func (m *Manager) resolveGraphNodes(keys []string) []graphNode {
+	nodes := make([]graphNode, len(keys))
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(keys) {
+		workers = len(keys)
+	}
+	if workers <= 1 {
+		for i, key := range keys {
+			nodes[i] = m.resolveGraphNode(key)
 		}
+		return nodes
+	}
 
-		// Process dependencies
-		for _, dep := range task.DependsOn {
-			var depWorkspace, depTask string
-
-			// Parse dependency specification
-			depParts := strings.Split(dep, ":")
-			if len(depParts) == 1 {
-				// Same workspace dependency
-				depWorkspace = currWorkspace
-				depTask = depParts[0]
-			} else if len(depParts) == 2 {
-				// Cross-workspace dependency
-				depWorkspace = depParts[0]
-				depTask = depParts[1]
-			} else {
-				return nil, nil, fmt.Errorf("invalid dependency format: %s", dep)
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= len(keys) {
+					return
+				}
+				nodes[i] = m.resolveGraphNode(keys[i])
 			}
+		}()
+	}
+	wg.Wait()
+
+	return nodes
+}
 
-			depKey := fmt.Sprintf("%s:%s", depWorkspace, depTask)
+func (m *Manager) resolveGraphNode(key string) graphNode {
+	node := graphNode{key: key}
 
-			// Verify dependency exists
-			if _, exists := m.config.GetTask(depWorkspace, depTask); !exists {
-				return nil, nil, fmt.Errorf("dependency %s not found", depKey)
-			}
+	// Parse the current task key; task names may contain colons
+	currWorkspace, currTask, ok := strings.Cut(key, ":")
+	if !ok {
+		node.err = fmt.Errorf("invalid task key format: %s", key)
+		return node
+	}
 
-			// Add edge: dependency -> current task (dependency must run before current)
-			graph[depKey] = append(graph[depKey], currentKey)
+	// Get the task definition
+	task, exists := m.config.GetTask(currWorkspace, currTask)
+	if !exists {
+		node.err = &TaskNotFoundError{Workspace: currWorkspace, Task: currTask}
+		return node
+	}
 
-			// Increment indegree of current task
-			indegrees[currentKey]++
+	execution, err := m.ResolveTaskExecution(currWorkspace, currTask)
+	if err != nil {
+		node.err = fmt.Errorf("failed to resolve task execution for %s: %w", key, err)
+		return node
+	}
+	node.execution = execution
 
-			// Add dependency to queue if not visited
-			if !visited[depKey] {
-				queue = append(queue, depKey)
-			}
+	// Process dependencies
+	for _, dep := range task.DependsOn {
+		depWorkspace, depTask, err := config.SplitDependency(currWorkspace, dep)
+		if err != nil {
+			node.err = err
+			return node
+		}
+		depTask = m.config.TaskName(depWorkspace, depTask)
+
+		// Verify dependency exists
+		if _, exists := m.config.GetTask(depWorkspace, depTask); !exists {
+			node.err = &TaskNotFoundError{Workspace: depWorkspace, Task: depTask, RequiredBy: key}
+			return node
 		}
+
+		node.deps = append(node.deps, fmt.Sprintf("%s:%s", depWorkspace, depTask))
 	}
 
-	return graph, indegrees, nil
+	return node
 }
 
 // topologicalSort performs topological sorting using Kahn's algorithm.
@@ -203,60 +481,125 @@ func (m *Manager) buildDependencyGraph(workspaceName, taskName string) (map[stri
 // - Process tasks in order, reducing indegree of their dependents
 // - Tasks become available when their indegree reaches 0
 // - If cycles exist, some tasks will never reach indegree 0
-func (m *Manager) topologicalSort(graph map[string][]string, indegrees map[string]int) ([]*TaskExecution, error) {
-	var result []*TaskExecution
-	queue := make([]string, 0)
+//
+// Available tasks are kept in a min-heap so the smallest key is always
+// processed next, which keeps the order deterministic without re-sorting.
+func (m *Manager) topologicalSort(graph map[string][]string, indegrees map[string]int, executions map[string]*TaskExecution) ([]*TaskExecution, error) {
+	result := make([]*TaskExecution, 0, len(indegrees))
+	queue := &keyHeap{}
 
 	// Find all tasks with no dependencies (indegree 0)
 	for task, degree := range indegrees {
 		if degree == 0 {
-			queue = append(queue, task)
+			*queue = append(*queue, task)
 		}
 	}
+	heap.Init(queue)
 
-	// Process queue
-	processedCount := 0
-	totalTasks := len(indegrees)
-
-	for len(queue) > 0 {
-		// Sort queue for deterministic ordering
-		sort.Strings(queue)
-
-		// Dequeue first task
-		currentKey := queue[0]
-		queue = queue[1:]
+	for queue.Len() > 0 {
+		// Dequeue the smallest available task
+		currentKey := heap.Pop(queue).(string)
 
-		// Create task execution
-		parts := strings.Split(currentKey, ":")
-		if len(parts) != 2 {
+		execution, ok := executions[currentKey]
+		if !ok {
 			return nil, fmt.Errorf("invalid task key: %s", currentKey)
 		}
-
-		execution, err := m.ResolveTaskExecution(parts[0], parts[1])
-		if err != nil {
-			return nil, fmt.Errorf("failed to resolve task execution for %s: %w", currentKey, err)
-		}
-
 		result = append(result, execution)
-		processedCount++
 
 		// Update indegrees of dependent tasks
 		for _, dependent := range graph[currentKey] {
 			indegrees[dependent]--
 			if indegrees[dependent] == 0 {
-				queue = append(queue, dependent)
+				heap.Push(queue, dependent)
 			}
 		}
 	}
 
 	// Check for cycles
-	if processedCount != totalTasks {
-		return nil, fmt.Errorf("circular dependency detected in dependency graph")
+	if len(result) != len(indegrees) {
+		return nil, &CycleError{Cycle: findCycle(graph, indegrees)}
 	}
 
 	return result, nil
 }
 
+// keyHeap is a min-heap of task keys for container/heap.
+type keyHeap []string
+
+func (h keyHeap) Len() int           { return len(h) }
+func (h keyHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h keyHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *keyHeap) Push(x any)        { *h = append(*h, x.(string)) }
+func (h *keyHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// findCycle returns one cycle among the tasks Kahn's algorithm could not
+// process (those left with a positive indegree), in depends-on order and
+// closed with its first task.
+func findCycle(graph map[string][]string, indegrees map[string]int) []string {
+	var remaining []string
+	for key, degree := range indegrees {
+		if degree > 0 {
+			remaining = append(remaining, key)
+		}
+	}
+	sort.Strings(remaining)
+
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int)
+	var stack []string
+	var cycle []string
+
+	var visit func(key string) bool
+	visit = func(key string) bool {
+		state[key] = inProgress
+		stack = append(stack, key)
+		dependents := append([]string(nil), graph[key]...)
+		sort.Strings(dependents)
+		for _, next := range dependents {
+			if indegrees[next] <= 0 {
+				continue
+			}
+			switch state[next] {
+			case inProgress:
+				for i := len(stack) - 1; i >= 0; i-- {
+					cycle = append(cycle, stack[i])
+					if stack[i] == next {
+						break
+					}
+				}
+				// Edges point from a dependency to its dependent, so walking
+				// the stack backwards yields depends-on order
+				cycle = append(cycle, cycle[0])
+				return true
+			case unvisited:
+				if visit(next) {
+					return true
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[key] = done
+		return false
+	}
+
+	for _, key := range remaining {
+		if state[key] == unvisited && visit(key) {
+			return cycle
+		}
+	}
+	return nil
+}
+
 func (m *Manager) resolveDependenciesRecursive(workspaceName, taskName string, executions *[]*TaskExecution, visited map[string]bool, processed map[string]bool) error {
 	key := fmt.Sprintf("%s:%s", workspaceName, taskName)
 
@@ -267,29 +610,22 @@ func (m *Manager) resolveDependenciesRecursive(workspaceName, taskName string, e
 
 	// Check for circular dependencies
 	if visited[key] {
-		return fmt.Errorf("circular dependency detected: %s", key)
+		return &CycleError{Cycle: []string{key, key}}
 	}
 	visited[key] = true
 	defer delete(visited, key) // Clear after processing to allow diamond dependencies
 
 	task, exists := m.config.GetTask(workspaceName, taskName)
 	if !exists {
-		return fmt.Errorf("task %s not found in workspace %s", taskName, workspaceName)
+		return &TaskNotFoundError{Workspace: workspaceName, Task: taskName}
 	}
 
 	for _, dep := range task.DependsOn {
-		parts := strings.Split(dep, ":")
-		var depWorkspace, depTask string
-
-		if len(parts) == 1 {
-			depWorkspace = workspaceName
-			depTask = parts[0]
-		} else if len(parts) == 2 {
-			depWorkspace = parts[0]
-			depTask = parts[1]
-		} else {
-			return fmt.Errorf("invalid dependency format: %s", dep)
+		depWorkspace, depTask, err := config.SplitDependency(workspaceName, dep)
+		if err != nil {
+			return err
 		}
+		depTask = m.config.TaskName(depWorkspace, depTask)
 
 		if err := m.resolveDependenciesRecursive(depWorkspace, depTask, executions, visited, processed); err != nil {
 			return err
@@ -313,15 +649,46 @@ func (m *Manager) resolveWorkspacePath(workspacePath string) (string, error) {
 	return filepath.Abs(filepath.Join(m.basePath, workspacePath))
 }
 
+// ValidateWorkspaces checks that the path of every workspace exists.
 func (m *Manager) ValidateWorkspaces() error {
-	for name, workspace := range m.config.Workspaces {
-		absPath, err := m.resolveWorkspacePath(workspace.Path)
-		if err != nil {
-			return fmt.Errorf("workspace %s: failed to resolve path: %w", name, err)
+	for _, name := range m.GetWorkspaces() {
+		if err := m.ValidateWorkspace(name); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		if _, err := os.Stat(absPath); os.IsNotExist(err) {
-			return fmt.Errorf("workspace %s: path does not exist: %s", name, absPath)
+// ValidateWorkspace checks that the path of one workspace exists.
+func (m *Manager) ValidateWorkspace(name string) error {
+	workspace, exists := m.config.GetWorkspace(name)
+	if !exists {
+		return &WorkspaceNotFoundError{Workspace: name}
+	}
+
+	absPath, err := m.resolveWorkspacePath(workspace.Path)
+	if err != nil {
+		return fmt.Errorf("workspace %s: failed to resolve path: %w", name, err)
+	}
+
+	if _, err := os.Stat(absPath); os.IsNotExist(err) {
+		return fmt.Errorf("workspace %s: path does not exist: %s", name, absPath)
+	}
+	return nil
+}
+
+// ValidateExecutions checks the paths of the workspaces the executions run
+// in, each workspace once, so a missing workspace that is not involved does
+// not block them.
+func (m *Manager) ValidateExecutions(executions []*TaskExecution) error {
+	checked := make(map[string]bool)
+	for _, execution := range executions {
+		if checked[execution.WorkspaceName] {
+			continue
+		}
+		checked[execution.WorkspaceName] = true
+		if err := m.ValidateWorkspace(execution.WorkspaceName); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -1,10 +1,14 @@
 package workspace
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
 	"doctrus/internal/config"
@@ -306,6 +310,174 @@ func TestManagerResolveTaskExecutionDefaultPath(t *testing.T) {
 	}
 }
 
+func TestManagerFilterByTags(t *testing.T) {
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"web": {Tags: []string{"js"}, Tasks: map[string]config.Task{
+				"build": {Command: []string{"npm", "run", "build"}},
+				"test":  {Command: []string{"npm", "test"}, Tags: []string{"unit", "js"}},
+			}},
+			"api": {Tags: []string{"go", "services"}, Tasks: map[string]config.Task{
+				"test": {Command: []string{"go", "test"}},
+			}},
+			"tools": {Tasks: map[string]config.Task{
+				"lint": {Command: []string{"lint"}},
+				"test": {Command: []string{"test"}, Tags: []string{"unit"}},
+			}},
+		},
+	}
+	manager := NewManager(cfg, t.TempDir())
+
+	if got, want := manager.TaskTags("web", "test"), []string{"unit", "js"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TaskTags(web, test) = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name           string
+		tags           []string
+		wantWorkspaces []string
+		wantTools      []string
+	}{
+		{name: "no tags", wantWorkspaces: []string{"api", "tools", "web"}, wantTools: []string{"lint", "test"}},
+		{name: "workspace tag", tags: []string{"services"}, wantWorkspaces: []string{"api"}, wantTools: []string{}},
+		{name: "task tag", tags: []string{"unit"}, wantWorkspaces: []string{"tools", "web"}, wantTools: []string{"test"}},
+		{name: "any of several", tags: []string{"go", "js"}, wantWorkspaces: []string{"api", "web"}, wantTools: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := manager.FilterWorkspaces(tt.tags); !reflect.DeepEqual(got, tt.wantWorkspaces) {
+				t.Errorf("FilterWorkspaces(%v) = %v, want %v", tt.tags, got, tt.wantWorkspaces)
+			}
+			got, err := manager.FilterTasks("tools", tt.tags)
+			if err != nil {
+				t.Fatalf("FilterTasks() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.wantTools) {
+				t.Errorf("FilterTasks(tools, %v) = %v, want %v", tt.tags, got, tt.wantTools)
+			}
+		})
+	}
+}
+
+func TestManagerMatchTasks(t *testing.T) {
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"frontend": {Tasks: map[string]config.Task{
+				"build":    {Command: []string{"npm", "run", "build"}},
+				"test":     {Command: []string{"npm", "test"}},
+				"test:e2e": {Command: []string{"npm", "run", "e2e"}},
+			}},
+			"frontoffice": {Tasks: map[string]config.Task{
+				"build": {Command: []string{"make"}},
+			}},
+			"api": {Tasks: map[string]config.Task{
+				"lint": {Command: []string{"golangci-lint", "run"}},
+				"test": {Command: []string{"go", "test"}},
+			}},
+		},
+	}
+	manager := NewManager(cfg, t.TempDir())
+
+	tests := []struct {
+		name      string
+		workspace string
+		task      string
+		want      []string
+		wantErr   error
+	}{
+		{name: "workspace pattern", workspace: "front*", task: "build", want: []string{"frontend:build", "frontoffice:build"}},
+		{name: "task pattern", workspace: "frontend", task: "test*", want: []string{"frontend:test", "frontend:test:e2e"}},
+		{name: "every workspace", task: "test", want: []string{"api:test", "frontend:test"}},
+		{name: "both patterns", workspace: "?pi", task: "*", want: []string{"api:lint", "api:test"}},
+		{name: "no match", workspace: "back*", task: "build", wantErr: ErrTaskNotFound},
+		{name: "malformed pattern", workspace: "[front", task: "build", wantErr: path.ErrBadPattern},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := manager.MatchTasks(tt.workspace, tt.task)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("MatchTasks() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MatchTasks() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatchTasks(%q, %q) = %v, want %v", tt.workspace, tt.task, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManagerDependents(t *testing.T) {
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"web": {Tasks: map[string]config.Task{
+				"setup":  {Command: []string{"npm", "ci"}, Aliases: []string{"s"}},
+				"build":  {Command: []string{"npm", "run", "build"}, DependsOn: []string{"setup"}},
+				"test":   {Command: []string{"npm", "test"}, DependsOn: []string{"s", "build"}},
+				"gen:js": {Command: []string{"npm", "run", "gen"}},
+			}},
+			"e2e": {Tasks: map[string]config.Task{
+				"run":   {Command: []string{"playwright", "test"}, DependsOn: []string{"web:setup", "setup", "web:gen:js"}},
+				"setup": {Command: []string{"playwright", "install"}},
+			}},
+		},
+	}
+	manager := NewManager(cfg, t.TempDir())
+
+	if got, want := manager.Dependents("web", "setup"), []string{"e2e:run", "web:build", "web:test"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependents(web, setup) = %v, want %v", got, want)
+	}
+	if got := manager.Dependents("web", "test"); len(got) != 0 {
+		t.Errorf("Dependents(web, test) = %v, want none", got)
+	}
+
+	// Task names may hold colons; dependencies split at the first one
+	if got, want := manager.Dependents("web", "gen:js"), []string{"e2e:run"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependents(web, gen:js) = %v, want %v", got, want)
+	}
+	executions, err := manager.ResolveDependencies("e2e", "run")
+	if err != nil {
+		t.Fatalf("ResolveDependencies(e2e, run) error = %v", err)
+	}
+	var keys []string
+	for _, execution := range executions {
+		keys = append(keys, execution.WorkspaceName+":"+execution.TaskName)
+	}
+	sort.Strings(keys)
+	if want := []string{"e2e:run", "e2e:setup", "web:gen:js", "web:setup"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("ResolveDependencies(e2e, run) = %v, want %v", keys, want)
+	}
+}
+
+func TestManagerUpdateTask(t *testing.T) {
+	manager := createTestManager(t, "")
+	if _, err := manager.ResolveTaskExecution("frontend", "test"); err != nil {
+		t.Fatalf("ResolveTaskExecution() error = %v", err)
+	}
+
+	if err := manager.UpdateTask("frontend", "test", config.Task{Command: []string{"npm", "test", "--watch"}}); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	execution, err := manager.ResolveTaskExecution("frontend", "test")
+	if err != nil {
+		t.Fatalf("ResolveTaskExecution() error = %v", err)
+	}
+	if !reflect.DeepEqual(execution.Task.Command, []string{"npm", "test", "--watch"}) {
+		t.Errorf("command = %v, want the updated task", execution.Task.Command)
+	}
+
+	if err := manager.UpdateTask("frontend", "deploy", config.Task{}); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("UpdateTask() error = %v, want a task not found error", err)
+	}
+}
+
 func TestManagerResolveDependencies(t *testing.T) {
 	cfg := &config.Config{
 		Version: "1.0",
@@ -328,6 +500,10 @@ func TestManagerResolveDependencies(t *testing.T) {
 						Command:   []string{"npm", "deploy"},
 						DependsOn: []string{"test"},
 					},
+					"integration": {
+						Command:   []string{"npm", "run", "integration"},
+						DependsOn: []string{"backend:c", "backend:test"},
+					},
 				},
 			},
 			"backend": {
@@ -335,6 +511,7 @@ func TestManagerResolveDependencies(t *testing.T) {
 				Tasks: map[string]config.Task{
 					"compile": {
 						Command: []string{"go", "build"},
+						Aliases: []string{"c"},
 					},
 					"test": {
 						Command:   []string{"go", "test"},
@@ -375,6 +552,13 @@ func TestManagerResolveDependencies(t *testing.T) {
 			expectedTaskOrder: []string{"frontend:clean", "frontend:build", "frontend:test", "frontend:deploy"},
 			wantErr:           false,
 		},
+		{
+			name:              "dependency named by an alias",
+			workspaceName:     "frontend",
+			taskName:          "integration",
+			expectedTaskOrder: []string{"backend:compile", "backend:test", "frontend:integration"},
+			wantErr:           false,
+		},
 		{
 			name:          "non-existent task",
 			workspaceName: "frontend",
@@ -556,6 +740,48 @@ func TestManagerResolveDependenciesDiamond(t *testing.T) {
 	}
 }
 
+func TestManagerDependencyGraph(t *testing.T) {
+	cfg := &config.Config{
+		Version: "1.0",
+		Workspaces: map[string]config.Workspace{
+			"app": {
+				Path: "./app",
+				Tasks: map[string]config.Task{
+					"deploy": {Command: []string{"echo"}, DependsOn: []string{"test", "build", "build"}},
+					"test":   {Command: []string{"echo"}, DependsOn: []string{"build"}},
+					"build":  {Command: []string{"echo"}, DependsOn: []string{"lib:build"}},
+					"lint":   {Command: []string{"echo"}},
+				},
+			},
+			"lib": {
+				Path: "./lib",
+				Tasks: map[string]config.Task{
+					"build": {Command: []string{"echo"}},
+				},
+			},
+		},
+	}
+
+	manager := NewManager(cfg, "/test")
+	graph, err := manager.DependencyGraph("app", "deploy")
+	if err != nil {
+		t.Fatalf("DependencyGraph() error = %v", err)
+	}
+	want := map[string][]string{
+		"app:deploy": {"app:build", "app:test"},
+		"app:test":   {"app:build"},
+		"app:build":  {"lib:build"},
+		"lib:build":  nil,
+	}
+	if !reflect.DeepEqual(graph, want) {
+		t.Errorf("DependencyGraph() = %v, want %v", graph, want)
+	}
+
+	if _, err := manager.DependencyGraph("app", "missing"); err == nil {
+		t.Error("DependencyGraph() of a missing task succeeded")
+	}
+}
+
 func TestManagerResolveDependenciesComplexDiamond(t *testing.T) {
 	// Test complex diamond with multiple levels and cross-workspace dependencies
 	// A depends on B and C
@@ -775,3 +1001,102 @@ func TestResolveWorkspacePath(t *testing.T) {
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && s[:len(substr)] == substr
 }
+
+func TestManagerResolveDependenciesLargeGraph(t *testing.T) {
+	// 50 workspaces with 40 tasks each; every task depends on the task
+	// before it in its workspace and on the same task in the previous
+	// workspace, so each level of the graph fans out
+	const workspaces, tasks = 50, 40
+	cfg := &config.Config{Version: "1.0", Workspaces: make(map[string]config.Workspace)}
+	for w := 0; w < workspaces; w++ {
+		ws := config.Workspace{Tasks: make(map[string]config.Task)}
+		for i := 0; i < tasks; i++ {
+			var deps []string
+			if i > 0 {
+				deps = append(deps, fmt.Sprintf("t%02d", i-1))
+			}
+			if w > 0 {
+				deps = append(deps, fmt.Sprintf("w%02d:t%02d", w-1, i))
+			}
+			ws.Tasks[fmt.Sprintf("t%02d", i)] = config.Task{Command: []string{"true"}, DependsOn: deps}
+		}
+		cfg.Workspaces[fmt.Sprintf("w%02d", w)] = ws
+	}
+
+	manager := NewManager(cfg, t.TempDir())
+	target := fmt.Sprintf("w%02d", workspaces-1)
+	executions, err := manager.ResolveDependencies(target, fmt.Sprintf("t%02d", tasks-1))
+	if err != nil {
+		t.Fatalf("ResolveDependencies() error = %v", err)
+	}
+	if len(executions) != workspaces*tasks {
+		t.Fatalf("got %d executions, want %d", len(executions), workspaces*tasks)
+	}
+
+	position := make(map[string]int, len(executions))
+	for i, execution := range executions {
+		position[execution.WorkspaceName+":"+execution.TaskName] = i
+	}
+	for key, i := range position {
+		execution := executions[i]
+		for _, dep := range execution.Task.DependsOn {
+			depKey := dep
+			if !strings.Contains(dep, ":") {
+				depKey = execution.WorkspaceName + ":" + dep
+			}
+			if position[depKey] >= i {
+				t.Fatalf("%s runs before its dependency %s", key, depKey)
+			}
+		}
+	}
+
+	// The order is deterministic: the smallest available key comes first
+	if first := executions[0]; first.WorkspaceName != "w00" || first.TaskName != "t00" {
+		t.Fatalf("first execution = %s:%s, want w00:t00", first.WorkspaceName, first.TaskName)
+	}
+}
+
+func TestManagerResolveTaskExecutionMemoized(t *testing.T) {
+	manager := createTestManager(t, "")
+
+	first, err := manager.ResolveTaskExecution("frontend", "build")
+	if err != nil {
+		t.Fatalf("ResolveTaskExecution() error = %v", err)
+	}
+	executions, err := manager.ResolveDependencies("frontend", "build")
+	if err != nil {
+		t.Fatalf("ResolveDependencies() error = %v", err)
+	}
+	if executions[0] != first {
+		t.Fatal("ResolveDependencies should reuse the resolved execution")
+	}
+}
+
+func TestManagerValidateExecutions(t *testing.T) {
+	baseDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(baseDir, "frontend"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manager := NewManager(createTestConfig(), baseDir)
+
+	// backend's directory is missing, which only matters for its own tasks
+	if err := manager.ValidateWorkspaces(); err == nil {
+		t.Fatal("ValidateWorkspaces() should report the missing backend path")
+	}
+
+	frontend, err := manager.ResolveDependencies("frontend", "build")
+	if err != nil {
+		t.Fatalf("ResolveDependencies() error = %v", err)
+	}
+	if err := manager.ValidateExecutions(frontend); err != nil {
+		t.Fatalf("ValidateExecutions(frontend) error = %v", err)
+	}
+
+	backend, err := manager.ResolveDependencies("backend", "test")
+	if err != nil {
+		t.Fatalf("ResolveDependencies() error = %v", err)
+	}
+	if err := manager.ValidateExecutions(backend); err == nil || !strings.Contains(err.Error(), "path does not exist") {
+		t.Fatalf("ValidateExecutions(backend) error = %v, want missing path", err)
+	}
+}
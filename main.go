@@ -7,7 +7,11 @@ import (
 	"doctrus/internal/cli"
 )
 
+// version is set at build time via -ldflags "-X main.version=..."
+var version = "dev"
+
 func main() {
+	cli.SetVersion(version)
 	if err := cli.Execute(); err != nil {
 		// Check if the error contains an exit code from a failed task
 		if exitCode := cli.GetExitCode(err); exitCode != 0 {
@@ -16,4 +20,4 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
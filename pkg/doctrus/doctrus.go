@@ -0,0 +1,512 @@
+// Package doctrus exposes doctrus's configuration loading, dependency
+// resolution, task execution and caching to other Go programs, so tools such
+// as custom CI runners can embed doctrus instead of shelling out to the
+// binary.
+//
+// An Engine is created from a doctrus.yml and runs tasks through the same
+// code as `doctrus run`, with its retries, timeouts, finally steps, output
+// restores and remote cache, without printing anything: task output is
+// written to the configured writers and every task's outcome is returned as
+// a Result.
+//
+//	engine, err := doctrus.New(doctrus.Options{ConfigPath: "doctrus.yml"})
+//	if err != nil {
+//		return err
+//	}
+//	results, err := engine.Run(ctx, "frontend:build")
+package doctrus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"doctrus/internal/agent"
+	"doctrus/internal/cache"
+	"doctrus/internal/cas"
+	"doctrus/internal/config"
+	"doctrus/internal/deps"
+	"doctrus/internal/docker"
+	"doctrus/internal/events"
+	"doctrus/internal/logging"
+	"doctrus/internal/taskrun"
+	"doctrus/internal/workspace"
+)
+
+// Config is a parsed doctrus.yml.
+type Config = config.Config
+
+// Workspace is a workspace definition from the configuration.
+type Workspace = config.Workspace
+
+// Task is a task definition from the configuration.
+type Task = config.Task
+
+// LoadConfig reads and validates a configuration file. An empty path searches
+// for doctrus.yml in the current directory and its parents. It returns the
+// configuration and the directory containing it.
+func LoadConfig(path string) (*Config, string, error) {
+	return config.Load(path)
+}
+
+// Diagnostic is a configuration problem with its file, line, column and
+// YAML path.
+type Diagnostic = config.Diagnostic
+
+// Diagnostics is the error returned by LoadConfig and ParseConfig for an
+// invalid configuration; it lists every problem at once. Use errors.As to
+// retrieve it.
+type Diagnostics = config.Diagnostics
+
+// ParseConfig decodes and validates a configuration document without
+// running providers. File only labels the diagnostics.
+func ParseConfig(file string, data []byte) (*Config, error) {
+	return config.Parse(file, data)
+}
+
+// Options configure an Engine.
+type Options struct {
+	// ConfigPath is the doctrus.yml to load; empty searches upwards from the
+	// working directory
+	ConfigPath string
+	// CacheDir overrides the cache location (default: .doctrus/cache next to
+	// the configuration)
+	CacheDir string
+	// Force runs tasks even when their cache is up to date
+	Force bool
+	// NoCache neither reads nor writes the cache
+	NoCache bool
+	// Timeout bounds tasks without a timeout of their own, the equivalent
+	// of doctrus run --timeout; zero means no limit
+	Timeout time.Duration
+	// Params holds the values of task params, the equivalent of doctrus run
+	// --param; a value for a param no task of a run declares fails the run
+	Params map[string]string
+	// Parallel is how many tasks may run at once, each starting as soon as
+	// its dependencies are done; zero or one runs them one at a time in
+	// dependency order
+	Parallel int
+	// Stdout and Stderr receive task output as it is produced; nil discards
+	// it (it is still available in each Result)
+	Stdout io.Writer
+	Stderr io.Writer
+	// Events receives task lifecycle events when set
+	Events *EventBus
+	// Strict checks every workspace path when the Engine is created; by
+	// default Run only checks the workspaces of the tasks it runs
+	Strict bool
+	// Env holds variables of the cli layer of every task's environment, the
+	// equivalent of doctrus run --env
+	Env map[string]string
+}
+
+// Event is a task lifecycle notification published on an EventBus.
+type Event = events.Event
+
+// EventType identifies the kind of Event.
+type EventType = events.Type
+
+// EventBus delivers events to subscribers; see NewEventBus.
+type EventBus = events.Bus
+
+// Subscriber receives events from an EventBus.
+type Subscriber = events.Subscriber
+
+// SubscriberFunc adapts a function to Subscriber.
+type SubscriberFunc = events.SubscriberFunc
+
+const (
+	EventTaskQueued   = events.TaskQueued
+	EventTaskStarted  = events.TaskStarted
+	EventOutputChunk  = events.OutputChunk
+	EventCacheHit     = events.CacheHit
+	EventTaskFinished = events.TaskFinished
+)
+
+// NewEventBus creates an event bus to pass in Options.Events.
+func NewEventBus() *EventBus {
+	return events.NewBus()
+}
+
+// Status is the outcome of a task in a run.
+type Status string
+
+const (
+	StatusSuccess Status = "success"
+	StatusFailed  Status = "failed"
+	StatusCached  Status = "cached"
+	// StatusCompound marks tasks without a command that only group dependencies
+	StatusCompound Status = "compound"
+)
+
+// TaskRef identifies a task as workspace:task.
+type TaskRef struct {
+	Workspace string
+	Task      string
+}
+
+func (r TaskRef) String() string {
+	return r.Workspace + ":" + r.Task
+}
+
+// Result describes one executed (or skipped) task.
+type Result struct {
+	TaskRef
+	Status   Status
+	ExitCode int
+	Duration time.Duration
+	Stdout   string
+	Stderr   string
+	// MissingOutputs lists the output patterns that matched no files after
+	// the task succeeded
+	MissingOutputs []string
+}
+
+// Errors returned by the Engine can be matched with errors.Is against these
+// sentinels, or with errors.As against the error types below for details.
+var (
+	ErrWorkspaceNotFound  = workspace.ErrWorkspaceNotFound
+	ErrTaskNotFound       = workspace.ErrTaskNotFound
+	ErrCircularDependency = workspace.ErrCircularDependency
+	ErrTaskFailed         = workspace.ErrTaskFailed
+	ErrOutputsMissing     = workspace.ErrOutputsMissing
+)
+
+// WorkspaceNotFoundError reports a reference to an undefined workspace.
+type WorkspaceNotFoundError = workspace.WorkspaceNotFoundError
+
+// TaskNotFoundError reports a reference to an undefined task.
+type TaskNotFoundError = workspace.TaskNotFoundError
+
+// CycleError reports a dependency cycle; Cycle lists the tasks involved.
+type CycleError = workspace.CycleError
+
+// MissingOutputsError reports a task with strict_outputs whose output
+// patterns matched no files.
+type MissingOutputsError = workspace.MissingOutputsError
+
+// TaskFailedError is returned by Run when a task exits with a non-zero code.
+type TaskFailedError = workspace.TaskFailedError
+
+// Engine resolves and runs tasks for one configuration. Tasks run one at a
+// time in dependency order unless Options.Parallel allows more; `pre`
+// commands from the configuration are a CLI feature and are not run by the
+// Engine.
+type Engine struct {
+	config    *config.Config
+	basePath  string
+	options   Options
+	workspace *workspace.Manager
+	tasks     *docker.TaskContexts
+	cache     *cache.Manager
+	runner    *taskrun.Runner
+}
+
+// New loads the configuration named in opts and creates an Engine for it.
+func New(opts Options) (*Engine, error) {
+	cfg, basePath, err := config.Load(opts.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return NewWithConfig(cfg, basePath, opts)
+}
+
+// NewWithConfig creates an Engine for an already loaded configuration whose
+// workspace paths are relative to basePath.
+func NewWithConfig(cfg *Config, basePath string, opts Options) (*Engine, error) {
+	if basePath == "" {
+		abs, err := filepath.Abs(".")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+		basePath = abs
+	}
+
+	cacheDir := opts.CacheDir
+	if cacheDir == "" {
+		cacheDir = filepath.Join(basePath, ".doctrus", "cache")
+	}
+
+	cacheManager := cache.NewManager(cacheDir)
+	if cfg.Cache != nil {
+		maxSize, err := config.ParseSize(cfg.Cache.MaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache max size: %w", err)
+		}
+		cacheManager.SetMaxSize(maxSize)
+	}
+	var remote cache.Remote
+	if remoteConfig := cfg.RemoteCache(); remoteConfig != nil {
+		var err error
+		if remote, err = cache.NewRemote(remoteConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	manager := workspace.NewManager(cfg, basePath)
+	if opts.Strict {
+		if err := manager.ValidateWorkspaces(); err != nil {
+			return nil, fmt.Errorf("workspace validation failed: %w", err)
+		}
+	}
+
+	executor := docker.NewExecutor(cfg, basePath)
+	executor.Register(config.ExecutorRemote, agent.NewExecutor(cfg, basePath))
+	executor.SetCLIEnv(opts.Env)
+
+	tasks := docker.NewTaskContexts()
+	return &Engine{
+		config:    cfg,
+		basePath:  basePath,
+		options:   opts,
+		workspace: manager,
+		tasks:     tasks,
+		cache:     cacheManager,
+		runner: &taskrun.Runner{
+			Config:    cfg,
+			BasePath:  basePath,
+			Workspace: manager,
+			Executor:  executor,
+			Tasks:     tasks,
+			Tracker:   deps.NewTracker(basePath),
+			Cache:     cacheManager,
+			CAS:       cas.New(filepath.Join(basePath, ".doctrus", "cas")),
+			Remote:    remote,
+			// The Engine prints nothing; problems that don't fail a task
+			// are dropped
+			Log:     logging.New(io.Discard, logging.LevelWarn, nil),
+			Timeout: max(opts.Timeout, 0),
+			Force:   opts.Force,
+			NoCache: opts.NoCache,
+		},
+	}, nil
+}
+
+// Config returns the engine's configuration.
+func (e *Engine) Config() *Config {
+	return e.config
+}
+
+// BasePath returns the directory workspace paths are resolved against.
+func (e *Engine) BasePath() string {
+	return e.basePath
+}
+
+// Resolve expands a task spec into the tasks it names. "workspace:task" names
+// one task; a bare "task" or "*:task" names that task in every workspace
+// defining it. Tasks named by an alias are returned under their names.
+func (e *Engine) Resolve(spec string) ([]TaskRef, error) {
+	workspaceName, taskName := config.SplitTaskSpec(spec)
+
+	if workspaceName != "" {
+		if _, exists := e.config.GetWorkspace(workspaceName); !exists {
+			return nil, &WorkspaceNotFoundError{Workspace: workspaceName}
+		}
+		if _, exists := e.config.GetTask(workspaceName, taskName); !exists {
+			return nil, &TaskNotFoundError{Workspace: workspaceName, Task: taskName}
+		}
+		return []TaskRef{{Workspace: workspaceName, Task: e.config.TaskName(workspaceName, taskName)}}, nil
+	}
+
+	var refs []TaskRef
+	for name := range e.config.Workspaces {
+		if _, exists := e.config.GetTask(name, taskName); exists {
+			refs = append(refs, TaskRef{Workspace: name, Task: e.config.TaskName(name, taskName)})
+		}
+	}
+	if len(refs) == 0 {
+		return nil, &TaskNotFoundError{Task: taskName}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Workspace < refs[j].Workspace })
+	return refs, nil
+}
+
+// Plan returns every task the specs need, dependencies first, each listed
+// once.
+func (e *Engine) Plan(specs ...string) ([]TaskRef, error) {
+	executions, err := e.plan(specs)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]TaskRef, len(executions))
+	for i, execution := range executions {
+		refs[i] = TaskRef{Workspace: execution.WorkspaceName, Task: execution.TaskName}
+	}
+	return refs, nil
+}
+
+func (e *Engine) plan(specs []string) ([]*workspace.TaskExecution, error) {
+	var ordered []*workspace.TaskExecution
+	seen := make(map[string]bool)
+
+	for _, spec := range specs {
+		refs, err := e.Resolve(spec)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			executions, err := e.workspace.ResolveDependencies(ref.Workspace, ref.Task)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve dependencies of %s: %w", ref, err)
+			}
+			for _, execution := range executions {
+				key := execution.WorkspaceName + ":" + execution.TaskName
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				ordered = append(ordered, execution)
+			}
+		}
+	}
+
+	return ordered, nil
+}
+
+// Run executes the tasks named by specs and their dependencies. It stops at
+// the first failing task, returning the results so far, in the order the
+// tasks finished, together with a *TaskFailedError.
+func (e *Engine) Run(ctx context.Context, specs ...string) ([]Result, error) {
+	executions, err := e.plan(specs)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.workspace.ValidateExecutions(executions); err != nil {
+		return nil, fmt.Errorf("workspace validation failed: %w", err)
+	}
+	if executions, err = taskrun.ApplyParams(executions, e.options.Params); err != nil {
+		return nil, err
+	}
+
+	// The scheduler resolves tasks itself; the run's executions carry the
+	// params
+	planned := make(map[string]*workspace.TaskExecution, len(executions))
+	var cacheKeys []string
+	for _, execution := range executions {
+		key := execution.WorkspaceName + ":" + execution.TaskName
+		planned[key] = execution
+		e.options.Events.Publish(Event{Type: EventTaskQueued, Workspace: execution.WorkspaceName, Task: execution.TaskName})
+		if execution.Task.Cache && !e.options.NoCache {
+			cacheKeys = append(cacheKeys, key)
+		}
+	}
+	// A failed prefetch only means entries are read one at a time
+	_ = e.cache.Prefetch(cacheKeys)
+
+	var mu sync.Mutex
+	var results []Result
+	scheduler := taskrun.NewScheduler(e.config, e.workspace, func(ctx context.Context, execution *workspace.TaskExecution, _ bool) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if applied, ok := planned[execution.WorkspaceName+":"+execution.TaskName]; ok {
+			execution = applied
+		}
+		result, err := e.runTask(ctx, execution)
+		mu.Lock()
+		results = append(results, result)
+		mu.Unlock()
+
+		finished := Event{
+			Type:      EventTaskFinished,
+			Workspace: result.Workspace,
+			Task:      result.Task,
+			Status:    string(result.Status),
+			ExitCode:  result.ExitCode,
+			Duration:  result.Duration,
+		}
+		if err != nil {
+			finished.Error = err.Error()
+		}
+		e.options.Events.Publish(finished)
+		return err
+	})
+	if e.options.Parallel > 1 {
+		scheduler.Slots = make(chan struct{}, e.options.Parallel)
+	}
+
+	var targets []taskrun.Target
+	for _, spec := range specs {
+		refs, err := e.Resolve(spec)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			targets = append(targets, taskrun.Target{Workspace: ref.Workspace, Task: ref.Task})
+		}
+	}
+	_, err = scheduler.RunTargets(ctx, targets)
+	return results, err
+}
+
+func (e *Engine) runTask(ctx context.Context, execution *workspace.TaskExecution) (Result, error) {
+	ref := TaskRef{Workspace: execution.WorkspaceName, Task: execution.TaskName}
+	result := Result{TaskRef: ref}
+
+	run, err := e.runner.Run(ctx, execution, taskrun.Hooks{
+		Start: func() (io.Writer, io.Writer) {
+			stdout, stderr := e.options.Stdout, e.options.Stderr
+			if bus := e.options.Events; bus.Active() {
+				stdout = withWriter(stdout, bus.OutputWriter(ref.Workspace, ref.Task, "stdout"))
+				stderr = withWriter(stderr, bus.OutputWriter(ref.Workspace, ref.Task, "stderr"))
+			}
+			e.options.Events.Publish(Event{Type: EventTaskStarted, Workspace: ref.Workspace, Task: ref.Task})
+			return stdout, stderr
+		},
+	})
+	result.Status = Status(run.Status)
+	result.MissingOutputs = run.MissingOutputs
+	if attempt := run.Attempt; attempt != nil {
+		result.ExitCode = attempt.Result.ExitCode
+		result.Duration = attempt.Duration
+		result.Stdout = attempt.Result.Stdout
+		result.Stderr = attempt.Result.Stderr
+	}
+	if err != nil {
+		// Errors naming no task get the task they failed
+		var failed *TaskFailedError
+		var missing *MissingOutputsError
+		var undeclared *workspace.UndeclaredInputsError
+		if errors.As(err, &failed) || errors.As(err, &missing) || errors.As(err, &undeclared) {
+			return result, err
+		}
+		return result, fmt.Errorf("task %s: %w", ref, err)
+	}
+	if run.Status == taskrun.StatusCached {
+		e.options.Events.Publish(Event{Type: EventCacheHit, Workspace: ref.Workspace, Task: ref.Task})
+	}
+	return result, nil
+}
+
+func withWriter(dest, extra io.Writer) io.Writer {
+	if dest == nil {
+		return extra
+	}
+	return io.MultiWriter(dest, extra)
+}
+
+// CancelTask stops one running task with cause, which defaults to
+// context.Canceled, without cancelling the context passed to Run. The Run
+// executing it fails with a *TaskFailedError that wraps the cause; other
+// concurrent Run calls are unaffected. It reports whether the task was
+// running.
+func (e *Engine) CancelTask(ref TaskRef, cause error) bool {
+	return e.tasks.Cancel(ref.String(), cause)
+}
+
+// ClearCache removes cached state, for one workspace when workspaceName is
+// set and for all tasks, together with their stored outputs, otherwise.
+func (e *Engine) ClearCache(workspaceName string) error {
+	if workspaceName != "" {
+		return e.cache.InvalidateWorkspace(workspaceName)
+	}
+	if err := e.cache.Clear(); err != nil {
+		return err
+	}
+	return e.runner.CAS.Clear()
+}
@@ -0,0 +1,350 @@
+package doctrus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+const testConfig = `version: "1.0"
+workspaces:
+  lib:
+    path: lib
+    tasks:
+      build:
+        command: ["sh", "-c", "echo lib > out.txt"]
+        aliases: [compile]
+        cache: true
+        inputs: ["src.txt"]
+        outputs: ["out.txt"]
+  app:
+    path: app
+    tasks:
+      build:
+        command: ["sh", "-c", "echo app"]
+        depends_on: ["lib:build"]
+      fail:
+        command: ["sh", "-c", "exit 3"]
+      slow:
+        command: ["sh", "-c", "exec sleep 10"]
+        timeout: 100ms
+      all:
+        depends_on: ["build", "lib:build"]
+      typo:
+        command: ["sh", "-c", "echo app > out.txt"]
+        outputs: ["output.txt"]
+      strict:
+        command: ["sh", "-c", "echo app > out.txt"]
+        outputs: ["output.txt"]
+        strict_outputs: true
+      flaky:
+        command: ["sh", "-c", "if [ -f tried ]; then echo ok; else touch tried; exit 1; fi"]
+        retry:
+          attempts: 2
+        finally:
+          - ["sh", "-c", "echo done > cleaned.txt"]
+      wait:
+        command: ["sh", "-c", "exec sleep 10"]
+      left:
+        command: ["sh", "-c", "touch left; for i in $(seq 50); do [ -f right ] && exit 0; sleep 0.1; done; exit 1"]
+      right:
+        command: ["sh", "-c", "touch right; for i in $(seq 50); do [ -f left ] && exit 0; sleep 0.1; done; exit 1"]
+      pair:
+        depends_on: ["left", "right"]
+      greet:
+        command: ["sh", "-c", "echo hello {{.params.who}}"]
+        params:
+          - name: who
+            required: true
+`
+
+func newTestEngine(t *testing.T, opts Options) *Engine {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("test tasks use sh")
+	}
+
+	dir := t.TempDir()
+	for _, sub := range []string{"lib", "app"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatalf("failed to create workspace: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lib", "src.txt"), []byte("v1"), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	configPath := filepath.Join(dir, "doctrus.yml")
+	if err := os.WriteFile(configPath, []byte(testConfig), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	opts.ConfigPath = configPath
+	engine, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return engine
+}
+
+func TestEnginePlan(t *testing.T) {
+	engine := newTestEngine(t, Options{})
+
+	refs, err := engine.Plan("app:all", "lib:build")
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+
+	var got []string
+	for _, ref := range refs {
+		got = append(got, ref.String())
+	}
+	want := []string{"lib:build", "app:build", "app:all"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Plan() = %v, want %v", got, want)
+	}
+
+	refs, err = engine.Plan("lib:compile", "lib:build", "compile")
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if len(refs) != 1 || refs[0].String() != "lib:build" {
+		t.Fatalf("Plan() = %v, want the aliased task once, by its name", refs)
+	}
+
+	if _, err := engine.Plan("missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("expected ErrTaskNotFound for an unknown task, got %v", err)
+	}
+}
+
+func TestEngineRunUsesCache(t *testing.T) {
+	var stdout bytes.Buffer
+	engine := newTestEngine(t, Options{Stdout: &stdout})
+
+	results, err := engine.Run(context.Background(), "app:build")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(results) != 2 || results[0].Status != StatusSuccess || results[1].Status != StatusSuccess {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if results[1].Stdout != "app\n" || stdout.String() != "app\n" {
+		t.Fatalf("unexpected output: result %q, writer %q", results[1].Stdout, stdout.String())
+	}
+
+	results, err = engine.Run(context.Background(), "lib:build")
+	if err != nil {
+		t.Fatalf("second Run() error = %v", err)
+	}
+	if results[0].Status != StatusCached {
+		t.Fatalf("expected lib:build to be cached, got %s", results[0].Status)
+	}
+
+	if err := engine.ClearCache(""); err != nil {
+		t.Fatalf("ClearCache() error = %v", err)
+	}
+	results, err = engine.Run(context.Background(), "lib:build")
+	if err != nil {
+		t.Fatalf("Run() after ClearCache error = %v", err)
+	}
+	if results[0].Status != StatusSuccess {
+		t.Fatalf("expected lib:build to run after clearing the cache, got %s", results[0].Status)
+	}
+}
+
+func TestEngineRunFailure(t *testing.T) {
+	engine := newTestEngine(t, Options{})
+
+	results, err := engine.Run(context.Background(), "fail")
+	var failed *TaskFailedError
+	if !errors.As(err, &failed) {
+		t.Fatalf("expected TaskFailedError, got %v", err)
+	}
+	if failed.ExitCode != 3 || failed.Workspace != "app" || failed.Task != "fail" {
+		t.Fatalf("unexpected error: %+v", failed)
+	}
+	if len(results) != 1 || results[0].Status != StatusFailed {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
+
+func TestEngineRunMissingOutputs(t *testing.T) {
+	engine := newTestEngine(t, Options{})
+
+	results, err := engine.Run(context.Background(), "app:typo")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Status != StatusSuccess || !reflect.DeepEqual(results[0].MissingOutputs, []string{"output.txt"}) {
+		t.Fatalf("results = %+v, want a success reporting output.txt as missing", results)
+	}
+
+	results, err = engine.Run(context.Background(), "app:strict")
+	var missingErr *MissingOutputsError
+	if !errors.Is(err, ErrOutputsMissing) || !errors.As(err, &missingErr) {
+		t.Fatalf("Run() error = %v, want a *MissingOutputsError", err)
+	}
+	if len(results) != 1 || results[0].Status != StatusFailed {
+		t.Fatalf("results = %+v, want a failure", results)
+	}
+}
+
+func TestEngineRunPublishesEvents(t *testing.T) {
+	bus := NewEventBus()
+	var got []string
+	bus.Subscribe(SubscriberFunc(func(e Event) {
+		if e.Type == EventOutputChunk {
+			got = append(got, string(e.Type)+" "+e.Key()+" "+e.Data)
+			return
+		}
+		got = append(got, string(e.Type)+" "+e.Key())
+	}))
+
+	engine := newTestEngine(t, Options{Events: bus})
+	if _, err := engine.Run(context.Background(), "app:build"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := []string{
+		"task_queued lib:build",
+		"task_queued app:build",
+		"task_started lib:build",
+		"task_finished lib:build",
+		"task_started app:build",
+		"output_chunk app:build app\n",
+		"task_finished app:build",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+}
+
+func TestEngineRunTimeout(t *testing.T) {
+	engine := newTestEngine(t, Options{})
+
+	results, err := engine.Run(context.Background(), "app:slow")
+	if !errors.Is(err, ErrTaskFailed) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run() error = %v, want a timed out task failure", err)
+	}
+	if len(results) != 1 || results[0].ExitCode != 124 {
+		t.Fatalf("results = %+v, want exit code 124", results)
+	}
+}
+
+func TestEngineRunTimeoutOption(t *testing.T) {
+	engine := newTestEngine(t, Options{Timeout: 100 * time.Millisecond})
+
+	if _, err := engine.Run(context.Background(), "app:wait"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run() error = %v, want a timed out task failure", err)
+	}
+}
+
+func TestEngineRunRetriesAndFinally(t *testing.T) {
+	engine := newTestEngine(t, Options{})
+
+	results, err := engine.Run(context.Background(), "app:flaky")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Status != StatusSuccess || results[0].Stdout != "ok\n" {
+		t.Fatalf("results = %+v, want the second attempt's success", results)
+	}
+	cleaned, err := os.ReadFile(filepath.Join(engine.BasePath(), "app", "cleaned.txt"))
+	if err != nil || string(cleaned) != "done\n" {
+		t.Fatalf("finally step did not run: %q, %v", cleaned, err)
+	}
+}
+
+func TestEngineRunParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{"value", map[string]string{"who": "world"}, "hello world\n", false},
+		{"missing required", nil, "", true},
+		{"unknown", map[string]string{"who": "world", "whom": "x"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newTestEngine(t, Options{Params: tt.params})
+
+			results, err := engine.Run(context.Background(), "app:greet")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (len(results) != 1 || results[0].Stdout != tt.want) {
+				t.Fatalf("results = %+v, want output %q", results, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngineRunParallel(t *testing.T) {
+	// Each task of the pair waits for the other to start
+	engine := newTestEngine(t, Options{Parallel: 2})
+
+	results, err := engine.Run(context.Background(), "app:pair")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(results) != 3 || results[2].Task != "pair" {
+		t.Fatalf("results = %+v, want both tasks and then the pair", results)
+	}
+}
+
+func TestEngineCancelTask(t *testing.T) {
+	engine := newTestEngine(t, Options{})
+	ref := TaskRef{Workspace: "app", Task: "slow"}
+	restart := errors.New("restart")
+
+	engine.options.Events = NewEventBus()
+	engine.options.Events.Subscribe(SubscriberFunc(func(event Event) {
+		if event.Type == EventTaskStarted {
+			go func() {
+				for !engine.CancelTask(ref, restart) {
+					time.Sleep(time.Millisecond)
+				}
+			}()
+		}
+	}))
+
+	ctx := context.Background()
+	_, err := engine.Run(ctx, ref.String())
+	if !errors.Is(err, restart) {
+		t.Fatalf("Run() error = %v, want cancellation cause", err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("task was cancelled by its timeout instead of CancelTask")
+	}
+}
+
+func TestEngineValidatesOnlyUsedWorkspaces(t *testing.T) {
+	engine := newTestEngine(t, Options{})
+	cfg := engine.Config()
+	cfg.Workspaces["optional"] = Workspace{
+		Path:  "missing",
+		Tasks: map[string]Task{"build": {Command: []string{"true"}}},
+	}
+
+	if _, err := NewWithConfig(cfg, engine.BasePath(), Options{Strict: true}); err == nil {
+		t.Fatal("NewWithConfig() with Strict should reject the missing workspace path")
+	}
+
+	lenient, err := NewWithConfig(cfg, engine.BasePath(), Options{})
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+	if _, err := lenient.Run(context.Background(), "app:build"); err != nil {
+		t.Fatalf("Run(app:build) error = %v", err)
+	}
+	if _, err := lenient.Run(context.Background(), "optional:build"); err == nil {
+		t.Fatal("Run(optional:build) should fail on the missing workspace path")
+	}
+}